@@ -0,0 +1,254 @@
+// Package alert raises alerts with a severity from rules on the transactions of a
+// subscription, e.g. a critical alert when a watched wallet sells more than half of its
+// position, and sends them to sinks such as webhooks. Rules are usually loaded from YAML,
+// see Config, so that operational alerting is configuration rather than code.
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+	"github.com/gerasimovvladislav/zensol-go/decoders"
+	"github.com/gerasimovvladislav/zensol-go/decoders/builtin"
+	"github.com/gerasimovvladislav/zensol-go/metrics"
+)
+
+// maxCooldowns is the number of cooldowns above which expired ones are dropped.
+const maxCooldowns = 4096
+
+// Event is what rules see of a transaction. Amounts are in UI units, e.g. SOL rather than
+// lamports.
+type Event struct {
+	Signature string `json:"signature"`
+	Slot      uint64 `json:"slot"`
+	// Type is the event type of metrics.EventType, e.g. sell.
+	Type string `json:"type"`
+	// Wallet is the fee payer, taken as the trader.
+	Wallet string `json:"wallet"`
+	// Mint and Amount are the token traded by the wallet and how much of it, see
+	// chainstream.TransactionNotification.InferTrade. They are empty for other events.
+	Mint   string  `json:"mint,omitempty"`
+	Amount float64 `json:"amount,omitempty"`
+	// SOL is the SOL paid or received in the trade, zero for trades in other quotes.
+	SOL float64 `json:"sol,omitempty"`
+	// PositionSold is the percentage of its balance of Mint the wallet sold, for sells.
+	PositionSold float64 `json:"positionSold,omitempty"`
+	// Fee is the transaction fee in SOL.
+	Fee float64 `json:"fee"`
+	// Programs are the programs invoked by top-level instructions.
+	Programs []string `json:"programs,omitempty"`
+	// Instructions are the names of the decoded instructions, e.g. buy.
+	Instructions []string `json:"instructions,omitempty"`
+}
+
+// NewEvent describes the notification for rules, decoding its instructions with registry.
+// A nil registry uses builtin.NewRegistry.
+func NewEvent(notification *chainstream.TransactionNotification, registry *decoders.Registry) *Event {
+	if registry == nil {
+		registry = builtin.NewRegistry()
+	}
+	trade := notification.InferTrade()
+	event := &Event{
+		Signature: notification.Signature(),
+		Slot:      notification.Slot(),
+		Type:      metrics.EventType(notification),
+		Wallet:    notification.Owner(),
+		Fee:       float64(notification.Params.Result.Value.Meta.Fee) / chainstream.LamportsPerSOL,
+	}
+	if trade.Side != chainstream.SideUnknown {
+		event.Mint = trade.Mint
+		event.Amount = uiAmount(trade.Amount, trade.Decimals)
+		if trade.QuoteMint == chainstream.WrappedSOLMint {
+			event.SOL = uiAmount(trade.QuoteAmount, trade.QuoteDecimals)
+		}
+	}
+	if trade.Side == chainstream.SideSell {
+		var pre, post uint64
+		for _, change := range notification.TokenBalanceChanges() {
+			if change.Owner == event.Wallet && change.Mint == trade.Mint {
+				pre, post = pre+change.Pre, post+change.Post
+			}
+		}
+		if pre > post {
+			event.PositionSold = float64(pre-post) / float64(pre) * 100
+		}
+	}
+	for _, instruction := range notification.Instructions() {
+		if !instruction.IsInner() && !contains(event.Programs, instruction.ProgramID) {
+			event.Programs = append(event.Programs, instruction.ProgramID)
+		}
+	}
+	for _, decoded := range registry.DecodeTransaction(notification) {
+		if decoded != nil {
+			event.Instructions = append(event.Instructions, decoded.Name)
+		}
+	}
+	return event
+}
+
+// field returns the value of a Field constant.
+func (e *Event) field(name string) float64 {
+	switch name {
+	case FieldAmount:
+		return e.Amount
+	case FieldSOL:
+		return e.SOL
+	case FieldPositionSold:
+		return e.PositionSold
+	case FieldFee:
+		return e.Fee
+	case FieldSlot:
+		return float64(e.Slot)
+	}
+	return 0
+}
+
+func uiAmount(amount uint64, decimals int) float64 {
+	value := float64(amount)
+	for i := 0; i < decimals; i++ {
+		value /= 10
+	}
+	return value
+}
+
+// Alert is raised by a rule matching an event.
+type Alert struct {
+	Rule     string    `json:"rule"`
+	Severity Severity  `json:"severity"`
+	Message  string    `json:"message"`
+	Event    *Event    `json:"event"`
+	Time     time.Time `json:"time"`
+}
+
+// Sink receives alerts, e.g. JSONLines or Webhook.
+type Sink func(ctx context.Context, alert Alert) error
+
+// Engine evaluates rules on notifications and sends the alerts to sinks. It is safe for
+// concurrent use.
+type Engine struct {
+	rules    []Rule
+	registry *decoders.Registry
+	sinks    []Sink
+	clock    clock.Clock
+
+	mu        sync.Mutex
+	cooldowns map[cooldownKey]time.Time
+}
+
+// Options of an Engine.
+type Options struct {
+	// Registry decodes instructions for Event.Instructions. Nil uses builtin.NewRegistry.
+	Registry *decoders.Registry
+	// Clock times alerts and cooldowns. Nil uses the system clock.
+	Clock clock.Clock
+}
+
+// cooldownKey identifies the wallet and mint a rule is silenced for.
+type cooldownKey struct {
+	rule, wallet, mint string
+}
+
+// New returns an engine evaluating the rules of the config and sending alerts to sinks.
+func New(config Config, options Options, sinks ...Sink) (*Engine, error) {
+	config.Rules = append([]Rule(nil), config.Rules...)
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	for _, sink := range sinks {
+		if sink == nil {
+			return nil, errors.New("alert: sink is nil")
+		}
+	}
+	if options.Registry == nil {
+		options.Registry = builtin.NewRegistry()
+	}
+	if options.Clock == nil {
+		options.Clock = clock.System()
+	}
+	return &Engine{
+		rules:     config.Rules,
+		registry:  options.Registry,
+		sinks:     sinks,
+		clock:     options.Clock,
+		cooldowns: make(map[cooldownKey]time.Time),
+	}, nil
+}
+
+// Evaluate returns the alerts of the rules matching the notification, in rule order. A
+// rule in cooldown for the wallet and mint of the event is skipped, and a returned alert
+// starts its cooldown.
+func (e *Engine) Evaluate(notification *chainstream.TransactionNotification) []Alert {
+	event := NewEvent(notification, e.registry)
+	now := e.clock.Now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var alerts []Alert
+	for i := range e.rules {
+		rule := &e.rules[i]
+		if !rule.matches(event) {
+			continue
+		}
+		if rule.Cooldown > 0 {
+			key := cooldownKey{rule.Name, event.Wallet, event.Mint}
+			if until, ok := e.cooldowns[key]; ok && now.Before(until) {
+				continue
+			}
+			e.setCooldown(key, now.Add(time.Duration(rule.Cooldown)), now)
+		}
+		alerts = append(alerts, Alert{
+			Rule:     rule.Name,
+			Severity: rule.Severity.orDefault(),
+			Message:  rule.format(event),
+			Event:    event,
+			Time:     now,
+		})
+	}
+	return alerts
+}
+
+// Observe evaluates the notification and sends its alerts to every sink. The errors of
+// the sinks are joined; a failing sink does not keep the alert from the others.
+func (e *Engine) Observe(ctx context.Context, notification *chainstream.TransactionNotification) error {
+	var errs []error
+	for _, alert := range e.Evaluate(notification) {
+		for _, sink := range e.sinks {
+			if err := sink(ctx, alert); err != nil {
+				errs = append(errs, fmt.Errorf("alert %s: %w", alert.Rule, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setCooldown silences key until the given time, dropping the expired cooldowns once there
+// are many, which bounds the memory of rules on many wallets.
+func (e *Engine) setCooldown(key cooldownKey, until, now time.Time) {
+	if len(e.cooldowns) >= maxCooldowns {
+		for k, t := range e.cooldowns {
+			if !now.Before(t) {
+				delete(e.cooldowns, k)
+			}
+		}
+	}
+	e.cooldowns[key] = until
+}
+
+// format returns the message of the rule for the event.
+func (r *Rule) format(event *Event) string {
+	summary := fmt.Sprintf("%s: %s by %s in %s", r.Name, event.Type, event.Wallet, event.Signature)
+	if r.message == nil {
+		return summary
+	}
+	var message strings.Builder
+	if err := r.message.Execute(&message, event); err != nil {
+		return fmt.Sprintf("%s (message: %v)", summary, err)
+	}
+	return message.String()
+}
@@ -0,0 +1,204 @@
+package alert_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/alert"
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+	"github.com/gerasimovvladislav/zensol-go/decoders"
+	"github.com/gerasimovvladislav/zensol-go/decoders/pumpfun"
+)
+
+const (
+	wallet = "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF"
+	mint   = "DNvtizsEYyknJoW3QYwDA7ncjxri3KBBTeLydEZCpump"
+)
+
+func loadNotification(t *testing.T, file string) *chainstream.TransactionNotification {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	var tx chainstream.TransactionNotification
+	if err := json.Unmarshal(data, &tx); err != nil {
+		t.Fatalf("failed to unmarshal tx: %v", err)
+	}
+	return &tx
+}
+
+func TestNewEvent(t *testing.T) {
+	event := alert.NewEvent(loadNotification(t, "../chainstream/testdata/sample_tx_sell.json"), decoders.NewRegistry(pumpfun.New()))
+	if event.Type != "sell" || event.Wallet != wallet || event.Mint != mint {
+		t.Errorf("event = %+v", event)
+	}
+	if event.Amount != 357547.484136 || event.SOL != 0.009899999 || event.PositionSold != 100 {
+		t.Errorf("amount = %v, sol = %v, position sold = %v", event.Amount, event.SOL, event.PositionSold)
+	}
+	if len(event.Instructions) == 0 {
+		t.Error("no decoded instructions")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	config, err := alert.Parse([]byte(`
+rules:
+  - name: whale-exit
+    severity: critical
+    type: sell
+    wallets: [` + wallet + `]
+    when:
+      - {field: positionSold, op: ">", value: 50}
+    message: '{{.Wallet}} sold {{printf "%.0f" .PositionSold}}% of {{.Mint}}'
+    cooldown: 10m
+  - name: large-sells
+    type: sell
+    when:
+      - {field: sol, op: ">=", value: 1}
+  - name: failures
+    severity: info
+    type: failed
+`))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	clk := clock.NewFake(time.Now())
+	engine, err := alert.New(config, alert.Options{Clock: clk})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	sell := loadNotification(t, "../chainstream/testdata/sample_tx_sell.json")
+
+	alerts := engine.Evaluate(sell)
+	if len(alerts) != 1 || alerts[0].Rule != "whale-exit" || alerts[0].Severity != alert.SeverityCritical {
+		t.Fatalf("alerts = %+v, expected whale-exit only", alerts)
+	}
+	if expected := wallet + " sold 100% of " + mint; alerts[0].Message != expected {
+		t.Errorf("message = %q, expected %q", alerts[0].Message, expected)
+	}
+
+	if alerts := engine.Evaluate(sell); len(alerts) != 0 {
+		t.Errorf("alerts during the cooldown = %+v", alerts)
+	}
+	clk.Advance(10 * time.Minute)
+	if alerts := engine.Evaluate(sell); len(alerts) != 1 {
+		t.Errorf("alerts after the cooldown = %+v", alerts)
+	}
+
+	alerts = engine.Evaluate(loadNotification(t, "../chainstream/testdata/sample_tx_create.json"))
+	if len(alerts) != 1 || alerts[0].Rule != "failures" || alerts[0].Severity != alert.SeverityInfo {
+		t.Errorf("alerts of a failed transaction = %+v", alerts)
+	}
+	if !strings.HasPrefix(alerts[0].Message, "failures: failed by ") {
+		t.Errorf("default message = %q", alerts[0].Message)
+	}
+}
+
+func TestObserve(t *testing.T) {
+	config := alert.Config{Rules: []alert.Rule{
+		{Name: "sells", Severity: alert.SeverityInfo, Type: "sell"},
+		{Name: "exits", Severity: alert.SeverityCritical, Type: "sell", When: []alert.Condition{{Field: alert.FieldPositionSold, Op: "==", Value: 100}}},
+	}}
+
+	var posted []alert.Alert
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a alert.Alert
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			t.Errorf("webhook received %v", err)
+		}
+		posted = append(posted, a)
+	}))
+	defer srv.Close()
+	var lines bytes.Buffer
+	errSink := errors.New("sink is down")
+
+	engine, err := alert.New(config, alert.Options{},
+		alert.JSONLines(&lines),
+		alert.MinSeverity(alert.SeverityCritical, alert.Webhook(srv.URL, nil)),
+		func(context.Context, alert.Alert) error { return errSink },
+	)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := engine.Observe(context.Background(), loadNotification(t, "../chainstream/testdata/sample_tx_sell.json")); !errors.Is(err, errSink) {
+		t.Errorf("Observe() error = %v, expected the sink error", err)
+	}
+	if n := strings.Count(lines.String(), "\n"); n != 2 {
+		t.Errorf("wrote %d JSON lines, expected both alerts", n)
+	}
+	if len(posted) != 1 || posted[0].Rule != "exits" || posted[0].Event.Mint != mint {
+		t.Errorf("posted %+v, expected the critical alert only", posted)
+	}
+}
+
+func TestWebhookStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	if err := alert.Webhook(srv.URL, nil)(context.Background(), alert.Alert{Rule: "a"}); err == nil {
+		t.Error("Webhook() succeeded with a 502 response")
+	}
+}
+
+func TestQueue(t *testing.T) {
+	started, release := make(chan struct{}, 3), make(chan struct{})
+	var delivered []string
+	var failed []string
+	errSink := errors.New("sink is down")
+	queue := alert.NewQueue(func(ctx context.Context, a alert.Alert) error {
+		started <- struct{}{}
+		<-release
+		if ctx.Err() != nil {
+			t.Errorf("alert %s delivered with %v", a.Rule, ctx.Err())
+		}
+		delivered = append(delivered, a.Rule)
+		if a.Rule == "b" {
+			return errSink
+		}
+		return nil
+	}, 1, func(a alert.Alert, err error) {
+		if errors.Is(err, errSink) {
+			failed = append(failed, a.Rule)
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := queue.Sink(ctx, alert.Alert{Rule: "a"}); err != nil {
+		t.Fatalf("Sink() error: %v", err)
+	}
+	<-started
+	// The sink is busy with a: b waits and c is dropped.
+	if err := queue.Sink(ctx, alert.Alert{Rule: "b"}); err != nil {
+		t.Fatalf("Sink() error: %v", err)
+	}
+	if err := queue.Sink(ctx, alert.Alert{Rule: "c"}); !errors.Is(err, alert.ErrQueueFull) {
+		t.Errorf("Sink() error = %v, expected ErrQueueFull", err)
+	}
+	// Queued alerts outlive the handler that raised them.
+	cancel()
+	close(release)
+	queue.Close()
+
+	if strings.Join(delivered, ",") != "a,b" || strings.Join(failed, ",") != "b" {
+		t.Errorf("delivered %v and failed %v, expected a and b delivered and b failed", delivered, failed)
+	}
+	if dropped := queue.Dropped(); dropped != 1 {
+		t.Errorf("Dropped() = %d, expected 1", dropped)
+	}
+	if err := queue.Sink(context.Background(), alert.Alert{Rule: "d"}); !errors.Is(err, alert.ErrQueueClosed) {
+		t.Errorf("Sink() error = %v after Close, expected ErrQueueClosed", err)
+	}
+}
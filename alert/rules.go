@@ -0,0 +1,230 @@
+package alert
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/gerasimovvladislav/zensol-go/metrics"
+)
+
+// ErrInvalidRules is returned by Parse, Load and New for rules that cannot be evaluated.
+var ErrInvalidRules = errors.New("alert: invalid rules")
+
+// Fields of the events conditions compare, see Event.
+const (
+	FieldAmount       = "amount"
+	FieldSOL          = "sol"
+	FieldPositionSold = "positionSold"
+	FieldFee          = "fee"
+	FieldSlot         = "slot"
+)
+
+// Config is a set of rules, usually loaded from YAML:
+//
+//	rules:
+//	  - name: whale-exit
+//	    severity: critical
+//	    type: sell
+//	    wallets: [53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF]
+//	    when:
+//	      - {field: positionSold, op: ">", value: 50}
+//	    message: "{{.Wallet}} sold {{printf \"%.0f\" .PositionSold}}% of {{.Mint}}"
+//	    cooldown: 10m
+type Config struct {
+	Rules []Rule `yaml:"rules" json:"rules"`
+}
+
+// Rule raises an alert for the events it matches: events of Type, by one of Wallets, of
+// one of Mints, invoking one of Programs and one of Instructions, meeting every condition
+// of When. Empty lists match every event.
+type Rule struct {
+	// Name identifies the rule in alerts and must be unique.
+	Name string `yaml:"name" json:"name"`
+	// Severity of the alerts. Zero uses DefaultSeverity.
+	Severity Severity `yaml:"severity" json:"severity,omitempty"`
+	// Type is the event type, one of the metrics.Event constants, e.g. sell. Empty matches
+	// every type.
+	Type string `yaml:"type" json:"type,omitempty"`
+	// Wallets are the traders to match, see Event.Wallet.
+	Wallets []string `yaml:"wallets" json:"wallets,omitempty"`
+	// Mints are the traded mints to match, see Event.Mint.
+	Mints []string `yaml:"mints" json:"mints,omitempty"`
+	// Programs are the invoked programs to match, see Event.Programs.
+	Programs []string `yaml:"programs" json:"programs,omitempty"`
+	// Instructions are the decoded instruction names to match, see Event.Instructions.
+	Instructions []string `yaml:"instructions" json:"instructions,omitempty"`
+	// When holds the thresholds the event must meet.
+	When []Condition `yaml:"when" json:"when,omitempty"`
+	// Message is a text/template executed with the Event. Empty uses a summary of the
+	// rule and the event.
+	Message string `yaml:"message" json:"message,omitempty"`
+	// Cooldown silences the rule for the same wallet and mint for this long after an
+	// alert. Zero alerts on every match.
+	Cooldown Duration `yaml:"cooldown" json:"cooldown,omitempty"`
+
+	message *template.Template
+}
+
+// Condition compares a field of the event, one of the Field constants, with Value. Op is
+// one of >, >=, <, <=, == and !=.
+type Condition struct {
+	Field string  `yaml:"field" json:"field"`
+	Op    string  `yaml:"op" json:"op"`
+	Value float64 `yaml:"value" json:"value"`
+}
+
+// Duration is a time.Duration written as text, e.g. 10m.
+type Duration time.Duration
+
+// MarshalText encodes the duration like time.Duration.String.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText parses the duration with time.ParseDuration. Empty text is zero.
+func (d *Duration) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*d = 0
+		return nil
+	}
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// Load reads rules from a YAML file, see Parse.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	return Parse(data)
+}
+
+// Parse parses and validates rules in YAML. Unknown keys are rejected, so that a typo does
+// not silently widen a rule.
+func Parse(data []byte) (Config, error) {
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		return Config{}, fmt.Errorf("%w: %v", ErrInvalidRules, err)
+	}
+	return config, config.Validate()
+}
+
+// Validate reports the problems of the rules, wrapping ErrInvalidRules.
+func (c *Config) Validate() error {
+	var errs []error
+	names := make(map[string]bool)
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		if rule.Name == "" {
+			errs = append(errs, fmt.Errorf("%w: rule %d: name is required", ErrInvalidRules, i))
+		} else if names[rule.Name] {
+			errs = append(errs, fmt.Errorf("%w: rule %q: duplicate name", ErrInvalidRules, rule.Name))
+		}
+		names[rule.Name] = true
+		if err := rule.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%w: rule %q: %v", ErrInvalidRules, rule.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Rule) validate() error {
+	if r.Severity != 0 && (r.Severity < SeverityInfo || r.Severity > SeverityCritical) {
+		return fmt.Errorf("unknown severity %d", r.Severity)
+	}
+	switch r.Type {
+	case "", metrics.EventFailed, metrics.EventBuy, metrics.EventSell, metrics.EventSwap, metrics.EventTransfer, metrics.EventOther:
+	default:
+		return fmt.Errorf("unknown type %q", r.Type)
+	}
+	if r.Cooldown < 0 {
+		return errors.New("cooldown is negative")
+	}
+	for _, condition := range r.When {
+		switch condition.Field {
+		case FieldAmount, FieldSOL, FieldFee, FieldSlot:
+		case FieldPositionSold:
+			if r.Type != metrics.EventSell {
+				return fmt.Errorf("field %s requires type %s", condition.Field, metrics.EventSell)
+			}
+		default:
+			return fmt.Errorf("unknown field %q", condition.Field)
+		}
+		if _, ok := operators[condition.Op]; !ok {
+			return fmt.Errorf("unknown operator %q", condition.Op)
+		}
+	}
+	if r.Message != "" {
+		message, err := template.New(r.Name).Option("missingkey=error").Parse(r.Message)
+		if err != nil {
+			return err
+		}
+		r.message = message
+	}
+	return nil
+}
+
+var operators = map[string]func(a, b float64) bool{
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+// matches reports whether the event meets the rule.
+func (r *Rule) matches(event *Event) bool {
+	if r.Type != "" && r.Type != event.Type {
+		return false
+	}
+	if len(r.Wallets) > 0 && !contains(r.Wallets, event.Wallet) {
+		return false
+	}
+	if len(r.Mints) > 0 && !contains(r.Mints, event.Mint) {
+		return false
+	}
+	if len(r.Programs) > 0 && !containsAny(r.Programs, event.Programs) {
+		return false
+	}
+	if len(r.Instructions) > 0 && !containsAny(r.Instructions, event.Instructions) {
+		return false
+	}
+	for _, condition := range r.When {
+		if !operators[condition.Op](event.field(condition.Field), condition.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(list, values []string) bool {
+	for _, value := range values {
+		if contains(list, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package alert_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/alert"
+)
+
+func TestParse(t *testing.T) {
+	config, err := alert.Parse([]byte(`
+rules:
+  - name: whale-exit
+    severity: critical
+    type: sell
+    wallets: [53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF]
+    when:
+      - {field: positionSold, op: ">", value: 50}
+    cooldown: 10m
+  - name: pump-buys
+    type: buy
+    programs: [6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P]
+`))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if len(config.Rules) != 2 {
+		t.Fatalf("parsed %d rules", len(config.Rules))
+	}
+	rule := config.Rules[0]
+	if rule.Severity != alert.SeverityCritical || time.Duration(rule.Cooldown) != 10*time.Minute {
+		t.Errorf("rule = %+v", rule)
+	}
+	if len(rule.When) != 1 || rule.When[0] != (alert.Condition{Field: alert.FieldPositionSold, Op: ">", Value: 50}) {
+		t.Errorf("conditions = %+v", rule.When)
+	}
+	if config.Rules[1].Severity != 0 {
+		t.Errorf("severity without a value = %v, expected unset", config.Rules[1].Severity)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name, yaml string
+	}{
+		{"unknown key", "rules:\n  - name: a\n    wallet: x\n"},
+		{"no name", "rules:\n  - type: sell\n"},
+		{"duplicate name", "rules:\n  - name: a\n  - name: a\n"},
+		{"unknown severity", "rules:\n  - name: a\n    severity: page\n"},
+		{"unknown type", "rules:\n  - name: a\n    type: mint\n"},
+		{"unknown field", "rules:\n  - name: a\n    when: [{field: volume, op: '>', value: 1}]\n"},
+		{"unknown operator", "rules:\n  - name: a\n    when: [{field: sol, op: '=>', value: 1}]\n"},
+		{"position of a buy", "rules:\n  - name: a\n    type: buy\n    when: [{field: positionSold, op: '>', value: 1}]\n"},
+		{"bad cooldown", "rules:\n  - name: a\n    cooldown: soon\n"},
+		{"bad message", "rules:\n  - name: a\n    message: '{{.Wallet'\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := alert.Parse([]byte(tt.yaml)); !errors.Is(err, alert.ErrInvalidRules) {
+				t.Errorf("Parse() error = %v, expected ErrInvalidRules", err)
+			}
+		})
+	}
+}
+
+func TestSeverityText(t *testing.T) {
+	for _, severity := range []alert.Severity{alert.SeverityInfo, alert.SeverityWarning, alert.SeverityCritical} {
+		text, err := severity.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText(%d) error: %v", severity, err)
+		}
+		var parsed alert.Severity
+		if err := parsed.UnmarshalText(text); err != nil || parsed != severity {
+			t.Errorf("UnmarshalText(%q) = %v, %v", text, parsed, err)
+		}
+	}
+	if _, err := alert.ParseSeverity("page"); err == nil {
+		t.Error("ParseSeverity(page) succeeded")
+	}
+}
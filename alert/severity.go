@@ -0,0 +1,74 @@
+package alert
+
+import "fmt"
+
+// Severity is the severity of an alert. The zero value is unset and stands for
+// DefaultSeverity.
+type Severity int
+
+// Severities, in increasing order.
+const (
+	SeverityInfo Severity = iota + 1
+	SeverityWarning
+	SeverityCritical
+)
+
+// DefaultSeverity is the severity of rules that do not set one.
+const DefaultSeverity = SeverityWarning
+
+// ParseSeverity parses info, warning or critical.
+func ParseSeverity(s string) (Severity, error) {
+	switch s {
+	case "info":
+		return SeverityInfo, nil
+	case "warning":
+		return SeverityWarning, nil
+	case "critical":
+		return SeverityCritical, nil
+	}
+	return 0, fmt.Errorf("unknown severity %q", s)
+}
+
+// String returns the name of the severity, empty when unset.
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	case 0:
+		return ""
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// MarshalText encodes the severity as its name.
+func (s Severity) MarshalText() ([]byte, error) {
+	if s != 0 && (s < SeverityInfo || s > SeverityCritical) {
+		return nil, fmt.Errorf("unknown severity %d", s)
+	}
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText parses the name of a severity. Empty text leaves it unset.
+func (s *Severity) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*s = 0
+		return nil
+	}
+	severity, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = severity
+	return nil
+}
+
+func (s Severity) orDefault() Severity {
+	if s == 0 {
+		return DefaultSeverity
+	}
+	return s
+}
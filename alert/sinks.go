@@ -0,0 +1,159 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of Webhook and NewQueue.
+const (
+	DefaultWebhookTimeout = 10 * time.Second
+	DefaultQueueSize      = 64
+)
+
+// Errors returned by the sink of a Queue.
+var (
+	ErrQueueFull   = errors.New("alert: queue full")
+	ErrQueueClosed = errors.New("alert: queue closed")
+)
+
+// JSONLines writes alerts to w as JSON lines. Writes are serialized.
+func JSONLines(w io.Writer) Sink {
+	var mu sync.Mutex
+	encoder := json.NewEncoder(w)
+	return func(_ context.Context, alert Alert) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return encoder.Encode(alert)
+	}
+}
+
+// Webhook posts alerts as JSON to url with client, nil using a client timing out after
+// DefaultWebhookTimeout. Responses other than 2xx are errors. Posting blocks the caller:
+// wrap the sink in a Queue to keep a slow endpoint from stalling a subscription.
+func Webhook(url string, client *http.Client) Sink {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultWebhookTimeout}
+	}
+	return func(ctx context.Context, alert Alert) error {
+		body, err := json.Marshal(alert)
+		if err != nil {
+			return fmt.Errorf("cannot encode alert: %w", err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("cannot post alert: %w", err)
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("cannot post alert: unexpected status %s", resp.Status)
+		}
+		return nil
+	}
+}
+
+// MinSeverity passes to sink only the alerts of at least min severity, e.g. to page on
+// critical alerts only.
+func MinSeverity(min Severity, sink Sink) Sink {
+	return func(ctx context.Context, alert Alert) error {
+		if alert.Severity < min {
+			return nil
+		}
+		return sink(ctx, alert)
+	}
+}
+
+// Queue delivers alerts to a sink in the background, in order, so that a slow sink does
+// not block the notification handler raising them. Alerts arriving while size of them wait
+// are dropped. It is safe for concurrent use.
+type Queue struct {
+	sink    Sink
+	onError func(Alert, error)
+	alerts  chan queuedAlert
+	done    chan struct{}
+	dropped atomic.Uint64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// queuedAlert is an alert waiting in a Queue with the context it was raised with.
+type queuedAlert struct {
+	ctx   context.Context
+	alert Alert
+}
+
+// NewQueue starts delivering the alerts passed to the Sink of the queue to sink, with at
+// most size of them waiting, DefaultQueueSize if size <= 0. The errors of sink are passed
+// to onError, which may be nil. Close must be called to stop the queue.
+func NewQueue(sink Sink, size int, onError func(Alert, error)) *Queue {
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	q := &Queue{
+		sink:    sink,
+		onError: onError,
+		alerts:  make(chan queuedAlert, size),
+		done:    make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+func (q *Queue) run() {
+	defer close(q.done)
+	for queued := range q.alerts {
+		if err := q.sink(queued.ctx, queued.alert); err != nil && q.onError != nil {
+			q.onError(queued.alert, err)
+		}
+	}
+}
+
+// Sink queues the alert without waiting for its delivery. It returns ErrQueueFull when the
+// alert is dropped and ErrQueueClosed once the queue is closed. The alert is delivered with
+// the values of ctx but not its cancellation, which usually ends with the handler.
+func (q *Queue) Sink(ctx context.Context, alert Alert) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case q.alerts <- queuedAlert{context.WithoutCancel(ctx), alert}:
+		return nil
+	default:
+		q.dropped.Add(1)
+		return ErrQueueFull
+	}
+}
+
+// Dropped returns the number of alerts dropped because the queue was full.
+func (q *Queue) Dropped() uint64 {
+	return q.dropped.Load()
+}
+
+// Close stops accepting alerts and waits for the queued ones to be delivered.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.alerts)
+	}
+	q.mu.Unlock()
+	<-q.done
+}
@@ -0,0 +1,184 @@
+// Package backfill loads historical transactions of an address over RPC and delivers them
+// in chronological order, reporting progress along the way.
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/rpc"
+)
+
+const defaultPageSize = 1000
+
+// Phase tells which part of the backfill is running.
+type Phase string
+
+const (
+	// PhaseSignatures is the walk back through the address history collecting signatures.
+	PhaseSignatures Phase = "signatures"
+	// PhaseTransactions is the fetch of full transactions, oldest first.
+	PhaseTransactions Phase = "transactions"
+)
+
+// Progress is a snapshot of the backfill state.
+type Progress struct {
+	Phase               Phase
+	SignaturesFetched   int
+	TransactionsFetched int
+	// CurrentSlot is the slot of the last signature or transaction processed.
+	CurrentSlot uint64
+	// SlotsRemaining is the distance between CurrentSlot and the target slot of the phase.
+	SlotsRemaining uint64
+	Elapsed        time.Duration
+	// ETA is the estimated time until the current phase completes, zero when unknown.
+	ETA time.Duration
+}
+
+// ProgressFunc receives progress updates. It is called synchronously and should return quickly.
+type ProgressFunc func(Progress)
+
+// Config describes a backfill.
+type Config struct {
+	// Address is the account whose history is loaded.
+	Address string
+	// FromSlot is the oldest slot to include. Zero loads the whole history.
+	FromSlot uint64
+	// Until stops the walk at this signature (exclusive), e.g. the oldest live notification.
+	Until string
+	// Commitment used for RPC calls, defaults to confirmed.
+	Commitment chainstream.Commitment
+	// PageSize is the getSignaturesForAddress page size, at most 1000.
+	PageSize int
+	// SkipFailed skips transactions that failed on-chain.
+	SkipFailed bool
+	// Progress is called after every page of signatures and every transaction.
+	Progress ProgressFunc
+}
+
+// Run collects the signatures of cfg.Address back to cfg.FromSlot and then delivers the
+// transactions to do from oldest to newest. A non-nil error returned by do stops the backfill.
+func Run(
+	ctx context.Context,
+	client *rpc.Client,
+	cfg Config,
+	do func(notification *chainstream.TransactionNotification) error,
+) error {
+	if cfg.Address == "" {
+		return errors.New("backfill: address is required")
+	}
+	if cfg.Commitment == 0 {
+		cfg.Commitment = chainstream.DefaultCommitment
+	}
+	if cfg.PageSize <= 0 || cfg.PageSize > defaultPageSize {
+		cfg.PageSize = defaultPageSize
+	}
+
+	started := time.Now()
+	report := func(p Progress) {
+		if cfg.Progress != nil {
+			p.Elapsed = time.Since(started)
+			cfg.Progress(p)
+		}
+	}
+
+	signatures, err := collectSignatures(ctx, client, cfg, report)
+	if err != nil {
+		return err
+	}
+
+	phaseStarted := time.Now()
+	progress := Progress{Phase: PhaseTransactions, SignaturesFetched: len(signatures)}
+	newest := uint64(0)
+	if len(signatures) > 0 {
+		newest = signatures[0].Slot
+	}
+	for i := len(signatures) - 1; i >= 0; i-- {
+		sig := signatures[i]
+		notification, err := client.GetTransaction(ctx, sig.Signature, cfg.Commitment)
+		if err != nil {
+			return fmt.Errorf("backfill: cannot fetch transaction %s: %w", sig.Signature, err)
+		}
+
+		progress.TransactionsFetched++
+		progress.CurrentSlot = sig.Slot
+		progress.SlotsRemaining = newest - sig.Slot
+		progress.ETA = eta(time.Since(phaseStarted), progress.TransactionsFetched, i)
+		report(progress)
+
+		if notification == nil {
+			continue
+		}
+		if err = do(notification); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectSignatures walks the address history from newest to oldest. The result is newest first.
+func collectSignatures(ctx context.Context, client *rpc.Client, cfg Config, report func(Progress)) ([]rpc.SignatureInfo, error) {
+	var (
+		signatures []rpc.SignatureInfo
+		before     string
+		topSlot    uint64
+		started    = time.Now()
+	)
+	for {
+		page, err := client.GetSignaturesForAddress(ctx, cfg.Address, &rpc.SignaturesOptions{
+			Limit:      cfg.PageSize,
+			Before:     before,
+			Until:      cfg.Until,
+			Commitment: cfg.Commitment,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("backfill: cannot fetch signatures: %w", err)
+		}
+		if len(page) == 0 {
+			return signatures, nil
+		}
+		if topSlot == 0 {
+			topSlot = page[0].Slot
+		}
+
+		done := len(page) < cfg.PageSize
+		for _, sig := range page {
+			if sig.Slot < cfg.FromSlot {
+				done = true
+				break
+			}
+			if cfg.SkipFailed && len(sig.Err) > 0 && string(sig.Err) != "null" {
+				continue
+			}
+			signatures = append(signatures, sig)
+		}
+
+		last := page[len(page)-1]
+		before = last.Signature
+		progress := Progress{
+			Phase:             PhaseSignatures,
+			SignaturesFetched: len(signatures),
+			CurrentSlot:       last.Slot,
+		}
+		if cfg.FromSlot > 0 && last.Slot > cfg.FromSlot {
+			progress.SlotsRemaining = last.Slot - cfg.FromSlot
+			progress.ETA = eta(time.Since(started), int(topSlot-last.Slot), int(progress.SlotsRemaining))
+		}
+		report(progress)
+
+		if done {
+			return signatures, nil
+		}
+	}
+}
+
+// eta extrapolates the time needed for remaining units from the time spent on done units.
+func eta(elapsed time.Duration, done, remaining int) time.Duration {
+	if done <= 0 || remaining <= 0 {
+		return 0
+	}
+	return time.Duration(float64(elapsed) / float64(done) * float64(remaining))
+}
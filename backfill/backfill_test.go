@@ -0,0 +1,183 @@
+package backfill_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/backfill"
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/rpc"
+)
+
+func TestRun(t *testing.T) {
+	history := []rpc.SignatureInfo{
+		{Signature: "sig-4", Slot: 400},
+		{Signature: "sig-3", Slot: 300},
+		{Signature: "sig-2", Slot: 200},
+		{Signature: "sig-1", Slot: 100},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int               `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		var result interface{}
+		switch req.Method {
+		case "getSignaturesForAddress":
+			var opts rpc.SignaturesOptions
+			_ = json.Unmarshal(req.Params[1], &opts)
+			start := 0
+			for i, sig := range history {
+				if sig.Signature == opts.Before {
+					start = i + 1
+				}
+			}
+			end := start + opts.Limit
+			if end > len(history) {
+				end = len(history)
+			}
+			result = history[start:end]
+		case "getTransaction":
+			var sig string
+			_ = json.Unmarshal(req.Params[0], &sig)
+			for _, info := range history {
+				if info.Signature == sig {
+					result = chainstream.TransactionValue{Slot: info.Slot}
+				}
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	defer srv.Close()
+
+	var (
+		delivered []string
+		updates   []backfill.Progress
+	)
+	err := backfill.Run(context.Background(), rpc.NewClient(srv.URL), backfill.Config{
+		Address:  "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF",
+		FromSlot: 150,
+		PageSize: 2,
+		Progress: func(p backfill.Progress) { updates = append(updates, p) },
+	}, func(notification *chainstream.TransactionNotification) error {
+		delivered = append(delivered, notification.Signature())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	expected := []string{"sig-2", "sig-3", "sig-4"}
+	if len(delivered) != len(expected) {
+		t.Fatalf("delivered %v, expected %v", delivered, expected)
+	}
+	for i := range expected {
+		if delivered[i] != expected[i] {
+			t.Errorf("delivered[%d] = %q, expected %q", i, delivered[i], expected[i])
+		}
+	}
+
+	if len(updates) != 5 {
+		t.Fatalf("got %d progress updates, expected 5", len(updates))
+	}
+	if first := updates[0]; first.Phase != backfill.PhaseSignatures || first.SlotsRemaining != 150 {
+		t.Errorf("first update = %+v", first)
+	}
+	if last := updates[len(updates)-1]; last.Phase != backfill.PhaseTransactions || last.TransactionsFetched != 3 || last.SlotsRemaining != 0 {
+		t.Errorf("last update = %+v", last)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int               `json:"id"`
+			Params []json.RawMessage `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		switch slot := string(req.Params[0]); slot {
+		case "101":
+			resp["error"] = map[string]interface{}{"code": -32007, "message": "Slot 101 was skipped"}
+		default:
+			tx := func(sig string, keys ...string) map[string]interface{} {
+				return map[string]interface{}{
+					"transaction": map[string]interface{}{"signatures": []string{sig}, "message": map[string]interface{}{"accountKeys": keys}},
+					"meta":        map[string]interface{}{"err": nil},
+				}
+			}
+			resp["result"] = map[string]interface{}{
+				"blockhash":    "hash-" + slot,
+				"blockTime":    1700000000,
+				"transactions": []interface{}{tx("a-"+slot, "wallet", "program"), tx("b-"+slot, "other")},
+			}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	var (
+		delivered []string
+		last      backfill.Progress
+	)
+	err := backfill.Blocks(context.Background(), rpc.NewClient(srv.URL), backfill.BlocksConfig{
+		FromSlot: 100,
+		ToSlot:   102,
+		Filter:   &chainstream.AccountKeysFilter{OneOf: []string{"program"}},
+		Progress: func(p backfill.Progress) { last = p },
+	}, func(notification *chainstream.TransactionNotification) error {
+		delivered = append(delivered, notification.Signature())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Blocks() error: %v", err)
+	}
+	if len(delivered) != 2 || delivered[0] != "a-100" || delivered[1] != "a-102" {
+		t.Errorf("delivered %v, expected [a-100 a-102]", delivered)
+	}
+	if last.CurrentSlot != 102 || last.TransactionsFetched != 2 || last.SlotsRemaining != 0 {
+		t.Errorf("last progress = %+v", last)
+	}
+}
+
+func TestGapsProcessed(t *testing.T) {
+	// The cluster confirms slot 102, the end of the gap, on the second getSlot.
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int               `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		switch req.Method {
+		case "getSlot":
+			calls = append(calls, req.Method)
+			resp["result"] = 100 + len(calls)
+		case "getBlock":
+			calls = append(calls, req.Method+" "+string(req.Params[0])+" "+string(req.Params[1]))
+			resp["result"] = map[string]interface{}{"transactions": []interface{}{}}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	gap := chainstream.Gap{FromSlot: 102, ToSlot: 102, Filter: chainstream.TransactionFilter{Commitment: chainstream.CommitmentProcessed}}
+	err := backfill.Gaps(rpc.NewClient(srv.URL))(context.Background(), gap, func(*chainstream.TransactionNotification) error { return nil })
+	if err != nil {
+		t.Fatalf("Gaps() error: %v", err)
+	}
+	if len(calls) != 3 || calls[0] != "getSlot" || calls[1] != "getSlot" || !strings.HasPrefix(calls[2], "getBlock 102 ") || !strings.Contains(calls[2], `"confirmed"`) {
+		t.Errorf("calls %q, expected getBlock of slot 102 at confirmed once it is confirmed", calls)
+	}
+}
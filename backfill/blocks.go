@@ -0,0 +1,134 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/rpc"
+)
+
+// PhaseBlocks is the slot by slot scan of full blocks.
+const PhaseBlocks Phase = "blocks"
+
+// Error codes returned by getBlock for slots that have no block.
+const (
+	errCodeSlotSkipped         = -32007
+	errCodeLongTermStorageSlot = -32009
+)
+
+// BlocksConfig describes a scan of full blocks.
+type BlocksConfig struct {
+	// FromSlot and ToSlot bound the scanned slots, both inclusive.
+	FromSlot uint64
+	ToSlot   uint64
+	// Commitment used for RPC calls, defaults to confirmed.
+	Commitment chainstream.Commitment
+	// Filter, when set, delivers only the transactions matching it.
+	Filter *chainstream.AccountKeysFilter
+	// SkipFailed skips transactions that failed on-chain.
+	SkipFailed bool
+	// Progress is called after every slot.
+	Progress ProgressFunc
+}
+
+// Blocks delivers the transactions of every block between cfg.FromSlot and cfg.ToSlot in
+// order. Blocks are decoded while they are downloaded, so busy slots do not have to fit in
+// memory as a whole. Skipped slots are passed over. A non-nil error returned by do stops the scan.
+func Blocks(
+	ctx context.Context,
+	client *rpc.Client,
+	cfg BlocksConfig,
+	do func(notification *chainstream.TransactionNotification) error,
+) error {
+	if cfg.ToSlot < cfg.FromSlot {
+		return errors.New("backfill: toSlot must not be before fromSlot")
+	}
+	if cfg.Commitment == 0 {
+		cfg.Commitment = chainstream.DefaultCommitment
+	}
+
+	started := time.Now()
+	progress := Progress{Phase: PhaseBlocks}
+	for slot := cfg.FromSlot; slot <= cfg.ToSlot; slot++ {
+		var doErr error
+		_, err := client.StreamBlock(ctx, slot, cfg.Commitment, func(notification *chainstream.TransactionNotification) error {
+			if cfg.SkipFailed && !notification.Succeeded() {
+				return nil
+			}
+			if cfg.Filter != nil && !cfg.Filter.Match(notification) {
+				return nil
+			}
+			progress.TransactionsFetched++
+			doErr = do(notification)
+			return doErr
+		})
+		if doErr != nil {
+			return doErr
+		}
+		var rpcErr *rpc.Error
+		if errors.As(err, &rpcErr) && (rpcErr.Code == errCodeSlotSkipped || rpcErr.Code == errCodeLongTermStorageSlot) {
+			err = nil
+		}
+		if err != nil {
+			return fmt.Errorf("backfill: slot %d: %w", slot, err)
+		}
+
+		if cfg.Progress != nil {
+			progress.CurrentSlot = slot
+			progress.SlotsRemaining = cfg.ToSlot - slot
+			progress.Elapsed = time.Since(started)
+			progress.ETA = eta(progress.Elapsed, int(slot-cfg.FromSlot+1), int(progress.SlotsRemaining))
+			cfg.Progress(progress)
+		}
+	}
+	return nil
+}
+
+// confirmPollInterval is the interval of the getSlot calls waiting for the confirmation
+// of a gap, about a slot.
+const confirmPollInterval = 400 * time.Millisecond
+
+// Gaps returns a chainstream backfill hook loading the blocks of a gap with Blocks at the
+// commitment of the subscription. getBlock does not support processed commitment: the
+// gaps of processed subscriptions, whose last slot may not be confirmed yet, are loaded as
+// confirmed once that slot is.
+func Gaps(client *rpc.Client) chainstream.BackfillFunc {
+	return func(ctx context.Context, gap chainstream.Gap, do func(notification *chainstream.TransactionNotification) error) error {
+		commitment := gap.Filter.Commitment
+		if commitment == chainstream.CommitmentProcessed {
+			if err := awaitConfirmed(ctx, client, gap.ToSlot); err != nil {
+				return err
+			}
+		}
+		if !commitment.AtLeast(chainstream.CommitmentConfirmed) {
+			commitment = chainstream.CommitmentConfirmed
+		}
+		return Blocks(ctx, client, BlocksConfig{
+			FromSlot:   gap.FromSlot,
+			ToSlot:     gap.ToSlot,
+			Commitment: commitment,
+			Filter:     gap.Filter.AccountKeys,
+		}, do)
+	}
+}
+
+// awaitConfirmed waits until the cluster has confirmed the slot.
+func awaitConfirmed(ctx context.Context, client *rpc.Client, slot uint64) error {
+	for {
+		confirmed, err := client.GetSlot(ctx, chainstream.CommitmentConfirmed)
+		if err != nil {
+			return fmt.Errorf("backfill: %w", err)
+		}
+		if confirmed >= slot {
+			return nil
+		}
+		select {
+		case <-time.After(confirmPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
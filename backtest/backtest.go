@@ -0,0 +1,196 @@
+// Package backtest runs a strategy over a recorded stream and reports the signals it
+// emitted together with hypothetical fills at the next observed trade price.
+package backtest
+
+import (
+	"context"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/replay"
+)
+
+// Side is the direction of a signal.
+type Side string
+
+const (
+	Buy  Side = "buy"
+	Sell Side = "sell"
+)
+
+// Signal is a decision emitted by a strategy.
+type Signal struct {
+	Name string `json:"name,omitempty"`
+	Mint string `json:"mint"`
+	Side Side   `json:"side"`
+	// Amount is the size in UI units of the mint.
+	Amount float64 `json:"amount"`
+	// Slot, Time and Signature identify the notification the signal was emitted on.
+	Slot      uint64    `json:"slot"`
+	Time      time.Time `json:"time"`
+	Signature string    `json:"signature"`
+}
+
+// Fill is a signal executed at the price of the next trade of its mint.
+type Fill struct {
+	Signal
+	// Price is in SOL per token.
+	Price         float64   `json:"price"`
+	FillSlot      uint64    `json:"fillSlot"`
+	FillTime      time.Time `json:"fillTime"`
+	FillSignature string    `json:"fillSignature"`
+}
+
+// MintSummary aggregates the fills of a mint.
+type MintSummary struct {
+	Bought   float64 `json:"bought"`
+	Sold     float64 `json:"sold"`
+	Cost     float64 `json:"cost"`
+	Proceeds float64 `json:"proceeds"`
+	// LastPrice is the last observed trade price in SOL per token.
+	LastPrice float64 `json:"lastPrice"`
+	// PnL is proceeds minus cost plus the remaining position valued at LastPrice, in SOL.
+	PnL float64 `json:"pnl"`
+}
+
+// Report is the outcome of a backtest.
+type Report struct {
+	Notifications int                     `json:"notifications"`
+	Signals       []Signal                `json:"signals"`
+	Fills         []Fill                  `json:"fills"`
+	Unfilled      []Signal                `json:"unfilled"`
+	Mints         map[string]*MintSummary `json:"mints"`
+}
+
+// Recorder collects the signals of a strategy for the current notification.
+type Recorder struct {
+	notification *chainstream.TransactionNotification
+	time         time.Time
+	previous     []Signal
+	emitted      []Signal
+}
+
+// Emit records a signal, stamping it with the slot, time and signature of the current notification.
+func (r *Recorder) Emit(signal Signal) {
+	signal.Slot = r.notification.Slot()
+	signal.Time = r.time
+	signal.Signature = r.notification.Signature()
+	r.emitted = append(r.emitted, signal)
+}
+
+// Signals returns the signals emitted so far in the backtest, including the current notification.
+func (r *Recorder) Signals() []Signal {
+	return append(r.previous[:len(r.previous):len(r.previous)], r.emitted...)
+}
+
+// Strategy inspects a notification and emits signals through the recorder.
+type Strategy func(notification *chainstream.TransactionNotification, recorder *Recorder) error
+
+// Run replays the recording through the strategy and fills every signal at the price of the
+// first trade of its mint against SOL in a later notification.
+func Run(ctx context.Context, r io.Reader, cfg replay.Config, strategy Strategy) (*Report, error) {
+	report := &Report{Mints: make(map[string]*MintSummary)}
+	pending := make(map[string][]Signal)
+
+	err := replay.Run(ctx, r, cfg, func(notification *chainstream.TransactionNotification) error {
+		report.Notifications++
+		ts, _ := notification.Time(cfg.AgeSource)
+
+		for mint, price := range tradePrices(notification) {
+			summary := report.mint(mint)
+			summary.LastPrice = price
+			for _, signal := range pending[mint] {
+				report.fill(Fill{Signal: signal, Price: price, FillSlot: notification.Slot(), FillTime: ts, FillSignature: notification.Signature()})
+			}
+			delete(pending, mint)
+		}
+
+		recorder := &Recorder{notification: notification, time: ts, previous: report.Signals}
+		if err := strategy(notification, recorder); err != nil {
+			return err
+		}
+		for _, signal := range recorder.emitted {
+			report.Signals = append(report.Signals, signal)
+			pending[signal.Mint] = append(pending[signal.Mint], signal)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, signals := range pending {
+		report.Unfilled = append(report.Unfilled, signals...)
+	}
+	sort.Slice(report.Unfilled, func(i, j int) bool { return report.Unfilled[i].Slot < report.Unfilled[j].Slot })
+	for _, summary := range report.Mints {
+		summary.PnL = summary.Proceeds - summary.Cost + (summary.Bought-summary.Sold)*summary.LastPrice
+	}
+	return report, nil
+}
+
+func (r *Report) mint(mint string) *MintSummary {
+	summary, ok := r.Mints[mint]
+	if !ok {
+		summary = &MintSummary{}
+		r.Mints[mint] = summary
+	}
+	return summary
+}
+
+func (r *Report) fill(fill Fill) {
+	r.Fills = append(r.Fills, fill)
+	summary := r.mint(fill.Mint)
+	switch fill.Side {
+	case Buy:
+		summary.Bought += fill.Amount
+		summary.Cost += fill.Amount * fill.Price
+	case Sell:
+		summary.Sold += fill.Amount
+		summary.Proceeds += fill.Amount * fill.Price
+	}
+}
+
+// tradePrices returns the SOL price of every mint traded against SOL in the notification,
+// taken from the last such swap route. Mints without a route, e.g. sold to a bonding curve
+// that pays out lamports directly, are priced from the balance changes of the fee payer.
+func tradePrices(notification *chainstream.TransactionNotification) map[string]float64 {
+	prices := make(map[string]float64)
+	for _, route := range notification.SwapRoutes() {
+		price := route.Price()
+		if price == 0 {
+			continue
+		}
+		switch {
+		case route.InputMint() == chainstream.WrappedSOLMint && route.OutputMint() != chainstream.WrappedSOLMint:
+			prices[route.OutputMint()] = price
+		case route.OutputMint() == chainstream.WrappedSOLMint && route.InputMint() != chainstream.WrappedSOLMint:
+			prices[route.InputMint()] = 1 / price
+		}
+	}
+	if !notification.Succeeded() {
+		return prices
+	}
+
+	payer := notification.Owner()
+	var lamports int64
+	for _, change := range notification.BalanceChanges() {
+		if change.Account == payer {
+			lamports = change.Delta + int64(notification.Params.Result.Value.Meta.Fee)
+		}
+	}
+	for _, change := range notification.TokenBalanceChanges() {
+		if change.Owner != payer || change.Mint == chainstream.WrappedSOLMint || change.Delta == 0 {
+			continue
+		}
+		if _, ok := prices[change.Mint]; ok || (lamports < 0) == (change.Delta < 0) {
+			continue
+		}
+		tokens := math.Abs(float64(change.Delta)) / math.Pow10(change.Decimals)
+		prices[change.Mint] = math.Abs(float64(lamports)) / chainstream.LamportsPerSOL / tokens
+	}
+	return prices
+}
@@ -0,0 +1,85 @@
+package backtest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/backtest"
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/replay"
+)
+
+const mint = "DNvtizsEYyknJoW3QYwDA7ncjxri3KBBTeLydEZCpump"
+
+// recording joins notification files into a JSON lines recording.
+func recording(t *testing.T, files ...string) *bytes.Buffer {
+	t.Helper()
+	var b bytes.Buffer
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = json.Compact(&b, data); err != nil {
+			t.Fatal(err)
+		}
+		b.WriteByte('\n')
+	}
+	return &b
+}
+
+func TestRun(t *testing.T) {
+	input := recording(t, "../chainstream/testdata/sample_tx_sell.json", "../chainstream/testdata/sample_tx_buy.json")
+
+	// Buy on the first notification and sell on the second one.
+	report, err := backtest.Run(context.Background(), input, replay.Config{Speed: replay.AsFastAsPossible},
+		func(n *chainstream.TransactionNotification, r *backtest.Recorder) error {
+			side := backtest.Buy
+			if len(r.Signals()) > 0 {
+				side = backtest.Sell
+			}
+			r.Emit(backtest.Signal{Name: "follow", Mint: mint, Side: side, Amount: 1000})
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if report.Notifications != 2 || len(report.Signals) != 2 {
+		t.Fatalf("report = %+v, expected 2 notifications and 2 signals", report)
+	}
+	if len(report.Fills) != 1 || len(report.Unfilled) != 1 || report.Unfilled[0].Side != backtest.Sell {
+		t.Fatalf("fills = %+v, unfilled = %+v, expected the buy filled by the next trade", report.Fills, report.Unfilled)
+	}
+
+	fill := report.Fills[0]
+	if fill.Side != backtest.Buy || fill.FillSignature == fill.Signature {
+		t.Errorf("fill %+v, expected the buy filled on the next notification", fill)
+	}
+	// The buy sample swaps 0.01 SOL for 357547.484136 tokens.
+	if expected := 0.01 / 357547.484136; math.Abs(fill.Price-expected) > 1e-15 {
+		t.Errorf("fill price %v, expected %v", fill.Price, expected)
+	}
+	summary := report.Mints[mint]
+	if summary == nil || summary.Bought != 1000 || summary.LastPrice != fill.Price {
+		t.Errorf("summary = %+v", summary)
+	}
+	t.Logf("📈 filled at %.12f SOL, pnl %.9f SOL", fill.Price, summary.PnL)
+}
+
+func TestTradePriceFromBalances(t *testing.T) {
+	input := recording(t, "../chainstream/testdata/sample_tx_sell.json")
+	report, err := backtest.Run(context.Background(), input, replay.Config{}, func(*chainstream.TransactionNotification, *backtest.Recorder) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if summary := report.Mints[mint]; summary == nil || summary.LastPrice <= 0 {
+		t.Errorf("summary = %+v, expected a price from the seller balances", summary)
+	}
+}
@@ -0,0 +1,138 @@
+package chainstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// maxPendingChanges bounds the changes of AccountState held while a snapshot loads.
+const maxPendingChanges = 1024
+
+// errNotDelivered is returned by handlers of notifications that did not pass them to the
+// caller, e.g. held or dropped as stale, so that they are not counted as delivered.
+var errNotDelivered = errors.New("chainstream: notification not delivered")
+
+// AccountSnapshot loads the current state of an account, e.g. with
+// rpc.Client.AccountSnapshot. It returns nil when the account does not exist.
+type AccountSnapshot func(ctx context.Context) (*AccountNotification, error)
+
+// Snapshot reports whether the state was loaded by the AccountSnapshot of AccountState
+// rather than streamed.
+func (a *AccountNotification) Snapshot() bool {
+	return a.snapshot
+}
+
+// AccountState delivers the state of an account followed by its changes. Every time the
+// accountSubscribe subscription is established, after a reconnect as well, the state
+// loaded by snapshot is passed to do, then the changes streamed meanwhile and the later
+// ones. Changes of a slot before the last state passed to do are dropped as stale, and the
+// same state is passed once, so do sees the account move forward only. As every change
+// carries the whole state, at most 1024 changes are held while a snapshot loads, the
+// oldest being dropped as stale. do is not called concurrently. A snapshot error ends the
+// subscription and is returned.
+func (c *C) AccountState(
+	ctx context.Context,
+	request *JSONRPCRequest,
+	snapshot AccountSnapshot,
+	do func(account *AccountNotification),
+	opts ...SubscribeOption,
+) error {
+	// Changes are held from the start, so that the first snapshot is passed first.
+	state := &accountState{c: c, snapshot: snapshot, do: do, loading: true}
+	opts = append(opts, func(o *subscribeOptions) { o.connected = state.load })
+	return c.notifications(ctx, request, "account", opts, func(event sessionEvent) (uint64, string) {
+		return event.account.Slot(), event.account.key()
+	}, func(event sessionEvent) error {
+		return state.change(event.account)
+	})
+}
+
+// accountState orders the snapshots and changes of AccountState.
+type accountState struct {
+	c        *C
+	snapshot AccountSnapshot
+	do       func(account *AccountNotification)
+
+	mu sync.Mutex
+	// generation counts the snapshots started, so that only the last one is delivered.
+	generation int
+	// loading is set while a snapshot loads, and pending holds the changes received
+	// meanwhile.
+	loading bool
+	pending []*AccountNotification
+	// last is the state passed to do last, empty before the first one, and lastSlot its
+	// slot.
+	last     string
+	lastSlot uint64
+}
+
+// load loads and delivers a snapshot, then the changes received meanwhile.
+func (s *accountState) load(ctx context.Context) error {
+	s.mu.Lock()
+	s.generation++
+	generation := s.generation
+	s.loading = true
+	s.mu.Unlock()
+
+	account, err := s.snapshot(ctx)
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("chainstream: cannot load account snapshot: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if generation != s.generation {
+		return nil
+	}
+	s.loading = false
+	pending := s.pending
+	s.pending = nil
+	if account != nil {
+		account.snapshot = true
+		pending = append([]*AccountNotification{account}, pending...)
+	}
+	for _, account := range pending {
+		switch err := s.deliver(account); {
+		case err == nil:
+			s.c.stats.delivered.Add(1)
+		case errors.Is(err, errNotDelivered):
+		case s.c.panicked(err):
+			s.c.stats.failed.Add(1)
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// change delivers a streamed change, or holds it while a snapshot loads.
+func (s *accountState) change(account *AccountNotification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loading {
+		if len(s.pending) == maxPendingChanges {
+			s.c.stats.stale.Add(1)
+			s.pending = append(s.pending[:0], s.pending[1:]...)
+		}
+		s.pending = append(s.pending, account)
+		return errNotDelivered
+	}
+	return s.deliver(account)
+}
+
+// deliver passes the state to do unless it is older than or the same as the last one,
+// returning errNotDelivered then.
+func (s *accountState) deliver(account *AccountNotification) error {
+	key := account.Params.Result.Value.stateKey()
+	if s.last != "" && (account.Slot() < s.lastSlot || key == s.last) {
+		s.c.stats.stale.Add(1)
+		return errNotDelivered
+	}
+	s.last, s.lastSlot = key, account.Slot()
+	return s.c.dispatch(account.Slot(), func() { s.do(account) })
+}
@@ -0,0 +1,135 @@
+package chainstream_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestAccountState(t *testing.T) {
+	sent := make(chan struct{})
+	srv := newFakeServer(t, func(ctx context.Context, conn *websocket.Conn, _ int) error {
+		for _, message := range []string{
+			accountNotification(10, 1000, `["","base64"]`),
+			accountNotification(12, 800, `["","base64"]`),
+		} {
+			if err := conn.Write(ctx, websocket.MessageText, []byte(message)); err != nil {
+				return err
+			}
+		}
+		close(sent)
+		<-ctx.Done()
+		return nil
+	})
+	client := chainstream.NewClient(chainstream.NewConfig(srv.endpoint()))
+	request, err := chainstream.NewAccountSubscribeRequest(chainstream.AccountSubscribeParams{Account: watchedAccount})
+	if err != nil {
+		t.Fatalf("NewAccountSubscribeRequest() error: %v", err)
+	}
+	snapshot := func(ctx context.Context) (*chainstream.AccountNotification, error) {
+		<-sent
+		var account chainstream.AccountNotification
+		err := json.Unmarshal([]byte(accountNotification(11, 900, `["","base64"]`)), &account)
+		return &account, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var states []string
+	err = client.AccountState(ctx, request, snapshot, func(a *chainstream.AccountNotification) {
+		states = append(states, strconv.FormatUint(a.Slot(), 10)+"/"+strconv.FormatBool(a.Snapshot()))
+		if a.Slot() == 12 {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("AccountState() error: %v", err)
+	}
+
+	expected := []string{"11/true", "12/false"}
+	if !reflect.DeepEqual(states, expected) {
+		t.Errorf("states %q, expected %q", states, expected)
+	}
+	if stats := client.Stats(); stats.Stale != 1 || stats.Delivered != 2 {
+		t.Errorf("Stale = %d and Delivered = %d, expected the change of slot 10 stale and the others delivered", stats.Stale, stats.Delivered)
+	}
+}
+
+func TestAccountStatePendingBound(t *testing.T) {
+	const changes = 1100
+	srv := newFakeServer(t, func(ctx context.Context, conn *websocket.Conn, _ int) error {
+		for slot := uint64(1); slot <= changes; slot++ {
+			if err := conn.Write(ctx, websocket.MessageText, []byte(accountNotification(slot, 1000+slot, `["","base64"]`))); err != nil {
+				return err
+			}
+		}
+		<-ctx.Done()
+		return nil
+	})
+	client := chainstream.NewClient(chainstream.NewConfig(srv.endpoint()))
+	request, err := chainstream.NewAccountSubscribeRequest(chainstream.AccountSubscribeParams{Account: watchedAccount})
+	if err != nil {
+		t.Fatalf("NewAccountSubscribeRequest() error: %v", err)
+	}
+	// The snapshot loads until the oldest changes held have been dropped.
+	snapshot := func(ctx context.Context) (*chainstream.AccountNotification, error) {
+		for client.Stats().Stale < changes-1024 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Millisecond):
+			}
+		}
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var slots []uint64
+	err = client.AccountState(ctx, request, snapshot, func(a *chainstream.AccountNotification) {
+		slots = append(slots, a.Slot())
+		if a.Slot() == changes {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("AccountState() error: %v", err)
+	}
+
+	if len(slots) != 1024 || slots[0] != changes-1023 {
+		t.Errorf("delivered %d changes, expected the last 1024 from slot %d", len(slots), changes-1023)
+	}
+	if stats := client.Stats(); stats.Stale != changes-1024 || stats.Delivered != 1024 {
+		t.Errorf("Stale = %d and Delivered = %d, expected %d and 1024", stats.Stale, stats.Delivered, changes-1024)
+	}
+}
+
+func TestAccountStateSnapshotError(t *testing.T) {
+	srv := newFakeServer(t, func(ctx context.Context, _ *websocket.Conn, _ int) error {
+		<-ctx.Done()
+		return nil
+	})
+	client := chainstream.NewClient(chainstream.NewConfig(srv.endpoint()))
+	request, err := chainstream.NewAccountSubscribeRequest(chainstream.AccountSubscribeParams{Account: watchedAccount})
+	if err != nil {
+		t.Fatalf("NewAccountSubscribeRequest() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	unavailable := errors.New("rpc unavailable")
+	err = client.AccountState(ctx, request, func(context.Context) (*chainstream.AccountNotification, error) {
+		return nil, unavailable
+	}, func(*chainstream.AccountNotification) {})
+	if !errors.Is(err, unavailable) {
+		t.Errorf("AccountState() error = %v, expected the snapshot error", err)
+	}
+}
@@ -0,0 +1,255 @@
+package chainstream
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/mr-tron/base58"
+)
+
+// AccountEncoding is the encoding of account data in notifications of the standard
+// Solana WebSocket API.
+type AccountEncoding string
+
+// Account data encodings.
+const (
+	EncodingBase58     AccountEncoding = "base58"
+	EncodingBase64     AccountEncoding = "base64"
+	EncodingBase64Zstd AccountEncoding = "base64+zstd"
+	// EncodingJSONParsed asks for the data of accounts of known programs, such as token
+	// accounts, parsed to JSON. Other accounts are sent in base64.
+	EncodingJSONParsed AccountEncoding = "jsonParsed"
+)
+
+// DefaultAccountEncoding is the encoding of AccountSubscribeParams without one.
+const DefaultAccountEncoding = EncodingBase64
+
+// Validate returns an error if the encoding is not one of the account data encodings.
+func (e AccountEncoding) Validate() error {
+	switch e {
+	case EncodingBase58, EncodingBase64, EncodingBase64Zstd, EncodingJSONParsed:
+		return nil
+	}
+	return fmt.Errorf("unknown account encoding %q", string(e))
+}
+
+// AccountSubscribeParams are the params of accountSubscribe, a method of the standard
+// Solana WebSocket API rather than of ChainStream. They are sent as the positional params
+// [account, {encoding, commitment}].
+type AccountSubscribeParams struct {
+	Account    string
+	Encoding   AccountEncoding
+	Commitment Commitment
+}
+
+type accountSubscribeOptions struct {
+	Encoding   AccountEncoding `json:"encoding"`
+	Commitment Commitment      `json:"commitment"`
+}
+
+// MarshalJSON encodes the params as positional params, with the defaults of unset fields.
+func (p AccountSubscribeParams) MarshalJSON() ([]byte, error) {
+	encoding := p.Encoding
+	if encoding == "" {
+		encoding = DefaultAccountEncoding
+	}
+	return json.Marshal([]interface{}{p.Account, accountSubscribeOptions{Encoding: encoding, Commitment: p.Commitment.orDefault()}})
+}
+
+// UnmarshalJSON decodes positional params.
+func (p *AccountSubscribeParams) UnmarshalJSON(data []byte) error {
+	var params []json.RawMessage
+	if err := json.Unmarshal(data, &params); err != nil {
+		return err
+	}
+	if len(params) == 0 || len(params) > 2 {
+		return fmt.Errorf("accountSubscribe takes 1 or 2 params, got %d", len(params))
+	}
+	var decoded AccountSubscribeParams
+	if err := json.Unmarshal(params[0], &decoded.Account); err != nil {
+		return fmt.Errorf("account: %w", err)
+	}
+	if len(params) == 2 {
+		var options accountSubscribeOptions
+		if err := json.Unmarshal(params[1], &options); err != nil {
+			return fmt.Errorf("options: %w", err)
+		}
+		decoded.Encoding, decoded.Commitment = options.Encoding, options.Commitment
+	}
+	*p = decoded
+	return nil
+}
+
+// Validate checks the params before they are sent: the account must be a valid key, and
+// the encoding and commitment known when set.
+func (p *AccountSubscribeParams) Validate() error {
+	var errs []error
+	if _, err := NormalizeKey(p.Account); err != nil {
+		errs = append(errs, fmt.Errorf("account: %w", err))
+	}
+	if p.Encoding != "" {
+		if err := p.Encoding.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if p.Commitment != 0 {
+		if err := p.Commitment.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewAccountSubscribeRequest returns an accountSubscribe request for the account. Unset
+// encoding and commitment use DefaultAccountEncoding and DefaultCommitment. It returns an
+// error if the params are invalid, see AccountSubscribeParams.Validate.
+func NewAccountSubscribeRequest(params AccountSubscribeParams) (*JSONRPCRequest, error) {
+	if params.Encoding == "" {
+		params.Encoding = DefaultAccountEncoding
+	}
+	params.Commitment = params.Commitment.orDefault()
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	return newRequest("accountSubscribe", params), nil
+}
+
+// AccountNotification is a change of an account, sent by accountSubscribe.
+type AccountNotification struct {
+	JSONRPC string                    `json:"jsonrpc"`
+	Method  string                    `json:"method"`
+	Params  AccountNotificationParams `json:"params"`
+
+	snapshot bool
+}
+
+// Slot returns the slot of the change.
+func (a *AccountNotification) Slot() uint64 {
+	return a.Params.Result.Context.Slot
+}
+
+// AccountNotificationParams contains subscription ID and payload.
+type AccountNotificationParams struct {
+	Subscription int64                   `json:"subscription"`
+	Result       AccountNotificationData `json:"result"`
+}
+
+// AccountNotificationData holds the context and the account.
+type AccountNotificationData struct {
+	Context ContextMetadata `json:"context"`
+	Value   AccountValue    `json:"value"`
+}
+
+// AccountValue is the state of an account.
+type AccountValue struct {
+	Lamports   uint64      `json:"lamports"`
+	Owner      string      `json:"owner"`
+	Data       AccountData `json:"data"`
+	Executable bool        `json:"executable"`
+	RentEpoch  uint64      `json:"rentEpoch"`
+	Space      uint64      `json:"space"`
+}
+
+// AccountData is the data of an account in the encoding of the subscription. Data in
+// binary encodings is held in Encoded, data parsed by the node in Parsed.
+type AccountData struct {
+	Encoding AccountEncoding
+	// Encoded is the data in Encoding, for the binary encodings.
+	Encoded string
+	// Parsed is the data parsed to JSON, for EncodingJSONParsed, e.g.
+	// {"program": "spl-token", "parsed": {...}, "space": 165}.
+	Parsed json.RawMessage
+}
+
+// MarshalJSON encodes the data like the node: [data, encoding] for binary encodings and
+// the parsed object for EncodingJSONParsed.
+func (d AccountData) MarshalJSON() ([]byte, error) {
+	if d.Parsed != nil {
+		return d.Parsed, nil
+	}
+	if d.Encoding == "" {
+		return []byte("null"), nil
+	}
+	return json.Marshal([]string{d.Encoded, string(d.Encoding)})
+}
+
+// UnmarshalJSON decodes [data, encoding] pairs, parsed objects and the bare base58 strings
+// of the legacy binary encoding.
+func (d *AccountData) UnmarshalJSON(data []byte) error {
+	var pair []string
+	var encoded string
+	switch {
+	case string(data) == "null":
+		*d = AccountData{}
+	case json.Unmarshal(data, &pair) == nil:
+		if len(pair) != 2 {
+			return fmt.Errorf("account data has %d elements, expected data and encoding", len(pair))
+		}
+		*d = AccountData{Encoding: AccountEncoding(pair[1]), Encoded: pair[0]}
+	case json.Unmarshal(data, &encoded) == nil:
+		*d = AccountData{Encoding: EncodingBase58, Encoded: encoded}
+	default:
+		*d = AccountData{Encoding: EncodingJSONParsed, Parsed: append(json.RawMessage(nil), data...)}
+	}
+	return nil
+}
+
+// Bytes decodes the data of binary encodings. Data in EncodingBase64Zstd is returned
+// still compressed with zstd. Parsed data is an error.
+func (d *AccountData) Bytes() ([]byte, error) {
+	switch d.Encoding {
+	case EncodingBase58:
+		return base58.Decode(d.Encoded)
+	case EncodingBase64, EncodingBase64Zstd:
+		return base64.StdEncoding.DecodeString(d.Encoded)
+	case EncodingJSONParsed:
+		return nil, errors.New("account data is parsed, subscribe with a binary encoding")
+	}
+	return nil, fmt.Errorf("unknown account encoding %q", string(d.Encoding))
+}
+
+// key tells changes apart: a change delivered twice has the same slot and state.
+func (a *AccountNotification) key() string {
+	return strconv.FormatUint(a.Slot(), 10) + "/" + a.Params.Result.Value.stateKey()
+}
+
+// stateKey identifies the state of an account.
+func (v *AccountValue) stateKey() string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(v.Data.Encoded))
+	_, _ = h.Write(v.Data.Parsed)
+	return strconv.FormatUint(v.Lamports, 10) + "/" + v.Owner + "/" + strconv.FormatUint(h.Sum64(), 16)
+}
+
+// decodeAccount is the session decoder of accountSubscribe subscriptions.
+func decodeAccount(data []byte, event *sessionEvent) error {
+	var account AccountNotification
+	if err := json.Unmarshal(data, &account); err != nil {
+		return fmt.Errorf("failed to decode account notification: %w", err)
+	}
+	event.account = &account
+	return nil
+}
+
+// AccountNotifications subscribes to the changes of an account with accountSubscribe, see
+// NewAccountSubscribeRequest. It is a method of the standard Solana WebSocket API, so the
+// endpoints of the client must be Solana RPC WebSocket endpoints rather than ChainStream
+// ones. Changes are received over scored endpoints with pings and reconnects, and a change
+// delivered twice is passed to do once. Panics in do are recovered, see Hooks.Panic.
+func (c *C) AccountNotifications(
+	ctx context.Context,
+	request *JSONRPCRequest,
+	do func(account *AccountNotification),
+	opts ...SubscribeOption,
+) error {
+	return c.notifications(ctx, request, "account", opts, func(event sessionEvent) (uint64, string) {
+		return event.account.Slot(), event.account.key()
+	}, func(event sessionEvent) error {
+		return c.dispatch(event.account.Slot(), func() { do(event.account) })
+	})
+}
@@ -0,0 +1,141 @@
+package chainstream_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+const (
+	watchedAccount = "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF"
+	systemProgram  = "11111111111111111111111111111111"
+)
+
+// accountNotification is an accountNotification of the standard Solana WebSocket API.
+func accountNotification(slot uint64, lamports uint64, data string) string {
+	return `{"jsonrpc":"2.0","method":"accountNotification","params":{"result":{"context":{"slot":` +
+		jsonNumber(slot) + `},"value":{"data":` + data + `,"executable":false,"lamports":` + jsonNumber(lamports) +
+		`,"owner":"` + systemProgram + `","rentEpoch":18446744073709551615,"space":0}},"subscription":1001}}`
+}
+
+func jsonNumber(n uint64) string {
+	data, _ := json.Marshal(n)
+	return string(data)
+}
+
+func TestAccountNotifications(t *testing.T) {
+	srv := newFakeServer(t, func(ctx context.Context, conn *websocket.Conn, _ int) error {
+		for _, message := range []string{
+			accountNotification(10, 1000, `["","base64"]`),
+			accountNotification(10, 1000, `["","base64"]`),
+			accountNotification(11, 900, `["AQI=","base64"]`),
+		} {
+			if err := conn.Write(ctx, websocket.MessageText, []byte(message)); err != nil {
+				return err
+			}
+		}
+		<-ctx.Done()
+		return nil
+	})
+	client := chainstream.NewClient(chainstream.NewConfig(srv.endpoint()))
+	request, err := chainstream.NewAccountSubscribeRequest(chainstream.AccountSubscribeParams{Account: watchedAccount})
+	if err != nil {
+		t.Fatalf("NewAccountSubscribeRequest() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var accounts []*chainstream.AccountNotification
+	err = client.AccountNotifications(ctx, request, func(a *chainstream.AccountNotification) {
+		accounts = append(accounts, a)
+		if a.Slot() == 11 {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("AccountNotifications() error: %v", err)
+	}
+
+	if len(accounts) != 2 || accounts[0].Slot() != 10 || accounts[1].Slot() != 11 {
+		t.Fatalf("received %d changes, expected slots 10 and 11 once", len(accounts))
+	}
+	value := accounts[1].Params.Result.Value
+	if value.Lamports != 900 || value.Owner != systemProgram || value.RentEpoch != 18446744073709551615 {
+		t.Errorf("account = %+v", value)
+	}
+	if data, err := value.Data.Bytes(); err != nil || !reflect.DeepEqual(data, []byte{1, 2}) {
+		t.Errorf("Bytes() = %v, %v", data, err)
+	}
+
+	sent := srv.request(1)
+	if sent.Method != "accountSubscribe" {
+		t.Errorf("subscribe method = %q", sent.Method)
+	}
+	expected := []interface{}{watchedAccount, map[string]interface{}{"encoding": "base64", "commitment": "confirmed"}}
+	if !reflect.DeepEqual(sent.Params, expected) {
+		t.Errorf("subscribe params = %v, expected %v", sent.Params, expected)
+	}
+}
+
+func TestAccountData(t *testing.T) {
+	tests := []struct {
+		name, json string
+		expected   chainstream.AccountData
+	}{
+		{"base58", `["ZiCa","base58"]`, chainstream.AccountData{Encoding: chainstream.EncodingBase58, Encoded: "ZiCa"}},
+		{"legacy binary", `"ZiCa"`, chainstream.AccountData{Encoding: chainstream.EncodingBase58, Encoded: "ZiCa"}},
+		{"parsed", `{"program":"spl-token","space":165}`, chainstream.AccountData{Encoding: chainstream.EncodingJSONParsed, Parsed: json.RawMessage(`{"program":"spl-token","space":165}`)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data chainstream.AccountData
+			if err := json.Unmarshal([]byte(tt.json), &data); err != nil {
+				t.Fatalf("Unmarshal() error: %v", err)
+			}
+			if !reflect.DeepEqual(data, tt.expected) {
+				t.Errorf("data = %+v, expected %+v", data, tt.expected)
+			}
+			encoded, err := json.Marshal(data)
+			if err != nil {
+				t.Fatalf("Marshal() error: %v", err)
+			}
+			var again chainstream.AccountData
+			if err := json.Unmarshal(encoded, &again); err != nil || !reflect.DeepEqual(again, tt.expected) {
+				t.Errorf("round trip = %+v, %v", again, err)
+			}
+		})
+	}
+
+	base58 := chainstream.AccountData{Encoding: chainstream.EncodingBase58, Encoded: "ZiCa"}
+	if data, err := base58.Bytes(); err != nil || string(data) != "abc" {
+		t.Errorf("Bytes() = %q, %v", data, err)
+	}
+	if _, err := tests[2].expected.Bytes(); err == nil {
+		t.Error("Bytes() of parsed data succeeded")
+	}
+}
+
+func TestNewAccountSubscribeRequestInvalid(t *testing.T) {
+	for _, params := range []chainstream.AccountSubscribeParams{
+		{Account: "not a key"},
+		{Account: watchedAccount, Encoding: "hex"},
+	} {
+		if _, err := chainstream.NewAccountSubscribeRequest(params); err == nil {
+			t.Errorf("NewAccountSubscribeRequest(%+v) succeeded", params)
+		}
+	}
+
+	client := chainstream.NewClient(chainstream.NewConfig("wss://example.com"))
+	request := &chainstream.JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "accountSubscribe", Params: chainstream.AccountSubscribeParams{Account: "not a key"}}
+	err := client.AccountNotifications(context.Background(), request, func(*chainstream.AccountNotification) {})
+	if !errors.Is(err, chainstream.ErrInvalidParams) {
+		t.Errorf("AccountNotifications() error = %v, expected ErrInvalidParams", err)
+	}
+}
@@ -0,0 +1,111 @@
+package chainstream
+
+// InstructionAttribution is the part of the balance changes of a transaction caused by
+// a top-level instruction and the inner instructions it invoked.
+type InstructionAttribution struct {
+	Index     int    `json:"index"`
+	ProgramID string `json:"programId"`
+	// SOL maps accounts to lamport deltas.
+	SOL map[string]int64 `json:"sol,omitempty"`
+	// Tokens maps token accounts to deltas in base units.
+	Tokens map[string]int64 `json:"tokens,omitempty"`
+}
+
+// BalanceAttribution splits the balance changes of a transaction between its instructions.
+type BalanceAttribution struct {
+	Instructions []InstructionAttribution `json:"instructions"`
+	// Fee is the transaction fee paid by the first account.
+	Fee uint64 `json:"fee"`
+	// UnattributedSOL and UnattributedTokens hold the parts of the balance changes that are
+	// not explained by decoded transfers, e.g. lamports moved directly by a program.
+	UnattributedSOL    map[string]int64 `json:"unattributedSol,omitempty"`
+	UnattributedTokens map[string]int64 `json:"unattributedTokens,omitempty"`
+}
+
+// AttributeBalances attributes the balance deltas of the transaction to its top-level
+// instructions using the transfers decoded from them and their inner instructions.
+// Failed transactions only pay the fee, so nothing is attributed to their instructions.
+func (t *TransactionNotification) AttributeBalances() *BalanceAttribution {
+	meta := &t.Params.Result.Value.Meta
+	attribution := &BalanceAttribution{Fee: meta.Fee}
+
+	instructions := t.Params.Result.Value.Transaction.Message.Instructions
+	keys := t.AccountKeys()
+	attribution.Instructions = make([]InstructionAttribution, len(instructions))
+	for i, compiled := range instructions {
+		attribution.Instructions[i] = InstructionAttribution{
+			Index:     i,
+			ProgramID: keyAt(keys, compiled.ProgramIDIndex),
+		}
+	}
+
+	explainedSOL := make(map[string]int64)
+	explainedTokens := make(map[string]int64)
+	if t.Succeeded() {
+		for _, transfer := range t.Transfers() {
+			if transfer.Index < 0 || transfer.Index >= len(attribution.Instructions) {
+				continue
+			}
+			ia := &attribution.Instructions[transfer.Index]
+			deltas, explained := &ia.SOL, explainedSOL
+			if transfer.Kind != TransferSOL {
+				deltas, explained = &ia.Tokens, explainedTokens
+			}
+			if *deltas == nil {
+				*deltas = make(map[string]int64)
+			}
+			if transfer.From != "" {
+				(*deltas)[transfer.From] -= int64(transfer.Amount)
+				explained[transfer.From] -= int64(transfer.Amount)
+			}
+			if transfer.To != "" {
+				(*deltas)[transfer.To] += int64(transfer.Amount)
+				explained[transfer.To] += int64(transfer.Amount)
+			}
+		}
+	}
+	if payer := t.Owner(); payer != "" {
+		explainedSOL[payer] -= int64(meta.Fee)
+	}
+
+	actualSOL := make(map[string]int64)
+	for _, change := range t.BalanceChanges() {
+		actualSOL[change.Account] = change.Delta
+	}
+	actualTokens := make(map[string]int64)
+	for _, change := range t.TokenBalanceChanges() {
+		actualTokens[change.Account] = change.Delta
+	}
+	attribution.UnattributedSOL = residual(actualSOL, explainedSOL)
+	attribution.UnattributedTokens = residual(actualTokens, explainedTokens)
+	return attribution
+}
+
+// Succeeded reports whether the transaction was executed without an error.
+func (t *TransactionNotification) Succeeded() bool {
+	err := t.Params.Result.Value.Meta.Err
+	return len(err) == 0 || string(err) == "null"
+}
+
+// residual returns the non-zero differences between actual and explained deltas.
+func residual(actual, explained map[string]int64) map[string]int64 {
+	var rest map[string]int64
+	add := func(account string, delta int64) {
+		if delta == 0 {
+			return
+		}
+		if rest == nil {
+			rest = make(map[string]int64)
+		}
+		rest[account] = delta
+	}
+	for account, delta := range actual {
+		add(account, delta-explained[account])
+	}
+	for account, delta := range explained {
+		if _, ok := actual[account]; !ok {
+			add(account, -delta)
+		}
+	}
+	return rest
+}
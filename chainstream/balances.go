@@ -0,0 +1,102 @@
+package chainstream
+
+import (
+	"sort"
+	"strconv"
+)
+
+// LamportsPerSOL is the number of lamports in one SOL.
+const LamportsPerSOL = 1_000_000_000
+
+// BalanceChange describes how the SOL balance of an account changed in a transaction.
+type BalanceChange struct {
+	Account string `json:"account"`
+	Pre     uint64 `json:"pre"`
+	Post    uint64 `json:"post"`
+	Delta   int64  `json:"delta"`
+}
+
+// TokenBalanceChange describes how the token balance of a token account changed in a transaction.
+// Amounts are in base units of the mint.
+type TokenBalanceChange struct {
+	Account  string `json:"account"`
+	Mint     string `json:"mint"`
+	Owner    string `json:"owner"`
+	Decimals int    `json:"decimals"`
+	Pre      uint64 `json:"pre"`
+	Post     uint64 `json:"post"`
+	Delta    int64  `json:"delta"`
+}
+
+// BalanceChanges returns the accounts whose SOL balance changed, in account key order.
+func (t *TransactionNotification) BalanceChanges() []BalanceChange {
+	keys := t.AccountKeys()
+	meta := &t.Params.Result.Value.Meta
+
+	var changes []BalanceChange
+	for i := 0; i < len(meta.PreBalances) && i < len(meta.PostBalances); i++ {
+		pre, post := meta.PreBalances[i], meta.PostBalances[i]
+		if pre == post {
+			continue
+		}
+		changes = append(changes, BalanceChange{
+			Account: keyAt(keys, i),
+			Pre:     pre,
+			Post:    post,
+			Delta:   int64(post) - int64(pre),
+		})
+	}
+	return changes
+}
+
+// TokenBalanceChanges returns the token accounts whose balance changed, in account key order.
+// Accounts created or closed by the transaction are reported with a zero pre or post balance.
+func (t *TransactionNotification) TokenBalanceChanges() []TokenBalanceChange {
+	keys := t.AccountKeys()
+	meta := &t.Params.Result.Value.Meta
+
+	byIndex := make(map[int]*TokenBalanceChange)
+	for _, balance := range meta.PreTokenBalances {
+		change := tokenBalanceChange(byIndex, keys, balance)
+		change.Pre = parseAmount(balance.UIAmount.Amount)
+	}
+	for _, balance := range meta.PostTokenBalances {
+		change := tokenBalanceChange(byIndex, keys, balance)
+		change.Post = parseAmount(balance.UIAmount.Amount)
+	}
+
+	indexes := make([]int, 0, len(byIndex))
+	for idx, change := range byIndex {
+		if change.Pre != change.Post {
+			indexes = append(indexes, idx)
+		}
+	}
+	sort.Ints(indexes)
+
+	changes := make([]TokenBalanceChange, 0, len(indexes))
+	for _, idx := range indexes {
+		change := byIndex[idx]
+		change.Delta = int64(change.Post) - int64(change.Pre)
+		changes = append(changes, *change)
+	}
+	return changes
+}
+
+// tokenBalanceChange returns the change for the balance's account, taking mint and owner
+// from the latest snapshot seen.
+func tokenBalanceChange(byIndex map[int]*TokenBalanceChange, keys []string, balance TokenBalance) *TokenBalanceChange {
+	change, ok := byIndex[balance.AccountIndex]
+	if !ok {
+		change = &TokenBalanceChange{Account: keyAt(keys, balance.AccountIndex)}
+		byIndex[balance.AccountIndex] = change
+	}
+	change.Mint = balance.Mint
+	change.Owner = balance.Owner
+	change.Decimals = balance.UIAmount.Decimals
+	return change
+}
+
+func parseAmount(amount string) uint64 {
+	v, _ := strconv.ParseUint(amount, 10, 64)
+	return v
+}
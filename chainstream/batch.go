@@ -0,0 +1,194 @@
+package chainstream
+
+import (
+	"context"
+	"time"
+)
+
+// Defaults of BatchConfig.
+const (
+	DefaultBatchSize         = 100
+	DefaultBatchLatency      = Duration(100 * time.Millisecond)
+	DefaultBatchFlushTimeout = Duration(5 * time.Second)
+)
+
+// BatchConfig controls how HandleTransactionsBatches groups notifications.
+type BatchConfig struct {
+	// MaxSize is the largest number of notifications in a batch, DefaultBatchSize if zero.
+	MaxSize int `json:"maxSize,omitempty"`
+	// MaxLatency is the longest time the first notification of a batch waits for the batch
+	// to fill, DefaultBatchLatency if zero.
+	MaxLatency Duration `json:"maxLatency,omitempty"`
+	// FlushTimeout bounds the handling of the notifications still pending once the context
+	// of the subscription is done, DefaultBatchFlushTimeout if zero. Those it cannot handle
+	// in time are counted in Stats.Dropped.
+	FlushTimeout Duration `json:"flushTimeout,omitempty"`
+}
+
+// BatchHandlerFunc handles a batch of notifications. A returned error applies to the
+// whole batch and is retried like the error of a HandlerFunc.
+type BatchHandlerFunc func(batch []*TransactionNotification) error
+
+// withBatches makes the subscription pass notifications to do in batches.
+func withBatches(do BatchHandlerFunc) SubscribeOption {
+	return func(o *subscribeOptions) { o.batch = do }
+}
+
+// HandleTransactionsBatches subscribes to Syndica transaction updates like
+// HandleTransactionsNotifications, but passes notifications to do in batches of up to
+// Pipeline.Batch.MaxSize, in the order they were admitted, so that sinks like databases
+// can write many at once. A batch is passed on once it is full or Pipeline.Batch.MaxLatency
+// after its first notification. Batches are handled one at a time, so Pipeline.Workers
+// does not apply. Failed batches are retried as a whole and their notifications are then
+// passed one by one to Hooks.DeadLetter, or end the subscription with WithFailFast. The
+// batches pending once ctx is done are still passed on within
+// Pipeline.Batch.FlushTimeout.
+func (c *C) HandleTransactionsBatches(
+	ctx context.Context,
+	request *JSONRPCRequest,
+	do BatchHandlerFunc,
+	opts ...SubscribeOption,
+) error {
+	return c.HandleTransactionsNotifications(ctx, request, nil, append(opts, withBatches(do))...)
+}
+
+// batcher groups dispatched notifications into batches handled by a single goroutine.
+type batcher struct {
+	in    chan *TransactionNotification
+	done  chan struct{}
+	stats *stats
+}
+
+// startBatcher starts grouping notifications into batches passed to handle with ctx,
+// which reports whether it settled the batch. Once ctx is done, the pending batches are
+// passed with a context detached from ctx and bounded by config.FlushTimeout; the
+// notifications of the batches that are not settled are counted as dropped.
+func (c *C) startBatcher(ctx context.Context, config BatchConfig, handle func(ctx context.Context, batch []*TransactionNotification) bool) *batcher {
+	size := config.MaxSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	latency := time.Duration(config.MaxLatency)
+	if latency <= 0 {
+		latency = time.Duration(DefaultBatchLatency)
+	}
+	flushTimeout := time.Duration(config.FlushTimeout)
+	if flushTimeout <= 0 {
+		flushTimeout = time.Duration(DefaultBatchFlushTimeout)
+	}
+
+	b := &batcher{
+		in:    make(chan *TransactionNotification, size),
+		done:  make(chan struct{}),
+		stats: &c.stats,
+	}
+	go func() {
+		defer close(b.done)
+		var (
+			batch    []*TransactionNotification
+			deadline <-chan time.Time
+			// detached is the context of the batches flushed once ctx is done.
+			detached       context.Context
+			cancelDetached context.CancelFunc = func() {}
+		)
+		defer func() { cancelDetached() }()
+		flush := func() {
+			handleCtx := ctx
+			if ctx.Err() != nil {
+				if detached == nil {
+					detached, cancelDetached = context.WithTimeout(context.WithoutCancel(ctx), flushTimeout)
+				}
+				handleCtx = detached
+			}
+			if len(batch) > 0 && (handleCtx.Err() != nil || !handle(handleCtx, batch)) {
+				b.stats.dropped.Add(uint64(len(batch)))
+			}
+			batch, deadline = nil, nil
+		}
+		for {
+			select {
+			case notification, ok := <-b.in:
+				if !ok {
+					flush()
+					return
+				}
+				batch = append(batch, notification)
+				if len(batch) == 1 {
+					deadline = c.clock().After(latency)
+				}
+				if len(batch) >= size {
+					flush()
+				}
+			case <-deadline:
+				flush()
+			}
+		}
+	}()
+	return b
+}
+
+// add queues the notification for the next batch, waiting while the queue is full. A
+// notification that cannot be queued before ctx is done is counted as dropped.
+func (b *batcher) add(ctx context.Context, notification *TransactionNotification) {
+	select {
+	case b.in <- notification:
+	case <-ctx.Done():
+		b.stats.dropped.Add(1)
+	}
+}
+
+// stop handles the pending batches and waits for them.
+func (b *batcher) stop() {
+	close(b.in)
+	<-b.done
+}
+
+// handleBatch calls do with the batch, retrying transient errors like handle. When all
+// attempts fail the batch error is passed to fail, or else every notification of the
+// batch to the dead letter hook. It reports whether the batch was settled, false when ctx
+// was done before.
+func (c *C) handleBatch(ctx context.Context, batch []*TransactionNotification, do BatchHandlerFunc, fail func(error) bool) bool {
+	err, canceled := c.retry(ctx, func() error { return c.attemptBatch(batch, do) })
+	switch {
+	case canceled:
+		return false
+	case err == nil:
+		c.stats.delivered.Add(uint64(len(batch)))
+		return true
+	}
+
+	c.stats.failed.Add(uint64(len(batch)))
+	if fail != nil && fail(err) {
+		return true
+	}
+	if c.config.Hooks.DeadLetter != nil {
+		c.stats.deadLettered.Add(uint64(len(batch)))
+		for _, notification := range batch {
+			c.config.Hooks.DeadLetter(notification, err)
+		}
+	}
+	return true
+}
+
+// attemptBatch runs Hooks.Enrich on every notification of the batch and the handler once.
+// A panic is returned as a PanicError identifying the first notification of the batch.
+func (c *C) attemptBatch(batch []*TransactionNotification, do BatchHandlerFunc) (err error) {
+	defer func() {
+		if p := recovered(recover(), batch[0].Slot(), batch[0].Signature()); p != nil {
+			err = p
+		}
+	}()
+	if enrich := c.config.Hooks.Enrich; enrich != nil {
+		start := time.Now()
+		for _, notification := range batch {
+			if err := enrich(notification); err != nil {
+				c.latency.since(StageEnrich, start)
+				return err
+			}
+		}
+		c.latency.since(StageEnrich, start)
+	}
+	start := time.Now()
+	defer c.latency.since(StageDispatch, start)
+	return do(batch)
+}
@@ -0,0 +1,145 @@
+package chainstream_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func batchSlots(batch []*chainstream.TransactionNotification) []uint64 {
+	slots := make([]uint64, len(batch))
+	for i, n := range batch {
+		slots[i] = n.Slot()
+	}
+	return slots
+}
+
+func TestBatchesBySize(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1, 2, 3, 4, 5))
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Pipeline.Batch = chainstream.BatchConfig{MaxSize: 2, MaxLatency: chainstream.Duration(time.Hour)}
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var batches [][]uint64
+	err := client.HandleTransactionsBatches(ctx, chainstream.FirehoseNoVotes(), func(batch []*chainstream.TransactionNotification) error {
+		batches = append(batches, batchSlots(batch))
+		return nil
+	}, chainstream.WithMaxNotifications(5))
+	if err != nil {
+		t.Fatalf("HandleTransactionsBatches() error: %v", err)
+	}
+
+	// The last, partial batch is handled when the subscription ends.
+	expected := [][]uint64{{1, 2}, {3, 4}, {5}}
+	if !reflect.DeepEqual(batches, expected) {
+		t.Errorf("batches = %v, expected %v", batches, expected)
+	}
+	if stats := client.Stats(); stats.Delivered != 5 {
+		t.Errorf("delivered %d notifications, expected 5", stats.Delivered)
+	}
+}
+
+func TestBatchesByLatency(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1, 2, 3))
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Pipeline.Batch.MaxLatency = chainstream.Duration(10 * time.Millisecond)
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var slots []uint64
+	err := client.HandleTransactionsBatches(ctx, chainstream.FirehoseNoVotes(), func(batch []*chainstream.TransactionNotification) error {
+		if slots = append(slots, batchSlots(batch)...); len(slots) == 3 {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("HandleTransactionsBatches() error: %v", err)
+	}
+	if !reflect.DeepEqual(slots, []uint64{1, 2, 3}) {
+		t.Errorf("slots = %v, expected a batch flushed after the latency", slots)
+	}
+}
+
+func TestBatchesFlushedOnCancel(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1, 2, 3))
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Pipeline.Batch.MaxLatency = chainstream.Duration(time.Hour)
+	var (
+		mu    sync.Mutex
+		slots []uint64
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// The subscription is canceled while the notifications wait for their batch.
+	config.Hooks.Filter = func(n *chainstream.TransactionNotification) bool {
+		if n.Slot() == 3 {
+			cancel()
+		}
+		return true
+	}
+	client := chainstream.NewClient(config)
+
+	err := client.HandleTransactionsBatches(ctx, chainstream.FirehoseNoVotes(), func(batch []*chainstream.TransactionNotification) error {
+		mu.Lock()
+		defer mu.Unlock()
+		slots = append(slots, batchSlots(batch)...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("HandleTransactionsBatches() error: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	stats := client.Stats()
+	if uint64(len(slots))+stats.Dropped != 3 || stats.Delivered != uint64(len(slots)) {
+		t.Errorf("handled %v and dropped %d, expected the 3 notifications accounted for", slots, stats.Dropped)
+	}
+	if len(slots) == 0 {
+		t.Error("the pending batch was discarded on cancel")
+	}
+}
+
+func TestBatchesDeadLetter(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1, 2))
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Pipeline.Batch.MaxSize = 2
+	var (
+		mu           sync.Mutex
+		deadLettered []uint64
+	)
+	config.Hooks.DeadLetter = func(n *chainstream.TransactionNotification, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		deadLettered = append(deadLettered, n.Slot())
+	}
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := client.HandleTransactionsBatches(ctx, chainstream.FirehoseNoVotes(), func([]*chainstream.TransactionNotification) error {
+		return errors.New("sink unavailable")
+	}, chainstream.WithMaxNotifications(2))
+	if err != nil {
+		t.Fatalf("HandleTransactionsBatches() error: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(deadLettered, []uint64{1, 2}) {
+		t.Errorf("dead lettered %v, expected both notifications of the batch", deadLettered)
+	}
+	if stats := client.Stats(); stats.Failed != 2 || stats.DeadLettered != 2 {
+		t.Errorf("stats = %+v, expected 2 failed and dead lettered", stats)
+	}
+}
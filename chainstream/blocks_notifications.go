@@ -0,0 +1,112 @@
+package chainstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// BlockNotification represents a block update message.
+type BlockNotification struct {
+	JSONRPC string                  `json:"jsonrpc"`
+	Method  string                  `json:"method"`
+	Params  BlockNotificationParams `json:"params"`
+}
+
+// Slot returns the slot of the block.
+func (b *BlockNotification) Slot() uint64 {
+	return b.Params.Result.Value.Slot
+}
+
+// BlockNotificationParams contains subscription ID and payload.
+type BlockNotificationParams struct {
+	Subscription int64                 `json:"subscription"`
+	Result       BlockNotificationData `json:"result"`
+}
+
+// BlockNotificationData holds the context and block data.
+type BlockNotificationData struct {
+	Context ContextMetadata `json:"context"`
+	Value   BlockValue      `json:"value"`
+}
+
+// BlockValue is a full block.
+type BlockValue struct {
+	Slot              uint64             `json:"slot"`
+	Blockhash         string             `json:"blockhash"`
+	PreviousBlockhash string             `json:"previousBlockhash"`
+	ParentSlot        uint64             `json:"parentSlot"`
+	BlockTime         *int64             `json:"blockTime,omitempty"`
+	BlockHeight       *uint64            `json:"blockHeight,omitempty"`
+	Transactions      []BlockTransaction `json:"transactions"`
+	Rewards           []Reward           `json:"rewards,omitempty"`
+}
+
+// BlockTransaction is a transaction contained in a block.
+type BlockTransaction struct {
+	Transaction EncodedTransaction `json:"transaction"`
+	Meta        TransactionMeta    `json:"meta"`
+	// Version is the version of the message, see TransactionValue.Version.
+	Version json.RawMessage `json:"version,omitempty"`
+}
+
+// Reward is a reward or fee credited to an account in a block.
+type Reward struct {
+	Pubkey      string `json:"pubkey"`
+	Lamports    int64  `json:"lamports"`
+	PostBalance uint64 `json:"postBalance"`
+	// RewardType is one of fee, rent, voting or staking.
+	RewardType string `json:"rewardType"`
+	Commission *uint8 `json:"commission,omitempty"`
+}
+
+// TransactionNotification returns the i-th transaction of the block in the form of a
+// transactions notification, so that the same decoders apply to it.
+func (v *BlockValue) TransactionNotification(i int, commitment string) *TransactionNotification {
+	tx := &v.Transactions[i]
+	var notification TransactionNotification
+	notification.JSONRPC = "2.0"
+	notification.Method = "transactionNotification"
+	value := &notification.Params.Result.Value
+	value.BlockTime = v.BlockTime
+	value.Slot = v.Slot
+	value.Transaction = tx.Transaction
+	value.Meta = tx.Meta
+	value.Version = tx.Version
+	context := &notification.Params.Result.Context
+	context.Slot = v.Slot
+	context.SlotStatus = commitment
+	context.Index = i
+	if len(tx.Transaction.Signatures) > 0 {
+		context.Signature = tx.Transaction.Signatures[0]
+	}
+	return &notification
+}
+
+// decodeBlock is the session decoder of blocksSubscribe subscriptions.
+func decodeBlock(data []byte, event *sessionEvent) error {
+	var block BlockNotification
+	if err := json.Unmarshal(data, &block); err != nil {
+		return fmt.Errorf("failed to decode block notification: %w", err)
+	}
+	event.block = &block
+	return nil
+}
+
+// BlocksNotifications subscribes to Syndica block updates. Like transactions, blocks are
+// received over scored endpoints with pings and reconnects, and a block delivered twice
+// is passed to do once. Panics in do are recovered, see Hooks.Panic.
+func (c *C) BlocksNotifications(
+	ctx context.Context,
+	request *JSONRPCRequest,
+	do func(block *BlockNotification),
+	opts ...SubscribeOption,
+) error {
+	return c.notifications(ctx, request, "blocks", opts, func(event sessionEvent) (uint64, string) {
+		block := event.block
+		return block.Slot(), block.Params.Result.Value.Blockhash + "/" + strconv.FormatUint(block.Slot(), 10)
+	}, func(event sessionEvent) error {
+		return c.dispatch(event.block.Slot(), func() { do(event.block) })
+	})
+}
@@ -0,0 +1,73 @@
+package chainstream_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func block(slot uint64, signatures ...string) *chainstream.BlockNotification {
+	var b chainstream.BlockNotification
+	b.JSONRPC = "2.0"
+	b.Method = "blockNotification"
+	b.Params.Result.Value.Slot = slot
+	b.Params.Result.Value.ParentSlot = slot - 1
+	b.Params.Result.Value.Blockhash = "hash"
+	for _, sig := range signatures {
+		var tx chainstream.BlockTransaction
+		tx.Transaction.Signatures = []string{sig}
+		b.Params.Result.Value.Transactions = append(b.Params.Result.Value.Transactions, tx)
+	}
+	b.Params.Result.Value.Rewards = []chainstream.Reward{{Pubkey: "leader", Lamports: 5000, RewardType: "fee"}}
+	return &b
+}
+
+func TestBlocksNotifications(t *testing.T) {
+	srv := newFakeServer(t, func(ctx context.Context, conn *websocket.Conn, _ int) error {
+		for _, b := range []*chainstream.BlockNotification{block(10, "a", "b"), block(10, "a", "b"), block(11)} {
+			if err := wsjson.Write(ctx, conn, b); err != nil {
+				return err
+			}
+		}
+		<-ctx.Done()
+		return nil
+	})
+	client := chainstream.NewClient(chainstream.NewConfig(srv.endpoint()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var blocks []*chainstream.BlockNotification
+	err := client.BlocksNotifications(ctx, chainstream.AllBlocks(), func(b *chainstream.BlockNotification) {
+		blocks = append(blocks, b)
+		if b.Slot() == 11 {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("BlocksNotifications() error: %v", err)
+	}
+
+	if len(blocks) != 2 || blocks[0].Slot() != 10 || blocks[1].Slot() != 11 {
+		t.Fatalf("received %d blocks, expected slots 10 and 11 once", len(blocks))
+	}
+	if method := srv.request(1).Method; method != "blocksSubscribe" {
+		t.Errorf("subscribe method = %q", method)
+	}
+	value := &blocks[0].Params.Result.Value
+	if value.ParentSlot != 9 || len(value.Rewards) != 1 || value.Rewards[0].Lamports != 5000 {
+		t.Errorf("block = %+v", value)
+	}
+	tx := value.TransactionNotification(1, "confirmed")
+	if tx.Signature() != "b" || tx.Slot() != 10 || tx.Params.Result.Context.Index != 1 {
+		t.Errorf("TransactionNotification(1) = %+v", tx.Params.Result.Context)
+	}
+	if stats := client.Stats(); stats.Duplicates != 1 {
+		t.Errorf("Stats().Duplicates = %d, expected 1", stats.Duplicates)
+	}
+}
@@ -0,0 +1,109 @@
+package chainstream
+
+import (
+	"context"
+	"fmt"
+)
+
+// Backpressure selects what the read buffer of a session does when it is full, see
+// PipelineConfig.ReadBuffer.
+type Backpressure int
+
+const (
+	// BackpressureBlock stops reading from the connection until the buffer has room, so
+	// nothing is lost but a slow handler holds back the server.
+	BackpressureBlock Backpressure = iota
+	// BackpressureDropOldest drops the oldest buffered notification to make room.
+	BackpressureDropOldest
+	// BackpressureDropNewest drops the notification just read.
+	BackpressureDropNewest
+)
+
+// MarshalText implements encoding.TextMarshaler.
+func (b Backpressure) MarshalText() ([]byte, error) {
+	switch b {
+	case BackpressureBlock:
+		return []byte("block"), nil
+	case BackpressureDropOldest:
+		return []byte("drop-oldest"), nil
+	case BackpressureDropNewest:
+		return []byte("drop-newest"), nil
+	}
+	return nil, fmt.Errorf("unknown backpressure %d", b)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (b *Backpressure) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "", "block":
+		*b = BackpressureBlock
+	case "drop-oldest":
+		*b = BackpressureDropOldest
+	case "drop-newest":
+		*b = BackpressureDropNewest
+	default:
+		return fmt.Errorf("unknown backpressure %q", text)
+	}
+	return nil
+}
+
+// readBuffer is the bounded queue between the reader of a session and the goroutine
+// consuming its events.
+type readBuffer struct {
+	queue  chan sessionEvent
+	policy Backpressure
+	stats  *stats
+}
+
+func newReadBuffer(size int, policy Backpressure, stats *stats) *readBuffer {
+	return &readBuffer{
+		queue:  make(chan sessionEvent, max(size, 1)),
+		policy: policy,
+		stats:  stats,
+	}
+}
+
+// push queues the event according to the backpressure policy. Read errors are never
+// dropped. It returns false when ctx is done.
+func (b *readBuffer) push(ctx context.Context, event sessionEvent) bool {
+	if b.policy == BackpressureBlock || event.err != nil {
+		select {
+		case b.queue <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	select {
+	case b.queue <- event:
+		return true
+	default:
+	}
+	if b.policy == BackpressureDropOldest {
+		// The consumer may empty the queue meanwhile, in which case nothing is dropped.
+		select {
+		case <-b.queue:
+			b.stats.dropped.Add(1)
+		default:
+		}
+	}
+	// The reader is the only producer, so once the oldest event is gone there is room;
+	// otherwise the event itself is the one dropped.
+	select {
+	case b.queue <- event:
+	default:
+		b.stats.dropped.Add(1)
+	}
+	return true
+}
+
+// forward passes the buffered events to events until the buffer is closed or ctx is done.
+func (b *readBuffer) forward(ctx context.Context, events chan<- sessionEvent) {
+	for event := range b.queue {
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package chainstream_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestBackpressureDropOldest(t *testing.T) {
+	const total = 10
+	srv := newFakeServer(t, sendSlots(1, 2, 3, 4, 5, 6, 7, 8, 9, 10))
+
+	var config chainstream.Config
+	if err := json.Unmarshal([]byte(`{"conn": {"wssApiEndpoint": "`+srv.endpoint()+`"}, "pipeline": {"readBuffer": 2, "backpressure": "drop-oldest"}}`), &config); err != nil {
+		t.Fatal(err)
+	}
+	config.SetDefaults()
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	client := chainstream.NewClient(&config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var delivered []uint64
+	err := client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) error {
+		// The first notification holds up the handler until the buffer overflows.
+		for len(delivered) == 0 && client.Stats().Dropped == 0 && ctx.Err() == nil {
+			time.Sleep(time.Millisecond)
+		}
+		delivered = append(delivered, n.Slot())
+		if n.Slot() == total {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+
+	stats := client.Stats()
+	if stats.Dropped == 0 || int(stats.Dropped)+len(delivered) != total {
+		t.Errorf("dropped %d and delivered %v, expected %d notifications in total", stats.Dropped, delivered, total)
+	}
+	if len(delivered) == 0 || delivered[len(delivered)-1] != total {
+		t.Errorf("delivered %v, expected the newest notification to be kept", delivered)
+	}
+}
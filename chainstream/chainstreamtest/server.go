@@ -0,0 +1,152 @@
+// Package chainstreamtest provides a fake ChainStream endpoint for the tests of packages
+// built on chainstream, in the spirit of net/http/httptest.
+package chainstreamtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+// Request is a request received by a Server.
+type Request struct {
+	ID     int             `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// TransactionParams decodes the params of a transactionsSubscribe request.
+func (r Request) TransactionParams() (chainstream.TransactionSubscribeParams, error) {
+	var params chainstream.TransactionSubscribeParams
+	err := json.Unmarshal(r.Params, &params)
+	return params, err
+}
+
+// Config customizes the answers of a Server.
+type Config struct {
+	// Respond, when set, answers the subscribe request that would be acknowledged with the
+	// subscription ID: with the result to send instead of the ID, or with an error that
+	// rejects the subscription. Returning neither acknowledges it with the ID. It may
+	// block until ctx, of the connection, is done.
+	Respond func(ctx context.Context, request Request, id int64) (interface{}, *chainstream.RPCError)
+	// Notify, when set, returns the notifications sent after acknowledging the subscribe
+	// request under the subscription ID. Their subscription is set to the ID.
+	Notify func(request Request, id int64) []*chainstream.TransactionNotification
+}
+
+// Server is a fake ChainStream endpoint multiplexing subscriptions on each connection, as
+// a chainstream.SubscriptionManager does. Subscribe requests are acknowledged with
+// subscription IDs counting from 1 on each connection; unsubscribe requests are
+// acknowledged and recorded.
+type Server struct {
+	*httptest.Server
+	config      Config
+	connections atomic.Int32
+
+	mu           sync.Mutex
+	subscribed   []Request
+	unsubscribed []int64
+}
+
+// NewServer starts a server closed at the end of the test.
+func NewServer(t testing.TB, config Config) *Server {
+	t.Helper()
+	s := &Server{config: config}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.CloseNow()
+	s.connections.Add(1)
+
+	ctx := r.Context()
+	var nextID int64
+	for {
+		var request Request
+		if err := wsjson.Read(ctx, conn, &request); err != nil {
+			return
+		}
+		response := map[string]interface{}{"jsonrpc": "2.0", "id": request.ID}
+		if strings.HasSuffix(request.Method, "Unsubscribe") {
+			var ids []int64
+			_ = json.Unmarshal(request.Params, &ids)
+			s.mu.Lock()
+			s.unsubscribed = append(s.unsubscribed, ids...)
+			s.mu.Unlock()
+			response["result"] = true
+			if err := wsjson.Write(ctx, conn, response); err != nil {
+				return
+			}
+			continue
+		}
+
+		s.mu.Lock()
+		s.subscribed = append(s.subscribed, request)
+		s.mu.Unlock()
+		nextID++
+		var result interface{} = nextID
+		var rpcErr *chainstream.RPCError
+		if s.config.Respond != nil {
+			if result, rpcErr = s.config.Respond(ctx, request, nextID); result == nil && rpcErr == nil {
+				result = nextID
+			}
+		}
+		if rpcErr != nil {
+			response["error"] = rpcErr
+		} else {
+			response["result"] = result
+		}
+		if err := wsjson.Write(ctx, conn, response); err != nil {
+			return
+		}
+		if rpcErr != nil || s.config.Notify == nil {
+			continue
+		}
+		for _, n := range s.config.Notify(request, nextID) {
+			n.Params.Subscription = nextID
+			if err := wsjson.Write(ctx, conn, n); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Endpoint returns the ws:// URL of the server.
+func (s *Server) Endpoint() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http")
+}
+
+// Connections returns the number of connections accepted.
+func (s *Server) Connections() int {
+	return int(s.connections.Load())
+}
+
+// Subscribed returns the subscribe requests received, in order.
+func (s *Server) Subscribed() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.subscribed...)
+}
+
+// Unsubscribed returns the subscription IDs released by unsubscribe requests, in order.
+func (s *Server) Unsubscribed() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int64(nil), s.unsubscribed...)
+}
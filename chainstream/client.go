@@ -2,22 +2,153 @@ package chainstream
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/gerasimovvladislav/zensol-go/clock"
 )
 
+// Client subscribes to transactions and blocks and reports on its subscriptions. *C
+// implements it, as well as AccountClient, LogsClient, ProgramClient and SignatureClient,
+// which are kept apart so that Client stays stable for the implementations outside of this
+// package.
 type Client interface {
 	TransactionsNotifications(
 		ctx context.Context,
 		request *JSONRPCRequest,
 		do func(notification *TransactionNotification),
+		opts ...SubscribeOption,
+	) error
+	HandleTransactionsNotifications(
+		ctx context.Context,
+		request *JSONRPCRequest,
+		do HandlerFunc,
+		opts ...SubscribeOption,
+	) error
+	HandleTransactionsBatches(
+		ctx context.Context,
+		request *JSONRPCRequest,
+		do BatchHandlerFunc,
+		opts ...SubscribeOption,
+	) error
+	TransactionsNotificationsRaw(
+		ctx context.Context,
+		request *JSONRPCRequest,
+		do func(raw json.RawMessage),
+		opts ...SubscribeOption,
+	) error
+	Stream(ctx context.Context, request *JSONRPCRequest, opts ...SubscribeOption) (<-chan *TransactionNotification, <-chan error)
+	BlocksNotifications(
+		ctx context.Context,
+		request *JSONRPCRequest,
+		do func(block *BlockNotification),
+		opts ...SubscribeOption,
 	) error
+	Stats() Stats
+	Latency() map[Stage]Histogram
+	RTT() Histogram
+	Endpoints() []EndpointScore
+	Snapshot() Snapshot
 }
 
+// AccountClient subscribes to account changes, see AccountNotifications and AccountState.
+type AccountClient interface {
+	AccountNotifications(
+		ctx context.Context,
+		request *JSONRPCRequest,
+		do func(account *AccountNotification),
+		opts ...SubscribeOption,
+	) error
+	AccountState(
+		ctx context.Context,
+		request *JSONRPCRequest,
+		snapshot AccountSnapshot,
+		do func(account *AccountNotification),
+		opts ...SubscribeOption,
+	) error
+}
+
+// LogsClient subscribes to program logs, see LogsNotifications.
+type LogsClient interface {
+	LogsNotifications(
+		ctx context.Context,
+		request *JSONRPCRequest,
+		do func(logs *LogsNotification),
+		opts ...SubscribeOption,
+	) error
+}
+
+// ProgramClient subscribes to the accounts owned by a program, see ProgramNotifications.
+type ProgramClient interface {
+	ProgramNotifications(
+		ctx context.Context,
+		request *JSONRPCRequest,
+		do func(program *ProgramNotification),
+		opts ...SubscribeOption,
+	) error
+}
+
+// SignatureClient follows transaction signatures, see SignatureNotifications and
+// WaitForConfirmation.
+type SignatureClient interface {
+	SignatureNotifications(
+		ctx context.Context,
+		request *JSONRPCRequest,
+		do func(signature *SignatureNotification),
+		opts ...SubscribeOption,
+	) error
+	WaitForConfirmation(ctx context.Context, signature string, commitment Commitment, opts ...SubscribeOption) (*SignatureStatus, error)
+}
+
+var (
+	_ Client          = (*C)(nil)
+	_ AccountClient   = (*C)(nil)
+	_ LogsClient      = (*C)(nil)
+	_ ProgramClient   = (*C)(nil)
+	_ SignatureClient = (*C)(nil)
+)
+
 type C struct {
-	config *Config
+	config  *Config
+	stats   stats
+	latency latencies
+	rtt     histogram
+	// endpoints is created on first use, after the config is complete.
+	endpoints     *endpointSet
+	endpointsOnce sync.Once
+	// httpClient dials the endpoints, nil for the default one, and race records the
+	// deliveries of redundant mode. They are created with endpoints.
+	httpClient *http.Client
+	race       *raceRecorder
+	// subs are the running subscriptions reported by Snapshot.
+	subsMu    sync.Mutex
+	subs      map[*subscriptionState]struct{}
+	nextSubID int64
 }
 
 func NewClient(config *Config) *C {
 	return &C{
-		config,
+		config: config,
+	}
+}
+
+// endpointSet returns the scores of the configured endpoints.
+func (c *C) endpointSet() *endpointSet {
+	c.endpointsOnce.Do(func() {
+		c.endpoints = newEndpointSet(&c.config.Conn)
+		c.httpClient = c.config.Conn.HTTPClient()
+		if c.config.Conn.Redundant {
+			c.race = newRaceRecorder()
+		}
+	})
+	return c.endpoints
+}
+
+// clock returns the configured clock or the system clock.
+func (c *C) clock() clock.Clock {
+	if c.config.Clock != nil {
+		return c.config.Clock
 	}
+	return clock.System()
 }
@@ -0,0 +1,76 @@
+package chainstream_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+func TestRetryBackoffWithFakeClock(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1))
+	fake := clock.NewFake(time.Now())
+
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Clock = fake
+	config.Pipeline.Retry = chainstream.RetryPolicy{MaxAttempts: 2, InitialBackoff: chainstream.Duration(time.Hour), Multiplier: 2}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go func() {
+		// The ping ticker and the backoff timer.
+		fake.BlockUntil(2)
+		fake.Advance(time.Hour)
+	}()
+
+	attempts := 0
+	err := chainstream.NewClient(config).HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(*chainstream.TransactionNotification) error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("transient")
+		}
+		cancel()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, expected the retry after an hour of fake time", attempts)
+	}
+}
+
+func TestStaleWithFakeClock(t *testing.T) {
+	fresh := notification(1)
+	fresh.Params.Result.Context.NodeTime = time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+	stale := notification(2)
+	stale.Params.Result.Context.NodeTime = fresh.Params.Result.Context.NodeTime.Add(-time.Minute)
+	srv := newFakeServer(t, sendNotifications(stale, fresh))
+
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Clock = clock.NewFake(fresh.Params.Result.Context.NodeTime.Add(time.Second))
+	config.Pipeline.MaxNotificationAge = chainstream.Duration(10 * time.Second)
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var delivered []uint64
+	err := client.TransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) {
+		delivered = append(delivered, n.Slot())
+		cancel()
+	})
+	if err != nil {
+		t.Fatalf("TransactionsNotifications() error: %v", err)
+	}
+	if len(delivered) != 1 || delivered[0] != 1 {
+		t.Errorf("delivered = %v, expected only the fresh notification", delivered)
+	}
+	if stats := client.Stats(); stats.Stale != 1 {
+		t.Errorf("Stats().Stale = %d, expected 1", stats.Stale)
+	}
+}
@@ -0,0 +1,99 @@
+package chainstream
+
+import "fmt"
+
+// Commitment is the commitment level of the transactions of a subscription, ordered from
+// the fastest to the safest. The zero value is unset and replaced with DefaultCommitment
+// by SubscriptionConfig.SetDefaults. It is written to and read from JSON as processed,
+// confirmed or finalized.
+type Commitment int
+
+// Commitment levels.
+const (
+	// CommitmentProcessed is a transaction in a block processed by the node, which may
+	// still be skipped.
+	CommitmentProcessed Commitment = iota + 1
+	// CommitmentConfirmed is a transaction in a block voted on by a supermajority.
+	CommitmentConfirmed
+	// CommitmentFinalized is a transaction in a block that cannot be rolled back.
+	CommitmentFinalized
+)
+
+// ParseCommitment returns the commitment level named s.
+func ParseCommitment(s string) (Commitment, error) {
+	var c Commitment
+	if err := c.UnmarshalText([]byte(s)); err != nil {
+		return 0, err
+	}
+	if c == 0 {
+		return 0, fmt.Errorf("unknown commitment %q", s)
+	}
+	return c, nil
+}
+
+// String returns the name of the level, empty when unset.
+func (c Commitment) String() string {
+	switch c {
+	case 0:
+		return ""
+	case CommitmentProcessed:
+		return "processed"
+	case CommitmentConfirmed:
+		return "confirmed"
+	case CommitmentFinalized:
+		return "finalized"
+	}
+	return fmt.Sprintf("Commitment(%d)", int(c))
+}
+
+// AtLeast reports whether c is level or a safer one, e.g. finalized is at least
+// confirmed. Unset levels are compared as DefaultCommitment.
+func (c Commitment) AtLeast(level Commitment) bool {
+	return c.orDefault() >= level.orDefault()
+}
+
+// Validate returns an error if the level is unset or unknown.
+func (c Commitment) Validate() error {
+	switch c {
+	case CommitmentProcessed, CommitmentConfirmed, CommitmentFinalized:
+		return nil
+	case 0:
+		return fmt.Errorf("commitment is required")
+	}
+	return fmt.Errorf("commitment: unknown level %d", int(c))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c Commitment) MarshalText() ([]byte, error) {
+	if c != 0 {
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. An empty name leaves the level unset.
+func (c *Commitment) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "":
+		*c = 0
+	case "processed":
+		*c = CommitmentProcessed
+	case "confirmed":
+		*c = CommitmentConfirmed
+	case "finalized":
+		*c = CommitmentFinalized
+	default:
+		return fmt.Errorf("unknown commitment %q", text)
+	}
+	return nil
+}
+
+// orDefault returns the level, DefaultCommitment if unset.
+func (c Commitment) orDefault() Commitment {
+	if c == 0 {
+		return DefaultCommitment
+	}
+	return c
+}
@@ -0,0 +1,46 @@
+package chainstream_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestCommitmentAtLeast(t *testing.T) {
+	tests := []struct {
+		commitment, level chainstream.Commitment
+		expected          bool
+	}{
+		{chainstream.CommitmentFinalized, chainstream.CommitmentConfirmed, true},
+		{chainstream.CommitmentConfirmed, chainstream.CommitmentConfirmed, true},
+		{chainstream.CommitmentProcessed, chainstream.CommitmentConfirmed, false},
+		{0, chainstream.CommitmentConfirmed, true},
+		{0, chainstream.CommitmentFinalized, false},
+	}
+	for _, tc := range tests {
+		if got := tc.commitment.AtLeast(tc.level); got != tc.expected {
+			t.Errorf("%q.AtLeast(%q) = %v, expected %v", tc.commitment, tc.level, got, tc.expected)
+		}
+	}
+}
+
+func TestCommitmentJSON(t *testing.T) {
+	data, err := json.Marshal(chainstream.TransactionFilter{Commitment: chainstream.CommitmentFinalized})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var filter chainstream.TransactionFilter
+	if err := json.Unmarshal(data, &filter); err != nil || filter.Commitment != chainstream.CommitmentFinalized {
+		t.Errorf("round trip of %s = %q, %v, expected finalized", data, filter.Commitment, err)
+	}
+	if err := json.Unmarshal([]byte(`{"commitment":"recent"}`), &filter); err == nil {
+		t.Error("an unknown commitment was read")
+	}
+	if _, err := chainstream.ParseCommitment("processed"); err != nil {
+		t.Errorf("ParseCommitment(processed) error: %v", err)
+	}
+	if _, err := chainstream.ParseCommitment(""); err == nil {
+		t.Error("ParseCommitment accepted an empty level")
+	}
+}
@@ -1,11 +1,552 @@
 package chainstream
 
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/clock"
+	"github.com/gerasimovvladislav/zensol-go/store"
+)
+
+const (
+	// DefaultNetwork is used by subscriptions that do not specify a network.
+	DefaultNetwork = NetworkMainnet
+	// DefaultCommitment is used by subscriptions that do not specify a commitment.
+	DefaultCommitment = CommitmentConfirmed
+)
+
+// Config describes a client declaratively: where to connect, what to subscribe to
+// and how received notifications are processed.
 type Config struct {
-	WssApiEndpoint string
+	Conn          ConnConfig           `json:"conn"`
+	Subscriptions []SubscriptionConfig `json:"subscriptions,omitempty"`
+	Pipeline      PipelineConfig       `json:"pipeline"`
+	// Hooks are callbacks invoked by the client; they cannot be loaded from a file.
+	Hooks Hooks `json:"-"`
+	// Store, when set, remembers the signatures handled successfully for Pipeline.DedupTTL
+	// so duplicates are dropped across restarts and between clients sharing the store, by
+	// subscription, see Pipeline.DedupNamespace.
+	Store store.Store `json:"-"`
+	// Clock drives pings, rotations, reconnect and retry delays and staleness checks.
+	// Nil uses the system clock.
+	Clock clock.Clock `json:"-"`
+	// RetryBudget, when set, bounds the reconnects, backfill retries and handler retries
+	// of the client, and of the other clients and subsystems sharing it.
+	RetryBudget *RetryBudget `json:"-"`
+}
+
+// Hooks are optional callbacks invoked by the client.
+type Hooks struct {
+	// Filter, when set, drops notifications it does not match before they are handled.
+	Filter Filter
+	// Enrich, when set, is called before every handler attempt, e.g. to look up data the
+	// handler needs. Its errors are retried and dead-lettered like handler errors. Lookups
+	// that may be slow belong in an enrich.Stage, whose Prefetch starts them without
+	// blocking.
+	Enrich HandlerFunc
+	// DeadLetter receives notifications whose handling failed after all retries.
+	DeadLetter DeadLetterFunc
+	// Downgrade is called after a firehose subscription has been downgraded.
+	Downgrade DowngradeFunc
+	// OrderKey returns the key of notifications handled in order by the workers of
+	// PipelineConfig.Workers. Nil uses the fee payer.
+	OrderKey OrderKeyFunc
+	// Connect is called when a subscription has established its first session.
+	Connect ConnectionFunc
+	// Disconnect is called when the session of a subscription has failed, Reconnect when
+	// it has been replaced. A subscription ends when the reconnect fails.
+	Disconnect ConnectionFunc
+	Reconnect  ConnectionFunc
+	// Panic is called with panics recovered from handlers and Enrich; the notification then
+	// counts as failed. Without it a panic ends the subscription, which returns the
+	// PanicError.
+	Panic PanicFunc
+	// Idle is called when a session is closed for receiving nothing within
+	// ConnConfig.IdleTimeout.
+	Idle IdleFunc
+	// Backfill, when set, loads the transactions of the slots that may have been missed
+	// while reconnecting. It runs beside the subscription, which holds the notifications
+	// of the new session meanwhile, up to a few thousand, so that the gap comes first.
+	Backfill BackfillFunc
+	// Trace, when set, starts a span around every attempt of a ContextHandlerFunc.
+	Trace TraceFunc
+}
+
+// DefaultDedupTTL is the default time signatures are kept in Config.Store.
+const DefaultDedupTTL = Duration(10 * time.Minute)
+
+// DefaultReadBuffer is the default number of notifications read ahead of the handler.
+const DefaultReadBuffer = 256
+
+// DefaultFailbackAfter is the default time an endpoint is avoided after a failure.
+const DefaultFailbackAfter = Duration(time.Minute)
+
+// Defaults of the ping settings of ConnConfig.
+const (
+	DefaultPingInterval    = Duration(30 * time.Second)
+	DefaultPingTimeout     = Duration(10 * time.Second)
+	DefaultMaxPingFailures = 2
+)
+
+// DefaultUnsubscribeTimeout is the default time an ending subscription waits for the
+// acknowledgement of its unsubscribe request.
+const DefaultUnsubscribeTimeout = Duration(2 * time.Second)
+
+// DefaultRotationOverlap is the longest time the replaced session stays open after a rotation.
+const DefaultRotationOverlap = Duration(30 * time.Second)
+
+// ConnConfig holds connection settings.
+type ConnConfig struct {
+	// WssApiEndpoint is the primary endpoint. It and the URLs of Endpoints may contain the
+	// {token} and {network} placeholders, substituted with Token and Network.
+	WssApiEndpoint string `json:"wssApiEndpoint"`
+	// Token is the API token substituted for {token} in endpoint URLs.
+	Token string `json:"token,omitempty"`
+	// Network is substituted for {network} in endpoint URLs, DefaultNetwork if empty.
+	Network Network `json:"network,omitempty"`
+	// Provider is the vendor of the endpoints, ProviderSyndica if empty. Its adapter
+	// translates the subscriptions to the API of the vendor, see Provider.
+	Provider ProviderName `json:"provider,omitempty"`
+	// Adapter, when set, is used instead of the adapter of Provider, e.g. for a vendor
+	// that is not built in.
+	Adapter Provider `json:"-"`
+	// Endpoints are additional endpoints serving the same data. The client connects to the
+	// healthy endpoints of the lowest priority, scored by ping RTT, delivery lag and recent
+	// failures, and fails over to the next priority when they fail.
+	Endpoints []EndpointConfig `json:"endpoints,omitempty"`
+	// Headers are sent on the WebSocket handshake to every endpoint, e.g. X-Api-Key or
+	// Authorization for endpoints that do not take the token in the URL.
+	Headers map[string]string `json:"headers,omitempty"`
+	// FailbackAfter is how long an endpoint is avoided after a connection failure. Once it
+	// has passed, a subscription connected to an endpoint of a higher priority moves back.
+	FailbackAfter Duration `json:"failbackAfter,omitempty"`
+	// RotateEvery proactively replaces the connection with a fresh one at this interval.
+	// Zero keeps a connection until it fails.
+	RotateEvery Duration `json:"rotateEvery,omitempty"`
+	// RotationOverlap caps how long the old connection keeps delivering after a rotation.
+	// It is closed earlier, as soon as the new connection delivers a notification at or
+	// beyond the last slot of the old one. Notifications received on both are delivered once.
+	RotationOverlap Duration `json:"rotationOverlap,omitempty"`
+	// PingInterval is the time between pings of the current connection.
+	PingInterval Duration `json:"pingInterval,omitempty"`
+	// PingTimeout is how long a ping may wait for its pong before it counts as failed.
+	PingTimeout Duration `json:"pingTimeout,omitempty"`
+	// MaxPingFailures is the number of consecutive failed pings after which the connection
+	// is considered dead and replaced.
+	MaxPingFailures int `json:"maxPingFailures,omitempty"`
+	// IdleTimeout replaces a connection that has received no notification for this long,
+	// e.g. a server that silently stopped sending while still answering pings. Zero
+	// disables the watchdog; filters matching few transactions need a long window.
+	IdleTimeout Duration `json:"idleTimeout,omitempty"`
+	// UnsubscribeTimeout is how long a subscription that ends, e.g. when its context is
+	// done, waits for the server to acknowledge its unsubscribe request before closing the
+	// connection. A negative timeout closes the connection without unsubscribing.
+	UnsubscribeTimeout Duration `json:"unsubscribeTimeout,omitempty"`
+	// Redundant subscribes on every healthy endpoint at once instead of only the best
+	// scoring one. Each notification is delivered once, from the endpoint that is first,
+	// and a failing connection is replaced by another without a gap. Client.EndpointRace
+	// reports which endpoint wins.
+	Redundant bool `json:"redundant,omitempty"`
+	// Resolve pins endpoint hosts to IP addresses, tried in order, bypassing DNS, e.g. for
+	// egress allowlists or where DNS is intercepted. TLS still verifies the host name.
+	Resolve map[string][]string `json:"resolve,omitempty"`
+	// DNSServer, e.g. 1.1.1.1:53, resolves the hosts not in Resolve instead of the system
+	// resolver.
+	DNSServer string `json:"dnsServer,omitempty"`
+	// DialContext, when set, opens the connections to endpoints and to DNSServer instead
+	// of a net.Dialer, e.g. to resolve over DNS over HTTPS or to go through a proxy. It
+	// receives the pinned address for hosts in Resolve.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error) `json:"-"`
+}
+
+// SubscriptionConfig describes a single transactionsSubscribe subscription.
+type SubscriptionConfig struct {
+	Name   string                     `json:"name"`
+	Params TransactionSubscribeParams `json:"params"`
+}
+
+// PipelineConfig holds settings applied to notifications before they reach the handler.
+type PipelineConfig struct {
+	// MaxNotificationAge drops notifications older than the given age instead of
+	// delivering them, e.g. after a reconnect. Zero delivers everything.
+	MaxNotificationAge Duration `json:"maxNotificationAge,omitempty"`
+	// AgeSource selects the timestamp used for MaxNotificationAge.
+	AgeSource AgeSource `json:"ageSource,omitempty"`
+	// SkipFailed drops notifications of transactions that failed, see
+	// TransactionNotification.Succeeded, before the handler is called.
+	SkipFailed bool `json:"skipFailed,omitempty"`
+	// AccountFilter, when set, drops notifications that do not match it. Unlike Hooks.Filter
+	// it can be applied by the server, see Downgrade.
+	AccountFilter *AccountKeysFilter `json:"accountFilter,omitempty"`
+	// Downgrade applies AccountFilter server-side to a firehose subscription the client
+	// cannot keep up with.
+	Downgrade DowngradeConfig `json:"downgrade"`
+	// DedupTTL is how long delivered signatures are kept in Config.Store.
+	DedupTTL Duration `json:"dedupTTL,omitempty"`
+	// DedupNamespace names the subscription in the keys of Config.Store: clients sharing a
+	// store drop the duplicates of the subscriptions of the same namespace only. Empty
+	// uses a hash of the method and params of the subscribe request.
+	DedupNamespace string `json:"dedupNamespace,omitempty"`
+	// Retry controls how handler errors are retried.
+	Retry RetryPolicy `json:"retry"`
+	// MaxGapSlots limits the slots passed to Hooks.Backfill after a reconnect to the most
+	// recent ones. Zero passes the whole gap.
+	MaxGapSlots uint64 `json:"maxGapSlots,omitempty"`
+	// Workers is the number of notifications handled concurrently, so heavy handlers do
+	// not hold up reading. By default notifications with the same Hooks.OrderKey always go
+	// to the same worker, so they are handled in the order they were received, which is
+	// (slot, index) order; see Ordering. Values up to 1 handle notifications one at a
+	// time; with more workers hooks and handlers must be safe for concurrent use.
+	Workers int `json:"workers,omitempty"`
+	// Ordering selects between per-key order and throughput for Workers.
+	Ordering Ordering `json:"ordering,omitempty"`
+	// Batch groups notifications for C.HandleTransactionsBatches.
+	Batch BatchConfig `json:"batch"`
+	// StreamBuffer is the capacity of the channels of C.Stream and C.TransactionsFeed.
+	// Zero makes the channel unbuffered.
+	StreamBuffer int `json:"streamBuffer,omitempty"`
+	// ReadBuffer is the number of notifications read ahead of the handler on every
+	// connection, DefaultReadBuffer if zero.
+	ReadBuffer int `json:"readBuffer,omitempty"`
+	// Backpressure selects what happens when the read buffer is full: reading blocks by
+	// default, or notifications are dropped and counted in Stats.Dropped.
+	Backpressure Backpressure `json:"backpressure,omitempty"`
+}
+
+// RetryPolicy describes retries of failed handler calls with exponential backoff. Gaps
+// Hooks.Backfill fails to load are retried with the same policy.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of handler calls per notification, or of backfill
+	// calls per gap, 1 disables retries.
+	MaxAttempts    int      `json:"maxAttempts,omitempty"`
+	InitialBackoff Duration `json:"initialBackoff,omitempty"`
+	MaxBackoff     Duration `json:"maxBackoff,omitempty"`
+	Multiplier     float64  `json:"multiplier,omitempty"`
+}
+
+// SetDefaults fills zero values of the retry policy with defaults.
+func (r *RetryPolicy) SetDefaults() {
+	if r.MaxAttempts == 0 {
+		r.MaxAttempts = 1
+	}
+	if r.InitialBackoff == 0 {
+		r.InitialBackoff = Duration(100 * time.Millisecond)
+	}
+	if r.MaxBackoff == 0 {
+		r.MaxBackoff = Duration(5 * time.Second)
+	}
+	if r.Multiplier == 0 {
+		r.Multiplier = 2
+	}
+}
+
+// Validate checks the retry policy.
+func (r *RetryPolicy) Validate() error {
+	if r.MaxAttempts < 1 {
+		return errors.New("pipeline: retry.maxAttempts must be at least 1")
+	}
+	if r.InitialBackoff < 0 || r.MaxBackoff < 0 {
+		return errors.New("pipeline: retry backoff must not be negative")
+	}
+	if r.Multiplier < 1 {
+		return errors.New("pipeline: retry.multiplier must be at least 1")
+	}
+	return nil
 }
 
+// NewConfig returns a config for the given endpoint with defaults applied.
 func NewConfig(wssApiEndpoint string) *Config {
-	return &Config{
-		WssApiEndpoint: wssApiEndpoint,
+	config := &Config{
+		Conn: ConnConfig{
+			WssApiEndpoint: wssApiEndpoint,
+		},
+	}
+	config.SetDefaults()
+	return config
+}
+
+// LoadConfig reads a JSON config file and applies defaults to it.
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{}
+	if err := config.MergeFile(path); err != nil {
+		return nil, err
+	}
+	config.SetDefaults()
+	return config, nil
+}
+
+// MergeFile overlays the values of a JSON config file on top of the current config.
+// Fields missing from the file keep their current values; lists are replaced.
+func (c *Config) MergeFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read config file: %w", err)
+	}
+	if err = json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("cannot parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// SetDefaults fills zero values with defaults.
+func (c *Config) SetDefaults() {
+	c.Conn.SetDefaults()
+	c.Pipeline.SetDefaults()
+	for i := range c.Subscriptions {
+		c.Subscriptions[i].SetDefaults(i)
+	}
+}
+
+// Validate checks every section and returns all problems found.
+func (c *Config) Validate() error {
+	errs := []error{c.Conn.Validate(), c.Pipeline.Validate()}
+	names := make(map[string]struct{}, len(c.Subscriptions))
+	for i := range c.Subscriptions {
+		sub := &c.Subscriptions[i]
+		if err := sub.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("subscriptions[%d]: %w", i, err))
+		}
+		if _, ok := names[sub.Name]; ok {
+			errs = append(errs, fmt.Errorf("subscriptions[%d]: duplicate name %q", i, sub.Name))
+		}
+		names[sub.Name] = struct{}{}
+	}
+	return errors.Join(errs...)
+}
+
+// SetDefaults fills zero values of the connection settings with defaults.
+func (c *ConnConfig) SetDefaults() {
+	if c.RotationOverlap == 0 {
+		c.RotationOverlap = DefaultRotationOverlap
+	}
+	if c.FailbackAfter == 0 {
+		c.FailbackAfter = DefaultFailbackAfter
+	}
+	if c.PingInterval == 0 {
+		c.PingInterval = DefaultPingInterval
+	}
+	if c.PingTimeout == 0 {
+		c.PingTimeout = DefaultPingTimeout
+	}
+	if c.MaxPingFailures == 0 {
+		c.MaxPingFailures = DefaultMaxPingFailures
+	}
+	if c.UnsubscribeTimeout == 0 {
+		c.UnsubscribeTimeout = DefaultUnsubscribeTimeout
+	}
+}
+
+// Validate checks the connection settings.
+func (c *ConnConfig) Validate() error {
+	if c.RotateEvery < 0 || c.RotationOverlap < 0 || c.FailbackAfter < 0 {
+		return errors.New("conn: rotateEvery, rotationOverlap and failbackAfter must not be negative")
+	}
+	if c.PingInterval < 0 || c.PingTimeout < 0 || c.MaxPingFailures < 0 || c.IdleTimeout < 0 {
+		return errors.New("conn: pingInterval, pingTimeout, maxPingFailures and idleTimeout must not be negative")
+	}
+	if c.WssApiEndpoint == "" {
+		return errors.New("conn: wssApiEndpoint is required")
+	}
+	errs := []error{c.validateEndpoint("wssApiEndpoint", c.WssApiEndpoint)}
+	if c.Network != "" {
+		if err := c.Network.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("conn: network: %w", err))
+		}
+	}
+	if c.Provider != "" {
+		if err := c.Provider.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("conn: provider: %w", err))
+		}
+	}
+	errs = append(errs, validateHeaders("headers", c.Headers), c.validateResolve())
+	for i, e := range c.Endpoints {
+		errs = append(errs,
+			c.validateEndpoint(fmt.Sprintf("endpoints[%d].url", i), e.URL),
+			validateHeaders(fmt.Sprintf("endpoints[%d].headers", i), e.Headers))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *ConnConfig) validateEndpoint(field, template string) error {
+	endpoint, err := ExpandEndpoint(template, c.Token, c.Network)
+	if err != nil {
+		return fmt.Errorf("conn: invalid %s: %w", field, err)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("conn: invalid %s: %w", field, err)
+	}
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return fmt.Errorf("conn: %s must use ws or wss scheme, got %q", field, u.Scheme)
+	}
+	return nil
+}
+
+// validHeaderName reports whether name is an HTTP token.
+func validHeaderName(name string) bool {
+	return name != "" && strings.IndexFunc(name, func(r rune) bool {
+		return r <= ' ' || r >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r)
+	}) < 0
+}
+
+func (c *ConnConfig) validateResolve() error {
+	for host, addresses := range c.Resolve {
+		if len(addresses) == 0 {
+			return fmt.Errorf("conn: resolve: no address for host %q", host)
+		}
+		for _, address := range addresses {
+			if net.ParseIP(address) == nil {
+				return fmt.Errorf("conn: resolve: %q of host %q is not an IP address", address, host)
+			}
+		}
+	}
+	if c.DNSServer != "" {
+		if _, _, err := net.SplitHostPort(c.DNSServer); err != nil {
+			return fmt.Errorf("conn: invalid dnsServer: %w", err)
+		}
+	}
+	return nil
+}
+
+func validateHeaders(field string, headers map[string]string) error {
+	for name, value := range headers {
+		if !validHeaderName(name) {
+			return fmt.Errorf("conn: %s: invalid header name %q", field, name)
+		}
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return fmt.Errorf("conn: %s: value of header %s contains a line break", field, name)
+		}
+	}
+	return nil
+}
+
+// SetDefaults fills zero values of the i-th subscription with defaults.
+func (s *SubscriptionConfig) SetDefaults(i int) {
+	if s.Name == "" {
+		s.Name = fmt.Sprintf("subscription-%d", i)
+	}
+	if s.Params.Network == "" {
+		s.Params.Network = DefaultNetwork
+	}
+	if s.Params.Filter.Commitment == 0 {
+		s.Params.Filter.Commitment = DefaultCommitment
+	}
+}
+
+// Validate checks the subscription settings.
+func (s *SubscriptionConfig) Validate() error {
+	if s.Name == "" {
+		return errors.New("name is required")
+	}
+	return s.Params.validate("params.")
+}
+
+// Normalize trims and deduplicates the account keys of the subscription filter and
+// returns warnings for keys that will never match.
+func (s *SubscriptionConfig) Normalize() ([]KeyWarning, error) {
+	if s.Params.Filter.AccountKeys == nil {
+		return nil, nil
+	}
+	return s.Params.Filter.AccountKeys.Normalize()
+}
+
+// Request builds the transactionsSubscribe request for the subscription.
+func (s *SubscriptionConfig) Request(id int) *JSONRPCRequest {
+	return &JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      id,
+		Method:  "transactionsSubscribe",
+		Params:  s.Params,
+	}
+}
+
+// SetDefaults fills zero values of the pipeline settings with defaults.
+func (p *PipelineConfig) SetDefaults() {
+	if p.DedupTTL == 0 {
+		p.DedupTTL = DefaultDedupTTL
+	}
+	if p.ReadBuffer == 0 {
+		p.ReadBuffer = DefaultReadBuffer
+	}
+	if p.Batch.MaxSize == 0 {
+		p.Batch.MaxSize = DefaultBatchSize
+	}
+	if p.Batch.MaxLatency == 0 {
+		p.Batch.MaxLatency = DefaultBatchLatency
+	}
+	if p.Batch.FlushTimeout == 0 {
+		p.Batch.FlushTimeout = DefaultBatchFlushTimeout
+	}
+	p.Retry.SetDefaults()
+}
+
+// Validate checks the pipeline settings.
+func (p *PipelineConfig) Validate() error {
+	if p.MaxNotificationAge < 0 || p.DedupTTL < 0 {
+		return errors.New("pipeline: maxNotificationAge and dedupTTL must not be negative")
+	}
+	if p.StreamBuffer < 0 || p.ReadBuffer < 0 || p.Workers < 0 {
+		return errors.New("pipeline: streamBuffer, readBuffer and workers must not be negative")
+	}
+	if p.Batch.MaxSize < 0 || p.Batch.MaxLatency < 0 || p.Batch.FlushTimeout < 0 {
+		return errors.New("pipeline: batch.maxSize, batch.maxLatency and batch.flushTimeout must not be negative")
+	}
+	if p.Downgrade.After < 0 || p.Downgrade.MaxLag < 0 {
+		return errors.New("pipeline: downgrade.after and downgrade.maxLag must not be negative")
+	}
+	if p.Downgrade.After > 0 && (p.AccountFilter == nil || len(p.AccountFilter.All)+len(p.AccountFilter.OneOf) == 0) {
+		return errors.New("pipeline: downgrade requires accountFilter with all or oneOf keys")
+	}
+	switch p.AgeSource {
+	case AgeFromNodeTime, AgeFromBlockTime:
+	default:
+		return fmt.Errorf("pipeline: unknown ageSource %d", p.AgeSource)
+	}
+	switch p.Ordering {
+	case OrderByKey, OrderNone:
+	default:
+		return fmt.Errorf("pipeline: unknown ordering %d", p.Ordering)
+	}
+	switch p.Backpressure {
+	case BackpressureBlock, BackpressureDropOldest, BackpressureDropNewest:
+	default:
+		return fmt.Errorf("pipeline: unknown backpressure %d", p.Backpressure)
+	}
+	return p.Retry.Validate()
+}
+
+// Duration is a time.Duration that is written to and read from JSON as a string like "1m30s".
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Plain numbers are read as nanoseconds.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch value := v.(type) {
+	case float64:
+		*d = Duration(value)
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", value, err)
+		}
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration %s", data)
 	}
+	return nil
 }
@@ -0,0 +1,147 @@
+package chainstream_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, `{
+		"conn": {"wssApiEndpoint": "wss://chainstream.api.syndica.io/api-key/test"},
+		"subscriptions": [
+			{"params": {"filter": {"excludeVotes": true}}},
+			{"name": "wallets", "params": {"network": "solana-devnet", "filter": {"commitment": "finalized", "accountKeys": {"oneOf": ["53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF"]}}}}
+		],
+		"pipeline": {"maxNotificationAge": "1500ms", "ageSource": "blockTime"}
+	}`)
+
+	config, err := chainstream.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	if err = config.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	first := config.Subscriptions[0]
+	if first.Name != "subscription-0" || first.Params.Network != chainstream.DefaultNetwork || first.Params.Filter.Commitment != chainstream.DefaultCommitment {
+		t.Errorf("defaults not applied: %+v", first)
+	}
+	if got := config.Subscriptions[1].Params.Filter.Commitment; got != chainstream.CommitmentFinalized {
+		t.Errorf("Commitment = %q, expected %q", got, chainstream.CommitmentFinalized)
+	}
+	if got := time.Duration(config.Pipeline.MaxNotificationAge); got != 1500*time.Millisecond {
+		t.Errorf("MaxNotificationAge = %v, expected %v", got, 1500*time.Millisecond)
+	}
+	if config.Pipeline.AgeSource != chainstream.AgeFromBlockTime {
+		t.Errorf("AgeSource = %v, expected %v", config.Pipeline.AgeSource, chainstream.AgeFromBlockTime)
+	}
+}
+
+func TestMergeFile(t *testing.T) {
+	config := chainstream.NewConfig("wss://example.com")
+	config.Pipeline.MaxNotificationAge = chainstream.Duration(time.Second)
+
+	path := writeConfig(t, `{"conn": {"wssApiEndpoint": "wss://override.example.com"}}`)
+	if err := config.MergeFile(path); err != nil {
+		t.Fatalf("MergeFile() error: %v", err)
+	}
+	if config.Conn.WssApiEndpoint != "wss://override.example.com" {
+		t.Errorf("WssApiEndpoint = %q, expected override", config.Conn.WssApiEndpoint)
+	}
+	if time.Duration(config.Pipeline.MaxNotificationAge) != time.Second {
+		t.Errorf("MaxNotificationAge was reset to %v", time.Duration(config.Pipeline.MaxNotificationAge))
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	subscription := func(name string, commitment chainstream.Commitment) chainstream.SubscriptionConfig {
+		return chainstream.SubscriptionConfig{Name: name, Params: chainstream.TransactionSubscribeParams{Network: "solana-mainnet", Filter: chainstream.TransactionFilter{Commitment: commitment}}}
+	}
+	tests := []struct {
+		name   string
+		modify func(config *chainstream.Config)
+		errors []string
+	}{
+		{name: "valid", modify: func(*chainstream.Config) {}},
+		{
+			name:   "endpoint scheme",
+			modify: func(config *chainstream.Config) { config.Conn.WssApiEndpoint = "https://example.com" },
+			errors: []string{`conn: wssApiEndpoint must use ws or wss scheme, got "https"`},
+		},
+		{
+			name:   "negative age",
+			modify: func(config *chainstream.Config) { config.Pipeline.MaxNotificationAge = -1 },
+			errors: []string{"pipeline: maxNotificationAge and dedupTTL must not be negative"},
+		},
+		{
+			name: "unknown commitment",
+			modify: func(config *chainstream.Config) {
+				config.Subscriptions = append(config.Subscriptions, subscription("b", chainstream.Commitment(7)))
+			},
+			errors: []string{"subscriptions[1]: params.filter.commitment: unknown level 7"},
+		},
+		{
+			name: "duplicate name",
+			modify: func(config *chainstream.Config) {
+				config.Subscriptions = append(config.Subscriptions, subscription("a", chainstream.CommitmentFinalized))
+			},
+			errors: []string{`subscriptions[1]: duplicate name "a"`},
+		},
+		{
+			name: "all at once",
+			modify: func(config *chainstream.Config) {
+				config.Conn.WssApiEndpoint = "https://example.com"
+				config.Pipeline.MaxNotificationAge = -1
+				config.Subscriptions = append(config.Subscriptions, subscription("a", chainstream.Commitment(7)))
+			},
+			errors: []string{
+				"conn: wssApiEndpoint must use ws or wss scheme",
+				"pipeline: maxNotificationAge and dedupTTL must not be negative",
+				"subscriptions[1]: params.filter.commitment: unknown level 7",
+				`subscriptions[1]: duplicate name "a"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := chainstream.NewConfig("wss://example.com")
+			config.Subscriptions = []chainstream.SubscriptionConfig{subscription("a", chainstream.CommitmentConfirmed)}
+			tt.modify(config)
+
+			err := config.Validate()
+			if len(tt.errors) == 0 {
+				if err != nil {
+					t.Errorf("Validate() error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() succeeded, expected %q", tt.errors)
+			}
+			// errors.Join puts every error on its own line.
+			if n := strings.Count(err.Error(), "\n") + 1; n != len(tt.errors) {
+				t.Errorf("Validate() error:\n%v\nexpected %d errors, got %d", err, len(tt.errors), n)
+			}
+			for _, expected := range tt.errors {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("Validate() error:\n%v\nexpected it to contain %q", err, expected)
+				}
+			}
+		})
+	}
+}
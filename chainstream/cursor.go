@@ -0,0 +1,99 @@
+package chainstream
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Cursor is the position of a transaction in the chain: its slot, its index in the block
+// and its signature. Like a Kafka offset, the cursor of the last handled notification is
+// saved, e.g. with a store.Store, and a later subscription starts after it with
+// WithCursor. Its text form is slot:index:signature.
+type Cursor struct {
+	Slot      uint64 `json:"slot"`
+	Index     int    `json:"index"`
+	Signature string `json:"signature"`
+}
+
+// Cursor returns the position of the notification. Notifications received live and
+// backfilled from blocks carry their index in the block; others, e.g. fetched with
+// getTransaction, have index 0.
+func (t *TransactionNotification) Cursor() Cursor {
+	return Cursor{Slot: t.Slot(), Index: t.Params.Result.Context.Index, Signature: t.Signature()}
+}
+
+// ParseCursor parses the text form of a cursor, slot:index:signature.
+func ParseCursor(s string) (Cursor, error) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 {
+		return Cursor{}, fmt.Errorf("invalid cursor %q, expected slot:index:signature", s)
+	}
+	slot, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor slot: %w", err)
+	}
+	index, err := strconv.Atoi(parts[1])
+	if err != nil || index < 0 {
+		return Cursor{}, fmt.Errorf("invalid cursor index %q", parts[1])
+	}
+	return Cursor{Slot: slot, Index: index, Signature: parts[2]}, nil
+}
+
+// String returns the text form of the cursor, empty for the zero cursor.
+func (c Cursor) String() string {
+	if c.IsZero() {
+		return ""
+	}
+	return strconv.FormatUint(c.Slot, 10) + ":" + strconv.Itoa(c.Index) + ":" + c.Signature
+}
+
+// MarshalText encodes the cursor in its text form.
+func (c Cursor) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText parses the text form of a cursor. Empty text is the zero cursor.
+func (c *Cursor) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*c = Cursor{}
+		return nil
+	}
+	cursor, err := ParseCursor(string(text))
+	if err != nil {
+		return err
+	}
+	*c = cursor
+	return nil
+}
+
+// IsZero reports whether the cursor is unset.
+func (c Cursor) IsZero() bool {
+	return c == Cursor{}
+}
+
+// Covers reports whether the notification is at or before the cursor, and so was handled
+// by a consumer that saved the cursor: it is of an earlier slot, or of the slot of the
+// cursor with the signature of the cursor or an index up to the cursor's. Index 0 also
+// stands for an unknown index, e.g. of notifications fetched with getTransaction or from
+// enhanced providers, so in the slot of the cursor indexes are only compared when both are
+// positive: the other notifications of the slot are delivered again rather than dropped
+// unhandled, at worst the first of its block.
+func (c Cursor) Covers(notification *TransactionNotification) bool {
+	position := notification.Cursor()
+	switch {
+	case position.Slot != c.Slot:
+		return position.Slot < c.Slot
+	case position.Signature == c.Signature:
+		return true
+	}
+	return position.Index > 0 && position.Index <= c.Index
+}
+
+// WithCursor starts the subscription after the cursor: the transactions of the slots
+// from the cursor to the first live notification are loaded with Hooks.Backfill, which
+// is required, and every notification the cursor covers is dropped. The gap is bounded
+// by Pipeline.MaxGapSlots like the gaps of reconnects. The zero cursor starts live.
+func WithCursor(cursor Cursor) SubscribeOption {
+	return func(o *subscribeOptions) { o.cursor = cursor }
+}
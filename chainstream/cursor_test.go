@@ -0,0 +1,124 @@
+package chainstream_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+// indexed returns a notification of the slot at the index in its block.
+func indexed(slot uint64, index int) *chainstream.TransactionNotification {
+	n := notification(slot)
+	n.Params.Result.Context.Index = index
+	n.Params.Result.Context.Signature = fmt.Sprintf("sig-%d-%d", slot, index)
+	return n
+}
+
+func TestWithCursor(t *testing.T) {
+	srv := newFakeServer(t, sendNotifications(indexed(12, 0), indexed(12, 1)))
+
+	var gaps []chainstream.Gap
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Hooks.Backfill = func(_ context.Context, gap chainstream.Gap, do func(*chainstream.TransactionNotification) error) error {
+		gaps = append(gaps, gap)
+		for _, n := range []*chainstream.TransactionNotification{indexed(10, 0), indexed(10, 1), indexed(10, 2), indexed(11, 0), indexed(12, 0)} {
+			if err := do(n); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var delivered []string
+	cursor := chainstream.Cursor{Slot: 10, Index: 1, Signature: "sig-10-1"}
+	err := client.TransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) {
+		delivered = append(delivered, n.Cursor().String())
+		if n.Cursor().Index == 1 {
+			cancel()
+		}
+	}, chainstream.WithCursor(cursor))
+	if err != nil {
+		t.Fatalf("TransactionsNotifications() error: %v", err)
+	}
+
+	if len(gaps) != 1 || gaps[0].FromSlot != 10 || gaps[0].ToSlot != 12 {
+		t.Errorf("gaps = %+v, expected slots 10 to 12", gaps)
+	}
+	// Index 0 may be unknown: 10:0 is delivered again rather than risk dropping it.
+	expected := []string{"10:0:sig-10-0", "10:2:sig-10-2", "11:0:sig-11-0", "12:0:sig-12-0", "12:1:sig-12-1"}
+	if !reflect.DeepEqual(delivered, expected) {
+		t.Errorf("delivered %q, expected %q", delivered, expected)
+	}
+}
+
+func TestCursorCoversWithoutIndexes(t *testing.T) {
+	// unindexed returns a notification of the slot fetched without its index.
+	unindexed := func(slot uint64, signature string) *chainstream.TransactionNotification {
+		n := notification(slot)
+		n.Params.Result.Context.Signature = signature
+		return n
+	}
+	tests := []struct {
+		cursor       chainstream.Cursor
+		notification *chainstream.TransactionNotification
+		covers       bool
+	}{
+		{chainstream.Cursor{Slot: 10, Signature: "b"}, unindexed(9, "a"), true},
+		{chainstream.Cursor{Slot: 10, Signature: "b"}, unindexed(10, "a"), false},
+		{chainstream.Cursor{Slot: 10, Signature: "b"}, unindexed(10, "b"), true},
+		{chainstream.Cursor{Slot: 10, Signature: "b"}, unindexed(10, "c"), false},
+		{chainstream.Cursor{Slot: 10, Signature: "b"}, indexed(10, 3), false},
+		{chainstream.Cursor{Slot: 10, Index: 5, Signature: "b"}, unindexed(10, "a"), false},
+		{chainstream.Cursor{Slot: 10, Index: 5, Signature: "b"}, indexed(10, 3), true},
+		{chainstream.Cursor{Slot: 10, Index: 5, Signature: "b"}, indexed(10, 6), false},
+		{chainstream.Cursor{Slot: 10, Index: 5, Signature: "b"}, unindexed(11, "a"), false},
+	}
+	for _, tt := range tests {
+		if covers := tt.cursor.Covers(tt.notification); covers != tt.covers {
+			t.Errorf("Cursor %q covers %q = %t, expected %t", tt.cursor, tt.notification.Cursor(), covers, tt.covers)
+		}
+	}
+}
+
+func TestWithCursorRequiresBackfill(t *testing.T) {
+	client := chainstream.NewClient(chainstream.NewConfig("wss://example.com"))
+	err := client.TransactionsNotifications(context.Background(), chainstream.FirehoseNoVotes(), func(*chainstream.TransactionNotification) {},
+		chainstream.WithCursor(chainstream.Cursor{Slot: 10}))
+	if err == nil {
+		t.Error("TransactionsNotifications() with a cursor and no backfill succeeded")
+	}
+}
+
+func TestCursorText(t *testing.T) {
+	cursor := indexed(42, 7).Cursor()
+	data, err := json.Marshal(struct {
+		Cursor chainstream.Cursor `json:"cursor"`
+	}{cursor})
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	if string(data) != `{"cursor":"42:7:sig-42-7"}` {
+		t.Errorf("Marshal() = %s", data)
+	}
+	parsed, err := chainstream.ParseCursor(cursor.String())
+	if err != nil || parsed != cursor {
+		t.Errorf("ParseCursor(%q) = %+v, %v", cursor, parsed, err)
+	}
+	for _, s := range []string{"42", "x:1:sig", "42:-1:sig", "42:x:sig"} {
+		if _, err := chainstream.ParseCursor(s); err == nil {
+			t.Errorf("ParseCursor(%q) succeeded", s)
+		}
+	}
+	if !(chainstream.Cursor{}).IsZero() || cursor.IsZero() {
+		t.Error("IsZero() is wrong")
+	}
+}
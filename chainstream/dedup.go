@@ -0,0 +1,100 @@
+package chainstream
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/internal/sigset"
+)
+
+// dedupKeyPrefix namespaces signatures in Config.Store.
+const dedupKeyPrefix = "chainstream/dedup/"
+
+// signatureSet is the set of recent signatures of a subscription.
+type signatureSet struct {
+	*sigset.Set
+	// namespace prefixes the signatures of the subscription in Config.Store, see
+	// dedupNamespace.
+	namespace string
+}
+
+func newSignatureSet(capacity int) *signatureSet {
+	return &signatureSet{Set: sigset.New(capacity)}
+}
+
+// dedupNamespace returns the namespace of the signatures of the subscription in
+// Config.Store: Pipeline.DedupNamespace, or a hash of the method and params of the
+// request.
+func (c *C) dedupNamespace(request *JSONRPCRequest) string {
+	if namespace := c.config.Pipeline.DedupNamespace; namespace != "" {
+		return namespace
+	}
+	params, _ := json.Marshal(request.Params)
+	sum := sha256.Sum256(append([]byte(request.Method+"\x00"), params...))
+	return hex.EncodeToString(sum[:8])
+}
+
+// dedupKey returns the key of the signature in Config.Store.
+func dedupKey(namespace, signature string) string {
+	return dedupKeyPrefix + namespace + "/" + signature
+}
+
+// firstSeen reports whether the signature is delivered for the first time, checking the
+// recent signatures and then the signatures of the namespace in Config.Store when it is
+// set. Store errors let the notification through: a duplicate delivery is preferred to a
+// lost one.
+func (c *C) firstSeen(ctx context.Context, seen *signatureSet, signature string) bool {
+	if !seen.Add(signature) {
+		return false
+	}
+	if c.config.Store == nil || signature == "" {
+		return true
+	}
+	if _, err := c.config.Store.Get(ctx, dedupKey(seen.namespace, signature)); err == nil {
+		return false
+	}
+	return true
+}
+
+// remember wraps do to record the signatures it handles successfully in Config.Store, so
+// that a notification that failed, was dead-lettered or was lost in a crash is delivered
+// again after a restart.
+func (c *C) remember(ctx context.Context, namespace string, do HandlerFunc) HandlerFunc {
+	if c.config.Store == nil {
+		return do
+	}
+	return func(notification *TransactionNotification) error {
+		if err := do(notification); err != nil {
+			return err
+		}
+		c.remembered(ctx, namespace, notification)
+		return nil
+	}
+}
+
+// rememberBatch is remember for batch handlers.
+func (c *C) rememberBatch(ctx context.Context, namespace string, do BatchHandlerFunc) BatchHandlerFunc {
+	if c.config.Store == nil {
+		return do
+	}
+	return func(batch []*TransactionNotification) error {
+		if err := do(batch); err != nil {
+			return err
+		}
+		for _, notification := range batch {
+			c.remembered(ctx, namespace, notification)
+		}
+		return nil
+	}
+}
+
+// remembered records the signature of a notification handled successfully in
+// Config.Store. Errors are ignored: the notification may be delivered again.
+func (c *C) remembered(ctx context.Context, namespace string, notification *TransactionNotification) {
+	if signature := notification.Signature(); signature != "" {
+		_ = c.config.Store.Set(ctx, dedupKey(namespace, signature), nil, time.Duration(c.config.Pipeline.DedupTTL))
+	}
+}
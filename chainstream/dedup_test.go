@@ -0,0 +1,96 @@
+package chainstream_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/store"
+)
+
+func TestDedupStore(t *testing.T) {
+	shared := store.NewMemory(nil)
+
+	run := func(last uint64, slots ...uint64) ([]uint64, chainstream.Stats) {
+		srv := newFakeServer(t, sendSlots(slots...))
+		config := chainstream.NewConfig(srv.endpoint())
+		config.Store = shared
+		client := chainstream.NewClient(config)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var delivered []uint64
+		err := client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) error {
+			delivered = append(delivered, n.Slot())
+			if n.Slot() == last {
+				cancel()
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+		}
+		return delivered, client.Stats()
+	}
+
+	if delivered, _ := run(3, 1, 2, 3); len(delivered) != 3 {
+		t.Fatalf("first run delivered %v, expected 3 notifications", delivered)
+	}
+	// A restarted client must not deliver what the previous one already did.
+	delivered, stats := run(5, 2, 3, 4, 5)
+	if len(delivered) != 2 || delivered[0] != 4 || delivered[1] != 5 {
+		t.Errorf("second run delivered %v, expected [4 5]", delivered)
+	}
+	if stats.Duplicates != 2 {
+		t.Errorf("Duplicates = %d, expected 2", stats.Duplicates)
+	}
+}
+
+func TestDedupStoreNamespaces(t *testing.T) {
+	shared := store.NewMemory(nil)
+
+	// run delivers the slots to a subscription of the namespace until the last slot is
+	// handled, failing permanently on the failing slot, and returns the slots handled
+	// successfully.
+	run := func(namespace string, failing, last uint64, slots ...uint64) []uint64 {
+		srv := newFakeServer(t, sendSlots(slots...))
+		config := chainstream.NewConfig(srv.endpoint())
+		config.Store = shared
+		config.Pipeline.DedupNamespace = namespace
+		client := chainstream.NewClient(config)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		var delivered []uint64
+		err := client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) error {
+			if n.Slot() == last {
+				defer cancel()
+			}
+			if n.Slot() == failing {
+				return chainstream.Permanent(errors.New("sink down"))
+			}
+			delivered = append(delivered, n.Slot())
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+		}
+		return delivered
+	}
+
+	if delivered := run("sniper", 2, 3, 1, 2, 3); !reflect.DeepEqual(delivered, []uint64{1, 3}) {
+		t.Fatalf("first run delivered %v, expected [1 3]", delivered)
+	}
+	// Another subscription sharing the store is not suppressed.
+	if delivered := run("indexer", 0, 3, 1, 2, 3); !reflect.DeepEqual(delivered, []uint64{1, 2, 3}) {
+		t.Errorf("other namespace delivered %v, expected [1 2 3]", delivered)
+	}
+	// The notification that failed is delivered again after a restart.
+	if delivered := run("sniper", 0, 2, 1, 2, 3); !reflect.DeepEqual(delivered, []uint64{2}) {
+		t.Errorf("restart delivered %v, expected the failed slot 2", delivered)
+	}
+}
@@ -0,0 +1,80 @@
+package chainstream
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// dialer opens connections as configured by ConnConfig.Resolve, DNSServer and DialContext.
+type dialer struct {
+	resolve  map[string][]string
+	resolver *net.Resolver
+	dial     func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+func newDialer(c *ConnConfig) *dialer {
+	d := &dialer{resolve: c.Resolve, dial: c.DialContext}
+	if d.dial == nil {
+		d.dial = (&net.Dialer{}).DialContext
+	}
+	if server := c.DNSServer; server != "" {
+		dial := d.dial
+		d.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dial(ctx, network, server)
+			},
+		}
+	}
+	return d
+}
+
+// DialContext connects to the address: to the pinned addresses of its host in turn if it
+// has any, or else to the addresses resolved by the DNS server if one is configured.
+func (d *dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, ok := d.resolve[host]
+	if !ok && d.resolver != nil && net.ParseIP(host) == nil {
+		if ips, err = d.resolver.LookupHost(ctx, host); err != nil {
+			return nil, err
+		}
+		ok = true
+	}
+	if !ok {
+		return d.dial(ctx, network, addr)
+	}
+	return dialFirst(ctx, d.dial, network, ips, port)
+}
+
+// dialFirst returns the first connection opened to one of the IP addresses.
+func dialFirst(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), network string, ips []string, port string) (net.Conn, error) {
+	var errs []error
+	for _, ip := range ips {
+		conn, err := dial(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// HTTPClient returns an HTTP client connecting as configured by Resolve, DNSServer and
+// DialContext, nil when none is set, e.g. to share them with the rpc client. It has no
+// timeout, as the WebSocket handshake requires.
+func (c *ConnConfig) HTTPClient() *http.Client {
+	if len(c.Resolve) == 0 && c.DNSServer == "" && c.DialContext == nil {
+		return nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = newDialer(c).DialContext
+	return &http.Client{Transport: transport}
+}
@@ -0,0 +1,52 @@
+package chainstream_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestResolvePinsEndpointHost(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1))
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+
+	var dialed atomic.Value
+	config := chainstream.NewConfig("ws://chainstream.invalid:" + port)
+	config.Conn.Resolve = map[string][]string{"chainstream.invalid": {"127.0.0.2", "127.0.0.1"}}
+	config.Conn.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if strings.HasPrefix(addr, "127.0.0.2:") {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: net.UnknownNetworkError("unreachable")}
+		}
+		dialed.Store(addr)
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}
+	if err := config.Conn.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := client.TransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(*chainstream.TransactionNotification) {
+		cancel()
+	})
+	if err != nil {
+		t.Fatalf("TransactionsNotifications() error: %v", err)
+	}
+	if got := dialed.Load(); got != "127.0.0.1:"+port {
+		t.Errorf("dialed %v, expected the second pinned address", got)
+	}
+}
+
+func TestResolveValidation(t *testing.T) {
+	config := chainstream.NewConfig("wss://chainstream.api.syndica.io")
+	config.Conn.Resolve = map[string][]string{"chainstream.api.syndica.io": {"chainstream.local"}}
+	if err := config.Conn.Validate(); err == nil || !strings.Contains(err.Error(), "not an IP address") {
+		t.Errorf("Validate() error = %v, expected an invalid address error", err)
+	}
+}
@@ -0,0 +1,106 @@
+package chainstream
+
+import "time"
+
+// DowngradeConfig controls switching a firehose subscription to a server-side filter when
+// the client cannot keep up with it.
+type DowngradeConfig struct {
+	// After is how long delivered notifications must lag behind by more than MaxLag before
+	// the subscription is downgraded. Zero disables downgrades.
+	After Duration `json:"after,omitempty"`
+	// MaxLag is the node time age above which a notification counts as delayed by backpressure.
+	MaxLag Duration `json:"maxLag,omitempty"`
+}
+
+// DowngradeEvent describes a firehose subscription replaced with a filtered one.
+type DowngradeEvent struct {
+	// Filter is the server-side filter now applied.
+	Filter AccountKeysFilter
+	// Lag is the age of the notification that triggered the downgrade.
+	Lag time.Duration
+	// Since is when notifications started lagging.
+	Since time.Time
+}
+
+// DowngradeFunc is called after a subscription has been downgraded.
+type DowngradeFunc func(event DowngradeEvent)
+
+// Match reports whether the transaction satisfies the filter the way the server applies it:
+// it mentions every key of All, at least one key of OneOf and no key of Exclude.
+func (f *AccountKeysFilter) Match(notification *TransactionNotification) bool {
+	keys := make(map[string]struct{})
+	for _, key := range notification.AccountKeys() {
+		keys[key] = struct{}{}
+	}
+	for _, key := range f.All {
+		if _, ok := keys[key]; !ok {
+			return false
+		}
+	}
+	for _, key := range f.Exclude {
+		if _, ok := keys[key]; ok {
+			return false
+		}
+	}
+	if len(f.OneOf) == 0 {
+		return true
+	}
+	for _, key := range f.OneOf {
+		if _, ok := keys[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// backpressure tracks how long delivered notifications have been lagging.
+type backpressure struct {
+	since time.Time
+}
+
+// downgradeRequest returns the request with Pipeline.AccountFilter applied server-side when
+// the request is a firehose, that is a transactionsSubscribe request without account keys.
+func (c *C) downgradeRequest(request *JSONRPCRequest) (*JSONRPCRequest, bool) {
+	filter := c.config.Pipeline.AccountFilter
+	if filter == nil || (len(filter.All) == 0 && len(filter.OneOf) == 0) {
+		return nil, false
+	}
+	params, ok := transactionParams(request)
+	if !ok || params.Filter.AccountKeys != nil {
+		return nil, false
+	}
+	keys := *filter
+	params.Filter.AccountKeys = &keys
+	downgraded := *request
+	downgraded.Params = params
+	return &downgraded, true
+}
+
+// transactionParams returns the params of a transactionsSubscribe request.
+func transactionParams(request *JSONRPCRequest) (TransactionSubscribeParams, bool) {
+	switch p := request.Params.(type) {
+	case TransactionSubscribeParams:
+		return p, true
+	case *TransactionSubscribeParams:
+		return *p, true
+	}
+	return TransactionSubscribeParams{}, false
+}
+
+// observe records the lag of a delivered notification and reports whether backpressure
+// has lasted long enough to downgrade.
+func (b *backpressure) observe(now time.Time, notification *TransactionNotification, config DowngradeConfig) (time.Duration, bool) {
+	nodeTime := notification.Params.Result.Context.NodeTime
+	if config.After <= 0 || nodeTime.IsZero() {
+		return 0, false
+	}
+	lag := now.Sub(nodeTime)
+	if lag <= time.Duration(config.MaxLag) {
+		b.since = time.Time{}
+		return lag, false
+	}
+	if b.since.IsZero() {
+		b.since = now
+	}
+	return lag, now.Sub(b.since) >= time.Duration(config.After)
+}
@@ -0,0 +1,145 @@
+package chainstream_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestFirehoseDowngrade(t *testing.T) {
+	const program = "6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"
+	withKey := func(slot uint64, lag time.Duration, keys ...string) *chainstream.TransactionNotification {
+		n := notification(slot)
+		n.Params.Result.Context.NodeTime = time.Now().Add(-lag)
+		n.Params.Result.Value.Transaction.Message.AccountKeys = keys
+		return n
+	}
+	firehose := sendNotifications(withKey(1, time.Hour, "wallet"), withKey(2, time.Hour, "wallet", program))
+	filtered := sendNotifications(withKey(3, 0, "wallet", program))
+	srv := newFakeServer(t, func(ctx context.Context, conn *websocket.Conn, n int) error {
+		if n == 1 {
+			return firehose(ctx, conn, n)
+		}
+		return filtered(ctx, conn, n)
+	})
+
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Pipeline.AccountFilter = &chainstream.AccountKeysFilter{OneOf: []string{program}}
+	config.Pipeline.Downgrade = chainstream.DowngradeConfig{After: 1, MaxLag: chainstream.Duration(time.Minute)}
+	var events []chainstream.DowngradeEvent
+	config.Hooks.Downgrade = func(e chainstream.DowngradeEvent) {
+		events = append(events, e)
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var delivered []uint64
+	err := client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) error {
+		delivered = append(delivered, n.Slot())
+		if n.Slot() == 3 {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+
+	if len(delivered) != 2 || delivered[0] != 2 || delivered[1] != 3 {
+		t.Errorf("delivered %v, expected [2 3]", delivered)
+	}
+	if len(events) != 1 || events[0].Lag < time.Minute {
+		t.Errorf("downgrade events = %+v, expected one", events)
+	}
+	params, _ := json.Marshal(srv.request(2).Params)
+	if !strings.Contains(string(params), `"oneOf":["`+program+`"]`) {
+		t.Errorf("second subscription params = %s, expected the account filter", params)
+	}
+	if stats := client.Stats(); stats.Downgrades != 1 || stats.Filtered != 1 {
+		t.Errorf("Stats() = %+v, expected one downgrade and one filtered notification", stats)
+	}
+}
+
+func TestFirehoseDowngradeRedundant(t *testing.T) {
+	const program = "6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"
+	withKey := func(slot uint64, lag time.Duration, keys ...string) *chainstream.TransactionNotification {
+		n := notification(slot)
+		n.Params.Result.Context.NodeTime = time.Now().Add(-lag)
+		n.Params.Result.Value.Transaction.Message.AccountKeys = keys
+		return n
+	}
+	firehose := sendNotifications(withKey(1, time.Hour, "wallet", program))
+	filtered := sendNotifications(withKey(2, 0, "wallet", program))
+	stream := func(ctx context.Context, conn *websocket.Conn, n int) error {
+		if n == 1 {
+			return firehose(ctx, conn, n)
+		}
+		return filtered(ctx, conn, n)
+	}
+	primary, mirror := newFakeServer(t, stream), newFakeServer(t, func(ctx context.Context, conn *websocket.Conn, n int) error {
+		// The mirror only delivers after the primary.
+		time.Sleep(50 * time.Millisecond)
+		return stream(ctx, conn, n)
+	})
+
+	config := chainstream.NewConfig(primary.endpoint())
+	config.Conn.Endpoints = []chainstream.EndpointConfig{{URL: mirror.endpoint()}}
+	config.Conn.Redundant = true
+	config.Pipeline.AccountFilter = &chainstream.AccountKeysFilter{OneOf: []string{program}}
+	config.Pipeline.Downgrade = chainstream.DowngradeConfig{After: 1, MaxLag: chainstream.Duration(time.Minute)}
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) error {
+		if n.Slot() == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+
+	if mirror.connections.Load() != 2 {
+		t.Fatalf("mirror connected %d times, expected a filtered subscription", mirror.connections.Load())
+	}
+	params, _ := json.Marshal(mirror.request(2).Params)
+	if !strings.Contains(string(params), `"oneOf":["`+program+`"]`) {
+		t.Errorf("second mirror subscription params = %s, expected the account filter", params)
+	}
+}
+
+func TestAccountKeysFilterMatch(t *testing.T) {
+	n := notification(1)
+	n.Params.Result.Value.Transaction.Message.AccountKeys = []string{"a", "b"}
+
+	tests := []struct {
+		name     string
+		filter   chainstream.AccountKeysFilter
+		expected bool
+	}{
+		{"empty", chainstream.AccountKeysFilter{}, true},
+		{"one of", chainstream.AccountKeysFilter{OneOf: []string{"x", "b"}}, true},
+		{"none of", chainstream.AccountKeysFilter{OneOf: []string{"x"}}, false},
+		{"all", chainstream.AccountKeysFilter{All: []string{"a", "b"}}, true},
+		{"not all", chainstream.AccountKeysFilter{All: []string{"a", "x"}}, false},
+		{"excluded", chainstream.AccountKeysFilter{OneOf: []string{"a"}, Exclude: []string{"b"}}, false},
+	}
+	for _, test := range tests {
+		if got := test.filter.Match(n); got != test.expected {
+			t.Errorf("%s: Match() = %v, expected %v", test.name, got, test.expected)
+		}
+	}
+}
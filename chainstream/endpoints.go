@@ -0,0 +1,244 @@
+package chainstream
+
+import (
+	"math"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// endpointAlpha is the weight of a new sample in the moving averages of an endpoint.
+	endpointAlpha = 0.2
+	// endpointFailureHalfLife is the time after which a connection failure counts half.
+	endpointFailureHalfLife = 10 * time.Minute
+	// endpointFailurePenalty is the score added per recent connection failure.
+	endpointFailurePenalty = time.Second
+	// endpointHysteresis is how much better another endpoint must score before the client
+	// moves away from a healthy one.
+	endpointHysteresis = 0.25
+)
+
+// EndpointConfig describes an additional endpoint the client may connect to.
+type EndpointConfig struct {
+	URL string `json:"url"`
+	// Priority orders endpoints for failover: the client connects to the healthy endpoints
+	// with the lowest priority and picks among them by score. The primary endpoint,
+	// ConnConfig.WssApiEndpoint, has priority 0.
+	Priority int `json:"priority,omitempty"`
+	// Headers are sent on the WebSocket handshake in addition to ConnConfig.Headers,
+	// replacing headers of the same name.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// EndpointScore is the observed quality of an endpoint. Lower scores are better.
+type EndpointScore struct {
+	URL string `json:"url"`
+	// RTT is the moving average of ping round-trip times.
+	RTT time.Duration `json:"rtt"`
+	// Lag is the moving average of the delay between a node observing a transaction and
+	// the client receiving it.
+	Lag time.Duration `json:"lag"`
+	// Failures is the number of recent connection failures, decaying over time.
+	Failures float64 `json:"failures"`
+	// Score combines RTT, Lag and Failures.
+	Score time.Duration `json:"score"`
+	// Connections is the number of sessions opened to the endpoint.
+	Connections int `json:"connections"`
+	// Priority is the failover priority of the endpoint, see EndpointConfig.
+	Priority int `json:"priority"`
+	// Healthy is false for ConnConfig.FailbackAfter after a connection failure.
+	Healthy bool `json:"healthy"`
+}
+
+// endpoint tracks the quality of a single endpoint.
+type endpoint struct {
+	url         string
+	header      http.Header
+	priority    int
+	rtt         float64
+	lag         float64
+	failures    float64
+	failedAt    time.Time
+	connections int
+}
+
+// endpointSet scores the configured endpoints and picks the one to connect to.
+type endpointSet struct {
+	mu        sync.Mutex
+	endpoints []*endpoint
+	current   *endpoint
+	// failbackAfter is how long an endpoint is unhealthy after a failure.
+	failbackAfter time.Duration
+}
+
+func newEndpointSet(config *ConnConfig) *endpointSet {
+	set := &endpointSet{failbackAfter: time.Duration(config.FailbackAfter)}
+	if set.failbackAfter <= 0 {
+		set.failbackAfter = time.Duration(DefaultFailbackAfter)
+	}
+	set.endpoints = append(set.endpoints, &endpoint{url: config.expand(config.WssApiEndpoint), header: handshakeHeader(config.Headers, nil)})
+	for _, e := range config.Endpoints {
+		set.endpoints = append(set.endpoints, &endpoint{url: config.expand(e.URL), header: handshakeHeader(config.Headers, e.Headers), priority: e.Priority})
+	}
+	return set
+}
+
+// handshakeHeader merges the connection headers with the headers of an endpoint.
+func handshakeHeader(conn, endpoint map[string]string) http.Header {
+	if len(conn)+len(endpoint) == 0 {
+		return nil
+	}
+	header := make(http.Header, len(conn)+len(endpoint))
+	for _, headers := range []map[string]string{conn, endpoint} {
+		for name, value := range headers {
+			header.Set(name, value)
+		}
+	}
+	return header
+}
+
+// candidates returns the healthy endpoints of the lowest priority, or all endpoints when
+// none is healthy. The excluded endpoints are left out.
+func (s *endpointSet) candidates(now time.Time, excluded []*endpoint) []*endpoint {
+	var all, tier []*endpoint
+	for _, e := range s.endpoints {
+		if slices.Contains(excluded, e) {
+			continue
+		}
+		all = append(all, e)
+		switch {
+		case !e.healthy(now, s.failbackAfter):
+		case len(tier) == 0 || e.priority < tier[0].priority:
+			tier = []*endpoint{e}
+		case e.priority == tier[0].priority:
+			tier = append(tier, e)
+		}
+	}
+	if len(tier) == 0 {
+		return all
+	}
+	return tier
+}
+
+// pick returns the endpoint for the next connection: the best scoring one among the
+// healthy endpoints of the lowest priority, other than the tried ones. The current
+// endpoint is kept unless it has just failed, it left that group or another endpoint
+// scores better by more than the hysteresis. It returns nil when every endpoint was tried.
+func (s *endpointSet) pick(now time.Time, failed bool, tried []*endpoint) *endpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	candidates := s.candidates(now, tried)
+	if len(candidates) == 0 {
+		return nil
+	}
+	best := candidates[0]
+	for _, e := range candidates[1:] {
+		if e.score(now) < best.score(now) {
+			best = e
+		}
+	}
+	if s.current != nil && !failed && best != s.current &&
+		best.priority == s.current.priority && s.current.healthy(now, s.failbackAfter) &&
+		best.score(now) >= s.current.score(now)*(1-endpointHysteresis) {
+		best = s.current
+	}
+	s.current = best
+	best.connections++
+	return best
+}
+
+// others returns the healthy endpoints other than the used ones, of any priority, for the
+// mirror sessions of redundant mode. A connection to each is counted.
+func (s *endpointSet) others(now time.Time, used []*endpoint) []*endpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var others []*endpoint
+	for _, e := range s.endpoints {
+		if e.healthy(now, s.failbackAfter) && !slices.Contains(used, e) {
+			e.connections++
+			others = append(others, e)
+		}
+	}
+	return others
+}
+
+// failback reports whether an endpoint of a lower priority than the current one is
+// healthy again, so the client should move back to it.
+func (s *endpointSet) failback(current *endpoint, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.candidates(now, nil)[0].priority < current.priority
+}
+
+func (s *endpointSet) observeRTT(e *endpoint, rtt time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.rtt = average(e.rtt, float64(rtt))
+}
+
+func (s *endpointSet) observeLag(e *endpoint, lag time.Duration) {
+	if lag < 0 {
+		lag = 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.lag = average(e.lag, float64(lag))
+}
+
+func (s *endpointSet) observeFailure(e *endpoint, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.failures = e.decayedFailures(now) + 1
+	e.failedAt = now
+}
+
+func (s *endpointSet) scores(now time.Time) []EndpointScore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scores := make([]EndpointScore, len(s.endpoints))
+	for i, e := range s.endpoints {
+		scores[i] = EndpointScore{
+			URL:         e.url,
+			RTT:         time.Duration(e.rtt),
+			Lag:         time.Duration(e.lag),
+			Failures:    e.decayedFailures(now),
+			Score:       time.Duration(e.score(now)),
+			Connections: e.connections,
+			Priority:    e.priority,
+			Healthy:     e.healthy(now, s.failbackAfter),
+		}
+	}
+	return scores
+}
+
+func (e *endpoint) decayedFailures(now time.Time) float64 {
+	if e.failures == 0 {
+		return 0
+	}
+	elapsed := now.Sub(e.failedAt)
+	return e.failures * math.Exp2(-float64(elapsed)/float64(endpointFailureHalfLife))
+}
+
+func (e *endpoint) healthy(now time.Time, failbackAfter time.Duration) bool {
+	return e.failedAt.IsZero() || now.Sub(e.failedAt) >= failbackAfter
+}
+
+func (e *endpoint) score(now time.Time) float64 {
+	return e.rtt + e.lag + e.decayedFailures(now)*float64(endpointFailurePenalty)
+}
+
+func average(current, sample float64) float64 {
+	if current == 0 {
+		return sample
+	}
+	return current + endpointAlpha*(sample-current)
+}
+
+// Endpoints returns the scores of the configured endpoints, the primary one first.
+func (c *C) Endpoints() []EndpointScore {
+	return c.endpointSet().scores(c.clock().Now())
+}
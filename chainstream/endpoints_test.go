@@ -0,0 +1,146 @@
+package chainstream_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+func TestEndpointSelection(t *testing.T) {
+	// The primary drops the first connection right after subscribing.
+	primary := newFakeServer(t, func(ctx context.Context, conn *websocket.Conn, n int) error {
+		if n == 1 {
+			return nil
+		}
+		return sendSlots(2)(ctx, conn, n)
+	})
+	backup := newFakeServer(t, sendSlots(1))
+	fake := clock.NewFake(time.Now())
+
+	config := chainstream.NewConfig(primary.endpoint())
+	config.Conn.Endpoints = []chainstream.EndpointConfig{{URL: backup.endpoint()}}
+	config.Clock = fake
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go func() {
+		// The ping ticker and the reconnect delay.
+		fake.BlockUntil(2)
+		fake.Advance(time.Second)
+	}()
+
+	var delivered []uint64
+	err := client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) error {
+		delivered = append(delivered, n.Slot())
+		cancel()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+
+	if len(delivered) != 1 || delivered[0] != 1 {
+		t.Errorf("delivered %v, expected slot 1 from the backup endpoint", delivered)
+	}
+	scores := client.Endpoints()
+	if len(scores) != 2 || scores[0].Failures < 0.9 || scores[1].Connections != 1 || scores[0].Score <= scores[1].Score {
+		t.Errorf("Endpoints() = %+v, expected the failed primary to score worse", scores)
+	}
+}
+
+func TestEndpointFailoverOnConnect(t *testing.T) {
+	// The primary refuses connections.
+	primary := newFakeServer(t, sendSlots(2))
+	primary.Close()
+	backup := newFakeServer(t, sendSlots(1))
+
+	config := chainstream.NewConfig(primary.endpoint())
+	config.Conn.Endpoints = []chainstream.EndpointConfig{{URL: backup.endpoint(), Priority: 1}}
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var delivered []uint64
+	err := client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) error {
+		delivered = append(delivered, n.Slot())
+		cancel()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+	if len(delivered) != 1 || delivered[0] != 1 {
+		t.Errorf("delivered %v, expected slot 1 from the backup endpoint", delivered)
+	}
+	if scores := client.Endpoints(); scores[0].Healthy || scores[0].Failures < 0.9 || scores[1].Connections != 1 {
+		t.Errorf("Endpoints() = %+v, expected the refusing primary to have failed", scores)
+	}
+
+	// Every endpoint failing fails the subscription.
+	backup.Close()
+	client = chainstream.NewClient(config)
+	if err = client.HandleTransactionsNotifications(context.Background(), chainstream.FirehoseNoVotes(), func(*chainstream.TransactionNotification) error { return nil }); err == nil {
+		t.Error("HandleTransactionsNotifications() succeeded with every endpoint refusing connections")
+	}
+}
+
+func TestEndpointFailback(t *testing.T) {
+	primary := newFakeServer(t, func(ctx context.Context, conn *websocket.Conn, n int) error {
+		if n == 1 {
+			return nil
+		}
+		return sendSlots(2)(ctx, conn, n)
+	})
+	backup := newFakeServer(t, sendSlots(1))
+	fake := clock.NewFake(time.Now())
+
+	config := chainstream.NewConfig(primary.endpoint())
+	config.Conn.Endpoints = []chainstream.EndpointConfig{{URL: backup.endpoint(), Priority: 1}}
+	config.Conn.FailbackAfter = chainstream.Duration(30 * time.Second)
+	config.Clock = fake
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go func() {
+		fake.BlockUntil(2)
+		fake.Advance(time.Second)
+	}()
+
+	var delivered []uint64
+	err := client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) error {
+		delivered = append(delivered, n.Slot())
+		if n.Slot() == 1 {
+			// The next ping tick finds the primary healthy again.
+			fake.Advance(30 * time.Second)
+			return nil
+		}
+		cancel()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+
+	if len(delivered) != 2 || delivered[0] != 1 || delivered[1] != 2 {
+		t.Errorf("delivered %v, expected slot 1 from the backup and slot 2 from the primary", delivered)
+	}
+	if stats := client.Stats(); stats.Failbacks != 1 {
+		t.Errorf("Stats().Failbacks = %d, expected 1", stats.Failbacks)
+	}
+	scores := client.Endpoints()
+	if !scores[0].Healthy || scores[0].Connections != 2 || scores[1].Priority != 1 {
+		t.Errorf("Endpoints() = %+v", scores)
+	}
+}
@@ -0,0 +1,74 @@
+package chainstream
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Kinds of subscription rejections, matched with errors.Is against the errors returned
+// when a subscription cannot be opened. ErrInvalidParams is returned before sending params
+// that TransactionSubscribeParams.Validate rejects.
+var (
+	ErrFilterTooLarge = errors.New("chainstream: filter too large")
+	ErrInvalidNetwork = errors.New("chainstream: invalid network")
+	ErrInvalidParams  = errors.New("chainstream: invalid params")
+	ErrRateLimited    = errors.New("chainstream: rate limited")
+	ErrUnauthorized   = errors.New("chainstream: unauthorized")
+)
+
+// SubscribeError is a subscription rejected by the server. It wraps ErrFilterTooLarge,
+// ErrInvalidNetwork, ErrRateLimited or ErrUnauthorized when the rejection is recognized.
+type SubscribeError struct {
+	Method string
+	RPCError
+	kind error
+}
+
+func (e *SubscribeError) Error() string {
+	return fmt.Sprintf("%s error %d: %s", e.Method, e.Code, e.Message)
+}
+
+func (e *SubscribeError) Unwrap() error { return e.kind }
+
+// newSubscribeError classifies the rejection of the subscribe method by its message. Rate
+// limits and quotas are checked first, as their messages name the API key, then the size
+// of the filter; "token" only names a credential outside of a filter: filters match token
+// programs and token accounts.
+func newSubscribeError(method string, rpcErr RPCError) *SubscribeError {
+	e := &SubscribeError{Method: method, RPCError: rpcErr}
+	message := strings.ToLower(rpcErr.Message)
+	switch {
+	case containsAny(message, "rate limit", "rate-limit", "ratelimit", "too many requests", "quota", "throttl"):
+		e.kind = ErrRateLimited
+	case containsAny(message, "filter too large", "filter is too large", "filter size", "too many account", "too many token account", "too many keys", "too many addresses"),
+		containsAny(message, "too many", "too large", "exceed") && strings.Contains(message, "filter"):
+		e.kind = ErrFilterTooLarge
+	case containsAny(message, "unauthorized", "forbidden", "api key", "api-key", "auth token", "access token"):
+		e.kind = ErrUnauthorized
+	case containsAny(message, "invalid token", "expired token", "token expired", "missing token") && !containsAny(message, "filter", "account", "program"):
+		e.kind = ErrUnauthorized
+	case containsAny(message, "network"):
+		e.kind = ErrInvalidNetwork
+	}
+	return e
+}
+
+// handshakeError wraps a failed WebSocket handshake with ErrUnauthorized when the server
+// refused the credentials.
+func handshakeError(resp *http.Response, err error) error {
+	if resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+		return fmt.Errorf("%w: %s: %w", ErrUnauthorized, resp.Status, err)
+	}
+	return err
+}
+
+func containsAny(s string, substrs ...string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
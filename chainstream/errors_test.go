@@ -0,0 +1,96 @@
+package chainstream_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+// rejectingServer answers every subscription with the error.
+func rejectingServer(t *testing.T, rpcErr chainstream.RPCError) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.CloseNow()
+		var request chainstream.JSONRPCRequest
+		if err = wsjson.Read(r.Context(), conn, &request); err != nil {
+			return
+		}
+		_ = wsjson.Write(r.Context(), conn, chainstream.JSONRPCResponse{JSONRPC: "2.0", ID: request.ID, Error: &rpcErr})
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func TestSubscribeErrors(t *testing.T) {
+	tests := []struct {
+		message string
+		kind    error
+	}{
+		{"Too many account keys in filter: 300 > 256", chainstream.ErrFilterTooLarge},
+		{"Invalid network: solana-devnet", chainstream.ErrInvalidNetwork},
+		{"Unauthorized: invalid api key", chainstream.ErrUnauthorized},
+		{"Invalid token", chainstream.ErrUnauthorized},
+		{"Too many token accounts in filter", chainstream.ErrFilterTooLarge},
+		{"Filter too large: 1200 keys", chainstream.ErrFilterTooLarge},
+		{"Too many keys: 300 > 256", chainstream.ErrFilterTooLarge},
+		{"Filter exceeds 256 accounts", chainstream.ErrFilterTooLarge},
+		{"Rate limit exceeded for this API key", chainstream.ErrRateLimited},
+		{"Too many requests for this api key", chainstream.ErrRateLimited},
+		{"Monthly quota exceeded for key", chainstream.ErrRateLimited},
+		{"Subscription limit reached for account", nil},
+		{"Invalid token program in filter", nil},
+		{"Internal error", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			client := chainstream.NewClient(chainstream.NewConfig(rejectingServer(t, chainstream.RPCError{Code: -32602, Message: tt.message})))
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			err := client.TransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(*chainstream.TransactionNotification) {})
+			var subErr *chainstream.SubscribeError
+			if !errors.As(err, &subErr) {
+				t.Fatalf("TransactionsNotifications() error = %v, expected a SubscribeError", err)
+			}
+			if subErr.Method != "transactionsSubscribe" || subErr.Code != -32602 || subErr.Message != tt.message {
+				t.Errorf("SubscribeError = %+v", subErr)
+			}
+			if tt.kind != nil && !errors.Is(err, tt.kind) {
+				t.Errorf("error %v is not %v", err, tt.kind)
+			}
+			for _, kind := range []error{chainstream.ErrFilterTooLarge, chainstream.ErrInvalidNetwork, chainstream.ErrRateLimited, chainstream.ErrUnauthorized} {
+				if kind != tt.kind && errors.Is(err, kind) {
+					t.Errorf("error %v is %v", err, kind)
+				}
+			}
+		})
+	}
+}
+
+func TestHandshakeUnauthorized(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid api key", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	client := chainstream.NewClient(chainstream.NewConfig("ws" + strings.TrimPrefix(srv.URL, "http")))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := client.TransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(*chainstream.TransactionNotification) {})
+	if !errors.Is(err, chainstream.ErrUnauthorized) {
+		t.Errorf("TransactionsNotifications() error = %v, expected ErrUnauthorized", err)
+	}
+}
@@ -0,0 +1,125 @@
+package chainstream_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+// fakeServer is a ChainStream stand-in. Every connection is acknowledged and then
+// handed to stream, which writes notifications until it returns.
+type fakeServer struct {
+	*httptest.Server
+	connections atomic.Int32
+	active      atomic.Int32
+	// silent holds off reading after the subscription, so that pings are not answered
+	// until it is reset.
+	silent atomic.Bool
+
+	mu       sync.Mutex
+	requests []chainstream.JSONRPCRequest
+	headers  []http.Header
+}
+
+// header returns the handshake header of the n-th connection, starting from 1.
+func (s *fakeServer) header(n int) http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n > len(s.headers) {
+		return nil
+	}
+	return s.headers[n-1]
+}
+
+// request returns the subscribe request received on the n-th connection, starting from 1.
+func (s *fakeServer) request(n int) chainstream.JSONRPCRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n > len(s.requests) {
+		return chainstream.JSONRPCRequest{}
+	}
+	return s.requests[n-1]
+}
+
+func newFakeServer(t *testing.T, stream func(ctx context.Context, conn *websocket.Conn, n int) error) *fakeServer {
+	t.Helper()
+	srv := &fakeServer{}
+	srv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.CloseNow()
+		n := int(srv.connections.Add(1))
+		srv.active.Add(1)
+		defer srv.active.Add(-1)
+
+		var request chainstream.JSONRPCRequest
+		if err = wsjson.Read(r.Context(), conn, &request); err != nil {
+			return
+		}
+		srv.mu.Lock()
+		srv.requests = append(srv.requests, request)
+		srv.headers = append(srv.headers, r.Header.Clone())
+		srv.mu.Unlock()
+		ack := chainstream.JSONRPCResponse{JSONRPC: "2.0", ID: request.ID, Result: 1000 + n}
+		if err = wsjson.Write(r.Context(), conn, ack); err != nil {
+			return
+		}
+		for srv.silent.Load() && r.Context().Err() == nil {
+			time.Sleep(time.Millisecond)
+		}
+		ctx := conn.CloseRead(r.Context())
+		_ = stream(ctx, conn, n)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// endpoint returns the ws:// URL of the server.
+func (s *fakeServer) endpoint() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http")
+}
+
+// notification returns a minimal notification for the given slot.
+func notification(slot uint64) *chainstream.TransactionNotification {
+	var n chainstream.TransactionNotification
+	n.JSONRPC = "2.0"
+	n.Method = "transactionNotification"
+	n.Params.Result.Value.Slot = slot
+	n.Params.Result.Context.Signature = fmt.Sprintf("sig-%d", slot)
+	return &n
+}
+
+// sendSlots writes notifications for the given slots and keeps the connection open.
+func sendSlots(slots ...uint64) func(ctx context.Context, conn *websocket.Conn, n int) error {
+	notifications := make([]*chainstream.TransactionNotification, len(slots))
+	for i, slot := range slots {
+		notifications[i] = notification(slot)
+	}
+	return sendNotifications(notifications...)
+}
+
+// sendNotifications writes the notifications and keeps the connection open.
+func sendNotifications(notifications ...*chainstream.TransactionNotification) func(ctx context.Context, conn *websocket.Conn, n int) error {
+	return func(ctx context.Context, conn *websocket.Conn, _ int) error {
+		for _, n := range notifications {
+			if err := wsjson.Write(ctx, conn, n); err != nil {
+				return err
+			}
+		}
+		<-ctx.Done()
+		return nil
+	}
+}
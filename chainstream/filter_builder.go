@@ -0,0 +1,69 @@
+package chainstream
+
+// FilterBuilder builds a TransactionFilter step by step, e.g.
+//
+//	chainstream.NewFilter().ExcludeVotes().Commitment(chainstream.CommitmentConfirmed).OneOf(wallets...).Build()
+type FilterBuilder struct {
+	filter TransactionFilter
+}
+
+// NewFilter starts a filter matching every transaction at DefaultCommitment.
+func NewFilter() *FilterBuilder {
+	return &FilterBuilder{}
+}
+
+// ExcludeVotes drops vote transactions.
+func (b *FilterBuilder) ExcludeVotes() *FilterBuilder {
+	b.filter.ExcludeVotes = true
+	return b
+}
+
+// Commitment sets the commitment level.
+func (b *FilterBuilder) Commitment(commitment Commitment) *FilterBuilder {
+	b.filter.Commitment = commitment
+	return b
+}
+
+// All requires transactions to mention every one of the keys. Repeated calls add keys.
+func (b *FilterBuilder) All(keys ...string) *FilterBuilder {
+	b.keys().All = append(b.keys().All, keys...)
+	return b
+}
+
+// OneOf requires transactions to mention at least one of the keys. Repeated calls add keys.
+func (b *FilterBuilder) OneOf(keys ...string) *FilterBuilder {
+	b.keys().OneOf = append(b.keys().OneOf, keys...)
+	return b
+}
+
+// Exclude drops transactions mentioning any of the keys. Repeated calls add keys.
+func (b *FilterBuilder) Exclude(keys ...string) *FilterBuilder {
+	b.keys().Exclude = append(b.keys().Exclude, keys...)
+	return b
+}
+
+func (b *FilterBuilder) keys() *AccountKeysFilter {
+	if b.filter.AccountKeys == nil {
+		b.filter.AccountKeys = &AccountKeysFilter{}
+	}
+	return b.filter.AccountKeys
+}
+
+// Build returns the filter. Later changes to the builder do not affect it.
+func (b *FilterBuilder) Build() TransactionFilter {
+	filter := b.filter
+	if keys := b.filter.AccountKeys; keys != nil {
+		filter.AccountKeys = &AccountKeysFilter{
+			All:     append([]string(nil), keys.All...),
+			OneOf:   append([]string(nil), keys.OneOf...),
+			Exclude: append([]string(nil), keys.Exclude...),
+		}
+	}
+	return filter
+}
+
+// Request returns a validated transactionsSubscribe request for the network with the
+// filter, see NewTransactionsSubscribeRequest.
+func (b *FilterBuilder) Request(network Network) (*JSONRPCRequest, error) {
+	return NewTransactionsSubscribeRequest(network, b.Build())
+}
@@ -0,0 +1,37 @@
+package chainstream_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestFilterBuilder(t *testing.T) {
+	builder := chainstream.NewFilter().
+		ExcludeVotes().
+		Commitment(chainstream.CommitmentFinalized).
+		OneOf("wallet-a", "wallet-b").
+		Exclude("wallet-c")
+	filter := builder.Build()
+
+	expected := chainstream.TransactionFilter{
+		ExcludeVotes: true,
+		Commitment:   chainstream.CommitmentFinalized,
+		AccountKeys: &chainstream.AccountKeysFilter{
+			OneOf:   []string{"wallet-a", "wallet-b"},
+			Exclude: []string{"wallet-c"},
+		},
+	}
+	if !reflect.DeepEqual(filter, expected) {
+		t.Errorf("Build() = %+v, expected %+v", filter, expected)
+	}
+
+	builder.OneOf("wallet-d")
+	if len(filter.AccountKeys.OneOf) != 2 {
+		t.Errorf("built filter changed with the builder: %v", filter.AccountKeys.OneOf)
+	}
+	if filter := chainstream.NewFilter().Build(); filter.AccountKeys != nil {
+		t.Errorf("empty builder set account keys %+v", filter.AccountKeys)
+	}
+}
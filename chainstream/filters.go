@@ -0,0 +1,146 @@
+package chainstream
+
+import "math"
+
+// Middleware wraps a handler with additional behaviour.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Chain wraps the handler with the middlewares. The first middleware sees a
+// notification first.
+func Chain(do HandlerFunc, middlewares ...Middleware) HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		do = middlewares[i](do)
+	}
+	return do
+}
+
+// Filter reports whether a notification is of interest.
+type Filter func(notification *TransactionNotification) bool
+
+// Where returns a middleware that passes only notifications matching all filters.
+// Other notifications are skipped without calling the next handler.
+func Where(filters ...Filter) Middleware {
+	match := AllOf(filters...)
+	return func(next HandlerFunc) HandlerFunc {
+		return func(notification *TransactionNotification) error {
+			if !match(notification) {
+				return nil
+			}
+			return next(notification)
+		}
+	}
+}
+
+// AllOf matches notifications matching every filter.
+func AllOf(filters ...Filter) Filter {
+	return func(notification *TransactionNotification) bool {
+		for _, f := range filters {
+			if !f(notification) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// AnyOf matches notifications matching at least one filter.
+func AnyOf(filters ...Filter) Filter {
+	return func(notification *TransactionNotification) bool {
+		for _, f := range filters {
+			if f(notification) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not inverts the filter.
+func Not(f Filter) Filter {
+	return func(notification *TransactionNotification) bool {
+		return !f(notification)
+	}
+}
+
+// ExcludeFailed matches transactions that executed successfully.
+func ExcludeFailed() Filter {
+	return (*TransactionNotification).Succeeded
+}
+
+// OnlySwapsAbove matches transactions with a swap route that pays or receives at least
+// the given amount of SOL.
+func OnlySwapsAbove(sol float64) Filter {
+	lamports := uint64(math.Ceil(sol * LamportsPerSOL))
+	return func(notification *TransactionNotification) bool {
+		for _, route := range notification.SwapRoutes() {
+			if route.InputMint() == WrappedSOLMint && route.InputAmount() >= lamports {
+				return true
+			}
+			if route.OutputMint() == WrappedSOLMint && route.OutputAmount() >= lamports {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// OnlyMints matches transactions that change token balances or move tokens of any of the
+// mints. TouchesMints also matches transactions that leave the balances unchanged.
+func OnlyMints(mints ...string) Filter {
+	set := make(map[string]struct{}, len(mints))
+	for _, mint := range mints {
+		set[mint] = struct{}{}
+	}
+	return func(notification *TransactionNotification) bool {
+		for _, change := range notification.TokenBalanceChanges() {
+			if _, ok := set[change.Mint]; ok {
+				return true
+			}
+		}
+		for _, transfer := range notification.Transfers() {
+			if _, ok := set[transfer.Mint]; ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// TouchesMints matches transactions with a token account of any of the mints among their
+// pre or post token balances, whether its balance changed or not, e.g. in a failed trade.
+// Server-side filters cannot express this, as the token accounts of a mint are not known
+// in advance and the mint itself is not always an account key of the transaction.
+func TouchesMints(mints ...string) Filter {
+	set := make(map[string]struct{}, len(mints))
+	for _, mint := range mints {
+		set[mint] = struct{}{}
+	}
+	return func(notification *TransactionNotification) bool {
+		meta := &notification.Params.Result.Value.Meta
+		for _, balances := range [][]TokenBalance{meta.PreTokenBalances, meta.PostTokenBalances} {
+			for _, balance := range balances {
+				if _, ok := set[balance.Mint]; ok {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// OnlyPrograms matches transactions that invoke any of the programs, including through
+// inner instructions.
+func OnlyPrograms(programIDs ...string) Filter {
+	set := make(map[string]struct{}, len(programIDs))
+	for _, id := range programIDs {
+		set[id] = struct{}{}
+	}
+	return func(notification *TransactionNotification) bool {
+		for _, ix := range notification.Instructions() {
+			if _, ok := set[ix.ProgramID]; ok {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,106 @@
+package chainstream_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestFilters(t *testing.T) {
+	buy := loadNotification(t, "testdata/sample_tx_buy.json")
+	failed := loadNotification(t, "testdata/sample_tx_create.json")
+	const mint = "DNvtizsEYyknJoW3QYwDA7ncjxri3KBBTeLydEZCpump"
+
+	tests := []struct {
+		name         string
+		filter       chainstream.Filter
+		notification *chainstream.TransactionNotification
+		expected     bool
+	}{
+		{"swap above smaller amount", chainstream.OnlySwapsAbove(0.01), buy, true},
+		{"swap above larger amount", chainstream.OnlySwapsAbove(1), buy, false},
+		{"mint traded", chainstream.OnlyMints("other", mint), buy, true},
+		{"mint not traded", chainstream.OnlyMints("other"), buy, false},
+		{"mint touched", chainstream.TouchesMints("other", mint), buy, true},
+		{"mint not touched", chainstream.TouchesMints("other"), buy, false},
+		{"mint touched by failed", chainstream.TouchesMints("6b4nxqPGhKNNCGX84Rd61LTwA1yEYRMeLWrEVoWspump"), failed, true},
+		{"mint not traded by failed", chainstream.OnlyMints("6b4nxqPGhKNNCGX84Rd61LTwA1yEYRMeLWrEVoWspump"), failed, false},
+		{"succeeded", chainstream.ExcludeFailed(), buy, true},
+		{"failed", chainstream.ExcludeFailed(), failed, false},
+		{"not", chainstream.Not(chainstream.ExcludeFailed()), failed, true},
+		{"any of", chainstream.AnyOf(chainstream.OnlySwapsAbove(1), chainstream.OnlyMints(mint)), buy, true},
+		{"all of", chainstream.AllOf(chainstream.OnlySwapsAbove(1), chainstream.OnlyMints(mint)), buy, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if matched := test.filter(test.notification); matched != test.expected {
+				t.Errorf("filter = %v, expected %v", matched, test.expected)
+			}
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+	trace := func(name string) chainstream.Middleware {
+		return func(next chainstream.HandlerFunc) chainstream.HandlerFunc {
+			return func(n *chainstream.TransactionNotification) error {
+				calls = append(calls, name)
+				return next(n)
+			}
+		}
+	}
+	do := chainstream.Chain(func(*chainstream.TransactionNotification) error {
+		calls = append(calls, "handler")
+		return nil
+	}, trace("first"), chainstream.Where(chainstream.ExcludeFailed()), trace("second"))
+
+	for _, path := range []string{"testdata/sample_tx_buy.json", "testdata/sample_tx_create.json"} {
+		if err := do(loadNotification(t, path)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []string{"first", "second", "handler", "first"}
+	if len(calls) != len(expected) {
+		t.Fatalf("calls = %v, expected %v", calls, expected)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatalf("calls = %v, expected %v", calls, expected)
+		}
+	}
+}
+
+func TestSkipFailed(t *testing.T) {
+	failed := notification(2)
+	failed.Params.Result.Value.Meta.Err = json.RawMessage(`{"InstructionError":[0,"Custom"]}`)
+	srv := newFakeServer(t, sendNotifications(notification(1), failed, notification(3)))
+
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Pipeline.SkipFailed = true
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var slots []uint64
+	err := client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) error {
+		slots = append(slots, n.Slot())
+		if n.Slot() == 3 {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+	if len(slots) != 2 || slots[0] != 1 || slots[1] != 3 {
+		t.Errorf("handled slots %v, expected the failed transaction of slot 2 to be skipped", slots)
+	}
+	if stats := client.Stats(); stats.Filtered != 1 {
+		t.Errorf("Stats().Filtered = %d, expected 1", stats.Filtered)
+	}
+}
@@ -0,0 +1,116 @@
+package chainstream
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// HandlerFunc handles a notification. A returned error is retried according to the
+// pipeline retry policy unless it is wrapped with Permanent.
+type HandlerFunc func(notification *TransactionNotification) error
+
+// DeadLetterFunc receives notifications whose handling failed after all retries.
+type DeadLetterFunc func(notification *TransactionNotification, err error)
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a handler error as not worth retrying.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// handle calls do, retrying transient errors with exponential backoff. When all attempts
+// fail the notification is passed to fail, which reports whether it has taken care of the
+// error, or else to the dead letter hook.
+func (c *C) handle(ctx context.Context, notification *TransactionNotification, do HandlerFunc, fail func(error) bool) {
+	err, canceled := c.retry(ctx, func() error { return c.attempt(notification, do) })
+	switch {
+	case canceled:
+		return
+	case err == nil:
+		c.stats.delivered.Add(1)
+		return
+	}
+
+	c.stats.failed.Add(1)
+	if fail != nil && fail(err) {
+		return
+	}
+	if c.config.Hooks.DeadLetter != nil {
+		c.stats.deadLettered.Add(1)
+		c.config.Hooks.DeadLetter(notification, err)
+	}
+}
+
+// retry calls attempt until it succeeds, fails with a permanent error or a panic, or runs
+// out of attempts or of Config.RetryBudget, backing off between attempts according to the
+// pipeline retry policy. It returns the last error, and canceled when ctx is done while
+// backing off.
+func (c *C) retry(ctx context.Context, attempt func() error) (err error, canceled bool) {
+	policy := c.config.Pipeline.Retry
+	backoff := time.Duration(policy.InitialBackoff)
+
+	for n := 1; ; n++ {
+		if err = attempt(); err == nil {
+			return nil, false
+		}
+		c.stats.handlerErrors.Add(1)
+		var panicked *PanicError
+		if IsPermanent(err) || errors.As(err, &panicked) || n >= policy.MaxAttempts || !c.config.RetryBudget.Allow(RetryHandler) {
+			return err, false
+		}
+
+		c.stats.retries.Add(1)
+		select {
+		case <-c.clock().After(backoff):
+		case <-ctx.Done():
+			return err, true
+		}
+		backoff = policy.next(backoff)
+	}
+}
+
+// next returns the backoff following backoff.
+func (r *RetryPolicy) next(backoff time.Duration) time.Duration {
+	backoff = time.Duration(float64(backoff) * r.Multiplier)
+	if maxBackoff := time.Duration(r.MaxBackoff); maxBackoff > 0 && backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+// attempt runs Hooks.Enrich and the handler once, recording the latency of each. A panic
+// is returned as a PanicError.
+func (c *C) attempt(notification *TransactionNotification, do HandlerFunc) (err error) {
+	defer func() {
+		if p := recovered(recover(), notification.Slot(), notification.Signature()); p != nil {
+			err = p
+		}
+	}()
+	if enrich := c.config.Hooks.Enrich; enrich != nil {
+		start := time.Now()
+		err := enrich(notification)
+		c.latency.since(StageEnrich, start)
+		if err != nil {
+			return err
+		}
+	}
+	start := time.Now()
+	defer c.latency.since(StageDispatch, start)
+	return do(notification)
+}
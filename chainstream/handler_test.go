@@ -0,0 +1,57 @@
+package chainstream_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestHandlerRetry(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1, 2, 3))
+
+	var deadLetters []uint64
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Pipeline.Retry = chainstream.RetryPolicy{MaxAttempts: 3, InitialBackoff: chainstream.Duration(time.Millisecond), Multiplier: 2}
+	config.Hooks.DeadLetter = func(n *chainstream.TransactionNotification, err error) {
+		deadLetters = append(deadLetters, n.Slot())
+	}
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	attempts := make(map[uint64]int)
+	err := client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) error {
+		attempts[n.Slot()]++
+		switch n.Slot() {
+		case 1:
+			if attempts[1] == 1 {
+				return errors.New("transient")
+			}
+		case 2:
+			return chainstream.Permanent(errors.New("bad notification"))
+		case 3:
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+
+	if attempts[1] != 2 || attempts[2] != 1 {
+		t.Errorf("attempts = %v, expected one retry of slot 1 and none of slot 2", attempts)
+	}
+	if len(deadLetters) != 1 || deadLetters[0] != 2 {
+		t.Errorf("dead letters = %v, expected [2]", deadLetters)
+	}
+
+	stats := client.Stats()
+	expected := chainstream.Stats{Received: 3, Delivered: 2, HandlerErrors: 2, Retries: 1, Failed: 1, DeadLettered: 1}
+	if stats != expected {
+		t.Errorf("Stats() = %+v\nexpected %+v", stats, expected)
+	}
+}
@@ -0,0 +1,45 @@
+package chainstream_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestHandshakeHeaders(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1))
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Conn.Headers = map[string]string{"X-Api-Key": "secret", "User-Agent": "zensol"}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := chainstream.NewClient(config).TransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(*chainstream.TransactionNotification) {
+		cancel()
+	})
+	if err != nil {
+		t.Fatalf("TransactionsNotifications() error: %v", err)
+	}
+
+	header := srv.header(1)
+	if header.Get("X-Api-Key") != "secret" || header.Get("User-Agent") != "zensol" {
+		t.Errorf("handshake header = %v", header)
+	}
+}
+
+func TestValidateHeaders(t *testing.T) {
+	for _, headers := range []map[string]string{
+		{"X Api Key": "secret"},
+		{"Authorization": "Bearer a\r\nX-Injected: b"},
+	} {
+		config := chainstream.NewConfig("wss://example.com")
+		config.Conn.Endpoints = []chainstream.EndpointConfig{{URL: "wss://backup.example.com", Headers: headers}}
+		if err := config.Conn.Validate(); err == nil {
+			t.Errorf("Validate() accepted headers %q", headers)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package chainstream
+
+// Instruction is a compiled instruction with its program and accounts resolved to keys.
+type Instruction struct {
+	// Index is the position of the top-level instruction this instruction belongs to.
+	Index int `json:"index"`
+	// InnerIndex is the position inside the inner instructions of Index, or -1 for a top-level instruction.
+	InnerIndex int      `json:"innerIndex"`
+	ProgramID  string   `json:"programId"`
+	Accounts   []string `json:"accounts"`
+	Data       string   `json:"data"`
+}
+
+// IsInner reports whether the instruction was invoked by another instruction.
+func (i *Instruction) IsInner() bool {
+	return i.InnerIndex >= 0
+}
+
+// AccountKeys returns all account keys referenced by the transaction: the static keys
+// of the message followed by writable and readonly keys loaded from lookup tables.
+func (t *TransactionNotification) AccountKeys() []string {
+	value := &t.Params.Result.Value
+	static := value.Transaction.Message.AccountKeys
+	loaded := value.Meta.LoadedAddresses
+	keys := make([]string, 0, len(static)+len(loaded.Writable)+len(loaded.Readonly))
+	keys = append(keys, static...)
+	keys = append(keys, loaded.Writable...)
+	keys = append(keys, loaded.Readonly...)
+	return keys
+}
+
+// Signers returns the accounts that signed the transaction. The first one pays the fee.
+func (t *TransactionNotification) Signers() []string {
+	message := &t.Params.Result.Value.Transaction.Message
+	n := message.Header.NumSignatures
+	if n <= 0 {
+		n = 1
+	}
+	if n > len(message.AccountKeys) {
+		n = len(message.AccountKeys)
+	}
+	return message.AccountKeys[:n]
+}
+
+// Instructions returns the instructions of the transaction in execution order:
+// every top-level instruction is followed by the inner instructions it invoked.
+func (t *TransactionNotification) Instructions() []Instruction {
+	keys := t.AccountKeys()
+	value := &t.Params.Result.Value
+
+	inner := make(map[int][]CompiledInstruction, len(value.Meta.InnerInstructions))
+	for _, ii := range value.Meta.InnerInstructions {
+		inner[ii.Index] = append(inner[ii.Index], ii.Instructions...)
+	}
+
+	var instructions []Instruction
+	for i, compiled := range value.Transaction.Message.Instructions {
+		instructions = append(instructions, resolveInstruction(keys, compiled, i, -1))
+		for j, compiledInner := range inner[i] {
+			instructions = append(instructions, resolveInstruction(keys, compiledInner, i, j))
+		}
+	}
+	return instructions
+}
+
+func resolveInstruction(keys []string, compiled CompiledInstruction, index, innerIndex int) Instruction {
+	accounts := make([]string, len(compiled.Accounts))
+	for i, idx := range compiled.Accounts {
+		accounts[i] = keyAt(keys, idx)
+	}
+	return Instruction{
+		Index:      index,
+		InnerIndex: innerIndex,
+		ProgramID:  keyAt(keys, compiled.ProgramIDIndex),
+		Accounts:   accounts,
+		Data:       compiled.Data,
+	}
+}
+
+func keyAt(keys []string, idx int) string {
+	if idx < 0 || idx >= len(keys) {
+		return ""
+	}
+	return keys[idx]
+}
@@ -0,0 +1,56 @@
+package chainstream_test
+
+import "testing"
+
+func TestInstructions(t *testing.T) {
+	tx := loadNotification(t, "testdata/sample_tx_buy.json")
+	instructions := tx.Instructions()
+
+	if len(instructions) != 7 {
+		t.Fatalf("len(Instructions()) = %d, expected 7", len(instructions))
+	}
+
+	tests := []struct {
+		pos        int
+		index      int
+		innerIndex int
+		programID  string
+	}{
+		{0, 0, -1, "ComputeBudget111111111111111111111111111111"},
+		{2, 2, -1, "6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"},
+		{3, 2, 0, "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"},
+		{4, 2, 1, "11111111111111111111111111111111"},
+	}
+	for _, tc := range tests {
+		ix := instructions[tc.pos]
+		if ix.Index != tc.index || ix.InnerIndex != tc.innerIndex || ix.ProgramID != tc.programID {
+			t.Errorf("Instructions()[%d] = {%d %d %s}, expected {%d %d %s}",
+				tc.pos, ix.Index, ix.InnerIndex, ix.ProgramID, tc.index, tc.innerIndex, tc.programID)
+		}
+	}
+
+	transfer := instructions[4]
+	if transfer.Accounts[0] != tx.Owner() || transfer.Accounts[1] != "8fC59gfiQerpTpTiEJVvB4u1UgHuBsEDGnxiQUU5AJQo" {
+		t.Errorf("unexpected transfer accounts %v", transfer.Accounts)
+	}
+}
+
+func TestBalanceChanges(t *testing.T) {
+	tx := loadNotification(t, "testdata/sample_tx_buy.json")
+
+	changes := tx.BalanceChanges()
+	if len(changes) != 3 {
+		t.Fatalf("len(BalanceChanges()) = %d, expected 3", len(changes))
+	}
+	if changes[0].Account != tx.Owner() || changes[0].Delta != -10109004 {
+		t.Errorf("BalanceChanges()[0] = %+v", changes[0])
+	}
+
+	tokenChanges := tx.TokenBalanceChanges()
+	if len(tokenChanges) != 2 {
+		t.Fatalf("len(TokenBalanceChanges()) = %d, expected 2", len(tokenChanges))
+	}
+	if tokenChanges[0].Delta != -357547484136 || tokenChanges[1].Delta != 357547484136 {
+		t.Errorf("unexpected token deltas %+v", tokenChanges)
+	}
+}
@@ -0,0 +1,89 @@
+package chainstream
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mr-tron/base58"
+)
+
+// PublicKeyLength is the length of a decoded Solana account key.
+const PublicKeyLength = 32
+
+// KeyWarning describes a filter key that is valid but will never change what is matched.
+type KeyWarning struct {
+	Field  string
+	Key    string
+	Reason string
+}
+
+func (w KeyWarning) String() string {
+	return fmt.Sprintf("%s: %s: %s", w.Field, w.Key, w.Reason)
+}
+
+// NormalizeKey trims surrounding whitespace from an account key and checks that it is
+// valid base58 decoding to 32 bytes. It does not check that the key is on the ed25519
+// curve: program derived addresses are not, and are valid keys.
+func NormalizeKey(key string) (string, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", errors.New("empty account key")
+	}
+	decoded, err := base58.Decode(key)
+	if err != nil {
+		return "", fmt.Errorf("account key %q is not valid base58: %w", key, err)
+	}
+	if len(decoded) != PublicKeyLength {
+		return "", fmt.Errorf("account key %q decodes to %d bytes, expected %d (check letter case)", key, len(decoded), PublicKeyLength)
+	}
+	return key, nil
+}
+
+// Normalize trims and validates every key of the filter in place and removes duplicates.
+// It returns warnings for keys that will never match and an error listing every invalid key.
+func (f *AccountKeysFilter) Normalize() ([]KeyWarning, error) {
+	var (
+		warnings []KeyWarning
+		errs     []error
+	)
+	normalize := func(field string, keys []string) []string {
+		seen := make(map[string]struct{}, len(keys))
+		result := keys[:0]
+		for i, key := range keys {
+			normalized, err := NormalizeKey(key)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s[%d]: %w", field, i, err))
+				continue
+			}
+			if _, ok := seen[normalized]; ok {
+				warnings = append(warnings, KeyWarning{field, normalized, "duplicate key"})
+				continue
+			}
+			seen[normalized] = struct{}{}
+			result = append(result, normalized)
+		}
+		return result
+	}
+
+	f.All = normalize("all", f.All)
+	f.OneOf = normalize("oneOf", f.OneOf)
+	f.Exclude = normalize("exclude", f.Exclude)
+
+	excluded := make(map[string]struct{}, len(f.Exclude))
+	for _, key := range f.Exclude {
+		excluded[key] = struct{}{}
+	}
+	for _, key := range f.All {
+		if _, ok := excluded[key]; ok {
+			warnings = append(warnings, KeyWarning{"all", key, "key is also excluded, the filter can never match"})
+		}
+	}
+	for _, key := range f.OneOf {
+		if _, ok := excluded[key]; ok {
+			warnings = append(warnings, KeyWarning{"oneOf", key, "key is also excluded and will never match"})
+		}
+	}
+
+	return warnings, errors.Join(errs...)
+}
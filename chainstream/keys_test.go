@@ -0,0 +1,59 @@
+package chainstream_test
+
+import (
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestNormalizeKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		expected string
+		valid    bool
+	}{
+		{"Valid", "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF", "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF", true},
+		{"Whitespace", "  11111111111111111111111111111111\n", "11111111111111111111111111111111", true},
+		{"Empty", " ", "", false},
+		{"Program Derived Address", "4wTV1YmiEkRvAtNtsSGPtUrqRYQMe5SKy2uB4Jjaxnjf", "4wTV1YmiEkRvAtNtsSGPtUrqRYQMe5SKy2uB4Jjaxnjf", true},
+		{"Invalid Character", "0xdeadbeef", "", false},
+		{"Ambiguous Letter", "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhO", "", false},
+		{"Wrong Length", "53CkQzZiYAqwSdYRUX546", "", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := chainstream.NormalizeKey(tc.key)
+			if (err == nil) != tc.valid {
+				t.Fatalf("NormalizeKey(%q) error = %v, expected valid = %v", tc.key, err, tc.valid)
+			}
+			if got != tc.expected {
+				t.Errorf("NormalizeKey(%q) = %q, expected %q", tc.key, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestAccountKeysFilterNormalize(t *testing.T) {
+	filter := chainstream.AccountKeysFilter{
+		OneOf:   []string{" 53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF", "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF", "6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"},
+		Exclude: []string{"6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"},
+	}
+
+	warnings, err := filter.Normalize()
+	if err != nil {
+		t.Fatalf("Normalize() error: %v", err)
+	}
+	if len(filter.OneOf) != 2 || filter.OneOf[0] != "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF" {
+		t.Errorf("OneOf = %v", filter.OneOf)
+	}
+	if len(warnings) != 2 {
+		t.Errorf("got warnings %v, expected duplicate and excluded key warnings", warnings)
+	}
+
+	filter.All = []string{"not-a-key"}
+	if _, err = filter.Normalize(); err == nil {
+		t.Error("expected error for invalid key")
+	}
+}
@@ -0,0 +1,130 @@
+package chainstream
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Stage is a step a notification passes through in the client.
+type Stage int
+
+const (
+	// StageDecode is JSON decoding of a received message.
+	StageDecode Stage = iota
+	// StageFilter covers deduplication, staleness checks and Hooks.Filter.
+	StageFilter
+	// StageEnrich is Hooks.Enrich.
+	StageEnrich
+	// StageDispatch is the handler passed to HandleTransactionsNotifications.
+	StageDispatch
+
+	numStages
+)
+
+// Stages lists all stages in pipeline order.
+var Stages = [...]Stage{StageDecode, StageFilter, StageEnrich, StageDispatch}
+
+func (s Stage) String() string {
+	switch s {
+	case StageDecode:
+		return "decode"
+	case StageFilter:
+		return "filter"
+	case StageEnrich:
+		return "enrich"
+	case StageDispatch:
+		return "dispatch"
+	}
+	return fmt.Sprintf("Stage(%d)", int(s))
+}
+
+// LatencyBuckets are the upper bounds of the latency histogram buckets.
+var LatencyBuckets = [...]time.Duration{
+	10 * time.Microsecond, 50 * time.Microsecond,
+	100 * time.Microsecond, 500 * time.Microsecond,
+	time.Millisecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 5 * time.Second,
+}
+
+// Histogram is a snapshot of the latencies observed in a stage.
+type Histogram struct {
+	// Counts[i] is the number of observations above LatencyBuckets[i-1] and not above
+	// LatencyBuckets[i]. The last element counts observations above all buckets.
+	Counts [len(LatencyBuckets) + 1]uint64 `json:"counts"`
+	Count  uint64                          `json:"count"`
+	Sum    time.Duration                   `json:"sum"`
+}
+
+// Mean returns the average observed latency.
+func (h *Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile returns the upper bound of the bucket holding the q-th quantile, 0 < q <= 1.
+// Observations above all buckets are reported as the largest bucket bound.
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(h.Count))
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	for i, count := range h.Counts[:len(LatencyBuckets)] {
+		if seen += count; seen >= rank {
+			return LatencyBuckets[i]
+		}
+	}
+	return LatencyBuckets[len(LatencyBuckets)-1]
+}
+
+// histogram holds the live counters behind Histogram.
+type histogram struct {
+	counts [len(LatencyBuckets) + 1]atomic.Uint64
+	count  atomic.Uint64
+	sum    atomic.Int64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	i := 0
+	for i < len(LatencyBuckets) && d > LatencyBuckets[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+	h.count.Add(1)
+	h.sum.Add(int64(d))
+}
+
+func (h *histogram) snapshot() Histogram {
+	var s Histogram
+	for i := range h.counts {
+		s.Counts[i] = h.counts[i].Load()
+	}
+	s.Count = h.count.Load()
+	s.Sum = time.Duration(h.sum.Load())
+	return s
+}
+
+// latencies holds a histogram per stage.
+type latencies [numStages]histogram
+
+// since records the time elapsed since start in the stage.
+func (l *latencies) since(stage Stage, start time.Time) {
+	l[stage].observe(time.Since(start))
+}
+
+// Latency returns a snapshot of the latency histograms of every stage.
+func (c *C) Latency() map[Stage]Histogram {
+	snapshot := make(map[Stage]Histogram, numStages)
+	for _, stage := range Stages {
+		snapshot[stage] = c.latency[stage].snapshot()
+	}
+	return snapshot
+}
@@ -0,0 +1,73 @@
+package chainstream_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestStageLatency(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1, 2, 3))
+
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Hooks.Filter = func(n *chainstream.TransactionNotification) bool { return n.Slot() != 2 }
+	enriched := 0
+	config.Hooks.Enrich = func(*chainstream.TransactionNotification) error {
+		enriched++
+		return nil
+	}
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) error {
+		if n.Slot() == 3 {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+
+	if stats := client.Stats(); stats.Filtered != 1 || stats.Delivered != 2 || enriched != 2 {
+		t.Errorf("Stats() = %+v, enriched %d, expected slot 2 to be filtered out", stats, enriched)
+	}
+	latency := client.Latency()
+	expected := map[chainstream.Stage]uint64{
+		chainstream.StageDecode:   3,
+		chainstream.StageFilter:   3,
+		chainstream.StageEnrich:   2,
+		chainstream.StageDispatch: 2,
+	}
+	for stage, count := range expected {
+		h := latency[stage]
+		if h.Count != count {
+			t.Errorf("%s: %d observations, expected %d", stage, h.Count, count)
+		}
+		t.Logf("⏱ %s: mean %v, p99 <= %v", stage, h.Mean(), h.Quantile(0.99))
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	var h chainstream.Histogram
+	h.Counts[0], h.Counts[4], h.Counts[len(h.Counts)-1] = 90, 9, 1
+	h.Count = 100
+
+	tests := []struct {
+		q        float64
+		expected time.Duration
+	}{
+		{0.5, 10 * time.Microsecond},
+		{0.95, time.Millisecond},
+		{1, 5 * time.Second},
+	}
+	for _, test := range tests {
+		if got := h.Quantile(test.q); got != test.expected {
+			t.Errorf("Quantile(%v) = %v, expected %v", test.q, got, test.expected)
+		}
+	}
+}
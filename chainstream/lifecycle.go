@@ -0,0 +1,59 @@
+package chainstream
+
+import "time"
+
+// ConnectionEvent describes a state transition of the connection of a subscription.
+type ConnectionEvent struct {
+	// Endpoint is the endpoint of the session, with the API key masked.
+	Endpoint string
+	Method   string
+	// Err is the cause of a disconnect.
+	Err error
+	// Attempt is the number of the connection within the subscription: 1 for the first
+	// connection, 2 for the first reconnect and so on.
+	Attempt int
+	// Downtime is the time between a disconnect and the following reconnect.
+	Downtime time.Duration
+}
+
+// ConnectionFunc observes connection state transitions, e.g. to emit metrics or alerts.
+type ConnectionFunc func(event ConnectionEvent)
+
+// lifecycle reports the connection state transitions of a subscription to Hooks.Connect,
+// Hooks.Disconnect and Hooks.Reconnect.
+type lifecycle struct {
+	c              *C
+	method         string
+	attempt        int
+	disconnectedAt time.Time
+}
+
+func (c *C) newLifecycle(request *JSONRPCRequest) *lifecycle {
+	return &lifecycle{c: c, method: request.Method}
+}
+
+// connected records that the session is established.
+func (l *lifecycle) connected(s *session) {
+	l.attempt++
+	hooks := &l.c.config.Hooks
+	event := ConnectionEvent{Endpoint: redactURL(s.endpoint.url), Method: l.method, Attempt: l.attempt}
+	if l.disconnectedAt.IsZero() {
+		if hooks.Connect != nil {
+			hooks.Connect(event)
+		}
+		return
+	}
+	event.Downtime = l.c.clock().Now().Sub(l.disconnectedAt)
+	l.disconnectedAt = time.Time{}
+	if hooks.Reconnect != nil {
+		hooks.Reconnect(event)
+	}
+}
+
+// disconnected records that the session failed with err.
+func (l *lifecycle) disconnected(s *session, err error) {
+	l.disconnectedAt = l.c.clock().Now()
+	if l.c.config.Hooks.Disconnect != nil {
+		l.c.config.Hooks.Disconnect(ConnectionEvent{Endpoint: redactURL(s.endpoint.url), Method: l.method, Err: err, Attempt: l.attempt})
+	}
+}
@@ -0,0 +1,65 @@
+package chainstream_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+func TestConnectionHooks(t *testing.T) {
+	// The first connection drops right after subscribing.
+	srv := newFakeServer(t, func(ctx context.Context, conn *websocket.Conn, n int) error {
+		if n == 1 {
+			return nil
+		}
+		return sendSlots(2)(ctx, conn, n)
+	})
+	fake := clock.NewFake(time.Now())
+
+	var events []string
+	var disconnect, reconnect chainstream.ConnectionEvent
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Hooks.Connect = func(event chainstream.ConnectionEvent) { events = append(events, "connect") }
+	config.Hooks.Disconnect = func(event chainstream.ConnectionEvent) {
+		events = append(events, "disconnect")
+		disconnect = event
+	}
+	config.Hooks.Reconnect = func(event chainstream.ConnectionEvent) {
+		events = append(events, "reconnect")
+		reconnect = event
+	}
+	config.Clock = fake
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go func() {
+		// The ping ticker and the reconnect delay.
+		fake.BlockUntil(2)
+		fake.Advance(time.Second)
+	}()
+
+	err := client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(*chainstream.TransactionNotification) error {
+		cancel()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+
+	if len(events) != 3 || events[0] != "connect" || events[1] != "disconnect" || events[2] != "reconnect" {
+		t.Fatalf("events %v, expected connect, disconnect and reconnect", events)
+	}
+	if disconnect.Err == nil || disconnect.Attempt != 1 || disconnect.Method != "transactionsSubscribe" || disconnect.Endpoint != srv.endpoint() {
+		t.Errorf("disconnect event %+v", disconnect)
+	}
+	if reconnect.Attempt != 2 || reconnect.Downtime != time.Second || reconnect.Err != nil {
+		t.Errorf("reconnect event %+v", reconnect)
+	}
+}
@@ -0,0 +1,186 @@
+package chainstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// LogsSubscribeParams are the params of logsSubscribe, a method of the standard Solana
+// WebSocket API rather than of ChainStream. They are sent as the positional params
+// [filter, {commitment}], where the filter is {"mentions": [Mentions]}, "all" or
+// "allWithVotes".
+type LogsSubscribeParams struct {
+	// Mentions, when set, limits the logs to the transactions mentioning the account.
+	// Nodes accept a single account.
+	Mentions string
+	// IncludeVotes includes vote transactions in the logs of every transaction, when
+	// Mentions is empty.
+	IncludeVotes bool
+	Commitment   Commitment
+}
+
+type logsSubscribeOptions struct {
+	Commitment Commitment `json:"commitment"`
+}
+
+type logsMentions struct {
+	Mentions []string `json:"mentions"`
+}
+
+// MarshalJSON encodes the params as positional params, with DefaultCommitment when the
+// commitment is unset.
+func (p LogsSubscribeParams) MarshalJSON() ([]byte, error) {
+	var filter interface{} = "all"
+	switch {
+	case p.Mentions != "":
+		filter = logsMentions{Mentions: []string{p.Mentions}}
+	case p.IncludeVotes:
+		filter = "allWithVotes"
+	}
+	return json.Marshal([]interface{}{filter, logsSubscribeOptions{Commitment: p.Commitment.orDefault()}})
+}
+
+// UnmarshalJSON decodes positional params.
+func (p *LogsSubscribeParams) UnmarshalJSON(data []byte) error {
+	var params []json.RawMessage
+	if err := json.Unmarshal(data, &params); err != nil {
+		return err
+	}
+	if len(params) == 0 || len(params) > 2 {
+		return fmt.Errorf("logsSubscribe takes 1 or 2 params, got %d", len(params))
+	}
+	var decoded LogsSubscribeParams
+	var filter string
+	var mentions logsMentions
+	switch {
+	case json.Unmarshal(params[0], &filter) == nil:
+		switch filter {
+		case "all":
+		case "allWithVotes":
+			decoded.IncludeVotes = true
+		default:
+			return fmt.Errorf("unknown logs filter %q", filter)
+		}
+	case json.Unmarshal(params[0], &mentions) == nil:
+		if len(mentions.Mentions) != 1 {
+			return fmt.Errorf("logs filter mentions %d accounts, expected 1", len(mentions.Mentions))
+		}
+		decoded.Mentions = mentions.Mentions[0]
+	default:
+		return fmt.Errorf("invalid logs filter %s", params[0])
+	}
+	if len(params) == 2 {
+		var options logsSubscribeOptions
+		if err := json.Unmarshal(params[1], &options); err != nil {
+			return fmt.Errorf("options: %w", err)
+		}
+		decoded.Commitment = options.Commitment
+	}
+	*p = decoded
+	return nil
+}
+
+// Validate checks the params before they are sent: Mentions must be a valid key, and
+// excludes IncludeVotes, and the commitment must be known when set.
+func (p *LogsSubscribeParams) Validate() error {
+	var errs []error
+	if p.Mentions != "" {
+		if _, err := NormalizeKey(p.Mentions); err != nil {
+			errs = append(errs, fmt.Errorf("mentions: %w", err))
+		}
+		if p.IncludeVotes {
+			errs = append(errs, errors.New("includeVotes applies to the logs of every transaction, not to mentions"))
+		}
+	}
+	if p.Commitment != 0 {
+		if err := p.Commitment.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewLogsSubscribeRequest returns a logsSubscribe request. An unset commitment uses
+// DefaultCommitment. It returns an error if the params are invalid, see
+// LogsSubscribeParams.Validate.
+func NewLogsSubscribeRequest(params LogsSubscribeParams) (*JSONRPCRequest, error) {
+	params.Commitment = params.Commitment.orDefault()
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	return newRequest("logsSubscribe", params), nil
+}
+
+// LogsNotification holds the logs of a transaction, sent by logsSubscribe.
+type LogsNotification struct {
+	JSONRPC string                 `json:"jsonrpc"`
+	Method  string                 `json:"method"`
+	Params  LogsNotificationParams `json:"params"`
+}
+
+// Slot returns the slot of the transaction.
+func (l *LogsNotification) Slot() uint64 {
+	return l.Params.Result.Context.Slot
+}
+
+// Signature returns the signature of the transaction.
+func (l *LogsNotification) Signature() string {
+	return l.Params.Result.Value.Signature
+}
+
+// Succeeded reports whether the transaction was executed without an error.
+func (l *LogsNotification) Succeeded() bool {
+	err := l.Params.Result.Value.Err
+	return len(err) == 0 || string(err) == "null"
+}
+
+// LogsNotificationParams contains subscription ID and payload.
+type LogsNotificationParams struct {
+	Subscription int64                `json:"subscription"`
+	Result       LogsNotificationData `json:"result"`
+}
+
+// LogsNotificationData holds the context and the logs.
+type LogsNotificationData struct {
+	Context ContextMetadata `json:"context"`
+	Value   LogsValue       `json:"value"`
+}
+
+// LogsValue is the outcome and the log messages of a transaction.
+type LogsValue struct {
+	Signature string `json:"signature"`
+	// Err is the transaction error, null when it succeeded.
+	Err  json.RawMessage `json:"err"`
+	Logs []string        `json:"logs"`
+}
+
+// decodeLogs is the session decoder of logsSubscribe subscriptions.
+func decodeLogs(data []byte, event *sessionEvent) error {
+	var logs LogsNotification
+	if err := json.Unmarshal(data, &logs); err != nil {
+		return fmt.Errorf("failed to decode logs notification: %w", err)
+	}
+	event.logs = &logs
+	return nil
+}
+
+// LogsNotifications subscribes to the logs of transactions with logsSubscribe, see
+// NewLogsSubscribeRequest. It is a method of the standard Solana WebSocket API, so the
+// endpoints of the client must be Solana RPC WebSocket endpoints rather than ChainStream
+// ones. Logs are received over scored endpoints with pings and reconnects, and the logs of
+// a transaction delivered twice are passed to do once. Panics in do are recovered, see
+// Hooks.Panic.
+func (c *C) LogsNotifications(
+	ctx context.Context,
+	request *JSONRPCRequest,
+	do func(logs *LogsNotification),
+	opts ...SubscribeOption,
+) error {
+	return c.notifications(ctx, request, "logs", opts, func(event sessionEvent) (uint64, string) {
+		return event.logs.Slot(), event.logs.Signature()
+	}, func(event sessionEvent) error {
+		return c.dispatch(event.logs.Slot(), func() { do(event.logs) })
+	})
+}
@@ -0,0 +1,102 @@
+package chainstream_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+// logsNotification is a logsNotification of the standard Solana WebSocket API.
+func logsNotification(slot uint64, signature, err string) string {
+	return `{"jsonrpc":"2.0","method":"logsNotification","params":{"result":{"context":{"slot":` + jsonNumber(slot) +
+		`},"value":{"signature":"` + signature + `","err":` + err +
+		`,"logs":["Program 11111111111111111111111111111111 invoke [1]","Program 11111111111111111111111111111111 success"]}},"subscription":1001}}`
+}
+
+func TestLogsNotifications(t *testing.T) {
+	srv := newFakeServer(t, func(ctx context.Context, conn *websocket.Conn, _ int) error {
+		for _, message := range []string{
+			logsNotification(10, "a", "null"),
+			logsNotification(10, "a", "null"),
+			logsNotification(11, "b", `{"InstructionError":[0,{"Custom":1}]}`),
+		} {
+			if err := conn.Write(ctx, websocket.MessageText, []byte(message)); err != nil {
+				return err
+			}
+		}
+		<-ctx.Done()
+		return nil
+	})
+	client := chainstream.NewClient(chainstream.NewConfig(srv.endpoint()))
+	request, err := chainstream.NewLogsSubscribeRequest(chainstream.LogsSubscribeParams{Mentions: watchedAccount, Commitment: chainstream.CommitmentProcessed})
+	if err != nil {
+		t.Fatalf("NewLogsSubscribeRequest() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var logs []*chainstream.LogsNotification
+	err = client.LogsNotifications(ctx, request, func(l *chainstream.LogsNotification) {
+		logs = append(logs, l)
+		if l.Slot() == 11 {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("LogsNotifications() error: %v", err)
+	}
+
+	if len(logs) != 2 || logs[0].Signature() != "a" || logs[1].Signature() != "b" {
+		t.Fatalf("received %d logs, expected a and b once", len(logs))
+	}
+	if !logs[0].Succeeded() || logs[1].Succeeded() {
+		t.Error("Succeeded() does not follow err")
+	}
+	if lines := logs[0].Params.Result.Value.Logs; len(lines) != 2 {
+		t.Errorf("logs = %q", lines)
+	}
+
+	sent := srv.request(1)
+	expected := []interface{}{map[string]interface{}{"mentions": []interface{}{watchedAccount}}, map[string]interface{}{"commitment": "processed"}}
+	if sent.Method != "logsSubscribe" || !reflect.DeepEqual(sent.Params, expected) {
+		t.Errorf("subscribed with %s %v, expected logsSubscribe %v", sent.Method, sent.Params, expected)
+	}
+}
+
+func TestLogsSubscribeParams(t *testing.T) {
+	for _, params := range []chainstream.LogsSubscribeParams{
+		{Commitment: chainstream.CommitmentConfirmed},
+		{IncludeVotes: true, Commitment: chainstream.CommitmentFinalized},
+		{Mentions: watchedAccount, Commitment: chainstream.CommitmentConfirmed},
+	} {
+		data, err := json.Marshal(params)
+		if err != nil {
+			t.Fatalf("Marshal(%+v) error: %v", params, err)
+		}
+		var decoded chainstream.LogsSubscribeParams
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded != params {
+			t.Errorf("Unmarshal(%s) = %+v, %v, expected %+v", data, decoded, err, params)
+		}
+	}
+
+	for _, params := range []chainstream.LogsSubscribeParams{
+		{Mentions: "not a key"},
+		{Mentions: watchedAccount, IncludeVotes: true},
+	} {
+		if _, err := chainstream.NewLogsSubscribeRequest(params); err == nil {
+			t.Errorf("NewLogsSubscribeRequest(%+v) succeeded", params)
+		}
+	}
+	client := chainstream.NewClient(chainstream.NewConfig("wss://example.com"))
+	request := &chainstream.JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "logsSubscribe", Params: chainstream.LogsSubscribeParams{Mentions: "not a key"}}
+	if err := client.LogsNotifications(context.Background(), request, func(*chainstream.LogsNotification) {}); !errors.Is(err, chainstream.ErrInvalidParams) {
+		t.Errorf("LogsNotifications() error = %v, expected ErrInvalidParams", err)
+	}
+}
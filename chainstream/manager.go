@@ -0,0 +1,609 @@
+package chainstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+// errConnectionLost fails the calls waiting for a response when the connection fails.
+var errConnectionLost = errors.New("connection lost")
+
+// errManagerStopped is returned by SubscriptionManager.Subscribe once Run has returned.
+var errManagerStopped = errors.New("subscription manager is not running")
+
+// SubscriptionManager multiplexes transactionsSubscribe subscriptions over a single
+// connection, so that they count once against the connection quota of the provider. It
+// requires ProviderSyndica.
+// Notifications are passed to the handler of their subscription by subscription ID. The
+// connection is kept like the one of HandleTransactionsNotifications and every subscription
+// is subscribed again after a reconnect. Each subscription has its own filter stage and
+// handler goroutine, fed by a queue of Pipeline.ReadBuffer notifications. A slow handler
+// does not hold up the others: the notifications arriving while its queue is full are
+// dropped and counted in Stats.Dropped.
+type SubscriptionManager struct {
+	c *C
+
+	mu sync.Mutex
+	// ctx is the context of Run, nil before Run and once it has returned.
+	ctx     context.Context
+	stopped bool
+	// session is the current connection, nil while disconnected. lost is closed when
+	// it fails.
+	session *session
+	lost    chan struct{}
+	nextID  int
+	pending map[int]*pendingCall
+	subs    map[*Subscription]struct{}
+	byID    map[int64]*Subscription
+}
+
+// pendingCall waits for the response to a request sent by the manager.
+type pendingCall struct {
+	// sub is the subscription being subscribed with the params of generation, registered
+	// under its ID as soon as the response is read so that no notification is missed.
+	sub        *Subscription
+	generation int
+	response   chan callResponse
+	// abandoned tells that the caller stopped waiting. The call stays pending so that a
+	// subscription acknowledged late is released rather than leaked.
+	abandoned bool
+}
+
+// callResponse is the response to a request sent by the manager.
+type callResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+	// stale tells that the subscription was acknowledged with params replaced meanwhile by
+	// UpdateFilter. The acknowledged subscription is released.
+	stale bool
+}
+
+// Subscription is a subscription multiplexed by a SubscriptionManager.
+type Subscription struct {
+	m       *SubscriptionManager
+	request JSONRPCRequest
+	do      HandlerFunc
+	queue   chan *TransactionNotification
+	seen    *signatureSet
+	state   *subscriptionState
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	ready     chan struct{}
+	readyOnce sync.Once
+	closeOnce sync.Once
+	err       error
+
+	// id is the subscription ID assigned by the server and subscribing is set while a
+	// subscribe request is in flight. info holds the last acknowledgement and generation
+	// counts the updates of the params of request. They are guarded by the manager.
+	id          int64
+	subscribing bool
+	info        SubscriptionInfo
+	generation  int
+	// updating serializes UpdateFilter calls.
+	updating sync.Mutex
+}
+
+// NewSubscriptionManager creates a manager of subscriptions sharing a connection. Run
+// must be called for the subscriptions to start.
+func (c *C) NewSubscriptionManager() *SubscriptionManager {
+	return &SubscriptionManager{
+		c:       c,
+		pending: make(map[int]*pendingCall),
+		subs:    make(map[*Subscription]struct{}),
+		byID:    make(map[int64]*Subscription),
+	}
+}
+
+// Run connects and passes notifications to the subscriptions until ctx is done, when it
+// returns nil and ends every subscription. It returns an error if it cannot connect.
+func (m *SubscriptionManager) Run(ctx context.Context) error {
+	c := m.c
+	if p := c.provider(); p.Name() != string(ProviderSyndica) {
+		return unsupported(p, "multiplexed transactionsSubscribe")
+	}
+	m.mu.Lock()
+	m.ctx = ctx
+	m.mu.Unlock()
+
+	var current *session
+	defer func() {
+		if current != nil {
+			current.close("subscription manager was stopped")
+		}
+		m.stop()
+	}()
+
+	events := make(chan sessionEvent)
+	current, err := m.connect(ctx, events, false)
+	if err != nil {
+		return err
+	}
+	connection := c.newLifecycle(&JSONRPCRequest{Method: "transactionsSubscribe"})
+	connection.connected(current)
+
+	clk := c.clock()
+	ticker := clk.NewTicker(c.pingInterval())
+	defer ticker.Stop()
+	idleTicks, stopWatchdog := c.watchdog()
+	defer stopWatchdog()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-idleTicks:
+			c.checkIdle(current, "transactionsSubscribe", clk.Now())
+		case <-ticker.C():
+			go c.ping(ctx, current)
+		case event := <-events:
+			if event.session != current {
+				continue
+			}
+			if event.err != nil {
+				if errors.Is(event.err, context.Canceled) || ctx.Err() != nil {
+					return nil
+				}
+				current.close("session failed")
+				c.endpointSet().observeFailure(current.endpoint, clk.Now())
+				connection.disconnected(current, event.err)
+				m.disconnected()
+				current = nil
+				if !c.awaitReconnect(ctx) {
+					return nil
+				}
+				if current, err = m.connect(ctx, events, true); err != nil {
+					if ctx.Err() != nil {
+						return nil
+					}
+					return err
+				}
+				c.stats.reconnects.Add(1)
+				connection.connected(current)
+				continue
+			}
+			current.lastReceived = clk.Now()
+			m.receive(event.raw)
+		}
+	}
+}
+
+// connect opens a connection to the best scoring endpoint that accepts one and subscribes
+// every subscription on it.
+func (m *SubscriptionManager) connect(ctx context.Context, events chan<- sessionEvent, failed bool) (*session, error) {
+	c := m.c
+	var (
+		e    *endpoint
+		conn *websocket.Conn
+	)
+	err := c.failover(ctx, failed, func(next *endpoint) error {
+		var err error
+		e = next
+		conn, err = c.dial(ctx, e)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s := c.startSession(ctx, e, conn, decodeRaw, events)
+	s.lastReceived = c.clock().Now()
+
+	m.mu.Lock()
+	m.session, m.lost = s, make(chan struct{})
+	m.mu.Unlock()
+	m.activate()
+	return s, nil
+}
+
+// disconnected forgets the failed connection: calls waiting for a response fail and every
+// subscription waits to be subscribed again.
+func (m *SubscriptionManager) disconnected() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.session = nil
+	close(m.lost)
+	m.byID = make(map[int64]*Subscription)
+	for s := range m.subs {
+		s.id = 0
+	}
+	for id, call := range m.pending {
+		if call.abandoned {
+			delete(m.pending, id)
+		}
+	}
+}
+
+// stop ends every subscription once Run returns. Calls waiting for a response fail as if
+// the connection was lost.
+func (m *SubscriptionManager) stop() {
+	m.mu.Lock()
+	if m.session != nil {
+		close(m.lost)
+	}
+	m.ctx, m.stopped, m.session = nil, true, nil
+	subs := m.subs
+	m.subs = make(map[*Subscription]struct{})
+	m.byID = make(map[int64]*Subscription)
+	m.mu.Unlock()
+	for s := range subs {
+		s.close(nil)
+	}
+}
+
+// activate subscribes, in the background, the subscriptions not subscribed on the current
+// connection yet.
+func (m *SubscriptionManager) activate() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.session == nil || m.ctx == nil {
+		return
+	}
+	for s := range m.subs {
+		if s.id != 0 || s.subscribing {
+			continue
+		}
+		s.subscribing = true
+		go m.subscribe(m.ctx, m.session, m.lost, s, s.request.Params, s.generation)
+	}
+}
+
+// subscribe sends the subscribe request of the subscription with the params of the
+// generation on the session. A rejected subscription is ended with the error; one lost with
+// the connection is subscribed on the next one and one whose params were updated meanwhile
+// is subscribed again with the new ones.
+func (m *SubscriptionManager) subscribe(ctx context.Context, session *session, lost <-chan struct{}, s *Subscription, params interface{}, generation int) {
+	response, err := m.call(ctx, session, lost, s.request.Method, params, s, generation)
+	m.mu.Lock()
+	s.subscribing = false
+	m.mu.Unlock()
+
+	switch {
+	case err == nil && response.stale:
+		m.activate()
+	case err == nil && response.Error != nil:
+		m.remove(s)
+		s.close(newSubscribeError(s.request.Method, *response.Error))
+	case errors.Is(err, errConnectionLost):
+		// The next connection may have been activated while the request was in flight.
+		m.activate()
+	}
+}
+
+// call sends a request on the session and waits for its response. A subscribe request of
+// sub carries the params of the generation.
+func (m *SubscriptionManager) call(ctx context.Context, session *session, lost <-chan struct{}, method string, params interface{}, sub *Subscription, generation int) (callResponse, error) {
+	call := &pendingCall{sub: sub, generation: generation, response: make(chan callResponse, 1)}
+	m.mu.Lock()
+	m.nextID++
+	id := m.nextID
+	m.pending[id] = call
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		if !call.abandoned {
+			delete(m.pending, id)
+		}
+		m.mu.Unlock()
+	}()
+
+	if err := wsjson.Write(ctx, session.conn, &JSONRPCRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params}); err != nil {
+		return callResponse{}, err
+	}
+	select {
+	case response := <-call.response:
+		return response, nil
+	case <-lost:
+		return callResponse{}, errConnectionLost
+	case <-ctx.Done():
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		select {
+		case response := <-call.response:
+			return response, nil
+		default:
+		}
+		call.abandoned = sub != nil
+		return callResponse{}, ctx.Err()
+	}
+}
+
+// receive routes a message to the call waiting for it or to its subscription.
+func (m *SubscriptionManager) receive(data []byte) {
+	var message struct {
+		ID *int `json:"id"`
+		callResponse
+		Params *struct {
+			Subscription int64 `json:"subscription"`
+		} `json:"params"`
+	}
+	if json.Unmarshal(data, &message) != nil {
+		return
+	}
+	switch {
+	case message.ID != nil:
+		m.respond(*message.ID, message.callResponse)
+	case message.Params != nil:
+		m.mu.Lock()
+		s := m.byID[message.Params.Subscription]
+		m.mu.Unlock()
+		if s == nil {
+			return
+		}
+		var notification TransactionNotification
+		if json.Unmarshal(data, &notification) != nil {
+			return
+		}
+		m.c.stats.received.Add(1)
+		select {
+		case s.queue <- &notification:
+		default:
+			m.c.stats.dropped.Add(1)
+		}
+	}
+}
+
+// respond passes the response to the call waiting for it, registering a subscription
+// acknowledged by the server under its ID. The ID the subscription replaces, after
+// UpdateFilter, keeps routing notifications until it is released, so none is missed;
+// the notifications received under both are delivered once.
+func (m *SubscriptionManager) respond(id int, response callResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	call := m.pending[id]
+	if call == nil {
+		return
+	}
+	delete(m.pending, id)
+	if s := call.sub; s != nil && response.Error == nil {
+		if _, ok := m.subs[s]; ok {
+			if subID, filter, ok := parseAcknowledgement(response.Result); ok {
+				if call.abandoned || call.generation != s.generation {
+					response.stale = true
+					m.releaseID(s, subID)
+				} else {
+					if s.id != 0 && s.id != subID {
+						m.releaseID(s, s.id)
+					}
+					s.id = subID
+					m.byID[subID] = s
+					s.info = SubscriptionInfo{
+						Method:       s.request.Method,
+						Params:       s.request.Params,
+						Filter:       filter,
+						SubscribedAt: m.c.clock().Now(),
+					}
+					if m.session != nil {
+						s.info.Endpoint = redactURL(m.session.endpoint.url)
+					}
+					s.readyOnce.Do(func() { close(s.ready) })
+				}
+			}
+		}
+	}
+	// The response is passed with the manager locked for a call giving up meanwhile to
+	// see it.
+	call.response <- response
+}
+
+// releaseID unsubscribes, in the background, a subscription ID of s that it no longer
+// uses and then stops routing its notifications. It must be called with the manager
+// locked.
+func (m *SubscriptionManager) releaseID(s *Subscription, id int64) {
+	ctx, session, lost := m.ctx, m.session, m.lost
+	if ctx == nil || session == nil {
+		return
+	}
+	go func() {
+		_, _ = m.call(ctx, session, lost, unsubscribeMethod(s.request.Method), []interface{}{id}, nil, 0)
+		m.mu.Lock()
+		if m.byID[id] == s && s.id != id {
+			delete(m.byID, id)
+		}
+		m.mu.Unlock()
+	}()
+}
+
+// remove forgets the subscription and returns the ID it was subscribed with.
+func (m *SubscriptionManager) remove(s *Subscription) (int64, *session, <-chan struct{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.subs, s)
+	id := s.id
+	if id != 0 && m.byID[id] == s {
+		delete(m.byID, id)
+	}
+	return id, m.session, m.lost
+}
+
+// Subscribe adds a subscription to the shared connection and returns once the server has
+// acknowledged it. The notifications of the subscription go through the filter stage and
+// are passed to do like with HandleTransactionsNotifications. The ID of the request is
+// replaced by the manager. Invalid params are rejected with ErrInvalidParams before they
+// are sent, see TransactionSubscribeParams.Validate.
+func (m *SubscriptionManager) Subscribe(ctx context.Context, request *JSONRPCRequest, do HandlerFunc) (*Subscription, error) {
+	if err := validateRequest(request); err != nil {
+		return nil, err
+	}
+	c := m.c
+	subCtx, cancel := context.WithCancel(context.Background())
+	s := &Subscription{
+		m:       m,
+		request: *request,
+		queue:   make(chan *TransactionNotification, max(c.config.Pipeline.ReadBuffer, 1)),
+		seen:    newSignatureSet(dedupCapacity),
+		state:   c.track(request),
+		ctx:     subCtx,
+		cancel:  cancel,
+		ready:   make(chan struct{}),
+	}
+	s.seen.namespace = c.dedupNamespace(request)
+	s.do = c.remember(subCtx, s.seen.namespace, do)
+
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		s.close(errManagerStopped)
+		return nil, errManagerStopped
+	}
+	m.subs[s] = struct{}{}
+	m.mu.Unlock()
+	go s.run()
+	m.activate()
+
+	select {
+	case <-s.ready:
+		return s, nil
+	case <-s.ctx.Done():
+		return nil, s.err
+	case <-ctx.Done():
+		_ = s.Unsubscribe(context.Background())
+		return nil, ctx.Err()
+	}
+}
+
+// run handles the notifications of the subscription until it ends.
+func (s *Subscription) run() {
+	c := s.m.c
+	for {
+		select {
+		case notification := <-s.queue:
+			if !c.admit(s.ctx, s.seen, notification) {
+				continue
+			}
+			s.state.admitted(notification.Slot(), s.seen.Len())
+			done := s.state.handling()
+			c.handle(s.ctx, notification, s.do, nil)
+			done()
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// close ends the subscription with err.
+func (s *Subscription) close(err error) {
+	s.closeOnce.Do(func() {
+		s.err = err
+		s.cancel()
+		s.m.c.untrack(s.state)
+	})
+}
+
+// Unsubscribe ends the subscription and asks the server to release it.
+func (s *Subscription) Unsubscribe(ctx context.Context) error {
+	id, session, lost := s.m.remove(s)
+	s.close(nil)
+	if id == 0 || session == nil {
+		return nil
+	}
+	method := unsubscribeMethod(s.request.Method)
+	response, err := s.m.call(ctx, session, lost, method, []interface{}{id}, nil, 0)
+	if err != nil {
+		return err
+	}
+	if response.Error != nil {
+		return fmt.Errorf("%s error %d: %s", method, response.Error.Code, response.Error.Message)
+	}
+	return nil
+}
+
+// Info returns what the server acknowledged for the subscription. Its ID is zero while
+// the subscription waits to be subscribed again after a reconnect.
+func (s *Subscription) Info() SubscriptionInfo {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	info := s.info
+	info.ID = s.id
+	return info
+}
+
+// UpdateFilter replaces the filter of the subscription without interrupting it: the
+// subscription is made again with the new filter on the shared connection and the previous
+// one released once the server has acknowledged the new one. Notifications received in
+// between under both are delivered once. It returns an error wrapping ErrInvalidParams if
+// the filter is invalid, see TransactionFilter.Validate, or an error if the server rejects
+// the new filter, in which case the previous one stays in place. While disconnected, the
+// new filter applies when the subscription is made again. If ctx ends before the server
+// responds, the previous filter stays in place too and a subscription acknowledged late is
+// released.
+func (s *Subscription) UpdateFilter(ctx context.Context, filter TransactionFilter) error {
+	if err := filter.Validate(); err != nil {
+		return fmt.Errorf("%w: filter: %w", ErrInvalidParams, err)
+	}
+	s.updating.Lock()
+	defer s.updating.Unlock()
+
+	m := s.m
+	m.mu.Lock()
+	params, ok := transactionParams(&s.request)
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("cannot update the filter of %s params %T", s.request.Method, s.request.Params)
+	}
+	previous := s.request.Params
+	params.Filter = filter
+	s.request.Params = params
+	s.generation++
+	generation := s.generation
+	subscribed := s.id != 0
+	session, lost := m.session, m.lost
+	m.mu.Unlock()
+	s.state.filterUpdated(params)
+
+	if !subscribed || session == nil {
+		// The subscription is made with the new filter once connected.
+		m.activate()
+		return nil
+	}
+	response, err := m.call(ctx, session, lost, s.request.Method, params, s, generation)
+	switch {
+	case errors.Is(err, errConnectionLost):
+		m.activate()
+		return nil
+	case err != nil:
+		s.rollback(previous, generation)
+		return err
+	case response.Error != nil:
+		s.rollback(previous, generation)
+		return newSubscribeError(s.request.Method, *response.Error)
+	}
+	return nil
+}
+
+// rollback restores the params replaced by the update of the generation.
+func (s *Subscription) rollback(previous interface{}, generation int) {
+	s.m.mu.Lock()
+	if s.generation == generation {
+		s.request.Params = previous
+		s.generation++
+	}
+	s.m.mu.Unlock()
+	s.state.filterUpdated(previous)
+}
+
+// Done is closed when the subscription has ended: it was unsubscribed, the manager was
+// stopped or the server rejected the subscription after a reconnect, see Err.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Err returns the reason the subscription ended, nil if it was unsubscribed or the manager
+// was stopped.
+func (s *Subscription) Err() error {
+	<-s.ctx.Done()
+	return s.err
+}
+
+// unsubscribeMethod returns the method releasing a subscription made with the subscribe
+// method, e.g. transactionsUnsubscribe for transactionsSubscribe.
+func unsubscribeMethod(method string) string {
+	return strings.TrimSuffix(method, "Subscribe") + "Unsubscribe"
+}
@@ -0,0 +1,328 @@
+package chainstream_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/chainstream/chainstreamtest"
+)
+
+// Valid account keys of the test subscriptions.
+const (
+	wallet         = "Wa11et1111111111111111111111111111111111111"
+	firstWallet    = "First11111111111111111111111111111111111111"
+	secondWallet   = "Second1111111111111111111111111111111111111"
+	rejectedWallet = "RejectedWa11et11111111111111111111111111111"
+	echoWallet     = "EchoWa11et111111111111111111111111111111111"
+	slowWallet     = "S1owWa11et111111111111111111111111111111111"
+)
+
+// muxServer acknowledges every subscribe request on a connection with a new subscription
+// ID and sends three notifications for it, with slots 100*ID+1 to 100*ID+3. Requests
+// mentioning rejectedWallet are rejected, those mentioning echoWallet acknowledged
+// with the filter echoed and those mentioning slowWallet acknowledged once hold is closed.
+type muxServer struct {
+	*chainstreamtest.Server
+	hold chan struct{}
+}
+
+func newMuxServer(t *testing.T) *muxServer {
+	t.Helper()
+	srv := &muxServer{hold: make(chan struct{})}
+	srv.Server = chainstreamtest.NewServer(t, chainstreamtest.Config{
+		Respond: func(ctx context.Context, request chainstreamtest.Request, id int64) (interface{}, *chainstream.RPCError) {
+			params := string(request.Params)
+			if strings.Contains(params, slowWallet) {
+				select {
+				case <-srv.hold:
+				case <-ctx.Done():
+				}
+			}
+			switch {
+			case strings.Contains(params, rejectedWallet):
+				return nil, &chainstream.RPCError{Code: -32602, Message: "invalid network"}
+			case strings.Contains(params, echoWallet):
+				transactionParams, _ := request.TransactionParams()
+				return map[string]interface{}{"subscription": id, "filter": transactionParams.Filter}, nil
+			}
+			return nil, nil
+		},
+		Notify: func(request chainstreamtest.Request, id int64) []*chainstream.TransactionNotification {
+			if request.Method != "transactionsSubscribe" {
+				return nil
+			}
+			var notifications []*chainstream.TransactionNotification
+			for i := uint64(1); i <= 3; i++ {
+				notifications = append(notifications, notification(uint64(id)*100+i))
+			}
+			return notifications
+		},
+	})
+	return srv
+}
+
+func TestSubscriptionManager(t *testing.T) {
+	srv := newMuxServer(t)
+	client := chainstream.NewClient(chainstream.NewConfig(srv.Endpoint()))
+	manager := client.NewSubscriptionManager()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- manager.Run(ctx) }()
+
+	var (
+		mu        sync.Mutex
+		delivered = make(map[string][]uint64)
+		all       sync.WaitGroup
+	)
+	subscribe := func(name string) *chainstream.Subscription {
+		all.Add(3)
+		sub, err := manager.Subscribe(ctx, chainstream.WatchWallets(name), func(n *chainstream.TransactionNotification) error {
+			mu.Lock()
+			defer mu.Unlock()
+			delivered[name] = append(delivered[name], n.Slot())
+			all.Done()
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Subscribe(%s) error: %v", name, err)
+		}
+		return sub
+	}
+	first := subscribe(firstWallet)
+	second := subscribe(secondWallet)
+	all.Wait()
+
+	mu.Lock()
+	if got := delivered[firstWallet]; len(got) != 3 || got[0] != 101 || got[2] != 103 {
+		t.Errorf("first subscription got %v, expected slots 101 to 103", got)
+	}
+	if got := delivered[secondWallet]; len(got) != 3 || got[0] != 201 || got[2] != 203 {
+		t.Errorf("second subscription got %v, expected slots 201 to 203", got)
+	}
+	mu.Unlock()
+	if n := srv.Connections(); n != 1 {
+		t.Errorf("opened %d connections, expected one shared connection", n)
+	}
+
+	_, err := manager.Subscribe(ctx, chainstream.WatchWallets(rejectedWallet), func(*chainstream.TransactionNotification) error { return nil })
+	if !errors.Is(err, chainstream.ErrInvalidNetwork) {
+		t.Errorf("Subscribe() error = %v, expected ErrInvalidNetwork", err)
+	}
+
+	if err := second.Unsubscribe(ctx); err != nil {
+		t.Fatalf("Unsubscribe() error: %v", err)
+	}
+	if unsubscribed := srv.Unsubscribed(); len(unsubscribed) != 1 || unsubscribed[0] != 2 {
+		t.Errorf("server released %v, expected subscription 2", unsubscribed)
+	}
+	select {
+	case <-second.Done():
+	default:
+		t.Error("unsubscribed subscription is not done")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() error: %v", err)
+	}
+	if err := first.Err(); err != nil {
+		t.Errorf("Err() = %v after the manager stopped, expected nil", err)
+	}
+}
+
+func TestSubscriptionManagerSlowHandler(t *testing.T) {
+	srv := newMuxServer(t)
+	config := chainstream.NewConfig(srv.Endpoint())
+	config.Pipeline.ReadBuffer = 1
+	client := chainstream.NewClient(config)
+	manager := client.NewSubscriptionManager()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() { _ = manager.Run(ctx) }()
+
+	// The first handler holds its first notification, so the queue of one fills up.
+	release := make(chan struct{})
+	defer close(release)
+	_, err := manager.Subscribe(ctx, chainstream.WatchWallets(firstWallet), func(*chainstream.TransactionNotification) error {
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe(first) error: %v", err)
+	}
+	var second sync.WaitGroup
+	second.Add(3)
+	_, err = manager.Subscribe(ctx, chainstream.WatchWallets(secondWallet), func(*chainstream.TransactionNotification) error {
+		second.Done()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe(second) error: %v", err)
+	}
+	delivered := make(chan struct{})
+	go func() {
+		second.Wait()
+		close(delivered)
+	}()
+	select {
+	case <-delivered:
+	case <-ctx.Done():
+		t.Fatal("the slow handler of the first subscription held up the second")
+	}
+	// The first handler takes one notification and the queue one more, unless the third
+	// arrives before the handler took the first.
+	if dropped := client.Stats().Dropped; dropped < 1 || dropped > 2 {
+		t.Errorf("dropped %d notifications, expected those beyond the full queue", dropped)
+	}
+}
+
+func TestSubscriptionInfo(t *testing.T) {
+	srv := newMuxServer(t)
+	client := chainstream.NewClient(chainstream.NewConfig(srv.Endpoint() + "/api-key/secret"))
+	manager := client.NewSubscriptionManager()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() { _ = manager.Run(ctx) }()
+
+	plain, err := manager.Subscribe(ctx, chainstream.WatchWallets(wallet), func(*chainstream.TransactionNotification) error { return nil })
+	if err != nil {
+		t.Fatalf("Subscribe() error: %v", err)
+	}
+	info := plain.Info()
+	if info.ID != 1 || info.Method != "transactionsSubscribe" || info.Filter != nil || info.SubscribedAt.IsZero() {
+		t.Errorf("Info() = %+v, expected subscription 1 without a filter echo", info)
+	}
+	if !strings.HasSuffix(info.Endpoint, "/api-key/xxx") {
+		t.Errorf("Info().Endpoint = %q, expected the token to be masked", info.Endpoint)
+	}
+
+	echoed, err := manager.Subscribe(ctx, chainstream.WatchWallets(echoWallet), func(*chainstream.TransactionNotification) error { return nil })
+	if err != nil {
+		t.Fatalf("Subscribe() error: %v", err)
+	}
+	info = echoed.Info()
+	if info.ID != 2 || !strings.Contains(string(info.Filter), echoWallet) {
+		t.Errorf("Info() = %+v, expected subscription 2 with the echoed filter", info)
+	}
+}
+
+func TestSubscriptionUpdateFilter(t *testing.T) {
+	srv := newMuxServer(t)
+	client := chainstream.NewClient(chainstream.NewConfig(srv.Endpoint()))
+	manager := client.NewSubscriptionManager()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() { _ = manager.Run(ctx) }()
+
+	var (
+		mu        sync.Mutex
+		delivered []uint64
+		all       sync.WaitGroup
+	)
+	all.Add(6)
+	sub, err := manager.Subscribe(ctx, chainstream.WatchWallets(firstWallet), func(n *chainstream.TransactionNotification) error {
+		mu.Lock()
+		defer mu.Unlock()
+		delivered = append(delivered, n.Slot())
+		all.Done()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe() error: %v", err)
+	}
+
+	filter := chainstream.WatchWallets(secondWallet).Params.(chainstream.TransactionSubscribeParams).Filter
+	if err := sub.UpdateFilter(ctx, filter); err != nil {
+		t.Fatalf("UpdateFilter() error: %v", err)
+	}
+	all.Wait()
+	mu.Lock()
+	if len(delivered) != 6 || delivered[5] != 203 {
+		t.Errorf("delivered %v, expected slots 101 to 103 and 201 to 203", delivered)
+	}
+	mu.Unlock()
+	if info := sub.Info(); info.ID != 2 {
+		t.Errorf("Info().ID = %d after the update, expected 2", info.ID)
+	}
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		unsubscribed := srv.Unsubscribed()
+		released := len(unsubscribed) == 1 && unsubscribed[0] == 1
+		if released {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the replaced subscription 1 was not released")
+		}
+	}
+
+	rejected := chainstream.WatchWallets(rejectedWallet).Params.(chainstream.TransactionSubscribeParams).Filter
+	if err := sub.UpdateFilter(ctx, rejected); !errors.Is(err, chainstream.ErrInvalidNetwork) {
+		t.Errorf("UpdateFilter() error = %v, expected ErrInvalidNetwork", err)
+	}
+	if info := sub.Info(); info.ID != 2 || strings.Contains(fmt.Sprint(info.Params), rejectedWallet) {
+		t.Errorf("Info() = %+v after a rejected update, expected subscription 2 to stay", info)
+	}
+	if connections := srv.Connections(); connections != 1 {
+		t.Errorf("opened %d connections, expected the update to reuse the connection", connections)
+	}
+}
+
+func TestSubscriptionUpdateFilterCanceled(t *testing.T) {
+	srv := newMuxServer(t)
+	client := chainstream.NewClient(chainstream.NewConfig(srv.Endpoint()))
+	manager := client.NewSubscriptionManager()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() { _ = manager.Run(ctx) }()
+
+	var delivered atomic.Int32
+	sub, err := manager.Subscribe(ctx, chainstream.WatchWallets(firstWallet), func(n *chainstream.TransactionNotification) error {
+		delivered.Add(1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe() error: %v", err)
+	}
+
+	slow := chainstream.WatchWallets(slowWallet).Params.(chainstream.TransactionSubscribeParams).Filter
+	updateCtx, updateCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer updateCancel()
+	if err := sub.UpdateFilter(updateCtx, slow); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("UpdateFilter() error = %v, expected context.DeadlineExceeded", err)
+	}
+	if info := sub.Info(); info.ID != 1 || strings.Contains(fmt.Sprint(info.Params), slowWallet) {
+		t.Errorf("Info() = %+v after a canceled update, expected subscription 1 to stay", info)
+	}
+
+	// The server acknowledges the update late: the subscription is released.
+	close(srv.hold)
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		unsubscribed := srv.Unsubscribed()
+		released := len(unsubscribed) == 1 && unsubscribed[0] == 2
+		if released {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the subscription 2 acknowledged late was not released")
+		}
+	}
+	if info := sub.Info(); info.ID != 1 || strings.Contains(fmt.Sprint(info.Params), slowWallet) {
+		t.Errorf("Info() = %+v after the late acknowledgement, expected subscription 1 to stay", info)
+	}
+	if n := delivered.Load(); n != 3 {
+		t.Errorf("delivered %d notifications, expected the 3 of subscription 1 only", n)
+	}
+}
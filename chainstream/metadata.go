@@ -0,0 +1,76 @@
+package chainstream
+
+import (
+	"context"
+	"time"
+)
+
+// Metadata describes how a notification was received. It is carried by the context passed
+// to a ContextHandlerFunc, see MetadataFrom.
+type Metadata struct {
+	// Subscription is the ID the server gave the subscription.
+	Subscription int64
+	// Endpoint is the endpoint the notification was received from, with the API key
+	// masked. It is empty for notifications not received live, e.g. backfilled or
+	// replayed ones.
+	Endpoint string
+	// ReceivedAt is the time the notification was received, by Config.Clock. It is zero
+	// for notifications not received live.
+	ReceivedAt time.Time
+}
+
+// ContextHandlerFunc handles a notification like HandlerFunc, with a context carrying the
+// Metadata of the notification and, with Hooks.Trace, its trace span. The context is done
+// when the subscription ends.
+type ContextHandlerFunc func(ctx context.Context, notification *TransactionNotification) error
+
+// TraceFunc starts the span of a handler attempt, e.g. with OpenTelemetry, returning the
+// context carrying it and a function ending it with the result of the attempt.
+type TraceFunc func(ctx context.Context, notification *TransactionNotification, metadata Metadata) (context.Context, func(err error))
+
+type metadataKey struct{}
+
+// MetadataFrom returns the metadata carried by the context of a ContextHandlerFunc.
+func MetadataFrom(ctx context.Context) (Metadata, bool) {
+	metadata, ok := ctx.Value(metadataKey{}).(Metadata)
+	return metadata, ok
+}
+
+// metadata returns the metadata of the notification.
+func (t *TransactionNotification) metadata() Metadata {
+	metadata := Metadata{Subscription: t.Params.Subscription, ReceivedAt: t.receivedAt}
+	if t.endpoint != nil {
+		metadata.Endpoint = redactURL(t.endpoint.url)
+	}
+	return metadata
+}
+
+// HandleTransactionsNotificationsContext subscribes to transaction updates like
+// HandleTransactionsNotifications, passing every handler attempt a context that carries
+// the metadata of the notification. It is derived from the context of the subscription,
+// done when the subscription ends, also by WithMaxDuration or WithMaxNotifications.
+func (c *C) HandleTransactionsNotificationsContext(
+	ctx context.Context,
+	request *JSONRPCRequest,
+	do ContextHandlerFunc,
+	opts ...SubscribeOption,
+) error {
+	opts = append(opts, func(o *subscribeOptions) { o.contextDo = do })
+	return c.HandleTransactionsNotifications(ctx, request, nil, opts...)
+}
+
+// withContext adapts do to a HandlerFunc, tracing every attempt with Hooks.Trace.
+func (c *C) withContext(ctx context.Context, do ContextHandlerFunc) HandlerFunc {
+	return func(notification *TransactionNotification) error {
+		metadata := notification.metadata()
+		ctx := context.WithValue(ctx, metadataKey{}, metadata)
+		trace := c.config.Hooks.Trace
+		if trace == nil {
+			return do(ctx, notification)
+		}
+		ctx, end := trace(ctx, notification, metadata)
+		err := do(ctx, notification)
+		end(err)
+		return err
+	}
+}
@@ -0,0 +1,90 @@
+package chainstream_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+type spanKey struct{}
+
+func TestHandleTransactionsNotificationsContext(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1, 2))
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Pipeline.Retry.MaxAttempts = 2
+	var ended []error
+	config.Hooks.Trace = func(ctx context.Context, n *chainstream.TransactionNotification, metadata chainstream.Metadata) (context.Context, func(error)) {
+		return context.WithValue(ctx, spanKey{}, n.Signature()), func(err error) {
+			ended = append(ended, err)
+		}
+	}
+	failure := errors.New("transient")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var handled []chainstream.Metadata
+	err := chainstream.NewClient(config).HandleTransactionsNotificationsContext(ctx, chainstream.FirehoseNoVotes(), func(ctx context.Context, n *chainstream.TransactionNotification) error {
+		metadata, ok := chainstream.MetadataFrom(ctx)
+		if !ok {
+			t.Fatal("the handler context carries no metadata")
+		}
+		if span := ctx.Value(spanKey{}); span != n.Signature() {
+			t.Errorf("the handler context carries span %v, expected %s", span, n.Signature())
+		}
+		if handled = append(handled, metadata); len(handled) == 1 {
+			return failure
+		}
+		if n.Slot() == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotificationsContext() error: %v", err)
+	}
+
+	if len(handled) != 3 {
+		t.Fatalf("handled %d attempts, expected a retried one and another", len(handled))
+	}
+	for i, metadata := range handled {
+		if metadata.Endpoint != srv.endpoint() || metadata.ReceivedAt.IsZero() {
+			t.Errorf("attempt %d has metadata %+v, expected the endpoint %s and a receive time", i, metadata, srv.endpoint())
+		}
+	}
+	if len(ended) != 3 || !errors.Is(ended[0], failure) || ended[1] != nil || ended[2] != nil {
+		t.Errorf("spans ended with %v, expected the failure then nil twice", ended)
+	}
+}
+
+func TestHandlerContextOfSubscription(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1, 2))
+	config := chainstream.NewConfig(srv.endpoint())
+	read := time.Unix(1700000000, 0)
+	config.Clock = clock.NewFake(read)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var (
+		handlerCtx context.Context
+		metadata   chainstream.Metadata
+	)
+	err := chainstream.NewClient(config).HandleTransactionsNotificationsContext(ctx, chainstream.FirehoseNoVotes(), func(ctx context.Context, n *chainstream.TransactionNotification) error {
+		handlerCtx = ctx
+		metadata, _ = chainstream.MetadataFrom(ctx)
+		return nil
+	}, chainstream.WithMaxNotifications(1))
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotificationsContext() error: %v", err)
+	}
+
+	if handlerCtx == nil || handlerCtx.Err() == nil || ctx.Err() != nil {
+		t.Error("the handler context is not done once the subscription has ended")
+	}
+	if !metadata.ReceivedAt.Equal(read) {
+		t.Errorf("ReceivedAt = %v, expected the time the notification was read, %v", metadata.ReceivedAt, read)
+	}
+}
@@ -0,0 +1,77 @@
+package chainstream
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Network is a Solana cluster served by ChainStream, named like the RPC host of the
+// cluster, e.g. solana-mainnet for solana-mainnet.api.syndica.io.
+type Network string
+
+// Networks served by ChainStream.
+const (
+	NetworkMainnet Network = "solana-mainnet"
+	NetworkDevnet  Network = "solana-devnet"
+	NetworkTestnet Network = "solana-testnet"
+)
+
+// Networks lists the networks served by ChainStream.
+var Networks = []Network{NetworkMainnet, NetworkDevnet, NetworkTestnet}
+
+// ParseNetwork returns the network named s, case-insensitively, with or without the
+// solana- prefix, e.g. devnet for NetworkDevnet. It returns an error wrapping
+// ErrInvalidNetwork for other names.
+func ParseNetwork(s string) (Network, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	for _, n := range Networks {
+		if name == string(n) || name == strings.TrimPrefix(string(n), "solana-") {
+			return n, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q, expected one of %s", ErrInvalidNetwork, s, networkNames())
+}
+
+// Validate returns an error wrapping ErrInvalidNetwork if the network is not one of
+// Networks.
+func (n Network) Validate() error {
+	for _, known := range Networks {
+		if n == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %q, expected one of %s", ErrInvalidNetwork, string(n), networkNames())
+}
+
+func (n Network) String() string { return string(n) }
+
+// UnmarshalText implements encoding.TextUnmarshaler with ParseNetwork, so that a typo in
+// a configuration file is reported when it is loaded. An empty name is kept for defaults.
+func (n *Network) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*n = ""
+		return nil
+	}
+	network, err := ParseNetwork(string(text))
+	if err != nil {
+		return err
+	}
+	*n = network
+	return nil
+}
+
+// orDefault returns the network, DefaultNetwork if empty.
+func (n Network) orDefault() Network {
+	if n == "" {
+		return DefaultNetwork
+	}
+	return n
+}
+
+func networkNames() string {
+	names := make([]string, len(Networks))
+	for i, n := range Networks {
+		names[i] = string(n)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,53 @@
+package chainstream_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestParseNetwork(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected chainstream.Network
+		valid    bool
+	}{
+		{"solana-mainnet", chainstream.NetworkMainnet, true},
+		{" Solana-Devnet", chainstream.NetworkDevnet, true},
+		{"testnet", chainstream.NetworkTestnet, true},
+		{"solana-mainet", "", false},
+		{"", "", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := chainstream.ParseNetwork(tc.name)
+			if (err == nil) != tc.valid {
+				t.Fatalf("ParseNetwork(%q) error = %v, expected valid = %v", tc.name, err, tc.valid)
+			}
+			if err != nil && !errors.Is(err, chainstream.ErrInvalidNetwork) {
+				t.Errorf("ParseNetwork(%q) error = %v, expected ErrInvalidNetwork", tc.name, err)
+			}
+			if got != tc.expected {
+				t.Errorf("ParseNetwork(%q) = %q, expected %q", tc.name, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestNetworkTypos(t *testing.T) {
+	var config chainstream.Config
+	if err := json.Unmarshal([]byte(`{"subscriptions":[{"params":{"network":"solana-mainet"}}]}`), &config); err == nil {
+		t.Error("a configuration with an unknown network was loaded")
+	}
+
+	params := chainstream.TransactionSubscribeParams{Network: "solana-mainet", Filter: chainstream.TransactionFilter{Commitment: chainstream.CommitmentConfirmed}}
+	if err := params.Validate(); !errors.Is(err, chainstream.ErrInvalidNetwork) {
+		t.Errorf("Validate() error = %v, expected ErrInvalidNetwork", err)
+	}
+	if _, err := chainstream.NewBlocksSubscribeRequest("solana-mainet"); err == nil {
+		t.Error("NewBlocksSubscribeRequest() accepted an unknown network")
+	}
+}
@@ -0,0 +1,141 @@
+package chainstream
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// notifications runs a subscription whose notifications are not transactions, e.g.
+// blocksSubscribe or accountSubscribe. Notifications are received over scored endpoints
+// with pings and reconnects. identify returns the slot of a notification and the key
+// telling duplicates apart, and handle is called once per key, see dispatch; it returns
+// errNotDelivered for a notification it did not pass on. name names the notifications in
+// the reason of closing the connection.
+func (c *C) notifications(
+	ctx context.Context,
+	request *JSONRPCRequest,
+	name string,
+	opts []SubscribeOption,
+	identify func(event sessionEvent) (slot uint64, key string),
+	handle func(event sessionEvent) error,
+) error {
+	var connects sync.WaitGroup
+	defer connects.Wait()
+	options := newSubscribeOptions(opts)
+	ctx, cancel := options.bound(ctx, c)
+	defer cancel()
+
+	events := make(chan sessionEvent)
+	decode := c.decoderFor(request)
+	current, err := c.openSession(ctx, request, decode, events, false)
+	if err != nil {
+		return err
+	}
+	connection := c.newLifecycle(request)
+	connected := func() {
+		connection.connected(current)
+		if options.connected == nil {
+			return
+		}
+		connects.Add(1)
+		go func() {
+			defer connects.Done()
+			if err := options.connected(ctx); err != nil {
+				options.abort(&handlerFailure{err})
+			}
+		}()
+	}
+	connected()
+	defer func() {
+		current.release("subscription of " + name + " notifications was closed")
+	}()
+
+	clk := c.clock()
+	ticker := clk.NewTicker(c.pingInterval())
+	defer ticker.Stop()
+	idleTicks, stopWatchdog := c.watchdog()
+	defer stopWatchdog()
+
+	seen := newSignatureSet(dedupCapacity)
+	handled := 0
+	state := c.track(request)
+	defer c.untrack(state)
+
+	for {
+		state.sessionsChanged(current, nil)
+		select {
+		case <-ctx.Done():
+			return options.failure(ctx)
+		case <-idleTicks:
+			c.checkIdle(current, request.Method, clk.Now())
+		case <-ticker.C():
+			go c.ping(ctx, current)
+		case event := <-events:
+			if event.session != current {
+				continue
+			}
+			if event.err != nil {
+				if errors.Is(event.err, context.Canceled) || ctx.Err() != nil {
+					return options.failure(ctx)
+				}
+				current.close("session failed")
+				c.endpointSet().observeFailure(current.endpoint, clk.Now())
+				connection.disconnected(current, event.err)
+				if !c.awaitReconnect(ctx) {
+					return options.failure(ctx)
+				}
+				next, err := c.openSession(ctx, request, decode, events, true)
+				if err != nil {
+					if ctx.Err() != nil {
+						return options.failure(ctx)
+					}
+					return err
+				}
+				current = next
+				c.stats.reconnects.Add(1)
+				connected()
+				continue
+			}
+
+			current.lastReceived = clk.Now()
+			c.stats.received.Add(1)
+			slot, key := identify(event)
+			if !seen.Add(key) {
+				c.stats.duplicates.Add(1)
+				continue
+			}
+			state.admitted(slot, seen.Len())
+			done := state.handling()
+			err := handle(event)
+			done()
+			switch {
+			case err == nil:
+				c.stats.delivered.Add(1)
+			case errors.Is(err, errNotDelivered):
+			case c.panicked(err):
+				c.stats.failed.Add(1)
+			default:
+				return err
+			}
+			if handled++; options.done(handled) {
+				return nil
+			}
+		}
+	}
+}
+
+// dispatch calls do with a notification of the slot, recording its latency. A panic is
+// returned as a PanicError.
+func (c *C) dispatch(slot uint64, do func()) (err error) {
+	defer func() {
+		if p := recovered(recover(), slot, ""); p != nil {
+			err = p
+		}
+	}()
+	start := time.Now()
+	defer c.latency.since(StageDispatch, start)
+	do()
+	return nil
+}
@@ -0,0 +1,103 @@
+package chainstream
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// SubscribeOption bounds a single subscription.
+type SubscribeOption func(*subscribeOptions)
+
+type subscribeOptions struct {
+	maxNotifications int
+	maxDuration      time.Duration
+	failFast         bool
+	rewind           *rewind
+	cursor           Cursor
+	batch            BatchHandlerFunc
+	// contextDo, if not nil, replaces the handler, passed the context of the subscription.
+	contextDo       ContextHandlerFunc
+	blockhashValid  BlockhashValidity
+	signatureLookup SignatureLookup
+	// connected, if not nil, is run in a goroutine every time the subscription is
+	// established, by the loop of notifications. An error ends the subscription.
+	connected func(ctx context.Context) error
+	abort     context.CancelCauseFunc
+}
+
+// WithMaxNotifications ends the subscription after n notifications have been handled,
+// counting the ones that failed. Zero does not limit the subscription.
+func WithMaxNotifications(n int) SubscribeOption {
+	return func(o *subscribeOptions) { o.maxNotifications = n }
+}
+
+// WithMaxDuration ends the subscription once it has been running for d, measured on
+// Config.Clock. Zero does not limit the subscription.
+func WithMaxDuration(d time.Duration) SubscribeOption {
+	return func(o *subscribeOptions) { o.maxDuration = d }
+}
+
+// WithFailFast ends the subscription when handling a notification fails after all retries,
+// and returns the handler error from the subscription call instead of passing the
+// notification to Hooks.DeadLetter.
+func WithFailFast() SubscribeOption {
+	return func(o *subscribeOptions) { o.failFast = true }
+}
+
+func newSubscribeOptions(opts []SubscribeOption) subscribeOptions {
+	var o subscribeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// bound returns a context canceled after the maximum duration of the subscription or when
+// a handler fails with WithFailFast.
+func (o *subscribeOptions) bound(ctx context.Context, c *C) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	o.abort = cancel
+	if o.maxDuration > 0 {
+		deadline := c.clock().After(o.maxDuration)
+		go func() {
+			select {
+			case <-deadline:
+				cancel(nil)
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return ctx, func() { cancel(nil) }
+}
+
+// handlerFailure is the cause of a subscription ended by a handler error.
+type handlerFailure struct {
+	err error
+}
+
+func (e *handlerFailure) Error() string { return e.err.Error() }
+
+// fail ends the subscription with the handler error when WithFailFast is set. It reports
+// whether it did.
+func (o *subscribeOptions) fail(err error) bool {
+	if !o.failFast || err == nil {
+		return false
+	}
+	o.abort(&handlerFailure{err})
+	return true
+}
+
+// failure returns the handler error that ended the subscription, if any.
+func (o *subscribeOptions) failure(ctx context.Context) error {
+	var failure *handlerFailure
+	if errors.As(context.Cause(ctx), &failure) {
+		return failure.err
+	}
+	return nil
+}
+
+// done reports whether the subscription has handled its maximum number of notifications.
+func (o *subscribeOptions) done(handled int) bool {
+	return o.maxNotifications > 0 && handled >= o.maxNotifications
+}
@@ -0,0 +1,77 @@
+package chainstream_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+func TestWithMaxNotifications(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1, 2, 3, 4))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var delivered []uint64
+	err := chainstream.NewClient(chainstream.NewConfig(srv.endpoint())).TransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) {
+		delivered = append(delivered, n.Slot())
+	}, chainstream.WithMaxNotifications(2))
+	if err != nil {
+		t.Fatalf("TransactionsNotifications() error: %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("subscription ended by the test timeout")
+	}
+	if len(delivered) != 2 {
+		t.Errorf("delivered %v, expected two notifications", delivered)
+	}
+}
+
+func TestWithMaxDuration(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1))
+	fake := clock.NewFake(time.Now())
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Clock = fake
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	notifications, errs := chainstream.NewClient(config).Stream(ctx, chainstream.FirehoseNoVotes(), chainstream.WithMaxDuration(time.Minute))
+	<-notifications
+	fake.Advance(time.Minute)
+	for range notifications {
+	}
+	if err := <-errs; err != nil || ctx.Err() != nil {
+		t.Errorf("subscription ended with %v, context %v", err, ctx.Err())
+	}
+}
+
+func TestWithFailFast(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1, 2, 3))
+	config := chainstream.NewConfig(srv.endpoint())
+	deadLettered := 0
+	config.Hooks.DeadLetter = func(*chainstream.TransactionNotification, error) { deadLettered++ }
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	errBroken := errors.New("broken")
+	var delivered []uint64
+	err := client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) error {
+		delivered = append(delivered, n.Slot())
+		if n.Slot() == 2 {
+			return errBroken
+		}
+		return nil
+	}, chainstream.WithFailFast())
+	if !errors.Is(err, errBroken) {
+		t.Fatalf("HandleTransactionsNotifications() error = %v, expected the handler error", err)
+	}
+	if len(delivered) != 2 || deadLettered != 0 {
+		t.Errorf("delivered %v, dead-lettered %d, expected the subscription to stop at slot 2", delivered, deadLettered)
+	}
+}
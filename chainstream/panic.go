@@ -0,0 +1,54 @@
+package chainstream
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is a panic recovered from a handler or Hooks.Enrich.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+	// Slot and Signature identify the notification being handled.
+	Slot      uint64
+	Signature string
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic handling notification %s: %v", e.Signature, e.Value)
+}
+
+// Unwrap returns the panic value when it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// PanicFunc is called with every panic recovered from a handler.
+type PanicFunc func(err *PanicError)
+
+// recovered turns a recovered panic value into a PanicError, or returns nil.
+func recovered(value interface{}, slot uint64, signature string) *PanicError {
+	if value == nil {
+		return nil
+	}
+	return &PanicError{Value: value, Stack: debug.Stack(), Slot: slot, Signature: signature}
+}
+
+// panicked reports a handler panic to Hooks.Panic and reports whether the subscription
+// may go on. Without the hook the subscription ends with the panic.
+func (c *C) panicked(err error) bool {
+	var p *PanicError
+	if !errors.As(err, &p) {
+		return true
+	}
+	c.stats.panics.Add(1)
+	if c.config.Hooks.Panic == nil {
+		return false
+	}
+	c.config.Hooks.Panic(p)
+	return true
+}
@@ -0,0 +1,61 @@
+package chainstream_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestHandlerPanic(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    bool
+		workers int
+	}{
+		{"without hook", false, 0},
+		{"with hook", true, 0},
+		{"with hook and workers", true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeServer(t, sendSlots(1, 2, 3))
+			config := chainstream.NewConfig(srv.endpoint())
+			config.Pipeline.Workers = tt.workers
+			panics := make(chan *chainstream.PanicError, 3)
+			if tt.hook {
+				config.Hooks.Panic = func(err *chainstream.PanicError) { panics <- err }
+			}
+			client := chainstream.NewClient(config)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			errBoom := errors.New("boom")
+			err := client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) error {
+				if n.Slot() == 2 {
+					panic(errBoom)
+				}
+				return nil
+			}, chainstream.WithMaxNotifications(3))
+
+			var p *chainstream.PanicError
+			if tt.hook {
+				if err != nil {
+					t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+				}
+				if stats := client.Stats(); stats.Panics != 1 || stats.Failed != 1 || stats.Delivered != 2 {
+					t.Errorf("Stats() = %+v, expected one panic and two delivered notifications", stats)
+				}
+				p = <-panics
+			} else if !errors.As(err, &p) {
+				t.Fatalf("HandleTransactionsNotifications() error = %v, expected a PanicError", err)
+			}
+			if !errors.Is(p, errBoom) || p.Slot != 2 || p.Signature != "sig-2" || len(p.Stack) == 0 {
+				t.Errorf("PanicError = %v at slot %d, signature %q", p, p.Slot, p.Signature)
+			}
+		})
+	}
+}
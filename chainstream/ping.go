@@ -0,0 +1,77 @@
+package chainstream
+
+import (
+	"context"
+	"time"
+
+	"nhooyr.io/websocket"
+)
+
+// pingInterval returns the time between pings, see ConnConfig.PingInterval.
+func (c *C) pingInterval() time.Duration {
+	if c.config.Conn.PingInterval > 0 {
+		return time.Duration(c.config.Conn.PingInterval)
+	}
+	return time.Duration(DefaultPingInterval)
+}
+
+// ping sends a ping on the session and waits ConnConfig.PingTimeout for the pong, recording
+// the round-trip time. Pings do not overlap: while the previous ping still waits for its
+// pong, the tick counts as another failure instead. A session failing
+// ConnConfig.MaxPingFailures pings in a row is closed so that the client reconnects.
+func (c *C) ping(ctx context.Context, s *session) {
+	if !s.pinging.CompareAndSwap(false, true) {
+		c.pingFailed(s)
+		return
+	}
+	timeout := time.Duration(c.config.Conn.PingTimeout)
+	if timeout <= 0 {
+		timeout = time.Duration(DefaultPingTimeout)
+	}
+
+	// The pong is awaited without a deadline, as the connection is closed when a ping
+	// is canceled; for the same reason it outlives ctx, so that the subscription can still
+	// be released, until the session is closed. A late pong still counts as a failure.
+	clk := c.clock()
+	start := clk.Now()
+	result := make(chan error, 1)
+	go func() {
+		err := s.conn.Ping(context.WithoutCancel(ctx))
+		s.pinging.Store(false)
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			if ctx.Err() == nil {
+				c.pingFailed(s)
+			}
+			return
+		}
+	case <-clk.After(timeout):
+		c.pingFailed(s)
+		return
+	}
+	s.pingFailures.Store(0)
+	c.stats.pings.Add(1)
+	rtt := clk.Now().Sub(start)
+	c.rtt.observe(rtt)
+	c.endpointSet().observeRTT(s.endpoint, rtt)
+}
+
+// pingFailed counts a failed ping of the session and closes it after
+// ConnConfig.MaxPingFailures failures in a row.
+func (c *C) pingFailed(s *session) {
+	c.stats.pingFailures.Add(1)
+	if int(s.pingFailures.Add(1)) < max(c.config.Conn.MaxPingFailures, 1) {
+		return
+	}
+	// Closing the connection makes the session reader fail, which triggers a reconnect.
+	_ = s.conn.Close(websocket.StatusGoingAway, "ping timeout")
+}
+
+// RTT returns a snapshot of the ping round-trip times of all sessions.
+func (c *C) RTT() Histogram {
+	return c.rtt.snapshot()
+}
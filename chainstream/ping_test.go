@@ -0,0 +1,101 @@
+package chainstream_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+func TestPingRTT(t *testing.T) {
+	srv := newFakeServer(t, sendSlots())
+	fake := clock.NewFake(time.Now())
+
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Clock = fake
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(*chainstream.TransactionNotification) error {
+			return nil
+		})
+	}()
+
+	fake.BlockUntil(1)
+	fake.Advance(30 * time.Second)
+	for client.Stats().Pings == 0 && ctx.Err() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+
+	rtt := client.RTT()
+	if stats := client.Stats(); stats.Pings != 1 || stats.PingFailures != 0 || rtt.Count != 1 {
+		t.Errorf("Stats() = %+v, RTT count %d, expected one answered ping", stats, rtt.Count)
+	}
+	t.Logf("🏓 rtt p50 <= %v", rtt.Quantile(0.5))
+}
+
+func TestPingFailuresReconnect(t *testing.T) {
+	srv := newFakeServer(t, sendSlots())
+	// No pong arrives until the client gives up on the connection.
+	srv.silent.Store(true)
+	fake := clock.NewFake(time.Now())
+
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Conn.PingInterval = chainstream.Duration(5 * time.Second)
+	config.Conn.PingTimeout = chainstream.Duration(time.Second)
+	config.Conn.MaxPingFailures = 2
+	config.Clock = fake
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(*chainstream.TransactionNotification) error {
+			return nil
+		})
+	}()
+
+	fake.BlockUntil(1)
+	for i := uint64(1); i <= 2; i++ {
+		fake.Advance(5 * time.Second)
+		if i == 1 {
+			// The ping ticker and the pong timeout.
+			fake.BlockUntil(2)
+			fake.Advance(time.Second)
+		}
+		// The second ping is not sent while the first one still waits for its pong.
+		for client.Stats().PingFailures < i && ctx.Err() == nil {
+			time.Sleep(time.Millisecond)
+		}
+		if i == 1 && srv.connections.Load() != 1 {
+			t.Errorf("reconnected after a single failed ping")
+		}
+	}
+	// The server answers the close handshake.
+	srv.silent.Store(false)
+	// The ping ticker and the reconnect delay.
+	fake.BlockUntil(2)
+	fake.Advance(time.Second)
+	for srv.connections.Load() < 2 && ctx.Err() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+	if n := srv.connections.Load(); n != 2 {
+		t.Errorf("%d connections, expected a reconnect after two failed pings", n)
+	}
+}
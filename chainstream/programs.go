@@ -0,0 +1,269 @@
+package chainstream
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/mr-tron/base58"
+)
+
+// MaxProgramFilters is the number of filters nodes accept in programSubscribe.
+const MaxProgramFilters = 4
+
+// maxMemcmpBase58 is the largest memcmp filter nodes accept in base58, in bytes.
+const maxMemcmpBase58 = 128
+
+// ProgramFilter selects the accounts of a program by their data: by the size of the data
+// or by bytes at an offset. Exactly one of DataSize and Memcmp is set; DataSize is a
+// pointer so that empty accounts can be matched with a size of 0.
+type ProgramFilter struct {
+	DataSize *uint64 `json:"dataSize,omitempty"`
+	Memcmp   *Memcmp `json:"memcmp,omitempty"`
+}
+
+// Memcmp matches accounts whose data holds Bytes at Offset.
+type Memcmp struct {
+	Offset uint64 `json:"offset"`
+	// Bytes are the bytes to match in Encoding.
+	Bytes string `json:"bytes"`
+	// Encoding is EncodingBase58, the default, or EncodingBase64.
+	Encoding AccountEncoding `json:"encoding,omitempty"`
+}
+
+// DataSizeFilter matches the accounts whose data is size bytes long.
+func DataSizeFilter(size uint64) ProgramFilter {
+	return ProgramFilter{DataSize: &size}
+}
+
+// MemcmpFilter matches the accounts whose data holds the bytes at the offset, e.g. the
+// mint of the pool state accounts of an AMM.
+func MemcmpFilter(offset uint64, data []byte) ProgramFilter {
+	return ProgramFilter{Memcmp: &Memcmp{Offset: offset, Bytes: base58.Encode(data), Encoding: EncodingBase58}}
+}
+
+// Match reports whether account data matches the filter, e.g. to check the accounts of a
+// getProgramAccounts snapshot against the filters of a subscription.
+func (f ProgramFilter) Match(data []byte) bool {
+	if f.Memcmp == nil {
+		return f.DataSize != nil && uint64(len(data)) == *f.DataSize
+	}
+	want, err := f.Memcmp.decode()
+	if err != nil || f.Memcmp.Offset > uint64(len(data)) {
+		return false
+	}
+	return bytes.HasPrefix(data[f.Memcmp.Offset:], want)
+}
+
+// validate checks that exactly one criterion is set and that the memcmp bytes decode.
+func (f ProgramFilter) validate() error {
+	switch {
+	case f.Memcmp == nil && f.DataSize == nil:
+		return errors.New("filter sets neither dataSize nor memcmp")
+	case f.Memcmp != nil && f.DataSize != nil:
+		return errors.New("filter sets both dataSize and memcmp")
+	case f.Memcmp == nil:
+		return nil
+	}
+	data, err := f.Memcmp.decode()
+	switch {
+	case err != nil:
+		return fmt.Errorf("memcmp: %w", err)
+	case len(data) == 0:
+		return errors.New("memcmp: bytes are empty")
+	case f.Memcmp.encoding() == EncodingBase58 && len(data) > maxMemcmpBase58:
+		return fmt.Errorf("memcmp: %d bytes in base58, nodes accept %d, use base64", len(data), maxMemcmpBase58)
+	}
+	return nil
+}
+
+func (m *Memcmp) encoding() AccountEncoding {
+	if m.Encoding == "" {
+		return EncodingBase58
+	}
+	return m.Encoding
+}
+
+// decode returns the bytes to match.
+func (m *Memcmp) decode() ([]byte, error) {
+	switch m.encoding() {
+	case EncodingBase58:
+		return base58.Decode(m.Bytes)
+	case EncodingBase64:
+		return base64.StdEncoding.DecodeString(m.Bytes)
+	}
+	return nil, fmt.Errorf("encoding %q is not base58 or base64", string(m.Encoding))
+}
+
+// ProgramSubscribeParams are the params of programSubscribe, a method of the standard
+// Solana WebSocket API rather than of ChainStream. They are sent as the positional params
+// [program, {encoding, commitment, filters}].
+type ProgramSubscribeParams struct {
+	Program string
+	// Filters, when set, limit the accounts to the ones matching all of them. Nodes accept
+	// MaxProgramFilters.
+	Filters    []ProgramFilter
+	Encoding   AccountEncoding
+	Commitment Commitment
+}
+
+type programSubscribeOptions struct {
+	Encoding   AccountEncoding `json:"encoding"`
+	Commitment Commitment      `json:"commitment"`
+	Filters    []ProgramFilter `json:"filters,omitempty"`
+}
+
+// MarshalJSON encodes the params as positional params, with the defaults of unset fields.
+func (p ProgramSubscribeParams) MarshalJSON() ([]byte, error) {
+	encoding := p.Encoding
+	if encoding == "" {
+		encoding = DefaultAccountEncoding
+	}
+	return json.Marshal([]interface{}{p.Program, programSubscribeOptions{Encoding: encoding, Commitment: p.Commitment.orDefault(), Filters: p.Filters}})
+}
+
+// UnmarshalJSON decodes positional params.
+func (p *ProgramSubscribeParams) UnmarshalJSON(data []byte) error {
+	var params []json.RawMessage
+	if err := json.Unmarshal(data, &params); err != nil {
+		return err
+	}
+	if len(params) == 0 || len(params) > 2 {
+		return fmt.Errorf("programSubscribe takes 1 or 2 params, got %d", len(params))
+	}
+	var decoded ProgramSubscribeParams
+	if err := json.Unmarshal(params[0], &decoded.Program); err != nil {
+		return fmt.Errorf("program: %w", err)
+	}
+	if len(params) == 2 {
+		var options programSubscribeOptions
+		if err := json.Unmarshal(params[1], &options); err != nil {
+			return fmt.Errorf("options: %w", err)
+		}
+		decoded.Encoding, decoded.Commitment, decoded.Filters = options.Encoding, options.Commitment, options.Filters
+	}
+	*p = decoded
+	return nil
+}
+
+// Validate checks the params before they are sent: the program must be a valid key, the
+// filters valid and at most MaxProgramFilters, and the encoding and commitment known when
+// set.
+func (p *ProgramSubscribeParams) Validate() error {
+	var errs []error
+	if _, err := NormalizeKey(p.Program); err != nil {
+		errs = append(errs, fmt.Errorf("program: %w", err))
+	}
+	if len(p.Filters) > MaxProgramFilters {
+		errs = append(errs, fmt.Errorf("%d filters, nodes accept %d", len(p.Filters), MaxProgramFilters))
+	}
+	for i, filter := range p.Filters {
+		if err := filter.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("filters[%d]: %w", i, err))
+		}
+	}
+	if p.Encoding != "" {
+		if err := p.Encoding.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if p.Commitment != 0 {
+		if err := p.Commitment.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewProgramSubscribeRequest returns a programSubscribe request for the accounts of the
+// program. Unset encoding and commitment use DefaultAccountEncoding and DefaultCommitment.
+// It returns an error if the params are invalid, see ProgramSubscribeParams.Validate.
+func NewProgramSubscribeRequest(params ProgramSubscribeParams) (*JSONRPCRequest, error) {
+	if params.Encoding == "" {
+		params.Encoding = DefaultAccountEncoding
+	}
+	params.Commitment = params.Commitment.orDefault()
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	return newRequest("programSubscribe", params), nil
+}
+
+// ProgramNotification is a change of an account owned by a program, sent by
+// programSubscribe.
+type ProgramNotification struct {
+	JSONRPC string                    `json:"jsonrpc"`
+	Method  string                    `json:"method"`
+	Params  ProgramNotificationParams `json:"params"`
+}
+
+// Slot returns the slot of the change.
+func (p *ProgramNotification) Slot() uint64 {
+	return p.Params.Result.Context.Slot
+}
+
+// Pubkey returns the address of the changed account.
+func (p *ProgramNotification) Pubkey() string {
+	return p.Params.Result.Value.Pubkey
+}
+
+// Account returns the state of the changed account.
+func (p *ProgramNotification) Account() *AccountValue {
+	return &p.Params.Result.Value.Account
+}
+
+// ProgramNotificationParams contains subscription ID and payload.
+type ProgramNotificationParams struct {
+	Subscription int64                   `json:"subscription"`
+	Result       ProgramNotificationData `json:"result"`
+}
+
+// ProgramNotificationData holds the context and the changed account.
+type ProgramNotificationData struct {
+	Context ContextMetadata `json:"context"`
+	Value   ProgramAccount  `json:"value"`
+}
+
+// ProgramAccount is an account of a program and its state.
+type ProgramAccount struct {
+	Pubkey  string       `json:"pubkey"`
+	Account AccountValue `json:"account"`
+}
+
+// key tells changes apart: a change delivered twice has the same account, slot and state.
+func (p *ProgramNotification) key() string {
+	return p.Pubkey() + "/" + strconv.FormatUint(p.Slot(), 10) + "/" + p.Account().stateKey()
+}
+
+// decodeProgram is the session decoder of programSubscribe subscriptions.
+func decodeProgram(data []byte, event *sessionEvent) error {
+	var program ProgramNotification
+	if err := json.Unmarshal(data, &program); err != nil {
+		return fmt.Errorf("failed to decode program notification: %w", err)
+	}
+	event.program = &program
+	return nil
+}
+
+// ProgramNotifications subscribes to the changes of the accounts owned by a program with
+// programSubscribe, see NewProgramSubscribeRequest. It is a method of the standard Solana
+// WebSocket API, so the endpoints of the client must be Solana RPC WebSocket endpoints
+// rather than ChainStream ones. Changes are received over scored endpoints with pings and
+// reconnects, and a change delivered twice is passed to do once. Panics in do are
+// recovered, see Hooks.Panic.
+func (c *C) ProgramNotifications(
+	ctx context.Context,
+	request *JSONRPCRequest,
+	do func(program *ProgramNotification),
+	opts ...SubscribeOption,
+) error {
+	return c.notifications(ctx, request, "program", opts, func(event sessionEvent) (uint64, string) {
+		return event.program.Slot(), event.program.key()
+	}, func(event sessionEvent) error {
+		return c.dispatch(event.program.Slot(), func() { do(event.program) })
+	})
+}
@@ -0,0 +1,142 @@
+package chainstream_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+const tokenProgram = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+
+// programNotification is a programNotification of the standard Solana WebSocket API.
+func programNotification(slot uint64, pubkey string, lamports uint64) string {
+	return `{"jsonrpc":"2.0","method":"programNotification","params":{"result":{"context":{"slot":` +
+		jsonNumber(slot) + `},"value":{"pubkey":"` + pubkey + `","account":{"data":["AQI=","base64"],"executable":false,"lamports":` +
+		jsonNumber(lamports) + `,"owner":"` + tokenProgram + `","rentEpoch":0,"space":2}}},"subscription":1001}}`
+}
+
+func TestProgramNotifications(t *testing.T) {
+	srv := newFakeServer(t, func(ctx context.Context, conn *websocket.Conn, _ int) error {
+		for _, message := range []string{
+			programNotification(10, watchedAccount, 1000),
+			programNotification(10, watchedAccount, 1000),
+			programNotification(10, systemProgram, 1000),
+			programNotification(11, watchedAccount, 900),
+		} {
+			if err := conn.Write(ctx, websocket.MessageText, []byte(message)); err != nil {
+				return err
+			}
+		}
+		<-ctx.Done()
+		return nil
+	})
+	client := chainstream.NewClient(chainstream.NewConfig(srv.endpoint()))
+	request, err := chainstream.NewProgramSubscribeRequest(chainstream.ProgramSubscribeParams{
+		Program: tokenProgram,
+		Filters: []chainstream.ProgramFilter{chainstream.DataSizeFilter(165), chainstream.MemcmpFilter(0, []byte("abc"))},
+	})
+	if err != nil {
+		t.Fatalf("NewProgramSubscribeRequest() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var changes []string
+	err = client.ProgramNotifications(ctx, request, func(p *chainstream.ProgramNotification) {
+		changes = append(changes, p.Pubkey())
+		if p.Slot() == 11 {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("ProgramNotifications() error: %v", err)
+	}
+
+	expected := []string{watchedAccount, systemProgram, watchedAccount}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("changes of %q, expected %q", changes, expected)
+	}
+
+	sent := srv.request(1)
+	filters := []interface{}{
+		map[string]interface{}{"dataSize": float64(165)},
+		map[string]interface{}{"memcmp": map[string]interface{}{"offset": float64(0), "bytes": "ZiCa", "encoding": "base58"}},
+	}
+	params := []interface{}{tokenProgram, map[string]interface{}{"encoding": "base64", "commitment": "confirmed", "filters": filters}}
+	if sent.Method != "programSubscribe" || !reflect.DeepEqual(sent.Params, params) {
+		t.Errorf("subscribed with %s %v, expected programSubscribe %v", sent.Method, sent.Params, params)
+	}
+}
+
+func TestProgramFilter(t *testing.T) {
+	data := []byte{1, 2, 3, 4}
+	for _, tt := range []struct {
+		filter   chainstream.ProgramFilter
+		expected bool
+	}{
+		{chainstream.DataSizeFilter(4), true},
+		{chainstream.DataSizeFilter(5), false},
+		{chainstream.DataSizeFilter(0), false},
+		{chainstream.MemcmpFilter(1, []byte{2, 3}), true},
+		{chainstream.MemcmpFilter(3, []byte{4, 5}), false},
+		{chainstream.MemcmpFilter(5, []byte{1}), false},
+		{chainstream.ProgramFilter{Memcmp: &chainstream.Memcmp{Offset: 2, Bytes: "AwQ=", Encoding: chainstream.EncodingBase64}}, true},
+	} {
+		if got := tt.filter.Match(data); got != tt.expected {
+			t.Errorf("Match() with %+v = %v, expected %v", tt.filter, got, tt.expected)
+		}
+	}
+	if !chainstream.DataSizeFilter(0).Match(nil) {
+		t.Error("DataSizeFilter(0) does not match an empty account")
+	}
+	if data, err := json.Marshal(chainstream.DataSizeFilter(0)); err != nil || string(data) != `{"dataSize":0}` {
+		t.Errorf("Marshal(DataSizeFilter(0)) = %s, %v", data, err)
+	}
+	empty := chainstream.ProgramSubscribeParams{Program: tokenProgram, Filters: []chainstream.ProgramFilter{chainstream.DataSizeFilter(0)}}
+	if _, err := chainstream.NewProgramSubscribeRequest(empty); err != nil {
+		t.Errorf("NewProgramSubscribeRequest() with DataSizeFilter(0) error: %v", err)
+	}
+}
+
+func TestProgramSubscribeParams(t *testing.T) {
+	params := chainstream.ProgramSubscribeParams{
+		Program:    tokenProgram,
+		Filters:    []chainstream.ProgramFilter{chainstream.DataSizeFilter(165)},
+		Encoding:   chainstream.EncodingJSONParsed,
+		Commitment: chainstream.CommitmentFinalized,
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	var decoded chainstream.ProgramSubscribeParams
+	if err := json.Unmarshal(data, &decoded); err != nil || !reflect.DeepEqual(decoded, params) {
+		t.Errorf("Unmarshal(%s) = %+v, %v", data, decoded, err)
+	}
+
+	for _, invalid := range []chainstream.ProgramSubscribeParams{
+		{Program: "not a key"},
+		{Program: tokenProgram, Filters: []chainstream.ProgramFilter{{}}},
+		{Program: tokenProgram, Filters: []chainstream.ProgramFilter{{DataSize: chainstream.DataSizeFilter(1).DataSize, Memcmp: &chainstream.Memcmp{Bytes: "2"}}}},
+		{Program: tokenProgram, Filters: []chainstream.ProgramFilter{{Memcmp: &chainstream.Memcmp{Bytes: "0OIl"}}}},
+		{Program: tokenProgram, Filters: []chainstream.ProgramFilter{chainstream.MemcmpFilter(0, []byte(strings.Repeat("x", 129)))}},
+		{Program: tokenProgram, Filters: make([]chainstream.ProgramFilter, 5)},
+	} {
+		if _, err := chainstream.NewProgramSubscribeRequest(invalid); err == nil {
+			t.Errorf("NewProgramSubscribeRequest(%+v) succeeded", invalid)
+		}
+	}
+	client := chainstream.NewClient(chainstream.NewConfig("wss://example.com"))
+	request := &chainstream.JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "programSubscribe", Params: chainstream.ProgramSubscribeParams{Program: "not a key"}}
+	if err := client.ProgramNotifications(context.Background(), request, func(*chainstream.ProgramNotification) {}); !errors.Is(err, chainstream.ErrInvalidParams) {
+		t.Errorf("ProgramNotifications() error = %v, expected ErrInvalidParams", err)
+	}
+}
@@ -0,0 +1,409 @@
+package chainstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnsupported is returned when a subscription uses a method the provider of the client
+// does not offer, see Provider.
+var ErrUnsupported = errors.New("chainstream: not supported by the provider")
+
+// errNoNotification is returned by decoders for messages carrying no notification, e.g. a
+// block of a provider without a transaction matching the filter. The message is skipped.
+var errNoNotification = errors.New("chainstream: message carries no notification")
+
+// Provider adapts the subscriptions of the client to the WebSocket API of a vendor, so that
+// TransactionsNotifications, BlocksNotifications and the others work the same against any
+// of them. Requests are built for ChainStream, e.g. with NewTransactionsSubscribeRequest;
+// the provider translates them before they are sent and translates the notifications of
+// transactionsSubscribe and blocksSubscribe back. The standard Solana subscriptions, e.g.
+// accountSubscribe, are served alike by every provider.
+type Provider interface {
+	// Name identifies the provider in errors.
+	Name() string
+	// Request returns the request sent for a ChainStream request, the request itself when
+	// the provider serves its method as is. It returns an error wrapping ErrUnsupported
+	// when the provider does not offer the method.
+	Request(request *JSONRPCRequest) (*JSONRPCRequest, error)
+	// DecodeTransactions decodes a notification of a translated transactionsSubscribe
+	// request with the params into the transactions it carries, none when the params
+	// filter all of them out.
+	DecodeTransactions(params TransactionSubscribeParams, data []byte) ([]*TransactionNotification, error)
+	// DecodeBlock decodes a notification of a translated blocksSubscribe request, nil when
+	// it carries no block, e.g. for a skipped slot.
+	DecodeBlock(data []byte) (*BlockNotification, error)
+}
+
+// ProviderName names a built-in Provider in ConnConfig.Provider.
+type ProviderName string
+
+// Built-in providers.
+const (
+	// ProviderSyndica is Syndica ChainStream, the default.
+	ProviderSyndica ProviderName = "syndica"
+	// ProviderHelius is Helius, whose enhanced WebSockets stream transactions with
+	// transactionSubscribe.
+	ProviderHelius ProviderName = "helius"
+	// ProviderTriton is Triton One, whose WebSockets take the transactionSubscribe of
+	// Helius.
+	ProviderTriton ProviderName = "triton"
+	// ProviderRPC is a plain Solana RPC node. Transactions are taken from the blocks of
+	// blockSubscribe, which the node must enable, and filtered by the client.
+	ProviderRPC ProviderName = "rpc"
+)
+
+// Providers lists the built-in providers.
+var Providers = []ProviderName{ProviderSyndica, ProviderHelius, ProviderTriton, ProviderRPC}
+
+// ParseProviderName returns the provider named s, case-insensitively.
+func ParseProviderName(s string) (ProviderName, error) {
+	name := ProviderName(strings.ToLower(strings.TrimSpace(s)))
+	if err := name.Validate(); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// Validate returns an error if the name is not one of Providers.
+func (n ProviderName) Validate() error {
+	for _, known := range Providers {
+		if n == known {
+			return nil
+		}
+	}
+	names := make([]string, len(Providers))
+	for i, p := range Providers {
+		names[i] = string(p)
+	}
+	return fmt.Errorf("unknown provider %q, expected one of %s", string(n), strings.Join(names, ", "))
+}
+
+func (n ProviderName) String() string { return string(n) }
+
+// Provider returns the adapter of the provider, the one of ProviderSyndica when the name
+// is empty or unknown.
+func (n ProviderName) Provider() Provider {
+	switch n {
+	case ProviderHelius, ProviderTriton:
+		return enhancedProvider{name: string(n)}
+	case ProviderRPC:
+		return rpcProvider{}
+	}
+	return syndicaProvider{}
+}
+
+// provider returns ConnConfig.Adapter, or the adapter of ConnConfig.Provider.
+func (c *C) provider() Provider {
+	if c.config.Conn.Adapter != nil {
+		return c.config.Conn.Adapter
+	}
+	return c.config.Conn.Provider.Provider()
+}
+
+// decoderFor returns the decoder of the notifications of the request, translated by the
+// provider of the client.
+func (c *C) decoderFor(request *JSONRPCRequest) decoder {
+	p := c.provider()
+	if _, ok := p.(syndicaProvider); ok {
+		return decoderFor(request.Method)
+	}
+	switch request.Method {
+	case "transactionsSubscribe":
+		params, err := subscribeParams(request)
+		return func(data []byte, event *sessionEvent) error {
+			if err != nil {
+				return err
+			}
+			notifications, err := p.DecodeTransactions(params, data)
+			switch {
+			case err != nil:
+				return err
+			case len(notifications) == 0:
+				return errNoNotification
+			case len(notifications) == 1:
+				event.notification = notifications[0]
+			default:
+				event.batch = notifications
+			}
+			return nil
+		}
+	case "blocksSubscribe":
+		return func(data []byte, event *sessionEvent) error {
+			block, err := p.DecodeBlock(data)
+			if err == nil && block == nil {
+				return errNoNotification
+			}
+			event.block = block
+			return err
+		}
+	}
+	return decoderFor(request.Method)
+}
+
+// subscribeParams returns the params of a transactionsSubscribe request, decoding them
+// when they are not TransactionSubscribeParams. Params with fields that
+// TransactionSubscribeParams lacks are not supported, as the provider would drop them.
+func subscribeParams(request *JSONRPCRequest) (TransactionSubscribeParams, error) {
+	if params, ok := transactionParams(request); ok {
+		return params, nil
+	}
+	var params TransactionSubscribeParams
+	data, err := json.Marshal(request.Params)
+	if err == nil {
+		err = json.Unmarshal(data, &params)
+	}
+	if err != nil {
+		return params, fmt.Errorf("cannot read transactionsSubscribe params: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&TransactionSubscribeParams{}); err != nil {
+		return params, fmt.Errorf("%w: transactionsSubscribe params: %v", ErrUnsupported, err)
+	}
+	return params, nil
+}
+
+// syndicaProvider serves the requests as they are.
+type syndicaProvider struct{}
+
+func (syndicaProvider) Name() string { return string(ProviderSyndica) }
+
+func (syndicaProvider) Request(request *JSONRPCRequest) (*JSONRPCRequest, error) {
+	return request, nil
+}
+
+func (syndicaProvider) DecodeTransactions(_ TransactionSubscribeParams, data []byte) ([]*TransactionNotification, error) {
+	var event sessionEvent
+	if err := decodeTransaction(data, &event); err != nil {
+		return nil, err
+	}
+	return []*TransactionNotification{event.notification}, nil
+}
+
+func (syndicaProvider) DecodeBlock(data []byte) (*BlockNotification, error) {
+	var event sessionEvent
+	if err := decodeBlock(data, &event); err != nil {
+		return nil, err
+	}
+	return event.block, nil
+}
+
+// streamOptions are the options of transactionSubscribe and blockSubscribe.
+type streamOptions struct {
+	Commitment                     Commitment `json:"commitment"`
+	Encoding                       string     `json:"encoding"`
+	TransactionDetails             string     `json:"transactionDetails"`
+	ShowRewards                    bool       `json:"showRewards"`
+	MaxSupportedTransactionVersion int        `json:"maxSupportedTransactionVersion"`
+}
+
+// newStreamOptions returns the options of full transactions in the JSON encoding, which
+// holds them in the form of ChainStream.
+func newStreamOptions(commitment Commitment, rewards bool) streamOptions {
+	return streamOptions{
+		Commitment:         commitment.orDefault(),
+		Encoding:           "json",
+		TransactionDetails: "full",
+		ShowRewards:        rewards,
+	}
+}
+
+// blockRequest returns the blockSubscribe request of the blocks mentioning the key, of
+// every block when it is empty.
+func blockRequest(request *JSONRPCRequest, mentions string, options streamOptions) *JSONRPCRequest {
+	var filter interface{} = "all"
+	if mentions != "" {
+		filter = map[string]string{"mentionsAccountOrProgram": mentions}
+	}
+	return &JSONRPCRequest{JSONRPC: "2.0", ID: request.ID, Method: "blockSubscribe", Params: []interface{}{filter, options}}
+}
+
+// unsupported returns the error of a method the provider does not offer.
+func unsupported(p Provider, method string) error {
+	return fmt.Errorf("%w: %s does not offer %s", ErrUnsupported, p.Name(), method)
+}
+
+// blockNotification is a notification of blockSubscribe.
+type blockNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  struct {
+		Subscription int64 `json:"subscription"`
+		Result       struct {
+			Context ContextMetadata `json:"context"`
+			Value   struct {
+				Slot  uint64          `json:"slot"`
+				Block *BlockValue     `json:"block"`
+				Err   json.RawMessage `json:"err"`
+			} `json:"value"`
+		} `json:"result"`
+	} `json:"params"`
+}
+
+// decodeBlockNotification decodes a notification of blockSubscribe into the form of
+// blocksSubscribe, nil when it carries no block.
+func decodeBlockNotification(data []byte) (*BlockNotification, error) {
+	var message blockNotification
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, fmt.Errorf("failed to decode block notification: %w", err)
+	}
+	result := &message.Params.Result
+	if result.Value.Block == nil {
+		return nil, nil
+	}
+	block := &BlockNotification{JSONRPC: message.JSONRPC, Method: message.Method}
+	block.Params.Subscription = message.Params.Subscription
+	block.Params.Result.Context = result.Context
+	block.Params.Result.Value = *result.Value.Block
+	block.Params.Result.Value.Slot = result.Value.Slot
+	return block, nil
+}
+
+// enhancedProvider serves transactionsSubscribe with the transactionSubscribe of Helius,
+// also taken by Triton, and blocksSubscribe with blockSubscribe.
+type enhancedProvider struct {
+	name string
+}
+
+// enhancedFilter is the filter of transactionSubscribe.
+type enhancedFilter struct {
+	Vote            bool     `json:"vote"`
+	AccountInclude  []string `json:"accountInclude,omitempty"`
+	AccountExclude  []string `json:"accountExclude,omitempty"`
+	AccountRequired []string `json:"accountRequired,omitempty"`
+}
+
+func (p enhancedProvider) Name() string { return p.name }
+
+func (p enhancedProvider) Request(request *JSONRPCRequest) (*JSONRPCRequest, error) {
+	switch request.Method {
+	case "transactionsSubscribe":
+		params, err := subscribeParams(request)
+		if err != nil {
+			return nil, err
+		}
+		filter := enhancedFilter{Vote: !params.Filter.ExcludeVotes}
+		if keys := params.Filter.AccountKeys; keys != nil {
+			filter.AccountInclude, filter.AccountExclude, filter.AccountRequired = keys.OneOf, keys.Exclude, keys.All
+		}
+		options := newStreamOptions(params.Filter.Commitment, false)
+		return &JSONRPCRequest{JSONRPC: "2.0", ID: request.ID, Method: "transactionSubscribe", Params: []interface{}{filter, options}}, nil
+	case "blocksSubscribe":
+		return blockRequest(request, "", newStreamOptions(DefaultCommitment, true)), nil
+	case "slotsSubscribe":
+		return nil, unsupported(p, request.Method)
+	}
+	return request, nil
+}
+
+// enhancedNotification is a notification of transactionSubscribe.
+type enhancedNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  struct {
+		Subscription int64 `json:"subscription"`
+		Result       struct {
+			Signature   string           `json:"signature"`
+			Slot        uint64           `json:"slot"`
+			Transaction BlockTransaction `json:"transaction"`
+		} `json:"result"`
+	} `json:"params"`
+}
+
+func (p enhancedProvider) DecodeTransactions(params TransactionSubscribeParams, data []byte) ([]*TransactionNotification, error) {
+	var message enhancedNotification
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, fmt.Errorf("failed to decode notification: %w", err)
+	}
+	result := &message.Params.Result
+	notification := &TransactionNotification{JSONRPC: message.JSONRPC, Method: message.Method}
+	notification.Params.Subscription = message.Params.Subscription
+	value := &notification.Params.Result.Value
+	value.Slot = result.Slot
+	value.Transaction = result.Transaction.Transaction
+	value.Meta = result.Transaction.Meta
+	value.Version = result.Transaction.Version
+	context := &notification.Params.Result.Context
+	context.Slot = result.Slot
+	context.SlotStatus = params.Filter.Commitment.orDefault().String()
+	context.Signature = result.Signature
+	context.IsVote = isVote(notification)
+	return []*TransactionNotification{notification}, nil
+}
+
+func (p enhancedProvider) DecodeBlock(data []byte) (*BlockNotification, error) {
+	return decodeBlockNotification(data)
+}
+
+// rpcProvider serves transactionsSubscribe and blocksSubscribe with the blockSubscribe of
+// Solana RPC nodes. The blocks of transactionsSubscribe mention a key every transaction
+// must hold, when the filter has one, and their transactions are filtered by the client.
+// A filter taking one of several keys is not supported, as no key covers it.
+type rpcProvider struct{}
+
+func (rpcProvider) Name() string { return string(ProviderRPC) }
+
+func (p rpcProvider) Request(request *JSONRPCRequest) (*JSONRPCRequest, error) {
+	switch request.Method {
+	case "transactionsSubscribe":
+		params, err := subscribeParams(request)
+		if err != nil {
+			return nil, err
+		}
+		mentions, err := mentionedKey(params.Filter.AccountKeys)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s cannot filter blocks: %v", ErrUnsupported, p.Name(), err)
+		}
+		return blockRequest(request, mentions, newStreamOptions(params.Filter.Commitment, false)), nil
+	case "blocksSubscribe":
+		return blockRequest(request, "", newStreamOptions(DefaultCommitment, true)), nil
+	case "slotsSubscribe":
+		return nil, unsupported(p, request.Method)
+	}
+	return request, nil
+}
+
+// mentionedKey returns a key every transaction matching the filter holds, as
+// blockSubscribe takes a single one, or an empty key when the filter takes every
+// transaction. It returns an error when the filter takes one of several keys, which no
+// single key covers.
+func mentionedKey(filter *AccountKeysFilter) (string, error) {
+	switch {
+	case filter == nil:
+		return "", nil
+	case len(filter.All) > 0:
+		return filter.All[0], nil
+	case len(filter.OneOf) == 1:
+		return filter.OneOf[0], nil
+	case len(filter.OneOf) > 1:
+		return "", fmt.Errorf("one of %d account keys", len(filter.OneOf))
+	}
+	return "", nil
+}
+
+func (rpcProvider) DecodeTransactions(params TransactionSubscribeParams, data []byte) ([]*TransactionNotification, error) {
+	block, err := decodeBlockNotification(data)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	value := &block.Params.Result.Value
+	commitment := params.Filter.Commitment.orDefault().String()
+	var notifications []*TransactionNotification
+	for i := range value.Transactions {
+		notification := value.TransactionNotification(i, commitment)
+		notification.Params.Subscription = block.Params.Subscription
+		notification.Params.Result.Context.IsVote = isVote(notification)
+		if params.Filter.Match(notification) {
+			notifications = append(notifications, notification)
+		}
+	}
+	return notifications, nil
+}
+
+func (rpcProvider) DecodeBlock(data []byte) (*BlockNotification, error) {
+	return decodeBlockNotification(data)
+}
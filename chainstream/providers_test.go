@@ -0,0 +1,243 @@
+package chainstream_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+const (
+	trader = "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF"
+	curve  = "62qc2CNXwrYqQScmEdiZFFAnJR262PxWEuNQtxfafNgV"
+)
+
+// sendMessages writes the messages and keeps the connection open.
+func sendMessages(messages ...interface{}) func(ctx context.Context, conn *websocket.Conn, n int) error {
+	return func(ctx context.Context, conn *websocket.Conn, _ int) error {
+		for _, message := range messages {
+			if err := wsjson.Write(ctx, conn, message); err != nil {
+				return err
+			}
+		}
+		<-ctx.Done()
+		return nil
+	}
+}
+
+// enhancedNotification returns the transactionSubscribe notification of Helius carrying
+// the transaction.
+func enhancedNotification(tx *chainstream.TransactionNotification) map[string]interface{} {
+	value := tx.Params.Result.Value
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "transactionNotification",
+		"params": map[string]interface{}{
+			"subscription": 7,
+			"result": map[string]interface{}{
+				"signature":   tx.Signature(),
+				"slot":        tx.Slot(),
+				"transaction": map[string]interface{}{"transaction": value.Transaction, "meta": value.Meta, "version": 0},
+			},
+		},
+	}
+}
+
+// blockNotification returns the blockSubscribe notification of a block of the
+// transactions, of a skipped slot without them.
+func blockNotification(slot uint64, txs ...*chainstream.TransactionNotification) map[string]interface{} {
+	var block interface{}
+	if len(txs) > 0 {
+		transactions := make([]chainstream.BlockTransaction, len(txs))
+		for i, tx := range txs {
+			transactions[i] = chainstream.BlockTransaction{Transaction: tx.Params.Result.Value.Transaction, Meta: tx.Params.Result.Value.Meta}
+		}
+		block = map[string]interface{}{"blockhash": "hash", "parentSlot": slot - 1, "transactions": transactions}
+	}
+	return map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "blockNotification",
+		"params": map[string]interface{}{
+			"subscription": 7,
+			"result": map[string]interface{}{
+				"context": map[string]interface{}{"slot": slot},
+				"value":   map[string]interface{}{"slot": slot, "block": block, "err": nil},
+			},
+		},
+	}
+}
+
+// subscribed returns the params of the request as decoded JSON.
+func subscribed(t *testing.T, params interface{}) interface{} {
+	t.Helper()
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded interface{}
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestProviderHelius(t *testing.T) {
+	buy := loadNotification(t, "testdata/sample_tx_buy.json")
+	sell := loadNotification(t, "testdata/sample_tx_sell.json")
+	srv := newFakeServer(t, sendMessages(enhancedNotification(buy), enhancedNotification(sell)))
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Conn.Provider = chainstream.ProviderHelius
+	request, err := chainstream.NewTransactionsSubscribeRequest("", chainstream.TransactionFilter{
+		ExcludeVotes: true,
+		AccountKeys:  &chainstream.AccountKeysFilter{OneOf: []string{trader}, Exclude: []string{curve}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var received []*chainstream.TransactionNotification
+	err = chainstream.NewClient(config).TransactionsNotifications(ctx, request, func(n *chainstream.TransactionNotification) {
+		if received = append(received, n); len(received) == 2 {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("TransactionsNotifications() error: %v", err)
+	}
+
+	sent := srv.request(1)
+	params := []interface{}{
+		map[string]interface{}{"vote": false, "accountInclude": []interface{}{trader}, "accountExclude": []interface{}{curve}},
+		map[string]interface{}{"commitment": "confirmed", "encoding": "json", "transactionDetails": "full", "showRewards": false, "maxSupportedTransactionVersion": float64(0)},
+	}
+	if sent.Method != "transactionSubscribe" || !reflect.DeepEqual(subscribed(t, sent.Params), params) {
+		t.Errorf("subscribed with %s %v, expected transactionSubscribe %v", sent.Method, sent.Params, params)
+	}
+	if len(received) != 2 {
+		t.Fatalf("received %d notifications, expected 2", len(received))
+	}
+	for i, expected := range []*chainstream.TransactionNotification{buy, sell} {
+		n := received[i]
+		if n.Signature() != expected.Signature() || n.Slot() != expected.Slot() || n.Owner() != expected.Owner() {
+			t.Errorf("notification %d is %s at %d, expected %s at %d", i, n.Signature(), n.Slot(), expected.Signature(), expected.Slot())
+		}
+		value := expected.Params.Result.Value
+		value.Version = json.RawMessage("0") // the version of enhancedNotification
+		if !reflect.DeepEqual(subscribed(t, n.Params.Result.Value), subscribed(t, value)) {
+			t.Errorf("notification %d has transaction %+v, expected %+v", i, n.Params.Result.Value, value)
+		}
+		if n.Params.Result.Context.SlotStatus != "confirmed" {
+			t.Errorf("notification %d has slot status %q, expected confirmed", i, n.Params.Result.Context.SlotStatus)
+		}
+	}
+}
+
+func TestProviderRPC(t *testing.T) {
+	buy := loadNotification(t, "testdata/sample_tx_buy.json")
+	sell := loadNotification(t, "testdata/sample_tx_sell.json")
+	srv := newFakeServer(t, sendMessages(blockNotification(100), blockNotification(101, buy, sell)))
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Conn.Provider = chainstream.ProviderRPC
+	client := chainstream.NewClient(config)
+
+	request, err := chainstream.NewTransactionsSubscribeRequest("", chainstream.TransactionFilter{
+		AccountKeys: &chainstream.AccountKeysFilter{All: []string{trader, curve}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var received []*chainstream.TransactionNotification
+	err = client.TransactionsNotifications(ctx, request, func(n *chainstream.TransactionNotification) {
+		received = append(received, n)
+		cancel()
+	})
+	if err != nil {
+		t.Fatalf("TransactionsNotifications() error: %v", err)
+	}
+	if len(received) != 1 || received[0].Signature() != sell.Params.Result.Value.Transaction.Signatures[0] || received[0].Slot() != 101 || received[0].Params.Result.Context.Index != 1 {
+		t.Fatalf("received %d notifications, expected the sell at index 1 of slot 101", len(received))
+	}
+	sent := srv.request(1)
+	filter := map[string]interface{}{"mentionsAccountOrProgram": trader}
+	if params, ok := subscribed(t, sent.Params).([]interface{}); sent.Method != "blockSubscribe" || !ok || len(params) != 2 || !reflect.DeepEqual(params[0], filter) {
+		t.Errorf("subscribed with %s %v, expected blockSubscribe mentioning %s", sent.Method, sent.Params, trader)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var blocks []uint64
+	err = client.BlocksNotifications(ctx, chainstream.AllBlocks(), func(block *chainstream.BlockNotification) {
+		blocks = append(blocks, block.Slot())
+		if len(block.Params.Result.Value.Transactions) != 2 {
+			t.Errorf("block %d has %d transactions, expected 2", block.Slot(), len(block.Params.Result.Value.Transactions))
+		}
+		cancel()
+	})
+	if err != nil {
+		t.Fatalf("BlocksNotifications() error: %v", err)
+	}
+	if !reflect.DeepEqual(blocks, []uint64{101}) {
+		t.Errorf("received blocks %v, expected the block of slot 101 only", blocks)
+	}
+	if sent := srv.request(2); sent.Method != "blockSubscribe" || !reflect.DeepEqual(subscribed(t, sent.Params).([]interface{})[0], "all") {
+		t.Errorf("subscribed with %s %v, expected blockSubscribe of all blocks", sent.Method, sent.Params)
+	}
+}
+
+func TestProviderUnsupported(t *testing.T) {
+	config := chainstream.NewConfig("ws://127.0.0.1:1")
+	config.Conn.Provider = chainstream.ProviderTriton
+	err := chainstream.NewClient(config).NewSubscriptionManager().Run(context.Background())
+	if !errors.Is(err, chainstream.ErrUnsupported) {
+		t.Errorf("Run() error %v, expected ErrUnsupported", err)
+	}
+}
+
+func TestRPCProviderUnsupportedFilter(t *testing.T) {
+	provider := chainstream.ProviderRPC.Provider()
+	oneOf, err := chainstream.NewTransactionsSubscribeRequest("", chainstream.TransactionFilter{
+		AccountKeys: &chainstream.AccountKeysFilter{OneOf: []string{trader, curve}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown := &chainstream.JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "transactionsSubscribe", Params: map[string]interface{}{
+		"filter": map[string]interface{}{"excludeVotes": true, "programIds": []string{curve}},
+	}}
+	for name, request := range map[string]*chainstream.JSONRPCRequest{"one of": oneOf, "unknown field": unknown} {
+		if _, err := provider.Request(request); !errors.Is(err, chainstream.ErrUnsupported) {
+			t.Errorf("Request(%s) error %v, expected ErrUnsupported", name, err)
+		}
+	}
+	known := &chainstream.JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "transactionsSubscribe", Params: map[string]interface{}{
+		"filter": map[string]interface{}{"excludeVotes": true, "accountKeys": map[string]interface{}{"oneOf": []string{curve}}},
+	}}
+	if sent, err := provider.Request(known); err != nil || sent.Method != "blockSubscribe" {
+		t.Errorf("Request() = %v, %v, expected a blockSubscribe request", sent, err)
+	}
+}
+
+func TestParseProviderName(t *testing.T) {
+	if name, err := chainstream.ParseProviderName(" Helius "); err != nil || name != chainstream.ProviderHelius {
+		t.Errorf("ParseProviderName() = %q, %v, expected helius", name, err)
+	}
+	if _, err := chainstream.ParseProviderName("quicknode"); err == nil {
+		t.Error("ParseProviderName(quicknode) succeeded")
+	}
+	config := chainstream.NewConfig("wss://example.com")
+	config.Conn.Provider = "quicknode"
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() accepted an unknown provider")
+	}
+}
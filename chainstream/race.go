@@ -0,0 +1,125 @@
+package chainstream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/internal/lru"
+)
+
+// EndpointRace reports how an endpoint fared against the others in redundant mode, see
+// ConnConfig.Redundant, to tell which providers deliver first.
+type EndpointRace struct {
+	URL string `json:"url"`
+	// Received is the number of notifications delivered by the endpoint.
+	Received uint64 `json:"received"`
+	// Wins is the number of signatures the endpoint delivered before any other.
+	Wins uint64 `json:"wins"`
+	// WinRate is Wins over the signatures delivered by any endpoint.
+	WinRate float64 `json:"winRate"`
+	// Lead is the mean time by which the wins of the endpoint came before the runner-up,
+	// over the wins another endpoint delivered as well.
+	Lead time.Duration `json:"lead"`
+	// Behind is the mean delay of the notifications the endpoint delivered after another.
+	Behind time.Duration `json:"behind"`
+}
+
+// raceRecorder records which endpoint delivers each signature first.
+type raceRecorder struct {
+	mu        sync.Mutex
+	first     *lru.Cache[string, *raceEntry]
+	endpoints map[*endpoint]*raceStats
+	// signatures is the number of distinct signatures delivered.
+	signatures uint64
+}
+
+// raceEntry is the first delivery of a signature.
+type raceEntry struct {
+	endpoint *endpoint
+	at       time.Time
+	// runnerUp tells that another endpoint has delivered the signature too.
+	runnerUp bool
+}
+
+type raceStats struct {
+	received, wins, leads, late uint64
+	lead, behind                time.Duration
+}
+
+func newRaceRecorder() *raceRecorder {
+	return &raceRecorder{
+		first:     lru.New[string, *raceEntry](dedupCapacity),
+		endpoints: make(map[*endpoint]*raceStats),
+	}
+}
+
+// observe records the delivery of the signature by the endpoint at the given time.
+func (r *raceRecorder) observe(signature string, e *endpoint, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.endpoints[e]
+	if stats == nil {
+		stats = &raceStats{}
+		r.endpoints[e] = stats
+	}
+
+	first, ok := r.first.Get(signature)
+	switch {
+	case !ok:
+		r.first.Add(signature, &raceEntry{endpoint: e, at: at})
+		r.signatures++
+		stats.received++
+		stats.wins++
+	case first.endpoint == e:
+		// Delivered twice by the same endpoint, e.g. during a rotation.
+	default:
+		stats.received++
+		stats.late++
+		delay := at.Sub(first.at)
+		stats.behind += delay
+		if !first.runnerUp {
+			first.runnerUp = true
+			winner := r.endpoints[first.endpoint]
+			winner.leads++
+			winner.lead += delay
+		}
+	}
+}
+
+// report returns the race statistics of the endpoints that delivered notifications.
+func (r *raceRecorder) report(endpoints []*endpoint) []EndpointRace {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var report []EndpointRace
+	for _, e := range endpoints {
+		stats := r.endpoints[e]
+		if stats == nil {
+			continue
+		}
+		race := EndpointRace{URL: e.url, Received: stats.received, Wins: stats.wins}
+		if r.signatures > 0 {
+			race.WinRate = float64(stats.wins) / float64(r.signatures)
+		}
+		if stats.leads > 0 {
+			race.Lead = stats.lead / time.Duration(stats.leads)
+		}
+		if stats.late > 0 {
+			race.Behind = stats.behind / time.Duration(stats.late)
+		}
+		report = append(report, race)
+	}
+	return report
+}
+
+// EndpointRace returns which endpoint delivered notifications first in redundant mode, in
+// the order of the configured endpoints. It is empty unless ConnConfig.Redundant is set.
+func (c *C) EndpointRace() []EndpointRace {
+	set := c.endpointSet()
+	if c.race == nil {
+		return nil
+	}
+	set.mu.Lock()
+	endpoints := append([]*endpoint(nil), set.endpoints...)
+	set.mu.Unlock()
+	return c.race.report(endpoints)
+}
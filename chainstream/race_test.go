@@ -0,0 +1,69 @@
+package chainstream_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestRedundantEndpointRace(t *testing.T) {
+	fast := newFakeServer(t, sendSlots(1, 2, 3))
+	slowSend := sendSlots(1, 2, 3)
+	slow := newFakeServer(t, func(ctx context.Context, conn *websocket.Conn, n int) error {
+		time.Sleep(100 * time.Millisecond)
+		return slowSend(ctx, conn, n)
+	})
+
+	config := chainstream.NewConfig(fast.endpoint())
+	config.Conn.Endpoints = []chainstream.EndpointConfig{{URL: slow.endpoint()}}
+	config.Conn.Redundant = true
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var (
+		mu        sync.Mutex
+		delivered []uint64
+	)
+	go func() {
+		_ = client.TransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) {
+			mu.Lock()
+			defer mu.Unlock()
+			delivered = append(delivered, n.Slot())
+		})
+	}()
+
+	var race []chainstream.EndpointRace
+	for {
+		race = client.EndpointRace()
+		if len(race) == 2 && race[1].Received == 3 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("the slow endpoint did not deliver: %+v", race)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	cancel()
+
+	if race[0].URL != fast.endpoint() || race[0].Wins != 3 || race[0].WinRate != 1 || race[0].Lead < 50*time.Millisecond {
+		t.Errorf("fast endpoint race = %+v, expected 3 wins with a lead", race[0])
+	}
+	if race[1].Wins != 0 || race[1].Behind < 50*time.Millisecond {
+		t.Errorf("slow endpoint race = %+v, expected no wins and a delay", race[1])
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(delivered) != 3 {
+		t.Errorf("delivered %v, expected every slot once", delivered)
+	}
+	if stats := client.Stats(); stats.MirrorCopies != 3 || stats.Duplicates != 0 {
+		t.Errorf("Stats() = %+v, expected the copies of the slow endpoint apart from duplicates", stats)
+	}
+}
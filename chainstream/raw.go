@@ -0,0 +1,121 @@
+package chainstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// decodeRaw is the session decoder of raw subscriptions. It keeps the message as received.
+func decodeRaw(data []byte, event *sessionEvent) error {
+	event.raw = data
+	return nil
+}
+
+// TransactionsNotificationsRaw subscribes to Syndica transaction updates and passes every
+// message to do exactly as received, without decoding it, e.g. to relay it to a queue or
+// disk. Connections are kept like in HandleTransactionsNotifications, but as notifications
+// are not decoded the pipeline does not apply: nothing is deduplicated, filtered or
+// retried, and a reconnect may deliver a notification twice. With a provider other than
+// ProviderSyndica the messages are in the form of the provider. Panics in do are
+// recovered, see Hooks.Panic.
+func (c *C) TransactionsNotificationsRaw(
+	ctx context.Context,
+	request *JSONRPCRequest,
+	do func(raw json.RawMessage),
+	opts ...SubscribeOption,
+) error {
+	options := newSubscribeOptions(opts)
+	ctx, cancel := options.bound(ctx, c)
+	defer cancel()
+
+	events := make(chan sessionEvent)
+	current, err := c.openSession(ctx, request, decodeRaw, events, false)
+	if err != nil {
+		return err
+	}
+	connection := c.newLifecycle(request)
+	connection.connected(current)
+	defer func() {
+		current.release("raw subscription of transactions notifications was closed")
+	}()
+
+	clk := c.clock()
+	ticker := clk.NewTicker(c.pingInterval())
+	defer ticker.Stop()
+	idleTicks, stopWatchdog := c.watchdog()
+	defer stopWatchdog()
+
+	handled := 0
+	state := c.track(request)
+	defer c.untrack(state)
+
+	for {
+		state.sessionsChanged(current, nil)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-idleTicks:
+			c.checkIdle(current, request.Method, clk.Now())
+		case <-ticker.C():
+			go c.ping(ctx, current)
+		case event := <-events:
+			if event.session != current {
+				continue
+			}
+			if event.err != nil {
+				if errors.Is(event.err, context.Canceled) || ctx.Err() != nil {
+					return nil
+				}
+				current.close("session failed")
+				c.endpointSet().observeFailure(current.endpoint, clk.Now())
+				connection.disconnected(current, event.err)
+				if !c.awaitReconnect(ctx) {
+					return nil
+				}
+				next, err := c.openSession(ctx, request, decodeRaw, events, true)
+				if err != nil {
+					if ctx.Err() != nil {
+						return nil
+					}
+					return err
+				}
+				current = next
+				c.stats.reconnects.Add(1)
+				connection.connected(current)
+				continue
+			}
+
+			current.lastReceived = clk.Now()
+			c.stats.received.Add(1)
+			done := state.handling()
+			err := c.handleRaw(event.raw, do)
+			done()
+			switch {
+			case err == nil:
+				c.stats.delivered.Add(1)
+			case c.panicked(err):
+				c.stats.failed.Add(1)
+			default:
+				return err
+			}
+			if handled++; options.done(handled) {
+				return nil
+			}
+		}
+	}
+}
+
+// handleRaw calls do, recording its latency. A panic is returned as a PanicError.
+func (c *C) handleRaw(raw json.RawMessage, do func(raw json.RawMessage)) (err error) {
+	defer func() {
+		if p := recovered(recover(), 0, ""); p != nil {
+			err = p
+		}
+	}()
+	start := time.Now()
+	defer c.latency.since(StageDispatch, start)
+	do(raw)
+	return nil
+}
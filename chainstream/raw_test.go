@@ -0,0 +1,41 @@
+package chainstream_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestTransactionsNotificationsRaw(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1, 2, 3))
+	client := chainstream.NewClient(chainstream.NewConfig(srv.endpoint()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var messages []json.RawMessage
+	err := client.TransactionsNotificationsRaw(ctx, chainstream.FirehoseNoVotes(), func(raw json.RawMessage) {
+		messages = append(messages, raw)
+	}, chainstream.WithMaxNotifications(3))
+	if err != nil {
+		t.Fatalf("TransactionsNotificationsRaw() error: %v", err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("received %d messages, expected 3", len(messages))
+	}
+	for i, raw := range messages {
+		var n chainstream.TransactionNotification
+		if err := json.Unmarshal(raw, &n); err != nil {
+			t.Fatalf("message %d is not a notification: %v", i, err)
+		}
+		if n.Slot() != uint64(i+1) {
+			t.Errorf("message %d has slot %d, expected %d", i, n.Slot(), i+1)
+		}
+	}
+	if stats := client.Stats(); stats.Received != 3 || stats.Delivered != 3 {
+		t.Errorf("stats = %+v, expected 3 received and delivered", stats)
+	}
+}
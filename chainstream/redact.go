@@ -0,0 +1,126 @@
+package chainstream
+
+import (
+	"crypto/sha256"
+	"errors"
+
+	"github.com/mr-tron/base58"
+
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+// RedactionPolicy removes identifying data from notifications before they are written
+// to logs, webhooks or archives. The zero policy leaves notifications unchanged.
+type RedactionPolicy struct {
+	// HashSignatures replaces transaction signatures with the base58 SHA-256 of Salt and
+	// the signature. Equal signatures still hash equally, so duplicates can be matched.
+	HashSignatures bool   `json:"hashSignatures,omitempty"`
+	Salt           string `json:"salt,omitempty"`
+	// TruncateKeys keeps only the first TruncateKeys characters of account keys and token
+	// owners. Known program IDs are kept. Zero keeps keys intact.
+	TruncateKeys int `json:"truncateKeys,omitempty"`
+	// DropLogs removes program log messages.
+	DropLogs bool `json:"dropLogs,omitempty"`
+}
+
+// Enabled reports whether the policy changes anything.
+func (p RedactionPolicy) Enabled() bool {
+	return p.HashSignatures || p.TruncateKeys > 0 || p.DropLogs
+}
+
+// Validate checks the policy.
+func (p RedactionPolicy) Validate() error {
+	if p.TruncateKeys < 0 {
+		return errors.New("redaction: truncateKeys must not be negative")
+	}
+	if p.Salt != "" && !p.HashSignatures {
+		return errors.New("redaction: salt is set but hashSignatures is disabled")
+	}
+	return nil
+}
+
+// Apply returns a redacted copy of the notification; the notification itself is not
+// modified. It returns the notification as is when the policy is not enabled.
+func (p RedactionPolicy) Apply(notification *TransactionNotification) *TransactionNotification {
+	if !p.Enabled() {
+		return notification
+	}
+	redacted := *notification
+	result := &redacted.Params.Result
+	tx := &result.Value.Transaction
+	meta := &result.Value.Meta
+
+	if p.HashSignatures {
+		result.Context.Signature = p.hash(result.Context.Signature)
+		tx.Signatures = p.mapKeys(tx.Signatures, p.hash)
+	}
+	if p.TruncateKeys > 0 {
+		tx.Message.AccountKeys = p.mapKeys(tx.Message.AccountKeys, p.truncate)
+		if lookups := tx.Message.AddressTableLookups; lookups != nil {
+			tx.Message.AddressTableLookups = make([]AddressTableLookup, len(lookups))
+			for i, lookup := range lookups {
+				lookup.AccountKey = p.truncate(lookup.AccountKey)
+				tx.Message.AddressTableLookups[i] = lookup
+			}
+		}
+		meta.LoadedAddresses.Writable = p.mapKeys(meta.LoadedAddresses.Writable, p.truncate)
+		meta.LoadedAddresses.Readonly = p.mapKeys(meta.LoadedAddresses.Readonly, p.truncate)
+		meta.PreTokenBalances = p.redactOwners(meta.PreTokenBalances)
+		meta.PostTokenBalances = p.redactOwners(meta.PostTokenBalances)
+	}
+	if p.DropLogs {
+		meta.LogMessages = nil
+	}
+	return &redacted
+}
+
+// Redact returns a middleware passing redacted copies of notifications to the next handler.
+func Redact(policy RedactionPolicy) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(notification *TransactionNotification) error {
+			return next(policy.Apply(notification))
+		}
+	}
+}
+
+func (p RedactionPolicy) hash(signature string) string {
+	if signature == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(p.Salt + signature))
+	return base58.Encode(sum[:])
+}
+
+func (p RedactionPolicy) truncate(key string) string {
+	if len(key) <= p.TruncateKeys {
+		return key
+	}
+	if _, known := programs.Name(key); known {
+		return key
+	}
+	return key[:p.TruncateKeys] + "…"
+}
+
+// mapKeys returns a new slice with f applied to every key, keeping nil slices nil.
+func (p RedactionPolicy) mapKeys(keys []string, f func(string) string) []string {
+	if keys == nil {
+		return nil
+	}
+	mapped := make([]string, len(keys))
+	for i, key := range keys {
+		mapped[i] = f(key)
+	}
+	return mapped
+}
+
+func (p RedactionPolicy) redactOwners(balances []TokenBalance) []TokenBalance {
+	if balances == nil {
+		return nil
+	}
+	redacted := make([]TokenBalance, len(balances))
+	for i, balance := range balances {
+		balance.Owner = p.truncate(balance.Owner)
+		redacted[i] = balance
+	}
+	return redacted
+}
@@ -0,0 +1,78 @@
+package chainstream_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+func TestRedactionPolicy(t *testing.T) {
+	tx := loadNotification(t, "testdata/sample_tx_sell.json")
+	signature, owner := tx.Signature(), tx.Owner()
+	policy := chainstream.RedactionPolicy{HashSignatures: true, Salt: "salt", TruncateKeys: 6, DropLogs: true}
+
+	redacted := policy.Apply(tx)
+	if tx.Signature() != signature || tx.Owner() != owner || len(tx.Params.Result.Value.Meta.LogMessages) == 0 {
+		t.Fatal("Apply() modified the original notification")
+	}
+	if redacted.Signature() == signature {
+		t.Errorf("Signature() = %q, expected a hash", redacted.Signature())
+	}
+	if got := redacted.Params.Result.Value.Transaction.Signatures[0]; got == tx.Params.Result.Value.Transaction.Signatures[0] {
+		t.Errorf("transaction signature = %q, expected a hash", got)
+	}
+	if again := policy.Apply(tx); again.Signature() != redacted.Signature() {
+		t.Error("hashes of the same signature differ")
+	}
+	if other := (chainstream.RedactionPolicy{HashSignatures: true}).Apply(tx); other.Signature() == redacted.Signature() {
+		t.Error("hash does not depend on the salt")
+	}
+	if got := redacted.Owner(); got != owner[:6]+"…" {
+		t.Errorf("Owner() = %q", got)
+	}
+	for _, key := range redacted.Params.Result.Value.Transaction.Message.AccountKeys {
+		if _, known := programs.Name(key); !known && !strings.HasSuffix(key, "…") {
+			t.Errorf("account key %q was not truncated", key)
+		}
+	}
+	for _, balance := range redacted.Params.Result.Value.Meta.PostTokenBalances {
+		if !strings.HasSuffix(balance.Owner, "…") || strings.HasSuffix(balance.Mint, "…") {
+			t.Errorf("token balance = %+v, expected truncated owner and intact mint", balance)
+		}
+	}
+	if logs := redacted.Params.Result.Value.Meta.LogMessages; logs != nil {
+		t.Errorf("LogMessages = %v, expected none", logs)
+	}
+
+	if (chainstream.RedactionPolicy{}).Apply(tx) != tx {
+		t.Error("zero policy copied the notification")
+	}
+}
+
+func TestRedactMiddleware(t *testing.T) {
+	tx := loadNotification(t, "testdata/sample_tx_buy.json")
+	var got *chainstream.TransactionNotification
+	do := chainstream.Chain(func(n *chainstream.TransactionNotification) error {
+		got = n
+		return nil
+	}, chainstream.Redact(chainstream.RedactionPolicy{DropLogs: true}))
+	if err := do(tx); err != nil {
+		t.Fatal(err)
+	}
+	if got == tx || got.Params.Result.Value.Meta.LogMessages != nil {
+		t.Error("handler did not receive a redacted copy")
+	}
+}
+
+func TestRedactionPolicyValidate(t *testing.T) {
+	for _, policy := range []chainstream.RedactionPolicy{{TruncateKeys: -1}, {Salt: "salt"}} {
+		if err := policy.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, expected error", policy)
+		}
+	}
+	if err := (chainstream.RedactionPolicy{HashSignatures: true, Salt: "salt"}).Validate(); err != nil {
+		t.Errorf("Validate() error: %v", err)
+	}
+}
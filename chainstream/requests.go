@@ -0,0 +1,44 @@
+package chainstream
+
+// NewTransactionsSubscribeRequest returns a transactionsSubscribe request for the network,
+// DefaultNetwork if empty, with the filter, DefaultCommitment if its commitment is unset.
+// It returns an error if the network or the filter is invalid, see
+// TransactionSubscribeParams.Validate.
+func NewTransactionsSubscribeRequest(network Network, filter TransactionFilter) (*JSONRPCRequest, error) {
+	sub := SubscriptionConfig{Params: TransactionSubscribeParams{Network: network, Filter: filter}}
+	sub.SetDefaults(0)
+	if err := sub.Params.Validate(); err != nil {
+		return nil, err
+	}
+	return sub.Request(1), nil
+}
+
+// NewBlocksSubscribeRequest returns a blocksSubscribe request for the network,
+// DefaultNetwork if empty. It returns an error if the network is not one of Networks.
+func NewBlocksSubscribeRequest(network Network) (*JSONRPCRequest, error) {
+	network = network.orDefault()
+	if err := network.Validate(); err != nil {
+		return nil, err
+	}
+	return newRequest("blocksSubscribe", BlockSubscribeParams{Network: network}), nil
+}
+
+// NewSlotsSubscribeRequest returns a slotsSubscribe request for the network,
+// DefaultNetwork if empty. It returns an error if the network is not one of Networks.
+func NewSlotsSubscribeRequest(network Network) (*JSONRPCRequest, error) {
+	network = network.orDefault()
+	if err := network.Validate(); err != nil {
+		return nil, err
+	}
+	return newRequest("slotsSubscribe", SlotSubscribeParams{Network: network}), nil
+}
+
+// newRequest returns a JSON-RPC 2.0 request with ID 1.
+func newRequest(method string, params interface{}) *JSONRPCRequest {
+	return &JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  method,
+		Params:  params,
+	}
+}
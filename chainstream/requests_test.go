@@ -0,0 +1,55 @@
+package chainstream_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestNewTransactionsSubscribeRequest(t *testing.T) {
+	request, err := chainstream.NewTransactionsSubscribeRequest("", chainstream.TransactionFilter{
+		ExcludeVotes: true,
+		AccountKeys:  &chainstream.AccountKeysFilter{OneOf: []string{"6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"}},
+	})
+	if err != nil {
+		t.Fatalf("NewTransactionsSubscribeRequest() error: %v", err)
+	}
+	data, err := json.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{`"jsonrpc":"2.0"`, `"id":1`, `"method":"transactionsSubscribe"`, `"network":"solana-mainnet"`, `"commitment":"confirmed"`} {
+		if !strings.Contains(string(data), part) {
+			t.Errorf("request %s is missing %s", data, part)
+		}
+	}
+
+	if _, err := chainstream.NewTransactionsSubscribeRequest("", chainstream.TransactionFilter{Commitment: chainstream.Commitment(7)}); err == nil {
+		t.Error("NewTransactionsSubscribeRequest() accepted an unknown commitment")
+	}
+	if _, err := chainstream.NewTransactionsSubscribeRequest("solana mainnet", chainstream.TransactionFilter{}); err == nil {
+		t.Error("NewTransactionsSubscribeRequest() accepted an invalid network")
+	}
+}
+
+func TestNewSlotsAndBlocksSubscribeRequest(t *testing.T) {
+	blocks, err := chainstream.NewBlocksSubscribeRequest("solana-devnet")
+	if err != nil {
+		t.Fatalf("NewBlocksSubscribeRequest() error: %v", err)
+	}
+	if blocks.Method != "blocksSubscribe" || blocks.Params.(chainstream.BlockSubscribeParams).Network != "solana-devnet" {
+		t.Errorf("NewBlocksSubscribeRequest() = %+v", blocks)
+	}
+	slots, err := chainstream.NewSlotsSubscribeRequest("")
+	if err != nil {
+		t.Fatalf("NewSlotsSubscribeRequest() error: %v", err)
+	}
+	if slots.JSONRPC != "2.0" || slots.Method != "slotsSubscribe" || slots.Params.(chainstream.SlotSubscribeParams).Network != chainstream.DefaultNetwork {
+		t.Errorf("NewSlotsSubscribeRequest() = %+v", slots)
+	}
+	if _, err := chainstream.NewSlotsSubscribeRequest("Solana/Mainnet"); err == nil {
+		t.Error("NewSlotsSubscribeRequest() accepted an invalid network")
+	}
+}
@@ -0,0 +1,133 @@
+package chainstream
+
+import (
+	"context"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+// Gap is the range of slots whose notifications may have been missed while the client was
+// reconnecting: from the slot of the last delivered notification to the slot of the first
+// notification of the new session, both inclusive. Notifications of the bounding slots
+// that were already delivered are dropped as duplicates.
+type Gap struct {
+	FromSlot uint64
+	ToSlot   uint64
+	// Filter is the filter of the subscription. The client applies it to the backfilled
+	// notifications, so a BackfillFunc may use it only to load less.
+	Filter TransactionFilter
+}
+
+// BackfillFunc loads the transactions of the gap, e.g. with RPC getBlock, and passes them
+// to do in slot order. An error returned by do stops the backfill.
+type BackfillFunc func(ctx context.Context, gap Gap, do func(notification *TransactionNotification) error) error
+
+// Match reports whether the notification passes the filter. Commitment is not checked.
+func (f *TransactionFilter) Match(notification *TransactionNotification) bool {
+	if f.ExcludeVotes && isVote(notification) {
+		return false
+	}
+	return f.AccountKeys == nil || f.AccountKeys.Match(notification)
+}
+
+// isVote reports whether the notification is a vote transaction.
+func isVote(notification *TransactionNotification) bool {
+	if notification.Params.Result.Context.IsVote {
+		return true
+	}
+	keys := notification.AccountKeys()
+	for _, instruction := range notification.Params.Result.Value.Transaction.Message.Instructions {
+		if keyAt(keys, instruction.ProgramIDIndex) == string(programs.Vote) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxHeld bounds the live notifications held while a gap is backfilled.
+const maxHeld = 4096
+
+// backfillEvent carries a notification of a gap, or the end of its backfill, to the loop
+// of the subscription.
+type backfillEvent struct {
+	notification *TransactionNotification
+	done         bool
+}
+
+// resume tracks the gap to backfill after a reconnect.
+type resume struct {
+	// delivered is the slot of the last delivered notification.
+	delivered uint64
+	// session is the reconnected session whose first notification closes the gap.
+	session *session
+	from    uint64
+}
+
+// reconnected records that the session replaced a failed one.
+func (r *resume) reconnected(s *session) {
+	if r.delivered == 0 {
+		return
+	}
+	if r.session == nil {
+		// A session failing before its first notification leaves the original gap open.
+		r.from = r.delivered
+	}
+	r.session = s
+}
+
+// gap returns the gap closed by the notification of the session, if any.
+func (r *resume) gap(s *session, slot uint64, maxSlots uint64) (Gap, bool) {
+	if r.session == nil || r.session != s {
+		return Gap{}, false
+	}
+	r.session = nil
+	if slot < r.from {
+		return Gap{}, false
+	}
+	gap := Gap{FromSlot: r.from, ToSlot: slot}
+	if maxSlots > 0 && gap.ToSlot-gap.FromSlot+1 > maxSlots {
+		gap.FromSlot = gap.ToSlot - maxSlots + 1
+	}
+	return gap, true
+}
+
+// backfill passes the notifications loaded by Hooks.Backfill for the gap and matching the
+// filter of the subscription to deliver, which admits them like live notifications. A
+// failed gap is loaded again according to the pipeline retry policy and
+// Config.RetryBudget; the notifications delivered by the failed attempts are dropped as
+// duplicates. It runs off the loop of the subscription, so that its sessions keep being
+// served meanwhile.
+func (c *C) backfill(ctx context.Context, gap Gap, request *JSONRPCRequest, deliver func(*TransactionNotification) error) {
+	if params, ok := transactionParams(request); ok {
+		gap.Filter = params.Filter
+	}
+	c.stats.gaps.Add(1)
+	policy := c.config.Pipeline.Retry
+	backoff := time.Duration(policy.InitialBackoff)
+	for n := 1; ; n++ {
+		err := c.config.Hooks.Backfill(ctx, gap, func(notification *TransactionNotification) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !gap.Filter.Match(notification) {
+				return nil
+			}
+			c.stats.backfilled.Add(1)
+			return deliver(notification)
+		})
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		if IsPermanent(err) || n >= policy.MaxAttempts || !c.config.RetryBudget.Allow(RetryBackfill) {
+			c.stats.backfillFails.Add(1)
+			return
+		}
+		select {
+		case <-c.clock().After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = policy.next(backoff)
+	}
+}
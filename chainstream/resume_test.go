@@ -0,0 +1,164 @@
+package chainstream_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestBackfillAfterReconnect(t *testing.T) {
+	srv := newFakeServer(t, func(ctx context.Context, conn *websocket.Conn, n int) error {
+		if n == 1 {
+			// The first connection drops after slot 2.
+			for _, slot := range []uint64{1, 2} {
+				if err := wsjson.Write(ctx, conn, notification(slot)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return sendSlots(5)(ctx, conn, n)
+	})
+
+	var gaps []chainstream.Gap
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Hooks.Backfill = func(_ context.Context, gap chainstream.Gap, do func(*chainstream.TransactionNotification) error) error {
+		gaps = append(gaps, gap)
+		vote := notification(3)
+		vote.Params.Result.Context.Signature = "vote"
+		vote.Params.Result.Context.IsVote = true
+		for _, n := range []*chainstream.TransactionNotification{notification(2), vote, notification(3), notification(4), notification(5)} {
+			if err := do(n); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var delivered []uint64
+	err := client.TransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) {
+		delivered = append(delivered, n.Slot())
+		if n.Slot() == 5 {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("TransactionsNotifications() error: %v", err)
+	}
+
+	if len(gaps) != 1 || gaps[0].FromSlot != 2 || gaps[0].ToSlot != 5 || !gaps[0].Filter.ExcludeVotes {
+		t.Errorf("gaps = %+v, expected slots 2 to 5 with the subscription filter", gaps)
+	}
+	if len(delivered) != 5 {
+		t.Fatalf("delivered %v, expected slots 1 to 5 once", delivered)
+	}
+	for i, slot := range delivered {
+		if slot != uint64(i+1) {
+			t.Errorf("delivered %v, expected slots 1 to 5 in order", delivered)
+			break
+		}
+	}
+	// Slot 2 is a duplicate, and so is the live slot 5 unless the handler cancels first.
+	if stats := client.Stats(); stats.Gaps != 1 || stats.Backfilled != 4 || stats.Duplicates < 1 || stats.Duplicates > 2 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestBackfillOffTheLoop(t *testing.T) {
+	srv := newFakeServer(t, func(ctx context.Context, conn *websocket.Conn, n int) error {
+		if n == 1 {
+			return wsjson.Write(ctx, conn, notification(2))
+		}
+		return sendSlots(5, 6, 7)(ctx, conn, n)
+	})
+
+	var client *chainstream.C
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Hooks.Backfill = func(ctx context.Context, _ chainstream.Gap, do func(*chainstream.TransactionNotification) error) error {
+		// The loop keeps receiving while the gap is loaded.
+		for client.Stats().Received < 4 {
+			select {
+			case <-time.After(time.Millisecond):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		for _, n := range []*chainstream.TransactionNotification{notification(3), notification(4)} {
+			if err := do(n); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	client = chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var delivered []uint64
+	err := client.TransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) {
+		if delivered = append(delivered, n.Slot()); n.Slot() == 7 {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("TransactionsNotifications() error: %v", err)
+	}
+	if !reflect.DeepEqual(delivered, []uint64{2, 3, 4, 5, 6, 7}) {
+		t.Errorf("delivered %v, expected the gap before the live slots held meanwhile", delivered)
+	}
+}
+
+func TestBackfillRetry(t *testing.T) {
+	srv := newFakeServer(t, func(ctx context.Context, conn *websocket.Conn, n int) error {
+		if n == 1 {
+			return wsjson.Write(ctx, conn, notification(2))
+		}
+		return sendSlots(5)(ctx, conn, n)
+	})
+
+	calls := 0
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Pipeline.Retry = chainstream.RetryPolicy{MaxAttempts: 2, InitialBackoff: chainstream.Duration(time.Millisecond), Multiplier: 1}
+	config.Hooks.Backfill = func(_ context.Context, _ chainstream.Gap, do func(*chainstream.TransactionNotification) error) error {
+		calls++
+		for _, n := range []*chainstream.TransactionNotification{notification(3), notification(4)} {
+			if err := do(n); err != nil {
+				return err
+			}
+			if calls == 1 {
+				return errors.New("rpc unavailable")
+			}
+		}
+		return nil
+	}
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var delivered []uint64
+	err := client.TransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) {
+		if delivered = append(delivered, n.Slot()); n.Slot() == 5 {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("TransactionsNotifications() error: %v", err)
+	}
+	if !reflect.DeepEqual(delivered, []uint64{2, 3, 4, 5}) {
+		t.Errorf("delivered %v, expected slots 2 to 5 once", delivered)
+	}
+	if stats := client.Stats(); calls != 2 || stats.BackfillFailures != 0 {
+		t.Errorf("backfilled %d times with %d failures, expected a successful retry", calls, stats.BackfillFailures)
+	}
+}
@@ -0,0 +1,199 @@
+package chainstream
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+// RetryKind names the subsystem taking a retry from a RetryBudget.
+type RetryKind string
+
+// Kinds of the retries taken from a RetryBudget.
+const (
+	// RetryReconnect is a new session replacing a failed one, of chainstream and geyser
+	// subscriptions.
+	RetryReconnect RetryKind = "reconnect"
+	// RetryBackfill is a new attempt of Hooks.Backfill for a gap it failed to load.
+	RetryBackfill RetryKind = "backfill"
+	// RetryHandler is a new attempt of a handler, batch handler or pipeline sink.
+	RetryHandler RetryKind = "handler"
+	// RetrySink is a replay of the events spooled while their sink failed.
+	RetrySink RetryKind = "sink"
+)
+
+// RetryBudget is a token bucket shared by the retries of several subsystems, possibly of
+// several clients, so that correlated failures such as a provider outage do not multiply
+// into a burst of retries. Every retry takes a token; tokens are refilled at a fixed rate
+// up to the capacity of the bucket.
+//
+// Retries that can be given up, of handlers, backfills and sinks, fail once the budget is
+// exhausted. Reconnects wait for a token instead. A nil budget allows every retry. It is
+// safe for concurrent use.
+//
+// Budgets are created with NewRetryBudget. A zero RetryBudget has no capacity and, like a
+// nil budget, allows every retry, only counting them.
+type RetryBudget struct {
+	// Clock drives the refill. Nil uses the system clock.
+	Clock clock.Clock
+	// OnExhausted, when set, is called when a retry finds the budget exhausted: a handler,
+	// backfill or sink retry given up, or a reconnect made to wait.
+	OnExhausted func(kind RetryKind)
+
+	capacity float64
+	rate     float64
+
+	mu        sync.Mutex
+	tokens    float64
+	refilled  time.Time
+	granted   map[RetryKind]uint64
+	exhausted map[RetryKind]uint64
+}
+
+// NewRetryBudget returns a full budget of capacity retries, refilled with perSecond
+// retries every second. Both must be positive: a budget never refilled would make
+// reconnects wait forever once exhausted.
+func NewRetryBudget(capacity int, perSecond float64) (*RetryBudget, error) {
+	if capacity <= 0 {
+		return nil, errors.New("chainstream: retry budget capacity must be positive")
+	}
+	if !(perSecond > 0) {
+		return nil, errors.New("chainstream: retry budget refill rate must be positive")
+	}
+	return &RetryBudget{
+		capacity: float64(capacity),
+		rate:     perSecond,
+		tokens:   float64(capacity),
+	}, nil
+}
+
+// RetryBudgetStats is a snapshot of a RetryBudget.
+type RetryBudgetStats struct {
+	// Tokens is the number of retries available, Capacity the most there can be.
+	Tokens   float64
+	Capacity float64
+	// Granted is the number of retries taken, by kind.
+	Granted map[RetryKind]uint64
+	// Exhausted is the number of retries that found the budget exhausted, by kind.
+	Exhausted map[RetryKind]uint64
+}
+
+// Kinds returns the kinds of the statistics, sorted.
+func (s RetryBudgetStats) Kinds() []RetryKind {
+	var kinds []RetryKind
+	for kind := range s.Granted {
+		kinds = append(kinds, kind)
+	}
+	for kind := range s.Exhausted {
+		if _, ok := s.Granted[kind]; !ok {
+			kinds = append(kinds, kind)
+		}
+	}
+	slices.Sort(kinds)
+	return kinds
+}
+
+func (b *RetryBudget) clock() clock.Clock {
+	if b.Clock == nil {
+		return clock.System()
+	}
+	return b.Clock
+}
+
+// take takes a token if there is one. Otherwise it returns the time until the next one.
+// It must be called with mu held.
+func (b *RetryBudget) take() time.Duration {
+	if b.capacity <= 0 {
+		// A zero budget, not made by NewRetryBudget.
+		return 0
+	}
+	now := b.clock().Now()
+	if !b.refilled.IsZero() {
+		b.tokens = min(b.capacity, b.tokens+now.Sub(b.refilled).Seconds()*b.rate)
+	}
+	b.refilled = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return max(time.Duration((1-b.tokens)/b.rate*float64(time.Second)), time.Millisecond)
+}
+
+// count records a retry of the kind, granted or not. It must be called with mu held.
+func (b *RetryBudget) count(kind RetryKind, granted bool) {
+	if b.granted == nil {
+		b.granted = make(map[RetryKind]uint64)
+		b.exhausted = make(map[RetryKind]uint64)
+	}
+	if granted {
+		b.granted[kind]++
+	} else {
+		b.exhausted[kind]++
+	}
+}
+
+// Allow takes a retry of the kind from the budget and reports whether there was one. A nil
+// budget always allows.
+func (b *RetryBudget) Allow(kind RetryKind) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	allowed := b.take() == 0
+	b.count(kind, allowed)
+	b.mu.Unlock()
+
+	if !allowed && b.OnExhausted != nil {
+		b.OnExhausted(kind)
+	}
+	return allowed
+}
+
+// Wait takes a retry of the kind from the budget, waiting for one if it is exhausted. It
+// returns the error of ctx if ctx is done first. A nil budget returns at once.
+func (b *RetryBudget) Wait(ctx context.Context, kind RetryKind) error {
+	if b == nil {
+		return nil
+	}
+	for waited := false; ; waited = true {
+		b.mu.Lock()
+		delay := b.take()
+		if delay == 0 || !waited {
+			b.count(kind, delay == 0)
+		}
+		b.mu.Unlock()
+
+		if delay == 0 {
+			return nil
+		}
+		if !waited && b.OnExhausted != nil {
+			b.OnExhausted(kind)
+		}
+		select {
+		case <-b.clock().After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Stats returns a snapshot of the budget.
+func (b *RetryBudget) Stats() RetryBudgetStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	tokens := b.tokens
+	if !b.refilled.IsZero() {
+		tokens = min(b.capacity, tokens+b.clock().Now().Sub(b.refilled).Seconds()*b.rate)
+	}
+	return RetryBudgetStats{
+		Tokens:    tokens,
+		Capacity:  b.capacity,
+		Granted:   maps.Clone(b.granted),
+		Exhausted: maps.Clone(b.exhausted),
+	}
+}
@@ -0,0 +1,127 @@
+package chainstream_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+func TestRetryBudget(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	budget, err := chainstream.NewRetryBudget(2, 1)
+	if err != nil {
+		t.Fatalf("NewRetryBudget() error: %v", err)
+	}
+	budget.Clock = fake
+	var exhausted []chainstream.RetryKind
+	budget.OnExhausted = func(kind chainstream.RetryKind) { exhausted = append(exhausted, kind) }
+
+	for i, expected := range []bool{true, true, false} {
+		if allowed := budget.Allow(chainstream.RetryHandler); allowed != expected {
+			t.Errorf("Allow() #%d = %v, expected %v", i+1, allowed, expected)
+		}
+	}
+	fake.Advance(time.Second)
+	if !budget.Allow(chainstream.RetryBackfill) {
+		t.Error("Allow() refused a retry refilled after a second")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	waited := make(chan error, 1)
+	go func() { waited <- budget.Wait(ctx, chainstream.RetryReconnect) }()
+	fake.BlockUntil(1)
+	fake.Advance(time.Second)
+	if err := <-waited; err != nil {
+		t.Errorf("Wait() error: %v", err)
+	}
+
+	stats := budget.Stats()
+	if stats.Granted[chainstream.RetryHandler] != 2 || stats.Granted[chainstream.RetryBackfill] != 1 || stats.Granted[chainstream.RetryReconnect] != 1 {
+		t.Errorf("granted %v", stats.Granted)
+	}
+	if expected := []chainstream.RetryKind{chainstream.RetryHandler, chainstream.RetryReconnect}; len(exhausted) != 2 || exhausted[0] != expected[0] || exhausted[1] != expected[1] {
+		t.Errorf("OnExhausted received %v, expected %v", exhausted, expected)
+	}
+	if stats.Tokens != 0 || stats.Capacity != 2 {
+		t.Errorf("%v of %v tokens left, expected none of 2", stats.Tokens, stats.Capacity)
+	}
+}
+
+func TestRetryBudgetWaitCanceled(t *testing.T) {
+	budget, err := chainstream.NewRetryBudget(1, 0.001)
+	if err != nil {
+		t.Fatalf("NewRetryBudget() error: %v", err)
+	}
+	budget.Allow(chainstream.RetryHandler)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := budget.Wait(ctx, chainstream.RetryReconnect); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() error %v, expected the error of the context", err)
+	}
+	var nilBudget *chainstream.RetryBudget
+	if !nilBudget.Allow(chainstream.RetryHandler) || nilBudget.Wait(ctx, chainstream.RetryReconnect) != nil {
+		t.Error("a nil budget refused a retry")
+	}
+}
+
+func TestRetryBudgetZero(t *testing.T) {
+	var exhausted int
+	budget := &chainstream.RetryBudget{OnExhausted: func(chainstream.RetryKind) { exhausted++ }}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if !budget.Allow(chainstream.RetryHandler) || budget.Wait(ctx, chainstream.RetryReconnect) != nil {
+		t.Error("a zero budget refused a retry")
+	}
+	if stats := budget.Stats(); stats.Granted[chainstream.RetryHandler] != 1 || stats.Granted[chainstream.RetryReconnect] != 1 || exhausted != 0 {
+		t.Errorf("granted %v, %d exhausted", stats.Granted, exhausted)
+	}
+}
+
+func TestNewRetryBudgetInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		capacity  int
+		perSecond float64
+	}{{0, 1}, {-1, 1}, {1, 0}, {1, -1}} {
+		if _, err := chainstream.NewRetryBudget(tt.capacity, tt.perSecond); err == nil {
+			t.Errorf("NewRetryBudget(%d, %v) succeeded", tt.capacity, tt.perSecond)
+		}
+	}
+}
+
+func TestRetryBudgetBoundsHandlerRetries(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1))
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Pipeline.Retry = chainstream.RetryPolicy{MaxAttempts: 5, InitialBackoff: chainstream.Duration(time.Millisecond), Multiplier: 1}
+	budget, err := chainstream.NewRetryBudget(1, 0.001)
+	if err != nil {
+		t.Fatalf("NewRetryBudget() error: %v", err)
+	}
+	config.RetryBudget = budget
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	dead := make(chan struct{})
+	config.Hooks.DeadLetter = func(*chainstream.TransactionNotification, error) {
+		close(dead)
+	}
+	attempts := 0
+	go func() {
+		<-dead
+		cancel()
+	}()
+	err = chainstream.NewClient(config).HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(*chainstream.TransactionNotification) error {
+		attempts++
+		return errors.New("sink unavailable")
+	})
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, expected the single retry of the budget", attempts)
+	}
+}
@@ -0,0 +1,67 @@
+package chainstream
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Archive is a local record of notifications a subscription can rewind through, see
+// WithRewind.
+type Archive interface {
+	// Replay passes the notifications recorded at or after since to do in the order they
+	// were recorded. An error returned by do stops the replay.
+	Replay(ctx context.Context, since time.Time, do func(notification *TransactionNotification) error) error
+}
+
+// rewind is the archive window replayed by WithRewind.
+type rewind struct {
+	archive Archive
+	window  time.Duration
+}
+
+// WithRewind replays the notifications of the last window from the archive through the
+// handler before the live notifications, e.g. after deploying new detection logic.
+// Replayed notifications are flagged, see TransactionNotification.Replayed; they bypass
+// Config.Store and MaxNotificationAge but not the filters, and the ones received live as
+// well are delivered once. They do not count towards WithMaxNotifications.
+func WithRewind(archive Archive, window time.Duration) SubscribeOption {
+	return func(o *subscribeOptions) { o.rewind = &rewind{archive: archive, window: window} }
+}
+
+// Replayed reports whether the notification was replayed from an archive by WithRewind
+// rather than received live.
+func (t *TransactionNotification) Replayed() bool {
+	return t.replayed
+}
+
+// rewind passes the notifications of the archive window through the filter stage to
+// dispatch.
+func (c *C) rewind(ctx context.Context, r *rewind, request *JSONRPCRequest, seen *signatureSet, dispatch func(*TransactionNotification)) error {
+	var filter TransactionFilter
+	if params, ok := transactionParams(request); ok {
+		filter = params.Filter
+	}
+	err := r.archive.Replay(ctx, c.clock().Now().Add(-r.window), func(notification *TransactionNotification) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !filter.Match(notification) {
+			return nil
+		}
+		notification.replayed = true
+		c.stats.rewound.Add(1)
+		if !seen.Add(notification.Signature()) {
+			c.stats.duplicates.Add(1)
+			return nil
+		}
+		if c.matches(notification) {
+			dispatch(notification)
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("cannot rewind: %w", err)
+	}
+	return nil
+}
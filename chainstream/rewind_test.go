@@ -0,0 +1,67 @@
+package chainstream_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+	"github.com/gerasimovvladislav/zensol-go/replay"
+)
+
+func TestWithRewind(t *testing.T) {
+	now := time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+
+	// The archive holds slots 4 to 6, slot 4 before the rewind window.
+	path := filepath.Join(t.TempDir(), "archive.jsonl")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := json.NewEncoder(f)
+	for slot, age := range map[uint64]time.Duration{4: 2 * time.Minute, 5: 30 * time.Second, 6: 10 * time.Second} {
+		n := notification(slot)
+		n.Params.Result.Context.NodeTime = now.Add(-age)
+		_ = enc.Encode(n)
+	}
+	_ = f.Close()
+
+	// Slot 6 is received live as well.
+	srv := newFakeServer(t, sendSlots(6, 7))
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Clock = fake
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	delivered := make(map[uint64]bool)
+	err = client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) error {
+		delivered[n.Slot()] = n.Replayed()
+		if n.Slot() == 7 {
+			cancel()
+		}
+		return nil
+	}, chainstream.WithRewind(replay.Archive{Path: path}, time.Minute))
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+
+	expected := map[uint64]bool{5: true, 6: true, 7: false}
+	if len(delivered) != len(expected) {
+		t.Fatalf("delivered %v, expected %v", delivered, expected)
+	}
+	for slot, replayed := range expected {
+		if r, ok := delivered[slot]; !ok || r != replayed {
+			t.Errorf("slot %d delivered %v, replayed %v, expected replayed %v", slot, ok, r, replayed)
+		}
+	}
+	if stats := client.Stats(); stats.Rewound != 2 || stats.Duplicates != 1 {
+		t.Errorf("Stats() = %+v, expected two rewound notifications and one duplicate", stats)
+	}
+}
@@ -0,0 +1,107 @@
+package chainstream_test
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+// slotFeed produces one slot every interval since start. Every connection replays
+// the last few slots first, so overlapping connections deliver duplicates.
+func slotFeed(start time.Time, interval time.Duration) func(ctx context.Context, conn *websocket.Conn, n int) error {
+	current := func() uint64 { return uint64(time.Since(start) / interval) }
+	return func(ctx context.Context, conn *websocket.Conn, _ int) error {
+		next := current()
+		if next > 3 {
+			next -= 3
+		}
+		for {
+			for ; next <= current(); next++ {
+				if err := wsjson.Write(ctx, conn, notification(next)); err != nil {
+					return err
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(interval / 2):
+			}
+		}
+	}
+}
+
+func TestSessionRotation(t *testing.T) {
+	srv := newFakeServer(t, slotFeed(time.Now(), 5*time.Millisecond))
+
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Conn.RotateEvery = chainstream.Duration(60 * time.Millisecond)
+	config.Conn.RotationOverlap = chainstream.Duration(30 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+
+	var (
+		mu    sync.Mutex
+		slots []uint64
+		seen  = make(map[string]int)
+	)
+	err := chainstream.NewClient(config).TransactionsNotifications(ctx, chainstream.FirehoseNoVotes(),
+		func(n *chainstream.TransactionNotification) {
+			mu.Lock()
+			defer mu.Unlock()
+			seen[n.Signature()]++
+			slots = append(slots, n.Slot())
+		})
+	if err != nil {
+		t.Fatalf("TransactionsNotifications() error: %v", err)
+	}
+
+	if got := srv.connections.Load(); got < 3 {
+		t.Errorf("got %d connections, expected rotations", got)
+	}
+	for sig, count := range seen {
+		if count > 1 {
+			t.Errorf("signature %s delivered %d times", sig, count)
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i] < slots[j] })
+	for i := 1; i < len(slots); i++ {
+		if slots[i] != slots[i-1]+1 {
+			t.Errorf("gap between slots %d and %d", slots[i-1], slots[i])
+		}
+	}
+}
+
+func TestRotationHandover(t *testing.T) {
+	srv := newFakeServer(t, slotFeed(time.Now(), 5*time.Millisecond))
+
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Conn.RotateEvery = chainstream.Duration(200 * time.Millisecond)
+	config.Conn.RotationOverlap = chainstream.Duration(10 * time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 450*time.Millisecond)
+	defer cancel()
+
+	activeAfterRotation := make(chan int32, 1)
+	go func() {
+		time.Sleep(320 * time.Millisecond)
+		activeAfterRotation <- srv.active.Load()
+	}()
+
+	err := chainstream.NewClient(config).TransactionsNotifications(ctx, chainstream.FirehoseNoVotes(),
+		func(*chainstream.TransactionNotification) {})
+	if err != nil {
+		t.Fatalf("TransactionsNotifications() error: %v", err)
+	}
+
+	if got := <-activeAfterRotation; got != 1 {
+		t.Errorf("got %d active connections after rotation, expected the old one to be released", got)
+	}
+}
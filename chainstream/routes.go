@@ -0,0 +1,191 @@
+package chainstream
+
+import (
+	"math"
+	"sort"
+)
+
+// WrappedSOLMint is the mint of wrapped SOL. Native SOL legs of a swap are reported with it.
+const WrappedSOLMint = "So11111111111111111111111111111111111111112"
+
+// SwapHop is a single exchange against a pool: the pool received InputAmount of InputMint
+// and paid out OutputAmount of OutputMint.
+type SwapHop struct {
+	// Pool is the authority that owns the pool vaults and signed the output transfer.
+	Pool           string `json:"pool"`
+	InputMint      string `json:"inputMint"`
+	InputAmount    uint64 `json:"inputAmount"`
+	InputDecimals  int    `json:"inputDecimals"`
+	OutputMint     string `json:"outputMint"`
+	OutputAmount   uint64 `json:"outputAmount"`
+	OutputDecimals int    `json:"outputDecimals"`
+}
+
+// SwapRoute is the token flow of a top-level instruction as a chain of hops A→B→C.
+type SwapRoute struct {
+	// Index is the top-level instruction the route was reconstructed from.
+	Index int       `json:"index"`
+	Hops  []SwapHop `json:"hops"`
+	// Complete is true when the output mint of every hop is the input mint of the next one.
+	Complete bool `json:"complete"`
+}
+
+// InputMint returns the mint paid into the first hop, empty for a route without hops.
+func (r *SwapRoute) InputMint() string { return r.first().InputMint }
+
+// InputAmount returns the amount paid into the first hop.
+func (r *SwapRoute) InputAmount() uint64 { return r.first().InputAmount }
+
+// OutputMint returns the mint received from the last hop, empty for a route without hops.
+func (r *SwapRoute) OutputMint() string { return r.last().OutputMint }
+
+// OutputAmount returns the amount received from the last hop.
+func (r *SwapRoute) OutputAmount() uint64 { return r.last().OutputAmount }
+
+// Price returns the effective execution price across all hops: input tokens paid per
+// output token received, in UI units. It is zero for a route without hops.
+func (r *SwapRoute) Price() float64 {
+	first, last := r.first(), r.last()
+	if last.OutputAmount == 0 {
+		return 0
+	}
+	input := float64(first.InputAmount) / math.Pow10(first.InputDecimals)
+	output := float64(last.OutputAmount) / math.Pow10(last.OutputDecimals)
+	return input / output
+}
+
+// first returns the first hop, a zero hop for a route without hops.
+func (r *SwapRoute) first() SwapHop {
+	if len(r.Hops) == 0 {
+		return SwapHop{}
+	}
+	return r.Hops[0]
+}
+
+// last returns the last hop, a zero hop for a route without hops.
+func (r *SwapRoute) last() SwapHop {
+	if len(r.Hops) == 0 {
+		return SwapHop{}
+	}
+	return r.Hops[len(r.Hops)-1]
+}
+
+// SwapRoutes reconstructs swap routes from the decoded transfers of every top-level instruction.
+// A hop is a transfer out of a pool, signed by the pool authority, paired with the nearest
+// transfer of another mint into an account of the same authority.
+func (t *TransactionNotification) SwapRoutes() []SwapRoute {
+	accounts := t.tokenAccounts()
+	decimals := make(map[string]int, len(accounts))
+	for _, balance := range accounts {
+		decimals[balance.Mint] = balance.UIAmount.Decimals
+	}
+
+	byInstruction := make(map[int][]Transfer)
+	var order []int
+	for _, transfer := range t.Transfers() {
+		if transfer.Kind != TransferSOL && transfer.Kind != TransferToken {
+			continue
+		}
+		if transfer.Kind == TransferSOL {
+			transfer.Mint = WrappedSOLMint
+		}
+		if _, ok := byInstruction[transfer.Index]; !ok {
+			order = append(order, transfer.Index)
+		}
+		byInstruction[transfer.Index] = append(byInstruction[transfer.Index], transfer)
+	}
+
+	signers := make(map[string]struct{})
+	for _, signer := range t.Signers() {
+		signers[signer] = struct{}{}
+	}
+
+	var routes []SwapRoute
+	for _, index := range order {
+		hops := pairHops(byInstruction[index], accounts, signers)
+		if len(hops) == 0 {
+			continue
+		}
+		route := SwapRoute{Index: index, Complete: true}
+		for _, hop := range hops {
+			hop.InputDecimals = mintDecimals(decimals, hop.InputMint)
+			hop.OutputDecimals = mintDecimals(decimals, hop.OutputMint)
+			if n := len(route.Hops); n > 0 && route.Hops[n-1].OutputMint != hop.InputMint {
+				route.Complete = false
+			}
+			route.Hops = append(route.Hops, hop)
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// pairHops matches pool output transfers with pool input transfers, ordered by position.
+// Transfers signed by a transaction signer are the trader's own and never pool outputs.
+func pairHops(transfers []Transfer, accounts map[string]TokenBalance, signers map[string]struct{}) []SwapHop {
+	used := make([]bool, len(transfers))
+	type positioned struct {
+		pos int
+		hop SwapHop
+	}
+	var found []positioned
+
+	for i, out := range transfers {
+		if out.Kind != TransferToken || out.Authority == "" || used[i] {
+			continue
+		}
+		if _, ok := signers[out.Authority]; ok {
+			continue
+		}
+		pool := out.Authority
+		best := -1
+		for j, in := range transfers {
+			if j == i || used[j] || in.Mint == out.Mint {
+				continue
+			}
+			if in.To != pool && accounts[in.To].Owner != pool {
+				continue
+			}
+			if best < 0 || abs(j-i) < abs(best-i) {
+				best = j
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		used[i], used[best] = true, true
+		in := transfers[best]
+		found = append(found, positioned{
+			pos: min(i, best),
+			hop: SwapHop{
+				Pool:         pool,
+				InputMint:    in.Mint,
+				InputAmount:  in.Amount,
+				OutputMint:   out.Mint,
+				OutputAmount: out.Amount,
+			},
+		})
+	}
+
+	// Hops are found by output position; order them by their first transfer.
+	sort.SliceStable(found, func(i, j int) bool { return found[i].pos < found[j].pos })
+	hops := make([]SwapHop, len(found))
+	for i := range found {
+		hops[i] = found[i].hop
+	}
+	return hops
+}
+
+func mintDecimals(decimals map[string]int, mint string) int {
+	if mint == WrappedSOLMint {
+		return 9
+	}
+	return decimals[mint]
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,87 @@
+package chainstream_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/mr-tron/base58"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func tokenTransferData(amount uint64) string {
+	data := make([]byte, 9)
+	data[0] = 3
+	binary.LittleEndian.PutUint64(data[1:], amount)
+	return base58.Encode(data)
+}
+
+func TestSwapRoutesBuy(t *testing.T) {
+	routes := loadNotification(t, "testdata/sample_tx_buy.json").SwapRoutes()
+	if len(routes) != 1 || len(routes[0].Hops) != 1 {
+		t.Fatalf("SwapRoutes() = %+v, expected a single hop", routes)
+	}
+
+	hop := routes[0].Hops[0]
+	expected := chainstream.SwapHop{
+		Pool:        "8fC59gfiQerpTpTiEJVvB4u1UgHuBsEDGnxiQUU5AJQo",
+		InputMint:   chainstream.WrappedSOLMint,
+		InputAmount: 10000000, InputDecimals: 9,
+		OutputMint:   "DNvtizsEYyknJoW3QYwDA7ncjxri3KBBTeLydEZCpump",
+		OutputAmount: 357547484136, OutputDecimals: 6,
+	}
+	if hop != expected {
+		t.Errorf("hop = %+v\nexpected %+v", hop, expected)
+	}
+}
+
+func TestSwapRoutesMultiHop(t *testing.T) {
+	// wallet swaps A→B on pool1 and B→C on pool2 within a single aggregator instruction.
+	keys := []string{"wallet", "walletA", "walletB", "walletC", "pool1A", "pool1B", "pool2B", "pool2C", "pool1", "pool2", "Tokenkeg", "aggregator"}
+	keys[10] = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+	balance := func(idx int, mint, owner string) chainstream.TokenBalance {
+		return chainstream.TokenBalance{AccountIndex: idx, Mint: mint, Owner: owner, UIAmount: chainstream.TokenAmountUI{Amount: "0", Decimals: 6}}
+	}
+	transfer := func(from, to, authority int, amount uint64) chainstream.CompiledInstruction {
+		return chainstream.CompiledInstruction{ProgramIDIndex: 10, Accounts: []int{from, to, authority}, Data: tokenTransferData(amount)}
+	}
+
+	var tx chainstream.TransactionNotification
+	tx.Params.Result.Value.Transaction.Message.AccountKeys = keys
+	tx.Params.Result.Value.Transaction.Message.Instructions = []chainstream.CompiledInstruction{{ProgramIDIndex: 11}}
+	tx.Params.Result.Value.Meta.InnerInstructions = []chainstream.InnerInstruction{{Index: 0, Instructions: []chainstream.CompiledInstruction{
+		transfer(1, 4, 0, 1000), // walletA → pool1A
+		transfer(5, 2, 8, 500),  // pool1B → walletB
+		transfer(2, 6, 0, 500),  // walletB → pool2B
+		transfer(7, 3, 9, 250),  // pool2C → walletC
+	}}}
+	tx.Params.Result.Value.Meta.PostTokenBalances = []chainstream.TokenBalance{
+		balance(1, "A", "wallet"), balance(2, "B", "wallet"), balance(3, "C", "wallet"),
+		balance(4, "A", "pool1"), balance(5, "B", "pool1"), balance(6, "B", "pool2"), balance(7, "C", "pool2"),
+	}
+
+	routes := tx.SwapRoutes()
+	if len(routes) != 1 {
+		t.Fatalf("SwapRoutes() = %+v, expected one route", routes)
+	}
+	route := routes[0]
+	if !route.Complete || len(route.Hops) != 2 {
+		t.Fatalf("route = %+v, expected two chained hops", route)
+	}
+	if route.Hops[0].Pool != "pool1" || route.Hops[1].Pool != "pool2" {
+		t.Errorf("pools = %s, %s", route.Hops[0].Pool, route.Hops[1].Pool)
+	}
+	if route.InputMint() != "A" || route.InputAmount() != 1000 || route.OutputMint() != "C" || route.OutputAmount() != 250 {
+		t.Errorf("net route %s %d → %s %d", route.InputMint(), route.InputAmount(), route.OutputMint(), route.OutputAmount())
+	}
+	if price := route.Price(); price != 4 {
+		t.Errorf("Price() = %v, expected 4", price)
+	}
+}
+
+func TestSwapRouteWithoutHops(t *testing.T) {
+	var route chainstream.SwapRoute
+	if route.InputMint() != "" || route.InputAmount() != 0 || route.OutputMint() != "" || route.OutputAmount() != 0 || route.Price() != 0 {
+		t.Errorf("route without hops = %s %d → %s %d at %v, expected zero values", route.InputMint(), route.InputAmount(), route.OutputMint(), route.OutputAmount(), route.Price())
+	}
+}
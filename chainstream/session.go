@@ -0,0 +1,360 @@
+package chainstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+// session is a single WebSocket connection carrying one subscription.
+type session struct {
+	endpoint *endpoint
+	decode   decoder
+	conn     *websocket.Conn
+	cancel   context.CancelFunc
+	// clock stamps the notifications with the time they are read.
+	clock clock.Clock
+	// lastSlot is the highest slot delivered by the session. It is only accessed by the
+	// goroutine consuming the session events.
+	lastSlot uint64
+	// lastReceived is the time of the last message of the session and idle tells that the
+	// watchdog has closed it. Both are only accessed by the goroutine consuming the events.
+	lastReceived time.Time
+	idle         bool
+	// pinging is set while a ping waits for its pong.
+	pinging atomic.Bool
+	// pingFailures is the number of consecutive failed pings.
+	pingFailures atomic.Int32
+	// method and subscription identify the subscription made on the connection, released
+	// by release. subscription is nil when the session has none.
+	method             string
+	subscription       interface{}
+	unsubscribeTimeout time.Duration
+	// ack receives the response to the unsubscribe request while release waits for it.
+	ack atomic.Pointer[chan JSONRPCResponse]
+	// readDone is closed when the reader stops.
+	readDone chan struct{}
+}
+
+// unsubscribeID is the JSON-RPC ID of unsubscribe requests sent by release.
+const unsubscribeID = 1 << 30
+
+// sessionEvent is a notification or a read error produced by a session.
+type sessionEvent struct {
+	session      *session
+	notification *TransactionNotification
+	// batch holds the notifications of a message carrying several, passed on as one event
+	// each, see Provider.DecodeTransactions.
+	batch     []*TransactionNotification
+	block     *BlockNotification
+	account   *AccountNotification
+	logs      *LogsNotification
+	program   *ProgramNotification
+	signature *SignatureNotification
+	raw       json.RawMessage
+	err       error
+}
+
+// decoder turns a received message into the payload of a session event.
+type decoder func(data []byte, event *sessionEvent) error
+
+// decoderFor returns the decoder of notifications of the subscribe method.
+func decoderFor(method string) decoder {
+	switch method {
+	case "blocksSubscribe":
+		return decodeBlock
+	case "accountSubscribe":
+		return decodeAccount
+	case "logsSubscribe":
+		return decodeLogs
+	case "programSubscribe":
+		return decodeProgram
+	case "signatureSubscribe":
+		return decodeSignature
+	}
+	return decodeTransaction
+}
+
+// decodeTransaction is the session decoder of transactionsSubscribe subscriptions.
+func decodeTransaction(data []byte, event *sessionEvent) error {
+	var notification TransactionNotification
+	if err := json.Unmarshal(data, &notification); err != nil {
+		return fmt.Errorf("failed to decode notification: %w", err)
+	}
+	event.notification = &notification
+	return nil
+}
+
+// awaitReconnect waits a second before replacing a failed session, then for a retry of
+// Config.RetryBudget. It reports false when ctx is done first.
+func (c *C) awaitReconnect(ctx context.Context) bool {
+	select {
+	case <-c.clock().After(time.Second):
+	case <-ctx.Done():
+		return false
+	}
+	return c.config.RetryBudget.Wait(ctx, RetryReconnect) == nil
+}
+
+// openSession connects to the best scoring endpoint, subscribes and starts reading
+// notifications decoded with decode into events, failing over to the next endpoint when
+// it cannot. failed tells that the previous session has failed, so the client may move to
+// another endpoint without hysteresis.
+func (c *C) openSession(ctx context.Context, request *JSONRPCRequest, decode decoder, events chan<- sessionEvent, failed bool) (*session, error) {
+	if err := validateRequest(request); err != nil {
+		return nil, err
+	}
+	// A method the provider does not offer is not a failure of the endpoint.
+	if _, err := c.provider().Request(request); err != nil {
+		return nil, err
+	}
+	var s *session
+	err := c.failover(ctx, failed, func(e *endpoint) error {
+		var err error
+		s, err = c.subscribe(ctx, e, request, decode, events)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.lastReceived = c.clock().Now()
+	return s, nil
+}
+
+// failover calls connect with the endpoints from the best scoring one, recording a failure
+// of every endpoint it fails on, until it succeeds. It returns the errors of every
+// endpoint when all fail, or the error of the last one when ctx is done.
+func (c *C) failover(ctx context.Context, failed bool, connect func(e *endpoint) error) error {
+	endpoints := c.endpointSet()
+	var (
+		tried []*endpoint
+		errs  []error
+	)
+	for {
+		e := endpoints.pick(c.clock().Now(), failed || len(tried) > 0, tried)
+		if e == nil {
+			if len(errs) == 1 {
+				return errs[0]
+			}
+			return errors.Join(errs...)
+		}
+		err := connect(e)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		endpoints.observeFailure(e, c.clock().Now())
+		tried = append(tried, e)
+		errs = append(errs, err)
+	}
+}
+
+// subscribe opens a session on the endpoint, sending the request as translated by the
+// provider of the client.
+func (c *C) subscribe(ctx context.Context, e *endpoint, request *JSONRPCRequest, decode decoder, events chan<- sessionEvent) (*session, error) {
+	request, err := c.provider().Request(request)
+	if err != nil {
+		return nil, err
+	}
+	wsConn, err := c.dial(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = wsjson.Write(ctx, wsConn, request); err != nil {
+		_ = wsConn.Close(websocket.StatusNormalClosure, "cannot subscribe")
+		return nil, fmt.Errorf("cannot send subscribe transactions: %w", err)
+	}
+
+	var subResp JSONRPCResponse
+	if err = wsjson.Read(ctx, wsConn, &subResp); err != nil {
+		_ = wsConn.Close(websocket.StatusNormalClosure, "cannot subscribe")
+		return nil, fmt.Errorf("cannot read subscribe response: %w", err)
+	}
+	if subResp.Error != nil {
+		_ = wsConn.Close(websocket.StatusNormalClosure, "subscription rejected")
+		return nil, newSubscribeError(request.Method, *subResp.Error)
+	}
+	if subResp.Result == nil {
+		_ = wsConn.Close(websocket.StatusNormalClosure, "subscription rejected")
+		return nil, errors.New("subscribe error: result is nil")
+	}
+
+	s := c.startSession(ctx, e, wsConn, decode, events)
+	s.method, s.subscription = request.Method, subResp.Result
+	return s, nil
+}
+
+// dial connects to the endpoint.
+func (c *C) dial(ctx context.Context, e *endpoint) (*websocket.Conn, error) {
+	wsConn, resp, err := websocket.Dial(ctx, e.url, &websocket.DialOptions{HTTPHeader: e.header, HTTPClient: c.httpClient})
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to chainstream transactions notifications: %w", handshakeError(resp, err))
+	}
+	return wsConn, nil
+}
+
+// startSession starts reading the messages of the connection, decoded with decode, into
+// events. Reading goes on after ctx is done, until the session is closed, so that release
+// can still read the acknowledgement of its unsubscribe request.
+func (c *C) startSession(ctx context.Context, e *endpoint, wsConn *websocket.Conn, decode decoder, events chan<- sessionEvent) *session {
+	// Canceling the context of a read closes the connection.
+	readCtx, cancelRead := context.WithCancel(context.WithoutCancel(ctx))
+	sessionCtx, cancel := context.WithCancel(ctx)
+	s := &session{
+		endpoint: e,
+		decode:   decode,
+		conn:     wsConn,
+		cancel: func() {
+			cancel()
+			cancelRead()
+		},
+		clock:              c.clock(),
+		unsubscribeTimeout: c.unsubscribeTimeout(),
+		readDone:           make(chan struct{}),
+	}
+	buffer := newReadBuffer(c.config.Pipeline.ReadBuffer, c.config.Pipeline.Backpressure, &c.stats)
+	go s.read(sessionCtx, readCtx, buffer, &c.latency)
+	go buffer.forward(sessionCtx, events)
+	return s
+}
+
+// read buffers notifications until the connection fails or is closed, so that a slow
+// consumer does not hold up reading unless PipelineConfig.Backpressure says so. Once ctx is
+// done notifications are dropped.
+func (s *session) read(ctx, readCtx context.Context, buffer *readBuffer, latency *latencies) {
+	defer close(s.readDone)
+	defer close(buffer.queue)
+	for {
+		_, data, err := s.conn.Read(readCtx)
+		if err == nil && s.acknowledged(data) {
+			continue
+		}
+		event := sessionEvent{session: s, err: err}
+		if err == nil {
+			event.err = s.decodeMessage(data, &event, latency)
+		}
+		if errors.Is(event.err, errNoNotification) {
+			continue
+		}
+		s.stamp(&event)
+		for _, notification := range event.batch {
+			buffer.push(ctx, sessionEvent{session: s, notification: notification})
+		}
+		if event.batch != nil {
+			continue
+		}
+		buffer.push(ctx, event)
+		if event.err != nil {
+			return
+		}
+	}
+}
+
+// stamp records where and when the transaction notifications of the event were read, so
+// that the time spent in the read buffer counts in their age.
+func (s *session) stamp(event *sessionEvent) {
+	if event.notification == nil && event.batch == nil {
+		return
+	}
+	now := s.clock.Now()
+	if n := event.notification; n != nil {
+		n.endpoint, n.receivedAt = s.endpoint, now
+	}
+	for _, n := range event.batch {
+		n.endpoint, n.receivedAt = s.endpoint, now
+	}
+}
+
+// decodeMessage decodes a notification into event, recording the decoding time.
+func (s *session) decodeMessage(data []byte, event *sessionEvent, latency *latencies) error {
+	start := time.Now()
+	defer latency.since(StageDecode, start)
+	return s.decode(data, event)
+}
+
+// acknowledged passes the message to release if it is the response to the unsubscribe
+// request.
+func (s *session) acknowledged(data []byte) bool {
+	ack := s.ack.Load()
+	if ack == nil {
+		return false
+	}
+	var response JSONRPCResponse
+	if err := json.Unmarshal(data, &response); err != nil || response.ID != unsubscribeID {
+		return false
+	}
+	select {
+	case *ack <- response:
+	default:
+	}
+	return true
+}
+
+// release unsubscribes from the server, see unsubscribe, and closes the connection. It is
+// used when the subscription ends rather than when the connection is replaced.
+func (s *session) release(reason string) {
+	_ = s.unsubscribe()
+	s.close(reason)
+}
+
+// unsubscribeTimeout returns ConnConfig.UnsubscribeTimeout, zero when unsubscribing is
+// disabled.
+func (c *C) unsubscribeTimeout() time.Duration {
+	switch timeout := c.config.Conn.UnsubscribeTimeout; {
+	case timeout < 0:
+		return 0
+	case timeout == 0:
+		return time.Duration(DefaultUnsubscribeTimeout)
+	default:
+		return time.Duration(timeout)
+	}
+}
+
+// unsubscribe sends the unsubscribe request of the subscription, e.g. transactionsUnsubscribe,
+// and waits up to ConnConfig.UnsubscribeTimeout for the server to acknowledge it, so that
+// the server releases the subscription at once instead of when it notices the closed
+// connection.
+func (s *session) unsubscribe() error {
+	if s.subscription == nil || s.unsubscribeTimeout <= 0 {
+		return nil
+	}
+	ack := make(chan JSONRPCResponse, 1)
+	s.ack.Store(&ack)
+	defer s.ack.Store(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.unsubscribeTimeout)
+	defer cancel()
+	method := unsubscribeMethod(s.method)
+	request := &JSONRPCRequest{JSONRPC: "2.0", ID: unsubscribeID, Method: method, Params: []interface{}{s.subscription}}
+	if err := wsjson.Write(ctx, s.conn, request); err != nil {
+		return fmt.Errorf("cannot send %s: %w", method, err)
+	}
+	select {
+	case response := <-ack:
+		if response.Error != nil {
+			return fmt.Errorf("%s error %d: %s", method, response.Error.Code, response.Error.Message)
+		}
+		return nil
+	case <-s.readDone:
+		return fmt.Errorf("connection closed before %s was acknowledged", method)
+	case <-ctx.Done():
+		return fmt.Errorf("%s was not acknowledged: %w", method, ctx.Err())
+	}
+}
+
+// close stops reading and closes the connection.
+func (s *session) close(reason string) {
+	s.cancel()
+	_ = s.conn.Close(websocket.StatusNormalClosure, reason)
+}
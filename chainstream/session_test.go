@@ -0,0 +1,31 @@
+package chainstream_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestUnsubscribeOnCancel(t *testing.T) {
+	srv := newMuxServer(t)
+	client := chainstream.NewClient(chainstream.NewConfig(srv.Endpoint()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := client.HandleTransactionsNotifications(ctx, chainstream.WatchWallets(wallet), func(n *chainstream.TransactionNotification) error {
+		if n.Slot() == 103 {
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+
+	// The subscription is released before the call returns.
+	if unsubscribed := srv.Unsubscribed(); len(unsubscribed) != 1 || unsubscribed[0] != 1 {
+		t.Errorf("server released %v, expected subscription 1", unsubscribed)
+	}
+}
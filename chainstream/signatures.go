@@ -0,0 +1,374 @@
+package chainstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrBlockhashExpired is returned by WaitForConfirmation when the blockhash of the
+// transaction expired before it reached the commitment, so it can no longer land.
+var ErrBlockhashExpired = errors.New("chainstream: blockhash expired")
+
+// DefaultBlockhashLifetime bounds WaitForConfirmation without WithBlockhashExpiry: a
+// blockhash is valid for 150 blocks, about a minute, and the margin covers slow slots.
+const DefaultBlockhashLifetime = 90 * time.Second
+
+// blockhashCheckInterval is the time between checks of a BlockhashValidity.
+const blockhashCheckInterval = 2 * time.Second
+
+// receivedSignature is the value of the notification of enableReceivedNotification.
+const receivedSignature = "receivedSignature"
+
+// SignatureSubscribeParams are the params of signatureSubscribe, a method of the standard
+// Solana WebSocket API rather than of ChainStream. They are sent as the positional params
+// [signature, {commitment, enableReceivedNotification}].
+type SignatureSubscribeParams struct {
+	Signature  string
+	Commitment Commitment
+	// ReceivedNotification asks for a notification when the node receives the transaction,
+	// before the one of its status at the commitment.
+	ReceivedNotification bool
+}
+
+type signatureSubscribeOptions struct {
+	Commitment           Commitment `json:"commitment"`
+	ReceivedNotification bool       `json:"enableReceivedNotification,omitempty"`
+}
+
+// MarshalJSON encodes the params as positional params, with DefaultCommitment when the
+// commitment is unset.
+func (p SignatureSubscribeParams) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{p.Signature, signatureSubscribeOptions{Commitment: p.Commitment.orDefault(), ReceivedNotification: p.ReceivedNotification}})
+}
+
+// UnmarshalJSON decodes positional params.
+func (p *SignatureSubscribeParams) UnmarshalJSON(data []byte) error {
+	var params []json.RawMessage
+	if err := json.Unmarshal(data, &params); err != nil {
+		return err
+	}
+	if len(params) == 0 || len(params) > 2 {
+		return fmt.Errorf("signatureSubscribe takes 1 or 2 params, got %d", len(params))
+	}
+	var decoded SignatureSubscribeParams
+	if err := json.Unmarshal(params[0], &decoded.Signature); err != nil {
+		return fmt.Errorf("signature: %w", err)
+	}
+	if len(params) == 2 {
+		var options signatureSubscribeOptions
+		if err := json.Unmarshal(params[1], &options); err != nil {
+			return fmt.Errorf("options: %w", err)
+		}
+		decoded.Commitment, decoded.ReceivedNotification = options.Commitment, options.ReceivedNotification
+	}
+	*p = decoded
+	return nil
+}
+
+// Validate checks the params before they are sent: the signature must be set and the
+// commitment known when set.
+func (p *SignatureSubscribeParams) Validate() error {
+	var errs []error
+	if p.Signature == "" {
+		errs = append(errs, errors.New("signature is required"))
+	}
+	if p.Commitment != 0 {
+		if err := p.Commitment.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewSignatureSubscribeRequest returns a signatureSubscribe request. An unset commitment
+// uses DefaultCommitment. It returns an error if the params are invalid, see
+// SignatureSubscribeParams.Validate.
+func NewSignatureSubscribeRequest(params SignatureSubscribeParams) (*JSONRPCRequest, error) {
+	params.Commitment = params.Commitment.orDefault()
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	return newRequest("signatureSubscribe", params), nil
+}
+
+// SignatureNotification is the status of a transaction, sent by signatureSubscribe.
+type SignatureNotification struct {
+	JSONRPC string                      `json:"jsonrpc"`
+	Method  string                      `json:"method"`
+	Params  SignatureNotificationParams `json:"params"`
+}
+
+// Slot returns the slot of the notification.
+func (s *SignatureNotification) Slot() uint64 {
+	return s.Params.Result.Context.Slot
+}
+
+// Received reports whether the notification only tells that the node received the
+// transaction, see SignatureSubscribeParams.ReceivedNotification.
+func (s *SignatureNotification) Received() bool {
+	return s.Params.Result.Value.Received
+}
+
+// Succeeded reports whether the transaction reached the commitment without an error.
+func (s *SignatureNotification) Succeeded() bool {
+	return !s.Received() && (len(s.Params.Result.Value.Err) == 0 || string(s.Params.Result.Value.Err) == "null")
+}
+
+// SignatureNotificationParams contains subscription ID and payload.
+type SignatureNotificationParams struct {
+	Subscription int64                     `json:"subscription"`
+	Result       SignatureNotificationData `json:"result"`
+}
+
+// SignatureNotificationData holds the context and the status.
+type SignatureNotificationData struct {
+	Context ContextMetadata `json:"context"`
+	Value   SignatureValue  `json:"value"`
+}
+
+// SignatureValue is the status of a transaction: {"err": ...} once it reached the
+// commitment, or "receivedSignature" when the node received it.
+type SignatureValue struct {
+	Received bool
+	// Err is the transaction error, null when it succeeded.
+	Err json.RawMessage
+}
+
+type signatureErr struct {
+	Err json.RawMessage `json:"err"`
+}
+
+// MarshalJSON encodes the value like the node.
+func (v SignatureValue) MarshalJSON() ([]byte, error) {
+	if v.Received {
+		return json.Marshal(receivedSignature)
+	}
+	err := v.Err
+	if len(err) == 0 {
+		err = json.RawMessage("null")
+	}
+	return json.Marshal(signatureErr{Err: err})
+}
+
+// UnmarshalJSON decodes "receivedSignature" and {"err": ...} values.
+func (v *SignatureValue) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		if s != receivedSignature {
+			return fmt.Errorf("unknown signature value %q", s)
+		}
+		*v = SignatureValue{Received: true}
+		return nil
+	}
+	var status signatureErr
+	if err := json.Unmarshal(data, &status); err != nil {
+		return err
+	}
+	*v = SignatureValue{Err: status.Err}
+	return nil
+}
+
+// key tells notifications apart: the node sends at most one of each kind.
+func (s *SignatureNotification) key() string {
+	return strconv.FormatBool(s.Received())
+}
+
+// decodeSignature is the session decoder of signatureSubscribe subscriptions.
+func decodeSignature(data []byte, event *sessionEvent) error {
+	var signature SignatureNotification
+	if err := json.Unmarshal(data, &signature); err != nil {
+		return fmt.Errorf("failed to decode signature notification: %w", err)
+	}
+	event.signature = &signature
+	return nil
+}
+
+// SignatureLookup returns the status of a transaction, with the commitment it reached, or
+// nil if the node does not know it, e.g. rpc.Client.LookupSignature.
+type SignatureLookup func(ctx context.Context, signature string) (*SignatureStatus, error)
+
+// WithSignatureLookup makes SignatureNotifications and WaitForConfirmation look the status
+// of the transaction up with lookup every time the subscription is established, after a
+// reconnect as well. signatureSubscribe only reports transactions reaching the commitment
+// after it, so the status of a transaction that reached it before, or while the
+// subscription was reconnecting, is taken from lookup instead. Errors of lookup are
+// ignored: the subscription still reports the status.
+func WithSignatureLookup(lookup SignatureLookup) SubscribeOption {
+	return func(o *subscribeOptions) { o.signatureLookup = lookup }
+}
+
+// signatureFound ends a signature subscription with the status found by its
+// SignatureLookup.
+type signatureFound struct {
+	notification *SignatureNotification
+}
+
+func (*signatureFound) Error() string { return "chainstream: signature status found" }
+
+// SignatureNotifications subscribes to the status of a transaction with
+// signatureSubscribe, see NewSignatureSubscribeRequest. It is a method of the standard
+// Solana WebSocket API, so the endpoints of the client must be Solana RPC WebSocket
+// endpoints rather than ChainStream ones. The node ends the subscription once the
+// transaction reached the commitment, so SignatureNotifications returns after passing
+// that status to do. With WithSignatureLookup, a status found at the commitment when
+// subscribing is passed to do instead. Panics in do are recovered, see Hooks.Panic.
+func (c *C) SignatureNotifications(
+	ctx context.Context,
+	request *JSONRPCRequest,
+	do func(signature *SignatureNotification),
+	opts ...SubscribeOption,
+) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if lookup := newSubscribeOptions(opts).signatureLookup; lookup != nil {
+		params, err := signatureParams(request)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, func(o *subscribeOptions) {
+			o.connected = func(ctx context.Context) error {
+				if status, err := lookup(ctx, params.Signature); err == nil && status != nil && status.Commitment >= params.Commitment.orDefault() {
+					cancel(&signatureFound{status.notification()})
+				}
+				return nil
+			}
+		})
+	}
+	delivered := false
+	err := c.notifications(ctx, request, "signature", opts, func(event sessionEvent) (uint64, string) {
+		return event.signature.Slot(), event.signature.key()
+	}, func(event sessionEvent) error {
+		if !event.signature.Received() {
+			delivered = true
+			defer cancel(nil)
+		}
+		return c.dispatch(event.signature.Slot(), func() { do(event.signature) })
+	})
+	var found *signatureFound
+	if err != nil || delivered || !errors.As(context.Cause(ctx), &found) {
+		return err
+	}
+	if err = c.dispatch(found.notification.Slot(), func() { do(found.notification) }); !c.panicked(err) {
+		return err
+	}
+	return nil
+}
+
+// signatureParams returns the params of a signatureSubscribe request.
+func signatureParams(request *JSONRPCRequest) (SignatureSubscribeParams, error) {
+	var params SignatureSubscribeParams
+	if p, ok := request.Params.(SignatureSubscribeParams); ok {
+		return p, nil
+	}
+	data, err := json.Marshal(request.Params)
+	if err == nil {
+		err = json.Unmarshal(data, &params)
+	}
+	if err != nil {
+		return params, fmt.Errorf("invalid signatureSubscribe params: %w", err)
+	}
+	return params, nil
+}
+
+// BlockhashValidity reports whether the blockhash of a transaction is still valid, e.g.
+// by comparing the block height with the last valid block height returned with the
+// blockhash, see rpc.Client.BlockhashValidity.
+type BlockhashValidity func(ctx context.Context) (bool, error)
+
+// WithBlockhashExpiry makes WaitForConfirmation check the blockhash with valid every 2s,
+// instead of waiting DefaultBlockhashLifetime. Errors of valid are taken as a valid
+// blockhash, so that a failing RPC node does not end the wait early.
+func WithBlockhashExpiry(valid BlockhashValidity) SubscribeOption {
+	return func(o *subscribeOptions) { o.blockhashValid = valid }
+}
+
+// SignatureStatus is the status of a transaction at a commitment.
+type SignatureStatus struct {
+	Signature  string
+	Slot       uint64
+	Commitment Commitment
+	// Err is the transaction error, null or empty when it succeeded.
+	Err json.RawMessage
+}
+
+// Succeeded reports whether the transaction was executed without an error.
+func (s *SignatureStatus) Succeeded() bool {
+	return len(s.Err) == 0 || string(s.Err) == "null"
+}
+
+// notification returns the signature notification of the status.
+func (s *SignatureStatus) notification() *SignatureNotification {
+	var notification SignatureNotification
+	notification.JSONRPC = "2.0"
+	notification.Method = "signatureNotification"
+	notification.Params.Result.Context.Slot = s.Slot
+	notification.Params.Result.Value.Err = s.Err
+	return &notification
+}
+
+// WaitForConfirmation blocks until the transaction with the signature reaches the
+// commitment, and returns its status, which holds the error of a failed transaction. It
+// returns ErrBlockhashExpired when the blockhash of the transaction expires first, see
+// WithBlockhashExpiry, and the error of the context when it is done first. An unset
+// commitment uses DefaultCommitment. A transaction may reach the commitment before it is
+// subscribed to: WithSignatureLookup returns its status at once.
+func (c *C) WaitForConfirmation(ctx context.Context, signature string, commitment Commitment, opts ...SubscribeOption) (*SignatureStatus, error) {
+	commitment = commitment.orDefault()
+	request, err := NewSignatureSubscribeRequest(SignatureSubscribeParams{Signature: signature, Commitment: commitment})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go c.expire(ctx, newSubscribeOptions(opts).blockhashValid, cancel)
+
+	var status *SignatureStatus
+	err = c.SignatureNotifications(ctx, request, func(notification *SignatureNotification) {
+		status = &SignatureStatus{
+			Signature:  signature,
+			Slot:       notification.Slot(),
+			Commitment: commitment,
+			Err:        notification.Params.Result.Value.Err,
+		}
+	}, opts...)
+	switch {
+	case err != nil:
+		return nil, err
+	case status != nil:
+		return status, nil
+	case context.Cause(ctx) != nil:
+		return nil, context.Cause(ctx)
+	}
+	return nil, errors.New("chainstream: signature subscription ended without a status")
+}
+
+// expire cancels the context with ErrBlockhashExpired once valid reports the blockhash
+// expired, or after DefaultBlockhashLifetime without valid.
+func (c *C) expire(ctx context.Context, valid BlockhashValidity, cancel context.CancelCauseFunc) {
+	clk := c.clock()
+	if valid == nil {
+		select {
+		case <-clk.After(DefaultBlockhashLifetime):
+			cancel(ErrBlockhashExpired)
+		case <-ctx.Done():
+		}
+		return
+	}
+	ticker := clk.NewTicker(blockhashCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			if ok, err := valid(ctx); err == nil && !ok {
+				cancel(ErrBlockhashExpired)
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package chainstream_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+const signature = "5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnbJLgp8uirBgmQpjKhoR4tjF3ZpRzrFmBV6UjKdiSZkQUW"
+
+// signatureNotification is a signatureNotification of the standard Solana WebSocket API.
+func signatureNotification(slot uint64, value string) string {
+	return `{"jsonrpc":"2.0","method":"signatureNotification","params":{"result":{"context":{"slot":` +
+		jsonNumber(slot) + `},"value":` + value + `},"subscription":1001}}`
+}
+
+func TestWaitForConfirmation(t *testing.T) {
+	srv := newFakeServer(t, func(ctx context.Context, conn *websocket.Conn, _ int) error {
+		for _, message := range []string{
+			signatureNotification(41, `"receivedSignature"`),
+			signatureNotification(42, `{"err":{"InstructionError":[0,{"Custom":1}]}}`),
+		} {
+			if err := conn.Write(ctx, websocket.MessageText, []byte(message)); err != nil {
+				return err
+			}
+		}
+		<-ctx.Done()
+		return nil
+	})
+	client := chainstream.NewClient(chainstream.NewConfig(srv.endpoint()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, err := client.WaitForConfirmation(ctx, signature, chainstream.CommitmentFinalized)
+	if err != nil {
+		t.Fatalf("WaitForConfirmation() error: %v", err)
+	}
+	if status.Slot != 42 || status.Commitment != chainstream.CommitmentFinalized || status.Succeeded() {
+		t.Errorf("status = %+v, expected the failed transaction at slot 42", status)
+	}
+
+	sent := srv.request(1)
+	expected := []interface{}{signature, map[string]interface{}{"commitment": "finalized"}}
+	if sent.Method != "signatureSubscribe" || !reflect.DeepEqual(sent.Params, expected) {
+		t.Errorf("subscribed with %s %v, expected signatureSubscribe %v", sent.Method, sent.Params, expected)
+	}
+}
+
+func TestWaitForConfirmationExpired(t *testing.T) {
+	srv := newFakeServer(t, func(ctx context.Context, _ *websocket.Conn, _ int) error {
+		<-ctx.Done()
+		return nil
+	})
+	fake := clock.NewFake(time.Now())
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Clock = fake
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() {
+		for ctx.Err() == nil {
+			fake.Advance(time.Second)
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	checks := 0
+	_, err := client.WaitForConfirmation(ctx, signature, chainstream.CommitmentConfirmed, chainstream.WithBlockhashExpiry(func(context.Context) (bool, error) {
+		checks++
+		return checks < 3, nil
+	}))
+	if !errors.Is(err, chainstream.ErrBlockhashExpired) {
+		t.Errorf("WaitForConfirmation() error = %v, expected ErrBlockhashExpired", err)
+	}
+	if checks != 3 {
+		t.Errorf("checked the blockhash %d times, expected 3", checks)
+	}
+}
+
+func TestWaitForConfirmationLookup(t *testing.T) {
+	// The transaction reached the commitment before the subscription: the node never
+	// notifies it.
+	srv := newFakeServer(t, func(ctx context.Context, _ *websocket.Conn, _ int) error {
+		<-ctx.Done()
+		return nil
+	})
+	client := chainstream.NewClient(chainstream.NewConfig(srv.endpoint()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	lookup := func(_ context.Context, sig string) (*chainstream.SignatureStatus, error) {
+		return &chainstream.SignatureStatus{Signature: sig, Slot: 42, Commitment: chainstream.CommitmentFinalized, Err: json.RawMessage(`{"InstructionError":[0,{"Custom":1}]}`)}, nil
+	}
+	status, err := client.WaitForConfirmation(ctx, signature, chainstream.CommitmentConfirmed, chainstream.WithSignatureLookup(lookup))
+	if err != nil {
+		t.Fatalf("WaitForConfirmation() error: %v", err)
+	}
+	if status.Slot != 42 || status.Commitment != chainstream.CommitmentConfirmed || status.Succeeded() {
+		t.Errorf("status = %+v, expected the failed transaction at slot 42", status)
+	}
+}
+
+func TestWaitForConfirmationLookupAfterReconnect(t *testing.T) {
+	// The first connection fails, and the transaction is confirmed before the second one.
+	srv := newFakeServer(t, func(ctx context.Context, _ *websocket.Conn, n int) error {
+		if n == 1 {
+			return nil
+		}
+		<-ctx.Done()
+		return nil
+	})
+	fake := clock.NewFake(time.Now())
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Clock = fake
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() {
+		for ctx.Err() == nil {
+			fake.Advance(100 * time.Millisecond)
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	var lookups atomic.Int32
+	lookup := func(_ context.Context, sig string) (*chainstream.SignatureStatus, error) {
+		if lookups.Add(1) == 1 {
+			return nil, nil
+		}
+		return &chainstream.SignatureStatus{Signature: sig, Slot: 43, Commitment: chainstream.CommitmentConfirmed}, nil
+	}
+	status, err := client.WaitForConfirmation(ctx, signature, chainstream.CommitmentConfirmed, chainstream.WithSignatureLookup(lookup))
+	if err != nil {
+		t.Fatalf("WaitForConfirmation() error: %v", err)
+	}
+	if status.Slot != 43 || !status.Succeeded() || srv.connections.Load() != 2 {
+		t.Errorf("status = %+v after %d connections, expected the status looked up after reconnecting", status, srv.connections.Load())
+	}
+}
+
+func TestSignatureValue(t *testing.T) {
+	for _, value := range []string{`"receivedSignature"`, `{"err":null}`, `{"err":{"InstructionError":[0,{"Custom":1}]}}`} {
+		var decoded chainstream.SignatureValue
+		if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+			t.Fatalf("Unmarshal(%s) error: %v", value, err)
+		}
+		if encoded, err := json.Marshal(decoded); err != nil || string(encoded) != value {
+			t.Errorf("Marshal() = %s, %v, expected %s", encoded, err, value)
+		}
+	}
+	var decoded chainstream.SignatureValue
+	if err := json.Unmarshal([]byte(`"unknown"`), &decoded); err == nil {
+		t.Error("Unmarshal() of an unknown value succeeded")
+	}
+	if _, err := chainstream.NewSignatureSubscribeRequest(chainstream.SignatureSubscribeParams{}); err == nil {
+		t.Error("NewSignatureSubscribeRequest() without a signature succeeded")
+	}
+}
@@ -0,0 +1,115 @@
+package chainstream
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultSlotDuration is the nominal slot duration, used by SlotClock until it has
+// measured the actual one.
+const DefaultSlotDuration = 400 * time.Millisecond
+
+const (
+	// slotSampleSpan is the smallest number of slots between two block times measuring the
+	// slot duration, as block times only have a resolution of a second.
+	slotSampleSpan = 25
+	// slotDurationWeight is the weight of a new measurement in the moving average.
+	slotDurationWeight = 0.2
+	// Measurements outside these bounds are discarded as clock errors of the producers.
+	minSlotDuration = 100 * time.Millisecond
+	maxSlotDuration = 2 * time.Second
+)
+
+// SlotTime is the time of a transaction. Estimated tells that the notification carried no
+// block time, e.g. at processed commitment, and Time was estimated from its slot.
+type SlotTime struct {
+	Time      time.Time `json:"time"`
+	Estimated bool      `json:"estimated"`
+}
+
+// SlotClock estimates the time of slots without a block time. It learns from the block
+// times of the transactions and blocks it observes, ideally finalized ones: the latest
+// block time anchors the estimates and the slot duration is a moving average measured
+// between block times, so the estimates follow the drift of the cluster from the nominal
+// slot duration. It is safe for concurrent use.
+type SlotClock struct {
+	mu sync.Mutex
+	// anchorSlot is the highest observed slot with a block time, zero before any.
+	anchorSlot uint64
+	anchorTime time.Time
+	// sampleSlot and sampleTime start the current measurement of the slot duration.
+	sampleSlot   uint64
+	sampleTime   time.Time
+	slotDuration time.Duration
+}
+
+// NewSlotClock returns a slot clock assuming DefaultSlotDuration until it has measured the
+// slot duration.
+func NewSlotClock() *SlotClock {
+	return &SlotClock{slotDuration: DefaultSlotDuration}
+}
+
+// Observe learns from the block time of the notification, if it has one.
+func (c *SlotClock) Observe(notification *TransactionNotification) {
+	if blockTime := notification.Params.Result.Value.BlockTime; blockTime != nil {
+		c.ObserveBlockTime(notification.Slot(), time.Unix(*blockTime, 0))
+	}
+}
+
+// ObserveBlock learns from the block time of the block, if it has one.
+func (c *SlotClock) ObserveBlock(block *BlockNotification) {
+	if blockTime := block.Params.Result.Value.BlockTime; blockTime != nil {
+		c.ObserveBlockTime(block.Slot(), time.Unix(*blockTime, 0))
+	}
+}
+
+// ObserveBlockTime learns that the slot was produced at the given time.
+func (c *SlotClock) ObserveBlockTime(slot uint64, blockTime time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if slot > c.anchorSlot {
+		c.anchorSlot, c.anchorTime = slot, blockTime
+	}
+	switch {
+	case c.sampleSlot == 0 || slot < c.sampleSlot:
+		c.sampleSlot, c.sampleTime = slot, blockTime
+	case slot-c.sampleSlot >= slotSampleSpan:
+		measured := blockTime.Sub(c.sampleTime) / time.Duration(slot-c.sampleSlot)
+		if measured >= minSlotDuration && measured <= maxSlotDuration {
+			c.slotDuration += time.Duration(slotDurationWeight * float64(measured-c.slotDuration))
+		}
+		c.sampleSlot, c.sampleTime = slot, blockTime
+	}
+}
+
+// SlotDuration returns the current estimate of the slot duration.
+func (c *SlotClock) SlotDuration() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.slotDuration
+}
+
+// Estimate returns the estimated time of the slot. The boolean result is false until a
+// block time has been observed.
+func (c *SlotClock) Estimate(slot uint64) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.anchorSlot == 0 {
+		return time.Time{}, false
+	}
+	return c.anchorTime.Add(time.Duration(int64(slot)-int64(c.anchorSlot)) * c.slotDuration), true
+}
+
+// Time returns the block time of the notification, or else the time estimated from its
+// slot, flagged as Estimated. The boolean result is false when the notification has no
+// block time and no block time has been observed yet.
+func (c *SlotClock) Time(notification *TransactionNotification) (SlotTime, bool) {
+	if blockTime := notification.Params.Result.Value.BlockTime; blockTime != nil {
+		return SlotTime{Time: time.Unix(*blockTime, 0)}, true
+	}
+	estimate, ok := c.Estimate(notification.Slot())
+	if !ok {
+		return SlotTime{}, false
+	}
+	return SlotTime{Time: estimate, Estimated: true}, true
+}
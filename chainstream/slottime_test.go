@@ -0,0 +1,42 @@
+package chainstream_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestSlotClock(t *testing.T) {
+	clock := chainstream.NewSlotClock()
+	if _, ok := clock.Estimate(1000); ok {
+		t.Fatal("Estimate() succeeded before any block time was observed")
+	}
+
+	// Slots of 450ms, with block times truncated to seconds.
+	start := time.Unix(1_700_000_000, 0)
+	for slot := uint64(1000); slot <= 3000; slot += 20 {
+		blockTime := start.Add(time.Duration(slot-1000) * 450 * time.Millisecond).Truncate(time.Second)
+		clock.ObserveBlockTime(slot, blockTime)
+	}
+	if d := clock.SlotDuration(); d < 440*time.Millisecond || d > 460*time.Millisecond {
+		t.Errorf("SlotDuration() = %v, expected about 450ms", d)
+	}
+
+	processed := notification(3100)
+	got, ok := clock.Time(processed)
+	if !ok || !got.Estimated {
+		t.Fatalf("Time() = %+v, %v, expected an estimated time", got, ok)
+	}
+	expected := start.Add(2100 * 450 * time.Millisecond)
+	if diff := got.Time.Sub(expected); diff < -2*time.Second || diff > 2*time.Second {
+		t.Errorf("estimated %v for slot 3100, expected about %v", got.Time, expected)
+	}
+
+	confirmed := notification(3100)
+	blockTime := expected.Unix()
+	confirmed.Params.Result.Value.BlockTime = &blockTime
+	if got, ok := clock.Time(confirmed); !ok || got.Estimated || got.Time.Unix() != blockTime {
+		t.Errorf("Time() = %+v, %v, expected the block time", got, ok)
+	}
+}
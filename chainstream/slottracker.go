@@ -0,0 +1,148 @@
+package chainstream
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+// SlotTracker tracks the highest slot reached by every commitment level, and the slots
+// known to be skipped. A slot reaching a level reaches the faster ones as well, e.g. a
+// finalized slot is confirmed. It is safe for concurrent use.
+type SlotTracker struct {
+	// Clock drives Poll. Nil uses the system clock.
+	Clock clock.Clock
+
+	mu      sync.Mutex
+	slots   [CommitmentFinalized + 1]uint64
+	skipped map[uint64]struct{}
+	// changed is closed and replaced whenever the tracker changes.
+	changed chan struct{}
+}
+
+// NewSlotTracker returns a tracker without any slot.
+func NewSlotTracker() *SlotTracker {
+	return &SlotTracker{skipped: make(map[uint64]struct{}), changed: make(chan struct{})}
+}
+
+// Observe records that slot reached the commitment level.
+func (t *SlotTracker) Observe(slot uint64, level Commitment) {
+	if level.Validate() != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	advanced := false
+	for l := CommitmentProcessed; l <= level; l++ {
+		if slot > t.slots[l] {
+			t.slots[l] = slot
+			advanced = true
+		}
+	}
+	if level == CommitmentFinalized {
+		// Skipped slots below the finalized one are settled.
+		for s := range t.skipped {
+			if s <= slot {
+				delete(t.skipped, s)
+			}
+		}
+	}
+	if advanced {
+		t.notify()
+	}
+}
+
+// ObserveNotification records the slot of the notification at its slot status, if it has
+// a known one.
+func (t *SlotTracker) ObserveNotification(notification *TransactionNotification) {
+	if level, err := ParseCommitment(notification.Params.Result.Context.SlotStatus); err == nil {
+		t.Observe(notification.Slot(), level)
+	}
+}
+
+// Skip records that slot was skipped: its transactions never reach a safer level.
+func (t *SlotTracker) Skip(slot uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if slot <= t.slots[CommitmentFinalized] {
+		return
+	}
+	t.skipped[slot] = struct{}{}
+	t.notify()
+}
+
+// Slot returns the highest slot that reached the level, zero before any.
+func (t *SlotTracker) Slot(level Commitment) uint64 {
+	if level.Validate() != nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.slots[level]
+}
+
+// Reached reports whether slot has reached the level: it is not skipped and a slot at or
+// above it has reached the level.
+func (t *SlotTracker) Reached(slot uint64, level Commitment) bool {
+	if level.Validate() != nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, skipped := t.skipped[slot]
+	return !skipped && slot <= t.slots[level]
+}
+
+// Skipped reports whether slot was recorded as skipped by Skip.
+func (t *SlotTracker) Skipped(slot uint64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, skipped := t.skipped[slot]
+	return skipped
+}
+
+// Changed returns a channel closed on the next change of the tracker.
+func (t *SlotTracker) Changed() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.changed
+}
+
+// notify wakes up the waiters of Changed. It must be called with mu held.
+func (t *SlotTracker) notify() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+// SlotSource returns the highest slot at a commitment level, e.g. rpc.Client.GetSlot.
+type SlotSource func(ctx context.Context, commitment string) (uint64, error)
+
+// Poll observes the confirmed and finalized slots of source every interval until ctx is
+// done. Failed calls are passed to onError when it is not nil and retried on the next tick.
+func (t *SlotTracker) Poll(ctx context.Context, source SlotSource, interval time.Duration, onError func(error)) {
+	clk := t.Clock
+	if clk == nil {
+		clk = clock.System()
+	}
+	ticker := clk.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, level := range []Commitment{CommitmentFinalized, CommitmentConfirmed} {
+			slot, err := source(ctx, level.String())
+			if err != nil {
+				if ctx.Err() == nil && onError != nil {
+					onError(err)
+				}
+				continue
+			}
+			t.Observe(slot, level)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package chainstream_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+func TestSlotTracker(t *testing.T) {
+	tracker := chainstream.NewSlotTracker()
+	changed := tracker.Changed()
+	tracker.Observe(10, chainstream.CommitmentConfirmed)
+	select {
+	case <-changed:
+	default:
+		t.Error("Observe() did not signal Changed")
+	}
+	tracker.Observe(8, chainstream.CommitmentFinalized)
+	tracker.Observe(9, chainstream.CommitmentConfirmed)
+
+	for level, expected := range map[chainstream.Commitment]uint64{
+		chainstream.CommitmentProcessed: 10,
+		chainstream.CommitmentConfirmed: 10,
+		chainstream.CommitmentFinalized: 8,
+	} {
+		if slot := tracker.Slot(level); slot != expected {
+			t.Errorf("Slot(%s) = %d, expected %d", level, slot, expected)
+		}
+	}
+
+	tracker.Skip(7)
+	tracker.Skip(9)
+	if tracker.Skipped(7) {
+		t.Error("Skip() recorded a finalized slot")
+	}
+	if !tracker.Skipped(9) || tracker.Reached(9, chainstream.CommitmentConfirmed) {
+		t.Error("a skipped slot reached confirmed")
+	}
+	if !tracker.Reached(10, chainstream.CommitmentConfirmed) || tracker.Reached(10, chainstream.CommitmentFinalized) {
+		t.Error("Reached() does not follow the observed slots")
+	}
+	tracker.Observe(10, chainstream.CommitmentFinalized)
+	if tracker.Skipped(9) {
+		t.Error("a finalized slot kept a skipped slot below it")
+	}
+}
+
+func TestSlotTrackerPoll(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	tracker := chainstream.NewSlotTracker()
+	tracker.Clock = fake
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	calls := 0
+	source := func(_ context.Context, commitment string) (uint64, error) {
+		calls++
+		if calls == 1 {
+			return 0, errors.New("unavailable")
+		}
+		if commitment == "finalized" {
+			return uint64(calls), nil
+		}
+		return uint64(calls) + 30, nil
+	}
+	var failures []error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tracker.Poll(ctx, source, time.Second, func(err error) { failures = append(failures, err) })
+	}()
+
+	fake.BlockUntil(1)
+	fake.Advance(time.Second)
+	for tracker.Slot(chainstream.CommitmentConfirmed) != 34 {
+		select {
+		case <-tracker.Changed():
+		case <-ctx.Done():
+			t.Fatal("Poll() did not observe the second tick")
+		}
+	}
+	cancel()
+	<-done
+	if len(failures) != 1 {
+		t.Errorf("Poll() reported %v, expected the failed call", failures)
+	}
+	if slot := tracker.Slot(chainstream.CommitmentFinalized); slot != 3 {
+		t.Errorf("finalized slot %d, expected 3", slot)
+	}
+}
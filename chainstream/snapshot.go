@@ -0,0 +1,233 @@
+package chainstream
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Snapshot is a read-only view of the client state for operational debugging.
+type Snapshot struct {
+	Time          time.Time           `json:"time"`
+	Subscriptions []SubscriptionState `json:"subscriptions"`
+	Endpoints     []EndpointScore     `json:"endpoints"`
+	// Race is the delivery race between the endpoints in redundant mode.
+	Race  []EndpointRace `json:"race,omitempty"`
+	Stats Stats          `json:"stats"`
+}
+
+// SubscriptionState describes a running subscription.
+type SubscriptionState struct {
+	// ID numbers the subscriptions of the client in the order they were started.
+	ID     int64       `json:"id"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params"`
+	Since  time.Time   `json:"since"`
+	// Endpoint is the endpoint of the current session, without credentials.
+	Endpoint string `json:"endpoint"`
+	// Sessions is the number of open sessions, two while a rotation overlaps.
+	Sessions int    `json:"sessions"`
+	LastSlot uint64 `json:"lastSlot"`
+	// Downgraded tells that Params were replaced by Pipeline.AccountFilter.
+	Downgraded bool `json:"downgraded"`
+	// Busy tells that the handler is running.
+	Busy bool `json:"busy"`
+	// Workers is the number of handlers that may run concurrently, see PipelineConfig.Workers.
+	Workers int `json:"workers"`
+	// Queued is the number of notifications waiting for a worker.
+	Queued int `json:"queued"`
+	// Utilization is the share of worker time since Since spent in the handler.
+	Utilization float64 `json:"utilization"`
+	// DedupEntries is the number of recent signatures kept to drop duplicates.
+	DedupEntries int `json:"dedupEntries"`
+}
+
+// subscriptionState holds the live state behind SubscriptionState. It is updated by the
+// goroutine running the subscription and read by Snapshot.
+type subscriptionState struct {
+	id     int64
+	method string
+	since  time.Time
+
+	mu         sync.Mutex
+	params     interface{}
+	endpoint   string
+	sessions   int
+	lastSlot   uint64
+	downgraded bool
+	// active is the number of running handlers and activeSince the sum of the times
+	// they started at, in Unix nanoseconds.
+	active       int
+	activeSince  int64
+	busy         time.Duration
+	dedupEntries int
+	workers      int
+	queued       func() int
+}
+
+// track registers a running subscription; the returned state is passed to untrack when
+// the subscription ends.
+func (c *C) track(request *JSONRPCRequest) *subscriptionState {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	if c.subs == nil {
+		c.subs = make(map[*subscriptionState]struct{})
+	}
+	c.nextSubID++
+	s := &subscriptionState{
+		id:     c.nextSubID,
+		method: request.Method,
+		since:  time.Now(),
+		params: request.Params,
+	}
+	c.subs[s] = struct{}{}
+	return s
+}
+
+func (c *C) untrack(s *subscriptionState) {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	delete(c.subs, s)
+}
+
+// sessionsChanged records the open sessions of the subscription.
+func (s *subscriptionState) sessionsChanged(current, previous *session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.endpoint = current.endpoint.url
+	s.lastSlot = current.lastSlot
+	s.sessions = 1
+	if previous != nil {
+		s.sessions++
+	}
+}
+
+// downgrade records the request replacing the original one.
+func (s *subscriptionState) downgrade(request *JSONRPCRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.params = request.Params
+	s.downgraded = true
+}
+
+// filterUpdated records params replaced by Subscription.UpdateFilter.
+func (s *subscriptionState) filterUpdated(params interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.params = params
+}
+
+// useWorkers records the worker pool of the subscription.
+func (s *subscriptionState) useWorkers(p *workerPool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.workers = p.workers
+	s.queued = p.queued
+}
+
+// admitted records a notification of the slot passed to the handler.
+func (s *subscriptionState) admitted(slot uint64, dedupEntries int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if slot > s.lastSlot {
+		s.lastSlot = slot
+	}
+	s.dedupEntries = dedupEntries
+}
+
+// handling marks a handler as running and returns the function marking it done.
+func (s *subscriptionState) handling() (done func()) {
+	start := time.Now()
+	s.mu.Lock()
+	s.active++
+	s.activeSince += start.UnixNano()
+	s.mu.Unlock()
+	return func() {
+		s.mu.Lock()
+		s.active--
+		s.activeSince -= start.UnixNano()
+		s.busy += time.Since(start)
+		s.mu.Unlock()
+	}
+}
+
+func (s *subscriptionState) snapshot(now time.Time) SubscriptionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	busy := s.busy + time.Duration(int64(s.active)*now.UnixNano()-s.activeSince)
+	workers := s.workers
+	if workers < 1 {
+		workers = 1
+	}
+	state := SubscriptionState{
+		ID:           s.id,
+		Method:       s.method,
+		Params:       s.params,
+		Since:        s.since,
+		Endpoint:     redactURL(s.endpoint),
+		Sessions:     s.sessions,
+		LastSlot:     s.lastSlot,
+		Downgraded:   s.downgraded,
+		Busy:         s.active > 0,
+		Workers:      workers,
+		DedupEntries: s.dedupEntries,
+	}
+	if s.queued != nil {
+		state.Queued = s.queued()
+	}
+	if elapsed := now.Sub(s.since); elapsed > 0 {
+		state.Utilization = float64(busy) / float64(elapsed) / float64(workers)
+	}
+	return state
+}
+
+// Snapshot returns the state of the running subscriptions together with the endpoint
+// scores and counters. Endpoint URLs are stripped of credentials, so the snapshot can be
+// served to operators.
+func (c *C) Snapshot() Snapshot {
+	now := time.Now()
+	snapshot := Snapshot{
+		Time:      now,
+		Endpoints: c.Endpoints(),
+		Stats:     c.Stats(),
+	}
+	for i := range snapshot.Endpoints {
+		snapshot.Endpoints[i].URL = redactURL(snapshot.Endpoints[i].URL)
+	}
+	snapshot.Race = c.EndpointRace()
+	for i := range snapshot.Race {
+		snapshot.Race[i].URL = redactURL(snapshot.Race[i].URL)
+	}
+
+	c.subsMu.Lock()
+	for s := range c.subs {
+		snapshot.Subscriptions = append(snapshot.Subscriptions, s.snapshot(now))
+	}
+	c.subsMu.Unlock()
+	sort.Slice(snapshot.Subscriptions, func(i, j int) bool {
+		return snapshot.Subscriptions[i].ID < snapshot.Subscriptions[j].ID
+	})
+	return snapshot
+}
+
+// redactURL removes user info and the query from an endpoint URL and masks the path
+// segment following "api-key", where Syndica endpoints carry the token.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	u.RawQuery = ""
+	segments := strings.Split(u.Path, "/")
+	for i := 1; i < len(segments); i++ {
+		if segments[i-1] == "api-key" && segments[i] != "" {
+			segments[i] = "xxx"
+		}
+	}
+	u.Path = strings.Join(segments, "/")
+	u.RawPath = ""
+	return u.String()
+}
@@ -0,0 +1,59 @@
+package chainstream_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestSnapshot(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(3, 5))
+	client := chainstream.NewClient(chainstream.NewConfig(srv.endpoint() + "/api-key/secret"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var snapshot chainstream.Snapshot
+	err := client.HandleTransactionsNotifications(ctx, chainstream.WatchWallets(wallet), func(n *chainstream.TransactionNotification) error {
+		if n.Slot() == 5 {
+			snapshot = client.Snapshot()
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+
+	if len(snapshot.Subscriptions) != 1 {
+		t.Fatalf("Subscriptions = %+v, expected one", snapshot.Subscriptions)
+	}
+	sub := snapshot.Subscriptions[0]
+	if sub.Method != "transactionsSubscribe" || !sub.Busy || sub.Sessions != 1 || sub.LastSlot != 5 || sub.DedupEntries != 2 {
+		t.Errorf("subscription = %+v", sub)
+	}
+	if sub.Utilization <= 0 || sub.Utilization > 1 {
+		t.Errorf("Utilization = %v", sub.Utilization)
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("snapshot leaks the endpoint token: %s", data)
+	}
+	if !strings.Contains(string(data), `"oneOf":["`+wallet+`"]`) {
+		t.Errorf("snapshot misses the subscription filter: %s", data)
+	}
+
+	if subs := client.Snapshot().Subscriptions; len(subs) != 0 {
+		t.Errorf("Subscriptions after return = %+v", subs)
+	}
+	if stats := client.Snapshot().Stats; stats.Received != 2 {
+		t.Errorf("Stats.Received = %d", stats.Received)
+	}
+}
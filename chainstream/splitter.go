@@ -0,0 +1,288 @@
+package chainstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// DefaultMaxPending is the default number of notifications a CommitmentSplitter holds
+// while they wait for a safer commitment level.
+const DefaultMaxPending = 100000
+
+// ErrDropped is passed to CommitmentSplitter.OnError, with the level they waited for, for
+// the notifications dropped beyond MaxPending, the oldest first.
+var ErrDropped = errors.New("chainstream: pending notification dropped")
+
+// ErrAbandoned is passed to CommitmentSplitter.OnError for the notifications whose
+// transaction was not included by the cluster: their slot is finalized or skipped and their
+// signature is unknown.
+var ErrAbandoned = errors.New("chainstream: transaction abandoned with its fork")
+
+// maxStatusSignatures is the most signatures getSignatureStatuses takes in one call.
+const maxStatusSignatures = 256
+
+// SignatureStatusFunc returns the commitment level reached by each of the signatures, zero
+// for those not found, e.g. rpc.Client.SignatureCommitments.
+type SignatureStatusFunc func(ctx context.Context, signatures []string) ([]Commitment, error)
+
+// CommitmentSplitter delivers the transactions of a processed subscription to handler sets
+// of the processed, confirmed and finalized stages, so speculative logic and durable
+// indexing share one subscription. Its Handle method is the handler of the subscription:
+// it runs the processed handlers at once and holds the notification until the Tracker
+// reports its slot confirmed, then finalized. Run then checks that the cluster included
+// the transaction at that level with Statuses, and passes a copy with the matching slot
+// status to the handlers of that stage.
+//
+// A slot reaching a level does not tell that the fork of the transaction did: a
+// transaction whose signature is still unknown once its slot is finalized, or whose slot
+// was recorded with SlotTracker.Skip, was abandoned with its fork. It is dropped and
+// passed to OnError with ErrAbandoned.
+type CommitmentSplitter struct {
+	// Tracker reports the slots reaching every level, e.g. fed by SlotTracker.Poll.
+	Tracker *SlotTracker
+	// Statuses reports the levels reached by the transactions.
+	Statuses SignatureStatusFunc
+	// MaxPending bounds the notifications waiting for a level, DefaultMaxPending if zero.
+	MaxPending int
+	// OnError, when set, receives the errors of the confirmed and finalized handlers,
+	// ErrDropped and ErrAbandoned, and with a nil notification the errors of Statuses,
+	// retried on the next change of the tracker. Errors of the processed handlers are
+	// returned by Handle instead.
+	OnError func(level Commitment, notification *TransactionNotification, err error)
+
+	handlers [CommitmentFinalized + 1][]HandlerFunc
+
+	mu sync.Mutex
+	// pending holds the notifications waiting for the confirmed and finalized levels, in
+	// the order they were handled.
+	pending [CommitmentFinalized + 1][]*TransactionNotification
+	// queued holds the notifications handled already, so retries of Handle do not queue
+	// them twice.
+	queued map[*TransactionNotification]struct{}
+	// wake tells Run that notifications were queued.
+	wake chan struct{}
+}
+
+// NewCommitmentSplitter returns a splitter following the tracker and checking the
+// transactions with statuses.
+func NewCommitmentSplitter(tracker *SlotTracker, statuses SignatureStatusFunc) *CommitmentSplitter {
+	return &CommitmentSplitter{
+		Tracker:  tracker,
+		Statuses: statuses,
+		queued:   make(map[*TransactionNotification]struct{}),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// On adds handlers of the stage of the level. Handlers must be added before the
+// subscription starts.
+func (s *CommitmentSplitter) On(level Commitment, handlers ...HandlerFunc) *CommitmentSplitter {
+	if level.Validate() == nil {
+		s.handlers[level] = append(s.handlers[level], handlers...)
+	}
+	return s
+}
+
+// Handle runs the processed handlers and queues the notification for the later stages,
+// even if they fail. It returns the first error of the processed handlers, which the
+// subscription may retry: the notification is queued once.
+func (s *CommitmentSplitter) Handle(notification *TransactionNotification) error {
+	s.enqueue(notification)
+	for _, do := range s.handlers[CommitmentProcessed] {
+		if err := do(notification); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueue queues the notification for the first later stage with handlers.
+func (s *CommitmentSplitter) enqueue(notification *TransactionNotification) {
+	level := s.next(CommitmentProcessed)
+	if level == 0 {
+		return
+	}
+	s.mu.Lock()
+	if _, ok := s.queued[notification]; ok {
+		s.mu.Unlock()
+		return
+	}
+	s.queued[notification] = struct{}{}
+	s.pending[level] = append(s.pending[level], notification)
+	drops := s.trim()
+	s.mu.Unlock()
+
+	s.report(drops)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the first level safer than level with handlers, zero if none.
+func (s *CommitmentSplitter) next(level Commitment) Commitment {
+	for l := level + 1; l <= CommitmentFinalized; l++ {
+		if len(s.handlers[l]) > 0 {
+			return l
+		}
+	}
+	return 0
+}
+
+// dropped is a notification dropped while waiting for a level.
+type dropped struct {
+	level        Commitment
+	notification *TransactionNotification
+}
+
+// trim drops the oldest pending notifications beyond MaxPending and returns them. It must
+// be called with mu held.
+func (s *CommitmentSplitter) trim() []dropped {
+	limit := s.MaxPending
+	if limit <= 0 {
+		limit = DefaultMaxPending
+	}
+	var drops []dropped
+	// The oldest notifications wait for finalization.
+	for level := CommitmentFinalized; level > CommitmentProcessed; level-- {
+		for len(s.pending[CommitmentConfirmed])+len(s.pending[CommitmentFinalized]) > limit && len(s.pending[level]) > 0 {
+			notification := s.pending[level][0]
+			drops = append(drops, dropped{level, notification})
+			delete(s.queued, notification)
+			s.pending[level] = s.pending[level][1:]
+		}
+	}
+	return drops
+}
+
+// report passes the dropped notifications to OnError.
+func (s *CommitmentSplitter) report(drops []dropped) {
+	if s.OnError == nil {
+		return
+	}
+	for _, d := range drops {
+		s.OnError(d.level, d.notification, ErrDropped)
+	}
+}
+
+// Pending returns the number of notifications waiting for a level.
+func (s *CommitmentSplitter) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending[CommitmentConfirmed]) + len(s.pending[CommitmentFinalized])
+}
+
+// Run passes the queued notifications to the handlers of the confirmed and finalized
+// stages as their transactions reach these levels, until ctx is done. Handlers of a stage
+// are called in the order of the notifications, from the goroutine of Run.
+func (s *CommitmentSplitter) Run(ctx context.Context) {
+	for {
+		changed := s.Tracker.Changed()
+		for level := CommitmentConfirmed; level <= CommitmentFinalized; level++ {
+			s.release(ctx, level)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		case <-s.wake:
+		}
+	}
+}
+
+// release passes the notifications whose transaction reached the level to its handlers,
+// and queues them for the next stage.
+func (s *CommitmentSplitter) release(ctx context.Context, level Commitment) {
+	// Only the transactions of the slots at the level may have reached it.
+	s.mu.Lock()
+	var candidates []*TransactionNotification
+	for _, notification := range s.pending[level] {
+		if slot := notification.Slot(); s.Tracker.Skipped(slot) || s.Tracker.Reached(slot, level) {
+			candidates = append(candidates, notification)
+		}
+	}
+	s.mu.Unlock()
+	if len(candidates) == 0 {
+		return
+	}
+
+	statuses, err := s.statuses(ctx, candidates)
+	if err != nil {
+		if ctx.Err() == nil && s.OnError != nil {
+			s.OnError(level, nil, err)
+		}
+		return
+	}
+	var due, abandoned []*TransactionNotification
+	settled := make(map[*TransactionNotification]struct{}, len(candidates))
+	for i, notification := range candidates {
+		slot := notification.Slot()
+		switch {
+		case statuses[i] >= level:
+			due = append(due, notification)
+		case statuses[i] == 0 && (s.Tracker.Skipped(slot) || s.Tracker.Reached(slot, CommitmentFinalized)):
+			abandoned = append(abandoned, notification)
+		default:
+			// Not there yet, or not known by the node yet.
+			continue
+		}
+		settled[notification] = struct{}{}
+	}
+
+	next := s.next(level)
+	s.mu.Lock()
+	s.pending[level] = slices.DeleteFunc(s.pending[level], func(notification *TransactionNotification) bool {
+		_, ok := settled[notification]
+		return ok
+	})
+	for _, notification := range abandoned {
+		delete(s.queued, notification)
+	}
+	for _, notification := range due {
+		if next == 0 {
+			delete(s.queued, notification)
+		} else {
+			s.pending[next] = append(s.pending[next], notification)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, notification := range abandoned {
+		if s.OnError != nil {
+			s.OnError(level, notification, ErrAbandoned)
+		}
+	}
+	for _, notification := range due {
+		staged := *notification
+		staged.Params.Result.Context.SlotStatus = level.String()
+		for _, do := range s.handlers[level] {
+			if err := do(&staged); err != nil && s.OnError != nil {
+				s.OnError(level, notification, err)
+			}
+		}
+	}
+}
+
+// statuses returns the levels reached by the transactions of the notifications, asking
+// Statuses for as many signatures at a time as getSignatureStatuses takes.
+func (s *CommitmentSplitter) statuses(ctx context.Context, notifications []*TransactionNotification) ([]Commitment, error) {
+	levels := make([]Commitment, 0, len(notifications))
+	for chunk := range slices.Chunk(notifications, maxStatusSignatures) {
+		signatures := make([]string, len(chunk))
+		for i, notification := range chunk {
+			signatures[i] = notification.Signature()
+		}
+		found, err := s.Statuses(ctx, signatures)
+		if err != nil {
+			return nil, err
+		}
+		if len(found) != len(signatures) {
+			return nil, fmt.Errorf("chainstream: %d statuses for %d signatures", len(found), len(signatures))
+		}
+		levels = append(levels, found...)
+	}
+	return levels, nil
+}
@@ -0,0 +1,189 @@
+package chainstream_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+// stage records the notifications a handler set received with their slot status.
+type stage struct {
+	mu       sync.Mutex
+	received []string
+	notify   chan struct{}
+}
+
+func newStage() *stage {
+	return &stage{notify: make(chan struct{}, 16)}
+}
+
+func (s *stage) handle(n *chainstream.TransactionNotification) error {
+	s.mu.Lock()
+	s.received = append(s.received, n.Signature()+"@"+n.Params.Result.Context.SlotStatus)
+	s.mu.Unlock()
+	s.notify <- struct{}{}
+	return nil
+}
+
+// wait waits for count notifications and returns all those received.
+func (s *stage) wait(t *testing.T, count int) []string {
+	t.Helper()
+	for range count {
+		select {
+		case <-s.notify:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a notification")
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.received...)
+}
+
+// cluster is a fake of the signature statuses of a cluster.
+type cluster struct {
+	mu     sync.Mutex
+	levels map[string]chainstream.Commitment
+}
+
+func (c *cluster) set(level chainstream.Commitment, signatures ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.levels == nil {
+		c.levels = make(map[string]chainstream.Commitment)
+	}
+	for _, signature := range signatures {
+		c.levels[signature] = level
+	}
+}
+
+func (c *cluster) statuses(_ context.Context, signatures []string) ([]chainstream.Commitment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	levels := make([]chainstream.Commitment, len(signatures))
+	for i, signature := range signatures {
+		levels[i] = c.levels[signature]
+	}
+	return levels, nil
+}
+
+func TestCommitmentSplitter(t *testing.T) {
+	tracker := chainstream.NewSlotTracker()
+	processed, confirmed, finalized := newStage(), newStage(), newStage()
+	var chain cluster
+	splitter := chainstream.NewCommitmentSplitter(tracker, chain.statuses).
+		On(chainstream.CommitmentProcessed, processed.handle).
+		On(chainstream.CommitmentConfirmed, confirmed.handle).
+		On(chainstream.CommitmentFinalized, finalized.handle)
+	var (
+		mu        sync.Mutex
+		abandoned []string
+	)
+	splitter.OnError = func(level chainstream.Commitment, n *chainstream.TransactionNotification, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !errors.Is(err, chainstream.ErrAbandoned) {
+			t.Errorf("OnError(%s, %v), expected an abandoned transaction", level, err)
+			return
+		}
+		abandoned = append(abandoned, n.Signature()+"@"+level.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go splitter.Run(ctx)
+
+	for _, slot := range []uint64{10, 11, 12, 13} {
+		n := notification(slot)
+		n.Params.Result.Context.SlotStatus = "processed"
+		if err := splitter.Handle(n); err != nil {
+			t.Fatalf("Handle() error: %v", err)
+		}
+	}
+	if received, expected := processed.wait(t, 4), []string{"sig-10@processed", "sig-11@processed", "sig-12@processed", "sig-13@processed"}; !reflect.DeepEqual(received, expected) {
+		t.Errorf("processed stage received %v, expected %v", received, expected)
+	}
+
+	// Slot 11 was skipped, and slot 13 of the subscription belongs to a fork the cluster
+	// abandoned while confirming the same slot.
+	chain.set(chainstream.CommitmentConfirmed, "sig-10", "sig-12")
+	tracker.Skip(11)
+	tracker.Observe(13, chainstream.CommitmentConfirmed)
+	if received, expected := confirmed.wait(t, 2), []string{"sig-10@confirmed", "sig-12@confirmed"}; !reflect.DeepEqual(received, expected) {
+		t.Errorf("confirmed stage received %v, expected %v", received, expected)
+	}
+
+	chain.set(chainstream.CommitmentFinalized, "sig-10")
+	tracker.Observe(13, chainstream.CommitmentFinalized)
+	if received, expected := finalized.wait(t, 1), []string{"sig-10@finalized"}; !reflect.DeepEqual(received, expected) {
+		t.Errorf("finalized stage received %v, expected %v", received, expected)
+	}
+	mu.Lock()
+	if expected := []string{"sig-11@confirmed", "sig-13@confirmed"}; !reflect.DeepEqual(abandoned, expected) {
+		t.Errorf("abandoned %v, expected %v", abandoned, expected)
+	}
+	mu.Unlock()
+	if pending := splitter.Pending(); pending != 1 {
+		t.Errorf("Pending() = %d, expected slot 12 waiting for its finalized status", pending)
+	}
+}
+
+func TestCommitmentSplitterRetry(t *testing.T) {
+	tracker := chainstream.NewSlotTracker()
+	finalized := newStage()
+	failure := errors.New("transient")
+	attempts := 0
+	var chain cluster
+	chain.set(chainstream.CommitmentFinalized, "sig-5")
+	splitter := chainstream.NewCommitmentSplitter(tracker, chain.statuses).
+		On(chainstream.CommitmentProcessed, func(*chainstream.TransactionNotification) error {
+			if attempts++; attempts == 1 {
+				return failure
+			}
+			return nil
+		}).
+		On(chainstream.CommitmentFinalized, finalized.handle)
+
+	n := notification(5)
+	if err := splitter.Handle(n); !errors.Is(err, failure) {
+		t.Fatalf("Handle() error %v, expected the handler error", err)
+	}
+	if err := splitter.Handle(n); err != nil {
+		t.Fatalf("Handle() error on retry: %v", err)
+	}
+	if pending := splitter.Pending(); pending != 1 {
+		t.Fatalf("Pending() = %d, expected the notification queued once", pending)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go splitter.Run(ctx)
+	tracker.Observe(5, chainstream.CommitmentFinalized)
+	if received, expected := finalized.wait(t, 1), []string{"sig-5@finalized"}; !reflect.DeepEqual(received, expected) {
+		t.Errorf("finalized stage received %v, expected %v", received, expected)
+	}
+}
+
+func TestCommitmentSplitterMaxPending(t *testing.T) {
+	splitter := chainstream.NewCommitmentSplitter(chainstream.NewSlotTracker(), nil).
+		On(chainstream.CommitmentConfirmed, func(*chainstream.TransactionNotification) error { return nil })
+	splitter.MaxPending = 2
+	var dropped []uint64
+	splitter.OnError = func(level chainstream.Commitment, n *chainstream.TransactionNotification, err error) {
+		if level != chainstream.CommitmentConfirmed || !errors.Is(err, chainstream.ErrDropped) {
+			t.Errorf("OnError(%s, %v), expected a dropped confirmed notification", level, err)
+		}
+		dropped = append(dropped, n.Slot())
+	}
+	for _, slot := range []uint64{1, 2, 3} {
+		splitter.Handle(notification(slot))
+	}
+	if !reflect.DeepEqual(dropped, []uint64{1}) || splitter.Pending() != 2 {
+		t.Errorf("dropped %v with %d pending, expected the oldest one", dropped, splitter.Pending())
+	}
+}
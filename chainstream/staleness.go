@@ -0,0 +1,66 @@
+package chainstream
+
+import (
+	"fmt"
+	"time"
+)
+
+// AgeSource selects which timestamp is used to compute the age of a notification.
+type AgeSource int
+
+const (
+	// AgeFromNodeTime uses the time the node observed the transaction.
+	AgeFromNodeTime AgeSource = iota
+	// AgeFromBlockTime uses the block time, falling back to node time when it is not set yet.
+	AgeFromBlockTime
+)
+
+// MarshalText implements encoding.TextMarshaler.
+func (s AgeSource) MarshalText() ([]byte, error) {
+	switch s {
+	case AgeFromNodeTime:
+		return []byte("nodeTime"), nil
+	case AgeFromBlockTime:
+		return []byte("blockTime"), nil
+	}
+	return nil, fmt.Errorf("unknown age source %d", s)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *AgeSource) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "", "nodeTime":
+		*s = AgeFromNodeTime
+	case "blockTime":
+		*s = AgeFromBlockTime
+	default:
+		return fmt.Errorf("unknown age source %q", text)
+	}
+	return nil
+}
+
+// Time returns the timestamp of the notification according to the given source.
+// The boolean result is false when the notification carries no usable timestamp.
+func (t *TransactionNotification) Time(source AgeSource) (time.Time, bool) {
+	if source == AgeFromBlockTime && t.Params.Result.Value.BlockTime != nil {
+		return time.Unix(*t.Params.Result.Value.BlockTime, 0), true
+	}
+	nodeTime := t.Params.Result.Context.NodeTime
+	if nodeTime.IsZero() {
+		return time.Time{}, false
+	}
+	return nodeTime, true
+}
+
+// IsStale reports whether the notification is older than maxAge at the given moment.
+// Notifications without a timestamp are never considered stale.
+func (t *TransactionNotification) IsStale(now time.Time, maxAge time.Duration, source AgeSource) bool {
+	if maxAge <= 0 {
+		return false
+	}
+	ts, ok := t.Time(source)
+	if !ok {
+		return false
+	}
+	return now.Sub(ts) > maxAge
+}
@@ -0,0 +1,48 @@
+package chainstream_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestIsStale(t *testing.T) {
+	tx := loadNotification(t, "testdata/sample_tx_buy.json")
+	nodeTime := tx.Params.Result.Context.NodeTime
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		maxAge time.Duration
+		source chainstream.AgeSource
+		stale  bool
+	}{
+		{"Disabled", nodeTime.Add(time.Hour), 0, chainstream.AgeFromNodeTime, false},
+		{"Fresh", nodeTime.Add(100 * time.Millisecond), 500 * time.Millisecond, chainstream.AgeFromNodeTime, false},
+		{"Stale", nodeTime.Add(time.Second), 500 * time.Millisecond, chainstream.AgeFromNodeTime, true},
+		{"BlockTime Fallback", nodeTime.Add(time.Second), 500 * time.Millisecond, chainstream.AgeFromBlockTime, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tx.IsStale(tc.now, tc.maxAge, tc.source); got != tc.stale {
+				t.Errorf("IsStale() = %v, expected %v", got, tc.stale)
+			}
+		})
+	}
+}
+
+func TestIsStaleBlockTime(t *testing.T) {
+	tx := loadNotification(t, "testdata/sample_tx_buy.json")
+	blockTime := tx.Params.Result.Context.NodeTime.Add(-10 * time.Second).Unix()
+	tx.Params.Result.Value.BlockTime = &blockTime
+
+	now := tx.Params.Result.Context.NodeTime
+	if !tx.IsStale(now, 5*time.Second, chainstream.AgeFromBlockTime) {
+		t.Error("expected notification to be stale by block time")
+	}
+	if tx.IsStale(now, 5*time.Second, chainstream.AgeFromNodeTime) {
+		t.Error("expected notification to be fresh by node time")
+	}
+}
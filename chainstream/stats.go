@@ -0,0 +1,122 @@
+package chainstream
+
+import "sync/atomic"
+
+// Stats is a snapshot of client counters accumulated over all subscriptions.
+type Stats struct {
+	// Received is the number of notifications read from the server.
+	Received uint64 `json:"received"`
+	// Delivered is the number of notifications handled successfully.
+	Delivered uint64 `json:"delivered"`
+	// Dropped is the number of notifications dropped from a full read buffer, see
+	// PipelineConfig.Backpressure, or from the full queue of a SubscriptionManager
+	// subscription, or left unhandled when their subscription ended, see
+	// PipelineConfig.Workers and BatchConfig.FlushTimeout.
+	Dropped uint64 `json:"dropped"`
+	// Duplicates is the number of notifications dropped as already delivered.
+	Duplicates uint64 `json:"duplicates"`
+	// MirrorCopies is the number of notifications dropped as delivered already by another
+	// session in redundant mode, see ConnConfig.Redundant. They are not Duplicates.
+	MirrorCopies uint64 `json:"mirrorCopies"`
+	// Stale is the number of notifications dropped as older than MaxNotificationAge, and
+	// of account states of AccountState dropped as superseded.
+	Stale uint64 `json:"stale"`
+	// Filtered is the number of notifications dropped by Pipeline.SkipFailed,
+	// Pipeline.AccountFilter and Hooks.Filter.
+	Filtered uint64 `json:"filtered"`
+	// HandlerErrors is the number of errors returned by handlers, including retried ones.
+	HandlerErrors uint64 `json:"handlerErrors"`
+	// Retries is the number of handler retries.
+	Retries uint64 `json:"retries"`
+	// Panics is the number of panics recovered from handlers.
+	Panics uint64 `json:"panics"`
+	// Failed is the number of notifications whose handling failed after all retries.
+	Failed uint64 `json:"failed"`
+	// DeadLettered is the number of failed notifications passed to the dead letter hook.
+	DeadLettered uint64 `json:"deadLettered"`
+	// Reconnects is the number of connections replaced after a failure.
+	Reconnects uint64 `json:"reconnects"`
+	// Rotations is the number of planned connection rotations.
+	Rotations uint64 `json:"rotations"`
+	// Pings is the number of pings answered with a pong, see C.RTT for their round-trip times.
+	Pings uint64 `json:"pings"`
+	// PingFailures is the number of pings left unanswered in time, see ConnConfig.MaxPingFailures.
+	PingFailures uint64 `json:"pingFailures"`
+	// IdleTimeouts is the number of connections replaced for receiving nothing within
+	// ConnConfig.IdleTimeout.
+	IdleTimeouts uint64 `json:"idleTimeouts"`
+	// Downgrades is the number of firehose subscriptions replaced with filtered ones.
+	Downgrades uint64 `json:"downgrades"`
+	// Failbacks is the number of sessions moved back to a preferred endpoint that recovered.
+	Failbacks uint64 `json:"failbacks"`
+	// Gaps is the number of reconnects whose missed slots were passed to Hooks.Backfill.
+	Gaps uint64 `json:"gaps"`
+	// Backfilled is the number of notifications delivered by Hooks.Backfill, before dropping
+	// duplicates.
+	Backfilled uint64 `json:"backfilled"`
+	// BackfillFailures is the number of gaps Hooks.Backfill could not load completely.
+	BackfillFailures uint64 `json:"backfillFailures"`
+	// Rewound is the number of notifications replayed from an archive by WithRewind,
+	// before dropping duplicates.
+	Rewound uint64 `json:"rewound"`
+}
+
+// stats holds the live counters behind Stats.
+type stats struct {
+	received      atomic.Uint64
+	delivered     atomic.Uint64
+	dropped       atomic.Uint64
+	duplicates    atomic.Uint64
+	mirrorCopies  atomic.Uint64
+	stale         atomic.Uint64
+	filtered      atomic.Uint64
+	handlerErrors atomic.Uint64
+	retries       atomic.Uint64
+	failed        atomic.Uint64
+	panics        atomic.Uint64
+	deadLettered  atomic.Uint64
+	reconnects    atomic.Uint64
+	rotations     atomic.Uint64
+	pings         atomic.Uint64
+	pingFailures  atomic.Uint64
+	idleTimeouts  atomic.Uint64
+	downgrades    atomic.Uint64
+	failbacks     atomic.Uint64
+	gaps          atomic.Uint64
+	backfilled    atomic.Uint64
+	backfillFails atomic.Uint64
+	rewound       atomic.Uint64
+}
+
+func (s *stats) snapshot() Stats {
+	return Stats{
+		Received:         s.received.Load(),
+		Delivered:        s.delivered.Load(),
+		Dropped:          s.dropped.Load(),
+		Duplicates:       s.duplicates.Load(),
+		MirrorCopies:     s.mirrorCopies.Load(),
+		Stale:            s.stale.Load(),
+		Filtered:         s.filtered.Load(),
+		HandlerErrors:    s.handlerErrors.Load(),
+		Retries:          s.retries.Load(),
+		Failed:           s.failed.Load(),
+		Panics:           s.panics.Load(),
+		DeadLettered:     s.deadLettered.Load(),
+		Reconnects:       s.reconnects.Load(),
+		Rotations:        s.rotations.Load(),
+		Pings:            s.pings.Load(),
+		PingFailures:     s.pingFailures.Load(),
+		IdleTimeouts:     s.idleTimeouts.Load(),
+		Downgrades:       s.downgrades.Load(),
+		Failbacks:        s.failbacks.Load(),
+		Gaps:             s.gaps.Load(),
+		Backfilled:       s.backfilled.Load(),
+		BackfillFailures: s.backfillFails.Load(),
+		Rewound:          s.rewound.Load(),
+	}
+}
+
+// Stats returns a snapshot of the client counters.
+func (c *C) Stats() Stats {
+	return c.stats.snapshot()
+}
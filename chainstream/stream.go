@@ -0,0 +1,141 @@
+package chainstream
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Stream subscribes to Syndica transaction updates like TransactionsNotifications and
+// delivers them over a channel of Config.Pipeline.StreamBuffer capacity. A consumer that
+// does not keep up with the channel slows the subscription down.
+//
+// The notifications channel is closed when the subscription ends, after which the error
+// channel yields the error that ended it, if any, and is closed as well.
+func (c *C) Stream(ctx context.Context, request *JSONRPCRequest, opts ...SubscribeOption) (<-chan *TransactionNotification, <-chan error) {
+	notifications := make(chan *TransactionNotification, c.config.Pipeline.StreamBuffer)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(notifications)
+		err := c.HandleTransactionsNotifications(ctx, request, func(notification *TransactionNotification) error {
+			select {
+			case notifications <- notification:
+				return nil
+			case <-ctx.Done():
+				return Permanent(ctx.Err())
+			}
+		}, opts...)
+		if err != nil {
+			errs <- err
+		}
+	}()
+	return notifications, errs
+}
+
+// ErrSubscribed is returned by Feed.Subscribe on a feed subscribed or closed already.
+var ErrSubscribed = errors.New("chainstream: stream already subscribed")
+
+// Feed is a subscription delivering updates of type T over a channel, whatever its
+// transport: C.TransactionsFeed subscribes over WebSocket, and the geyser and replay
+// packages provide streams over Yellowstone gRPC and recordings. Application code taking
+// a Feed runs on any of them.
+type Feed[T any] interface {
+	// Subscribe starts the subscription, which runs until ctx is done, Close is called or
+	// it fails. Connection failures are reported by Err once Updates is closed.
+	Subscribe(ctx context.Context) error
+	// Updates returns the channel of the updates. It is closed when the subscription ends,
+	// or by Close on a stream never subscribed.
+	Updates() <-chan T
+	// Err returns the error that ended the subscription, nil while it runs and when it was
+	// ended by its context or Close.
+	Err() error
+	// Close ends the subscription and waits for Updates to be closed. It returns Err.
+	Close() error
+}
+
+// NewFeed adapts a subscription passing its updates to a handler, such as
+// C.HandleTransactionsNotifications, to a Feed with a channel of buffer capacity. run
+// must return once its context is done or do fails. A consumer that does not keep up with
+// the channel slows the subscription down.
+func NewFeed[T any](buffer int, run func(ctx context.Context, do func(update T) error) error) Feed[T] {
+	return &funcFeed[T]{
+		run:     run,
+		updates: make(chan T, buffer),
+		done:    make(chan struct{}),
+	}
+}
+
+// funcFeed is the Feed returned by NewFeed.
+type funcFeed[T any] struct {
+	run     func(ctx context.Context, do func(update T) error) error
+	updates chan T
+	done    chan struct{}
+
+	mu      sync.Mutex
+	started bool
+	cancel  context.CancelFunc
+	err     error
+}
+
+func (s *funcFeed[T]) Subscribe(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return ErrSubscribed
+	}
+	s.started = true
+	ctx, s.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(s.done)
+		err := s.run(ctx, func(update T) error {
+			select {
+			case s.updates <- update:
+				return nil
+			case <-ctx.Done():
+				return Permanent(ctx.Err())
+			}
+		})
+		if ctx.Err() != nil {
+			err = nil
+		}
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		close(s.updates)
+	}()
+	return nil
+}
+
+func (s *funcFeed[T]) Updates() <-chan T {
+	return s.updates
+}
+
+func (s *funcFeed[T]) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *funcFeed[T]) Close() error {
+	s.mu.Lock()
+	if !s.started {
+		s.started = true
+		close(s.updates)
+		close(s.done)
+	} else {
+		s.cancel()
+	}
+	s.mu.Unlock()
+	<-s.done
+	return s.Err()
+}
+
+// TransactionsFeed returns a Feed of the transaction updates of the request, see
+// HandleTransactionsNotifications, over a channel of Config.Pipeline.StreamBuffer
+// capacity.
+func (c *C) TransactionsFeed(request *JSONRPCRequest, opts ...SubscribeOption) Feed[*TransactionNotification] {
+	return NewFeed(c.config.Pipeline.StreamBuffer, func(ctx context.Context, do func(notification *TransactionNotification) error) error {
+		return c.HandleTransactionsNotifications(ctx, request, do, opts...)
+	})
+}
@@ -0,0 +1,116 @@
+package chainstream_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestStream(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1, 2, 3))
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Pipeline.StreamBuffer = 8
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	notifications, errs := client.Stream(ctx, chainstream.FirehoseNoVotes())
+	var slots []uint64
+	for n := range notifications {
+		slots = append(slots, n.Slot())
+		if len(slots) == 3 {
+			cancel()
+		}
+	}
+	if len(slots) != 3 || slots[0] != 1 || slots[2] != 3 {
+		t.Errorf("slots = %v, expected 1, 2, 3", slots)
+	}
+	if err, ok := <-errs; ok {
+		t.Errorf("error after cancel: %v", err)
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	srv := newFakeServer(t, sendSlots())
+	endpoint := srv.endpoint()
+	srv.Close()
+
+	notifications, errs := chainstream.NewClient(chainstream.NewConfig(endpoint)).Stream(context.Background(), chainstream.FirehoseNoVotes())
+	if _, ok := <-notifications; ok {
+		t.Error("received a notification from a closed server")
+	}
+	if err := <-errs; err == nil {
+		t.Error("expected a connection error")
+	}
+}
+
+// consume collects the slots of a stream until it has n of them, then closes it.
+func consume(t *testing.T, stream chainstream.Feed[*chainstream.TransactionNotification], n int) []uint64 {
+	t.Helper()
+	var slots []uint64
+	for notification := range stream.Updates() {
+		if slots = append(slots, notification.Slot()); len(slots) == n {
+			if err := stream.Close(); err != nil {
+				t.Errorf("Close() error: %v", err)
+			}
+		}
+	}
+	return slots
+}
+
+func TestTransactionsFeed(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1, 2, 3))
+	stream := chainstream.NewClient(chainstream.NewConfig(srv.endpoint())).TransactionsFeed(chainstream.FirehoseNoVotes())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := stream.Subscribe(ctx); err != nil {
+		t.Fatalf("Subscribe() error: %v", err)
+	}
+	if err := stream.Subscribe(ctx); !errors.Is(err, chainstream.ErrSubscribed) {
+		t.Errorf("second Subscribe() error %v, expected ErrSubscribed", err)
+	}
+	if slots := consume(t, stream, 3); !reflect.DeepEqual(slots, []uint64{1, 2, 3}) {
+		t.Errorf("slots = %v, expected 1, 2, 3", slots)
+	}
+	if err := stream.Err(); err != nil {
+		t.Errorf("Err() = %v after Close", err)
+	}
+}
+
+func TestNewFeed(t *testing.T) {
+	failure := errors.New("connection lost")
+	stream := chainstream.NewFeed(0, func(ctx context.Context, do func(update int) error) error {
+		for i := 1; i <= 2; i++ {
+			if err := do(i); err != nil {
+				return err
+			}
+		}
+		return failure
+	})
+	if err := stream.Subscribe(context.Background()); err != nil {
+		t.Fatalf("Subscribe() error: %v", err)
+	}
+	var updates []int
+	for update := range stream.Updates() {
+		updates = append(updates, update)
+	}
+	if !reflect.DeepEqual(updates, []int{1, 2}) || !errors.Is(stream.Err(), failure) || !errors.Is(stream.Close(), failure) {
+		t.Errorf("received %v ending with %v, expected 1, 2 ending with %v", updates, stream.Err(), failure)
+	}
+
+	unused := chainstream.NewFeed(0, func(ctx context.Context, do func(update int) error) error {
+		t.Error("a closed stream subscribed")
+		return nil
+	})
+	if err := unused.Close(); err != nil {
+		t.Errorf("Close() error: %v", err)
+	}
+	if _, ok := <-unused.Updates(); ok || !errors.Is(unused.Subscribe(context.Background()), chainstream.ErrSubscribed) {
+		t.Error("a closed stream can still be subscribed")
+	}
+}
@@ -0,0 +1,47 @@
+package chainstream
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SubscriptionInfo is what the server acknowledged for a subscription, to compare the
+// subscription the server runs with the one requested, e.g. when a filter matches less
+// than expected.
+type SubscriptionInfo struct {
+	// ID is the subscription ID assigned by the server, zero while the subscription waits
+	// to be subscribed again after a reconnect.
+	ID     int64       `json:"id"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params"`
+	// Filter is the effective filter echoed by the server, nil if it only returned the ID.
+	// Servers that echo the filter acknowledge with {"subscription": <id>, "filter": {...}}.
+	Filter json.RawMessage `json:"filter,omitempty"`
+	// SubscribedAt is when the server last acknowledged the subscription.
+	SubscribedAt time.Time `json:"subscribedAt"`
+	// Endpoint is the endpoint of the acknowledging connection, without credentials.
+	Endpoint string `json:"endpoint"`
+}
+
+// parseAcknowledgement returns the subscription ID and the echoed filter of the result of
+// a subscribe response: the ID alone or an object carrying it as subscription or id.
+func parseAcknowledgement(result json.RawMessage) (id int64, filter json.RawMessage, ok bool) {
+	if json.Unmarshal(result, &id) == nil {
+		return id, nil, true
+	}
+	var echo struct {
+		Subscription *int64          `json:"subscription"`
+		ID           *int64          `json:"id"`
+		Filter       json.RawMessage `json:"filter"`
+	}
+	if json.Unmarshal(result, &echo) != nil {
+		return 0, nil, false
+	}
+	switch {
+	case echo.Subscription != nil:
+		return *echo.Subscription, echo.Filter, true
+	case echo.ID != nil:
+		return *echo.ID, echo.Filter, true
+	}
+	return 0, nil, false
+}
@@ -0,0 +1,50 @@
+package chainstream
+
+import "github.com/gerasimovvladislav/zensol-go/programs"
+
+const (
+	pumpFunProgramID       = string(programs.PumpFun)
+	tokenMetadataProgramID = string(programs.TokenMetadata)
+)
+
+// newTemplateRequest returns a transactionsSubscribe request with default network,
+// default commitment and vote transactions excluded.
+func newTemplateRequest(keys *AccountKeysFilter) *JSONRPCRequest {
+	sub := SubscriptionConfig{
+		Params: TransactionSubscribeParams{
+			Filter: TransactionFilter{
+				ExcludeVotes: true,
+				AccountKeys:  keys,
+			},
+		},
+	}
+	sub.SetDefaults(0)
+	return sub.Request(1)
+}
+
+// WatchProgram returns a request for every transaction invoking or mentioning the program.
+func WatchProgram(programID string) *JSONRPCRequest {
+	return newTemplateRequest(&AccountKeysFilter{OneOf: []string{programID}})
+}
+
+// WatchWallets returns a request for every transaction mentioning at least one of the wallets.
+func WatchWallets(wallets ...string) *JSONRPCRequest {
+	return newTemplateRequest(&AccountKeysFilter{OneOf: wallets})
+}
+
+// FirehoseNoVotes returns a request for all non-vote transactions of the network.
+func FirehoseNoVotes() *JSONRPCRequest {
+	return newTemplateRequest(nil)
+}
+
+// NewTokenLaunches returns a request for pump.fun token creations: transactions that
+// invoke both the pump.fun program and the Metaplex token metadata program, which
+// regular buys and sells do not touch.
+func NewTokenLaunches() *JSONRPCRequest {
+	return newTemplateRequest(&AccountKeysFilter{All: []string{pumpFunProgramID, tokenMetadataProgramID}})
+}
+
+// AllBlocks returns a blocksSubscribe request for every block of the default network.
+func AllBlocks() *JSONRPCRequest {
+	return newRequest("blocksSubscribe", BlockSubscribeParams{Network: DefaultNetwork})
+}
@@ -0,0 +1,49 @@
+package chainstream_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestTemplates(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  *chainstream.JSONRPCRequest
+		expected string
+	}{
+		{
+			"WatchProgram",
+			chainstream.WatchProgram("6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"),
+			`{"jsonrpc":"2.0","id":1,"method":"transactionsSubscribe","params":{"network":"solana-mainnet","verified":false,"filter":{"excludeVotes":true,"commitment":"confirmed","accountKeys":{"oneOf":["6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"]}}}}`,
+		},
+		{
+			"WatchWallets",
+			chainstream.WatchWallets("53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF", "C6StTJpfK6nUcQzouAWZEvE1YLwxrgsTLsDAwHgXwQ8k"),
+			`{"jsonrpc":"2.0","id":1,"method":"transactionsSubscribe","params":{"network":"solana-mainnet","verified":false,"filter":{"excludeVotes":true,"commitment":"confirmed","accountKeys":{"oneOf":["53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF","C6StTJpfK6nUcQzouAWZEvE1YLwxrgsTLsDAwHgXwQ8k"]}}}}`,
+		},
+		{
+			"FirehoseNoVotes",
+			chainstream.FirehoseNoVotes(),
+			`{"jsonrpc":"2.0","id":1,"method":"transactionsSubscribe","params":{"network":"solana-mainnet","verified":false,"filter":{"excludeVotes":true,"commitment":"confirmed"}}}`,
+		},
+		{
+			"NewTokenLaunches",
+			chainstream.NewTokenLaunches(),
+			`{"jsonrpc":"2.0","id":1,"method":"transactionsSubscribe","params":{"network":"solana-mainnet","verified":false,"filter":{"excludeVotes":true,"commitment":"confirmed","accountKeys":{"all":["6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P","metaqbxxUerdq28cj1RbAWkYQm3ybzjb6a8bt518x1s"]}}}}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := json.Marshal(tc.request)
+			if err != nil {
+				t.Fatalf("failed to marshal request: %v", err)
+			}
+			if string(data) != tc.expected {
+				t.Errorf("request = %s\nexpected %s", data, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package chainstream
+
+import "fmt"
+
+// Mints of the stablecoins recognized as quotes by InferTrade.
+const (
+	USDCMint = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+	USDTMint = "Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB"
+)
+
+// QuoteMints are the mints InferTrade prices trades in, in order of preference. Native SOL
+// is reported as WrappedSOLMint.
+var QuoteMints = []string{WrappedSOLMint, USDCMint, USDTMint}
+
+// tokenAccountRent is the rent-exempt balance of a token account. SOL movements of up to it by
+// the trader are taken for account creation and closing rather than a trade.
+const tokenAccountRent = 2_039_280
+
+// TradeSide is the direction of a trade from the point of view of the trader.
+type TradeSide int
+
+const (
+	SideUnknown TradeSide = iota
+	SideBuy
+	SideSell
+)
+
+// MarshalText implements encoding.TextMarshaler.
+func (s TradeSide) MarshalText() ([]byte, error) {
+	switch s {
+	case SideUnknown:
+		return []byte("unknown"), nil
+	case SideBuy:
+		return []byte("buy"), nil
+	case SideSell:
+		return []byte("sell"), nil
+	}
+	return nil, fmt.Errorf("unknown trade side %d", s)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *TradeSide) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "", "unknown":
+		*s = SideUnknown
+	case "buy":
+		*s = SideBuy
+	case "sell":
+		*s = SideSell
+	default:
+		return fmt.Errorf("unknown trade side %q", text)
+	}
+	return nil
+}
+
+// Confidence tells how reliably the side of a trade was inferred.
+type Confidence int
+
+const (
+	// ConfidenceNone is reported with SideUnknown.
+	ConfidenceNone Confidence = iota
+	// ConfidenceLow is reported when no quote moved against the token, e.g. a token to
+	// token swap or a token bought with an unrecognized quote.
+	ConfidenceLow
+	// ConfidenceMedium is reported when the token moved against a quote, but other quotes
+	// moved as well.
+	ConfidenceMedium
+	// ConfidenceHigh is reported when exactly one token and one quote moved, in opposite
+	// directions.
+	ConfidenceHigh
+)
+
+// MarshalText implements encoding.TextMarshaler.
+func (c Confidence) MarshalText() ([]byte, error) {
+	switch c {
+	case ConfidenceNone:
+		return []byte("none"), nil
+	case ConfidenceLow:
+		return []byte("low"), nil
+	case ConfidenceMedium:
+		return []byte("medium"), nil
+	case ConfidenceHigh:
+		return []byte("high"), nil
+	}
+	return nil, fmt.Errorf("unknown confidence %d", c)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *Confidence) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "", "none":
+		*c = ConfidenceNone
+	case "low":
+		*c = ConfidenceLow
+	case "medium":
+		*c = ConfidenceMedium
+	case "high":
+		*c = ConfidenceHigh
+	default:
+		return fmt.Errorf("unknown confidence %q", text)
+	}
+	return nil
+}
+
+// Trade is the net effect of a transaction on the balances of its trader: Amount of Mint
+// bought or sold for QuoteAmount of QuoteMint. Amounts are in base units of their mints.
+type Trade struct {
+	Trader        string     `json:"trader"`
+	Side          TradeSide  `json:"side"`
+	Mint          string     `json:"mint,omitempty"`
+	Amount        uint64     `json:"amount,omitempty"`
+	Decimals      int        `json:"decimals,omitempty"`
+	QuoteMint     string     `json:"quoteMint,omitempty"`
+	QuoteAmount   uint64     `json:"quoteAmount,omitempty"`
+	QuoteDecimals int        `json:"quoteDecimals,omitempty"`
+	Confidence    Confidence `json:"confidence"`
+}
+
+// InferTrade infers the trade of the fee payer from its balance deltas, for swaps whose
+// direction is not explicit in their instructions and that SwapRoutes cannot pair. Native
+// and wrapped SOL are netted, the fee is not counted, and SOL movements within the rent of
+// a token account are ignored. Failed transactions and transactions that do not look like
+// a trade are reported with SideUnknown.
+func (t *TransactionNotification) InferTrade() Trade {
+	trader := t.Owner()
+	trade := Trade{Trader: trader}
+	if trader == "" || !t.Succeeded() {
+		return trade
+	}
+
+	deltas := make(map[string]int64)
+	decimals := map[string]int{WrappedSOLMint: 9}
+	var order []string
+	add := func(mint string, delta int64) {
+		if _, ok := deltas[mint]; !ok {
+			order = append(order, mint)
+		}
+		deltas[mint] += delta
+	}
+	for _, change := range t.BalanceChanges() {
+		if change.Account == trader {
+			add(WrappedSOLMint, change.Delta+int64(t.Params.Result.Value.Meta.Fee))
+		}
+	}
+	for _, change := range t.TokenBalanceChanges() {
+		if change.Owner == trader {
+			add(change.Mint, change.Delta)
+			decimals[change.Mint] = change.Decimals
+		}
+	}
+	if d := deltas[WrappedSOLMint]; d >= -tokenAccountRent && d <= tokenAccountRent {
+		deltas[WrappedSOLMint] = 0
+	}
+
+	var bases, quotes []string
+	for _, mint := range QuoteMints {
+		if deltas[mint] != 0 {
+			quotes = append(quotes, mint)
+		}
+	}
+	for _, mint := range order {
+		if deltas[mint] != 0 && !isQuoteMint(mint) {
+			bases = append(bases, mint)
+		}
+	}
+
+	set := func(base, quote string, confidence Confidence) Trade {
+		trade.Side = SideBuy
+		if deltas[base] < 0 {
+			trade.Side = SideSell
+		}
+		trade.Mint, trade.Amount, trade.Decimals = base, absAmount(deltas[base]), decimals[base]
+		if quote != "" {
+			trade.QuoteMint, trade.QuoteAmount, trade.QuoteDecimals = quote, absAmount(deltas[quote]), decimals[quote]
+		}
+		trade.Confidence = confidence
+		return trade
+	}
+	opposite := func(a, b string) bool { return (deltas[a] < 0) != (deltas[b] < 0) }
+
+	switch {
+	case len(bases) == 1:
+		for _, quote := range quotes {
+			if opposite(bases[0], quote) {
+				if len(quotes) == 1 {
+					return set(bases[0], quote, ConfidenceHigh)
+				}
+				return set(bases[0], quote, ConfidenceMedium)
+			}
+		}
+		return set(bases[0], "", ConfidenceLow)
+	case len(bases) == 0 && len(quotes) == 2 && opposite(quotes[0], quotes[1]):
+		// A swap between quotes is a trade of the less preferred one.
+		return set(quotes[1], quotes[0], ConfidenceMedium)
+	case len(bases) == 2 && len(quotes) == 0 && opposite(bases[0], bases[1]):
+		// Without a quote the token received is taken as bought with the token paid.
+		if deltas[bases[0]] > 0 {
+			return set(bases[0], bases[1], ConfidenceLow)
+		}
+		return set(bases[1], bases[0], ConfidenceLow)
+	}
+	return trade
+}
+
+func isQuoteMint(mint string) bool {
+	for _, quote := range QuoteMints {
+		if quote == mint {
+			return true
+		}
+	}
+	return false
+}
+
+func absAmount(delta int64) uint64 {
+	if delta < 0 {
+		return uint64(-delta)
+	}
+	return uint64(delta)
+}
@@ -0,0 +1,80 @@
+package chainstream_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestInferTradeSell(t *testing.T) {
+	trade := loadNotification(t, "testdata/sample_tx_sell.json").InferTrade()
+	expected := chainstream.Trade{
+		Trader:        "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF",
+		Side:          chainstream.SideSell,
+		Mint:          "DNvtizsEYyknJoW3QYwDA7ncjxri3KBBTeLydEZCpump",
+		Amount:        357547484136,
+		Decimals:      6,
+		QuoteMint:     chainstream.WrappedSOLMint,
+		QuoteAmount:   9899999,
+		QuoteDecimals: 9,
+		Confidence:    chainstream.ConfidenceHigh,
+	}
+	if trade != expected {
+		t.Errorf("InferTrade() = %+v\nexpected %+v", trade, expected)
+	}
+}
+
+// balancesTx returns a transaction of wallet changing its SOL balance by lamports and its
+// token balances by the given deltas per mint.
+func balancesTx(lamports int64, tokens map[string]int64) *chainstream.TransactionNotification {
+	var tx chainstream.TransactionNotification
+	value := &tx.Params.Result.Value
+	value.Transaction.Message.AccountKeys = []string{"wallet"}
+	value.Meta.PreBalances = []uint64{1_000_000_000}
+	value.Meta.PostBalances = []uint64{uint64(1_000_000_000 + lamports)}
+	for mint, delta := range tokens {
+		idx := len(value.Transaction.Message.AccountKeys)
+		value.Transaction.Message.AccountKeys = append(value.Transaction.Message.AccountKeys, "account-"+mint)
+		balance := func(amount int64) chainstream.TokenBalance {
+			return chainstream.TokenBalance{AccountIndex: idx, Mint: mint, Owner: "wallet", UIAmount: chainstream.TokenAmountUI{Amount: strconv.FormatInt(amount, 10), Decimals: 6}}
+		}
+		value.Meta.PreTokenBalances = append(value.Meta.PreTokenBalances, balance(1_000_000))
+		value.Meta.PostTokenBalances = append(value.Meta.PostTokenBalances, balance(1_000_000+delta))
+	}
+	return &tx
+}
+
+func TestInferTrade(t *testing.T) {
+	tests := []struct {
+		name       string
+		tx         *chainstream.TransactionNotification
+		side       chainstream.TradeSide
+		mint       string
+		quote      string
+		confidence chainstream.Confidence
+	}{
+		{"buy with USDC", balancesTx(0, map[string]int64{"A": 500, chainstream.USDCMint: -100}), chainstream.SideBuy, "A", chainstream.USDCMint, chainstream.ConfidenceHigh},
+		{"rent is not a quote", balancesTx(-2_039_280, map[string]int64{"A": 500}), chainstream.SideBuy, "A", "", chainstream.ConfidenceLow},
+		{"several quotes", balancesTx(-50_000_000, map[string]int64{"A": -500, chainstream.USDCMint: 100}), chainstream.SideSell, "A", chainstream.USDCMint, chainstream.ConfidenceMedium},
+		{"between quotes", balancesTx(-50_000_000, map[string]int64{chainstream.USDCMint: 100}), chainstream.SideBuy, chainstream.USDCMint, chainstream.WrappedSOLMint, chainstream.ConfidenceMedium},
+		{"token to token", balancesTx(0, map[string]int64{"A": -500, "B": 300}), chainstream.SideBuy, "B", "A", chainstream.ConfidenceLow},
+		{"no trade", balancesTx(-5000, nil), chainstream.SideUnknown, "", "", chainstream.ConfidenceNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trade := tt.tx.InferTrade()
+			if trade.Side != tt.side || trade.Mint != tt.mint || trade.QuoteMint != tt.quote || trade.Confidence != tt.confidence {
+				t.Errorf("InferTrade() = %+v", trade)
+			}
+		})
+	}
+}
+
+func TestInferTradeFailed(t *testing.T) {
+	tx := balancesTx(-50_000_000, map[string]int64{"A": 500})
+	tx.Params.Result.Value.Meta.Err = []byte(`{"InstructionError":[0,"Custom"]}`)
+	if trade := tx.InferTrade(); trade.Side != chainstream.SideUnknown {
+		t.Errorf("InferTrade() = %+v, expected an unknown side for a failed transaction", trade)
+	}
+}
@@ -6,11 +6,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
+	"slices"
+	"sync"
 	"time"
-
-	"nhooyr.io/websocket"
-	"nhooyr.io/websocket/wsjson"
 )
 
 // TransactionNotification represents a transaction update message.
@@ -18,6 +16,11 @@ type TransactionNotification struct {
 	JSONRPC string                        `json:"jsonrpc"`
 	Method  string                        `json:"method"`
 	Params  TransactionNotificationParams `json:"params"`
+
+	replayed bool
+	// endpoint and receivedAt tell where and when the notification was received live.
+	endpoint   *endpoint
+	receivedAt time.Time
 }
 
 // Slot returns the Solana slot in which the transaction was processed.
@@ -56,6 +59,9 @@ type TransactionValue struct {
 	Slot        uint64             `json:"slot"`
 	Transaction EncodedTransaction `json:"transaction"`
 	Meta        TransactionMeta    `json:"meta"`
+	// Version is the version of the message as getTransaction reports it, "legacy" or 0,
+	// empty when the provider does not report it.
+	Version json.RawMessage `json:"version,omitempty"`
 }
 
 // EncodedTransaction holds message and signature information.
@@ -107,6 +113,8 @@ type TransactionMeta struct {
 	PreBalances       []uint64           `json:"preBalances"`
 	PreTokenBalances  []TokenBalance     `json:"preTokenBalances"`
 	Rewards           []interface{}      `json:"rewards,omitempty"`
+	// ComputeUnitsConsumed is nil when the provider does not report it.
+	ComputeUnitsConsumed *uint64 `json:"computeUnitsConsumed,omitempty"`
 }
 
 // InnerInstruction represents an instruction executed inside another.
@@ -138,52 +146,442 @@ type TokenAmountUI struct {
 	UIAmountString string  `json:"uiAmountString"`
 }
 
+// dedupCapacity is the number of recent signatures remembered to drop duplicates
+// delivered by overlapping sessions.
+const dedupCapacity = 10000
+
 // TransactionsNotifications subscribes to Syndica transaction updates.
 func (c *C) TransactionsNotifications(
 	ctx context.Context,
 	request *JSONRPCRequest,
 	do func(notification *TransactionNotification),
+	opts ...SubscribeOption,
 ) error {
-RECONNECT:
-	wsConn, _, err := websocket.Dial(ctx, c.config.WssApiEndpoint, nil)
+	return c.HandleTransactionsNotifications(ctx, request, func(notification *TransactionNotification) error {
+		do(notification)
+		return nil
+	}, opts...)
+}
+
+// HandleTransactionsNotifications subscribes to Syndica transaction updates with a handler
+// that can fail. Failed calls are retried according to Config.Pipeline.Retry and then
+// passed to Config.Hooks.DeadLetter, or end the subscription with WithFailFast. Handler
+// panics are recovered, see Hooks.Panic. The other options bound the subscription, which
+// then ends without an error. Invalid params are rejected with ErrInvalidParams before they
+// are sent, see TransactionSubscribeParams.Validate.
+func (c *C) HandleTransactionsNotifications(
+	ctx context.Context,
+	request *JSONRPCRequest,
+	do HandlerFunc,
+	opts ...SubscribeOption,
+) (err error) {
+	options := newSubscribeOptions(opts)
+	ctx, cancel := options.bound(ctx, c)
+	defer cancel()
+	defer func() {
+		if failure := options.failure(ctx); failure != nil {
+			err = failure
+		}
+	}()
+	if options.contextDo != nil {
+		// The handler sees the subscription end, e.g. with WithMaxDuration.
+		do = c.withContext(ctx, options.contextDo)
+	}
+
+	if !options.cursor.IsZero() && c.config.Hooks.Backfill == nil {
+		return errors.New("chainstream: WithCursor requires Hooks.Backfill")
+	}
+
+	events := make(chan sessionEvent)
+	decode := c.decoderFor(request)
+	current, err := c.openSession(ctx, request, decode, events, false)
 	if err != nil {
-		return fmt.Errorf("cannot connect to chainstream transactions notifications: %w", err)
+		return err
 	}
+	connection := c.newLifecycle(request)
+	connection.connected(current)
+	// previous is the session being replaced during a rotation, kept open until the
+	// new session delivers its last slot or the overlap window ends.
+	var previous *session
+	// mirrors are the sessions on the other endpoints in redundant mode.
+	var mirrors []*session
 	defer func() {
-		_ = wsConn.Close(websocket.StatusNormalClosure, "subscription of transactions notifications was closed")
+		current.release("subscription of transactions notifications was closed")
+		if previous != nil {
+			previous.release("subscription of transactions notifications was closed")
+		}
+		for _, mirror := range mirrors {
+			mirror.release("subscription of transactions notifications was closed")
+		}
 	}()
 
-	if err = wsjson.Write(ctx, wsConn, request); err != nil {
-		return fmt.Errorf("cannot send subscribe transactions: %w", err)
+	clk := c.clock()
+	ticker := clk.NewTicker(c.pingInterval())
+	defer ticker.Stop()
+	idleTicks, stopWatchdog := c.watchdog()
+	defer stopWatchdog()
+
+	var rotate, overlapEnd <-chan time.Time
+	if rotateEvery := time.Duration(c.config.Conn.RotateEvery); rotateEvery > 0 {
+		rotateTicker := clk.NewTicker(rotateEvery)
+		defer rotateTicker.Stop()
+		rotate = rotateTicker.C()
 	}
 
-	var subResp JSONRPCResponse
-	if err = wsjson.Read(ctx, wsConn, &subResp); err != nil {
-		return fmt.Errorf("cannot read subscribe response: %w", err)
+	seen := newSignatureSet(dedupCapacity)
+	seen.namespace = c.dedupNamespace(request)
+	do = c.remember(ctx, seen.namespace, do)
+	var (
+		pressure   backpressure
+		downgraded bool
+		gaps       resume
+		handled    int
+	)
+	if cursor := options.cursor; !cursor.IsZero() {
+		// Starting after a cursor is backfilling the gap of a reconnect from its slot.
+		gaps.delivered = cursor.Slot
+		gaps.reconnected(current)
+	}
+	state := c.track(request)
+	defer c.untrack(state)
+
+	// fail ends the subscription on handler failures with WithFailFast and on panics
+	// without Hooks.Panic.
+	fail := func(err error) bool {
+		if !c.panicked(err) {
+			options.abort(&handlerFailure{err})
+			return true
+		}
+		return options.fail(err)
+	}
+	// dispatch passes an admitted notification to the handler, directly, through the
+	// worker pool or in batches.
+	handle := func(notification *TransactionNotification) {
+		if ctx.Err() != nil {
+			return
+		}
+		done := state.handling()
+		c.handle(ctx, notification, do, fail)
+		done()
 	}
-	if subResp.Result == 0 {
-		return fmt.Errorf("subscribe error: result is nil")
+	dispatch := handle
+	if options.batch != nil {
+		batchDo := c.rememberBatch(ctx, seen.namespace, options.batch)
+		batches := c.startBatcher(ctx, c.config.Pipeline.Batch, func(ctx context.Context, batch []*TransactionNotification) bool {
+			done := state.handling()
+			defer done()
+			return c.handleBatch(ctx, batch, batchDo, fail)
+		})
+		defer batches.stop()
+		dispatch = func(notification *TransactionNotification) {
+			batches.add(ctx, notification)
+		}
+	} else if workers := c.config.Pipeline.Workers; workers > 1 {
+		pool := startWorkers(ctx, workers, c.config.Pipeline.Ordering, c.config.Hooks.OrderKey, &c.stats, handle)
+		defer pool.stop()
+		state.useWorkers(pool)
+		dispatch = func(notification *TransactionNotification) {
+			pool.dispatch(ctx, notification)
+		}
+	}
+	if cursor := options.cursor; !cursor.IsZero() {
+		next := dispatch
+		dispatch = func(notification *TransactionNotification) {
+			if cursor.Covers(notification) {
+				c.stats.duplicates.Add(1)
+				return
+			}
+			next(notification)
+		}
+	}
+	if options.rewind != nil {
+		if err := c.rewind(ctx, options.rewind, request, seen, dispatch); err != nil {
+			return err
+		}
 	}
 
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
+	// rotateSession replaces the current session with a new one, keeping the current one
+	// open during the overlap. It keeps the current session when the new one cannot be
+	// opened and tries again on the next rotation.
+	rotateSession := func() bool {
+		next, err := c.openSession(ctx, request, decode, events, false)
+		if err != nil {
+			return false
+		}
+		if previous != nil {
+			previous.close("session rotated")
+		}
+		previous, current = current, next
+		overlapEnd = clk.After(time.Duration(c.config.Conn.RotationOverlap))
+		return true
+	}
+
+	// openMirrors connects, in redundant mode, to the healthy endpoints without a session.
+	openMirrors := func() {
+		if !c.config.Conn.Redundant {
+			return
+		}
+		used := []*endpoint{current.endpoint}
+		if previous != nil {
+			used = append(used, previous.endpoint)
+		}
+		for _, mirror := range mirrors {
+			used = append(used, mirror.endpoint)
+		}
+		for _, e := range c.endpointSet().others(clk.Now(), used) {
+			mirror, err := c.subscribe(ctx, e, request, decode, events)
+			if err != nil {
+				if ctx.Err() == nil {
+					c.endpointSet().observeFailure(e, clk.Now())
+				}
+				continue
+			}
+			mirror.lastReceived = clk.Now()
+			mirrors = append(mirrors, mirror)
+		}
+	}
+	openMirrors()
+
+	// Gaps are backfilled off the loop. Their notifications come back through backfilled
+	// to be admitted by the loop, and the live notifications are held meanwhile so that
+	// they follow the gap.
+	backfilled := make(chan backfillEvent)
+	backfillCtx, stopBackfills := context.WithCancel(ctx)
+	var backfills sync.WaitGroup
+	defer backfills.Wait()
+	defer stopBackfills()
+	var (
+		backfilling int
+		held        []*TransactionNotification
+	)
+	startBackfill := func(gap Gap) {
+		backfilling++
+		backfills.Add(1)
+		go func(request *JSONRPCRequest) {
+			defer backfills.Done()
+			send := func(event backfillEvent) error {
+				select {
+				case backfilled <- event:
+					return nil
+				case <-backfillCtx.Done():
+					return backfillCtx.Err()
+				}
+			}
+			c.backfill(backfillCtx, gap, request, func(notification *TransactionNotification) error {
+				return send(backfillEvent{notification: notification})
+			})
+			_ = send(backfillEvent{done: true})
+		}(request)
+	}
+	// deliver admits and dispatches a live notification, and reports whether the
+	// subscription is done.
+	deliver := func(notification *TransactionNotification) bool {
+		if !c.admit(ctx, seen, notification) {
+			return false
+		}
+		state.admitted(notification.Slot(), seen.Len())
+		dispatch(notification)
+		if slot := notification.Slot(); slot > gaps.delivered {
+			gaps.delivered = slot
+		}
+		handled++
+		return options.done(handled)
+	}
 
 	for {
+		state.sessionsChanged(current, previous)
 		select {
-		case <-ticker.C:
-			_ = wsConn.Ping(ctx)
 		case <-ctx.Done():
 			return nil
-		default:
-			var notification TransactionNotification
-			if err = wsjson.Read(ctx, wsConn, &notification); err != nil {
-				if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+		case <-idleTicks:
+			c.checkIdle(current, request.Method, clk.Now())
+			for _, mirror := range mirrors {
+				c.checkIdle(mirror, request.Method, clk.Now())
+			}
+		case <-ticker.C():
+			go c.ping(ctx, current)
+			for _, mirror := range mirrors {
+				go c.ping(ctx, mirror)
+			}
+			openMirrors()
+			// Move back to a preferred endpoint once it is healthy again. In redundant mode
+			// it is connected to anyway.
+			if previous == nil && !c.config.Conn.Redundant && c.endpointSet().failback(current.endpoint, clk.Now()) {
+				if rotateSession() {
+					c.stats.failbacks.Add(1)
+				}
+			}
+		case <-rotate:
+			if rotateSession() {
+				c.stats.rotations.Add(1)
+			}
+		case event := <-backfilled:
+			if !event.done {
+				if c.admit(ctx, seen, event.notification) {
+					dispatch(event.notification)
+				}
+				continue
+			}
+			if backfilling--; backfilling > 0 {
+				continue
+			}
+			for len(held) > 0 {
+				notification := held[0]
+				held = held[1:]
+				if deliver(notification) {
 					return nil
 				}
-				time.Sleep(time.Second)
-				goto RECONNECT
 			}
-			do(&notification)
+			held = nil
+		case <-overlapEnd:
+			if previous != nil {
+				previous.close("session rotated")
+				previous = nil
+			}
+		case event := <-events:
+			if event.err != nil {
+				if i := slices.Index(mirrors, event.session); i >= 0 {
+					event.session.close("session failed")
+					c.endpointSet().observeFailure(event.session.endpoint, clk.Now())
+					mirrors = slices.Delete(mirrors, i, i+1)
+					continue
+				}
+				switch event.session {
+				case previous:
+					previous.close("session failed")
+					previous = nil
+					continue
+				case current:
+				default:
+					continue
+				}
+				if errors.Is(event.err, context.Canceled) || ctx.Err() != nil {
+					return nil
+				}
+				current.close("session failed")
+				c.endpointSet().observeFailure(current.endpoint, clk.Now())
+				connection.disconnected(current, event.err)
+				if len(mirrors) > 0 {
+					// A mirror has been delivering all along, so nothing was missed.
+					current, mirrors = mirrors[0], mirrors[1:]
+					c.stats.reconnects.Add(1)
+					connection.connected(current)
+					continue
+				}
+				if !c.awaitReconnect(ctx) {
+					return nil
+				}
+				next, err := c.openSession(ctx, request, decode, events, true)
+				if err != nil {
+					if ctx.Err() != nil {
+						return nil
+					}
+					return err
+				}
+				current = next
+				c.stats.reconnects.Add(1)
+				connection.connected(current)
+				if c.config.Hooks.Backfill != nil {
+					gaps.reconnected(current)
+				}
+				continue
+			}
+
+			notification := event.notification
+			event.session.lastReceived = clk.Now()
+			c.stats.received.Add(1)
+			if nodeTime := notification.Params.Result.Context.NodeTime; !nodeTime.IsZero() {
+				c.endpointSet().observeLag(event.session.endpoint, clk.Now().Sub(nodeTime))
+			}
+			if slot := notification.Slot(); slot > event.session.lastSlot {
+				event.session.lastSlot = slot
+			}
+			// The replaced session is released once the new one has caught up with it.
+			if previous != nil && event.session == current && current.lastSlot >= previous.lastSlot {
+				previous.close("session rotated")
+				previous = nil
+			}
+			if gap, ok := gaps.gap(event.session, notification.Slot(), c.config.Pipeline.MaxGapSlots); ok {
+				startBackfill(gap)
+			}
+			if lag, persistent := pressure.observe(clk.Now(), notification, c.config.Pipeline.Downgrade); persistent && !downgraded {
+				if filtered, ok := c.downgradeRequest(request); ok {
+					if next, err := c.openSession(ctx, filtered, decode, events, false); err == nil {
+						if previous != nil {
+							previous.close("subscription downgraded")
+						}
+						previous, current = current, next
+						request, downgraded = filtered, true
+						state.downgrade(request)
+						// The mirrors shed the firehose too.
+						for _, mirror := range mirrors {
+							mirror.close("subscription downgraded")
+						}
+						mirrors = nil
+						openMirrors()
+						overlapEnd = clk.After(time.Duration(c.config.Conn.RotationOverlap))
+						c.stats.downgrades.Add(1)
+						if hook := c.config.Hooks.Downgrade; hook != nil {
+							hook(DowngradeEvent{Filter: *c.config.Pipeline.AccountFilter, Lag: lag, Since: pressure.since})
+						}
+					}
+				}
+			}
+			if c.race != nil {
+				c.race.observe(notification.Signature(), event.session.endpoint, clk.Now())
+			}
+			if !options.cursor.IsZero() && options.cursor.Covers(notification) {
+				c.stats.duplicates.Add(1)
+				continue
+			}
+			if len(mirrors) > 0 && seen.Has(notification.Signature()) {
+				// The copy of another session in redundant mode.
+				c.stats.mirrorCopies.Add(1)
+				continue
+			}
+			// Beyond maxHeld, the live notifications no longer wait for the backfill.
+			if backfilling > 0 && len(held) < maxHeld {
+				held = append(held, notification)
+				continue
+			}
+			if deliver(notification) {
+				return nil
+			}
 		}
 	}
 }
+
+// admit runs the filter stage: it reports whether the notification is new, fresh and
+// matches Hooks.Filter, counting the ones dropped.
+func (c *C) admit(ctx context.Context, seen *signatureSet, notification *TransactionNotification) bool {
+	start := time.Now()
+	defer c.latency.since(StageFilter, start)
+
+	if !c.firstSeen(ctx, seen, notification.Signature()) {
+		c.stats.duplicates.Add(1)
+		return false
+	}
+	if notification.IsStale(c.clock().Now(), time.Duration(c.config.Pipeline.MaxNotificationAge), c.config.Pipeline.AgeSource) {
+		c.stats.stale.Add(1)
+		return false
+	}
+	return c.matches(notification)
+}
+
+// matches reports whether the notification passes Pipeline.SkipFailed,
+// Pipeline.AccountFilter and Hooks.Filter, counting the ones dropped.
+func (c *C) matches(notification *TransactionNotification) bool {
+	if c.config.Pipeline.SkipFailed && !notification.Succeeded() {
+		c.stats.filtered.Add(1)
+		return false
+	}
+	if filter := c.config.Pipeline.AccountFilter; filter != nil && !filter.Match(notification) {
+		c.stats.filtered.Add(1)
+		return false
+	}
+	if filter := c.config.Hooks.Filter; filter != nil && !filter(notification) {
+		c.stats.filtered.Add(1)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,174 @@
+package chainstream
+
+import (
+	"encoding/binary"
+
+	"github.com/mr-tron/base58"
+
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+const (
+	systemProgramID    = string(programs.System)
+	tokenProgramID     = string(programs.Token)
+	token2022ProgramID = string(programs.Token2022)
+)
+
+// TransferKind tells what a Transfer moves.
+type TransferKind string
+
+const (
+	// TransferSOL moves lamports between system accounts. CreateAccount funding is reported as a SOL transfer.
+	TransferSOL TransferKind = "sol"
+	// TransferToken moves tokens between token accounts.
+	TransferToken TransferKind = "token"
+	// TransferMint creates tokens in a token account; From is empty.
+	TransferMint TransferKind = "mint"
+	// TransferBurn destroys tokens of a token account; To is empty.
+	TransferBurn TransferKind = "burn"
+)
+
+// Transfer is a movement of SOL or tokens decoded from a System or SPL Token instruction.
+type Transfer struct {
+	Kind TransferKind `json:"kind"`
+	// Index and InnerIndex locate the instruction the same way as in Instruction.
+	Index      int    `json:"index"`
+	InnerIndex int    `json:"innerIndex"`
+	ProgramID  string `json:"programId"`
+	// From and To are wallets for SOL transfers and token accounts otherwise.
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// Mint is set for token movements when it is known from the instruction or token balances.
+	Mint      string `json:"mint,omitempty"`
+	Authority string `json:"authority,omitempty"`
+	// Amount is in lamports for SOL transfers and base units of the mint otherwise.
+	Amount uint64 `json:"amount"`
+}
+
+// Transfers decodes SOL and token movements from the System and SPL Token instructions of
+// the transaction, including inner instructions, in execution order.
+func (t *TransactionNotification) Transfers() []Transfer {
+	accounts := t.tokenAccounts()
+
+	var transfers []Transfer
+	for _, ix := range t.Instructions() {
+		transfer, ok := decodeTransfer(&ix)
+		if !ok {
+			continue
+		}
+		if transfer.Kind != TransferSOL && transfer.Mint == "" {
+			if transfer.Mint = accounts[transfer.From].Mint; transfer.Mint == "" {
+				transfer.Mint = accounts[transfer.To].Mint
+			}
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers
+}
+
+// tokenAccounts maps token accounts to their latest balance snapshot.
+func (t *TransactionNotification) tokenAccounts() map[string]TokenBalance {
+	keys := t.AccountKeys()
+	meta := &t.Params.Result.Value.Meta
+	accounts := make(map[string]TokenBalance, len(meta.PostTokenBalances))
+	for _, balances := range [][]TokenBalance{meta.PreTokenBalances, meta.PostTokenBalances} {
+		for _, balance := range balances {
+			accounts[keyAt(keys, balance.AccountIndex)] = balance
+		}
+	}
+	return accounts
+}
+
+func decodeTransfer(ix *Instruction) (Transfer, bool) {
+	data, err := base58.Decode(ix.Data)
+	if err != nil {
+		return Transfer{}, false
+	}
+	transfer := Transfer{
+		Index:      ix.Index,
+		InnerIndex: ix.InnerIndex,
+		ProgramID:  ix.ProgramID,
+	}
+
+	switch ix.ProgramID {
+	case systemProgramID:
+		if len(data) < 12 {
+			return Transfer{}, false
+		}
+		transfer.Kind = TransferSOL
+		transfer.Amount = binary.LittleEndian.Uint64(data[4:12])
+		switch binary.LittleEndian.Uint32(data[:4]) {
+		case 0, 2: // CreateAccount, Transfer: [from, to, ...]
+			return transfer, setAccounts(&transfer, ix.Accounts, 0, 1, -1, -1)
+		case 3: // CreateAccountWithSeed: [from, to, ...]
+			var ok bool
+			if transfer.Amount, ok = seededLamports(data); !ok {
+				return Transfer{}, false
+			}
+			return transfer, setAccounts(&transfer, ix.Accounts, 0, 1, -1, -1)
+		case 11: // TransferWithSeed: [from, base, to]
+			return transfer, setAccounts(&transfer, ix.Accounts, 0, 2, -1, 1)
+		}
+	case tokenProgramID, token2022ProgramID:
+		if len(data) < 9 {
+			return Transfer{}, false
+		}
+		transfer.Amount = binary.LittleEndian.Uint64(data[1:9])
+		switch data[0] {
+		case 3: // Transfer: [source, destination, authority]
+			transfer.Kind = TransferToken
+			return transfer, setAccounts(&transfer, ix.Accounts, 0, 1, -1, 2)
+		case 12: // TransferChecked: [source, mint, destination, authority]
+			transfer.Kind = TransferToken
+			return transfer, setAccounts(&transfer, ix.Accounts, 0, 2, 1, 3)
+		case 7, 14: // MintTo, MintToChecked: [mint, account, authority]
+			transfer.Kind = TransferMint
+			return transfer, setAccounts(&transfer, ix.Accounts, -1, 1, 0, 2)
+		case 8, 15: // Burn, BurnChecked: [account, mint, authority]
+			transfer.Kind = TransferBurn
+			return transfer, setAccounts(&transfer, ix.Accounts, 0, -1, 1, 2)
+		}
+	}
+	return Transfer{}, false
+}
+
+// seededLamports returns the lamports of CreateAccountWithSeed data: the instruction tag,
+// the base pubkey and the seed, a u64 length and its bytes, come before them.
+func seededLamports(data []byte) (uint64, bool) {
+	const seedOffset = 4 + 32
+	if len(data) < seedOffset+8 {
+		return 0, false
+	}
+	seedLen := binary.LittleEndian.Uint64(data[seedOffset:])
+	if seedLen > uint64(len(data)) {
+		return 0, false
+	}
+	lamports := seedOffset + 8 + int(seedLen)
+	if len(data) < lamports+8 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint64(data[lamports:]), true
+}
+
+// setAccounts fills transfer accounts from instruction account positions, -1 meaning absent.
+// It reports false when a required position is out of range.
+func setAccounts(transfer *Transfer, accounts []string, from, to, mint, authority int) bool {
+	for _, pos := range []int{from, to, mint, authority} {
+		if pos >= len(accounts) {
+			return false
+		}
+	}
+	if from >= 0 {
+		transfer.From = accounts[from]
+	}
+	if to >= 0 {
+		transfer.To = accounts[to]
+	}
+	if mint >= 0 {
+		transfer.Mint = accounts[mint]
+	}
+	if authority >= 0 {
+		transfer.Authority = accounts[authority]
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package chainstream_test
+
+import (
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestTransfers(t *testing.T) {
+	tx := loadNotification(t, "testdata/sample_tx_buy.json")
+	transfers := tx.Transfers()
+
+	expected := []chainstream.Transfer{
+		{Kind: chainstream.TransferToken, Index: 2, InnerIndex: 0, ProgramID: "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA",
+			From: "2KedgPeWSFS8vocGcDLg2Rxy6FVj69wF4UhcAkucPyqe", To: "2Bbpz6yGq54VSwRHxptxdNuo9sTNBesFPwPuTvw7kJVY",
+			Mint: "DNvtizsEYyknJoW3QYwDA7ncjxri3KBBTeLydEZCpump", Authority: "8fC59gfiQerpTpTiEJVvB4u1UgHuBsEDGnxiQUU5AJQo", Amount: 357547484136},
+		{Kind: chainstream.TransferSOL, Index: 2, InnerIndex: 1, ProgramID: "11111111111111111111111111111111",
+			From: "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF", To: "8fC59gfiQerpTpTiEJVvB4u1UgHuBsEDGnxiQUU5AJQo", Amount: 10000000},
+		{Kind: chainstream.TransferSOL, Index: 2, InnerIndex: 2, ProgramID: "11111111111111111111111111111111",
+			From: "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF", To: "7hTckgnGnLQR6sdH7YkqFTAA7VwTfYFaZ6EhEsU3saCX", Amount: 100000},
+	}
+	if len(transfers) != len(expected) {
+		t.Fatalf("Transfers() = %+v, expected %d transfers", transfers, len(expected))
+	}
+	for i := range expected {
+		if transfers[i] != expected[i] {
+			t.Errorf("Transfers()[%d] = %+v\nexpected %+v", i, transfers[i], expected[i])
+		}
+	}
+}
+
+func TestAttributeBalances(t *testing.T) {
+	owner := "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF"
+
+	buy := loadNotification(t, "testdata/sample_tx_buy.json").AttributeBalances()
+	if got := buy.Instructions[2].SOL[owner]; got != -10100000 {
+		t.Errorf("buy: SOL attributed to owner = %d, expected -10100000", got)
+	}
+	if got := buy.Instructions[2].Tokens["2Bbpz6yGq54VSwRHxptxdNuo9sTNBesFPwPuTvw7kJVY"]; got != 357547484136 {
+		t.Errorf("buy: tokens attributed = %d, expected 357547484136", got)
+	}
+	if len(buy.UnattributedSOL) != 0 || len(buy.UnattributedTokens) != 0 {
+		t.Errorf("buy: unexpected unattributed deltas %v %v", buy.UnattributedSOL, buy.UnattributedTokens)
+	}
+
+	// The sell pays out SOL by changing lamports directly, which no transfer explains.
+	sell := loadNotification(t, "testdata/sample_tx_sell.json").AttributeBalances()
+	if len(sell.Instructions[2].SOL) != 0 {
+		t.Errorf("sell: unexpected SOL attributed %v", sell.Instructions[2].SOL)
+	}
+	if sell.UnattributedSOL["8fC59gfiQerpTpTiEJVvB4u1UgHuBsEDGnxiQUU5AJQo"] != -9999999 {
+		t.Errorf("sell: unattributed SOL = %v", sell.UnattributedSOL)
+	}
+
+	failed := loadNotification(t, "testdata/sample_tx_create.json")
+	if failed.Succeeded() {
+		t.Error("expected create sample to be failed")
+	}
+	if attribution := failed.AttributeBalances(); len(attribution.UnattributedSOL) != 0 {
+		t.Errorf("failed: unexpected unattributed SOL %v", attribution.UnattributedSOL)
+	}
+}
+
+func TestTransfersCreateAccountWithSeed(t *testing.T) {
+	funder := "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF"
+	stake := "7hTckgnGnLQR6sdH7YkqFTAA7VwTfYFaZ6EhEsU3saCX"
+	var tx chainstream.TransactionNotification
+	tx.Params.Result.Value.Transaction.Message.AccountKeys = []string{funder, stake, "11111111111111111111111111111111"}
+	tx.Params.Result.Value.Transaction.Message.Instructions = []chainstream.CompiledInstruction{{
+		ProgramIDIndex: 2,
+		Accounts:       []int{0, 1, 0},
+		// CreateAccountWithSeed of a stake account: base 53Ck..., seed "stake:0",
+		// 1002282880 lamports, 200 bytes owned by the stake program.
+		Data: "2XrypqwxSRvxjzdpUhgwsk45LPfGu4V24F5UBn9WEwtng5xH5iCsdKhPXWdgwimucwpGzQvXSb2sHPjGU24D7S5ddCXvwYw9rRHEsBxni2wtFcwphinhhAcbW8KNq28nUpj7R2w",
+	}}
+
+	expected := chainstream.Transfer{Kind: chainstream.TransferSOL, Index: 0, InnerIndex: -1, ProgramID: "11111111111111111111111111111111",
+		From: funder, To: stake, Amount: 1002282880}
+	if transfers := tx.Transfers(); len(transfers) != 1 || transfers[0] != expected {
+		t.Errorf("Transfers() = %+v, expected %+v", transfers, expected)
+	}
+}
@@ -35,27 +35,27 @@ type AccountKeysFilter struct {
 // TransactionFilter contains optional filters for transaction subscriptions.
 type TransactionFilter struct {
 	ExcludeVotes bool               `json:"excludeVotes"`
-	Commitment   string             `json:"commitment"`
+	Commitment   Commitment         `json:"commitment"`
 	AccountKeys  *AccountKeysFilter `json:"accountKeys,omitempty"`
 }
 
 // TransactionSubscribeParams contains params for transactionsSubscribe.
 type TransactionSubscribeParams struct {
-	Network  string            `json:"network"`
+	Network  Network           `json:"network"`
 	Verified bool              `json:"verified"`
 	Filter   TransactionFilter `json:"filter"`
 }
 
 // BlockSubscribeParams contains params for blocksSubscribe.
 type BlockSubscribeParams struct {
-	Network  string `json:"network"`
-	Verified bool   `json:"verified"`
+	Network  Network `json:"network"`
+	Verified bool    `json:"verified"`
 }
 
 // SlotSubscribeParams contains params for slotsSubscribe.
 type SlotSubscribeParams struct {
-	Network  string `json:"network"`
-	Verified bool   `json:"verified"`
+	Network  Network `json:"network"`
+	Verified bool    `json:"verified"`
 }
 
 // ContextMetadata provides contextual information tied to a transaction/slot/block.
@@ -0,0 +1,98 @@
+package chainstream
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// syndicaStreamHost is the host of Syndica ChainStream. The JSON-RPC API of a network is
+// served by <network>.api.syndica.io with the same API key path.
+const syndicaStreamHost = "chainstream.api.syndica.io"
+
+// ExpandEndpoint substitutes the placeholders of an endpoint template such as
+// wss://chainstream.api.syndica.io/api-key/{token}: {token} with the API token and
+// {network} with the network, DefaultNetwork if empty. Other placeholders and unknown
+// networks are an error.
+func ExpandEndpoint(template, token string, network Network) (string, error) {
+	network = network.orDefault()
+	var b strings.Builder
+	rest := template
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			b.WriteString(rest)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated placeholder in endpoint %q", template)
+		}
+		b.WriteString(rest[:start])
+		switch name := rest[start+1 : start+end]; name {
+		case "token":
+			if token == "" {
+				return "", fmt.Errorf("endpoint %q requires a token", template)
+			}
+			b.WriteString(url.PathEscape(token))
+		case "network":
+			if err := network.Validate(); err != nil {
+				return "", err
+			}
+			b.WriteString(url.PathEscape(string(network)))
+		default:
+			return "", fmt.Errorf("unknown placeholder {%s} in endpoint %q", name, template)
+		}
+		rest = rest[start+end+1:]
+	}
+}
+
+// RPCURL derives the HTTP JSON-RPC URL matching a WebSocket endpoint, e.g. for the
+// backfill or rpc client: ws becomes http and wss https. The ChainStream host of Syndica
+// is replaced with the RPC host of the network, DefaultNetwork if empty, keeping the API
+// key path.
+func RPCURL(endpoint string, network Network) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	default:
+		return "", fmt.Errorf("endpoint scheme must be ws or wss, got %q", u.Scheme)
+	}
+	if u.Host == syndicaStreamHost {
+		network = network.orDefault()
+		if err := network.Validate(); err != nil {
+			return "", err
+		}
+		u.Host = string(network) + strings.TrimPrefix(syndicaStreamHost, "chainstream")
+	}
+	return u.String(), nil
+}
+
+// Endpoint returns WssApiEndpoint with its placeholders substituted, see ExpandEndpoint.
+func (c *ConnConfig) Endpoint() (string, error) {
+	return ExpandEndpoint(c.WssApiEndpoint, c.Token, c.Network)
+}
+
+// RPCURL returns the HTTP JSON-RPC URL matching WssApiEndpoint, see RPCURL.
+func (c *ConnConfig) RPCURL() (string, error) {
+	endpoint, err := c.Endpoint()
+	if err != nil {
+		return "", err
+	}
+	return RPCURL(endpoint, c.Network)
+}
+
+// expand substitutes the placeholders of an endpoint URL, leaving it as is when they
+// cannot be substituted, which Validate reports.
+func (c *ConnConfig) expand(template string) string {
+	if endpoint, err := ExpandEndpoint(template, c.Token, c.Network); err == nil {
+		return endpoint
+	}
+	return template
+}
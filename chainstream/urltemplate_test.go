@@ -0,0 +1,60 @@
+package chainstream_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestExpandEndpoint(t *testing.T) {
+	tests := []struct {
+		template, token string
+		network         chainstream.Network
+		expected        string
+		fails           bool
+	}{
+		{template: "wss://chainstream.api.syndica.io/api-key/{token}", token: "secret", expected: "wss://chainstream.api.syndica.io/api-key/secret"},
+		{template: "wss://{network}.example.com/{token}", token: "a/b", network: chainstream.NetworkDevnet, expected: "wss://solana-devnet.example.com/a%2Fb"},
+		{template: "wss://{network}.example.com", expected: "wss://solana-mainnet.example.com"},
+		{template: "wss://example.com/{token}", fails: true},
+		{template: "wss://example.com/{region}", token: "secret", fails: true},
+		{template: "wss://example.com/{token", token: "secret", fails: true},
+		{template: "wss://{network}.example.com", network: "solana-mainet", fails: true},
+	}
+	for _, tt := range tests {
+		got, err := chainstream.ExpandEndpoint(tt.template, tt.token, tt.network)
+		if tt.fails {
+			if err == nil {
+				t.Errorf("ExpandEndpoint(%q) = %q, expected an error", tt.template, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.expected {
+			t.Errorf("ExpandEndpoint(%q) = %q, %v, expected %q", tt.template, got, err, tt.expected)
+		}
+	}
+}
+
+func TestConnConfigRPCURL(t *testing.T) {
+	config := chainstream.NewConfig("wss://chainstream.api.syndica.io/api-key/{token}")
+	config.Conn.Token = "secret"
+	if err := config.Conn.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if got, err := config.Conn.RPCURL(); err != nil || got != "https://solana-mainnet.api.syndica.io/api-key/secret" {
+		t.Errorf("RPCURL() = %q, %v", got, err)
+	}
+
+	config.Conn.Token = ""
+	if err := config.Conn.Validate(); err == nil {
+		t.Error("expected Validate() to require a token for the {token} placeholder")
+	}
+
+	if got, err := chainstream.RPCURL("ws://localhost:8900/ws", ""); err != nil || got != "http://localhost:8900/ws" {
+		t.Errorf("RPCURL() = %q, %v", got, err)
+	}
+	if _, err := chainstream.RPCURL("wss://chainstream.api.syndica.io/api-key/secret", "solana-mainet"); !errors.Is(err, chainstream.ErrInvalidNetwork) {
+		t.Errorf("RPCURL() error = %v, expected ErrInvalidNetwork", err)
+	}
+}
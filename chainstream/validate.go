@@ -0,0 +1,125 @@
+package chainstream
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Validate checks the filter before it is sent, as the server only rejects it with an
+// opaque error: the commitment must be processed, confirmed or finalized and every account
+// key valid base58 decoding to a public key. A key of All that is also excluded, or a
+// OneOf whose keys are all excluded, is an error too, as the filter could never match.
+func (f *TransactionFilter) Validate() error {
+	return f.validate("")
+}
+
+func (f *TransactionFilter) validate(prefix string) error {
+	var errs []error
+	if err := f.Commitment.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("%s%w", prefix, err))
+	}
+
+	keys := f.AccountKeys
+	if keys == nil {
+		return errors.Join(errs...)
+	}
+	check := func(field string, list []string) {
+		for i, key := range list {
+			if _, err := NormalizeKey(key); err != nil {
+				errs = append(errs, fmt.Errorf("%saccountKeys.%s[%d]: %w", prefix, field, i, err))
+			}
+		}
+	}
+	check("all", keys.All)
+	check("oneOf", keys.OneOf)
+	check("exclude", keys.Exclude)
+
+	excluded := make(map[string]bool, len(keys.Exclude))
+	for _, key := range keys.Exclude {
+		excluded[strings.TrimSpace(key)] = true
+	}
+	for i, key := range keys.All {
+		if excluded[strings.TrimSpace(key)] {
+			errs = append(errs, fmt.Errorf("%saccountKeys.all[%d]: %s is also excluded, the filter can never match", prefix, i, key))
+		}
+	}
+	if len(keys.OneOf) > 0 && len(excluded) > 0 {
+		allExcluded := true
+		for _, key := range keys.OneOf {
+			allExcluded = allExcluded && excluded[strings.TrimSpace(key)]
+		}
+		if allExcluded {
+			errs = append(errs, fmt.Errorf("%saccountKeys.oneOf: every key is also excluded, the filter can never match", prefix))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the params before they are sent: the network is required and must be
+// one of Networks, and the filter is checked with TransactionFilter.Validate.
+func (p *TransactionSubscribeParams) Validate() error {
+	return p.validate("")
+}
+
+func (p *TransactionSubscribeParams) validate(prefix string) error {
+	var errs []error
+	if err := validateParamsNetwork(p.Network); err != nil {
+		errs = append(errs, fmt.Errorf("%s%w", prefix, err))
+	}
+	errs = append(errs, p.Filter.validate(prefix+"filter."))
+	return errors.Join(errs...)
+}
+
+// validateRequest checks the params of a request before it is sent: the params of
+// transactionsSubscribe with TransactionSubscribeParams.Validate, of the standard
+// subscriptions with the Validate method of their params, e.g.
+// AccountSubscribeParams.Validate, and the network of blocksSubscribe and slotsSubscribe.
+// Other params are left to the server.
+func validateRequest(request *JSONRPCRequest) error {
+	var err error
+	switch p := request.Params.(type) {
+	case TransactionSubscribeParams:
+		err = p.Validate()
+	case *TransactionSubscribeParams:
+		err = p.Validate()
+	case BlockSubscribeParams:
+		err = validateParamsNetwork(p.Network)
+	case *BlockSubscribeParams:
+		err = validateParamsNetwork(p.Network)
+	case SlotSubscribeParams:
+		err = validateParamsNetwork(p.Network)
+	case *SlotSubscribeParams:
+		err = validateParamsNetwork(p.Network)
+	case AccountSubscribeParams:
+		err = p.Validate()
+	case *AccountSubscribeParams:
+		err = p.Validate()
+	case LogsSubscribeParams:
+		err = p.Validate()
+	case *LogsSubscribeParams:
+		err = p.Validate()
+	case ProgramSubscribeParams:
+		err = p.Validate()
+	case *ProgramSubscribeParams:
+		err = p.Validate()
+	case SignatureSubscribeParams:
+		err = p.Validate()
+	case *SignatureSubscribeParams:
+		err = p.Validate()
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidParams, request.Method, err)
+	}
+	return nil
+}
+
+func validateParamsNetwork(network Network) error {
+	if network == "" {
+		return errors.New("network is required")
+	}
+	if err := network.Validate(); err != nil {
+		return fmt.Errorf("network: %w", err)
+	}
+	return nil
+}
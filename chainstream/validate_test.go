@@ -0,0 +1,57 @@
+package chainstream_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestTransactionFilterValidate(t *testing.T) {
+	const (
+		a = "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF"
+		b = "6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"
+	)
+	tests := []struct {
+		name   string
+		filter chainstream.TransactionFilter
+		valid  bool
+	}{
+		{"Valid", chainstream.TransactionFilter{Commitment: chainstream.CommitmentConfirmed, AccountKeys: &chainstream.AccountKeysFilter{All: []string{a}, Exclude: []string{b}}}, true},
+		{"One Of Partly Excluded", chainstream.TransactionFilter{Commitment: chainstream.CommitmentProcessed, AccountKeys: &chainstream.AccountKeysFilter{OneOf: []string{a, b}, Exclude: []string{b}}}, true},
+		{"Missing Commitment", chainstream.TransactionFilter{}, false},
+		{"Unknown Commitment", chainstream.TransactionFilter{Commitment: chainstream.Commitment(7)}, false},
+		{"Malformed Key", chainstream.TransactionFilter{Commitment: chainstream.CommitmentConfirmed, AccountKeys: &chainstream.AccountKeysFilter{OneOf: []string{"wallet"}}}, false},
+		{"All Excluded", chainstream.TransactionFilter{Commitment: chainstream.CommitmentConfirmed, AccountKeys: &chainstream.AccountKeysFilter{All: []string{a}, Exclude: []string{" " + a}}}, false},
+		{"One Of Excluded", chainstream.TransactionFilter{Commitment: chainstream.CommitmentConfirmed, AccountKeys: &chainstream.AccountKeysFilter{OneOf: []string{a}, Exclude: []string{a}}}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.filter.Validate(); (err == nil) != tc.valid {
+				t.Errorf("Validate() error = %v, expected valid = %v", err, tc.valid)
+			}
+		})
+	}
+
+	params := chainstream.TransactionSubscribeParams{Network: "solana mainnet", Filter: chainstream.TransactionFilter{Commitment: chainstream.CommitmentConfirmed}}
+	if err := params.Validate(); err == nil {
+		t.Error("Validate() accepted an invalid network")
+	}
+}
+
+func TestInvalidParamsAreNotSent(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1))
+	client := chainstream.NewClient(chainstream.NewConfig(srv.endpoint()))
+
+	err := client.HandleTransactionsNotifications(context.Background(), chainstream.WatchWallets("wallet"), func(*chainstream.TransactionNotification) error {
+		return nil
+	})
+	if !errors.Is(err, chainstream.ErrInvalidParams) {
+		t.Errorf("HandleTransactionsNotifications() error = %v, expected ErrInvalidParams", err)
+	}
+	if connections := srv.connections.Load(); connections != 0 {
+		t.Errorf("opened %d connections for invalid params, expected none", connections)
+	}
+}
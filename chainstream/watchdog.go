@@ -0,0 +1,48 @@
+package chainstream
+
+import (
+	"time"
+
+	"nhooyr.io/websocket"
+)
+
+// IdleEvent describes a session closed by the watchdog because it received nothing within
+// ConnConfig.IdleTimeout.
+type IdleEvent struct {
+	Endpoint string
+	Method   string
+	// Idle is the time since the session received its last message.
+	Idle     time.Duration
+	LastSlot uint64
+}
+
+// IdleFunc is called when the watchdog closes an idle session, before the client reconnects.
+type IdleFunc func(event IdleEvent)
+
+// watchdog returns the ticks on which the current session is checked for idleness, or
+// nil when ConnConfig.IdleTimeout is not set.
+func (c *C) watchdog() (ticks <-chan time.Time, stop func()) {
+	idle := time.Duration(c.config.Conn.IdleTimeout)
+	if idle <= 0 {
+		return nil, func() {}
+	}
+	// Checking four times per window closes an idle session within 1.25 windows.
+	ticker := c.clock().NewTicker(idle / 4)
+	return ticker.C(), ticker.Stop
+}
+
+// checkIdle closes the session when it has received nothing within ConnConfig.IdleTimeout.
+// The session reader then fails, which triggers a reconnect.
+func (c *C) checkIdle(s *session, method string, now time.Time) {
+	idle := now.Sub(s.lastReceived)
+	if s.idle || idle < time.Duration(c.config.Conn.IdleTimeout) {
+		return
+	}
+	s.idle = true
+	c.stats.idleTimeouts.Add(1)
+	if c.config.Hooks.Idle != nil {
+		c.config.Hooks.Idle(IdleEvent{Endpoint: redactURL(s.endpoint.url), Method: method, Idle: idle, LastSlot: s.lastSlot})
+	}
+	// Closing waits for the close handshake, which a silent server may never complete.
+	go func() { _ = s.conn.Close(websocket.StatusGoingAway, "idle timeout") }()
+}
@@ -0,0 +1,130 @@
+package chainstream_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+func TestIdleWatchdog(t *testing.T) {
+	// The server sends a single notification and then stays silent.
+	srv := newFakeServer(t, sendSlots(7))
+	fake := clock.NewFake(time.Now())
+
+	idle := make(chan chainstream.IdleEvent, 1)
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Conn.IdleTimeout = chainstream.Duration(20 * time.Second)
+	config.Hooks.Idle = func(event chainstream.IdleEvent) { idle <- event }
+	config.Clock = fake
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan struct{}, 2)
+	done := make(chan error, 1)
+	go func() {
+		done <- client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(*chainstream.TransactionNotification) error {
+			received <- struct{}{}
+			return nil
+		})
+	}()
+
+	// The ping and watchdog tickers.
+	fake.BlockUntil(2)
+	<-received
+	fake.Advance(20 * time.Second)
+
+	select {
+	case event := <-idle:
+		if event.Method != "transactionsSubscribe" || event.Idle != 20*time.Second || event.LastSlot != 7 {
+			t.Errorf("IdleEvent = %+v", event)
+		}
+	case <-ctx.Done():
+		t.Fatal("idle session not detected")
+	}
+	// The tickers and the reconnect delay.
+	fake.BlockUntil(3)
+	fake.Advance(time.Second)
+	for client.Stats().Reconnects == 0 && ctx.Err() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+	if stats := client.Stats(); stats.IdleTimeouts != 1 || stats.Reconnects != 1 || srv.connections.Load() != 2 {
+		t.Errorf("Stats() = %+v, expected one idle reconnect", stats)
+	}
+}
+
+func TestIdleWatchdogMirror(t *testing.T) {
+	// The primary keeps sending, the mirror goes silent after the first notification.
+	more := make(chan struct{})
+	primary := newFakeServer(t, func(ctx context.Context, conn *websocket.Conn, n int) error {
+		if err := wsjson.Write(ctx, conn, notification(1)); err != nil {
+			return err
+		}
+		select {
+		case <-more:
+		case <-ctx.Done():
+			return nil
+		}
+		return sendSlots(2)(ctx, conn, n)
+	})
+	mirror := newFakeServer(t, sendSlots(1))
+	fake := clock.NewFake(time.Now())
+
+	idle := make(chan chainstream.IdleEvent, 2)
+	config := chainstream.NewConfig(primary.endpoint())
+	config.Conn.Endpoints = []chainstream.EndpointConfig{{URL: mirror.endpoint()}}
+	config.Conn.Redundant = true
+	config.Conn.IdleTimeout = chainstream.Duration(20 * time.Second)
+	config.Hooks.Idle = func(event chainstream.IdleEvent) { idle <- event }
+	config.Clock = fake
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	received := make(chan uint64, 2)
+	done := make(chan error, 1)
+	go func() {
+		done <- client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) error {
+			received <- n.Slot()
+			return nil
+		})
+	}()
+
+	// The ping and watchdog tickers.
+	fake.BlockUntil(2)
+	<-received
+	for client.Stats().MirrorCopies == 0 && ctx.Err() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	fake.Advance(10 * time.Second)
+	close(more)
+	<-received
+	fake.Advance(10 * time.Second)
+
+	select {
+	case event := <-idle:
+		if event.Endpoint != mirror.endpoint() {
+			t.Errorf("IdleEvent = %+v, expected the mirror", event)
+		}
+	case <-ctx.Done():
+		t.Fatal("idle mirror not detected")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+	if stats := client.Stats(); stats.IdleTimeouts != 1 || stats.Reconnects != 0 {
+		t.Errorf("Stats() = %+v, expected the mirror closed and the primary kept", stats)
+	}
+}
@@ -0,0 +1,128 @@
+package chainstream
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+// workerQueueSize is the number of notifications waiting for each worker. A full queue
+// holds back the subscription.
+const workerQueueSize = 16
+
+// Ordering selects how the workers of PipelineConfig.Workers share notifications.
+type Ordering int
+
+const (
+	// OrderByKey passes notifications of the same Hooks.OrderKey to the same worker, so
+	// they are handled in the order they were received.
+	OrderByKey Ordering = iota
+	// OrderNone passes every notification to the next free worker for the highest
+	// throughput. Notifications may be handled in any order.
+	OrderNone
+)
+
+// MarshalText implements encoding.TextMarshaler.
+func (o Ordering) MarshalText() ([]byte, error) {
+	switch o {
+	case OrderByKey:
+		return []byte("key"), nil
+	case OrderNone:
+		return []byte("none"), nil
+	}
+	return nil, fmt.Errorf("unknown ordering %d", o)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (o *Ordering) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "", "key":
+		*o = OrderByKey
+	case "none":
+		*o = OrderNone
+	default:
+		return fmt.Errorf("unknown ordering %q", text)
+	}
+	return nil
+}
+
+// OrderKeyFunc returns the key of a notification whose order must be kept, see
+// PipelineConfig.Workers.
+type OrderKeyFunc func(notification *TransactionNotification) string
+
+// workerPool handles notifications concurrently. With OrderByKey every worker has its own
+// queue and notifications of the same order key always go to the same worker, so they are
+// handled in the order they were received. With OrderNone the workers share one queue.
+type workerPool struct {
+	workers int
+	queues  []chan *TransactionNotification
+	key     OrderKeyFunc
+	stats   *stats
+	wg      sync.WaitGroup
+}
+
+// startWorkers starts n workers calling handle. Notifications still queued when ctx is
+// done are drained and counted as dropped.
+func startWorkers(ctx context.Context, n int, ordering Ordering, key OrderKeyFunc, stats *stats, handle func(*TransactionNotification)) *workerPool {
+	if key == nil {
+		key = (*TransactionNotification).Owner
+	}
+	p := &workerPool{workers: n, key: key, stats: stats}
+	if ordering == OrderNone {
+		p.queues = []chan *TransactionNotification{make(chan *TransactionNotification, n*workerQueueSize)}
+	} else {
+		p.queues = make([]chan *TransactionNotification, n)
+		for i := range p.queues {
+			p.queues[i] = make(chan *TransactionNotification, workerQueueSize)
+		}
+	}
+	for i := 0; i < n; i++ {
+		queue := p.queues[i%len(p.queues)]
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for notification := range queue {
+				if ctx.Err() != nil {
+					p.stats.dropped.Add(1)
+					continue
+				}
+				handle(notification)
+			}
+		}()
+	}
+	return p
+}
+
+// dispatch queues the notification, waiting while the queue is full. A notification that
+// cannot be queued before ctx is done is counted as dropped.
+func (p *workerPool) dispatch(ctx context.Context, notification *TransactionNotification) {
+	queue := p.queues[0]
+	if len(p.queues) > 1 {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(p.key(notification)))
+		queue = p.queues[h.Sum32()%uint32(len(p.queues))]
+	}
+	select {
+	case queue <- notification:
+	case <-ctx.Done():
+		p.stats.dropped.Add(1)
+	}
+}
+
+// queued returns the number of notifications waiting for a worker.
+func (p *workerPool) queued() int {
+	n := 0
+	for _, queue := range p.queues {
+		n += len(queue)
+	}
+	return n
+}
+
+// stop waits for the workers to handle the queued notifications.
+func (p *workerPool) stop() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
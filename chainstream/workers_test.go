@@ -0,0 +1,142 @@
+package chainstream_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+func TestWorkersKeepOrderPerKey(t *testing.T) {
+	const total = 30
+	slots := make([]uint64, total)
+	for i := range slots {
+		slots[i] = uint64(i + 1)
+	}
+	srv := newFakeServer(t, sendSlots(slots...))
+
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Pipeline.Workers = 4
+	config.Hooks.OrderKey = func(n *chainstream.TransactionNotification) string {
+		return fmt.Sprint(n.Slot() % 3)
+	}
+	client := chainstream.NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	delivered := make(map[uint64][]uint64)
+	err := client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) error {
+		// Later notifications of a key would overtake slower earlier ones without ordering.
+		time.Sleep(time.Duration(total-n.Slot()) * 100 * time.Microsecond)
+		mu.Lock()
+		defer mu.Unlock()
+		delivered[n.Slot()%3] = append(delivered[n.Slot()%3], n.Slot())
+		return nil
+	}, chainstream.WithMaxNotifications(total))
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("subscription ended by the test timeout")
+	}
+
+	n := 0
+	for key, slots := range delivered {
+		n += len(slots)
+		for i := 1; i < len(slots); i++ {
+			if slots[i] <= slots[i-1] {
+				t.Errorf("key %d delivered out of order: %v", key, slots)
+				break
+			}
+		}
+	}
+	if n != total {
+		t.Errorf("delivered %d notifications, expected %d", n, total)
+	}
+}
+
+func TestWorkersUnordered(t *testing.T) {
+	const workers = 4
+	// The notifications share the order key, so only unordered workers run them at once.
+	srv := newFakeServer(t, sendSlots(1, 2, 3, 4))
+
+	var config chainstream.Config
+	if err := json.Unmarshal([]byte(`{"conn": {"wssApiEndpoint": "`+srv.endpoint()+`"}, "pipeline": {"workers": 4, "ordering": "none"}}`), &config); err != nil {
+		t.Fatal(err)
+	}
+	config.SetDefaults()
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	client := chainstream.NewClient(&config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var started sync.WaitGroup
+	started.Add(workers)
+	all := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(all)
+	}()
+	err := client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(*chainstream.TransactionNotification) error {
+		started.Done()
+		select {
+		case <-all:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}, chainstream.WithMaxNotifications(workers))
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("notifications were not handled concurrently")
+	}
+}
+
+func TestWorkersDropQueuedOnCancel(t *testing.T) {
+	srv := newFakeServer(t, sendSlots(1, 2, 3))
+	config := chainstream.NewConfig(srv.endpoint())
+	config.Pipeline.Workers = 2
+	config.Hooks.OrderKey = func(*chainstream.TransactionNotification) string { return "" }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// The subscription is canceled while slot 2 waits behind slot 1 in the same queue.
+	started := make(chan struct{})
+	config.Hooks.Filter = func(n *chainstream.TransactionNotification) bool {
+		if n.Slot() == 3 {
+			<-started
+			cancel()
+		}
+		return true
+	}
+	client := chainstream.NewClient(config)
+
+	var handled []uint64
+	err := client.HandleTransactionsNotifications(ctx, chainstream.FirehoseNoVotes(), func(n *chainstream.TransactionNotification) error {
+		handled = append(handled, n.Slot())
+		close(started)
+		<-ctx.Done()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("HandleTransactionsNotifications() error: %v", err)
+	}
+	if len(handled) != 1 || handled[0] != 1 {
+		t.Errorf("handled %v, expected only slot 1", handled)
+	}
+	// Slot 3 is dropped too unless the subscription ended before dispatching it.
+	if dropped := client.Stats().Dropped; dropped < 1 || dropped > 2 {
+		t.Errorf("dropped %d notifications, expected the queued ones", dropped)
+	}
+}
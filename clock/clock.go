@@ -0,0 +1,35 @@
+// Package clock abstracts time so that time-based behaviour can be tested deterministically.
+package clock
+
+import "time"
+
+// Clock provides the current time, timers and tickers.
+type Clock interface {
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks at intervals.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// System returns the clock backed by the time package.
+func System() Clock {
+	return systemClock{}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (systemClock) NewTicker(d time.Duration) Ticker       { return systemTicker{time.NewTicker(d)} }
+
+type systemTicker struct {
+	*time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time { return t.Ticker.C }
@@ -0,0 +1,131 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a manually advanced clock for tests.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+	changed chan struct{}
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	period   time.Duration
+	ch       chan time.Time
+	stopped  bool
+}
+
+// NewFake creates a fake clock set to now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now, changed: make(chan struct{})}
+}
+
+// Now returns the fake current time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// After returns a channel receiving the time once the clock is advanced by d.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.add(d, 0).ch
+}
+
+// NewTicker returns a ticker firing every time the clock is advanced past a multiple of d.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	return &fakeTicker{f, f.add(d, d)}
+}
+
+func (f *Fake) add(d, period time.Duration) *fakeWaiter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := &fakeWaiter{deadline: f.now.Add(d), period: period, ch: make(chan time.Time, 1)}
+	if d <= 0 && period == 0 {
+		w.ch <- f.now
+		return w
+	}
+	f.waiters = append(f.waiters, w)
+	close(f.changed)
+	f.changed = make(chan struct{})
+	return w
+}
+
+// Advance moves the clock forward and fires due timers and tickers.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+
+	active := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.stopped {
+			continue
+		}
+		for !w.deadline.After(f.now) {
+			select {
+			case w.ch <- w.deadline:
+			default:
+			}
+			if w.period == 0 {
+				w.stopped = true
+				break
+			}
+			w.deadline = w.deadline.Add(w.period)
+		}
+		if !w.stopped {
+			active = append(active, w)
+		}
+	}
+	f.waiters = active
+}
+
+// Waiters returns the number of pending timers and tickers.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, w := range f.waiters {
+		if !w.stopped {
+			n++
+		}
+	}
+	return n
+}
+
+// BlockUntil blocks until at least n timers and tickers are pending.
+func (f *Fake) BlockUntil(n int) {
+	for {
+		f.mu.Lock()
+		pending := 0
+		for _, w := range f.waiters {
+			if !w.stopped {
+				pending++
+			}
+		}
+		changed := f.changed
+		f.mu.Unlock()
+		if pending >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+type fakeTicker struct {
+	clock  *Fake
+	waiter *fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.waiter.ch }
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.waiter.stopped = true
+}
@@ -0,0 +1,47 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+
+	after := fake.After(time.Second)
+	ticker := fake.NewTicker(300 * time.Millisecond)
+	if got := fake.Waiters(); got != 2 {
+		t.Fatalf("Waiters() = %d, expected 2", got)
+	}
+
+	fake.Advance(500 * time.Millisecond)
+	select {
+	case <-after:
+		t.Fatal("timer fired early")
+	case tick := <-ticker.C():
+		if !tick.Equal(start.Add(300 * time.Millisecond)) {
+			t.Errorf("tick at %v", tick)
+		}
+	}
+
+	fake.Advance(500 * time.Millisecond)
+	select {
+	case now := <-after:
+		if !now.Equal(start.Add(time.Second)) {
+			t.Errorf("timer fired at %v", now)
+		}
+	default:
+		t.Fatal("timer did not fire")
+	}
+
+	ticker.Stop()
+	if got := fake.Waiters(); got != 0 {
+		t.Errorf("Waiters() = %d after stop, expected 0", got)
+	}
+	if !fake.Now().Equal(start.Add(time.Second)) {
+		t.Errorf("Now() = %v", fake.Now())
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/backfill"
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/rpc"
+)
+
+func runBackfill(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	rpcURL := fs.String("rpc", envOr("ZENSOL_RPC", ""), "HTTP RPC endpoint (env ZENSOL_RPC)")
+	address := fs.String("address", "", "account whose history is loaded")
+	fromSlot := fs.Uint64("from-slot", 0, "oldest slot to include, 0 loads the whole history")
+	var commitment chainstream.Commitment
+	fs.TextVar(&commitment, "commitment", chainstream.DefaultCommitment, "commitment: confirmed or finalized")
+	skipFailed := fs.Bool("skip-failed", false, "skip failed transactions")
+	raw := fs.Bool("raw", false, "print transactions as JSON lines instead of summaries")
+	quiet := fs.Bool("quiet", false, "do not report progress on stderr")
+	var redact redactFlags
+	redact.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := redact.validate(); err != nil {
+		return err
+	}
+	if *rpcURL == "" {
+		return errors.New("-rpc is required")
+	}
+
+	var lastReport time.Time
+	progress := func(p backfill.Progress) {
+		if *quiet || time.Since(lastReport) < time.Second {
+			return
+		}
+		lastReport = time.Now()
+		fmt.Fprintf(os.Stderr, "%s: signatures=%d transactions=%d slot=%d slots-remaining=%d elapsed=%s eta=%s\n",
+			p.Phase, p.SignaturesFetched, p.TransactionsFetched, p.CurrentSlot, p.SlotsRemaining,
+			p.Elapsed.Truncate(time.Second), p.ETA.Truncate(time.Second))
+	}
+
+	out := json.NewEncoder(os.Stdout)
+	return backfill.Run(ctx, rpc.NewClient(*rpcURL), backfill.Config{
+		Address:    *address,
+		FromSlot:   *fromSlot,
+		Commitment: commitment,
+		SkipFailed: *skipFailed,
+		Progress:   progress,
+	}, func(notification *chainstream.TransactionNotification) error {
+		notification = redact.policy.Apply(notification)
+		if *raw {
+			return out.Encode(notification)
+		}
+		printSummary(os.Stdout, notification)
+		return nil
+	})
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/gerasimovvladislav/zensol-go/metrics"
+)
+
+func runDashboard(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("dashboard", flag.ContinueOnError)
+	title := fs.String("title", "zensol", "dashboard title")
+	out := fs.String("out", "-", "output file, - writes stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	dashboard, err := metrics.Dashboard(*title)
+	if err != nil {
+		return err
+	}
+	dashboard = append(dashboard, '\n')
+	if *out == "-" {
+		_, err = os.Stdout.Write(dashboard)
+		return err
+	}
+	return os.WriteFile(*out, dashboard, 0o644)
+}
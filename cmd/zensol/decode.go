@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/decoders"
+	"github.com/gerasimovvladislav/zensol-go/decoders/builtin"
+	"github.com/gerasimovvladislav/zensol-go/programs"
+	"github.com/gerasimovvladislav/zensol-go/rpc"
+)
+
+// registry decodes the instructions with the decoders compiled in, see package builtin.
+var registry = builtin.NewRegistry()
+
+// decodedView is the fully decoded representation of a transaction printed by the decode command.
+// Decoded holds the decoded instructions in the order of Instructions, nil for those
+// without a decoder.
+type decodedView struct {
+	Signature     string                           `json:"signature"`
+	Slot          uint64                           `json:"slot"`
+	Owner         string                           `json:"owner"`
+	Fee           uint64                           `json:"fee"`
+	Err           json.RawMessage                  `json:"err,omitempty"`
+	Instructions  []chainstream.Instruction        `json:"instructions"`
+	Decoded       []*decoders.Decoded              `json:"decoded"`
+	Transfers     []chainstream.Transfer           `json:"transfers"`
+	Routes        []chainstream.SwapRoute          `json:"routes,omitempty"`
+	Attribution   *chainstream.BalanceAttribution  `json:"attribution"`
+	Balances      []chainstream.BalanceChange      `json:"balanceChanges"`
+	TokenBalances []chainstream.TokenBalanceChange `json:"tokenBalanceChanges"`
+	Logs          []string                         `json:"logs"`
+}
+
+func runDecode(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("decode", flag.ContinueOnError)
+	file := fs.String("file", "-", "notification JSON file, - reads stdin")
+	rpcURL := fs.String("rpc", envOr("ZENSOL_RPC", ""), "HTTP RPC endpoint used with -signature (env ZENSOL_RPC)")
+	signature := fs.String("signature", "", "fetch the transaction by signature via RPC instead of reading a notification")
+	asJSON := fs.Bool("json", false, "print the decoded view as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var (
+		notification *chainstream.TransactionNotification
+		err          error
+	)
+	if *signature != "" {
+		if *rpcURL == "" {
+			return errors.New("-rpc is required with -signature")
+		}
+		notification, err = rpc.NewClient(*rpcURL).GetTransaction(ctx, *signature, chainstream.DefaultCommitment)
+		if err == nil && notification == nil {
+			err = fmt.Errorf("transaction %s not found", *signature)
+		}
+	} else {
+		notification, err = readNotification(*file)
+	}
+	if err != nil {
+		return err
+	}
+
+	view := decode(notification)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(view)
+	}
+	printDecoded(os.Stdout, view)
+	return nil
+}
+
+func decode(notification *chainstream.TransactionNotification) *decodedView {
+	meta := notification.Params.Result.Value.Meta
+	view := &decodedView{
+		Signature:     notification.Signature(),
+		Slot:          notification.Slot(),
+		Owner:         notification.Owner(),
+		Fee:           meta.Fee,
+		Instructions:  notification.Instructions(),
+		Decoded:       registry.DecodeTransaction(notification),
+		Transfers:     notification.Transfers(),
+		Routes:        notification.SwapRoutes(),
+		Attribution:   notification.AttributeBalances(),
+		Balances:      notification.BalanceChanges(),
+		TokenBalances: notification.TokenBalanceChanges(),
+		Logs:          meta.LogMessages,
+	}
+	if !notification.Succeeded() {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, meta.Err); err == nil {
+			view.Err = compact.Bytes()
+		} else {
+			view.Err = meta.Err
+		}
+	}
+	return view
+}
+
+func printDecoded(w io.Writer, view *decodedView) {
+	fmt.Fprintf(w, "signature: %s\nslot:      %d\nowner:     %s\nfee:       %d\n", view.Signature, view.Slot, view.Owner, view.Fee)
+	if view.Err != nil {
+		fmt.Fprintf(w, "error:     %s\n", view.Err)
+	}
+
+	fmt.Fprintln(w, "\ninstructions:")
+	for i, ix := range view.Instructions {
+		program := ix.ProgramID
+		if name, ok := programs.Name(program); ok {
+			program = name
+		}
+		details := fmt.Sprintf("accounts=%d data=%s", len(ix.Accounts), ix.Data)
+		if i < len(view.Decoded) && view.Decoded[i] != nil {
+			args, _ := json.Marshal(view.Decoded[i].Args)
+			details = fmt.Sprintf("%s %s", view.Decoded[i].Name, args)
+		}
+		if ix.IsInner() {
+			fmt.Fprintf(w, "    #%d.%d %s %s\n", ix.Index, ix.InnerIndex, program, details)
+		} else {
+			fmt.Fprintf(w, "  #%d %s %s\n", ix.Index, program, details)
+		}
+	}
+
+	fmt.Fprintln(w, "\ntransfers:")
+	for _, transfer := range view.Transfers {
+		fmt.Fprintf(w, "  #%d.%d %s %s -> %s %d", transfer.Index, transfer.InnerIndex, transfer.Kind, transfer.From, transfer.To, transfer.Amount)
+		if transfer.Mint != "" {
+			fmt.Fprintf(w, " mint=%s", transfer.Mint)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(view.Routes) > 0 {
+		fmt.Fprintln(w, "\nswap routes:")
+	}
+	for _, route := range view.Routes {
+		fmt.Fprintf(w, "  #%d %s %d -> %s %d price=%g hops=%d\n", route.Index,
+			route.InputMint(), route.InputAmount(), route.OutputMint(), route.OutputAmount(), route.Price(), len(route.Hops))
+	}
+
+	fmt.Fprintln(w, "\nSOL balance changes:")
+	for _, change := range view.Balances {
+		fmt.Fprintf(w, "  %s %+d\n", change.Account, change.Delta)
+	}
+
+	fmt.Fprintln(w, "\ntoken balance changes:")
+	for _, change := range view.TokenBalances {
+		fmt.Fprintf(w, "  %s owner=%s mint=%s %+d\n", change.Account, change.Owner, change.Mint, change.Delta)
+	}
+
+	fmt.Fprintln(w, "\nlogs:")
+	for _, line := range view.Logs {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+}
+
+func readNotification(file string) (*chainstream.TransactionNotification, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read notification: %w", err)
+	}
+	var notification chainstream.TransactionNotification
+	if err = json.Unmarshal(data, &notification); err != nil {
+		return nil, fmt.Errorf("cannot parse notification: %w", err)
+	}
+	return &notification, nil
+}
@@ -0,0 +1,71 @@
+// Command zensol is a command line client for the Syndica ChainStream WebSocket API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"stream", "stream transaction notifications", runStream},
+	{"decode", "print the decoded view of a transaction", runDecode},
+	{"watch-wallet", "tail balance changes of wallets", runWatchWallet},
+	{"watch-mint", "tail transactions touching an SPL mint", runWatchMint},
+	{"backfill", "load historical transactions of an address over RPC", runBackfill},
+	{"replay", "play back a recorded stream", runReplay},
+	{"query", "search a recorded stream by slot, time, account or instruction", runQuery},
+	{"sanitize", "replace wallets in captured notifications", runSanitize},
+	{"dashboard", "print a Grafana dashboard of the -metrics series", runDashboard},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, cmd := range commands {
+		if cmd.name != os.Args[1] {
+			continue
+		}
+		if err := cmd.run(ctx, os.Args[2:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "zensol %s: %v\n", cmd.name, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "zensol: unknown command %q\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: zensol <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+// envOr returns the value of the environment variable key or fallback when it is unset.
+func envOr(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/replay"
+)
+
+func runQuery(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	file := fs.String("file", "", "recording written by `zensol stream -raw`")
+	fromSlot := fs.Uint64("from-slot", 0, "oldest slot to include")
+	toSlot := fs.Uint64("to-slot", 0, "newest slot to include, 0 does not bound the slots")
+	since := fs.String("since", "", "oldest time to include, RFC 3339")
+	until := fs.String("until", "", "time to stop before, RFC 3339")
+	blockTime := fs.Bool("block-time", false, "compare -since and -until with the block time instead of the node time")
+	accounts := fs.String("accounts", "", "comma-separated account keys, a transaction must mention one of them")
+	programs := fs.String("programs", "", "comma-separated programs, a transaction must invoke one of them")
+	instructions := fs.String("instructions", "", "comma-separated decoded instruction names, e.g. buy,sell")
+	skipFailed := fs.Bool("skip-failed", false, "skip failed transactions")
+	limit := fs.Int("limit", 0, "stop after this many transactions, 0 prints them all")
+	events := fs.Bool("events", false, "print the matching instructions, decoded, instead of the transactions")
+	raw := fs.Bool("raw", false, "print notifications as JSON lines instead of summaries")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("-file is required")
+	}
+
+	q := replay.Query{
+		FromSlot:     *fromSlot,
+		ToSlot:       *toSlot,
+		Programs:     splitList(*programs),
+		Instructions: splitList(*instructions),
+		Registry:     registry,
+		SkipFailed:   *skipFailed,
+		Limit:        *limit,
+	}
+	var err error
+	if q.Since, err = parseTime(*since); err != nil {
+		return fmt.Errorf("-since: %w", err)
+	}
+	if q.Until, err = parseTime(*until); err != nil {
+		return fmt.Errorf("-until: %w", err)
+	}
+	if keys := splitList(*accounts); len(keys) > 0 {
+		q.Accounts = &chainstream.AccountKeysFilter{OneOf: keys}
+	}
+	archive := replay.Archive{Path: *file}
+	if *blockTime {
+		archive.AgeSource = chainstream.AgeFromBlockTime
+	}
+
+	out := json.NewEncoder(os.Stdout)
+	if *events {
+		return archive.Events(ctx, q, func(event replay.Event) error {
+			return out.Encode(event)
+		})
+	}
+	return archive.Query(ctx, q, func(notification *chainstream.TransactionNotification) error {
+		if *raw {
+			return out.Encode(notification)
+		}
+		printSummary(os.Stdout, notification)
+		return nil
+	})
+}
+
+// parseTime parses an RFC 3339 time, the zero time when s is empty.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
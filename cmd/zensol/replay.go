@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/replay"
+)
+
+func runReplay(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	file := fs.String("file", "", "recording written by `zensol stream -raw`")
+	speed := fs.Float64("speed", 1, "replay speed multiplier, 0 replays as fast as possible")
+	blockTime := fs.Bool("block-time", false, "pace by block time instead of node time")
+	raw := fs.Bool("raw", false, "print notifications as JSON lines instead of summaries")
+	var redact redactFlags
+	redact.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := redact.validate(); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("-file is required")
+	}
+
+	cfg := replay.Config{Speed: *speed}
+	if *blockTime {
+		cfg.AgeSource = chainstream.AgeFromBlockTime
+	}
+	out := json.NewEncoder(os.Stdout)
+	return replay.RunFile(ctx, *file, cfg, func(notification *chainstream.TransactionNotification) error {
+		notification = redact.policy.Apply(notification)
+		if *raw {
+			return out.Encode(notification)
+		}
+		printSummary(os.Stdout, notification)
+		return nil
+	})
+}
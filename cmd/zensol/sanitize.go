@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gerasimovvladislav/zensol-go/sanitize"
+)
+
+func runSanitize(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("sanitize", flag.ContinueOnError)
+	in := fs.String("in", "", "notification or recording to sanitize, stdin when empty")
+	out := fs.String("out", "", "output file, stdout when empty")
+	seed := fs.String("seed", envOr("ZENSOL_SANITIZE_SEED", ""), "seed of the replacement keys, random when empty (env ZENSOL_SANITIZE_SEED)")
+	keepMints := fs.Bool("keep-mints", false, "keep token mints")
+	dropLogs := fs.Bool("drop-logs", false, "drop program log messages")
+	indent := fs.Bool("indent", false, "write indented JSON, as used for testdata")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	s := sanitize.New(sanitize.Config{Seed: *seed, KeepMints: *keepMints, DropLogs: *dropLogs})
+	n, err := s.Copy(w, r, *indent)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "sanitized %d notifications\n", n)
+	return nil
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/alert"
+	"github.com/gerasimovvladislav/zensol-go/backfill"
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/metrics"
+	"github.com/gerasimovvladislav/zensol-go/metrics/pprofdebug"
+	"github.com/gerasimovvladislav/zensol-go/replay"
+	"github.com/gerasimovvladislav/zensol-go/rpc"
+)
+
+const syndicaEndpoint = "wss://chainstream.api.syndica.io/api-key/{token}"
+
+// connFlags holds the flags shared by commands that open a subscription.
+type connFlags struct {
+	endpoint     string
+	token        string
+	network      string
+	provider     string
+	commitment   string
+	accounts     string
+	exclude      string
+	mints        string
+	excludeVotes bool
+	skipFailed   bool
+	headers      headerFlag
+	resolve      resolveFlag
+	dnsServer    string
+	idleTimeout  time.Duration
+}
+
+// headerFlag collects repeated "Name: value" handshake headers.
+type headerFlag map[string]string
+
+func (h headerFlag) String() string { return fmt.Sprint(map[string]string(h)) }
+
+func (h headerFlag) Set(s string) error {
+	name, value, ok := strings.Cut(s, ":")
+	if !ok {
+		return fmt.Errorf("header %q is not in the form \"Name: value\"", s)
+	}
+	h[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	return nil
+}
+
+// resolveFlag collects repeated "host=ip" pins.
+type resolveFlag map[string][]string
+
+func (r resolveFlag) String() string { return fmt.Sprint(map[string][]string(r)) }
+
+func (r resolveFlag) Set(s string) error {
+	host, ip, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("resolve %q is not in the form host=ip", s)
+	}
+	r[host] = append(r[host], ip)
+	return nil
+}
+
+func (f *connFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.endpoint, "endpoint", envOr("ZENSOL_ENDPOINT", ""), "WebSocket endpoint (env ZENSOL_ENDPOINT)")
+	fs.StringVar(&f.token, "token", envOr("ZENSOL_TOKEN", ""), "Syndica API token, substituted for {token} in -endpoint or used with the default endpoint (env ZENSOL_TOKEN)")
+	fs.StringVar(&f.network, "network", envOr("ZENSOL_NETWORK", string(chainstream.DefaultNetwork)), "network: solana-mainnet, solana-devnet or solana-testnet (env ZENSOL_NETWORK)")
+	fs.StringVar(&f.provider, "provider", envOr("ZENSOL_PROVIDER", string(chainstream.ProviderSyndica)), "provider of -endpoint: syndica, helius, triton or rpc (env ZENSOL_PROVIDER)")
+	fs.StringVar(&f.commitment, "commitment", envOr("ZENSOL_COMMITMENT", chainstream.DefaultCommitment.String()), "commitment: processed, confirmed or finalized (env ZENSOL_COMMITMENT)")
+	fs.StringVar(&f.accounts, "accounts", "", "comma-separated account keys, a transaction must mention one of them")
+	fs.StringVar(&f.exclude, "exclude", "", "comma-separated account keys to exclude")
+	fs.StringVar(&f.mints, "mints", "", "comma-separated SPL mints, a transaction must hold a token account of one of them")
+	fs.BoolVar(&f.excludeVotes, "exclude-votes", true, "exclude vote transactions")
+	fs.BoolVar(&f.skipFailed, "skip-failed", false, "drop failed transactions")
+	f.headers = headerFlag{}
+	fs.Var(f.headers, "header", `WebSocket handshake header "Name: value", may be repeated, e.g. "X-Api-Key: <api-key>"`)
+	f.resolve = resolveFlag{}
+	fs.Var(f.resolve, "resolve", "pin an endpoint host to an IP address, host=ip, may be repeated")
+	fs.StringVar(&f.dnsServer, "dns-server", "", "DNS server resolving endpoint hosts instead of the system resolver, e.g. 1.1.1.1:53")
+	fs.DurationVar(&f.idleTimeout, "idle-timeout", 0, "reconnect when no notification arrives for this long, 0 disables")
+}
+
+func (f *connFlags) config() (*chainstream.Config, error) {
+	network, err := chainstream.ParseNetwork(f.network)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := chainstream.ParseProviderName(f.provider)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := f.endpoint
+	if endpoint == "" {
+		if provider != chainstream.ProviderSyndica {
+			return nil, fmt.Errorf("-endpoint is required with the %s provider", provider)
+		}
+		if f.token == "" {
+			return nil, errors.New("either -endpoint or -token is required")
+		}
+		endpoint = syndicaEndpoint
+	}
+	config := chainstream.NewConfig(endpoint)
+	config.Conn.Token = f.token
+	config.Conn.Network = network
+	config.Conn.Provider = provider
+	if len(f.headers) > 0 {
+		config.Conn.Headers = f.headers
+	}
+	if len(f.resolve) > 0 {
+		config.Conn.Resolve = f.resolve
+	}
+	config.Conn.DNSServer = f.dnsServer
+	config.Conn.IdleTimeout = chainstream.Duration(f.idleTimeout)
+	config.Pipeline.SkipFailed = f.skipFailed
+	if mints := splitList(f.mints); len(mints) > 0 {
+		// -mints narrows the filter already in place rather than replacing it.
+		filter := chainstream.TouchesMints(mints...)
+		if config.Hooks.Filter != nil {
+			filter = chainstream.AllOf(config.Hooks.Filter, filter)
+		}
+		config.Hooks.Filter = filter
+	}
+	if err := config.Conn.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// subscription builds and validates the subscription described by the flags.
+// Warnings about filter keys are printed to stderr.
+func (f *connFlags) subscription() (*chainstream.SubscriptionConfig, error) {
+	network, err := chainstream.ParseNetwork(f.network)
+	if err != nil {
+		return nil, err
+	}
+	commitment, err := chainstream.ParseCommitment(f.commitment)
+	if err != nil {
+		return nil, err
+	}
+	sub := &chainstream.SubscriptionConfig{
+		Name: "cli",
+		Params: chainstream.TransactionSubscribeParams{
+			Network: network,
+			Filter: chainstream.TransactionFilter{
+				ExcludeVotes: f.excludeVotes,
+				Commitment:   commitment,
+			},
+		},
+	}
+	oneOf, exclude := splitList(f.accounts), splitList(f.exclude)
+	if len(oneOf) > 0 || len(exclude) > 0 {
+		sub.Params.Filter.AccountKeys = &chainstream.AccountKeysFilter{
+			OneOf:   oneOf,
+			Exclude: exclude,
+		}
+	}
+
+	warnings, err := sub.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+	return sub, sub.Validate()
+}
+
+func runStream(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("stream", flag.ContinueOnError)
+	var conn connFlags
+	conn.register(fs)
+	raw := fs.Bool("raw", false, "print notifications as JSON lines instead of summaries")
+	metricsAddr := fs.String("metrics", "", "serve Prometheus metrics and /debug/snapshot on this address, e.g. :9090")
+	metricsName := fs.String("metrics-subscription", "", "subscription label of the -metrics series, e.g. the deployment name")
+	var debug metrics.DebugConfig
+	profiles := fs.Bool("pprof", false, "serve pprof profiles under /debug/pprof/ on the -metrics address")
+	fs.BoolVar(&debug.Runtime, "runtime-metrics", false, "add Go runtime metrics: goroutines, heap and GC pauses, to the -metrics series")
+	maxNotifications := fs.Int("max-notifications", 0, "exit after this many notifications, 0 runs until interrupted")
+	maxDuration := fs.Duration("max-duration", 0, "exit after this long, 0 runs until interrupted")
+	rpcURL := fs.String("rpc", envOr("ZENSOL_RPC", ""), "HTTP RPC endpoint used to backfill slots missed while reconnecting (env ZENSOL_RPC)")
+	archive := fs.String("archive", "", "recording of -raw output to rewind through, see -rewind")
+	rewind := fs.Duration("rewind", 0, "replay this much of -archive before streaming live")
+	var cursor chainstream.Cursor
+	fs.TextVar(&cursor, "cursor", chainstream.Cursor{}, "start after this slot:index:signature, backfilling from its slot over -rpc")
+	alertRules := fs.String("alerts", "", "YAML alert rules evaluated on every notification, alerts are printed to stderr as JSON lines")
+	alertWebhook := fs.String("alert-webhook", "", "also post the alerts of -alerts as JSON to this URL")
+	var redact redactFlags
+	redact.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := redact.validate(); err != nil {
+		return err
+	}
+	if (*profiles || debug.Runtime) && *metricsAddr == "" {
+		return errors.New("-pprof and -runtime-metrics require -metrics")
+	}
+	config, err := conn.config()
+	if err != nil {
+		return err
+	}
+	sub, err := conn.subscription()
+	if err != nil {
+		return err
+	}
+
+	if *rpcURL != "" {
+		rpcClient := rpc.NewClient(*rpcURL)
+		if httpClient := config.Conn.HTTPClient(); httpClient != nil {
+			rpcClient = rpcClient.WithHTTPClient(httpClient)
+		}
+		config.Hooks.Backfill = backfill.Gaps(rpcClient)
+	}
+	opts := []chainstream.SubscribeOption{chainstream.WithMaxNotifications(*maxNotifications), chainstream.WithMaxDuration(*maxDuration)}
+	if !cursor.IsZero() {
+		if *rpcURL == "" {
+			return errors.New("-cursor requires -rpc")
+		}
+		opts = append(opts, chainstream.WithCursor(cursor))
+	}
+	if *rewind > 0 {
+		if *archive == "" {
+			return errors.New("-rewind requires -archive")
+		}
+		opts = append(opts, chainstream.WithRewind(replay.Archive{Path: *archive}, *rewind))
+	}
+
+	var alerts *alert.Engine
+	if *alertRules != "" {
+		rules, err := alert.Load(*alertRules)
+		if err != nil {
+			return err
+		}
+		sinks := []alert.Sink{alert.JSONLines(os.Stderr)}
+		if *alertWebhook != "" {
+			webhook := alert.NewQueue(alert.Webhook(*alertWebhook, nil), 0, func(a alert.Alert, err error) {
+				fmt.Fprintf(os.Stderr, "alerts: alert %s: %v\n", a.Rule, err)
+			})
+			defer webhook.Close()
+			sinks = append(sinks, webhook.Sink)
+		}
+		if alerts, err = alert.New(rules, alert.Options{}, sinks...); err != nil {
+			return err
+		}
+	} else if *alertWebhook != "" {
+		return errors.New("-alert-webhook requires -alerts")
+	}
+
+	out := json.NewEncoder(os.Stdout)
+	client := chainstream.NewClient(config)
+	var events *metrics.Events
+	if *metricsAddr != "" {
+		events = &metrics.Events{}
+		exporter := &metrics.Exporter{Source: client, Subscription: *metricsName, Network: sub.Params.Network.String(), Events: events}
+		mux := metrics.NewServeMux(exporter, client, debug)
+		if *profiles {
+			pprofdebug.Register(mux)
+		}
+		srv := &http.Server{Addr: *metricsAddr, Handler: mux}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fmt.Fprintf(os.Stderr, "metrics: %v\n", err)
+			}
+		}()
+		defer srv.Close()
+	}
+	return client.TransactionsNotifications(ctx, sub.Request(1), func(notification *chainstream.TransactionNotification) {
+		if events != nil {
+			events.Observe(notification)
+		}
+		if alerts != nil {
+			if err := alerts.Observe(ctx, notification); err != nil {
+				fmt.Fprintf(os.Stderr, "alerts: %v\n", err)
+			}
+		}
+		notification = redact.policy.Apply(notification)
+		if *raw {
+			_ = out.Encode(notification)
+			return
+		}
+		printSummary(os.Stdout, notification)
+	}, opts...)
+}
+
+// printSummary writes a one-line human readable summary of the notification.
+func printSummary(w io.Writer, notification *chainstream.TransactionNotification) {
+	value := notification.Params.Result.Value
+	status := "ok"
+	if !notification.Succeeded() {
+		status = "failed"
+	}
+	ts, _ := notification.Time(chainstream.AgeFromBlockTime)
+	fmt.Fprintf(w, "%s slot=%d index=%d status=%s fee=%d owner=%s sig=%s\n",
+		ts.UTC().Format(time.RFC3339Nano),
+		notification.Slot(),
+		notification.Params.Result.Context.Index,
+		status,
+		value.Meta.Fee,
+		notification.Owner(),
+		notification.Signature(),
+	)
+}
+
+// redactFlags holds the flags controlling redaction of printed notifications.
+type redactFlags struct {
+	policy chainstream.RedactionPolicy
+}
+
+func (f *redactFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.policy.HashSignatures, "hash-signatures", false, "replace signatures with salted hashes")
+	fs.StringVar(&f.policy.Salt, "hash-salt", "", "salt of -hash-signatures (env ZENSOL_HASH_SALT)")
+	fs.IntVar(&f.policy.TruncateKeys, "truncate-keys", 0, "keep only this many leading characters of account keys, 0 keeps them intact")
+	fs.BoolVar(&f.policy.DropLogs, "drop-logs", false, "drop program log messages")
+}
+
+// validate checks the policy once the flags are parsed. The salt defaults from the
+// environment only when signatures are hashed, so that setting it does not break the
+// commands run without -hash-signatures.
+func (f *redactFlags) validate() error {
+	if f.policy.HashSignatures && f.policy.Salt == "" {
+		f.policy.Salt = os.Getenv("ZENSOL_HASH_SALT")
+	}
+	return f.policy.Validate()
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
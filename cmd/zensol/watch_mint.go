@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/rpc"
+	"github.com/gerasimovvladislav/zensol-go/watch"
+)
+
+func runWatchMint(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("watch-mint", flag.ContinueOnError)
+	var conn connFlags
+	conn.register(fs)
+	rpcURL := fs.String("rpc", envOr("ZENSOL_RPC", ""), "HTTP RPC endpoint looking up the largest token accounts of the mint (env ZENSOL_RPC)")
+	minAmount := fs.Uint64("min-amount", 0, "leave token accounts holding fewer base units out of the filter")
+	refresh := fs.Duration("refresh", watch.DefaultRefresh, "time between lookups of the largest token accounts")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("exactly one mint address is required")
+	}
+	if *rpcURL == "" {
+		return errors.New("-rpc is required")
+	}
+
+	config, err := conn.config()
+	if err != nil {
+		return err
+	}
+	commitment, err := chainstream.ParseCommitment(conn.commitment)
+	if err != nil {
+		return err
+	}
+	rpcClient := rpc.NewClient(*rpcURL)
+	if httpClient := config.Conn.HTTPClient(); httpClient != nil {
+		rpcClient = rpcClient.WithHTTPClient(httpClient)
+	}
+
+	manager := chainstream.NewClient(config).NewSubscriptionManager()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- manager.Run(ctx) }()
+
+	err = watch.Mint(ctx, manager, rpcClient, watch.MintConfig{
+		Mint:       fs.Arg(0),
+		Network:    config.Conn.Network,
+		Commitment: commitment,
+		MinAmount:  *minAmount,
+		Refresh:    *refresh,
+		OnUpdate: func(accounts []string) {
+			fmt.Fprintf(os.Stderr, "zensol watch-mint: filtering on %d token accounts: %s\n", len(accounts), strings.Join(accounts, ","))
+		},
+		OnError: func(err error) {
+			fmt.Fprintf(os.Stderr, "zensol watch-mint: %v\n", err)
+		},
+	}, func(notification *chainstream.TransactionNotification) error {
+		printSummary(os.Stdout, notification)
+		return nil
+	})
+	cancel()
+	if runErr := <-done; err == nil {
+		err = runErr
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/tracker"
+)
+
+func runWatchWallet(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("watch-wallet", flag.ContinueOnError)
+	var conn connFlags
+	conn.register(fs)
+	state := fs.String("state", "", "file to persist wallet positions to, restored on startup")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	wallets := append(splitList(conn.accounts), fs.Args()...)
+	if len(wallets) == 0 {
+		return errors.New("at least one wallet address is required")
+	}
+	conn.accounts = strings.Join(wallets, ",")
+
+	config, err := conn.config()
+	if err != nil {
+		return err
+	}
+	sub, err := conn.subscription()
+	if err != nil {
+		return err
+	}
+
+	watched := make(map[string]struct{}, len(wallets))
+	for _, wallet := range wallets {
+		watched[wallet] = struct{}{}
+	}
+
+	positions := tracker.New(wallets...)
+	if *state != "" {
+		if err = positions.Load(*state); err != nil {
+			return err
+		}
+		positions.Watch(wallets...)
+		go func() {
+			_ = positions.SaveEvery(ctx, *state, 30*time.Second, func(err error) {
+				fmt.Fprintf(os.Stderr, "zensol watch-wallet: %v\n", err)
+			})
+		}()
+	}
+
+	client := chainstream.NewClient(config)
+	err = client.TransactionsNotifications(ctx, sub.Request(1), func(notification *chainstream.TransactionNotification) {
+		positions.Apply(notification)
+		printWalletActivity(os.Stdout, notification, watched)
+	})
+	if *state != "" {
+		if saveErr := positions.Save(*state); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+	return err
+}
+
+// printWalletActivity writes SOL and token balance changes of the watched wallets.
+func printWalletActivity(w io.Writer, notification *chainstream.TransactionNotification, watched map[string]struct{}) {
+	ts, _ := notification.Time(chainstream.AgeFromBlockTime)
+	header := fmt.Sprintf("%s slot=%d sig=%s", ts.UTC().Format(time.RFC3339), notification.Slot(), notification.Signature())
+
+	var lines []string
+	for _, change := range notification.BalanceChanges() {
+		if _, ok := watched[change.Account]; ok {
+			lines = append(lines, fmt.Sprintf("  %s SOL %+.9f", change.Account, float64(change.Delta)/chainstream.LamportsPerSOL))
+		}
+	}
+	for _, change := range notification.TokenBalanceChanges() {
+		if _, ok := watched[change.Owner]; ok {
+			lines = append(lines, fmt.Sprintf("  %s %s %+d (decimals %d)", change.Owner, change.Mint, change.Delta, change.Decimals))
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "  no balance changes for watched wallets")
+	}
+
+	fmt.Fprintln(w, header)
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+}
@@ -0,0 +1,28 @@
+// Package builtin combines the decoders of the subpackages of decoders in a registry. The
+// set is chosen at compile time: every decoder is linked by default, and building with the
+// zensol_nodecoders tag links only those enabled with a zensol_decoder_<name> tag, e.g.
+//
+//	go build -tags zensol_nodecoders,zensol_decoder_pumpfun,zensol_decoder_token
+//
+// keeps the pump.fun and SPL Token decoders. The names are those of the subpackages:
+// computebudget, pumpfun, system and token, the latter decoding Token-2022 as well.
+//
+// Programs that need a fixed set regardless of tags should not import this package but
+// register the decoders they use with decoders.NewRegistry, which links only those.
+package builtin
+
+import "github.com/gerasimovvladislav/zensol-go/decoders"
+
+// linked holds the decoders compiled in, appended by the init functions of the files
+// guarded by build tags.
+var linked []decoders.Decoder
+
+// Decoders returns the decoders compiled in.
+func Decoders() []decoders.Decoder {
+	return append([]decoders.Decoder(nil), linked...)
+}
+
+// NewRegistry returns a registry of the decoders compiled in.
+func NewRegistry() *decoders.Registry {
+	return decoders.NewRegistry(linked...)
+}
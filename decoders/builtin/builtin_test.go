@@ -0,0 +1,19 @@
+//go:build !zensol_nodecoders
+
+package builtin_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/decoders/builtin"
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+func TestNewRegistry(t *testing.T) {
+	// Without the zensol_nodecoders tag, every decoder is linked.
+	expected := []programs.ID{programs.System, programs.PumpFun, programs.ComputeBudget, programs.Token, programs.Token2022}
+	if ids := builtin.NewRegistry().Programs(); !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Programs() = %v, expected %v", ids, expected)
+	}
+}
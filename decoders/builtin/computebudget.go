@@ -0,0 +1,9 @@
+//go:build !zensol_nodecoders || zensol_decoder_computebudget
+
+package builtin
+
+import "github.com/gerasimovvladislav/zensol-go/decoders/computebudget"
+
+func init() {
+	linked = append(linked, computebudget.New())
+}
@@ -0,0 +1,16 @@
+//go:build zensol_nodecoders && !zensol_decoder_computebudget && !zensol_decoder_pumpfun && !zensol_decoder_system && !zensol_decoder_token
+
+package builtin_test
+
+import (
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/decoders/builtin"
+)
+
+func TestNewRegistry(t *testing.T) {
+	// The zensol_nodecoders tag alone links no decoder.
+	if ids := builtin.NewRegistry().Programs(); len(ids) != 0 {
+		t.Errorf("Programs() = %v, expected none", ids)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !zensol_nodecoders || zensol_decoder_pumpfun
+
+package builtin
+
+import "github.com/gerasimovvladislav/zensol-go/decoders/pumpfun"
+
+func init() {
+	linked = append(linked, pumpfun.New())
+}
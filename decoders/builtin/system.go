@@ -0,0 +1,9 @@
+//go:build !zensol_nodecoders || zensol_decoder_system
+
+package builtin
+
+import "github.com/gerasimovvladislav/zensol-go/decoders/system"
+
+func init() {
+	linked = append(linked, system.New())
+}
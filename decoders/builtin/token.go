@@ -0,0 +1,9 @@
+//go:build !zensol_nodecoders || zensol_decoder_token
+
+package builtin
+
+import "github.com/gerasimovvladislav/zensol-go/decoders/token"
+
+func init() {
+	linked = append(linked, token.New(), token.New2022())
+}
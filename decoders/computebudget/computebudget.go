@@ -0,0 +1,64 @@
+// Package computebudget decodes the instructions of the Compute Budget program.
+package computebudget
+
+import (
+	"fmt"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/decoders"
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+// RequestHeapFrame requests a heap of Bytes bytes.
+type RequestHeapFrame struct {
+	Bytes uint32 `json:"bytes"`
+}
+
+// SetComputeUnitLimit sets the compute units the transaction may consume.
+type SetComputeUnitLimit struct {
+	Units uint32 `json:"units"`
+}
+
+// SetComputeUnitPrice sets the priority fee per compute unit.
+type SetComputeUnitPrice struct {
+	MicroLamports uint64 `json:"microLamports"`
+}
+
+// SetLoadedAccountsDataSizeLimit bounds the size of the accounts the transaction loads.
+type SetLoadedAccountsDataSizeLimit struct {
+	Bytes uint32 `json:"bytes"`
+}
+
+// New returns the decoder of the Compute Budget program.
+func New() decoders.Decoder { return decoder{} }
+
+type decoder struct{}
+
+func (decoder) Program() programs.ID { return programs.ComputeBudget }
+
+func (decoder) Decode(ix *chainstream.Instruction) (*decoders.Decoded, error) {
+	data, err := decoders.NewData(programs.ComputeBudget, ix)
+	if err != nil {
+		return nil, err
+	}
+	accounts := decoders.NewAccounts(ix)
+	result := func(name string, args interface{}) (*decoders.Decoded, error) {
+		return decoders.Result(programs.ComputeBudget, name, args, data, accounts)
+	}
+
+	switch discriminator := data.U8(); discriminator {
+	case 1:
+		return result("requestHeapFrame", &RequestHeapFrame{Bytes: data.U32()})
+	case 2:
+		return result("setComputeUnitLimit", &SetComputeUnitLimit{Units: data.U32()})
+	case 3:
+		return result("setComputeUnitPrice", &SetComputeUnitPrice{MicroLamports: data.U64()})
+	case 4:
+		return result("setLoadedAccountsDataSizeLimit", &SetLoadedAccountsDataSizeLimit{Bytes: data.U32()})
+	default:
+		if err := data.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w %d", decoders.ErrUnknownInstruction, discriminator)
+	}
+}
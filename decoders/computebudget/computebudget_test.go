@@ -0,0 +1,13 @@
+package computebudget_test
+
+import (
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/decoders/computebudget"
+	"github.com/gerasimovvladislav/zensol-go/decoders/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.CheckFile(t, computebudget.New(), "testdata/cases.json")
+	conformance.CheckTransactions(t, computebudget.New(), "../../chainstream/testdata/sample_tx_*.json")
+}
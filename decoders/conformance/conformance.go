@@ -0,0 +1,285 @@
+// Package conformance checks instruction decoders against golden cases and recorded
+// transactions, so that every decoder, built in or contributed, meets the same bar:
+//
+//   - golden instructions decode to the expected output, or fail with the expected error;
+//   - decoded instructions are attributed to the program of the decoder;
+//   - instructions of other programs fail with decoders.ErrWrongProgram;
+//   - truncated data and missing accounts never panic, and an instruction stripped of its
+//     accounts fails unless the golden one has none;
+//   - every instruction of the program in recorded transactions decodes or fails with
+//     decoders.ErrUnknownInstruction.
+//
+// Golden cases are kept in a JSON file per decoder, see Case. Run the tests with
+// -conformance.update to write the outputs of the decoders as the expected ones, e.g.
+// after adding cases extracted from new recordings with Extract, and review the diff.
+package conformance
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mr-tron/base58"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/decoders"
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+var update = flag.Bool("conformance.update", false, "write the outputs of the decoders as the expected outputs of conformance cases")
+
+// Case is a golden instruction and the expected result of decoding it.
+type Case struct {
+	Name string `json:"name"`
+	// Instruction is the instruction as resolved by chainstream, with base58 data.
+	Instruction chainstream.Instruction `json:"instruction"`
+	// Expected is the decoded instruction as JSON. It is empty when Error is set.
+	Expected json.RawMessage `json:"expected,omitempty"`
+	// Error, when set, is part of the message of the error decoding must fail with, e.g.
+	// unknown instruction.
+	Error string `json:"error,omitempty"`
+}
+
+// Load reads the cases of a golden file.
+func Load(path string) ([]Case, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cases []Case
+	if err := json.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("conformance: cannot parse %s: %w", path, err)
+	}
+	return cases, nil
+}
+
+// Save writes the cases to a golden file.
+func Save(path string, cases []Case) error {
+	data, err := json.MarshalIndent(cases, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// CheckFile checks the decoder against the cases of a golden file, see Check. With
+// -conformance.update, the expected outputs are rewritten instead.
+func CheckFile(t *testing.T, d decoders.Decoder, path string) {
+	t.Helper()
+	cases, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		for i := range cases {
+			cases[i].Expected, cases[i].Error = nil, ""
+			decoded, err := d.Decode(&cases[i].Instruction)
+			if err != nil {
+				cases[i].Error = err.Error()
+				continue
+			}
+			if cases[i].Expected, err = json.Marshal(decoded); err != nil {
+				t.Fatalf("%s: cannot marshal the decoded instruction: %v", cases[i].Name, err)
+			}
+		}
+		if err := Save(path, cases); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	Check(t, d, cases)
+}
+
+// Check checks the decoder against the golden cases, each in a subtest. It fails if there
+// are no cases, as a decoder without them cannot be held to the bar.
+func Check(t *testing.T, d decoders.Decoder, cases []Case) {
+	t.Helper()
+	if len(cases) == 0 {
+		t.Fatalf("no conformance cases for %s", d.Program())
+	}
+	names := make(map[string]bool, len(cases))
+	for _, c := range cases {
+		if names[c.Name] {
+			t.Errorf("case %q is defined twice", c.Name)
+		}
+		names[c.Name] = true
+		t.Run(c.Name, func(t *testing.T) {
+			checkCase(t, d, c)
+			checkRobust(t, d, c.Instruction)
+		})
+	}
+	t.Run("wrong program", func(t *testing.T) {
+		ix := cases[0].Instruction
+		ix.ProgramID = string(programs.Vote)
+		if d.Program() == programs.Vote {
+			ix.ProgramID = string(programs.System)
+		}
+		if _, err := decode(d, &ix); !errors.Is(err, decoders.ErrWrongProgram) {
+			t.Errorf("Decode() of an instruction of %s error = %v, expected ErrWrongProgram", ix.ProgramID, err)
+		}
+	})
+}
+
+func checkCase(t *testing.T, d decoders.Decoder, c Case) {
+	t.Helper()
+	decoded, err := decode(d, &c.Instruction)
+	if c.Error != "" {
+		if err == nil || !strings.Contains(err.Error(), c.Error) {
+			t.Errorf("Decode() error = %v, expected an error containing %q", err, c.Error)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if decoded.Program != d.Program() {
+		t.Errorf("Decode().Program = %s, expected %s", decoded.Program, d.Program())
+	}
+	got, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatalf("cannot marshal the decoded instruction: %v", err)
+	}
+	if !equalJSON(got, c.Expected) {
+		t.Errorf("Decode() = %s\nexpected %s", got, c.Expected)
+	}
+}
+
+// checkRobust decodes the instruction with its data truncated at every length and without
+// accounts.
+func checkRobust(t *testing.T, d decoders.Decoder, ix chainstream.Instruction) {
+	t.Helper()
+	data, err := base58.Decode(ix.Data)
+	if err != nil {
+		return
+	}
+	for n := range data {
+		truncated := ix
+		truncated.Data = base58.Encode(data[:n])
+		if _, err := decode(d, &truncated); errors.Is(err, errPanic) {
+			t.Errorf("Decode() with %d of %d bytes of data: %v", n, len(data), err)
+		}
+	}
+
+	stripped := ix
+	stripped.Accounts = nil
+	_, err = decode(d, &stripped)
+	if errors.Is(err, errPanic) {
+		t.Errorf("Decode() without accounts: %v", err)
+	} else if err == nil && len(ix.Accounts) > 0 {
+		if _, err := decode(d, &ix); err == nil {
+			t.Error("Decode() succeeded without the accounts of the instruction")
+		}
+	}
+}
+
+var errPanic = errors.New("decoder panicked")
+
+// decode decodes the instruction, turning a panic into an error wrapping errPanic.
+func decode(d decoders.Decoder, ix *chainstream.Instruction) (decoded *decoders.Decoded, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			decoded, err = nil, fmt.Errorf("%w: %v", errPanic, r)
+		}
+	}()
+	decoded, err = d.Decode(ix)
+	if err == nil && decoded == nil {
+		err = errors.New("Decode() returned neither a result nor an error")
+	}
+	return decoded, err
+}
+
+// equalJSON reports whether two JSON documents hold the same values, regardless of the
+// order of object keys.
+func equalJSON(a, b []byte) bool {
+	va, errA := unmarshalJSON(a)
+	vb, errB := unmarshalJSON(b)
+	return errA == nil && errB == nil && reflect.DeepEqual(va, vb)
+}
+
+func unmarshalJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	return v, dec.Decode(&v)
+}
+
+// CheckTransactions decodes every instruction of the program of the decoder in the
+// recorded transactions of the files matching the pattern: notification JSON files such as
+// chainstream/testdata/sample_tx_buy.json or JSON lines written by zensol stream -raw.
+// Each must decode, or fail with decoders.ErrUnknownInstruction, without panicking.
+func CheckTransactions(t *testing.T, d decoders.Decoder, pattern string) {
+	t.Helper()
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := 0
+	for _, path := range paths {
+		notifications, err := ReadNotifications(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range Extract(d.Program(), notifications...) {
+			seen++
+			decoded, err := decode(d, &c.Instruction)
+			switch {
+			case errors.Is(err, decoders.ErrUnknownInstruction):
+			case err != nil:
+				t.Errorf("%s: %s: Decode() error: %v", path, c.Name, err)
+			case decoded.Program != d.Program():
+				t.Errorf("%s: %s: Decode().Program = %s, expected %s", path, c.Name, decoded.Program, d.Program())
+			}
+		}
+	}
+	if seen == 0 {
+		t.Errorf("no instruction of %s in recordings matching %s", d.Program(), pattern)
+	}
+}
+
+// ReadNotifications reads the notifications of a file holding one notification as JSON or
+// a notification per line.
+func ReadNotifications(path string) ([]*chainstream.TransactionNotification, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var notifications []*chainstream.TransactionNotification
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for dec.More() {
+		var notification chainstream.TransactionNotification
+		if err := dec.Decode(&notification); err != nil {
+			return nil, fmt.Errorf("conformance: cannot parse %s: %w", path, err)
+		}
+		notifications = append(notifications, &notification)
+	}
+	return notifications, nil
+}
+
+// Extract returns the instructions of the program in the notifications as cases without
+// an expected output, named after the signature and the position of the instruction.
+// Added to a golden file, they get their expected output with -conformance.update.
+func Extract(program programs.ID, notifications ...*chainstream.TransactionNotification) []Case {
+	var cases []Case
+	for _, notification := range notifications {
+		for _, ix := range notification.Instructions() {
+			if ix.ProgramID != string(program) {
+				continue
+			}
+			name := fmt.Sprintf("%.8s #%d", notification.Signature(), ix.Index)
+			if ix.IsInner() {
+				name = fmt.Sprintf("%s.%d", name, ix.InnerIndex)
+			}
+			cases = append(cases, Case{Name: name, Instruction: ix})
+		}
+	}
+	return cases
+}
@@ -0,0 +1,139 @@
+package decoders
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/mr-tron/base58"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+// Data reads the little-endian fields of instruction data in order. The first read past
+// the end is recorded and returned by Err, later reads return zero values, so that a
+// decoder checks once after reading every field.
+type Data struct {
+	b   []byte
+	off int
+	err error
+}
+
+// NewData returns a reader of the base58 data of the instruction, checking that the
+// instruction belongs to the program.
+func NewData(program programs.ID, ix *chainstream.Instruction) (*Data, error) {
+	if ix.ProgramID != string(program) {
+		return nil, fmt.Errorf("%w: %s", ErrWrongProgram, ix.ProgramID)
+	}
+	if ix.Data == "" {
+		// base58 rejects the empty string, which encodes instructions without data.
+		return &Data{}, nil
+	}
+	b, err := base58.Decode(ix.Data)
+	if err != nil {
+		return nil, fmt.Errorf("decoders: instruction data is not base58: %w", err)
+	}
+	return &Data{b: b}, nil
+}
+
+// Len returns the length of the data.
+func (d *Data) Len() int { return len(d.b) }
+
+// Remaining returns the number of bytes not read yet.
+func (d *Data) Remaining() int { return len(d.b) - d.off }
+
+// Bytes reads n bytes.
+func (d *Data) Bytes(n int) []byte {
+	if d.err != nil || n < 0 || d.off+n > len(d.b) {
+		if d.err == nil {
+			d.err = fmt.Errorf("decoders: instruction data of %d bytes is too short, reading %d bytes at %d", len(d.b), n, d.off)
+		}
+		return nil
+	}
+	b := d.b[d.off : d.off+n]
+	d.off += n
+	return b
+}
+
+// U8 reads a byte.
+func (d *Data) U8() uint8 {
+	if b := d.Bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+// U32 reads a uint32.
+func (d *Data) U32() uint32 {
+	if b := d.Bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+// U64 reads a uint64.
+func (d *Data) U64() uint64 {
+	if b := d.Bytes(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+// String reads a Borsh string: a uint32 length followed by the bytes.
+func (d *Data) String() string {
+	n := d.U32()
+	if uint64(n) > uint64(len(d.b)) {
+		d.Bytes(len(d.b) + 1)
+		return ""
+	}
+	return string(d.Bytes(int(n)))
+}
+
+// Key reads a 32-byte public key in base58.
+func (d *Data) Key() string {
+	if b := d.Bytes(chainstream.PublicKeyLength); b != nil {
+		return base58.Encode(b)
+	}
+	return ""
+}
+
+// Err returns the error of the first read past the end.
+func (d *Data) Err() error { return d.err }
+
+// Accounts reads the accounts of an instruction by position. Like Data, the first
+// missing account is recorded and returned by Err.
+type Accounts struct {
+	ix  *chainstream.Instruction
+	err error
+}
+
+// NewAccounts returns a reader of the accounts of the instruction.
+func NewAccounts(ix *chainstream.Instruction) *Accounts {
+	return &Accounts{ix: ix}
+}
+
+// At returns the account at position i.
+func (a *Accounts) At(i int) string {
+	if i >= len(a.ix.Accounts) {
+		if a.err == nil {
+			a.err = fmt.Errorf("decoders: instruction has %d accounts, expected at least %d", len(a.ix.Accounts), i+1)
+		}
+		return ""
+	}
+	return a.ix.Accounts[i]
+}
+
+// Err returns the error of the first missing account.
+func (a *Accounts) Err() error { return a.err }
+
+// Result returns the decoded instruction, or the error of a read past the end of the
+// data or the accounts.
+func Result(program programs.ID, name string, args interface{}, data *Data, accounts *Accounts) (*Decoded, error) {
+	if err := data.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if err := accounts.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &Decoded{Program: program, Name: name, Args: args}, nil
+}
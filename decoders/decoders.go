@@ -0,0 +1,118 @@
+// Package decoders decodes the instructions of well-known programs into typed arguments
+// and named accounts. The decoders of the subpackages are combined in a Registry; each
+// one is checked against golden cases with the conformance package.
+//
+// Only the subpackages a program imports are linked: a registry of explicitly chosen
+// decoders, e.g. NewRegistry(pumpfun.New(), token.New()), links those alone. Package
+// builtin provides a registry of the decoders selected with build tags.
+package decoders
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+var (
+	// ErrUnknownInstruction is returned by a decoder for an instruction of its program it
+	// does not know, e.g. an administrative one.
+	ErrUnknownInstruction = errors.New("decoders: unknown instruction")
+	// ErrWrongProgram is returned by a decoder for an instruction of another program.
+	ErrWrongProgram = errors.New("decoders: instruction of another program")
+	// ErrNoDecoder is returned by Registry.Decode for programs without a decoder.
+	ErrNoDecoder = errors.New("decoders: no decoder for program")
+)
+
+// Decoded is a decoded instruction.
+type Decoded struct {
+	// Program is the program of the instruction.
+	Program programs.ID `json:"program"`
+	// Name is the name of the instruction in the program IDL, e.g. transferChecked.
+	Name string `json:"name"`
+	// Args holds the arguments and named accounts of the instruction, a struct of the
+	// decoder such as token.Transfer.
+	Args interface{} `json:"args"`
+}
+
+// Decoder decodes the instructions of a program. Decode must not panic on malformed data
+// or missing accounts but return an error, and must return ErrWrongProgram and
+// ErrUnknownInstruction for instructions it cannot decode.
+type Decoder interface {
+	Program() programs.ID
+	Decode(ix *chainstream.Instruction) (*Decoded, error)
+}
+
+// Registry maps programs to their decoders. It is safe for concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	decoders map[programs.ID]Decoder
+}
+
+// NewRegistry returns a registry of the decoders. It panics if two decode the same
+// program, see Register.
+func NewRegistry(decoders ...Decoder) *Registry {
+	r := &Registry{decoders: make(map[programs.ID]Decoder, len(decoders))}
+	for _, d := range decoders {
+		if err := r.Register(d); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Register adds a decoder. It returns an error if the program already has one.
+func (r *Registry) Register(d Decoder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.decoders[d.Program()]; ok {
+		return fmt.Errorf("decoders: program %s is registered twice", d.Program())
+	}
+	r.decoders[d.Program()] = d
+	return nil
+}
+
+// Lookup returns the decoder of a program.
+func (r *Registry) Lookup(program string) (Decoder, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.decoders[programs.ID(program)]
+	return d, ok
+}
+
+// Programs returns the programs with a decoder, sorted.
+func (r *Registry) Programs() []programs.ID {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := make([]programs.ID, 0, len(r.decoders))
+	for id := range r.decoders {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Decode decodes the instruction with the decoder of its program. It returns ErrNoDecoder
+// for programs without one.
+func (r *Registry) Decode(ix *chainstream.Instruction) (*Decoded, error) {
+	d, ok := r.Lookup(ix.ProgramID)
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrNoDecoder, ix.ProgramID)
+	}
+	return d.Decode(ix)
+}
+
+// DecodeTransaction decodes every instruction of the transaction in execution order, see
+// chainstream.TransactionNotification.Instructions. Instructions that cannot be decoded
+// are nil.
+func (r *Registry) DecodeTransaction(notification *chainstream.TransactionNotification) []*Decoded {
+	instructions := notification.Instructions()
+	decoded := make([]*Decoded, len(instructions))
+	for i := range instructions {
+		decoded[i], _ = r.Decode(&instructions[i])
+	}
+	return decoded
+}
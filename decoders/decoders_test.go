@@ -0,0 +1,57 @@
+package decoders_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/decoders"
+	"github.com/gerasimovvladislav/zensol-go/decoders/computebudget"
+	"github.com/gerasimovvladislav/zensol-go/decoders/conformance"
+	"github.com/gerasimovvladislav/zensol-go/decoders/pumpfun"
+	"github.com/gerasimovvladislav/zensol-go/decoders/system"
+	"github.com/gerasimovvladislav/zensol-go/decoders/token"
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+func TestRegistryDecodeTransaction(t *testing.T) {
+	registry := decoders.NewRegistry(computebudget.New(), pumpfun.New(), system.New(), token.New())
+	notifications, err := conformance.ReadNotifications("../chainstream/testdata/sample_tx_buy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, decoded := range registry.DecodeTransaction(notifications[0]) {
+		if decoded == nil {
+			names = append(names, "")
+			continue
+		}
+		names = append(names, decoded.Name)
+	}
+	// The last instruction is the trade event of pump.fun, which is not decoded.
+	expected := []string{"setComputeUnitLimit", "setComputeUnitPrice", "buy", "transfer", "transfer", "transfer", ""}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("DecodeTransaction() names = %q, expected %q", names, expected)
+	}
+
+	buy := registry.DecodeTransaction(notifications[0])[2].Args.(*pumpfun.Buy)
+	if buy.Mint != "DNvtizsEYyknJoW3QYwDA7ncjxri3KBBTeLydEZCpump" || buy.MaxSolCost != 10302000 {
+		t.Errorf("buy = %+v", buy)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := decoders.NewRegistry(system.New(), token.New(), token.New2022())
+	if err := registry.Register(token.New()); err == nil {
+		t.Error("Register() of a second Token decoder succeeded")
+	}
+	expected := []programs.ID{programs.System, programs.Token, programs.Token2022}
+	if ids := registry.Programs(); !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Programs() = %v, expected %v", ids, expected)
+	}
+	if _, err := registry.Decode(&chainstream.Instruction{ProgramID: string(programs.Memo)}); !errors.Is(err, decoders.ErrNoDecoder) {
+		t.Errorf("Decode() of a Memo instruction error = %v, expected ErrNoDecoder", err)
+	}
+}
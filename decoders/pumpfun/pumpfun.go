@@ -0,0 +1,85 @@
+// Package pumpfun decodes the trading and token creation instructions of the pump.fun
+// bonding curve program.
+package pumpfun
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/decoders"
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+// Anchor discriminators of the instructions.
+var (
+	createDiscriminator = []byte{24, 30, 200, 40, 5, 28, 7, 119}
+	buyDiscriminator    = []byte{102, 6, 61, 18, 1, 218, 235, 234}
+	sellDiscriminator   = []byte{51, 230, 133, 164, 1, 127, 131, 173}
+)
+
+// Create launches a token on a new bonding curve. Creator is empty in instructions of
+// program versions that did not pass it.
+type Create struct {
+	Mint         string `json:"mint"`
+	BondingCurve string `json:"bondingCurve"`
+	User         string `json:"user"`
+	Name         string `json:"name"`
+	Symbol       string `json:"symbol"`
+	URI          string `json:"uri"`
+	Creator      string `json:"creator,omitempty"`
+}
+
+// Buy buys Amount tokens for at most MaxSolCost lamports.
+type Buy struct {
+	Mint         string `json:"mint"`
+	BondingCurve string `json:"bondingCurve"`
+	User         string `json:"user"`
+	Amount       uint64 `json:"amount"`
+	MaxSolCost   uint64 `json:"maxSolCost"`
+}
+
+// Sell sells Amount tokens for at least MinSolOutput lamports.
+type Sell struct {
+	Mint         string `json:"mint"`
+	BondingCurve string `json:"bondingCurve"`
+	User         string `json:"user"`
+	Amount       uint64 `json:"amount"`
+	MinSolOutput uint64 `json:"minSolOutput"`
+}
+
+// New returns the decoder of the pump.fun program.
+func New() decoders.Decoder { return decoder{} }
+
+type decoder struct{}
+
+func (decoder) Program() programs.ID { return programs.PumpFun }
+
+func (decoder) Decode(ix *chainstream.Instruction) (*decoders.Decoded, error) {
+	data, err := decoders.NewData(programs.PumpFun, ix)
+	if err != nil {
+		return nil, err
+	}
+	accounts := decoders.NewAccounts(ix)
+	result := func(name string, args interface{}) (*decoders.Decoded, error) {
+		return decoders.Result(programs.PumpFun, name, args, data, accounts)
+	}
+
+	switch discriminator := data.Bytes(8); {
+	case bytes.Equal(discriminator, createDiscriminator):
+		create := &Create{Mint: accounts.At(0), BondingCurve: accounts.At(2), User: accounts.At(7), Name: data.String(), Symbol: data.String(), URI: data.String()}
+		if data.Err() == nil && data.Remaining() > 0 {
+			create.Creator = data.Key()
+		}
+		return result("create", create)
+	case bytes.Equal(discriminator, buyDiscriminator):
+		return result("buy", &Buy{Mint: accounts.At(2), BondingCurve: accounts.At(3), User: accounts.At(6), Amount: data.U64(), MaxSolCost: data.U64()})
+	case bytes.Equal(discriminator, sellDiscriminator):
+		return result("sell", &Sell{Mint: accounts.At(2), BondingCurve: accounts.At(3), User: accounts.At(6), Amount: data.U64(), MinSolOutput: data.U64()})
+	default:
+		if err := data.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w %x", decoders.ErrUnknownInstruction, discriminator)
+	}
+}
@@ -0,0 +1,13 @@
+package pumpfun_test
+
+import (
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/decoders/conformance"
+	"github.com/gerasimovvladislav/zensol-go/decoders/pumpfun"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.CheckFile(t, pumpfun.New(), "testdata/cases.json")
+	conformance.CheckTransactions(t, pumpfun.New(), "../../chainstream/testdata/sample_tx_*.json")
+}
@@ -0,0 +1,72 @@
+// Package system decodes the instructions of the System program.
+package system
+
+import (
+	"fmt"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/decoders"
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+// CreateAccount funds a new account and assigns it to Owner.
+type CreateAccount struct {
+	From     string `json:"from"`
+	Account  string `json:"account"`
+	Lamports uint64 `json:"lamports"`
+	Space    uint64 `json:"space"`
+	Owner    string `json:"owner"`
+}
+
+// Assign assigns an account to Owner.
+type Assign struct {
+	Account string `json:"account"`
+	Owner   string `json:"owner"`
+}
+
+// Transfer moves Lamports between system accounts.
+type Transfer struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Lamports uint64 `json:"lamports"`
+}
+
+// Allocate allocates Space bytes to an account.
+type Allocate struct {
+	Account string `json:"account"`
+	Space   uint64 `json:"space"`
+}
+
+// New returns the decoder of the System program.
+func New() decoders.Decoder { return decoder{} }
+
+type decoder struct{}
+
+func (decoder) Program() programs.ID { return programs.System }
+
+func (decoder) Decode(ix *chainstream.Instruction) (*decoders.Decoded, error) {
+	data, err := decoders.NewData(programs.System, ix)
+	if err != nil {
+		return nil, err
+	}
+	accounts := decoders.NewAccounts(ix)
+	result := func(name string, args interface{}) (*decoders.Decoded, error) {
+		return decoders.Result(programs.System, name, args, data, accounts)
+	}
+
+	switch discriminator := data.U32(); discriminator {
+	case 0:
+		return result("createAccount", &CreateAccount{From: accounts.At(0), Account: accounts.At(1), Lamports: data.U64(), Space: data.U64(), Owner: data.Key()})
+	case 1:
+		return result("assign", &Assign{Account: accounts.At(0), Owner: data.Key()})
+	case 2:
+		return result("transfer", &Transfer{From: accounts.At(0), To: accounts.At(1), Lamports: data.U64()})
+	case 8:
+		return result("allocate", &Allocate{Account: accounts.At(0), Space: data.U64()})
+	default:
+		if err := data.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w %d", decoders.ErrUnknownInstruction, discriminator)
+	}
+}
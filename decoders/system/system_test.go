@@ -0,0 +1,13 @@
+package system_test
+
+import (
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/decoders/conformance"
+	"github.com/gerasimovvladislav/zensol-go/decoders/system"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.CheckFile(t, system.New(), "testdata/cases.json")
+	conformance.CheckTransactions(t, system.New(), "../../chainstream/testdata/sample_tx_*.json")
+}
@@ -0,0 +1,115 @@
+// Package token decodes the instructions of the SPL Token and Token-2022 programs that
+// move tokens and manage token accounts. Amounts are in base units of the mint.
+package token
+
+import (
+	"fmt"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/decoders"
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+// InitializeAccount initializes a token account of Mint owned by Owner.
+type InitializeAccount struct {
+	Account string `json:"account"`
+	Mint    string `json:"mint"`
+	Owner   string `json:"owner"`
+}
+
+// Transfer moves tokens between token accounts. Mint and Decimals are only set by
+// transferChecked.
+type Transfer struct {
+	Source      string `json:"source"`
+	Mint        string `json:"mint,omitempty"`
+	Destination string `json:"destination"`
+	Authority   string `json:"authority"`
+	Amount      uint64 `json:"amount"`
+	Decimals    *uint8 `json:"decimals,omitempty"`
+}
+
+// MintTo creates tokens in a token account. Decimals is only set by mintToChecked.
+type MintTo struct {
+	Mint      string `json:"mint"`
+	Account   string `json:"account"`
+	Authority string `json:"authority"`
+	Amount    uint64 `json:"amount"`
+	Decimals  *uint8 `json:"decimals,omitempty"`
+}
+
+// Burn destroys tokens of a token account. Decimals is only set by burnChecked.
+type Burn struct {
+	Account   string `json:"account"`
+	Mint      string `json:"mint"`
+	Authority string `json:"authority"`
+	Amount    uint64 `json:"amount"`
+	Decimals  *uint8 `json:"decimals,omitempty"`
+}
+
+// CloseAccount closes a token account and sends its lamports to Destination.
+type CloseAccount struct {
+	Account     string `json:"account"`
+	Destination string `json:"destination"`
+	Authority   string `json:"authority"`
+}
+
+// SyncNative updates the amount of a wrapped SOL account to its lamports.
+type SyncNative struct {
+	Account string `json:"account"`
+}
+
+// New returns the decoder of the SPL Token program.
+func New() decoders.Decoder { return decoder{programs.Token} }
+
+// New2022 returns the decoder of the Token-2022 program, for the instructions it shares
+// with the SPL Token program.
+func New2022() decoders.Decoder { return decoder{programs.Token2022} }
+
+type decoder struct {
+	program programs.ID
+}
+
+func (d decoder) Program() programs.ID { return d.program }
+
+func (d decoder) Decode(ix *chainstream.Instruction) (*decoders.Decoded, error) {
+	data, err := decoders.NewData(d.program, ix)
+	if err != nil {
+		return nil, err
+	}
+	accounts := decoders.NewAccounts(ix)
+	result := func(name string, args interface{}) (*decoders.Decoded, error) {
+		return decoders.Result(d.program, name, args, data, accounts)
+	}
+	decimals := func() *uint8 {
+		decimals := data.U8()
+		return &decimals
+	}
+
+	switch discriminator := data.U8(); discriminator {
+	case 1:
+		return result("initializeAccount", &InitializeAccount{Account: accounts.At(0), Mint: accounts.At(1), Owner: accounts.At(2)})
+	case 3:
+		return result("transfer", &Transfer{Source: accounts.At(0), Destination: accounts.At(1), Authority: accounts.At(2), Amount: data.U64()})
+	case 7:
+		return result("mintTo", &MintTo{Mint: accounts.At(0), Account: accounts.At(1), Authority: accounts.At(2), Amount: data.U64()})
+	case 8:
+		return result("burn", &Burn{Account: accounts.At(0), Mint: accounts.At(1), Authority: accounts.At(2), Amount: data.U64()})
+	case 9:
+		return result("closeAccount", &CloseAccount{Account: accounts.At(0), Destination: accounts.At(1), Authority: accounts.At(2)})
+	case 12:
+		return result("transferChecked", &Transfer{Source: accounts.At(0), Mint: accounts.At(1), Destination: accounts.At(2), Authority: accounts.At(3), Amount: data.U64(), Decimals: decimals()})
+	case 14:
+		return result("mintToChecked", &MintTo{Mint: accounts.At(0), Account: accounts.At(1), Authority: accounts.At(2), Amount: data.U64(), Decimals: decimals()})
+	case 15:
+		return result("burnChecked", &Burn{Account: accounts.At(0), Mint: accounts.At(1), Authority: accounts.At(2), Amount: data.U64(), Decimals: decimals()})
+	case 17:
+		return result("syncNative", &SyncNative{Account: accounts.At(0)})
+	case 18:
+		return result("initializeAccount3", &InitializeAccount{Account: accounts.At(0), Mint: accounts.At(1), Owner: data.Key()})
+	default:
+		if err := data.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w %d", decoders.ErrUnknownInstruction, discriminator)
+	}
+}
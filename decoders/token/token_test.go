@@ -0,0 +1,13 @@
+package token_test
+
+import (
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/decoders/conformance"
+	"github.com/gerasimovvladislav/zensol-go/decoders/token"
+)
+
+func TestConformance(t *testing.T) {
+	conformance.CheckFile(t, token.New(), "testdata/cases.json")
+	conformance.CheckTransactions(t, token.New(), "../../chainstream/testdata/sample_tx_*.json")
+}
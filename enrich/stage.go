@@ -0,0 +1,207 @@
+// Package enrich looks up data attached to notifications, such as token metadata, wallet
+// labels or USD prices, in the background with bounded concurrency and caching, so slow
+// lookups never hold up the dispatch of notifications.
+package enrich
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+	"github.com/gerasimovvladislav/zensol-go/internal/lru"
+)
+
+// Defaults of Config.
+const (
+	DefaultConcurrency = 4
+	DefaultMaxPending  = 1000
+	DefaultCacheSize   = 10000
+	DefaultTimeout     = 2 * time.Second
+	DefaultRetryAfter  = 10 * time.Second
+)
+
+// ErrUnavailable is returned by Value when the value was not looked up in time.
+var ErrUnavailable = errors.New("enrich: value unavailable")
+
+// Config describes the lookup of one kind of data.
+type Config[V any] struct {
+	// Keys returns the keys a notification needs values of, e.g. its mints.
+	Keys func(notification *chainstream.TransactionNotification) []string
+	// Lookup loads the value of a key, e.g. over RPC.
+	Lookup func(ctx context.Context, key string) (V, error)
+	// Concurrency bounds the lookups running at once. Zero uses DefaultConcurrency.
+	Concurrency int
+	// MaxPending bounds the lookups running or waiting to run; keys requested beyond it
+	// are looked up on a later request. Zero uses DefaultMaxPending.
+	MaxPending int
+	// CacheSize is the number of keys cached. Zero uses DefaultCacheSize.
+	CacheSize int
+	// TTL is how long a looked up value is cached. Zero caches it until it is evicted.
+	TTL time.Duration
+	// RetryAfter is how long a failed lookup is cached before the key is looked up again.
+	// Zero uses DefaultRetryAfter.
+	RetryAfter time.Duration
+	// Timeout bounds a single lookup and the time Value waits for it. Zero uses
+	// DefaultTimeout.
+	Timeout time.Duration
+	// Fallback returns the value used while the value of a key is not available. Nil uses
+	// the zero value.
+	Fallback func(key string) V
+	// Clock measures cache ages and Value waits. Nil uses the system clock.
+	Clock clock.Clock
+}
+
+// result is a finished or running lookup.
+type result[V any] struct {
+	value V
+	err   error
+	at    time.Time
+	done  chan struct{}
+}
+
+// Stage looks up and caches the values of one kind of data. It is safe for concurrent use.
+type Stage[V any] struct {
+	config Config[V]
+	cache  *lru.Cache[string, *result[V]]
+	sem    chan struct{}
+
+	mu       sync.Mutex
+	inflight map[string]*result[V]
+}
+
+// New returns an enrichment stage.
+func New[V any](config Config[V]) *Stage[V] {
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultConcurrency
+	}
+	if config.MaxPending <= 0 {
+		config.MaxPending = DefaultMaxPending
+	}
+	if config.CacheSize <= 0 {
+		config.CacheSize = DefaultCacheSize
+	}
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = DefaultRetryAfter
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.Clock == nil {
+		config.Clock = clock.System()
+	}
+	return &Stage[V]{
+		config:   config,
+		cache:    lru.New[string, *result[V]](config.CacheSize),
+		sem:      make(chan struct{}, config.Concurrency),
+		inflight: make(map[string]*result[V]),
+	}
+}
+
+// Prefetch starts the lookups of the keys of the notification that are not cached
+// without waiting for them. It always returns nil, so it can be used as
+// chainstream.Hooks.Enrich.
+func (s *Stage[V]) Prefetch(notification *chainstream.TransactionNotification) error {
+	for _, key := range s.config.Keys(notification) {
+		s.start(key)
+	}
+	return nil
+}
+
+// Get returns the cached value of the key without waiting, starting its lookup when it is
+// not cached. While the value is not available it returns the fallback and false.
+func (s *Stage[V]) Get(key string) (V, bool) {
+	if r := s.start(key); r != nil {
+		select {
+		case <-r.done:
+			if r.err == nil {
+				return r.value, true
+			}
+		default:
+		}
+	}
+	return s.fallback(key), false
+}
+
+// Value returns the value of the key, waiting up to Config.Timeout for its lookup. When it
+// is not available in time, or the lookup failed, it returns the fallback with
+// ErrUnavailable or the lookup error.
+func (s *Stage[V]) Value(ctx context.Context, key string) (V, error) {
+	r := s.start(key)
+	if r == nil {
+		return s.fallback(key), ErrUnavailable
+	}
+	select {
+	case <-r.done:
+		if r.err != nil {
+			return s.fallback(key), r.err
+		}
+		return r.value, nil
+	case <-s.config.Clock.After(s.config.Timeout):
+		return s.fallback(key), ErrUnavailable
+	case <-ctx.Done():
+		return s.fallback(key), ctx.Err()
+	}
+}
+
+// start returns the cached or running lookup of the key, starting one when there is
+// none. It returns nil when too many lookups are pending.
+func (s *Stage[V]) start(key string) *result[V] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.cache.Get(key); ok && s.fresh(r) {
+		return r
+	}
+	if r, ok := s.inflight[key]; ok {
+		return r
+	}
+	if len(s.inflight) >= s.config.MaxPending {
+		return nil
+	}
+	r := &result[V]{done: make(chan struct{})}
+	s.inflight[key] = r
+	go s.lookup(key, r)
+	return r
+}
+
+func (s *Stage[V]) lookup(key string, r *result[V]) {
+	s.sem <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	r.value, r.err = s.config.Lookup(ctx, key)
+	cancel()
+	<-s.sem
+	r.at = s.config.Clock.Now()
+
+	s.mu.Lock()
+	delete(s.inflight, key)
+	s.cache.Add(key, r)
+	s.mu.Unlock()
+	close(r.done)
+}
+
+// Shed evicts the least recently used fraction of the cached values, see
+// memguard.Shedder. Running lookups are kept.
+func (s *Stage[V]) Shed(fraction float64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cache.Shed(fraction)
+}
+
+// fresh reports whether the finished lookup may still be used.
+func (s *Stage[V]) fresh(r *result[V]) bool {
+	age := s.config.Clock.Now().Sub(r.at)
+	if r.err != nil {
+		return age < s.config.RetryAfter
+	}
+	return s.config.TTL <= 0 || age < s.config.TTL
+}
+
+func (s *Stage[V]) fallback(key string) V {
+	if s.config.Fallback == nil {
+		var zero V
+		return zero
+	}
+	return s.config.Fallback(key)
+}
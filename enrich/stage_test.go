@@ -0,0 +1,116 @@
+package enrich_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+	"github.com/gerasimovvladislav/zensol-go/enrich"
+)
+
+// slowLookup returns the key in upper case once release is closed, tracking the lookups
+// running at once.
+type slowLookup struct {
+	release         chan struct{}
+	calls           atomic.Int32
+	running, maxRun atomic.Int32
+}
+
+func (l *slowLookup) lookup(ctx context.Context, key string) (string, error) {
+	l.calls.Add(1)
+	n := l.running.Add(1)
+	defer l.running.Add(-1)
+	for {
+		max := l.maxRun.Load()
+		if n <= max || l.maxRun.CompareAndSwap(max, n) {
+			break
+		}
+	}
+	select {
+	case <-l.release:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	if key == "bad" {
+		return "", errors.New("lookup failed")
+	}
+	return "label:" + key, nil
+}
+
+func TestStage(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := &slowLookup{release: make(chan struct{})}
+	stage := enrich.New(enrich.Config[string]{
+		Keys: func(n *chainstream.TransactionNotification) []string {
+			return []string{n.Signature() + "-a", n.Signature() + "-b", n.Signature() + "-c"}
+		},
+		Lookup:      l.lookup,
+		Concurrency: 2,
+		TTL:         time.Minute,
+		Timeout:     time.Minute,
+		Fallback:    func(key string) string { return "unknown" },
+		Clock:       fake,
+	})
+
+	var n chainstream.TransactionNotification
+	n.Params.Result.Context.Signature = "sig"
+	// Prefetching does not wait for the slow lookups.
+	if err := stage.Prefetch(&n); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := stage.Get("sig-a"); ok || v != "unknown" {
+		t.Errorf("Get() = %q, %v before the lookup finished, expected the fallback", v, ok)
+	}
+
+	var wg sync.WaitGroup
+	for _, key := range []string{"sig-a", "sig-b", "sig-c"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := stage.Value(context.Background(), key); err != nil || v != "label:"+key {
+				t.Errorf("Value(%q) = %q, %v", key, v, err)
+			}
+		}()
+	}
+	for l.calls.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(l.release)
+	wg.Wait()
+
+	if l.calls.Load() != 3 || l.maxRun.Load() > 2 {
+		t.Errorf("%d lookups, %d at once, expected 3 lookups and at most 2 at once", l.calls.Load(), l.maxRun.Load())
+	}
+	// Cached values are returned without a new lookup until the TTL passes.
+	if v, ok := stage.Get("sig-b"); !ok || v != "label:sig-b" || l.calls.Load() != 3 {
+		t.Errorf("Get() = %q, %v after %d lookups", v, ok, l.calls.Load())
+	}
+	fake.Advance(time.Minute)
+	if _, err := stage.Value(context.Background(), "sig-b"); err != nil || l.calls.Load() != 4 {
+		t.Errorf("Value() error %v after %d lookups, expected a new lookup", err, l.calls.Load())
+	}
+
+	if v, err := stage.Value(context.Background(), "bad"); err == nil || v != "unknown" {
+		t.Errorf("Value() = %q, %v for a failed lookup, expected the fallback", v, err)
+	}
+}
+
+func TestStageTimeout(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := &slowLookup{release: make(chan struct{})}
+	defer close(l.release)
+	stage := enrich.New(enrich.Config[string]{Lookup: l.lookup, Timeout: time.Second, Clock: fake})
+
+	go func() {
+		fake.BlockUntil(1)
+		fake.Advance(time.Second)
+	}()
+	if v, err := stage.Value(context.Background(), "slow"); !errors.Is(err, enrich.ErrUnavailable) || v != "" {
+		t.Errorf("Value() = %q, %v, expected ErrUnavailable", v, err)
+	}
+}
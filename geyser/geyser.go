@@ -0,0 +1,316 @@
+// Package geyser subscribes to transactions over the Yellowstone gRPC (Geyser) protocol
+// and delivers them as chainstream.TransactionNotification, so the handlers of ChainStream
+// subscriptions run unchanged on a Yellowstone endpoint.
+//
+// The protocol is spoken over HTTP/2 with TLS by the standard library, without generated
+// code: only the transaction subscription and its messages are implemented.
+package geyser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+	"github.com/gerasimovvladislav/zensol-go/internal/sigset"
+)
+
+// Defaults of Config.
+const (
+	DefaultPingInterval   = 10 * time.Second
+	DefaultReconnectDelay = time.Second
+	// DefaultMaxReconnectDelay bounds the backoff between reconnect attempts.
+	DefaultMaxReconnectDelay = 30 * time.Second
+)
+
+// dedupCapacity is the number of recent signatures remembered to drop the transactions
+// replayed after a reconnect.
+const dedupCapacity = 10000
+
+// Config configures a Yellowstone client.
+type Config struct {
+	// Endpoint is the https URL of the Yellowstone gRPC server.
+	Endpoint string
+	// Token is sent in the x-token header, the authentication of most providers.
+	Token string
+	// Headers are added to the request, e.g. for providers authenticating otherwise.
+	Headers map[string]string
+	// HTTPClient must speak HTTP/2, which http.DefaultClient, the default, does over TLS.
+	HTTPClient *http.Client
+	// PingInterval is the interval of the pings keeping idle streams open through load
+	// balancers.
+	PingInterval time.Duration
+	// ReconnectDelay is the first delay before reconnecting, doubled on every failed
+	// attempt up to MaxReconnectDelay.
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+	// IdleTimeout reconnects when no transaction arrives for this long, like
+	// chainstream.ConnConfig.IdleTimeout, e.g. from a server that still sends its pings.
+	// Zero disables the watchdog; filters matching few transactions need a long window.
+	IdleTimeout time.Duration
+	// Clock drives pings and reconnects. Nil uses the system clock.
+	Clock clock.Clock
+	// RetryBudget, when set, bounds the reconnects together with the other subsystems
+	// sharing it: a reconnect waits for a retry of the budget after its delay.
+	RetryBudget *chainstream.RetryBudget
+}
+
+// SetDefaults fills the unset fields with their defaults.
+func (c *Config) SetDefaults() {
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+	if c.PingInterval == 0 {
+		c.PingInterval = DefaultPingInterval
+	}
+	if c.ReconnectDelay == 0 {
+		c.ReconnectDelay = DefaultReconnectDelay
+	}
+	if c.MaxReconnectDelay == 0 {
+		c.MaxReconnectDelay = DefaultMaxReconnectDelay
+	}
+	if c.Clock == nil {
+		c.Clock = clock.System()
+	}
+}
+
+// Validate checks the configuration: the endpoint must be an https URL, the durations
+// must be positive and the idle timeout must not be negative.
+func (c *Config) Validate() error {
+	var errs []error
+	if u, err := url.Parse(c.Endpoint); err != nil || u.Scheme != "https" || u.Host == "" {
+		errs = append(errs, fmt.Errorf("geyser: endpoint %q is not an https URL", c.Endpoint))
+	}
+	if c.PingInterval <= 0 {
+		errs = append(errs, errors.New("geyser: ping interval must be positive"))
+	}
+	if c.ReconnectDelay <= 0 || c.MaxReconnectDelay < c.ReconnectDelay {
+		errs = append(errs, errors.New("geyser: reconnect delays must be positive and ordered"))
+	}
+	if c.IdleTimeout < 0 {
+		errs = append(errs, errors.New("geyser: idle timeout must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// Client subscribes to a Yellowstone gRPC endpoint.
+type Client struct {
+	config Config
+}
+
+// NewClient creates a client, filling the unset fields of the configuration with their
+// defaults.
+func NewClient(config Config) *Client {
+	config.SetDefaults()
+	return &Client{config: config}
+}
+
+// TransactionsNotifications subscribes to the transactions matching the params, see
+// HandleTransactionsNotifications.
+func (c *Client) TransactionsNotifications(
+	ctx context.Context,
+	params chainstream.TransactionSubscribeParams,
+	do func(notification *chainstream.TransactionNotification),
+) error {
+	return c.HandleTransactionsNotifications(ctx, params, func(notification *chainstream.TransactionNotification) error {
+		do(notification)
+		return nil
+	})
+}
+
+// TransactionsFeed returns a Feed of the transactions matching the params, see
+// HandleTransactionsNotifications, over an unbuffered channel.
+func (c *Client) TransactionsFeed(params chainstream.TransactionSubscribeParams) chainstream.Feed[*chainstream.TransactionNotification] {
+	return chainstream.NewFeed(0, func(ctx context.Context, do func(notification *chainstream.TransactionNotification) error) error {
+		return c.HandleTransactionsNotifications(ctx, params, do)
+	})
+}
+
+// HandleTransactionsNotifications subscribes to the transactions matching the params,
+// translated with NewSubscribeRequest, and passes them to do as chainstream
+// notifications. The slot status of the notifications is the commitment of the
+// subscription, and their node time the creation time of the update.
+//
+// A failed first connection is returned. Later failures reconnect with backoff, resuming
+// from the slot of the last transaction and dropping those delivered already; rejected
+// credentials, see StatusError, end the subscription instead. An error returned by do ends
+// the subscription and is returned. It returns nil once ctx is done.
+func (c *Client) HandleTransactionsNotifications(
+	ctx context.Context,
+	params chainstream.TransactionSubscribeParams,
+	do chainstream.HandlerFunc,
+) error {
+	if err := c.config.Validate(); err != nil {
+		return err
+	}
+	if params.Filter.Commitment == 0 {
+		params.Filter.Commitment = chainstream.DefaultCommitment
+	}
+	if err := params.Filter.Validate(); err != nil {
+		return err
+	}
+	request := NewSubscribeRequest(params)
+	s, err := c.open(ctx, &request)
+	if err != nil {
+		return err
+	}
+
+	sub := &subscription{client: c, request: request, do: do, seen: sigset.New(dedupCapacity)}
+	delay := c.config.ReconnectDelay
+	for {
+		err = sub.run(ctx, s)
+		s.close()
+		if ctx.Err() != nil {
+			return nil
+		}
+		var failure *handlerError
+		if errors.As(err, &failure) {
+			return failure.err
+		}
+		if errors.Is(err, chainstream.ErrUnauthorized) {
+			return err
+		}
+		for {
+			select {
+			case <-c.config.Clock.After(delay):
+			case <-ctx.Done():
+				return nil
+			}
+			if c.config.RetryBudget.Wait(ctx, chainstream.RetryReconnect) != nil {
+				return nil
+			}
+			// Resume from the last slot, whose remaining transactions were not all
+			// delivered yet.
+			request.FromSlot = sub.lastSlot
+			if s, err = c.open(ctx, &request); err == nil {
+				delay = c.config.ReconnectDelay
+				break
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, chainstream.ErrUnauthorized) {
+				return err
+			}
+			delay = min(2*delay, c.config.MaxReconnectDelay)
+		}
+	}
+}
+
+// errIdle fails a stream closed by the watchdog, see Config.IdleTimeout.
+var errIdle = errors.New("geyser: no transaction within the idle timeout")
+
+// handlerError marks the errors returned by the handler.
+type handlerError struct{ err error }
+
+func (e *handlerError) Error() string { return e.err.Error() }
+
+// subscription holds the state of a subscription kept across reconnects.
+type subscription struct {
+	client   *Client
+	request  SubscribeRequest
+	do       chainstream.HandlerFunc
+	seen     *sigset.Set
+	lastSlot uint64
+	// lastReceived is the time of the last transaction of the stream, in Unix nanoseconds
+	// of the clock.
+	lastReceived atomic.Int64
+}
+
+// run reads the stream until it fails, replying to the pings of the server and sending
+// its own.
+func (sub *subscription) run(ctx context.Context, s *stream) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go sub.ping(ctx, s)
+	var idle atomic.Bool
+	if sub.client.config.IdleTimeout > 0 {
+		sub.received()
+		go sub.watch(ctx, s, &idle)
+	}
+
+	var pingID int32 = 1
+	pong := &SubscribeRequest{Ping: &pingID}
+	for {
+		message, err := s.recv()
+		if idle.Load() {
+			return errIdle
+		}
+		if err == io.EOF {
+			return errors.New("geyser: stream closed by the server")
+		}
+		if err != nil {
+			return err
+		}
+		u, err := decodeUpdate(message, sub.request.Commitment)
+		if err != nil {
+			return err
+		}
+		switch {
+		case u.ping:
+			if err := s.send(pong); err != nil {
+				return err
+			}
+		case u.transaction != nil:
+			n := u.transaction
+			sub.received()
+			sub.lastSlot = max(sub.lastSlot, n.Slot())
+			if !sub.seen.Add(n.Signature()) {
+				continue
+			}
+			if err := sub.do(n); err != nil {
+				return &handlerError{err}
+			}
+		}
+	}
+}
+
+// ping sends a ping every PingInterval until ctx is done.
+func (sub *subscription) ping(ctx context.Context, s *stream) {
+	ticker := sub.client.config.Clock.NewTicker(sub.client.config.PingInterval)
+	defer ticker.Stop()
+	var id int32
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			id++
+			if s.send(&SubscribeRequest{Ping: &id}) != nil {
+				return
+			}
+		}
+	}
+}
+
+// received records the arrival of a transaction for the watchdog.
+func (sub *subscription) received() {
+	sub.lastReceived.Store(sub.client.config.Clock.Now().UnixNano())
+}
+
+// watch closes the stream once it has received no transaction within IdleTimeout, which
+// fails run with errIdle and reconnects. It returns when ctx is done.
+func (sub *subscription) watch(ctx context.Context, s *stream, idle *atomic.Bool) {
+	timeout := sub.client.config.IdleTimeout
+	// Checking four times per window closes an idle stream within 1.25 windows.
+	ticker := sub.client.config.Clock.NewTicker(timeout / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C():
+			if now.Sub(time.Unix(0, sub.lastReceived.Load())) >= timeout {
+				idle.Store(true)
+				s.close()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package geyser_test
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/geyser"
+)
+
+const trader = "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF"
+
+func loadNotification(t *testing.T, file string) *chainstream.TransactionNotification {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	var tx chainstream.TransactionNotification
+	if err := json.Unmarshal(data, &tx); err != nil {
+		t.Fatalf("failed to unmarshal tx: %v", err)
+	}
+	return &tx
+}
+
+// fakeServer is a Yellowstone server over HTTP/2 with TLS. serve is called for every
+// stream with its number, from 1, and returns the grpc-status ending it.
+type fakeServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests [][]geyser.SubscribeRequest
+}
+
+type serveFunc func(ctx context.Context, n int, send func(message []byte), requests <-chan geyser.SubscribeRequest) string
+
+func newFakeServer(t *testing.T, serve serveFunc) *fakeServer {
+	t.Helper()
+	srv := &fakeServer{}
+	srv.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/geyser.Geyser/Subscribe" || r.Header.Get("X-Token") != "token" {
+			w.Header().Set("Content-Type", "application/grpc")
+			w.Header().Set("Grpc-Status", "16")
+			w.Header().Set("Grpc-Message", "invalid token")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		srv.mu.Lock()
+		srv.requests = append(srv.requests, nil)
+		n := len(srv.requests)
+		srv.mu.Unlock()
+
+		requests := make(chan geyser.SubscribeRequest, 16)
+		go func() {
+			for {
+				var header [5]byte
+				if _, err := io.ReadFull(r.Body, header[:]); err != nil {
+					return
+				}
+				message := make([]byte, binary.BigEndian.Uint32(header[1:]))
+				if _, err := io.ReadFull(r.Body, message); err != nil {
+					return
+				}
+				var request geyser.SubscribeRequest
+				if err := request.UnmarshalBinary(message); err != nil {
+					t.Errorf("UnmarshalBinary() error: %v", err)
+					return
+				}
+				srv.mu.Lock()
+				srv.requests[n-1] = append(srv.requests[n-1], request)
+				srv.mu.Unlock()
+				requests <- request
+			}
+		}()
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		send := func(message []byte) {
+			header := make([]byte, 5)
+			binary.BigEndian.PutUint32(header[1:], uint32(len(message)))
+			w.Write(append(header, message...))
+			w.(http.Flusher).Flush()
+		}
+		w.Header().Set("Grpc-Status", serve(r.Context(), n, send, requests))
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// stream returns the requests received on stream n.
+func (srv *fakeServer) stream(n int) []geyser.SubscribeRequest {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return append([]geyser.SubscribeRequest(nil), srv.requests[n-1]...)
+}
+
+func (srv *fakeServer) config() geyser.Config {
+	return geyser.Config{
+		Endpoint:       srv.URL,
+		Token:          "token",
+		HTTPClient:     srv.Client(),
+		ReconnectDelay: 10 * time.Millisecond,
+	}
+}
+
+func update(t *testing.T, n *chainstream.TransactionNotification) []byte {
+	t.Helper()
+	message, err := geyser.MarshalTransactionUpdate(n, "zensol")
+	if err != nil {
+		t.Fatalf("MarshalTransactionUpdate() error: %v", err)
+	}
+	return message
+}
+
+// normalized returns v as decoded JSON without nulls and empty arrays, which the RPC and
+// Yellowstone forms do not agree on.
+func normalized(t *testing.T, v interface{}) interface{} {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded interface{}
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	var prune func(v interface{}) interface{}
+	prune = func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for key, value := range v {
+				if value = prune(value); value == nil {
+					delete(v, key)
+				} else {
+					v[key] = value
+				}
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				return nil
+			}
+			for i := range v {
+				v[i] = prune(v[i])
+			}
+		}
+		return v
+	}
+	return prune(decoded)
+}
+
+func TestTransactionsNotifications(t *testing.T) {
+	fixtures := []*chainstream.TransactionNotification{
+		loadNotification(t, "../chainstream/testdata/sample_tx_buy.json"),
+		loadNotification(t, "../chainstream/testdata/sample_tx_sell.json"),
+		loadNotification(t, "../chainstream/testdata/sample_tx_create.json"),
+	}
+	units := uint64(37827)
+	fixtures[0].Params.Result.Value.Meta.ComputeUnitsConsumed = &units
+	fixtures[1].Params.Result.Value.Version = json.RawMessage("0")
+	// The client answers the ping sent after the transactions once it handled them.
+	pinged := make(chan geyser.SubscribeRequest, 1)
+	srv := newFakeServer(t, func(ctx context.Context, _ int, send func([]byte), requests <-chan geyser.SubscribeRequest) string {
+		<-requests
+		for _, n := range fixtures {
+			send(update(t, n))
+		}
+		send([]byte{0x32, 0x00}) // a ping update
+		select {
+		case request := <-requests:
+			pinged <- request
+		case <-ctx.Done():
+		}
+		<-ctx.Done()
+		return "0"
+	})
+
+	params := chainstream.TransactionSubscribeParams{Filter: chainstream.TransactionFilter{
+		ExcludeVotes: true,
+		AccountKeys:  &chainstream.AccountKeysFilter{OneOf: []string{trader}},
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var pong geyser.SubscribeRequest
+	go func() {
+		select {
+		case pong = <-pinged:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	var received []*chainstream.TransactionNotification
+	err := geyser.NewClient(srv.config()).TransactionsNotifications(ctx, params, func(n *chainstream.TransactionNotification) {
+		received = append(received, n)
+	})
+	if err != nil {
+		t.Fatalf("TransactionsNotifications() error: %v", err)
+	}
+	if pong.Ping == nil || pong.Transactions != nil {
+		t.Errorf("answered the ping with %+v, expected a ping request", pong)
+	}
+
+	vote := false
+	expected := geyser.SubscribeRequest{
+		Transactions: map[string]geyser.TransactionsFilter{"zensol": {Vote: &vote, AccountInclude: []string{trader}}},
+		Commitment:   chainstream.CommitmentConfirmed,
+	}
+	if requests := srv.stream(1); len(requests) == 0 || !reflect.DeepEqual(requests[0], expected) {
+		t.Errorf("subscribed with %+v, expected %+v", requests, expected)
+	}
+	if len(received) != len(fixtures) {
+		t.Fatalf("received %d notifications, expected %d", len(received), len(fixtures))
+	}
+	for i, fixture := range fixtures {
+		n := received[i]
+		context := fixture.Params.Result.Context
+		context.Slot, context.SlotStatus = fixture.Slot(), "confirmed"
+		if !reflect.DeepEqual(n.Params.Result.Context, context) {
+			t.Errorf("notification %d has context %+v, expected %+v", i, n.Params.Result.Context, context)
+		}
+		value := fixture.Params.Result.Value
+		value.BlockTime, value.Transaction.MessageHash, value.Meta.Rewards = nil, "", nil
+		if value.Version == nil {
+			value.Version = json.RawMessage(`"legacy"`)
+		}
+		if !reflect.DeepEqual(normalized(t, n.Params.Result.Value), normalized(t, value)) {
+			t.Errorf("notification %d has value %+v, expected %+v", i, n.Params.Result.Value, value)
+		}
+		if n.Succeeded() != fixture.Succeeded() || string(n.Params.Result.Value.Meta.Err) != string(normalizedErr(t, fixture)) {
+			t.Errorf("notification %d has error %s, expected %s", i, n.Params.Result.Value.Meta.Err, fixture.Params.Result.Value.Meta.Err)
+		}
+	}
+}
+
+// normalizedErr returns the compact JSON of the error of the notification.
+func normalizedErr(t *testing.T, n *chainstream.TransactionNotification) []byte {
+	t.Helper()
+	data, err := json.Marshal(n.Params.Result.Value.Meta.Err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestTransactionsNotificationsResume(t *testing.T) {
+	buy := loadNotification(t, "../chainstream/testdata/sample_tx_buy.json")
+	sell := loadNotification(t, "../chainstream/testdata/sample_tx_sell.json")
+	srv := newFakeServer(t, func(ctx context.Context, n int, send func([]byte), requests <-chan geyser.SubscribeRequest) string {
+		<-requests
+		send(update(t, buy))
+		if n == 1 {
+			return "14"
+		}
+		send(update(t, sell))
+		<-ctx.Done()
+		return "0"
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var received []string
+	err := geyser.NewClient(srv.config()).TransactionsNotifications(ctx, chainstream.TransactionSubscribeParams{}, func(n *chainstream.TransactionNotification) {
+		if received = append(received, n.Signature()); len(received) == 2 {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("TransactionsNotifications() error: %v", err)
+	}
+	if expected := []string{buy.Signature(), sell.Signature()}; !reflect.DeepEqual(received, expected) {
+		t.Errorf("received %v, expected %v once each", received, expected)
+	}
+	if requests := srv.stream(2); len(requests) == 0 || requests[0].FromSlot != buy.Slot() {
+		t.Errorf("resumed with %+v, expected from slot %d", requests, buy.Slot())
+	}
+}
+
+func TestTransactionsNotificationsIdle(t *testing.T) {
+	buy := loadNotification(t, "../chainstream/testdata/sample_tx_buy.json")
+	// The first stream stays silent until the watchdog closes it.
+	srv := newFakeServer(t, func(ctx context.Context, n int, send func([]byte), requests <-chan geyser.SubscribeRequest) string {
+		<-requests
+		if n > 1 {
+			send(update(t, buy))
+		}
+		<-ctx.Done()
+		return "0"
+	})
+	config := srv.config()
+	config.IdleTimeout = 100 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var received []string
+	err := geyser.NewClient(config).TransactionsNotifications(ctx, chainstream.TransactionSubscribeParams{}, func(n *chainstream.TransactionNotification) {
+		received = append(received, n.Signature())
+		cancel()
+	})
+	if err != nil {
+		t.Fatalf("TransactionsNotifications() error: %v", err)
+	}
+	if len(received) != 1 || received[0] != buy.Signature() {
+		t.Fatalf("received %v, expected the buy of the second stream", received)
+	}
+	if requests := srv.stream(2); len(requests) == 0 {
+		t.Error("did not reconnect after the idle timeout")
+	}
+}
+
+func TestTransactionsNotificationsHandlerError(t *testing.T) {
+	buy := loadNotification(t, "../chainstream/testdata/sample_tx_buy.json")
+	srv := newFakeServer(t, func(ctx context.Context, _ int, send func([]byte), requests <-chan geyser.SubscribeRequest) string {
+		<-requests
+		send(update(t, buy))
+		<-ctx.Done()
+		return "0"
+	})
+	failure := errors.New("handler failed")
+	err := geyser.NewClient(srv.config()).HandleTransactionsNotifications(context.Background(), chainstream.TransactionSubscribeParams{}, func(*chainstream.TransactionNotification) error {
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("HandleTransactionsNotifications() error %v, expected the handler error", err)
+	}
+}
+
+func TestTransactionsNotificationsUnauthorized(t *testing.T) {
+	srv := newFakeServer(t, nil)
+	config := srv.config()
+	config.Token = "wrong"
+	err := geyser.NewClient(config).TransactionsNotifications(context.Background(), chainstream.TransactionSubscribeParams{}, func(*chainstream.TransactionNotification) {})
+	var status *geyser.StatusError
+	if !errors.Is(err, chainstream.ErrUnauthorized) || !errors.As(err, &status) || status.Message != "invalid token" {
+		t.Errorf("TransactionsNotifications() error %v, expected ErrUnauthorized", err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	config := geyser.Config{Endpoint: "http://example.com:10000"}
+	config.SetDefaults()
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() accepted a cleartext endpoint")
+	}
+	config.Endpoint = "https://example.com:10000"
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validate() error: %v", err)
+	}
+	config.IdleTimeout = -time.Second
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() accepted a negative idle timeout")
+	}
+}
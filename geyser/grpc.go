@@ -0,0 +1,194 @@
+package geyser
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+// subscribePath is the path of the Subscribe method of the Geyser service.
+const subscribePath = "/geyser.Geyser/Subscribe"
+
+// maxMessageSize bounds the messages read from the server, blocks of large transactions
+// included.
+const maxMessageSize = 64 << 20
+
+// gRPC status codes of the rejected credentials.
+const (
+	codePermissionDenied = 7
+	codeUnauthenticated  = 16
+)
+
+// StatusError is a gRPC status other than OK ending the stream.
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("geyser: grpc status %d: %s", e.Code, e.Message)
+}
+
+// Unwrap returns chainstream.ErrUnauthorized for rejected tokens.
+func (e *StatusError) Unwrap() error {
+	if e.Code == codeUnauthenticated || e.Code == codePermissionDenied {
+		return chainstream.ErrUnauthorized
+	}
+	return nil
+}
+
+// stream is a bidirectional gRPC stream of the Subscribe method, carried by an HTTP/2
+// request whose body stays open for the requests sent after the first one.
+type stream struct {
+	response *http.Response
+	body     *io.PipeWriter
+	cancel   context.CancelFunc
+
+	mu sync.Mutex
+}
+
+// open starts a Subscribe stream with the request as its first message.
+func (c *Client) open(ctx context.Context, request *SubscribeRequest) (*stream, error) {
+	first, err := request.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	target, err := url.JoinPath(c.config.Endpoint, subscribePath)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	reader, writer := io.Pipe()
+	// The transport watches ctx only once the body is read, so a done ctx must end the
+	// body too.
+	context.AfterFunc(ctx, func() {
+		reader.CloseWithError(ctx.Err())
+	})
+	body := requestBody{Reader: io.MultiReader(bytes.NewReader(frame(first)), reader), pipe: reader}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, body)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	if c.config.Token != "" {
+		req.Header.Set("X-Token", c.config.Token)
+	}
+	for name, value := range c.config.Headers {
+		req.Header.Set(name, value)
+	}
+
+	response, err := c.config.HTTPClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("geyser: cannot subscribe: %w", err)
+	}
+	s := &stream{response: response, body: writer, cancel: cancel}
+	switch {
+	case response.StatusCode != http.StatusOK:
+		err = fmt.Errorf("geyser: cannot subscribe: unexpected status %s", response.Status)
+	case response.ProtoMajor != 2:
+		err = fmt.Errorf("geyser: cannot subscribe: %s instead of HTTP/2", response.Proto)
+	case !strings.HasPrefix(response.Header.Get("Content-Type"), "application/grpc"):
+		err = fmt.Errorf("geyser: cannot subscribe: unexpected content type %q", response.Header.Get("Content-Type"))
+	default:
+		// Errors before any message come in the headers only.
+		err = status(response.Header)
+	}
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// requestBody is the body of the stream. Its Close, called by the transport once the
+// stream ends, fails the pending and later writes of requests.
+type requestBody struct {
+	io.Reader
+	pipe *io.PipeReader
+}
+
+func (b requestBody) Close() error {
+	return b.pipe.Close()
+}
+
+// frame prefixes an uncompressed message with its gRPC header.
+func frame(message []byte) []byte {
+	data := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(data[1:], uint32(len(message)))
+	return append(data, message...)
+}
+
+// send writes a request to the stream. It is safe for concurrent use.
+func (s *stream) send(request *SubscribeRequest) error {
+	message, err := request.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err = s.body.Write(frame(message)); err != nil {
+		return fmt.Errorf("geyser: cannot send request: %w", err)
+	}
+	return nil
+}
+
+// recv reads the next message. It returns the status of the stream once it ends, io.EOF
+// when that status is OK.
+func (s *stream) recv() ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(s.response.Body, header[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			if err := status(s.response.Trailer); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("geyser: cannot read message: %w", err)
+	}
+	if header[0] != 0 {
+		return nil, errors.New("geyser: compressed messages are not supported")
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxMessageSize {
+		return nil, fmt.Errorf("geyser: message of %d bytes exceeds %d", size, maxMessageSize)
+	}
+	message := make([]byte, size)
+	if _, err := io.ReadFull(s.response.Body, message); err != nil {
+		return nil, fmt.Errorf("geyser: cannot read message: %w", err)
+	}
+	return message, nil
+}
+
+// close ends the stream in both directions.
+func (s *stream) close() {
+	s.cancel()
+	s.body.Close()
+	s.response.Body.Close()
+}
+
+// status returns the StatusError of the grpc-status in h, nil when it is OK or missing.
+func status(h http.Header) error {
+	value := h.Get("Grpc-Status")
+	if value == "" || value == "0" {
+		return nil
+	}
+	code, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("geyser: invalid grpc status %q", value)
+	}
+	message, _ := url.PathUnescape(h.Get("Grpc-Message"))
+	return &StatusError{Code: code, Message: message}
+}
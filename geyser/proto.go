@@ -0,0 +1,782 @@
+package geyser
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mr-tron/base58"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+// SubscribeRequest is the SubscribeRequest message of Yellowstone, limited to the
+// transaction subscriptions.
+type SubscribeRequest struct {
+	// Transactions are the transaction filters by name. Updates carry the names of the
+	// filters they matched.
+	Transactions map[string]TransactionsFilter
+	// Commitment is the commitment level of the updates, the default of the server when
+	// unset.
+	Commitment chainstream.Commitment
+	// FromSlot, when not zero, replays the updates from that slot, within the retention of
+	// the server.
+	FromSlot uint64
+	// Ping, when not nil, asks the server for a pong with that id. Requests with a ping keep
+	// the filters of the subscription.
+	Ping *int32
+}
+
+// TransactionsFilter is the SubscribeRequestFilterTransactions message of Yellowstone.
+type TransactionsFilter struct {
+	// Vote and Failed, when not nil, select the vote or failed transactions only, or
+	// exclude them.
+	Vote   *bool
+	Failed *bool
+	// Signature selects a single transaction.
+	Signature string
+	// AccountInclude matches transactions with any of the accounts, AccountRequired with
+	// all of them, and AccountExclude drops transactions with any of them.
+	AccountInclude  []string
+	AccountExclude  []string
+	AccountRequired []string
+}
+
+// filterName is the name of the transaction filter of the subscriptions.
+const filterName = "zensol"
+
+// NewSubscribeRequest translates the params of a ChainStream transactionsSubscribe to a
+// Yellowstone subscription: ExcludeVotes to Vote, AccountKeys.OneOf to AccountInclude,
+// AccountKeys.All to AccountRequired and AccountKeys.Exclude to AccountExclude. Network
+// and Verified do not apply, the endpoint decides the network. An unset commitment is
+// chainstream.DefaultCommitment.
+func NewSubscribeRequest(params chainstream.TransactionSubscribeParams) SubscribeRequest {
+	commitment := params.Filter.Commitment
+	if commitment == 0 {
+		commitment = chainstream.DefaultCommitment
+	}
+	var filter TransactionsFilter
+	if params.Filter.ExcludeVotes {
+		vote := false
+		filter.Vote = &vote
+	}
+	if keys := params.Filter.AccountKeys; keys != nil {
+		filter.AccountInclude = keys.OneOf
+		filter.AccountRequired = keys.All
+		filter.AccountExclude = keys.Exclude
+	}
+	return SubscribeRequest{
+		Transactions: map[string]TransactionsFilter{filterName: filter},
+		Commitment:   commitment,
+	}
+}
+
+// Protobuf values of the CommitmentLevel enum of Yellowstone.
+const (
+	levelProcessed = 0
+	levelConfirmed = 1
+	levelFinalized = 2
+)
+
+// MarshalBinary encodes the request in the protobuf wire format.
+func (r *SubscribeRequest) MarshalBinary() ([]byte, error) {
+	var e encoder
+	for name, filter := range r.Transactions {
+		e.message(3, func(entry *encoder) {
+			entry.string(1, name)
+			entry.message(2, filter.encode)
+		})
+	}
+	switch r.Commitment {
+	case 0:
+	case chainstream.CommitmentProcessed:
+		e.optionalUint(6, levelProcessed)
+	case chainstream.CommitmentConfirmed:
+		e.optionalUint(6, levelConfirmed)
+	case chainstream.CommitmentFinalized:
+		e.optionalUint(6, levelFinalized)
+	default:
+		return nil, fmt.Errorf("geyser: unknown commitment %v", r.Commitment)
+	}
+	if r.Ping != nil {
+		e.message(9, func(ping *encoder) {
+			ping.uint(1, uint64(uint32(*r.Ping)))
+		})
+	}
+	if r.FromSlot != 0 {
+		e.optionalUint(11, r.FromSlot)
+	}
+	return e.buf, nil
+}
+
+func (f TransactionsFilter) encode(e *encoder) {
+	if f.Vote != nil {
+		e.optionalUint(1, boolValue(*f.Vote))
+	}
+	if f.Failed != nil {
+		e.optionalUint(2, boolValue(*f.Failed))
+	}
+	for _, key := range f.AccountInclude {
+		e.string(3, key)
+	}
+	for _, key := range f.AccountExclude {
+		e.string(4, key)
+	}
+	if f.Signature != "" {
+		e.string(5, f.Signature)
+	}
+	for _, key := range f.AccountRequired {
+		e.string(6, key)
+	}
+}
+
+func boolValue(v bool) uint64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// UnmarshalBinary decodes a request in the protobuf wire format. Filters other than the
+// transaction ones are ignored.
+func (r *SubscribeRequest) UnmarshalBinary(data []byte) error {
+	*r = SubscribeRequest{}
+	err := fields(data, func(f field) error {
+		switch f.number {
+		case 3:
+			var name string
+			var filter TransactionsFilter
+			err := fields(f.data, func(f field) error {
+				switch f.number {
+				case 1:
+					name = string(f.data)
+				case 2:
+					return filter.decode(f.data)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if r.Transactions == nil {
+				r.Transactions = make(map[string]TransactionsFilter)
+			}
+			r.Transactions[name] = filter
+		case 6:
+			switch f.n {
+			case levelProcessed:
+				r.Commitment = chainstream.CommitmentProcessed
+			case levelConfirmed:
+				r.Commitment = chainstream.CommitmentConfirmed
+			case levelFinalized:
+				r.Commitment = chainstream.CommitmentFinalized
+			default:
+				return fmt.Errorf("unknown commitment level %d", f.n)
+			}
+		case 9:
+			var id int32
+			err := fields(f.data, func(f field) error {
+				if f.number == 1 {
+					id = int32(f.n)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			r.Ping = &id
+		case 11:
+			r.FromSlot = f.n
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("geyser: cannot decode subscribe request: %w", err)
+	}
+	return nil
+}
+
+func (f *TransactionsFilter) decode(data []byte) error {
+	return fields(data, func(field field) error {
+		switch field.number {
+		case 1:
+			vote := field.n != 0
+			f.Vote = &vote
+		case 2:
+			failed := field.n != 0
+			f.Failed = &failed
+		case 3:
+			f.AccountInclude = append(f.AccountInclude, string(field.data))
+		case 4:
+			f.AccountExclude = append(f.AccountExclude, string(field.data))
+		case 5:
+			f.Signature = string(field.data)
+		case 6:
+			f.AccountRequired = append(f.AccountRequired, string(field.data))
+		}
+		return nil
+	})
+}
+
+// update is a decoded SubscribeUpdate message. Updates other than transactions, pings and
+// pongs are left empty.
+type update struct {
+	filters     []string
+	transaction *chainstream.TransactionNotification
+	ping        bool
+	pong        *int32
+}
+
+// decodeUpdate decodes a SubscribeUpdate message, reporting the transactions with the
+// commitment of the subscription as their slot status.
+func decodeUpdate(data []byte, commitment chainstream.Commitment) (update, error) {
+	var u update
+	var createdAt time.Time
+	err := fields(data, func(f field) error {
+		switch f.number {
+		case 1:
+			u.filters = append(u.filters, string(f.data))
+		case 4:
+			n, err := decodeTransactionUpdate(f.data)
+			if err != nil {
+				return err
+			}
+			u.transaction = n
+		case 6:
+			u.ping = true
+		case 9:
+			var id int32
+			err := fields(f.data, func(f field) error {
+				if f.number == 1 {
+					id = int32(f.n)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			u.pong = &id
+		case 11:
+			var seconds, nanos uint64
+			err := fields(f.data, func(f field) error {
+				switch f.number {
+				case 1:
+					seconds = f.n
+				case 2:
+					nanos = f.n
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			createdAt = time.Unix(int64(seconds), int64(nanos)).UTC()
+		}
+		return nil
+	})
+	if err != nil {
+		return update{}, fmt.Errorf("geyser: cannot decode update: %w", err)
+	}
+	if u.transaction != nil {
+		u.transaction.Params.Result.Context.SlotStatus = commitment.String()
+		u.transaction.Params.Result.Context.NodeTime = createdAt
+	}
+	return u, nil
+}
+
+// decodeTransactionUpdate decodes a SubscribeUpdateTransaction message.
+func decodeTransactionUpdate(data []byte) (*chainstream.TransactionNotification, error) {
+	n := &chainstream.TransactionNotification{JSONRPC: "2.0", Method: "transactionNotification"}
+	result := &n.Params.Result
+	result.Value.Transaction = chainstream.EncodedTransaction{
+		Signatures: []string{},
+		Message: chainstream.TransactionMessage{
+			AccountKeys:  []string{},
+			Instructions: []chainstream.CompiledInstruction{},
+		},
+	}
+	result.Value.Meta = chainstream.TransactionMeta{
+		Err:               json.RawMessage("null"),
+		InnerInstructions: []chainstream.InnerInstruction{},
+		LoadedAddresses:   chainstream.LoadedAddresses{Writable: []string{}, Readonly: []string{}},
+		LogMessages:       []string{},
+		PostBalances:      []uint64{},
+		PostTokenBalances: []chainstream.TokenBalance{},
+		PreBalances:       []uint64{},
+		PreTokenBalances:  []chainstream.TokenBalance{},
+	}
+	err := fields(data, func(f field) error {
+		switch f.number {
+		case 1:
+			return decodeTransactionInfo(f.data, n)
+		case 2:
+			result.Context.Slot = f.n
+			result.Value.Slot = f.n
+		}
+		return nil
+	})
+	return n, err
+}
+
+// decodeTransactionInfo decodes a SubscribeUpdateTransactionInfo message.
+func decodeTransactionInfo(data []byte, n *chainstream.TransactionNotification) error {
+	result := &n.Params.Result
+	return fields(data, func(f field) error {
+		switch f.number {
+		case 1:
+			result.Context.Signature = base58.Encode(f.data)
+		case 2:
+			result.Context.IsVote = f.n != 0
+		case 3:
+			return decodeTransaction(f.data, &result.Value)
+		case 4:
+			return decodeMeta(f.data, &result.Value.Meta)
+		case 5:
+			result.Context.Index = int(f.n)
+		}
+		return nil
+	})
+}
+
+// Versions of TransactionValue.Version.
+var (
+	versionLegacy = json.RawMessage(`"legacy"`)
+	version0      = json.RawMessage(`0`)
+)
+
+// decodeTransaction decodes a Transaction message into the transaction and the version of
+// the value.
+func decodeTransaction(data []byte, value *chainstream.TransactionValue) error {
+	tx := &value.Transaction
+	return fields(data, func(f field) error {
+		switch f.number {
+		case 1:
+			tx.Signatures = append(tx.Signatures, base58.Encode(f.data))
+		case 2:
+			versioned, err := decodeMessage(f.data, &tx.Message)
+			value.Version = versionLegacy
+			if versioned {
+				value.Version = version0
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// decodeMessage decodes a Message message and reports whether it is versioned.
+func decodeMessage(data []byte, m *chainstream.TransactionMessage) (bool, error) {
+	var versioned bool
+	err := fields(data, func(f field) error {
+		switch f.number {
+		case 1:
+			return fields(f.data, func(f field) error {
+				switch f.number {
+				case 1:
+					m.Header.NumSignatures = int(f.n)
+				case 2:
+					m.Header.NumReadonlySigned = int(f.n)
+				case 3:
+					m.Header.NumReadonlyUnsigned = int(f.n)
+				}
+				return nil
+			})
+		case 2:
+			m.AccountKeys = append(m.AccountKeys, base58.Encode(f.data))
+		case 3:
+			m.RecentBlockhash = base58.Encode(f.data)
+		case 4:
+			ix, err := decodeInstruction(f.data)
+			if err != nil {
+				return err
+			}
+			m.Instructions = append(m.Instructions, ix)
+		case 5:
+			versioned = f.n != 0
+		case 6:
+			var lookup chainstream.AddressTableLookup
+			err := fields(f.data, func(f field) error {
+				switch f.number {
+				case 1:
+					lookup.AccountKey = base58.Encode(f.data)
+				case 2:
+					lookup.WritableIndexes = indexes(f.data)
+				case 3:
+					lookup.ReadonlyIndexes = indexes(f.data)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if lookup.WritableIndexes == nil {
+				lookup.WritableIndexes = []int{}
+			}
+			if lookup.ReadonlyIndexes == nil {
+				lookup.ReadonlyIndexes = []int{}
+			}
+			m.AddressTableLookups = append(m.AddressTableLookups, lookup)
+		}
+		return nil
+	})
+	return versioned, err
+}
+
+// decodeInstruction decodes a CompiledInstruction or an InnerInstruction message, whose
+// stack height is dropped.
+func decodeInstruction(data []byte) (chainstream.CompiledInstruction, error) {
+	ix := chainstream.CompiledInstruction{Accounts: []int{}}
+	err := fields(data, func(f field) error {
+		switch f.number {
+		case 1:
+			ix.ProgramIDIndex = int(f.n)
+		case 2:
+			ix.Accounts = indexes(f.data)
+		case 3:
+			ix.Data = base58.Encode(f.data)
+		}
+		return nil
+	})
+	return ix, err
+}
+
+// indexes converts account indexes, encoded as bytes by Yellowstone.
+func indexes(data []byte) []int {
+	values := make([]int, len(data))
+	for i, b := range data {
+		values[i] = int(b)
+	}
+	return values
+}
+
+func decodeMeta(data []byte, meta *chainstream.TransactionMeta) error {
+	var innerNone, logsNone bool
+	err := fields(data, func(f field) error {
+		switch f.number {
+		case 1:
+			var err error
+			return fields(f.data, func(f field) error {
+				if f.number == 1 {
+					meta.Err, err = decodeTransactionError(f.data)
+				}
+				return err
+			})
+		case 2:
+			meta.Fee = f.n
+		case 3, 4:
+			values, err := f.varints()
+			if err != nil {
+				return err
+			}
+			if f.number == 3 {
+				meta.PreBalances = append(meta.PreBalances, values...)
+			} else {
+				meta.PostBalances = append(meta.PostBalances, values...)
+			}
+		case 5:
+			inner := chainstream.InnerInstruction{Instructions: []chainstream.CompiledInstruction{}}
+			err := fields(f.data, func(f field) error {
+				switch f.number {
+				case 1:
+					inner.Index = int(f.n)
+				case 2:
+					ix, err := decodeInstruction(f.data)
+					if err != nil {
+						return err
+					}
+					inner.Instructions = append(inner.Instructions, ix)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			meta.InnerInstructions = append(meta.InnerInstructions, inner)
+		case 6:
+			meta.LogMessages = append(meta.LogMessages, string(f.data))
+		case 7, 8:
+			balance, err := decodeTokenBalance(f.data)
+			if err != nil {
+				return err
+			}
+			if f.number == 7 {
+				meta.PreTokenBalances = append(meta.PreTokenBalances, balance)
+			} else {
+				meta.PostTokenBalances = append(meta.PostTokenBalances, balance)
+			}
+		case 10:
+			innerNone = f.n != 0
+		case 11:
+			logsNone = f.n != 0
+		case 12:
+			meta.LoadedAddresses.Writable = append(meta.LoadedAddresses.Writable, base58.Encode(f.data))
+		case 13:
+			meta.LoadedAddresses.Readonly = append(meta.LoadedAddresses.Readonly, base58.Encode(f.data))
+		case 16:
+			units := f.n
+			meta.ComputeUnitsConsumed = &units
+		}
+		return nil
+	})
+	if innerNone {
+		meta.InnerInstructions = nil
+	}
+	if logsNone {
+		meta.LogMessages = nil
+	}
+	return err
+}
+
+func decodeTokenBalance(data []byte) (chainstream.TokenBalance, error) {
+	var balance chainstream.TokenBalance
+	err := fields(data, func(f field) error {
+		switch f.number {
+		case 1:
+			balance.AccountIndex = int(f.n)
+		case 2:
+			balance.Mint = string(f.data)
+		case 3:
+			return fields(f.data, func(f field) error {
+				amount := &balance.UIAmount
+				switch f.number {
+				case 1:
+					amount.UIAmount = math.Float64frombits(f.n)
+				case 2:
+					amount.Decimals = int(f.n)
+				case 3:
+					amount.Amount = string(f.data)
+				case 4:
+					amount.UIAmountString = string(f.data)
+				}
+				return nil
+			})
+		case 4:
+			balance.Owner = string(f.data)
+		case 5:
+			balance.ProgramID = string(f.data)
+		}
+		return nil
+	})
+	return balance, err
+}
+
+// MarshalTransactionUpdate encodes the notification as a SubscribeUpdate message matching
+// the filters, the inverse of the mapping of the client. It serves recorded notifications
+// from test servers and relays. The message hash, block time and rewards are not part of
+// the message and are dropped.
+func MarshalTransactionUpdate(n *chainstream.TransactionNotification, filters ...string) ([]byte, error) {
+	value := &n.Params.Result.Value
+	signature, err := decodeKey(n.Signature())
+	if err != nil {
+		return nil, err
+	}
+	var txErr []byte
+	if !n.Succeeded() {
+		if txErr, err = encodeTransactionError(value.Meta.Err); err != nil {
+			return nil, err
+		}
+	}
+	tx, err := encodeTransaction(value)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := encodeMeta(&value.Meta, txErr)
+	if err != nil {
+		return nil, err
+	}
+
+	var e encoder
+	for _, filter := range filters {
+		e.string(1, filter)
+	}
+	e.message(4, func(update *encoder) {
+		update.message(1, func(info *encoder) {
+			info.bytes(1, signature)
+			info.bool(2, n.Params.Result.Context.IsVote)
+			info.message(3, embed(tx))
+			info.message(4, embed(meta))
+			info.uint(5, uint64(n.Params.Result.Context.Index))
+		})
+		update.uint(2, value.Slot)
+	})
+	if nodeTime := n.Params.Result.Context.NodeTime; !nodeTime.IsZero() {
+		e.message(11, func(ts *encoder) {
+			ts.uint(1, uint64(nodeTime.Unix()))
+			ts.uint(2, uint64(nodeTime.Nanosecond()))
+		})
+	}
+	return e.buf, nil
+}
+
+// decodeKey decodes a base58 key, signature or instruction data, empty when key is.
+func decodeKey(key string) ([]byte, error) {
+	if key == "" {
+		return nil, nil
+	}
+	data, err := base58.Decode(key)
+	if err != nil {
+		return nil, fmt.Errorf("geyser: invalid base58 %q: %w", key, err)
+	}
+	return data, nil
+}
+
+func encodeTransaction(value *chainstream.TransactionValue) ([]byte, error) {
+	tx := &value.Transaction
+	var e encoder
+	for _, signature := range tx.Signatures {
+		data, err := decodeKey(signature)
+		if err != nil {
+			return nil, err
+		}
+		e.bytes(1, data)
+	}
+	m := &tx.Message
+	var message encoder
+	message.message(1, func(header *encoder) {
+		header.uint(1, uint64(m.Header.NumSignatures))
+		header.uint(2, uint64(m.Header.NumReadonlySigned))
+		header.uint(3, uint64(m.Header.NumReadonlyUnsigned))
+	})
+	for _, key := range m.AccountKeys {
+		data, err := decodeKey(key)
+		if err != nil {
+			return nil, err
+		}
+		message.bytes(2, data)
+	}
+	blockhash, err := decodeKey(m.RecentBlockhash)
+	if err != nil {
+		return nil, err
+	}
+	message.bytes(3, blockhash)
+	for _, ix := range m.Instructions {
+		data, err := encodeInstruction(ix)
+		if err != nil {
+			return nil, err
+		}
+		message.message(4, embed(data))
+	}
+	message.bool(5, versioned(value))
+	for _, lookup := range m.AddressTableLookups {
+		key, err := decodeKey(lookup.AccountKey)
+		if err != nil {
+			return nil, err
+		}
+		message.message(6, func(l *encoder) {
+			l.bytes(1, key)
+			l.bytes(2, indexBytes(lookup.WritableIndexes))
+			l.bytes(3, indexBytes(lookup.ReadonlyIndexes))
+		})
+	}
+	e.message(2, embed(message.buf))
+	return e.buf, nil
+}
+
+// versioned reports whether the message of the value is versioned, taking messages with
+// address table lookups as versioned when the version is not reported.
+func versioned(value *chainstream.TransactionValue) bool {
+	if len(value.Version) == 0 {
+		return len(value.Transaction.Message.AddressTableLookups) > 0
+	}
+	return string(value.Version) != string(versionLegacy)
+}
+
+func encodeInstruction(ix chainstream.CompiledInstruction) ([]byte, error) {
+	data, err := decodeKey(ix.Data)
+	if err != nil {
+		return nil, err
+	}
+	var e encoder
+	e.uint(1, uint64(ix.ProgramIDIndex))
+	e.bytes(2, indexBytes(ix.Accounts))
+	e.bytes(3, data)
+	return e.buf, nil
+}
+
+func indexBytes(indexes []int) []byte {
+	data := make([]byte, len(indexes))
+	for i, index := range indexes {
+		data[i] = byte(index)
+	}
+	return data
+}
+
+func encodeMeta(meta *chainstream.TransactionMeta, txErr []byte) ([]byte, error) {
+	var e encoder
+	if txErr != nil {
+		e.message(1, func(err *encoder) {
+			err.bytes(1, txErr)
+		})
+	}
+	e.uint(2, meta.Fee)
+	e.packed(3, meta.PreBalances)
+	e.packed(4, meta.PostBalances)
+	for _, inner := range meta.InnerInstructions {
+		var instructions [][]byte
+		for _, ix := range inner.Instructions {
+			data, err := encodeInstruction(ix)
+			if err != nil {
+				return nil, err
+			}
+			instructions = append(instructions, data)
+		}
+		e.message(5, func(i *encoder) {
+			i.uint(1, uint64(inner.Index))
+			for _, data := range instructions {
+				i.message(2, embed(data))
+			}
+		})
+	}
+	for _, line := range meta.LogMessages {
+		e.string(6, line)
+	}
+	for _, balance := range meta.PreTokenBalances {
+		e.message(7, encodeTokenBalance(balance))
+	}
+	for _, balance := range meta.PostTokenBalances {
+		e.message(8, encodeTokenBalance(balance))
+	}
+	e.bool(10, meta.InnerInstructions == nil)
+	e.bool(11, meta.LogMessages == nil)
+	for _, key := range meta.LoadedAddresses.Writable {
+		data, err := decodeKey(key)
+		if err != nil {
+			return nil, err
+		}
+		e.bytes(12, data)
+	}
+	for _, key := range meta.LoadedAddresses.Readonly {
+		data, err := decodeKey(key)
+		if err != nil {
+			return nil, err
+		}
+		e.bytes(13, data)
+	}
+	if meta.ComputeUnitsConsumed != nil {
+		e.optionalUint(16, *meta.ComputeUnitsConsumed)
+	}
+	return e.buf, nil
+}
+
+func encodeTokenBalance(balance chainstream.TokenBalance) func(e *encoder) {
+	return func(e *encoder) {
+		e.uint(1, uint64(balance.AccountIndex))
+		e.string(2, balance.Mint)
+		e.message(3, func(amount *encoder) {
+			amount.double(1, balance.UIAmount.UIAmount)
+			amount.uint(2, uint64(balance.UIAmount.Decimals))
+			amount.string(3, balance.UIAmount.Amount)
+			amount.string(4, balance.UIAmount.UIAmountString)
+		})
+		e.string(4, balance.Owner)
+		e.string(5, balance.ProgramID)
+	}
+}
+
+// embed encodes a message encoded beforehand.
+func embed(data []byte) func(e *encoder) {
+	return func(e *encoder) {
+		e.buf = append(e.buf, data...)
+	}
+}
@@ -0,0 +1,312 @@
+package geyser
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// transactionErrors are the variants of the TransactionError of Solana, in the order of
+// their bincode tags.
+var transactionErrors = []string{
+	"AccountInUse",
+	"AccountLoadedTwice",
+	"AccountNotFound",
+	"ProgramAccountNotFound",
+	"InsufficientFundsForFee",
+	"InvalidAccountForFee",
+	"AlreadyProcessed",
+	"BlockhashNotFound",
+	"InstructionError",
+	"CallChainTooDeep",
+	"MissingSignatureForFee",
+	"InvalidAccountIndex",
+	"SignatureFailure",
+	"InvalidProgramForExecution",
+	"SanitizeFailure",
+	"ClusterMaintenance",
+	"AccountBorrowOutstanding",
+	"WouldExceedMaxBlockCostLimit",
+	"UnsupportedVersion",
+	"InvalidWritableAccount",
+	"WouldExceedMaxAccountCostLimit",
+	"WouldExceedAccountDataBlockLimit",
+	"TooManyAccountLocks",
+	"AddressLookupTableNotFound",
+	"InvalidAddressLookupTableOwner",
+	"InvalidAddressLookupTableData",
+	"InvalidAddressLookupTableIndex",
+	"InvalidRentPayingAccount",
+	"WouldExceedMaxVoteCostLimit",
+	"WouldExceedAccountDataTotalLimit",
+	"DuplicateInstruction",
+	"InsufficientFundsForRent",
+	"MaxLoadedAccountsDataSizeExceeded",
+	"InvalidLoadedAccountsDataSizeLimit",
+	"ResanitizationNeeded",
+	"ProgramExecutionTemporarilyRestricted",
+	"UnbalancedTransaction",
+	"ProgramCacheHitMaxLimit",
+	"CommitCancelled",
+}
+
+// instructionErrors are the variants of the InstructionError of Solana, in the order of
+// their bincode tags.
+var instructionErrors = []string{
+	"GenericError",
+	"InvalidArgument",
+	"InvalidInstructionData",
+	"InvalidAccountData",
+	"AccountDataTooSmall",
+	"InsufficientFunds",
+	"IncorrectProgramId",
+	"MissingRequiredSignature",
+	"AccountAlreadyInitialized",
+	"UninitializedAccount",
+	"UnbalancedInstruction",
+	"ModifiedProgramId",
+	"ExternalAccountLamportSpend",
+	"ExternalAccountDataModified",
+	"ReadonlyLamportChange",
+	"ReadonlyDataModified",
+	"DuplicateAccountIndex",
+	"ExecutableModified",
+	"RentEpochModified",
+	"NotEnoughAccountKeys",
+	"AccountDataSizeChanged",
+	"AccountNotExecutable",
+	"AccountBorrowFailed",
+	"AccountBorrowOutstanding",
+	"DuplicateAccountOutOfSync",
+	"Custom",
+	"InvalidError",
+	"ExecutableDataModified",
+	"ExecutableLamportChange",
+	"ExecutableAccountNotRentExempt",
+	"UnsupportedProgramId",
+	"CallDepth",
+	"MissingAccount",
+	"ReentrancyNotAllowed",
+	"MaxSeedLengthExceeded",
+	"InvalidSeeds",
+	"InvalidRealloc",
+	"ComputationalBudgetExceeded",
+	"PrivilegeEscalation",
+	"ProgramEnvironmentSetupFailure",
+	"ProgramFailedToComplete",
+	"ProgramFailedToCompile",
+	"Immutable",
+	"IncorrectAuthority",
+	"BorshIoError",
+	"AccountNotRentExempt",
+	"InvalidAccountOwner",
+	"ArithmeticOverflow",
+	"UnsupportedSysvar",
+	"IllegalOwner",
+	"MaxAccountsDataAllocationsExceeded",
+	"MaxAccountsExceeded",
+	"MaxInstructionTraceLengthExceeded",
+	"BuiltinProgramsMustConsumeComputeUnits",
+}
+
+// Variants of TransactionError and InstructionError holding values.
+const (
+	instructionErrorTag      = 8
+	duplicateInstructionTag  = 30
+	insufficientRentTag      = 31
+	temporarilyRestrictedTag = 35
+	customTag                = 25
+	borshIoErrorTag          = 44
+)
+
+var errBincode = errors.New("geyser: truncated transaction error")
+
+// bincodeReader reads the bincode encoding of a transaction error.
+type bincodeReader []byte
+
+func (r *bincodeReader) u8() (uint8, error) {
+	if len(*r) < 1 {
+		return 0, errBincode
+	}
+	v := (*r)[0]
+	*r = (*r)[1:]
+	return v, nil
+}
+
+func (r *bincodeReader) u32() (uint32, error) {
+	if len(*r) < 4 {
+		return 0, errBincode
+	}
+	v := binary.LittleEndian.Uint32(*r)
+	*r = (*r)[4:]
+	return v, nil
+}
+
+func (r *bincodeReader) string() (string, error) {
+	if len(*r) < 8 {
+		return "", errBincode
+	}
+	size := binary.LittleEndian.Uint64(*r)
+	*r = (*r)[8:]
+	if size > uint64(len(*r)) {
+		return "", errBincode
+	}
+	s := string((*r)[:size])
+	*r = (*r)[size:]
+	return s, nil
+}
+
+// decodeTransactionError converts the bincode encoding of a TransactionError, as sent by
+// Yellowstone, to its JSON form in RPC responses, e.g. {"InstructionError":[2,{"Custom":1}]}.
+// Unknown variants become {"Unknown":tag}.
+func decodeTransactionError(data []byte) (json.RawMessage, error) {
+	r := bincodeReader(data)
+	tag, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	switch {
+	case tag == instructionErrorTag:
+		index, err := r.u8()
+		if err != nil {
+			return nil, err
+		}
+		inner, err := decodeInstructionError(&r)
+		if err != nil {
+			return nil, err
+		}
+		value = map[string]interface{}{"InstructionError": []interface{}{index, inner}}
+	case tag == duplicateInstructionTag:
+		index, err := r.u8()
+		if err != nil {
+			return nil, err
+		}
+		value = map[string]interface{}{transactionErrors[tag]: index}
+	case tag == insufficientRentTag || tag == temporarilyRestrictedTag:
+		index, err := r.u8()
+		if err != nil {
+			return nil, err
+		}
+		value = map[string]interface{}{transactionErrors[tag]: map[string]uint8{"account_index": index}}
+	case int(tag) < len(transactionErrors):
+		value = transactionErrors[tag]
+	default:
+		value = map[string]uint32{"Unknown": tag}
+	}
+	return json.Marshal(value)
+}
+
+func decodeInstructionError(r *bincodeReader) (interface{}, error) {
+	tag, err := r.u32()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case tag == customTag:
+		code, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]uint32{"Custom": code}, nil
+	case tag == borshIoErrorTag:
+		message, err := r.string()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"BorshIoError": message}, nil
+	case int(tag) < len(instructionErrors):
+		return instructionErrors[tag], nil
+	}
+	return map[string]uint32{"Unknown": tag}, nil
+}
+
+// encodeTransactionError converts the JSON form of a transaction error to its bincode
+// encoding, see decodeTransactionError.
+func encodeTransactionError(err json.RawMessage) ([]byte, error) {
+	var name string
+	if json.Unmarshal(err, &name) == nil {
+		tag, ok := indexOf(transactionErrors, name)
+		if !ok {
+			return nil, fmt.Errorf("geyser: unknown transaction error %q", name)
+		}
+		return binary.LittleEndian.AppendUint32(nil, tag), nil
+	}
+	var variant map[string]json.RawMessage
+	if e := json.Unmarshal(err, &variant); e != nil || len(variant) != 1 {
+		return nil, fmt.Errorf("geyser: cannot encode transaction error %s", err)
+	}
+	for name, value := range variant {
+		tag, ok := indexOf(transactionErrors, name)
+		if !ok {
+			return nil, fmt.Errorf("geyser: unknown transaction error %q", name)
+		}
+		data := binary.LittleEndian.AppendUint32(nil, tag)
+		switch tag {
+		case instructionErrorTag:
+			var pair []json.RawMessage
+			var index uint8
+			if e := json.Unmarshal(value, &pair); e != nil || len(pair) != 2 || json.Unmarshal(pair[0], &index) != nil {
+				return nil, fmt.Errorf("geyser: cannot encode instruction error %s", value)
+			}
+			inner, e := encodeInstructionError(pair[1])
+			if e != nil {
+				return nil, e
+			}
+			return append(append(data, index), inner...), nil
+		case duplicateInstructionTag:
+			var index uint8
+			if e := json.Unmarshal(value, &index); e != nil {
+				return nil, fmt.Errorf("geyser: cannot encode %s: %w", name, e)
+			}
+			return append(data, index), nil
+		case insufficientRentTag, temporarilyRestrictedTag:
+			var account struct {
+				Index uint8 `json:"account_index"`
+			}
+			if e := json.Unmarshal(value, &account); e != nil {
+				return nil, fmt.Errorf("geyser: cannot encode %s: %w", name, e)
+			}
+			return append(data, account.Index), nil
+		}
+		return nil, fmt.Errorf("geyser: transaction error %q holds no value", name)
+	}
+	return nil, nil
+}
+
+func encodeInstructionError(err json.RawMessage) ([]byte, error) {
+	var name string
+	if json.Unmarshal(err, &name) == nil {
+		tag, ok := indexOf(instructionErrors, name)
+		if !ok {
+			return nil, fmt.Errorf("geyser: unknown instruction error %q", name)
+		}
+		return binary.LittleEndian.AppendUint32(nil, tag), nil
+	}
+	var variant struct {
+		Custom       *uint32 `json:"Custom"`
+		BorshIoError *string `json:"BorshIoError"`
+	}
+	if e := json.Unmarshal(err, &variant); e != nil {
+		return nil, fmt.Errorf("geyser: cannot encode instruction error %s", err)
+	}
+	switch {
+	case variant.Custom != nil:
+		return binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, customTag), *variant.Custom), nil
+	case variant.BorshIoError != nil:
+		data := binary.LittleEndian.AppendUint32(nil, borshIoErrorTag)
+		data = binary.LittleEndian.AppendUint64(data, uint64(len(*variant.BorshIoError)))
+		return append(data, *variant.BorshIoError...), nil
+	}
+	return nil, fmt.Errorf("geyser: unknown instruction error %s", err)
+}
+
+func indexOf(names []string, name string) (uint32, bool) {
+	for i, n := range names {
+		if n == name {
+			return uint32(i), true
+		}
+	}
+	return 0, false
+}
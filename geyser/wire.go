@@ -0,0 +1,182 @@
+package geyser
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("geyser: truncated message")
+
+// encoder appends protobuf fields to a buffer.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) tag(field int, wireType int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(field)<<3|uint64(wireType))
+}
+
+// uint encodes a varint field, omitted when zero like proto3 scalars.
+func (e *encoder) uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+// optionalUint encodes a varint field even when zero, for proto3 optional fields.
+func (e *encoder) optionalUint(field int, v uint64) {
+	e.tag(field, wireVarint)
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *encoder) bool(field int, v bool) {
+	if v {
+		e.uint(field, 1)
+	}
+}
+
+func (e *encoder) double(field int, v float64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireFixed64)
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+// bytes encodes a length-delimited field, omitted when empty.
+func (e *encoder) bytes(field int, v []byte) {
+	if len(v) == 0 {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(v)))
+	e.buf = append(e.buf, v...)
+}
+
+func (e *encoder) string(field int, v string) {
+	e.bytes(field, []byte(v))
+}
+
+// message encodes a nested message, even when empty so that its presence is kept.
+func (e *encoder) message(field int, encode func(e *encoder)) {
+	var nested encoder
+	encode(&nested)
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(nested.buf)))
+	e.buf = append(e.buf, nested.buf...)
+}
+
+// packed encodes a packed repeated varint field.
+func (e *encoder) packed(field int, values []uint64) {
+	if len(values) == 0 {
+		return
+	}
+	var packed []byte
+	for _, v := range values {
+		packed = binary.AppendUvarint(packed, v)
+	}
+	e.bytes(field, packed)
+}
+
+// field is a decoded protobuf field: a varint or fixed value in n, the payload of
+// length-delimited fields in data.
+type field struct {
+	number   int
+	wireType int
+	n        uint64
+	data     []byte
+}
+
+// decoder reads the fields of a message.
+type decoder struct {
+	data []byte
+}
+
+// next reads the next field. It reports false at the end of the message.
+func (d *decoder) next() (field, bool, error) {
+	if len(d.data) == 0 {
+		return field{}, false, nil
+	}
+	key, err := d.varint()
+	if err != nil {
+		return field{}, false, err
+	}
+	f := field{number: int(key >> 3), wireType: int(key & 7)}
+	switch f.wireType {
+	case wireVarint:
+		f.n, err = d.varint()
+	case wireFixed64:
+		if len(d.data) < 8 {
+			return f, false, errTruncated
+		}
+		f.n, d.data = binary.LittleEndian.Uint64(d.data), d.data[8:]
+	case wireFixed32:
+		if len(d.data) < 4 {
+			return f, false, errTruncated
+		}
+		f.n, d.data = uint64(binary.LittleEndian.Uint32(d.data)), d.data[4:]
+	case wireBytes:
+		var size uint64
+		if size, err = d.varint(); err == nil {
+			if size > uint64(len(d.data)) {
+				return f, false, errTruncated
+			}
+			f.data, d.data = d.data[:size], d.data[size:]
+		}
+	default:
+		return f, false, fmt.Errorf("geyser: unsupported wire type %d of field %d", f.wireType, f.number)
+	}
+	return f, err == nil, err
+}
+
+func (d *decoder) varint() (uint64, error) {
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	d.data = d.data[n:]
+	return v, nil
+}
+
+// fields calls do with every field of the message.
+func fields(data []byte, do func(f field) error) error {
+	d := decoder{data: data}
+	for {
+		f, ok, err := d.next()
+		if err != nil || !ok {
+			return err
+		}
+		if err = do(f); err != nil {
+			return fmt.Errorf("field %d: %w", f.number, err)
+		}
+	}
+}
+
+// varints returns the values of a repeated varint field, packed or not.
+func (f field) varints() ([]uint64, error) {
+	if f.wireType == wireVarint {
+		return []uint64{f.n}, nil
+	}
+	var values []uint64
+	d := decoder{data: f.data}
+	for len(d.data) > 0 {
+		v, err := d.varint()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
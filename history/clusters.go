@@ -0,0 +1,168 @@
+package history
+
+import (
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/internal/lru"
+)
+
+// Defaults of ClusterConfig.
+const (
+	DefaultClusterMinWallets = 3
+	DefaultClusterMaxFunded  = 100000
+)
+
+// ClusterConfig controls the detection of wallet clusters.
+type ClusterConfig struct {
+	// Window is the longest time between the first and the last transaction of a cluster
+	// on a mint. Zero uses DefaultCorrelationWindow.
+	Window time.Duration
+	// MinWallets is the smallest number of wallets of a common funder reported as a
+	// cluster. Zero uses DefaultClusterMinWallets.
+	MinWallets int
+	// MinFunding is the smallest SOL transfer, in lamports, taken as the funding of a wallet.
+	MinFunding uint64
+	// MaxFunded is the number of funded wallets remembered, the least recently funded
+	// are forgotten first. Zero uses DefaultClusterMaxFunded.
+	MaxFunded int
+	// AgeSource selects the timestamp of transactions.
+	AgeSource chainstream.AgeSource
+}
+
+// ClusterEvent reports wallets funded from a common source acting on the same mint within
+// the window, as bundlers and sybil wallets of a launch do.
+type ClusterEvent struct {
+	Mint   string
+	Funder string
+	// Wallets are the signers funded by Funder, in the order they first acted on the mint.
+	Wallets      []string
+	Transactions []*chainstream.TransactionNotification
+	First        time.Time
+	Last         time.Time
+}
+
+// ClusterDetector tracks which wallet funded which from SOL transfers and reports clusters
+// of wallets of a common funder trading a mint together. Funding transfers must be among
+// the added notifications, e.g. by subscribing to the wallets of interest. It is not safe
+// for concurrent use.
+type ClusterDetector struct {
+	config     ClusterConfig
+	correlator *Correlator
+	// funders maps a wallet to the wallet that first funded it.
+	funders *lru.Cache[string, string]
+}
+
+// NewClusterDetector returns a cluster detector.
+func NewClusterDetector(config ClusterConfig) *ClusterDetector {
+	if config.MinWallets <= 0 {
+		config.MinWallets = DefaultClusterMinWallets
+	}
+	if config.MaxFunded <= 0 {
+		config.MaxFunded = DefaultClusterMaxFunded
+	}
+	return &ClusterDetector{
+		config: config,
+		correlator: NewCorrelator(CorrelatorConfig{
+			Links:     []Link{LinkMint},
+			Window:    config.Window,
+			MinSize:   config.MinWallets,
+			AgeSource: config.AgeSource,
+		}),
+		funders: lru.New[string, string](config.MaxFunded),
+	}
+}
+
+// Funder returns the wallet that first funded the wallet.
+func (d *ClusterDetector) Funder(wallet string) (string, bool) {
+	return d.funders.Get(wallet)
+}
+
+// Add records the funding transfers of the notification, links it with the transactions
+// of its mints and returns the clusters found in the groups whose window has ended.
+func (d *ClusterDetector) Add(notification *chainstream.TransactionNotification) []ClusterEvent {
+	if notification.Succeeded() {
+		for _, transfer := range notification.Transfers() {
+			if transfer.Kind != chainstream.TransferSOL || transfer.From == transfer.To || transfer.Amount < d.config.MinFunding {
+				continue
+			}
+			if _, known := d.funders.Get(transfer.To); !known {
+				d.funders.Add(transfer.To, transfer.From)
+			}
+		}
+	}
+	return d.clusters(d.correlator.Add(notification))
+}
+
+// Shed forgets the least recently used fraction of the funders, see memguard.Shedder.
+func (d *ClusterDetector) Shed(fraction float64) int {
+	return d.funders.Shed(fraction)
+}
+
+// Flush returns the clusters of all open groups.
+func (d *ClusterDetector) Flush() []ClusterEvent {
+	return d.clusters(d.correlator.Flush())
+}
+
+// Record returns a middleware adding every handled notification to the detector and
+// passing the clusters it finds to emit. The middleware must not be called concurrently.
+func (d *ClusterDetector) Record(emit func(ClusterEvent)) chainstream.Middleware {
+	return func(next chainstream.HandlerFunc) chainstream.HandlerFunc {
+		return func(notification *chainstream.TransactionNotification) error {
+			for _, event := range d.Add(notification) {
+				emit(event)
+			}
+			return next(notification)
+		}
+	}
+}
+
+// clusters splits the mint groups by the funders of their signers.
+func (d *ClusterDetector) clusters(groups []CorrelationEvent) []ClusterEvent {
+	var events []ClusterEvent
+	for _, group := range groups {
+		byFunder := make(map[string]*ClusterEvent)
+		var order []string
+		for _, notification := range group.Transactions {
+			ts, hasTime := notification.Time(d.config.AgeSource)
+			for _, signer := range notification.Signers() {
+				funder, known := d.funders.Get(signer)
+				if !known {
+					continue
+				}
+				cluster, found := byFunder[funder]
+				if !found {
+					cluster = &ClusterEvent{Mint: group.Key, Funder: funder, First: group.First, Last: group.First}
+					byFunder[funder] = cluster
+					order = append(order, funder)
+				}
+				cluster.add(signer, notification, ts, hasTime)
+			}
+		}
+		for _, funder := range order {
+			if cluster := byFunder[funder]; len(cluster.Wallets) >= d.config.MinWallets {
+				events = append(events, *cluster)
+			}
+		}
+	}
+	return events
+}
+
+// add records the transaction of the wallet in the cluster.
+func (e *ClusterEvent) add(wallet string, notification *chainstream.TransactionNotification, ts time.Time, hasTime bool) {
+	if len(e.Transactions) == 0 && hasTime {
+		e.First = ts
+	}
+	if hasTime {
+		e.Last = ts
+	}
+	if n := len(e.Transactions); n == 0 || e.Transactions[n-1] != notification {
+		e.Transactions = append(e.Transactions, notification)
+	}
+	for _, w := range e.Wallets {
+		if w == wallet {
+			return
+		}
+	}
+	e.Wallets = append(e.Wallets, wallet)
+}
@@ -0,0 +1,63 @@
+package history_test
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/mr-tron/base58"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/history"
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+// funding returns a System transfer of lamports from the funder to the wallet.
+func funding(funder, wallet string, lamports uint64, offset time.Duration) *chainstream.TransactionNotification {
+	n := notification(1, funder, wallet, string(programs.System))
+	n.Params.Result.Context.NodeTime = t0.Add(offset)
+	n.Params.Result.Value.Transaction.Message.Header.NumSignatures = 1
+	data := make([]byte, 12)
+	binary.LittleEndian.PutUint32(data, 2)
+	binary.LittleEndian.PutUint64(data[4:], lamports)
+	n.Params.Result.Value.Transaction.Message.Instructions = []chainstream.CompiledInstruction{
+		{ProgramIDIndex: 2, Accounts: []int{0, 1}, Data: base58.Encode(data)},
+	}
+	return n
+}
+
+func TestClusterDetector(t *testing.T) {
+	d := history.NewClusterDetector(history.ClusterConfig{Window: 5 * time.Second, MinFunding: 1000})
+	for _, n := range []*chainstream.TransactionNotification{
+		funding("funder", "w1", 1_000_000, -time.Minute),
+		funding("funder", "w2", 1_000_000, -time.Minute),
+		funding("funder", "w3", 1_000_000, -time.Minute),
+		funding("other", "w4", 1_000_000, -time.Minute),
+		funding("funder", "w5", 10, -time.Minute),
+	} {
+		d.Add(n)
+	}
+	if funder, ok := d.Funder("w1"); !ok || funder != "funder" {
+		t.Fatalf("Funder(w1) = %q, %v", funder, ok)
+	}
+	if _, ok := d.Funder("w5"); ok {
+		t.Error("a transfer below MinFunding was taken as funding")
+	}
+
+	for i, wallet := range []string{"w1", "w4", "w2", "w5", "w3"} {
+		if events := d.Add(trade(wallet, "mint", time.Duration(i)*time.Second)); len(events) != 0 {
+			t.Fatalf("Add() reported %d clusters inside the window", len(events))
+		}
+	}
+	events := d.Flush()
+	if len(events) != 1 {
+		t.Fatalf("Flush() = %d clusters, expected 1", len(events))
+	}
+	cluster := events[0]
+	if cluster.Mint != "mint" || cluster.Funder != "funder" || len(cluster.Wallets) != 3 || len(cluster.Transactions) != 3 {
+		t.Errorf("cluster = %s funded by %s with wallets %v", cluster.Mint, cluster.Funder, cluster.Wallets)
+	}
+	if cluster.Last.Sub(cluster.First) != 4*time.Second {
+		t.Errorf("cluster spans %s, expected 4s", cluster.Last.Sub(cluster.First))
+	}
+}
@@ -0,0 +1,208 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+// Link is the kind of key shared by correlated transactions.
+type Link int
+
+const (
+	// LinkSigner links transactions signed by the same account.
+	LinkSigner Link = iota
+	// LinkMint links transactions changing balances of the same token.
+	LinkMint
+	// LinkAccount links transactions referencing the same account.
+	LinkAccount
+)
+
+func (l Link) String() string {
+	switch l {
+	case LinkSigner:
+		return "signer"
+	case LinkMint:
+		return "mint"
+	case LinkAccount:
+		return "account"
+	}
+	return fmt.Sprintf("Link(%d)", int(l))
+}
+
+// DefaultCorrelationWindow is the default time between the first and the last
+// transaction of a correlation group.
+const DefaultCorrelationWindow = 10 * time.Second
+
+// CorrelatorConfig controls how transactions are linked.
+type CorrelatorConfig struct {
+	// Links are the kinds of keys linking transactions. Nil links by signer and mint.
+	Links []Link
+	// Window is the longest time from the first transaction of a group to the last one.
+	// Zero uses DefaultCorrelationWindow.
+	Window time.Duration
+	// MinSize is the smallest group reported. Values below 2 report every group of at
+	// least two transactions.
+	MinSize int
+	// AgeSource selects the timestamp of transactions.
+	AgeSource chainstream.AgeSource
+	// Ignore reports keys that link too many transactions to be meaningful. Nil ignores
+	// known programs, Jito tip accounts, sysvars and wrapped SOL.
+	Ignore func(key string) bool
+}
+
+// CorrelationEvent is a group of transactions sharing a key within the window, in the
+// order they were added; e.g. a token creation followed by the first buys and the
+// liquidity deposit of the same mint.
+type CorrelationEvent struct {
+	Link         Link
+	Key          string
+	First        time.Time
+	Last         time.Time
+	Transactions []*chainstream.TransactionNotification
+}
+
+type groupKey struct {
+	link Link
+	key  string
+}
+
+// Correlator groups transactions sharing signers, mints or accounts within a time window.
+// It is driven by notification timestamps, so it gives the same groups when a recording
+// is replayed. It is not safe for concurrent use.
+type Correlator struct {
+	config CorrelatorConfig
+	now    time.Time
+	open   map[groupKey]*CorrelationEvent
+	// order lists the open groups by their first transaction, oldest first.
+	order []*CorrelationEvent
+}
+
+// NewCorrelator returns a correlator.
+func NewCorrelator(config CorrelatorConfig) *Correlator {
+	if config.Links == nil {
+		config.Links = []Link{LinkSigner, LinkMint}
+	}
+	if config.Window <= 0 {
+		config.Window = DefaultCorrelationWindow
+	}
+	if config.MinSize < 2 {
+		config.MinSize = 2
+	}
+	if config.Ignore == nil {
+		config.Ignore = ignoreCommon
+	}
+	return &Correlator{config: config, open: make(map[groupKey]*CorrelationEvent)}
+}
+
+// Add links the transaction with the open groups and returns the groups whose window has
+// ended, oldest first. Transactions without a timestamp are placed at the time of the
+// latest transaction.
+func (c *Correlator) Add(notification *chainstream.TransactionNotification) []CorrelationEvent {
+	if ts, ok := notification.Time(c.config.AgeSource); ok && ts.After(c.now) {
+		c.now = ts
+	}
+	events := c.expire(c.now.Add(-c.config.Window))
+
+	for _, key := range c.keys(notification) {
+		g, ok := c.open[key]
+		if !ok {
+			g = &CorrelationEvent{Link: key.link, Key: key.key, First: c.now}
+			c.open[key] = g
+			c.order = append(c.order, g)
+		}
+		g.Last = c.now
+		g.Transactions = append(g.Transactions, notification)
+	}
+	return events
+}
+
+// Flush closes all open groups and returns the ones reaching MinSize, oldest first.
+func (c *Correlator) Flush() []CorrelationEvent {
+	var events []CorrelationEvent
+	for _, g := range c.order {
+		if len(g.Transactions) >= c.config.MinSize {
+			events = append(events, *g)
+		}
+	}
+	c.open = make(map[groupKey]*CorrelationEvent)
+	c.order = nil
+	return events
+}
+
+// Record returns a middleware adding every handled notification to the correlator and
+// passing the groups it closes to emit. The middleware must not be called concurrently.
+func (c *Correlator) Record(emit func(CorrelationEvent)) chainstream.Middleware {
+	return func(next chainstream.HandlerFunc) chainstream.HandlerFunc {
+		return func(notification *chainstream.TransactionNotification) error {
+			for _, event := range c.Add(notification) {
+				emit(event)
+			}
+			return next(notification)
+		}
+	}
+}
+
+// expire closes the groups started before the cutoff.
+func (c *Correlator) expire(cutoff time.Time) []CorrelationEvent {
+	var events []CorrelationEvent
+	n := 0
+	for _, g := range c.order {
+		if !g.First.Before(cutoff) {
+			break
+		}
+		n++
+		delete(c.open, groupKey{g.Link, g.Key})
+		if len(g.Transactions) >= c.config.MinSize {
+			events = append(events, *g)
+		}
+	}
+	c.order = c.order[n:]
+	return events
+}
+
+// keys returns the distinct keys of the transaction for the configured links.
+func (c *Correlator) keys(notification *chainstream.TransactionNotification) []groupKey {
+	var keys []groupKey
+	seen := make(map[groupKey]struct{})
+	add := func(link Link, key string) {
+		k := groupKey{link, key}
+		if _, ok := seen[k]; ok || key == "" || c.config.Ignore(key) {
+			return
+		}
+		seen[k] = struct{}{}
+		keys = append(keys, k)
+	}
+
+	for _, link := range c.config.Links {
+		switch link {
+		case LinkSigner:
+			for _, signer := range notification.Signers() {
+				add(link, signer)
+			}
+		case LinkMint:
+			meta := &notification.Params.Result.Value.Meta
+			for _, balances := range [][]chainstream.TokenBalance{meta.PreTokenBalances, meta.PostTokenBalances} {
+				for _, balance := range balances {
+					add(link, balance.Mint)
+				}
+			}
+		case LinkAccount:
+			for _, account := range notification.AccountKeys() {
+				add(link, account)
+			}
+		}
+	}
+	return keys
+}
+
+// ignoreCommon reports keys shared by unrelated transactions.
+func ignoreCommon(key string) bool {
+	if _, known := programs.Name(key); known {
+		return true
+	}
+	return programs.IsJitoTip(key) || key == chainstream.WrappedSOLMint || strings.HasPrefix(key, "Sysvar")
+}
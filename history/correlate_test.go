@@ -0,0 +1,82 @@
+package history_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/history"
+)
+
+var t0 = time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+
+// trade returns a transaction of the signer changing a balance of the mint at t0+offset.
+func trade(signer, mint string, offset time.Duration) *chainstream.TransactionNotification {
+	n := notification(1, signer)
+	n.Params.Result.Context.NodeTime = t0.Add(offset)
+	n.Params.Result.Value.Meta.PostTokenBalances = []chainstream.TokenBalance{{Owner: signer, Mint: mint}}
+	return n
+}
+
+func TestCorrelator(t *testing.T) {
+	c := history.NewCorrelator(history.CorrelatorConfig{Window: 5 * time.Second})
+
+	create := trade("creator", "mint", 0)
+	buys := []*chainstream.TransactionNotification{
+		trade("buyer1", "mint", time.Second),
+		trade("buyer2", "mint", 2*time.Second),
+		trade("buyer1", "other", 3*time.Second),
+	}
+	for _, n := range append([]*chainstream.TransactionNotification{create}, buys...) {
+		if events := c.Add(n); len(events) != 0 {
+			t.Fatalf("Add() closed %d groups inside the window", len(events))
+		}
+	}
+
+	// Six seconds after the creation the mint group is closed; the group of buyer1 started
+	// at one second and is still open.
+	events := c.Add(trade("late", "late-mint", 6*time.Second))
+	if len(events) != 1 {
+		t.Fatalf("Add() closed %d groups, expected the mint group", len(events))
+	}
+	group := events[0]
+	if group.Link != history.LinkMint || group.Key != "mint" || len(group.Transactions) != 3 || group.Transactions[0] != create {
+		t.Errorf("group = %s %s with %d transactions", group.Link, group.Key, len(group.Transactions))
+	}
+	if group.Last.Sub(group.First) != 2*time.Second {
+		t.Errorf("group spans %s, expected 2s", group.Last.Sub(group.First))
+	}
+
+	rest := c.Flush()
+	if len(rest) != 1 || rest[0].Link != history.LinkSigner || rest[0].Key != "buyer1" || len(rest[0].Transactions) != 2 {
+		t.Errorf("Flush() = %+v, expected the buyer1 group", rest)
+	}
+}
+
+func TestCorrelatorIgnoresCommonKeys(t *testing.T) {
+	c := history.NewCorrelator(history.CorrelatorConfig{Links: []history.Link{history.LinkMint}})
+	c.Add(trade("a", chainstream.WrappedSOLMint, 0))
+	c.Add(trade("b", chainstream.WrappedSOLMint, time.Second))
+	if events := c.Flush(); len(events) != 0 {
+		t.Errorf("Flush() = %d groups, expected wrapped SOL ignored", len(events))
+	}
+}
+
+func TestCorrelatorMiddleware(t *testing.T) {
+	c := history.NewCorrelator(history.CorrelatorConfig{Window: time.Second})
+	var events []history.CorrelationEvent
+	do := chainstream.Chain(func(*chainstream.TransactionNotification) error { return nil },
+		c.Record(func(e history.CorrelationEvent) { events = append(events, e) }))
+	for _, n := range []*chainstream.TransactionNotification{
+		trade("a", "mint", 0),
+		trade("b", "mint", 500*time.Millisecond),
+		trade("c", "x", 2*time.Second),
+	} {
+		if err := do(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(events) != 1 || events[0].Key != "mint" {
+		t.Errorf("emitted %+v, expected the mint group", events)
+	}
+}
@@ -0,0 +1,211 @@
+// Package history keeps recent notifications in memory so logic that runs after a
+// notification was handled, e.g. correlating a token launch with the buys that follow,
+// can look back without RPC calls.
+package history
+
+import (
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/memguard"
+)
+
+// bucket holds the notifications of one slot.
+type bucket struct {
+	slot          uint64
+	notifications []*chainstream.TransactionNotification
+}
+
+// Ring keeps the notifications of the last N slots, indexed by signature and account.
+// Slots are kept in a ring of N buckets: a notification of a new slot replaces the slot
+// N slots older, and the slots left behind by a jump of the latest slot, e.g. over
+// skipped slots, are dropped. It is safe for concurrent use.
+type Ring struct {
+	mu         sync.RWMutex
+	buckets    []bucket
+	latest     uint64
+	signatures map[string]*chainstream.TransactionNotification
+	// accounts holds the notifications of each account by slot, so that evicting a slot
+	// costs the notifications it held.
+	accounts map[string]map[uint64][]*chainstream.TransactionNotification
+}
+
+// NewRing returns a ring keeping the last slots slots.
+func NewRing(slots int) *Ring {
+	if slots < 1 {
+		slots = 1
+	}
+	return &Ring{
+		buckets:    make([]bucket, slots),
+		signatures: make(map[string]*chainstream.TransactionNotification),
+		accounts:   make(map[string]map[uint64][]*chainstream.TransactionNotification),
+	}
+}
+
+// Add records the notification and reports whether it was kept. Notifications older than
+// the last N slots and signatures already recorded are not kept.
+func (r *Ring) Add(notification *chainstream.TransactionNotification) bool {
+	slot := notification.Slot()
+	signature := notification.Signature()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tooOld(slot) {
+		return false
+	}
+	if _, ok := r.signatures[signature]; ok && signature != "" {
+		return false
+	}
+	if slot > r.latest {
+		r.latest = slot
+		r.evictOld()
+	}
+
+	b := &r.buckets[slot%uint64(len(r.buckets))]
+	if b.slot != slot {
+		r.evict(b)
+		b.slot = slot
+	}
+	b.notifications = append(b.notifications, notification)
+	if signature != "" {
+		r.signatures[signature] = notification
+	}
+	for _, account := range accounts(notification) {
+		slots := r.accounts[account]
+		if slots == nil {
+			slots = make(map[uint64][]*chainstream.TransactionNotification)
+			r.accounts[account] = slots
+		}
+		slots[slot] = append(slots[slot], notification)
+	}
+	return true
+}
+
+// Record returns a middleware adding every handled notification to the ring.
+func (r *Ring) Record() chainstream.Middleware {
+	return func(next chainstream.HandlerFunc) chainstream.HandlerFunc {
+		return func(notification *chainstream.TransactionNotification) error {
+			r.Add(notification)
+			return next(notification)
+		}
+	}
+}
+
+// BySignature returns the notification of the transaction with the signature.
+func (r *Ring) BySignature(signature string) (*chainstream.TransactionNotification, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	notification, ok := r.signatures[signature]
+	return notification, ok
+}
+
+// ByAccount returns the notifications mentioning the account, as an account key or as the
+// owner of a token balance, ordered by slot.
+func (r *Ring) ByAccount(account string) []*chainstream.TransactionNotification {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	slots := r.accounts[account]
+	ordered := make([]uint64, 0, len(slots))
+	for slot := range slots {
+		ordered = append(ordered, slot)
+	}
+	slices.Sort(ordered)
+	var found []*chainstream.TransactionNotification
+	for _, slot := range ordered {
+		found = append(found, slots[slot]...)
+	}
+	return found
+}
+
+// BySlot returns the notifications of the slot in the order they were added.
+func (r *Ring) BySlot(slot uint64) []*chainstream.TransactionNotification {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	b := &r.buckets[slot%uint64(len(r.buckets))]
+	if b.slot != slot {
+		return nil
+	}
+	return append([]*chainstream.TransactionNotification(nil), b.notifications...)
+}
+
+// Len returns the number of notifications kept.
+func (r *Ring) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.signatures)
+}
+
+// Shed drops the notifications of the oldest fraction of the slots kept, see
+// memguard.Shedder, and returns how many notifications it dropped. Later notifications
+// of the dropped slots are still kept.
+func (r *Ring) Shed(fraction float64) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var kept []*bucket
+	for i := range r.buckets {
+		if len(r.buckets[i].notifications) > 0 {
+			kept = append(kept, &r.buckets[i])
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].slot < kept[j].slot })
+
+	dropped := 0
+	for _, b := range kept[:memguard.Portion(len(kept), fraction)] {
+		dropped += len(b.notifications)
+		r.evict(b)
+	}
+	return dropped
+}
+
+// tooOld reports whether the slot has already left the ring.
+func (r *Ring) tooOld(slot uint64) bool {
+	return r.latest >= uint64(len(r.buckets)) && slot <= r.latest-uint64(len(r.buckets))
+}
+
+// evictOld evicts the buckets of the slots that have left the ring.
+func (r *Ring) evictOld() {
+	for i := range r.buckets {
+		if b := &r.buckets[i]; len(b.notifications) > 0 && r.tooOld(b.slot) {
+			r.evict(b)
+		}
+	}
+}
+
+// evict removes the notifications of the bucket from the indexes.
+func (r *Ring) evict(b *bucket) {
+	for _, notification := range b.notifications {
+		delete(r.signatures, notification.Signature())
+		for _, account := range accounts(notification) {
+			if slots := r.accounts[account]; slots != nil {
+				delete(slots, b.slot)
+				if len(slots) == 0 {
+					delete(r.accounts, account)
+				}
+			}
+		}
+	}
+	b.notifications = nil
+}
+
+// accounts returns the distinct account keys and token owners of the notification.
+func accounts(notification *chainstream.TransactionNotification) []string {
+	keys := notification.AccountKeys()
+	meta := &notification.Params.Result.Value.Meta
+	for _, balances := range [][]chainstream.TokenBalance{meta.PreTokenBalances, meta.PostTokenBalances} {
+		for _, balance := range balances {
+			keys = append(keys, balance.Owner)
+		}
+	}
+	seen := make(map[string]struct{}, len(keys))
+	distinct := keys[:0]
+	for _, key := range keys {
+		if _, ok := seen[key]; ok || key == "" {
+			continue
+		}
+		seen[key] = struct{}{}
+		distinct = append(distinct, key)
+	}
+	return distinct
+}
@@ -0,0 +1,102 @@
+package history_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/history"
+)
+
+func notification(slot uint64, accounts ...string) *chainstream.TransactionNotification {
+	var n chainstream.TransactionNotification
+	n.Params.Result.Value.Slot = slot
+	n.Params.Result.Context.Signature = fmt.Sprintf("sig-%d-%v", slot, accounts)
+	n.Params.Result.Value.Transaction.Message.AccountKeys = accounts
+	return &n
+}
+
+func TestRing(t *testing.T) {
+	ring := history.NewRing(3)
+	create := notification(10, "creator", "mint")
+	buy := notification(11, "buyer", "mint")
+	if !ring.Add(create) || !ring.Add(buy) || !ring.Add(notification(11, "other")) {
+		t.Fatal("Add() rejected a recent notification")
+	}
+	if ring.Add(create) {
+		t.Error("Add() accepted a duplicate signature")
+	}
+
+	if got, ok := ring.BySignature(create.Signature()); !ok || got != create {
+		t.Errorf("BySignature() = %v, %v", got, ok)
+	}
+	if got := ring.ByAccount("mint"); len(got) != 2 || got[0] != create || got[1] != buy {
+		t.Errorf("ByAccount(mint) = %d notifications, expected create and buy", len(got))
+	}
+	if got := ring.BySlot(11); len(got) != 2 {
+		t.Errorf("BySlot(11) = %d notifications, expected 2", len(got))
+	}
+
+	// Slot 13 takes the bucket of slot 10.
+	ring.Add(notification(13, "mint"))
+	if _, ok := ring.BySignature(create.Signature()); ok {
+		t.Error("slot 10 is still kept after slot 13")
+	}
+	if got := ring.ByAccount("mint"); len(got) != 2 || got[0] != buy {
+		t.Errorf("ByAccount(mint) = %d notifications after eviction", len(got))
+	}
+	if ring.Add(notification(10, "late")) {
+		t.Error("Add() accepted a slot that left the ring")
+	}
+	if ring.Len() != 3 || ring.ByAccount("creator") != nil {
+		t.Errorf("Len() = %d, expected 3 with creator evicted", ring.Len())
+	}
+}
+
+func TestRingSlotJump(t *testing.T) {
+	ring := history.NewRing(3)
+	old := notification(2, "mint")
+	ring.Add(old)
+	ring.Add(notification(100, "buyer"))
+
+	if _, ok := ring.BySignature(old.Signature()); ok {
+		t.Error("slot 2 is still kept after slot 100")
+	}
+	if got := ring.ByAccount("mint"); got != nil {
+		t.Errorf("ByAccount(mint) = %d notifications, expected slot 2 evicted", len(got))
+	}
+	if ring.Len() != 1 {
+		t.Errorf("Len() = %d, expected only slot 100", ring.Len())
+	}
+}
+
+func TestRingTokenOwners(t *testing.T) {
+	n := notification(1, "payer")
+	n.Params.Result.Value.Meta.PostTokenBalances = []chainstream.TokenBalance{{Owner: "receiver", Mint: "mint"}}
+	ring := history.NewRing(10)
+	ring.Add(n)
+	if got := ring.ByAccount("receiver"); len(got) != 1 {
+		t.Errorf("ByAccount(receiver) = %d notifications, expected the token owner indexed", len(got))
+	}
+}
+
+func TestRingShed(t *testing.T) {
+	ring := history.NewRing(10)
+	for slot := uint64(10); slot < 14; slot++ {
+		ring.Add(notification(slot, "wallet", fmt.Sprint("mint", slot)))
+	}
+	ring.Add(notification(10, "other"))
+
+	if n := ring.Shed(0.5); n != 3 {
+		t.Errorf("Shed(0.5) = %d notifications, expected 3", n)
+	}
+	if ring.Len() != 2 || len(ring.BySlot(10)) != 0 || len(ring.BySlot(12)) != 1 {
+		t.Errorf("kept %d notifications, expected slots 12 and 13", ring.Len())
+	}
+	if got := ring.ByAccount("wallet"); len(got) != 2 || got[0].Slot() != 12 {
+		t.Errorf("ByAccount(wallet) = %d notifications, expected slots 12 and 13", len(got))
+	}
+	if got := ring.ByAccount("other"); len(got) != 0 {
+		t.Error("the account index kept a shed notification")
+	}
+}
@@ -0,0 +1,44 @@
+package lru_test
+
+import (
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/internal/lru"
+)
+
+func TestCacheEviction(t *testing.T) {
+	cache := lru.New[string, int](2)
+	cache.Add("a", 1)
+	cache.Add("b", 2)
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	cache.Add("c", 3)
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("expected least recently used b to be evicted")
+	}
+	if v, ok := cache.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %d, %v", v, ok)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Len() = %d, expected 2", cache.Len())
+	}
+}
+
+func TestCacheShed(t *testing.T) {
+	cache := lru.New[int, int](10)
+	for i := 0; i < 4; i++ {
+		cache.Add(i, i)
+	}
+	cache.Get(0)
+
+	if n := cache.Shed(0.5); n != 2 {
+		t.Errorf("Shed(0.5) = %d, expected 2", n)
+	}
+	for key, kept := range map[int]bool{0: true, 1: false, 2: false, 3: true} {
+		if _, ok := cache.Get(key); ok != kept {
+			t.Errorf("Get(%d) cached = %v, expected %v", key, ok, kept)
+		}
+	}
+}
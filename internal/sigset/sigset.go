@@ -0,0 +1,51 @@
+// Package sigset implements a set of the most recent transaction signatures, used to drop
+// the transactions delivered twice.
+package sigset
+
+// Set remembers the most recent signatures up to a fixed capacity, evicting the oldest
+// ones first. It is not safe for concurrent use.
+type Set struct {
+	seen  map[string]struct{}
+	order []string
+	next  int
+}
+
+// New creates a set remembering at most capacity signatures.
+func New(capacity int) *Set {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Set{
+		seen:  make(map[string]struct{}, capacity),
+		order: make([]string, capacity),
+	}
+}
+
+// Add records the signature and reports whether it was not seen before. Empty signatures
+// are never recorded and always reported as new.
+func (s *Set) Add(signature string) bool {
+	if signature == "" {
+		return true
+	}
+	if _, ok := s.seen[signature]; ok {
+		return false
+	}
+	if evicted := s.order[s.next]; evicted != "" {
+		delete(s.seen, evicted)
+	}
+	s.order[s.next] = signature
+	s.next = (s.next + 1) % len(s.order)
+	s.seen[signature] = struct{}{}
+	return true
+}
+
+// Has reports whether the signature is remembered.
+func (s *Set) Has(signature string) bool {
+	_, ok := s.seen[signature]
+	return ok
+}
+
+// Len returns the number of signatures remembered.
+func (s *Set) Len() int {
+	return len(s.seen)
+}
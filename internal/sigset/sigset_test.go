@@ -0,0 +1,25 @@
+package sigset_test
+
+import (
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/internal/sigset"
+)
+
+func TestSetEviction(t *testing.T) {
+	set := sigset.New(2)
+	for _, signature := range []string{"a", "b", "c"} {
+		if !set.Add(signature) {
+			t.Errorf("Add(%s) reported a duplicate", signature)
+		}
+	}
+	if set.Add("c") {
+		t.Error("Add(c) reported c as new")
+	}
+	if set.Has("a") || !set.Has("b") || set.Len() != 2 {
+		t.Errorf("Has(a) = %v, Has(b) = %v, Len() = %d, expected a evicted", set.Has("a"), set.Has("b"), set.Len())
+	}
+	if !set.Add("") || !set.Add("") || set.Len() != 2 {
+		t.Error("empty signatures were recorded")
+	}
+}
@@ -0,0 +1,262 @@
+// Package memguard keeps long-running processes under a heap ceiling: a Guard watches the
+// heap and, when it approaches the ceiling, makes the registered caches and buffers shed
+// their oldest entries before the process runs out of memory.
+package memguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	rtmetrics "runtime/metrics"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+// Defaults of Config.
+const (
+	DefaultHighWater    = 0.85
+	DefaultShedFraction = 0.25
+	DefaultInterval     = 5 * time.Second
+)
+
+// heapObjects is the runtime/metrics key of the memory occupied by heap objects.
+const heapObjects = "/memory/classes/heap/objects:bytes"
+
+// Shedder is a cache or buffer that frees memory on demand.
+type Shedder interface {
+	// Shed drops the given fraction, between 0 and 1, of the entries, oldest first, and
+	// returns how many it dropped.
+	Shed(fraction float64) int
+}
+
+// ShedFunc adapts a function to a Shedder.
+type ShedFunc func(fraction float64) int
+
+// Shed implements Shedder.
+func (f ShedFunc) Shed(fraction float64) int {
+	return f(fraction)
+}
+
+// Portion returns how many of total entries a Shedder drops for the fraction: at least one
+// when total is not zero and the fraction is positive, at most total.
+func Portion(total int, fraction float64) int {
+	if total <= 0 || fraction <= 0 {
+		return 0
+	}
+	n := int(math.Ceil(float64(total) * fraction))
+	if n > total {
+		n = total
+	}
+	return n
+}
+
+// Config describes a Guard.
+type Config struct {
+	// Ceiling is the heap size, in bytes, the process must stay under. It is required.
+	Ceiling uint64
+	// HighWater is the fraction of the ceiling above which the guard sheds, 0.85 by
+	// default.
+	HighWater float64
+	// ShedFraction is the fraction of its entries every shedder drops in one round, 0.25
+	// by default. A heap still above the high water mark at the next check sheds again.
+	ShedFraction float64
+	// Interval is the time between checks of Run, 5s by default.
+	Interval time.Duration
+	// OnShed, if not nil, is called after every round of shedding.
+	OnShed func(event ShedEvent)
+	// ReadHeap returns the heap size. Nil reads the memory occupied by heap objects from
+	// the runtime.
+	ReadHeap func() uint64
+	// Clock drives Run. Nil uses the system clock.
+	Clock clock.Clock
+}
+
+// SetDefaults fills unset fields with their defaults.
+func (c *Config) SetDefaults() {
+	if c.HighWater == 0 {
+		c.HighWater = DefaultHighWater
+	}
+	if c.ShedFraction == 0 {
+		c.ShedFraction = DefaultShedFraction
+	}
+	if c.Interval == 0 {
+		c.Interval = DefaultInterval
+	}
+	if c.ReadHeap == nil {
+		c.ReadHeap = readHeap
+	}
+	if c.Clock == nil {
+		c.Clock = clock.System()
+	}
+}
+
+// Validate checks the config after SetDefaults.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Ceiling == 0 {
+		errs = append(errs, errors.New("memguard: ceiling is required"))
+	}
+	if c.HighWater <= 0 || c.HighWater > 1 {
+		errs = append(errs, fmt.Errorf("memguard: high water %v is not in (0, 1]", c.HighWater))
+	}
+	if c.ShedFraction <= 0 || c.ShedFraction > 1 {
+		errs = append(errs, fmt.Errorf("memguard: shed fraction %v is not in (0, 1]", c.ShedFraction))
+	}
+	if c.Interval < 0 {
+		errs = append(errs, errors.New("memguard: interval must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// ShedEvent describes a round of shedding.
+type ShedEvent struct {
+	// Heap is the heap size that triggered the round, After the heap size once the shed
+	// entries were collected.
+	Heap  uint64
+	After uint64
+	// Dropped is the number of entries dropped by every shedder, by name.
+	Dropped map[string]int
+}
+
+// Stats are the statistics of a Guard.
+type Stats struct {
+	Ceiling uint64
+	// Heap is the heap size at the last check.
+	Heap uint64
+	// Sheds is the number of rounds of shedding.
+	Sheds uint64
+	// Dropped is the total number of entries dropped by every shedder, by name.
+	Dropped map[string]uint64
+}
+
+type shedder struct {
+	name string
+	Shedder
+}
+
+// Guard sheds the registered shedders when the heap approaches the ceiling. It is safe for
+// concurrent use.
+type Guard struct {
+	config Config
+	// checking serializes the checks, which shed and collect without holding mu so that
+	// Register and Stats do not wait for them.
+	checking sync.Mutex
+
+	mu       sync.Mutex
+	shedders []shedder
+	heap     uint64
+	sheds    uint64
+	dropped  map[string]uint64
+}
+
+// New returns a guard. It returns an error if the config is invalid, see Config.Validate.
+func New(config Config) (*Guard, error) {
+	config.SetDefaults()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &Guard{config: config, dropped: make(map[string]uint64)}, nil
+}
+
+// Register adds a shedder under the name, which labels its statistics. Shedders are shed
+// in the order they were registered.
+func (g *Guard) Register(name string, s Shedder) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.shedders = append(g.shedders, shedder{name: name, Shedder: s})
+	if _, ok := g.dropped[name]; !ok {
+		g.dropped[name] = 0
+	}
+}
+
+// Check reads the heap size and, when it is above the high water mark, sheds every
+// shedder and runs a garbage collection. It reports whether it shed.
+func (g *Guard) Check() bool {
+	event, shed := g.check()
+	if shed && g.config.OnShed != nil {
+		g.config.OnShed(event)
+	}
+	return shed
+}
+
+// check reads the heap and sheds, see Check.
+func (g *Guard) check() (ShedEvent, bool) {
+	g.checking.Lock()
+	defer g.checking.Unlock()
+
+	heap := g.config.ReadHeap()
+	g.mu.Lock()
+	g.heap = heap
+	shedders := slices.Clone(g.shedders)
+	g.mu.Unlock()
+	if float64(heap) < g.config.HighWater*float64(g.config.Ceiling) {
+		return ShedEvent{}, false
+	}
+
+	event := ShedEvent{Heap: heap, Dropped: make(map[string]int, len(shedders))}
+	for _, s := range shedders {
+		event.Dropped[s.name] += s.Shed(g.config.ShedFraction)
+	}
+	runtime.GC()
+	event.After = g.config.ReadHeap()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for name, n := range event.Dropped {
+		g.dropped[name] += uint64(n)
+	}
+	g.sheds++
+	g.heap = event.After
+	return event, true
+}
+
+// Run checks the heap every Config.Interval until the context is done.
+func (g *Guard) Run(ctx context.Context) error {
+	ticker := g.config.Clock.NewTicker(g.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C():
+			g.Check()
+		}
+	}
+}
+
+// Stats returns the statistics of the guard.
+func (g *Guard) Stats() Stats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	stats := Stats{Ceiling: g.config.Ceiling, Heap: g.heap, Sheds: g.sheds, Dropped: make(map[string]uint64, len(g.dropped))}
+	for name, n := range g.dropped {
+		stats.Dropped[name] = n
+	}
+	return stats
+}
+
+// Names returns the names of the registered shedders, sorted.
+func (s Stats) Names() []string {
+	names := make([]string, 0, len(s.Dropped))
+	for name := range s.Dropped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// readHeap reads the memory occupied by heap objects, live and dead ones not yet swept.
+func readHeap() uint64 {
+	sample := []rtmetrics.Sample{{Name: heapObjects}}
+	rtmetrics.Read(sample)
+	if sample[0].Value.Kind() != rtmetrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
@@ -0,0 +1,138 @@
+package memguard_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/clock"
+	"github.com/gerasimovvladislav/zensol-go/memguard"
+)
+
+// entries is a shedder of n entries.
+type entries struct {
+	n int
+}
+
+func (e *entries) Shed(fraction float64) int {
+	n := memguard.Portion(e.n, fraction)
+	e.n -= n
+	return n
+}
+
+func TestGuard(t *testing.T) {
+	var heap atomic.Uint64
+	events := make(chan memguard.ShedEvent, 1)
+	clk := clock.NewFake(time.Unix(1700000000, 0))
+	guard, err := memguard.New(memguard.Config{
+		Ceiling:  1000,
+		ReadHeap: func() uint64 { return heap.Load() },
+		OnShed:   func(event memguard.ShedEvent) { events <- event },
+		Clock:    clk,
+	})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	cache, ring := &entries{n: 100}, &entries{n: 3}
+	guard.Register("cache", cache)
+	guard.Register("ring", ring)
+
+	heap.Store(849)
+	if guard.Check() {
+		t.Error("Check() shed under the high water mark")
+	}
+
+	heap.Store(900)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- guard.Run(ctx) }()
+	clk.BlockUntil(1)
+	clk.Advance(memguard.DefaultInterval)
+	event := <-events
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if cache.n != 75 || ring.n != 2 {
+		t.Errorf("kept %d and %d entries, expected 75 and 2", cache.n, ring.n)
+	}
+	if event.Heap != 900 || event.Dropped["cache"] != 25 || event.Dropped["ring"] != 1 {
+		t.Errorf("event = %+v", event)
+	}
+	stats := guard.Stats()
+	if stats.Ceiling != 1000 || stats.Heap != 900 || stats.Sheds != 1 || stats.Dropped["cache"] != 25 || stats.Dropped["ring"] != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestGuardShedUnlocked(t *testing.T) {
+	guard, err := memguard.New(memguard.Config{Ceiling: 1000, ReadHeap: func() uint64 { return 1000 }})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	// A shedder may use the guard, e.g. report its stats, while it is shed.
+	guard.Register("stats", memguard.ShedFunc(func(float64) int {
+		guard.Stats()
+		guard.Register("late", &entries{})
+		return 1
+	}))
+	done := make(chan bool)
+	go func() { done <- guard.Check() }()
+	select {
+	case shed := <-done:
+		if !shed {
+			t.Error("Check() did not shed at the ceiling")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Check() deadlocked on a shedder using the guard")
+	}
+	if stats := guard.Stats(); stats.Sheds != 1 || stats.Dropped["stats"] != 1 {
+		t.Errorf("Stats() = %+v, expected a round dropping 1 entry", stats)
+	}
+}
+
+func TestGuardDefaultHeap(t *testing.T) {
+	guard, err := memguard.New(memguard.Config{Ceiling: 1 << 50})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if guard.Check() {
+		t.Error("Check() shed far under the ceiling")
+	}
+	if guard.Stats().Heap == 0 {
+		t.Error("the heap size was not read from the runtime")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, config := range []memguard.Config{
+		{},
+		{Ceiling: 1, HighWater: 1.5},
+		{Ceiling: 1, ShedFraction: -0.1},
+		{Ceiling: 1, Interval: -time.Second},
+	} {
+		if _, err := memguard.New(config); err == nil {
+			t.Errorf("New(%+v) succeeded", config)
+		}
+	}
+}
+
+func TestPortion(t *testing.T) {
+	for _, tt := range []struct {
+		total    int
+		fraction float64
+		expected int
+	}{
+		{0, 0.5, 0},
+		{10, 0, 0},
+		{10, 0.25, 3},
+		{3, 0.1, 1},
+		{10, 1, 10},
+	} {
+		if got := memguard.Portion(tt.total, tt.fraction); got != tt.expected {
+			t.Errorf("Portion(%d, %v) = %d, expected %d", tt.total, tt.fraction, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+//go:generate go run ../cmd/zensol dashboard -out dashboards/zensol.json
+
+// Grafana dashboard model, limited to the fields Dashboard sets.
+type (
+	dashboard struct {
+		UID           string     `json:"uid"`
+		Title         string     `json:"title"`
+		Tags          []string   `json:"tags"`
+		SchemaVersion int        `json:"schemaVersion"`
+		Refresh       string     `json:"refresh"`
+		Time          timeRange  `json:"time"`
+		Templating    templating `json:"templating"`
+		Panels        []panel    `json:"panels"`
+	}
+	timeRange struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	templating struct {
+		List []variable `json:"list"`
+	}
+	variable struct {
+		Name       string      `json:"name"`
+		Label      string      `json:"label"`
+		Type       string      `json:"type"`
+		Query      string      `json:"query"`
+		Datasource *datasource `json:"datasource,omitempty"`
+		Refresh    int         `json:"refresh,omitempty"`
+		Multi      bool        `json:"multi,omitempty"`
+		IncludeAll bool        `json:"includeAll,omitempty"`
+		AllValue   string      `json:"allValue,omitempty"`
+	}
+	datasource struct {
+		Type string `json:"type"`
+		UID  string `json:"uid"`
+	}
+	panel struct {
+		ID          int         `json:"id"`
+		Type        string      `json:"type"`
+		Title       string      `json:"title"`
+		Description string      `json:"description,omitempty"`
+		Datasource  datasource  `json:"datasource"`
+		GridPos     gridPos     `json:"gridPos"`
+		FieldConfig fieldConfig `json:"fieldConfig"`
+		Targets     []target    `json:"targets"`
+	}
+	gridPos struct {
+		H int `json:"h"`
+		W int `json:"w"`
+		X int `json:"x"`
+		Y int `json:"y"`
+	}
+	fieldConfig struct {
+		Defaults struct {
+			Unit string `json:"unit"`
+		} `json:"defaults"`
+	}
+	target struct {
+		RefID        string `json:"refId"`
+		Expr         string `json:"expr"`
+		LegendFormat string `json:"legendFormat"`
+	}
+)
+
+// promDatasource refers to the datasource picked with the datasource variable.
+var promDatasource = datasource{Type: "prometheus", UID: "${datasource}"}
+
+// selector restricts series to the subscriptions and networks picked in the dashboard.
+// Series without the labels match the All value.
+const selector = `{` + LabelSubscription + `=~"$` + LabelSubscription + `",` + LabelNetwork + `=~"$` + LabelNetwork + `"}`
+
+// Dashboard returns a Grafana dashboard of the metric set as indented JSON, with a panel
+// for every group of counters, latency and RTT quantiles, events by program and type, the
+// memory guard and the Go runtime. Variables select the Prometheus datasource, subscriptions and networks.
+// An example generated with go generate is in dashboards/zensol.json.
+func Dashboard(title string) ([]byte, error) {
+	d := dashboard{
+		UID:           "zensol",
+		Title:         title,
+		Tags:          []string{"zensol", "solana"},
+		SchemaVersion: 39,
+		Refresh:       "30s",
+		Time:          timeRange{From: "now-1h", To: "now"},
+		Templating: templating{List: []variable{
+			{Name: "datasource", Label: "Data source", Type: "datasource", Query: "prometheus"},
+			labelVariable(LabelSubscription, "Subscription"),
+			labelVariable(LabelNetwork, "Network"),
+		}},
+	}
+
+	add := func(title, unit string, targets ...target) {
+		for i := range targets {
+			targets[i].RefID = string(rune('A' + i))
+		}
+		p := panel{
+			ID:         len(d.Panels) + 1,
+			Type:       "timeseries",
+			Title:      title,
+			Datasource: promDatasource,
+			// Two panels per row.
+			GridPos: gridPos{H: 8, W: 12, X: 12 * (len(d.Panels) % 2), Y: 8 * (len(d.Panels) / 2)},
+			Targets: targets,
+		}
+		p.FieldConfig.Defaults.Unit = unit
+		d.Panels = append(d.Panels, p)
+	}
+
+	var (
+		titles  []string
+		grouped = make(map[string][]target)
+	)
+	for _, c := range counters {
+		if _, ok := grouped[c.panel]; !ok {
+			titles = append(titles, c.panel)
+		}
+		grouped[c.panel] = append(grouped[c.panel], target{
+			Expr:         fmt.Sprintf("sum(rate(%s%s[$__rate_interval]))", c.name, selector),
+			LegendFormat: c.help,
+		})
+	}
+	for _, title := range titles {
+		add(title, "ops", grouped[title]...)
+	}
+
+	add("Stage latency (p99)", "s", target{
+		Expr:         fmt.Sprintf("histogram_quantile(0.99, sum by (le, %s) (rate(%s_bucket%s[$__rate_interval])))", LabelStage, stageLatency, selector),
+		LegendFormat: "{{" + LabelStage + "}}",
+	})
+	add("Ping RTT", "s",
+		target{Expr: fmt.Sprintf("histogram_quantile(0.5, sum by (le) (rate(%s_bucket%s[$__rate_interval])))", pingRTT, selector), LegendFormat: "p50"},
+		target{Expr: fmt.Sprintf("histogram_quantile(0.99, sum by (le) (rate(%s_bucket%s[$__rate_interval])))", pingRTT, selector), LegendFormat: "p99"},
+	)
+	add("Events by type", "ops", target{
+		Expr:         fmt.Sprintf("sum by (%s) (rate(%s%s[$__rate_interval]))", LabelEventType, eventsTotal, selector),
+		LegendFormat: "{{" + LabelEventType + "}}",
+	})
+	add("Events by program", "ops", target{
+		Expr:         fmt.Sprintf("sum by (%s) (rate(%s%s[$__rate_interval]))", LabelProgram, eventsTotal, selector),
+		LegendFormat: "{{" + LabelProgram + "}}",
+	})
+
+	// Memory guard panels, empty unless Exporter.Memory is set.
+	add("Memory guard", "bytes",
+		target{Expr: fmt.Sprintf("sum(%s%s)", memoryHeap, selector), LegendFormat: "heap"},
+		target{Expr: fmt.Sprintf("sum(%s%s)", memoryCeiling, selector), LegendFormat: "ceiling"},
+	)
+	add("Shed entries", "ops",
+		target{Expr: fmt.Sprintf("sum by (%s) (rate(%s%s[$__rate_interval]))", LabelCache, memoryShedEntries, selector), LegendFormat: "{{" + LabelCache + "}}"},
+		target{Expr: fmt.Sprintf("sum(rate(%s%s[$__rate_interval]))", memorySheds, selector), LegendFormat: "rounds"},
+	)
+
+	// Retry budget panels, empty unless Exporter.RetryBudget is set.
+	add("Retry budget", "ops",
+		target{Expr: fmt.Sprintf("sum by (%s) (rate(%s%s[$__rate_interval]))", LabelRetryKind, retryGranted, selector), LegendFormat: "granted {{" + LabelRetryKind + "}}"},
+		target{Expr: fmt.Sprintf("sum by (%s) (rate(%s%s[$__rate_interval]))", LabelRetryKind, retryExhausted, selector), LegendFormat: "exhausted {{" + LabelRetryKind + "}}"},
+	)
+	add("Retry budget tokens", "none", target{Expr: fmt.Sprintf("sum(%s%s)", retryTokens, selector), LegendFormat: "tokens"})
+
+	// Go runtime panels, empty unless Exporter.Runtime is set.
+	add("Goroutines", "none", target{Expr: fmt.Sprintf("sum(%s%s)", goGoroutines, selector), LegendFormat: "goroutines"})
+	add("Heap", "bytes",
+		target{Expr: fmt.Sprintf("sum(%s%s)", goHeapObjects, selector), LegendFormat: "objects"},
+		target{Expr: fmt.Sprintf("sum(%s%s)", goHeapGoal, selector), LegendFormat: "goal"},
+	)
+	add("Heap allocations", "Bps", target{Expr: fmt.Sprintf("sum(rate(%s%s[$__rate_interval]))", goHeapAllocs, selector), LegendFormat: "allocated"})
+	add("GC cycles", "ops", target{Expr: fmt.Sprintf("sum(rate(%s%s[$__rate_interval]))", goGCCycles, selector), LegendFormat: "cycles"})
+	add("GC pauses", "s",
+		target{Expr: fmt.Sprintf("histogram_quantile(0.5, sum by (le) (rate(%s_bucket%s[$__rate_interval])))", goGCPauses, selector), LegendFormat: "p50"},
+		target{Expr: fmt.Sprintf("histogram_quantile(0.99, sum by (le) (rate(%s_bucket%s[$__rate_interval])))", goGCPauses, selector), LegendFormat: "p99"},
+	)
+
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// labelVariable is a multi-value dashboard variable listing the values of a label.
+func labelVariable(name, title string) variable {
+	return variable{
+		Name:       name,
+		Label:      title,
+		Type:       "query",
+		Query:      fmt.Sprintf("label_values(zensol_notifications_received_total, %s)", name),
+		Datasource: &promDatasource,
+		Refresh:    2,
+		Multi:      true,
+		IncludeAll: true,
+		AllValue:   ".*",
+	}
+}
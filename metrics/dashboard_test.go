@@ -0,0 +1,28 @@
+package metrics_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/metrics"
+)
+
+func TestDashboardUpToDate(t *testing.T) {
+	dashboard, err := metrics.Dashboard("zensol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	example, err := os.ReadFile("dashboards/zensol.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(append(dashboard, '\n'), example) {
+		t.Error("dashboards/zensol.json is out of date, run go generate ./metrics")
+	}
+	for _, metric := range metrics.Metrics() {
+		if !bytes.Contains(dashboard, []byte(metric.Name)) {
+			t.Errorf("dashboard does not chart %s", metric.Name)
+		}
+	}
+}
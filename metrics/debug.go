@@ -0,0 +1,25 @@
+package metrics
+
+import "net/http"
+
+// DebugConfig selects the diagnostics served by NewServeMux with the metrics, e.g. to find
+// why a streamer falls behind in production. Runtime metrics are off by default. Profiles
+// are served by the opt-in package metrics/pprofdebug, registered on the returned mux.
+type DebugConfig struct {
+	// Runtime exposes Go runtime metrics with the statistics, see Exporter.Runtime.
+	Runtime bool `json:"runtime,omitempty"`
+}
+
+// NewServeMux returns a mux serving the exporter on /, the snapshot of the source on
+// /debug/snapshot and the diagnostics enabled in debug.
+func NewServeMux(exporter *Exporter, snapshots Snapshotter, debug DebugConfig) *http.ServeMux {
+	if debug.Runtime && !exporter.Runtime {
+		copied := *exporter
+		copied.Runtime = true
+		exporter = &copied
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", exporter)
+	mux.Handle("/debug/snapshot", SnapshotHandler(snapshots))
+	return mux
+}
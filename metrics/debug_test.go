@@ -0,0 +1,50 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/metrics"
+)
+
+type fakeSnapshotter struct{}
+
+func (fakeSnapshotter) Snapshot() chainstream.Snapshot { return chainstream.Snapshot{} }
+
+func TestNewServeMux(t *testing.T) {
+	get := func(mux *http.ServeMux, path string) (int, string) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		body, _ := io.ReadAll(rec.Body)
+		return rec.Code, string(body)
+	}
+	exporter := &metrics.Exporter{Source: fakeSource{}, Subscription: "sniper"}
+
+	mux := metrics.NewServeMux(exporter, fakeSnapshotter{}, metrics.DebugConfig{})
+	if _, body := get(mux, "/metrics"); strings.Contains(body, "go_goroutines") {
+		t.Error("runtime metrics are exposed without DebugConfig.Runtime")
+	}
+	if _, body := get(mux, "/debug/pprof/"); strings.Contains(body, "profiles") {
+		t.Error("pprof is served without metrics/pprofdebug")
+	}
+
+	mux = metrics.NewServeMux(exporter, fakeSnapshotter{}, metrics.DebugConfig{Runtime: true})
+	_, body := get(mux, "/metrics")
+	for _, line := range []string{
+		"# TYPE go_goroutines gauge",
+		`go_goroutines{subscription="sniper"} `,
+		`go_heap_objects_bytes{subscription="sniper"} `,
+		`go_gc_pause_seconds_bucket{subscription="sniper",le="+Inf"} `,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("metrics are missing %q", line)
+		}
+	}
+	if exporter.Runtime {
+		t.Error("NewServeMux modified the exporter")
+	}
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+const (
+	eventsTotal = "zensol_events_total"
+	eventsHelp  = "Handled transactions by invoked program and event type."
+)
+
+// Event types of zensol_events_total, see EventType.
+const (
+	EventFailed   = "failed"
+	EventBuy      = "buy"
+	EventSell     = "sell"
+	EventSwap     = "swap"
+	EventTransfer = "transfer"
+	EventOther    = "other"
+)
+
+// OtherProgram is the program label of programs unknown to the programs package.
+const OtherProgram = "other"
+
+// Events counts transactions by program and event type for zensol_events_total. The zero
+// value is ready to use and safe for concurrent use.
+type Events struct {
+	mu     sync.Mutex
+	counts map[eventKey]uint64
+}
+
+type eventKey struct {
+	program, eventType string
+}
+
+type eventCount struct {
+	eventKey
+	count uint64
+}
+
+// Add counts an event of the program. Keep the number of distinct values small, each pair
+// is a series.
+func (e *Events) Add(program, eventType string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.counts == nil {
+		e.counts = make(map[eventKey]uint64)
+	}
+	e.counts[eventKey{program, eventType}]++
+}
+
+// Observe counts the transaction once for every distinct program invoked by its top-level
+// instructions, with the event type of the transaction. Programs unknown to the programs
+// package are counted as OtherProgram, which bounds the number of series.
+func (e *Events) Observe(notification *chainstream.TransactionNotification) {
+	eventType := EventType(notification)
+	seen := make(map[string]bool)
+	for _, instruction := range notification.Instructions() {
+		if instruction.IsInner() {
+			continue
+		}
+		program := instruction.ProgramID
+		if _, ok := programs.Name(program); !ok {
+			program = OtherProgram
+		}
+		if !seen[program] {
+			seen[program] = true
+			e.Add(program, eventType)
+		}
+	}
+	if len(seen) == 0 {
+		e.Add(OtherProgram, eventType)
+	}
+}
+
+// EventType classifies a transaction: EventFailed if it failed, EventBuy or EventSell for
+// an inferred trade, EventSwap for other swaps, EventTransfer if it moved funds and
+// EventOther otherwise.
+func EventType(notification *chainstream.TransactionNotification) string {
+	if !notification.Succeeded() {
+		return EventFailed
+	}
+	switch notification.InferTrade().Side {
+	case chainstream.SideBuy:
+		return EventBuy
+	case chainstream.SideSell:
+		return EventSell
+	}
+	if len(notification.SwapRoutes()) > 0 {
+		return EventSwap
+	}
+	if len(notification.Transfers()) > 0 {
+		return EventTransfer
+	}
+	return EventOther
+}
+
+// snapshot returns the counts ordered by program and event type.
+func (e *Events) snapshot() []eventCount {
+	e.mu.Lock()
+	counts := make([]eventCount, 0, len(e.counts))
+	for key, count := range e.counts {
+		counts = append(counts, eventCount{key, count})
+	}
+	e.mu.Unlock()
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].program != counts[j].program {
+			return counts[i].program < counts[j].program
+		}
+		return counts[i].eventType < counts[j].eventType
+	})
+	return counts
+}
@@ -0,0 +1,37 @@
+package metrics_test
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/metrics"
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+func TestEventsObserve(t *testing.T) {
+	data, err := os.ReadFile("../chainstream/testdata/sample_tx_sell.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sell chainstream.TransactionNotification
+	if err := json.Unmarshal(data, &sell); err != nil {
+		t.Fatal(err)
+	}
+	if got := metrics.EventType(&sell); got != metrics.EventSell {
+		t.Errorf("EventType() = %q, expected %q", got, metrics.EventSell)
+	}
+
+	events := &metrics.Events{}
+	events.Observe(&sell)
+	var out strings.Builder
+	if err := (&metrics.Exporter{Source: fakeSource{}, Events: events}).Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	line := `zensol_events_total{program="` + string(programs.PumpFun) + `",event_type="sell"} 1`
+	if !strings.Contains(out.String(), line+"\n") {
+		t.Errorf("output is missing %q:\n%s", line, out.String())
+	}
+}
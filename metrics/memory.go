@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gerasimovvladislav/zensol-go/memguard"
+)
+
+// Names of the memory guard metrics, exposed with Exporter.Memory.
+const (
+	memoryHeap        = "zensol_memory_heap_bytes"
+	memoryCeiling     = "zensol_memory_ceiling_bytes"
+	memorySheds       = "zensol_memory_sheds_total"
+	memoryShedEntries = "zensol_memory_shed_entries_total"
+)
+
+// typedMetric describes a metric exposed with the given Prometheus type.
+type typedMetric struct {
+	name string
+	typ  string
+	help string
+}
+
+// memoryMetrics lists the memory guard metrics in exposition order.
+var memoryMetrics = []typedMetric{
+	{memoryHeap, "gauge", "Heap size at the last check of the memory guard."},
+	{memoryCeiling, "gauge", "Heap ceiling enforced by the memory guard."},
+	{memorySheds, "counter", "Rounds of shedding of the memory guard."},
+	{memoryShedEntries, "counter", "Entries dropped by caches and buffers shed by the memory guard."},
+}
+
+// writeMemory writes the statistics of the memory guard; labels is empty or ends with a
+// comma.
+func writeMemory(w io.Writer, labels string, stats memguard.Stats) {
+	for _, m := range memoryMetrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.typ)
+		switch m.name {
+		case memoryHeap:
+			fmt.Fprintf(w, "%s%s %d\n", m.name, braced(labels), stats.Heap)
+		case memoryCeiling:
+			fmt.Fprintf(w, "%s%s %d\n", m.name, braced(labels), stats.Ceiling)
+		case memorySheds:
+			fmt.Fprintf(w, "%s%s %d\n", m.name, braced(labels), stats.Sheds)
+		case memoryShedEntries:
+			for _, name := range stats.Names() {
+				fmt.Fprintf(w, "%s{%s} %d\n", m.name, strings.TrimSuffix(labels+label(LabelCache, name), ","), stats.Dropped[name])
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package metrics
+
+// Label names of the metric set. They are part of the stable interface of the package,
+// like the metric names, so that dashboards and alerts keep working across versions.
+const (
+	// LabelSubscription is Exporter.Subscription, on every series when set.
+	LabelSubscription = "subscription"
+	// LabelNetwork is Exporter.Network, on every series when set.
+	LabelNetwork = "network"
+	// LabelStage is the pipeline stage of zensol_stage_latency_seconds.
+	LabelStage = "stage"
+	// LabelProgram is the program ID of zensol_events_total, see Events.
+	LabelProgram = "program"
+	// LabelEventType is the event type of zensol_events_total, see EventType.
+	LabelEventType = "event_type"
+	// LabelCache is the name a cache was registered with in the memory guard, on
+	// zensol_memory_shed_entries_total, see memguard.Guard.Register.
+	LabelCache = "cache"
+	// LabelRetryKind is the subsystem taking retries on the zensol_retry_budget_ counters,
+	// see chainstream.RetryKind.
+	LabelRetryKind = "kind"
+)
+
+// Metric describes a metric family exposed by Exporter.
+type Metric struct {
+	Name string `json:"name"`
+	// Type is the Prometheus type, counter, gauge or histogram.
+	Type string `json:"type"`
+	Help string `json:"help"`
+	// Labels lists the labels of the series. The subscription and network labels are only
+	// present when set on the Exporter.
+	Labels []string `json:"labels"`
+}
+
+// Metrics returns the metric set exposed by Exporter, in exposition order. The go_ metrics
+// are only exposed with Exporter.Runtime, the zensol_memory_ ones with Exporter.Memory and
+// the zensol_retry_budget_ ones with Exporter.RetryBudget.
+func Metrics() []Metric {
+	common := []string{LabelSubscription, LabelNetwork}
+	with := func(labels ...string) []string {
+		return append(append([]string(nil), common...), labels...)
+	}
+
+	var set []Metric
+	for _, c := range counters {
+		set = append(set, Metric{Name: c.name, Type: "counter", Help: c.help, Labels: with()})
+	}
+	set = append(set,
+		Metric{Name: stageLatency, Type: "histogram", Help: "Time spent by notifications in each pipeline stage.", Labels: with(LabelStage)},
+		Metric{Name: pingRTT, Type: "histogram", Help: "Round-trip time of pings.", Labels: with()},
+		Metric{Name: eventsTotal, Type: "counter", Help: eventsHelp, Labels: with(LabelProgram, LabelEventType)},
+	)
+	for _, m := range memoryMetrics {
+		labels := with()
+		if m.name == memoryShedEntries {
+			labels = with(LabelCache)
+		}
+		set = append(set, Metric{Name: m.name, Type: m.typ, Help: m.help, Labels: labels})
+	}
+	for _, m := range retryMetrics {
+		labels := with()
+		if m.name != retryTokens {
+			labels = with(LabelRetryKind)
+		}
+		set = append(set, Metric{Name: m.name, Type: m.typ, Help: m.help, Labels: labels})
+	}
+	for _, m := range runtimeMetrics {
+		set = append(set, Metric{Name: m.name, Type: m.typ, Help: m.help, Labels: with()})
+	}
+	return set
+}
@@ -0,0 +1,20 @@
+// Package pprofdebug serves the net/http/pprof profiles next to the metrics. It is a
+// separate package because importing net/http/pprof registers its handlers on
+// http.DefaultServeMux: only programs that opt in to profiles, such as cmd/zensol, should
+// import it.
+package pprofdebug
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// Register serves the profiles on mux under /debug/pprof/, e.g. on the mux returned by
+// metrics.NewServeMux.
+func Register(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
@@ -0,0 +1,23 @@
+package pprofdebug_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/metrics/pprofdebug"
+)
+
+func TestRegister(t *testing.T) {
+	mux := http.NewServeMux()
+	pprofdebug.Register(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	body, _ := io.ReadAll(rec.Body)
+	if rec.Code != http.StatusOK || !strings.Contains(string(body), "profiles") {
+		t.Errorf("GET /debug/pprof/ = %d, expected the profile index", rec.Code)
+	}
+}
@@ -0,0 +1,183 @@
+// Package metrics exposes client statistics in the Prometheus text exposition format.
+// The metric and label names, documented by Metrics, are stable, and Dashboard charts them
+// in Grafana.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/memguard"
+)
+
+// Source provides the statistics to expose. chainstream.Client implements it.
+type Source interface {
+	Stats() chainstream.Stats
+	Latency() map[chainstream.Stage]chainstream.Histogram
+	RTT() chainstream.Histogram
+}
+
+// counter describes a Stats field exposed as a Prometheus counter, shown in the dashboard
+// panel with the given title.
+type counter struct {
+	name  string
+	help  string
+	panel string
+	value func(s *chainstream.Stats) uint64
+}
+
+var counters = []counter{
+	{"zensol_notifications_received_total", "Notifications read from the server.", "Throughput", func(s *chainstream.Stats) uint64 { return s.Received }},
+	{"zensol_notifications_delivered_total", "Notifications handled successfully.", "Throughput", func(s *chainstream.Stats) uint64 { return s.Delivered }},
+	{"zensol_notifications_dropped_total", "Notifications dropped from a full read buffer or subscription queue, or left unhandled when their subscription ended.", "Dropped notifications", func(s *chainstream.Stats) uint64 { return s.Dropped }},
+	{"zensol_notifications_duplicate_total", "Notifications dropped as already delivered.", "Dropped notifications", func(s *chainstream.Stats) uint64 { return s.Duplicates }},
+	{"zensol_notifications_mirror_copies_total", "Notifications dropped as delivered already by another session in redundant mode.", "Dropped notifications", func(s *chainstream.Stats) uint64 { return s.MirrorCopies }},
+	{"zensol_notifications_stale_total", "Notifications dropped as too old.", "Dropped notifications", func(s *chainstream.Stats) uint64 { return s.Stale }},
+	{"zensol_notifications_filtered_total", "Notifications dropped by the filter hook.", "Dropped notifications", func(s *chainstream.Stats) uint64 { return s.Filtered }},
+	{"zensol_handler_errors_total", "Errors returned by handlers, including retried ones.", "Handler failures", func(s *chainstream.Stats) uint64 { return s.HandlerErrors }},
+	{"zensol_handler_retries_total", "Handler retries.", "Handler failures", func(s *chainstream.Stats) uint64 { return s.Retries }},
+	{"zensol_handler_panics_total", "Panics recovered from handlers.", "Handler failures", func(s *chainstream.Stats) uint64 { return s.Panics }},
+	{"zensol_notifications_failed_total", "Notifications whose handling failed after all retries.", "Handler failures", func(s *chainstream.Stats) uint64 { return s.Failed }},
+	{"zensol_notifications_dead_lettered_total", "Failed notifications passed to the dead letter hook.", "Handler failures", func(s *chainstream.Stats) uint64 { return s.DeadLettered }},
+	{"zensol_reconnects_total", "Connections replaced after a failure.", "Connections", func(s *chainstream.Stats) uint64 { return s.Reconnects }},
+	{"zensol_rotations_total", "Planned connection rotations.", "Connections", func(s *chainstream.Stats) uint64 { return s.Rotations }},
+	{"zensol_pings_total", "Pings answered with a pong.", "Connections", func(s *chainstream.Stats) uint64 { return s.Pings }},
+	{"zensol_ping_failures_total", "Pings left unanswered.", "Connections", func(s *chainstream.Stats) uint64 { return s.PingFailures }},
+	{"zensol_idle_timeouts_total", "Connections replaced for receiving nothing within the idle timeout.", "Connections", func(s *chainstream.Stats) uint64 { return s.IdleTimeouts }},
+	{"zensol_downgrades_total", "Firehose subscriptions replaced with filtered ones.", "Connections", func(s *chainstream.Stats) uint64 { return s.Downgrades }},
+	{"zensol_failbacks_total", "Sessions moved back to a preferred endpoint that recovered.", "Connections", func(s *chainstream.Stats) uint64 { return s.Failbacks }},
+	{"zensol_gaps_total", "Reconnects whose missed slots were backfilled.", "Backfill", func(s *chainstream.Stats) uint64 { return s.Gaps }},
+	{"zensol_notifications_backfilled_total", "Notifications delivered by the backfill hook.", "Backfill", func(s *chainstream.Stats) uint64 { return s.Backfilled }},
+	{"zensol_backfill_failures_total", "Gaps the backfill hook could not load completely.", "Backfill", func(s *chainstream.Stats) uint64 { return s.BackfillFailures }},
+	{"zensol_notifications_rewound_total", "Notifications replayed from an archive before live delivery.", "Backfill", func(s *chainstream.Stats) uint64 { return s.Rewound }},
+}
+
+// Names of the histograms.
+const (
+	stageLatency = "zensol_stage_latency_seconds"
+	pingRTT      = "zensol_ping_rtt_seconds"
+)
+
+// Exporter exposes the statistics of a source for Prometheus scrapes.
+type Exporter struct {
+	Source Source
+	// Subscription and Network, when not empty, label every series of the exporter as
+	// subscription and network, so that one dashboard serves all deployments.
+	Subscription string
+	Network      string
+	// Events, if not nil, is exposed as zensol_events_total.
+	Events *Events
+	// Memory, if not nil, is exposed as the zensol_memory_ metrics: the heap size and
+	// ceiling, and the entries shed by cache.
+	Memory *memguard.Guard
+	// RetryBudget, if not nil, is exposed as the zensol_retry_budget_ metrics: the retries
+	// available, and those taken and refused by kind.
+	RetryBudget *chainstream.RetryBudget
+	// Runtime adds Go runtime metrics: goroutines, heap size and allocations, and GC
+	// cycles and pauses.
+	Runtime bool
+}
+
+// WritePrometheus writes the statistics of the source in the Prometheus text format.
+func WritePrometheus(w io.Writer, source Source) error {
+	return (&Exporter{Source: source}).Write(w)
+}
+
+// Write writes the statistics in the Prometheus text format.
+func (e *Exporter) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	labels := e.labels()
+
+	stats := e.Source.Stats()
+	for _, c := range counters {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s counter\n%s%s %d\n", c.name, c.help, c.name, c.name, braced(labels), c.value(&stats))
+	}
+
+	fmt.Fprintf(bw, "# HELP %s Time spent by notifications in each pipeline stage.\n# TYPE %s histogram\n", stageLatency, stageLatency)
+	histograms := e.Source.Latency()
+	for _, stage := range chainstream.Stages {
+		writeHistogram(bw, stageLatency, labels+label(LabelStage, stage.String()), histograms[stage])
+	}
+
+	fmt.Fprintf(bw, "# HELP %s Round-trip time of pings.\n# TYPE %s histogram\n", pingRTT, pingRTT)
+	writeHistogram(bw, pingRTT, labels, e.Source.RTT())
+
+	if e.Events != nil {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s counter\n", eventsTotal, eventsHelp, eventsTotal)
+		for _, c := range e.Events.snapshot() {
+			fmt.Fprintf(bw, "%s{%s} %d\n", eventsTotal, strings.TrimSuffix(labels+label(LabelProgram, c.program)+label(LabelEventType, c.eventType), ","), c.count)
+		}
+	}
+
+	if e.Memory != nil {
+		writeMemory(bw, labels, e.Memory.Stats())
+	}
+
+	if e.RetryBudget != nil {
+		writeRetryBudget(bw, labels, e.RetryBudget.Stats())
+	}
+
+	if e.Runtime {
+		writeRuntime(bw, labels)
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP serves the statistics in the Prometheus text format.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = e.Write(w)
+}
+
+// labels returns the labels of every series, each followed by a comma.
+func (e *Exporter) labels() string {
+	var labels string
+	if e.Subscription != "" {
+		labels += label(LabelSubscription, e.Subscription)
+	}
+	if e.Network != "" {
+		labels += label(LabelNetwork, e.Network)
+	}
+	return labels
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// label formats a label pair followed by a comma.
+func label(name, value string) string {
+	return name + `="` + labelEscaper.Replace(value) + `",`
+}
+
+// braced returns labels, each followed by a comma, in braces, or nothing without labels.
+func braced(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + strings.TrimSuffix(labels, ",") + "}"
+}
+
+// writeHistogram writes the series of a histogram; labels is empty or ends with a comma.
+func writeHistogram(w io.Writer, name, labels string, h chainstream.Histogram) {
+	var cumulative uint64
+	for i, bound := range chainstream.LatencyBuckets {
+		cumulative += h.Counts[i]
+		fmt.Fprintf(w, "%s_bucket{%sle=%q} %d\n", name, labels, formatFloat(bound.Seconds()), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, labels, h.Count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, braced(labels), formatFloat(h.Sum.Seconds()))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, braced(labels), h.Count)
+}
+
+// Handler serves the statistics of the source for Prometheus scrapes.
+func Handler(source Source) http.Handler {
+	return &Exporter{Source: source}
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,106 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+	"github.com/gerasimovvladislav/zensol-go/memguard"
+	"github.com/gerasimovvladislav/zensol-go/metrics"
+)
+
+type fakeSource struct{}
+
+func (fakeSource) Stats() chainstream.Stats {
+	return chainstream.Stats{Received: 7, Delivered: 5}
+}
+
+func (fakeSource) Latency() map[chainstream.Stage]chainstream.Histogram {
+	var h chainstream.Histogram
+	h.Counts[0], h.Counts[4] = 2, 1
+	h.Count, h.Sum = 3, 1500*time.Microsecond
+	return map[chainstream.Stage]chainstream.Histogram{chainstream.StageDecode: h}
+}
+
+func (fakeSource) RTT() chainstream.Histogram {
+	var h chainstream.Histogram
+	h.Counts[8], h.Count, h.Sum = 1, 1, 80*time.Millisecond
+	return h
+}
+
+func TestWritePrometheus(t *testing.T) {
+	var out strings.Builder
+	if err := metrics.WritePrometheus(&out, fakeSource{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"zensol_notifications_received_total 7",
+		"zensol_notifications_delivered_total 5",
+		`zensol_stage_latency_seconds_bucket{stage="decode",le="1e-05"} 2`,
+		`zensol_stage_latency_seconds_bucket{stage="decode",le="0.0005"} 2`,
+		`zensol_stage_latency_seconds_bucket{stage="decode",le="0.001"} 3`,
+		`zensol_stage_latency_seconds_bucket{stage="decode",le="+Inf"} 3`,
+		`zensol_stage_latency_seconds_sum{stage="decode"} 0.0015`,
+		`zensol_stage_latency_seconds_count{stage="dispatch"} 0`,
+		`zensol_ping_rtt_seconds_bucket{le="0.1"} 1`,
+		"zensol_ping_rtt_seconds_sum 0.08",
+		"zensol_ping_rtt_seconds_count 1",
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("output is missing %q", line)
+		}
+	}
+}
+
+func TestExporterLabels(t *testing.T) {
+	events := &metrics.Events{}
+	events.Add("6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P", metrics.EventBuy)
+	events.Add("6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P", metrics.EventBuy)
+	events.Add(metrics.OtherProgram, metrics.EventFailed)
+	guard, err := memguard.New(memguard.Config{Ceiling: 1 << 30, ReadHeap: func() uint64 { return 1 << 30 }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	guard.Register("owners", memguard.ShedFunc(func(float64) int { return 3 }))
+	guard.Check()
+	budget, err := chainstream.NewRetryBudget(2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	budget.Clock = clock.NewFake(time.Now())
+	budget.Allow(chainstream.RetryReconnect)
+	budget.Allow(chainstream.RetryHandler)
+	budget.Allow(chainstream.RetryHandler)
+	exporter := &metrics.Exporter{Source: fakeSource{}, Subscription: `sniper "eu"`, Network: "solana-mainnet", Events: events, Memory: guard, RetryBudget: budget, Runtime: true}
+
+	var out strings.Builder
+	if err := exporter.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`zensol_notifications_received_total{subscription="sniper \"eu\"",network="solana-mainnet"} 7`,
+		`zensol_stage_latency_seconds_bucket{subscription="sniper \"eu\"",network="solana-mainnet",stage="decode",le="+Inf"} 3`,
+		`zensol_ping_rtt_seconds_count{subscription="sniper \"eu\"",network="solana-mainnet"} 1`,
+		`zensol_events_total{subscription="sniper \"eu\"",network="solana-mainnet",program="6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P",event_type="buy"} 2`,
+		`zensol_events_total{subscription="sniper \"eu\"",network="solana-mainnet",program="other",event_type="failed"} 1`,
+		`zensol_memory_ceiling_bytes{subscription="sniper \"eu\"",network="solana-mainnet"} 1073741824`,
+		`zensol_memory_sheds_total{subscription="sniper \"eu\"",network="solana-mainnet"} 1`,
+		`zensol_memory_shed_entries_total{subscription="sniper \"eu\"",network="solana-mainnet",cache="owners"} 3`,
+		`zensol_retry_budget_tokens{subscription="sniper \"eu\"",network="solana-mainnet"} 0`,
+		`zensol_retry_budget_granted_total{subscription="sniper \"eu\"",network="solana-mainnet",kind="handler"} 1`,
+		`zensol_retry_budget_exhausted_total{subscription="sniper \"eu\"",network="solana-mainnet",kind="handler"} 1`,
+		`zensol_retry_budget_granted_total{subscription="sniper \"eu\"",network="solana-mainnet",kind="reconnect"} 1`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("output is missing %q", line)
+		}
+	}
+
+	for _, metric := range metrics.Metrics() {
+		if !strings.Contains(out.String(), "# TYPE "+metric.Name+" "+metric.Type+"\n") {
+			t.Errorf("output is missing the %s %s documented by Metrics", metric.Type, metric.Name)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+// Names of the retry budget metrics, exposed with Exporter.RetryBudget.
+const (
+	retryTokens    = "zensol_retry_budget_tokens"
+	retryGranted   = "zensol_retry_budget_granted_total"
+	retryExhausted = "zensol_retry_budget_exhausted_total"
+)
+
+// retryMetrics lists the retry budget metrics in exposition order.
+var retryMetrics = []typedMetric{
+	{retryTokens, "gauge", "Retries available in the retry budget."},
+	{retryGranted, "counter", "Retries taken from the retry budget."},
+	{retryExhausted, "counter", "Retries given up or delayed by an exhausted retry budget."},
+}
+
+// writeRetryBudget writes the statistics of the retry budget; labels is empty or ends
+// with a comma.
+func writeRetryBudget(w io.Writer, labels string, stats chainstream.RetryBudgetStats) {
+	for _, m := range retryMetrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.typ)
+		switch m.name {
+		case retryTokens:
+			fmt.Fprintf(w, "%s%s %s\n", m.name, braced(labels), formatFloat(stats.Tokens))
+		case retryGranted, retryExhausted:
+			counts := stats.Granted
+			if m.name == retryExhausted {
+				counts = stats.Exhausted
+			}
+			for _, kind := range stats.Kinds() {
+				fmt.Fprintf(w, "%s{%s} %d\n", m.name, strings.TrimSuffix(labels+label(LabelRetryKind, string(kind)), ","), counts[kind])
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	rtmetrics "runtime/metrics"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+// Names of the Go runtime metrics, exposed with Exporter.Runtime.
+const (
+	goGoroutines  = "go_goroutines"
+	goHeapObjects = "go_heap_objects_bytes"
+	goHeapGoal    = "go_heap_goal_bytes"
+	goHeapAllocs  = "go_heap_allocs_bytes_total"
+	goGCCycles    = "go_gc_cycles_total"
+	goGCPauses    = "go_gc_pause_seconds"
+)
+
+// runtimeMetric describes a Go runtime metric exposed with the given Prometheus type,
+// read from the runtime/metrics key.
+type runtimeMetric struct {
+	name string
+	typ  string
+	help string
+	key  string
+}
+
+var runtimeMetrics = []runtimeMetric{
+	{goGoroutines, "gauge", "Goroutines that currently exist.", "/sched/goroutines:goroutines"},
+	{goHeapObjects, "gauge", "Memory occupied by live heap objects and dead ones not yet swept.", "/memory/classes/heap/objects:bytes"},
+	{goHeapGoal, "gauge", "Heap size targeted by the end of the current GC cycle.", "/gc/heap/goal:bytes"},
+	{goHeapAllocs, "counter", "Memory allocated to the heap.", "/gc/heap/allocs:bytes"},
+	{goGCCycles, "counter", "Completed GC cycles.", "/gc/cycles/total:gc-cycles"},
+	{goGCPauses, "histogram", "Stop-the-world pauses of the garbage collector.", "/sched/pauses/total/gc:seconds"},
+}
+
+// writeRuntime writes the Go runtime metrics; labels is empty or ends with a comma.
+// Metrics the runtime does not support are written without series.
+func writeRuntime(w io.Writer, labels string) {
+	samples := make([]rtmetrics.Sample, len(runtimeMetrics))
+	for i, m := range runtimeMetrics {
+		samples[i].Name = m.key
+	}
+	rtmetrics.Read(samples)
+
+	for i, m := range runtimeMetrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.typ)
+		switch value := samples[i].Value; value.Kind() {
+		case rtmetrics.KindUint64:
+			fmt.Fprintf(w, "%s%s %d\n", m.name, braced(labels), value.Uint64())
+		case rtmetrics.KindFloat64:
+			fmt.Fprintf(w, "%s%s %s\n", m.name, braced(labels), formatFloat(value.Float64()))
+		case rtmetrics.KindFloat64Histogram:
+			writeHistogram(w, m.name, labels, rebucket(value.Float64Histogram()))
+		}
+	}
+}
+
+// rebucket converts a runtime histogram of seconds to the buckets of
+// chainstream.LatencyBuckets, counting each runtime bucket in the first bucket covering
+// its upper bound. The runtime does not record the sum, which is estimated from the
+// middle of the runtime buckets.
+func rebucket(h *rtmetrics.Float64Histogram) chainstream.Histogram {
+	var result chainstream.Histogram
+	var sum float64
+	for i, count := range h.Counts {
+		if count == 0 {
+			continue
+		}
+		lower, upper := h.Buckets[i], h.Buckets[i+1]
+		j := 0
+		for j < len(chainstream.LatencyBuckets) && upper > chainstream.LatencyBuckets[j].Seconds() {
+			j++
+		}
+		result.Counts[j] += count
+		result.Count += count
+		switch {
+		case math.IsInf(upper, 1):
+			sum += lower * float64(count)
+		case math.IsInf(lower, -1):
+			sum += upper * float64(count)
+		default:
+			sum += (lower + upper) / 2 * float64(count)
+		}
+	}
+	result.Sum = time.Duration(sum * float64(time.Second))
+	return result
+}
@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+// Snapshotter provides the pipeline state. chainstream.Client implements it.
+type Snapshotter interface {
+	Snapshot() chainstream.Snapshot
+}
+
+// SnapshotHandler serves the pipeline state of the source as an indented JSON document.
+func SnapshotHandler(source Snapshotter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(source.Snapshot())
+	})
+}
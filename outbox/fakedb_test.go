@@ -0,0 +1,187 @@
+package outbox_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// fakeDB is an in-memory database/sql driver understanding the statements of the outbox.
+type fakeDB struct {
+	mu sync.Mutex
+	fakeTables
+	// failInsert makes the next outbox insert fail.
+	failInsert bool
+}
+
+type fakeTables struct {
+	nextSeq int64
+	// outbox holds the id, signature, slot and payload of the messages by sequence number.
+	outbox      map[int64][]driver.Value
+	keys        map[[2]string]int64
+	checkpoints map[string][]driver.Value
+}
+
+func (t fakeTables) clone() fakeTables {
+	return fakeTables{nextSeq: t.nextSeq, outbox: maps.Clone(t.outbox), keys: maps.Clone(t.keys), checkpoints: maps.Clone(t.checkpoints)}
+}
+
+func newFakeDB() (*sql.DB, *fakeDB) {
+	db := &fakeDB{fakeTables: fakeTables{outbox: map[int64][]driver.Value{}, keys: map[[2]string]int64{}, checkpoints: map[string][]driver.Value{}}}
+	return sql.OpenDB(db), db
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: db}, nil }
+func (db *fakeDB) Driver() driver.Driver                        { return nil }
+
+func (db *fakeDB) len() int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return len(db.outbox)
+}
+
+// fakeConn runs statements on the tables of its transaction, or on the database.
+type fakeConn struct {
+	db *fakeDB
+	tx *fakeTables
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	tables := c.db.fakeTables.clone()
+	c.tx = &tables
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.fakeTables = *c.tx
+	c.tx = nil
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.tx = nil
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	db := s.conn.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	tables := &db.fakeTables
+	if s.conn.tx != nil {
+		tables = s.conn.tx
+	}
+	switch {
+	case strings.HasPrefix(s.query, "INSERT INTO zensol_outbox "):
+		if db.failInsert {
+			db.failInsert = false
+			return nil, errors.New("insert failed")
+		}
+		for _, row := range tables.outbox {
+			if row[0] == args[0] {
+				return nil, fmt.Errorf("duplicate outbox id %v", args[0])
+			}
+		}
+		tables.nextSeq++
+		tables.outbox[tables.nextSeq] = args
+	case strings.HasPrefix(s.query, "INSERT INTO zensol_outbox_keys "):
+		key := [2]string{args[0].(string), args[1].(string)}
+		if _, ok := tables.keys[key]; ok {
+			return nil, fmt.Errorf("duplicate key %v", key)
+		}
+		tables.keys[key] = args[2].(int64)
+	case strings.HasPrefix(s.query, "DELETE FROM zensol_outbox_keys "):
+		for key, slot := range tables.keys {
+			if key[0] == args[0] && slot < args[1].(int64) {
+				delete(tables.keys, key)
+			}
+		}
+	case strings.HasPrefix(s.query, "INSERT INTO zensol_checkpoints "):
+		tables.checkpoints[args[0].(string)] = args[1:]
+	case strings.HasPrefix(s.query, "UPDATE zensol_checkpoints "):
+		if row, ok := tables.checkpoints[args[2].(string)]; ok && row[0].(int64) < args[3].(int64) {
+			tables.checkpoints[args[2].(string)] = args[:2]
+		}
+	case strings.HasPrefix(s.query, "DELETE FROM zensol_outbox "):
+		for seq, row := range tables.outbox {
+			if row[0] == args[0] {
+				delete(tables.outbox, seq)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unexpected statement %q", s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	db := s.conn.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	tables := &db.fakeTables
+	if s.conn.tx != nil {
+		tables = s.conn.tx
+	}
+	rows := &fakeRows{}
+	switch {
+	case strings.HasPrefix(s.query, "SELECT slot, signature FROM zensol_checkpoints "):
+		rows.columns = []string{"slot", "signature"}
+		if row, ok := tables.checkpoints[args[0].(string)]; ok {
+			rows.rows = append(rows.rows, row)
+		}
+	case strings.HasPrefix(s.query, "SELECT slot FROM zensol_outbox_keys "):
+		rows.columns = []string{"slot"}
+		if slot, ok := tables.keys[[2]string{args[0].(string), args[1].(string)}]; ok {
+			rows.rows = append(rows.rows, []driver.Value{slot})
+		}
+	case strings.HasPrefix(s.query, "SELECT id, signature, slot, payload FROM zensol_outbox "):
+		rows.columns = []string{"id", "signature", "slot", "payload"}
+		for _, seq := range slices.Sorted(maps.Keys(tables.outbox)) {
+			rows.rows = append(rows.rows, tables.outbox[seq])
+		}
+	default:
+		return nil, fmt.Errorf("unexpected query %q", s.query)
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
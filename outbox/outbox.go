@@ -0,0 +1,344 @@
+// Package outbox implements the transactional outbox pattern for database sinks: a
+// notification and the subscription checkpoint are written in one transaction, and a
+// separate publisher drains the outbox table. Notifications are deduplicated by their slot
+// and signature, which also make their outbox ID: every notification written is published
+// at least once, always with the same ID, so consumers that skip IDs they have seen process
+// it exactly once.
+//
+// The tables are created by the application, e.g. for PostgreSQL:
+//
+//	CREATE TABLE zensol_outbox (
+//		seq       BIGSERIAL PRIMARY KEY,
+//		id        TEXT NOT NULL UNIQUE,
+//		signature TEXT NOT NULL,
+//		slot      BIGINT NOT NULL,
+//		payload   BYTEA NOT NULL
+//	);
+//	CREATE TABLE zensol_outbox_keys (
+//		checkpoint TEXT NOT NULL,
+//		id         TEXT NOT NULL,
+//		slot       BIGINT NOT NULL,
+//		PRIMARY KEY (checkpoint, id)
+//	);
+//	CREATE TABLE zensol_checkpoints (
+//		name      TEXT PRIMARY KEY,
+//		slot      BIGINT NOT NULL,
+//		signature TEXT NOT NULL
+//	);
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+// Defaults of Config.
+const (
+	DefaultTable           = "zensol_outbox"
+	DefaultKeyTable        = "zensol_outbox_keys"
+	DefaultCheckpointTable = "zensol_checkpoints"
+	DefaultCheckpoint      = "default"
+	DefaultKeepSlots       = 1000
+	DefaultBatchSize       = 100
+	DefaultPollInterval    = time.Second
+)
+
+// Config controls an outbox.
+type Config struct {
+	// Table, KeyTable and CheckpointTable name the outbox, written keys and checkpoint
+	// tables.
+	Table           string
+	KeyTable        string
+	CheckpointTable string
+	// Checkpoint names the checkpoint row of the subscription, and its written keys.
+	Checkpoint string
+	// KeepSlots is the number of slots before the checkpoint whose keys are kept to drop
+	// replays and late notifications of concurrent workers, DefaultKeepSlots if zero.
+	// Notifications older than that are skipped.
+	KeepSlots uint64
+	// Placeholder returns the bind parameter n, starting from 1. Nil uses "?"; PostgreSQL
+	// needs Dollar.
+	Placeholder func(n int) string
+	// BatchSize is the number of messages read per query by Drain.
+	BatchSize int
+	// PollInterval is the time between drains in Run.
+	PollInterval time.Duration
+	// Clock drives Run. Nil uses the system clock.
+	Clock clock.Clock
+}
+
+// Dollar returns PostgreSQL bind parameters: $1, $2 and so on.
+func Dollar(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// Checkpoint is the position of the notification of the latest slot written to the outbox.
+type Checkpoint struct {
+	Slot      uint64
+	Signature string
+}
+
+// Message is a notification waiting in the outbox.
+type Message struct {
+	// ID is made of the slot and signature of the notification, see ID; consumers use it to
+	// drop duplicates.
+	ID        string
+	Signature string
+	Slot      uint64
+	// Payload is the notification as JSON.
+	Payload []byte
+}
+
+// Outbox writes notifications with the checkpoint and publishes them.
+type Outbox struct {
+	db     *sql.DB
+	config Config
+}
+
+// New returns an outbox stored in db.
+func New(db *sql.DB, config Config) *Outbox {
+	if config.Table == "" {
+		config.Table = DefaultTable
+	}
+	if config.KeyTable == "" {
+		config.KeyTable = DefaultKeyTable
+	}
+	if config.KeepSlots == 0 {
+		config.KeepSlots = DefaultKeepSlots
+	}
+	if config.CheckpointTable == "" {
+		config.CheckpointTable = DefaultCheckpointTable
+	}
+	if config.Checkpoint == "" {
+		config.Checkpoint = DefaultCheckpoint
+	}
+	if config.Placeholder == nil {
+		config.Placeholder = func(int) string { return "?" }
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	if config.Clock == nil {
+		config.Clock = clock.System()
+	}
+	return &Outbox{db: db, config: config}
+}
+
+// query replaces the %[n]s verbs of format with bind parameters.
+func (o *Outbox) query(format string, table string, params int) string {
+	args := []interface{}{table}
+	for i := 1; i <= params; i++ {
+		args = append(args, o.config.Placeholder(i))
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// ID returns the outbox ID of the notification of the slot and signature.
+func ID(slot uint64, signature string) string {
+	return strconv.FormatUint(slot, 10) + ":" + signature
+}
+
+// Write stores the notification in the outbox and advances the checkpoint to its slot in
+// one transaction. apply, when not nil, runs in the same transaction, e.g. to update the
+// application tables. Notifications written already, e.g. replayed after a restart, are
+// skipped, as are those more than Config.KeepSlots slots before the checkpoint.
+// Notifications may be written out of order, e.g. by several workers.
+func (o *Outbox) Write(ctx context.Context, notification *chainstream.TransactionNotification, apply func(tx *sql.Tx) error) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("outbox: cannot encode notification: %w", err)
+	}
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("outbox: cannot begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	checkpoint, found, err := o.checkpoint(ctx, tx)
+	if err != nil {
+		return err
+	}
+	slot, signature := notification.Slot(), notification.Signature()
+	if found && slot+o.config.KeepSlots < checkpoint.Slot {
+		return nil
+	}
+	id := ID(slot, signature)
+	written, err := o.written(ctx, tx, id)
+	if err != nil || written {
+		return err
+	}
+	if apply != nil {
+		if err = apply(tx); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.ExecContext(ctx,
+		o.query("INSERT INTO %[1]s (id, signature, slot, payload) VALUES (%[2]s, %[3]s, %[4]s, %[5]s)", o.config.Table, 4),
+		id, signature, int64(slot), payload); err != nil {
+		return fmt.Errorf("outbox: cannot write notification: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx,
+		o.query("INSERT INTO %[1]s (checkpoint, id, slot) VALUES (%[2]s, %[3]s, %[4]s)", o.config.KeyTable, 3),
+		o.config.Checkpoint, id, int64(slot)); err != nil {
+		return fmt.Errorf("outbox: cannot write key: %w", err)
+	}
+	if !found || slot > checkpoint.Slot {
+		if err = o.saveCheckpoint(ctx, tx, Checkpoint{Slot: slot, Signature: signature}, found); err != nil {
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("outbox: cannot commit transaction: %w", err)
+	}
+	return nil
+}
+
+// written reports whether the notification of the ID was written already.
+func (o *Outbox) written(ctx context.Context, tx *sql.Tx, id string) (bool, error) {
+	var slot int64
+	err := tx.QueryRowContext(ctx,
+		o.query("SELECT slot FROM %[1]s WHERE checkpoint = %[2]s AND id = %[3]s", o.config.KeyTable, 2),
+		o.config.Checkpoint, id).Scan(&slot)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("outbox: cannot read key: %w", err)
+	}
+	return true, nil
+}
+
+// saveCheckpoint advances the checkpoint and drops the keys that fell behind
+// Config.KeepSlots. A concurrent transaction that advanced it further is kept.
+func (o *Outbox) saveCheckpoint(ctx context.Context, tx *sql.Tx, checkpoint Checkpoint, exists bool) error {
+	var err error
+	if exists {
+		_, err = tx.ExecContext(ctx,
+			o.query("UPDATE %[1]s SET slot = %[2]s, signature = %[3]s WHERE name = %[4]s AND slot < %[5]s", o.config.CheckpointTable, 4),
+			int64(checkpoint.Slot), checkpoint.Signature, o.config.Checkpoint, int64(checkpoint.Slot))
+	} else {
+		_, err = tx.ExecContext(ctx,
+			o.query("INSERT INTO %[1]s (name, slot, signature) VALUES (%[2]s, %[3]s, %[4]s)", o.config.CheckpointTable, 3),
+			o.config.Checkpoint, int64(checkpoint.Slot), checkpoint.Signature)
+	}
+	if err != nil {
+		return fmt.Errorf("outbox: cannot save checkpoint: %w", err)
+	}
+	if checkpoint.Slot <= o.config.KeepSlots {
+		return nil
+	}
+	if _, err = tx.ExecContext(ctx,
+		o.query("DELETE FROM %[1]s WHERE checkpoint = %[2]s AND slot < %[3]s", o.config.KeyTable, 2),
+		o.config.Checkpoint, int64(checkpoint.Slot-o.config.KeepSlots)); err != nil {
+		return fmt.Errorf("outbox: cannot drop old keys: %w", err)
+	}
+	return nil
+}
+
+// queryer is implemented by *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (o *Outbox) checkpoint(ctx context.Context, q queryer) (Checkpoint, bool, error) {
+	var checkpoint Checkpoint
+	var slot int64
+	err := q.QueryRowContext(ctx,
+		o.query("SELECT slot, signature FROM %[1]s WHERE name = %[2]s", o.config.CheckpointTable, 1),
+		o.config.Checkpoint).Scan(&slot, &checkpoint.Signature)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Checkpoint{}, false, nil
+	}
+	if err != nil {
+		return Checkpoint{}, false, fmt.Errorf("outbox: cannot read checkpoint: %w", err)
+	}
+	checkpoint.Slot = uint64(slot)
+	return checkpoint, true, nil
+}
+
+// Checkpoint returns the position of the notification of the latest slot written. A
+// subscription resumes from its slot after a restart, e.g. with Hooks.Backfill.
+func (o *Outbox) Checkpoint(ctx context.Context) (Checkpoint, bool, error) {
+	return o.checkpoint(ctx, o.db)
+}
+
+// Handler returns a handler writing notifications to the outbox. Its errors are retried by
+// the pipeline like other handler errors.
+func (o *Outbox) Handler(ctx context.Context) chainstream.HandlerFunc {
+	return func(notification *chainstream.TransactionNotification) error {
+		return o.Write(ctx, notification, nil)
+	}
+}
+
+// Drain passes the messages of the outbox to publish in the order they were written,
+// deleting each one once it is published, and returns the number published. It stops at
+// the first error; the message stays in the outbox and is published again by the next
+// drain. Only one publisher may drain an outbox at a time.
+func (o *Outbox) Drain(ctx context.Context, publish func(ctx context.Context, message Message) error) (int, error) {
+	published := 0
+	for {
+		messages, err := o.batch(ctx)
+		if err != nil || len(messages) == 0 {
+			return published, err
+		}
+		for _, message := range messages {
+			if err = publish(ctx, message); err != nil {
+				return published, err
+			}
+			if _, err = o.db.ExecContext(ctx,
+				o.query("DELETE FROM %[1]s WHERE id = %[2]s", o.config.Table, 1), message.ID); err != nil {
+				return published, fmt.Errorf("outbox: cannot delete message %s: %w", message.ID, err)
+			}
+			published++
+		}
+	}
+}
+
+func (o *Outbox) batch(ctx context.Context) ([]Message, error) {
+	rows, err := o.db.QueryContext(ctx,
+		fmt.Sprintf("SELECT id, signature, slot, payload FROM %s ORDER BY seq LIMIT %d", o.config.Table, o.config.BatchSize))
+	if err != nil {
+		return nil, fmt.Errorf("outbox: cannot read messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var message Message
+		var slot int64
+		if err = rows.Scan(&message.ID, &message.Signature, &slot, &message.Payload); err != nil {
+			return nil, fmt.Errorf("outbox: cannot read messages: %w", err)
+		}
+		message.Slot = uint64(slot)
+		messages = append(messages, message)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("outbox: cannot read messages: %w", err)
+	}
+	return messages, nil
+}
+
+// Run drains the outbox every Config.PollInterval until ctx is done.
+func (o *Outbox) Run(ctx context.Context, publish func(ctx context.Context, message Message) error) error {
+	ticker := o.config.Clock.NewTicker(o.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C():
+			_, _ = o.Drain(ctx, publish)
+		}
+	}
+}
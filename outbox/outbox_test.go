@@ -0,0 +1,135 @@
+package outbox_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/outbox"
+)
+
+func notification(slot uint64, signature string) *chainstream.TransactionNotification {
+	var n chainstream.TransactionNotification
+	n.Params.Result.Value.Slot = slot
+	n.Params.Result.Context.Signature = signature
+	return &n
+}
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+	db, fake := newFakeDB()
+	box := outbox.New(db, outbox.Config{})
+
+	applied := 0
+	apply := func(*sql.Tx) error {
+		applied++
+		return nil
+	}
+	for i, slot := range []uint64{10, 11, 12} {
+		if err := box.Write(ctx, notification(slot, fmt.Sprint("sig-", i)), apply); err != nil {
+			t.Fatalf("Write() error: %v", err)
+		}
+	}
+
+	// A failed insert rolls back the application changes and the checkpoint.
+	fake.failInsert = true
+	if err := box.Write(ctx, notification(13, "sig-3"), nil); err == nil {
+		t.Fatal("Write() succeeded with a failing insert")
+	}
+	// Notifications written already, replayed after a restart, are skipped.
+	if err := box.Write(ctx, notification(11, "sig-1"), apply); err != nil {
+		t.Fatal(err)
+	}
+	checkpoint, found, err := box.Checkpoint(ctx)
+	if err != nil || !found || checkpoint != (outbox.Checkpoint{Slot: 12, Signature: "sig-2"}) {
+		t.Fatalf("Checkpoint() = %+v, %v, %v", checkpoint, found, err)
+	}
+	if applied != 3 {
+		t.Errorf("apply called %d times, expected 3", applied)
+	}
+
+	// The publisher fails on the second message, which stays in the outbox.
+	var published []string
+	failing := func(_ context.Context, message outbox.Message) error {
+		if len(published) == 1 {
+			return errors.New("broker unavailable")
+		}
+		published = append(published, message.Signature)
+		return nil
+	}
+	if n, err := box.Drain(ctx, failing); err == nil || n != 1 || fake.len() != 2 {
+		t.Fatalf("Drain() = %d, %v with %d left, expected one published message", n, err, fake.len())
+	}
+	var ids []string
+	n, err := box.Drain(ctx, func(_ context.Context, message outbox.Message) error {
+		published = append(published, message.Signature)
+		ids = append(ids, message.ID)
+		return nil
+	})
+	if err != nil || n != 2 || fake.len() != 0 {
+		t.Fatalf("Drain() = %d, %v with %d left", n, err, fake.len())
+	}
+	if expected := []string{"sig-0", "sig-1", "sig-2"}; !reflect.DeepEqual(published, expected) || !reflect.DeepEqual(ids, []string{"11:sig-1", "12:sig-2"}) {
+		t.Errorf("published %v with IDs %v, expected %v", published, ids, expected)
+	}
+}
+
+func TestOutboxWorkersOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	db, fake := newFakeDB()
+	box := outbox.New(db, outbox.Config{KeepSlots: 10})
+
+	// The second worker writes the later slot first.
+	first, second := notification(20, "sig-a"), notification(21, "sig-b")
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	wrote := make(chan struct{})
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		<-wrote
+		errs <- box.Write(ctx, first, nil)
+	}()
+	go func() {
+		defer wg.Done()
+		defer close(wrote)
+		errs <- box.Write(ctx, second, nil)
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Write() error: %v", err)
+		}
+	}
+	if fake.len() != 2 {
+		t.Fatalf("%d messages in the outbox, expected the late notification too", fake.len())
+	}
+	checkpoint, _, err := box.Checkpoint(ctx)
+	if err != nil || checkpoint != (outbox.Checkpoint{Slot: 21, Signature: "sig-b"}) {
+		t.Fatalf("Checkpoint() = %+v, %v, expected the latest slot", checkpoint, err)
+	}
+
+	// Replays are dropped, whatever their order, and notifications too old to be checked
+	// are skipped.
+	for _, n := range []*chainstream.TransactionNotification{second, first, notification(5, "sig-old")} {
+		if err := box.Write(ctx, n, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var ids []string
+	if _, err := box.Drain(ctx, func(_ context.Context, message outbox.Message) error {
+		ids = append(ids, message.ID)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{outbox.ID(21, "sig-b"), outbox.ID(20, "sig-a")}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("published %v, expected %v", ids, expected)
+	}
+}
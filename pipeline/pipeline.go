@@ -0,0 +1,310 @@
+// Package pipeline declares the handling of a transaction subscription as a chain of
+// stages, e.g.
+//
+//	err := pipeline.Source(client, request).
+//		Filter(chainstream.ExcludeFailed()).
+//		Decode(builtin.NewRegistry()).
+//		Enrich(metadata.Prefetch).
+//		Sink(store).
+//		Run(ctx)
+//
+// Stages run in the order they are added, in the handler of the subscription, so the
+// retries, dead letters, worker pool and batching of the client apply to the pipeline as
+// a whole. The pipeline is validated before subscribing: stages must be compatible with
+// the stages before them, e.g. FilterDecoded needs a Decode stage, and it must end in
+// sinks.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/decoders"
+)
+
+// ErrInvalid is returned by Validate, Run and Handle for pipelines that cannot run.
+var ErrInvalid = errors.New("pipeline: invalid pipeline")
+
+// Event is a notification passing through a pipeline with the results of its stages.
+type Event struct {
+	Notification *chainstream.TransactionNotification
+	// Decoded holds the decoded instructions in the order of Notification.Instructions,
+	// nil for those without a decoder. It is set by the Decode stage.
+	Decoded []*decoders.Decoded
+}
+
+// Sink receives the events passing every stage. A returned error is retried like the
+// error of a chainstream.HandlerFunc, with every stage and sink of the pipeline.
+type Sink func(ctx context.Context, event *Event) error
+
+// BatchSink receives the events passing every stage in batches, see
+// chainstream.C.HandleTransactionsBatches.
+type BatchSink func(ctx context.Context, events []*Event) error
+
+// handler handles an event. Stages wrap the handler of the next stage.
+type handler func(ctx context.Context, event *Event) error
+
+// stage is a step of a pipeline.
+type stage struct {
+	name string
+	wrap func(next handler) handler
+}
+
+// Pipeline is a subscription and the stages handling its notifications, built with
+// Source and the methods adding stages. Problems found while adding stages are reported
+// by Validate and Run, which keeps the chain of calls declarative.
+type Pipeline struct {
+	client  *chainstream.C
+	request *chainstream.JSONRPCRequest
+	options []chainstream.SubscribeOption
+	stages  []stage
+	sinks   []Sink
+	batch   BatchSink
+	decoded bool
+	errs    []error
+}
+
+// Source starts a pipeline handling the notifications of a transactionsSubscribe request.
+func Source(client *chainstream.C, request *chainstream.JSONRPCRequest) *Pipeline {
+	p := &Pipeline{client: client, request: request}
+	switch {
+	case client == nil:
+		p.invalid("source: client is required")
+	case request == nil:
+		p.invalid("source: request is required")
+	case request.Method != "transactionsSubscribe":
+		p.invalid("source: method %q does not deliver transactions", request.Method)
+	}
+	return p
+}
+
+// New starts a pipeline without a source. It can handle notifications with Handle, e.g.
+// in tests or when replaying a recording, but not Run.
+func New() *Pipeline {
+	return &Pipeline{}
+}
+
+// Options adds options to the subscription of Run, e.g. chainstream.WithFailFast.
+func (p *Pipeline) Options(options ...chainstream.SubscribeOption) *Pipeline {
+	p.options = append(p.options, options...)
+	return p
+}
+
+// Filter drops notifications not matching every filter.
+func (p *Pipeline) Filter(filters ...chainstream.Filter) *Pipeline {
+	match := chainstream.AllOf(filters...)
+	return p.add("filter", func(next handler) handler {
+		return func(ctx context.Context, event *Event) error {
+			if !match(event.Notification) {
+				return nil
+			}
+			return next(ctx, event)
+		}
+	})
+}
+
+// Decode decodes the instructions of notifications into Event.Decoded.
+func (p *Pipeline) Decode(registry *decoders.Registry) *Pipeline {
+	switch {
+	case registry == nil:
+		p.invalid("decode: registry is required")
+	case p.decoded:
+		p.invalid("decode: instructions are already decoded")
+	}
+	p.decoded = true
+	return p.add("decode", func(next handler) handler {
+		return func(ctx context.Context, event *Event) error {
+			event.Decoded = registry.DecodeTransaction(event.Notification)
+			return next(ctx, event)
+		}
+	})
+}
+
+// FilterDecoded drops notifications without a decoded instruction matching the filter. It
+// must follow Decode.
+func (p *Pipeline) FilterDecoded(match func(decoded *decoders.Decoded) bool) *Pipeline {
+	if !p.decoded {
+		p.invalid("filter decoded: instructions are not decoded, add Decode before")
+	}
+	return p.add("filter decoded", func(next handler) handler {
+		return func(ctx context.Context, event *Event) error {
+			for _, decoded := range event.Decoded {
+				if decoded != nil && match(decoded) {
+					return next(ctx, event)
+				}
+			}
+			return nil
+		}
+	})
+}
+
+// Enrich calls enrich with every notification, e.g. enrich.Stage.Prefetch. A returned
+// error is retried like the error of a sink.
+func (p *Pipeline) Enrich(enrich chainstream.HandlerFunc) *Pipeline {
+	if enrich == nil {
+		p.invalid("enrich: function is required")
+	}
+	return p.add("enrich", func(next handler) handler {
+		return func(ctx context.Context, event *Event) error {
+			if err := enrich(event.Notification); err != nil {
+				return err
+			}
+			return next(ctx, event)
+		}
+	})
+}
+
+// Use wraps the following stages with the middlewares. The first middleware sees a
+// notification first, see chainstream.Chain.
+func (p *Pipeline) Use(middlewares ...chainstream.Middleware) *Pipeline {
+	return p.add("middleware", func(next handler) handler {
+		return func(ctx context.Context, event *Event) error {
+			do := chainstream.Chain(func(*chainstream.TransactionNotification) error {
+				return next(ctx, event)
+			}, middlewares...)
+			return do(event.Notification)
+		}
+	})
+}
+
+// Sink adds sinks receiving the events passing every stage, in order. An event reaches a
+// sink only when the sinks before it succeeded.
+func (p *Pipeline) Sink(sinks ...Sink) *Pipeline {
+	for _, sink := range sinks {
+		if sink == nil {
+			p.invalid("sink: function is required")
+		}
+	}
+	if p.batch != nil {
+		p.invalid("sink: the pipeline already ends in a batch sink")
+	}
+	p.sinks = append(p.sinks, sinks...)
+	return p
+}
+
+// Batch ends the pipeline in a sink receiving events in batches, for sinks like databases
+// that write many at once. Batches are handled one at a time, so Pipeline.Workers of the
+// client does not apply, and a pipeline has either a batch sink or sinks.
+func (p *Pipeline) Batch(sink BatchSink) *Pipeline {
+	switch {
+	case sink == nil:
+		p.invalid("batch: function is required")
+	case p.batch != nil:
+		p.invalid("batch: the pipeline already ends in a batch sink")
+	case len(p.sinks) > 0:
+		p.invalid("batch: the pipeline already ends in sinks")
+	}
+	p.batch = sink
+	return p
+}
+
+// Stages returns the names of the stages in order, e.g. for logging the topology.
+func (p *Pipeline) Stages() []string {
+	names := make([]string, 0, len(p.stages)+1)
+	for _, s := range p.stages {
+		names = append(names, s.name)
+	}
+	switch {
+	case p.batch != nil:
+		names = append(names, "batch")
+	case len(p.sinks) > 0:
+		names = append(names, fmt.Sprintf("sinks (%d)", len(p.sinks)))
+	}
+	return names
+}
+
+// Validate reports the problems of the pipeline, wrapping ErrInvalid.
+func (p *Pipeline) Validate() error {
+	errs := p.errs
+	if len(p.sinks) == 0 && p.batch == nil {
+		errs = append(errs, fmt.Errorf("%w: no sink", ErrInvalid))
+	}
+	return errors.Join(errs...)
+}
+
+// Run subscribes and passes the notifications through the pipeline until ctx is done or
+// the subscription ends, see chainstream.C.HandleTransactionsNotifications.
+func (p *Pipeline) Run(ctx context.Context) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if p.client == nil {
+		return fmt.Errorf("%w: no source", ErrInvalid)
+	}
+	if p.batch != nil {
+		return p.client.HandleTransactionsBatches(ctx, p.request, p.batchHandler(ctx), p.options...)
+	}
+	return p.client.HandleTransactionsNotifications(ctx, p.request, p.Handler(ctx), p.options...)
+}
+
+// Handle passes a notification through the pipeline, ending in a batch of one with a
+// batch sink.
+func (p *Pipeline) Handle(ctx context.Context, notification *chainstream.TransactionNotification) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if p.batch != nil {
+		return p.batchHandler(ctx)([]*chainstream.TransactionNotification{notification})
+	}
+	return p.Handler(ctx)(notification)
+}
+
+// Handler returns the stages and sinks as a handler, e.g. for a subscription opened
+// another way. Sinks receive ctx. The pipeline must be valid and must not end in a batch
+// sink.
+func (p *Pipeline) Handler(ctx context.Context) chainstream.HandlerFunc {
+	do := p.chain(func(ctx context.Context, event *Event) error {
+		for _, sink := range p.sinks {
+			if err := sink(ctx, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return func(notification *chainstream.TransactionNotification) error {
+		return do(ctx, &Event{Notification: notification})
+	}
+}
+
+// batchHandler runs the stages of every notification of a batch, collecting the events
+// passing them for the batch sink. A stage failing fails the batch.
+func (p *Pipeline) batchHandler(ctx context.Context) chainstream.BatchHandlerFunc {
+	return func(batch []*chainstream.TransactionNotification) error {
+		events := make([]*Event, 0, len(batch))
+		do := p.chain(func(_ context.Context, event *Event) error {
+			events = append(events, event)
+			return nil
+		})
+		for _, notification := range batch {
+			if err := do(ctx, &Event{Notification: notification}); err != nil {
+				return err
+			}
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		return p.batch(ctx, events)
+	}
+}
+
+// chain wraps last with the stages.
+func (p *Pipeline) chain(last handler) handler {
+	for i := len(p.stages) - 1; i >= 0; i-- {
+		last = p.stages[i].wrap(last)
+	}
+	return last
+}
+
+func (p *Pipeline) add(name string, wrap func(next handler) handler) *Pipeline {
+	if p.batch != nil || len(p.sinks) > 0 {
+		p.invalid("%s: stage follows the sinks", name)
+	}
+	p.stages = append(p.stages, stage{name: name, wrap: wrap})
+	return p
+}
+
+func (p *Pipeline) invalid(format string, args ...interface{}) {
+	p.errs = append(p.errs, fmt.Errorf("%w: %s", ErrInvalid, fmt.Sprintf(format, args...)))
+}
@@ -0,0 +1,169 @@
+package pipeline_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/decoders"
+	"github.com/gerasimovvladislav/zensol-go/decoders/pumpfun"
+	"github.com/gerasimovvladislav/zensol-go/pipeline"
+)
+
+func loadNotification(t *testing.T, file string) *chainstream.TransactionNotification {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	var tx chainstream.TransactionNotification
+	if err := json.Unmarshal(data, &tx); err != nil {
+		t.Fatalf("failed to unmarshal tx: %v", err)
+	}
+	return &tx
+}
+
+func isBuy(decoded *decoders.Decoded) bool {
+	_, ok := decoded.Args.(*pumpfun.Buy)
+	return ok
+}
+
+func TestHandle(t *testing.T) {
+	var (
+		order []string
+		sunk  []*pipeline.Event
+	)
+	trace := func(name string) chainstream.Middleware {
+		return func(next chainstream.HandlerFunc) chainstream.HandlerFunc {
+			return func(notification *chainstream.TransactionNotification) error {
+				order = append(order, name)
+				return next(notification)
+			}
+		}
+	}
+	p := pipeline.New().
+		Filter(chainstream.ExcludeFailed()).
+		Use(trace("first"), trace("second")).
+		Decode(decoders.NewRegistry(pumpfun.New())).
+		FilterDecoded(isBuy).
+		Enrich(func(*chainstream.TransactionNotification) error {
+			order = append(order, "enrich")
+			return nil
+		}).
+		Sink(func(_ context.Context, event *pipeline.Event) error {
+			sunk = append(sunk, event)
+			return nil
+		})
+
+	for _, file := range []string{"sample_tx_buy", "sample_tx_sell"} {
+		if err := p.Handle(context.Background(), loadNotification(t, "../chainstream/testdata/"+file+".json")); err != nil {
+			t.Fatalf("Handle(%s) error: %v", file, err)
+		}
+	}
+	if len(sunk) != 1 {
+		t.Fatalf("sunk %d events, expected the buy only", len(sunk))
+	}
+	if buy := sunk[0].Decoded[2].Args.(*pumpfun.Buy); buy.MaxSolCost != 10302000 {
+		t.Errorf("decoded buy = %+v", buy)
+	}
+	if expected := []string{"first", "second", "enrich", "first", "second"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("stages ran in order %q, expected %q", order, expected)
+	}
+	expected := []string{"filter", "middleware", "decode", "filter decoded", "enrich", "sinks (1)"}
+	if stages := p.Stages(); !reflect.DeepEqual(stages, expected) {
+		t.Errorf("Stages() = %q, expected %q", stages, expected)
+	}
+}
+
+func TestHandleErrors(t *testing.T) {
+	notification := loadNotification(t, "../chainstream/testdata/sample_tx_buy.json")
+	errSink := errors.New("sink is down")
+	var calls int
+	p := pipeline.New().Sink(
+		func(context.Context, *pipeline.Event) error { return errSink },
+		func(context.Context, *pipeline.Event) error { calls++; return nil },
+	)
+	if err := p.Handle(context.Background(), notification); !errors.Is(err, errSink) {
+		t.Errorf("Handle() error = %v, expected the sink error", err)
+	}
+	if calls != 0 {
+		t.Error("a sink received an event after the sink before it failed")
+	}
+
+	errEnrich := errors.New("lookup failed")
+	p = pipeline.New().
+		Enrich(func(*chainstream.TransactionNotification) error { return errEnrich }).
+		Sink(func(context.Context, *pipeline.Event) error { calls++; return nil })
+	if err := p.Handle(context.Background(), notification); !errors.Is(err, errEnrich) {
+		t.Errorf("Handle() error = %v, expected the enrich error", err)
+	}
+	if calls != 0 {
+		t.Error("the sink received an event that failed enrichment")
+	}
+}
+
+func TestBatch(t *testing.T) {
+	var batches [][]*pipeline.Event
+	p := pipeline.New().
+		Decode(decoders.NewRegistry(pumpfun.New())).
+		Batch(func(_ context.Context, events []*pipeline.Event) error {
+			batches = append(batches, events)
+			return nil
+		})
+	if err := p.Handle(context.Background(), loadNotification(t, "../chainstream/testdata/sample_tx_sell.json")); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+	if len(batches) != 1 || len(batches[0]) != 1 || batches[0][0].Decoded == nil {
+		t.Errorf("batches = %v, expected one decoded event", batches)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	client := chainstream.NewClient(chainstream.NewConfig("wss://example.com"))
+	transactions, err := chainstream.NewTransactionsSubscribeRequest(chainstream.NetworkMainnet, chainstream.NewFilter().Build())
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks, err := chainstream.NewBlocksSubscribeRequest(chainstream.NetworkMainnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := func(context.Context, *pipeline.Event) error { return nil }
+	batch := func(context.Context, []*pipeline.Event) error { return nil }
+
+	tests := []struct {
+		name     string
+		pipeline *pipeline.Pipeline
+		valid    bool
+	}{
+		{"valid", pipeline.Source(client, transactions).Decode(decoders.NewRegistry(pumpfun.New())).FilterDecoded(isBuy).Sink(sink), true},
+		{"batch", pipeline.Source(client, transactions).Filter(chainstream.ExcludeFailed()).Batch(batch), true},
+		{"no client", pipeline.Source(nil, transactions).Sink(sink), false},
+		{"blocks", pipeline.Source(client, blocks).Sink(sink), false},
+		{"no sink", pipeline.Source(client, transactions).Decode(decoders.NewRegistry(pumpfun.New())), false},
+		{"filter decoded before decode", pipeline.Source(client, transactions).FilterDecoded(isBuy).Decode(decoders.NewRegistry(pumpfun.New())).Sink(sink), false},
+		{"decoded twice", pipeline.Source(client, transactions).Decode(decoders.NewRegistry(pumpfun.New())).Decode(decoders.NewRegistry(pumpfun.New())).Sink(sink), false},
+		{"stage after sink", pipeline.Source(client, transactions).Sink(sink).Filter(chainstream.ExcludeFailed()), false},
+		{"batch and sink", pipeline.Source(client, transactions).Sink(sink).Batch(batch), false},
+		{"nil sink", pipeline.Source(client, transactions).Sink(nil), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.pipeline.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("Validate() error: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, pipeline.ErrInvalid) {
+				t.Errorf("Validate() error = %v, expected ErrInvalid", err)
+			}
+		})
+	}
+
+	if err := pipeline.New().Sink(sink).Run(context.Background()); !errors.Is(err, pipeline.ErrInvalid) {
+		t.Errorf("Run() without a source error = %v, expected ErrInvalid", err)
+	}
+}
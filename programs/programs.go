@@ -0,0 +1,90 @@
+// Package programs lists the addresses of well-known Solana programs and accounts, for use
+// in subscription filters and transaction decoders.
+package programs
+
+// ID is the base58 address of a program or account.
+type ID string
+
+func (id ID) String() string { return string(id) }
+
+// Native and SPL programs.
+const (
+	System          ID = "11111111111111111111111111111111"
+	Vote            ID = "Vote111111111111111111111111111111111111111"
+	ComputeBudget   ID = "ComputeBudget111111111111111111111111111111"
+	Token           ID = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+	Token2022       ID = "TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb"
+	AssociatedToken ID = "ATokenGPvbdGVxr1b2hvZbsiqW5xWH25efTNsLJA8knL"
+	Memo            ID = "MemoSq4gqABAXKb96qnH8TysNcWxMyWCqXgDLGmfcHr"
+	MemoV1          ID = "Memo1UhkJRfHyvLMcVucJwxXeuD728EqVDDwQDxFMNo"
+)
+
+// Token launch and metadata programs.
+const (
+	TokenMetadata ID = "metaqbxxUerdq28cj1RbAWkYQm3ybzjb6a8bt518x1s"
+	PumpFun       ID = "6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"
+)
+
+// DEX and aggregator programs.
+const (
+	RaydiumAMM    ID = "675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8"
+	RaydiumCLMM   ID = "CAMMCzo5YL8w4VFF8KVHrK22GGUsp5VTaW7grrKgrWqK"
+	RaydiumCPMM   ID = "CPMMoo8L3F4NbTegBCKVNunggL7H1ZpdTHKxQB5qKP1C"
+	OrcaWhirlpool ID = "whirLbMiicVdio4qvUfM5KAg6Ct8VwpYzGff3uctyCc"
+	JupiterV6     ID = "JUP6LkbZbjS1jKKwapdHNy74zcZ3tLUZoi5QNyVTaV4"
+)
+
+// JitoTipAccounts are the accounts that receive Jito bundle tips.
+var JitoTipAccounts = []ID{
+	"96gYZGLnJYVFmbjzopPSU6QiEV5fGqZNyN9nmNhvrZU5",
+	"HFqU5x63VTqvQss8hp11i4wVV8bD44PvwucfZ2bU7gRe",
+	"Cw8CFyM9FkoMi7K7Crf6HNQqf4uEMzpKw6QNghXLvLkY",
+	"ADaUMid9yfUytqMBgopwjb2DTLSokTSzL1zt6iGPaS49",
+	"DfXygSm4jCyNCybVYYK6DwvWqjKee8pbDmJGcLWNDXjh",
+	"ADuUkR4vqLUMWXxW9gh6D6L8pMSawimctcNZ5pGwDcEt",
+	"DttWaMuVvTiduZRnguLF7jNxTgiMBZ1hyAumKUiL2KRL",
+	"3AVi9Tg9Uo68tJfuvoKvqKNWKkC5wPdSSdeBnizKZ6jT",
+}
+
+var names = map[ID]string{
+	System:          "System",
+	Vote:            "Vote",
+	ComputeBudget:   "Compute Budget",
+	Token:           "SPL Token",
+	Token2022:       "Token-2022",
+	AssociatedToken: "Associated Token Account",
+	Memo:            "Memo",
+	MemoV1:          "Memo v1",
+	TokenMetadata:   "Metaplex Token Metadata",
+	PumpFun:         "pump.fun",
+	RaydiumAMM:      "Raydium AMM",
+	RaydiumCLMM:     "Raydium CLMM",
+	RaydiumCPMM:     "Raydium CPMM",
+	OrcaWhirlpool:   "Orca Whirlpool",
+	JupiterV6:       "Jupiter v6",
+}
+
+// Name returns a human-readable name of a well-known program and false for other addresses.
+func Name(address string) (string, bool) {
+	name, ok := names[ID(address)]
+	return name, ok
+}
+
+// IsJitoTip reports whether the address is a Jito tip account.
+func IsJitoTip(address string) bool {
+	for _, tip := range JitoTipAccounts {
+		if string(tip) == address {
+			return true
+		}
+	}
+	return false
+}
+
+// Strings converts IDs to plain strings, e.g. for account key filters.
+func Strings(ids ...ID) []string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = string(id)
+	}
+	return s
+}
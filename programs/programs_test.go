@@ -0,0 +1,31 @@
+package programs_test
+
+import (
+	"testing"
+
+	"github.com/mr-tron/base58"
+
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+func TestAddresses(t *testing.T) {
+	ids := append([]programs.ID{
+		programs.System, programs.ComputeBudget, programs.Token, programs.Token2022,
+		programs.AssociatedToken, programs.Memo, programs.MemoV1, programs.TokenMetadata,
+		programs.PumpFun, programs.RaydiumAMM, programs.RaydiumCLMM, programs.RaydiumCPMM,
+		programs.OrcaWhirlpool, programs.JupiterV6,
+	}, programs.JitoTipAccounts...)
+
+	for _, id := range ids {
+		key, err := base58.Decode(id.String())
+		if err != nil || len(key) != 32 {
+			t.Errorf("%s is not a valid public key", id)
+		}
+	}
+	if name, ok := programs.Name(programs.PumpFun.String()); !ok || name != "pump.fun" {
+		t.Errorf("Name(PumpFun) = %q, %v", name, ok)
+	}
+	if !programs.IsJitoTip("96gYZGLnJYVFmbjzopPSU6QiEV5fGqZNyN9nmNhvrZU5") || programs.IsJitoTip(programs.System.String()) {
+		t.Error("IsJitoTip() misclassified an address")
+	}
+}
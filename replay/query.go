@@ -0,0 +1,183 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/decoders"
+)
+
+// errDone stops a query at its limit or past its slot range.
+var errDone = errors.New("replay: query done")
+
+// Query selects notifications of a recording. Unset fields do not restrict the query, and
+// a notification must match every set field.
+type Query struct {
+	// FromSlot and ToSlot bound the slots, both included. Zero ToSlot does not bound them.
+	// Recordings follow the slots, so a query stops at the first notification past ToSlot.
+	FromSlot uint64
+	ToSlot   uint64
+	// Since and Until bound the recorded time, see Archive.AgeSource; Until is excluded.
+	// Notifications without a timestamp do not match a time bound.
+	Since time.Time
+	Until time.Time
+	// Accounts matches the account keys of the transactions, see
+	// chainstream.AccountKeysFilter.Match.
+	Accounts *chainstream.AccountKeysFilter
+	// Programs matches the transactions invoking one of the programs, in a top-level or an
+	// inner instruction.
+	Programs []string
+	// Instructions matches the transactions with an instruction decoded by Registry with
+	// one of the names, e.g. buy or transferChecked.
+	Instructions []string
+	// Registry decodes the instructions for Instructions and Archive.Events.
+	Registry *decoders.Registry
+	// SkipFailed skips the transactions that failed on-chain.
+	SkipFailed bool
+	// Where, if not nil, must also report the notification matching.
+	Where func(notification *chainstream.TransactionNotification) bool
+	// Limit stops the query after that many matches. Zero does not limit it.
+	Limit int
+}
+
+// Validate checks the query: the slot range must not be reversed, and Instructions
+// requires Registry.
+func (q *Query) Validate() error {
+	var errs []error
+	if q.ToSlot != 0 && q.ToSlot < q.FromSlot {
+		errs = append(errs, fmt.Errorf("replay: slot range %d to %d is reversed", q.FromSlot, q.ToSlot))
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Until.After(q.Since) {
+		errs = append(errs, errors.New("replay: until must be after since"))
+	}
+	if len(q.Instructions) > 0 && q.Registry == nil {
+		errs = append(errs, errors.New("replay: instructions require a registry"))
+	}
+	if q.Limit < 0 {
+		errs = append(errs, errors.New("replay: limit must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// Match reports whether the notification matches the query, taking its time from the age
+// source.
+func (q *Query) Match(notification *chainstream.TransactionNotification, source chainstream.AgeSource) bool {
+	slot := notification.Slot()
+	if slot < q.FromSlot || (q.ToSlot != 0 && slot > q.ToSlot) {
+		return false
+	}
+	if !q.Since.IsZero() || !q.Until.IsZero() {
+		ts, ok := notification.Time(source)
+		if !ok || ts.Before(q.Since) || (!q.Until.IsZero() && !ts.Before(q.Until)) {
+			return false
+		}
+	}
+	if q.SkipFailed && !notification.Succeeded() {
+		return false
+	}
+	if q.Accounts != nil && !q.Accounts.Match(notification) {
+		return false
+	}
+	if len(q.Programs) > 0 || len(q.Instructions) > 0 {
+		if len(q.events(notification)) == 0 {
+			return false
+		}
+	}
+	return q.Where == nil || q.Where(notification)
+}
+
+// Event is an instruction of a recorded transaction, decoded when Query.Registry has a
+// decoder for its program.
+type Event struct {
+	Signature string `json:"signature"`
+	Slot      uint64 `json:"slot"`
+	// Index is the position of the instruction in execution order, see
+	// chainstream.TransactionNotification.Instructions.
+	Index       int                     `json:"index"`
+	Instruction chainstream.Instruction `json:"instruction"`
+	// Decoded is nil for instructions without a decoder.
+	Decoded *decoders.Decoded `json:"decoded,omitempty"`
+}
+
+// events returns the instructions of the notification matching Programs and
+// Instructions, every instruction when neither is set.
+func (q *Query) events(notification *chainstream.TransactionNotification) []Event {
+	instructions := notification.Instructions()
+	var events []Event
+	for i := range instructions {
+		ix := &instructions[i]
+		if len(q.Programs) > 0 && !contains(q.Programs, ix.ProgramID) {
+			continue
+		}
+		var decoded *decoders.Decoded
+		if q.Registry != nil {
+			decoded, _ = q.Registry.Decode(ix)
+		}
+		if len(q.Instructions) > 0 && (decoded == nil || !contains(q.Instructions, decoded.Name)) {
+			continue
+		}
+		events = append(events, Event{
+			Signature:   notification.Signature(),
+			Slot:        notification.Slot(),
+			Index:       i,
+			Instruction: *ix,
+			Decoded:     decoded,
+		})
+	}
+	return events
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Query passes the recorded notifications matching the query to do in the order they were
+// recorded, reading no further than Query.ToSlot. A non-nil error returned by do stops the query.
+func (a Archive) Query(ctx context.Context, q Query, do chainstream.HandlerFunc) error {
+	if err := q.Validate(); err != nil {
+		return err
+	}
+	matched := 0
+	err := RunFile(ctx, a.Path, Config{AgeSource: a.AgeSource}, func(notification *chainstream.TransactionNotification) error {
+		if q.ToSlot != 0 && notification.Slot() > q.ToSlot {
+			return errDone
+		}
+		if !q.Match(notification, a.AgeSource) {
+			return nil
+		}
+		if err := do(notification); err != nil {
+			return err
+		}
+		if matched++; q.Limit > 0 && matched >= q.Limit {
+			return errDone
+		}
+		return nil
+	})
+	if errors.Is(err, errDone) {
+		return nil
+	}
+	return err
+}
+
+// Events passes the instructions of the recorded notifications matching the query to do,
+// decoded with Query.Registry: the instructions matching Query.Programs and
+// Query.Instructions, or all of them when neither is set. Query.Limit counts
+// notifications. A non-nil error returned by do stops the query.
+func (a Archive) Events(ctx context.Context, q Query, do func(event Event) error) error {
+	return a.Query(ctx, q, func(notification *chainstream.TransactionNotification) error {
+		for _, event := range q.events(notification) {
+			if err := do(event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,132 @@
+package replay_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/decoders"
+	"github.com/gerasimovvladislav/zensol-go/decoders/pumpfun"
+	"github.com/gerasimovvladislav/zensol-go/replay"
+)
+
+const (
+	pumpFun = "6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"
+	trader  = "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF"
+	curve   = "62qc2CNXwrYqQScmEdiZFFAnJR262PxWEuNQtxfafNgV"
+)
+
+// archive records the sample transactions as JSON lines in a file.
+func archive(t *testing.T) replay.Archive {
+	t.Helper()
+	var b bytes.Buffer
+	for _, name := range []string{"buy", "sell", "create"} {
+		data, err := os.ReadFile("../chainstream/testdata/sample_tx_" + name + ".json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = json.Compact(&b, data); err != nil {
+			t.Fatal(err)
+		}
+		b.WriteByte('\n')
+	}
+	path := filepath.Join(t.TempDir(), "archive.jsonl")
+	if err := os.WriteFile(path, b.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return replay.Archive{Path: path}
+}
+
+func TestArchiveQuery(t *testing.T) {
+	a := archive(t)
+	tests := []struct {
+		name     string
+		query    replay.Query
+		expected []uint64
+	}{
+		{"everything", replay.Query{}, []uint64{330588464, 330587252, 343271756}},
+		{"slot range", replay.Query{FromSlot: 330588000, ToSlot: 340000000}, []uint64{330588464}},
+		{"time range", replay.Query{Since: time.Date(2025, 4, 1, 11, 59, 10, 0, time.UTC), Until: time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)}, []uint64{330587252}},
+		{"account", replay.Query{Accounts: &chainstream.AccountKeysFilter{All: []string{trader, curve}}}, []uint64{330587252}},
+		{"program", replay.Query{Programs: []string{"ATokenGPvbdGVxr1b2hvZbsiqW5xWH25efTNsLJA8knL"}}, []uint64{343271756}},
+		{"instruction", replay.Query{Instructions: []string{"sell"}, Registry: decoders.NewRegistry(pumpfun.New())}, []uint64{330587252}},
+		{"succeeded", replay.Query{Programs: []string{pumpFun}, SkipFailed: true}, []uint64{330588464, 330587252}},
+		{"where", replay.Query{Where: func(n *chainstream.TransactionNotification) bool { return n.Slot()%2 == 1 }}, nil},
+		{"limit", replay.Query{Limit: 2}, []uint64{330588464, 330587252}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var slots []uint64
+			err := a.Query(context.Background(), tt.query, func(n *chainstream.TransactionNotification) error {
+				slots = append(slots, n.Slot())
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Query() error: %v", err)
+			}
+			if !reflect.DeepEqual(slots, tt.expected) {
+				t.Errorf("slots %v, expected %v", slots, tt.expected)
+			}
+		})
+	}
+}
+
+func TestArchiveQueryStopsAfterToSlot(t *testing.T) {
+	// The line after slot 3 is not JSON: reading it would fail the query.
+	path := filepath.Join(t.TempDir(), "archive.jsonl")
+	data := recording(t, time.Now(), 0, time.Second, 2*time.Second) + "not json\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var slots []uint64
+	err := replay.Archive{Path: path}.Query(context.Background(), replay.Query{ToSlot: 2}, func(n *chainstream.TransactionNotification) error {
+		slots = append(slots, n.Slot())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Query() error: %v", err)
+	}
+	if !reflect.DeepEqual(slots, []uint64{1, 2}) {
+		t.Errorf("slots %v, expected 1 and 2", slots)
+	}
+}
+
+func TestArchiveEvents(t *testing.T) {
+	var events []replay.Event
+	err := archive(t).Events(context.Background(), replay.Query{Instructions: []string{"buy", "sell"}, Registry: decoders.NewRegistry(pumpfun.New()), SkipFailed: true}, func(event replay.Event) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Events() error: %v", err)
+	}
+	var names []string
+	for _, event := range events {
+		if event.Decoded == nil {
+			t.Fatalf("event %+v was not decoded", event)
+		}
+		names = append(names, event.Decoded.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"buy", "sell"}) {
+		t.Errorf("events %q, expected buy and sell", names)
+	}
+}
+
+func TestQueryValidate(t *testing.T) {
+	for _, q := range []replay.Query{
+		{FromSlot: 10, ToSlot: 5},
+		{Instructions: []string{"buy"}},
+		{Since: time.Unix(10, 0), Until: time.Unix(5, 0)},
+		{Limit: -1},
+	} {
+		if err := (replay.Archive{}).Query(context.Background(), q, func(*chainstream.TransactionNotification) error { return nil }); err == nil {
+			t.Errorf("Query(%+v) succeeded", q)
+		}
+	}
+}
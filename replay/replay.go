@@ -0,0 +1,133 @@
+// Package replay plays recorded notifications back through a handler, paced like the
+// original stream or faster, for backtesting and debugging.
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+// maxLineSize bounds a single recorded notification.
+const maxLineSize = 16 << 20
+
+// AsFastAsPossible replays without waiting between notifications.
+const AsFastAsPossible = 0
+
+// Config describes a replay.
+type Config struct {
+	// Speed multiplies the pace of the recording: 1 replays in real time, 10 ten times
+	// faster and AsFastAsPossible does not wait at all.
+	Speed float64
+	// AgeSource selects the recorded timestamp used for pacing.
+	AgeSource chainstream.AgeSource
+	// Clock, when set, is advanced to the recorded time of every notification before it is
+	// delivered, so components driven by it, e.g. a chainstream.Config or a tracker, see
+	// the recorded time instead of the wall clock.
+	Clock *clock.Fake
+	// Pacer waits between notifications. Nil uses the system clock.
+	Pacer clock.Clock
+}
+
+// Run reads notifications recorded as JSON lines, as written by `zensol stream -raw`, and
+// passes them to do in order. Notifications without a timestamp are delivered without
+// waiting. A non-nil error returned by do stops the replay.
+func Run(ctx context.Context, r io.Reader, cfg Config, do chainstream.HandlerFunc) error {
+	pacer := cfg.Pacer
+	if pacer == nil {
+		pacer = clock.System()
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), maxLineSize)
+
+	var (
+		first   time.Time
+		started time.Time
+		line    int
+	)
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var notification chainstream.TransactionNotification
+		if err := json.Unmarshal(scanner.Bytes(), &notification); err != nil {
+			return fmt.Errorf("replay: line %d: %w", line, err)
+		}
+
+		if ts, ok := notification.Time(cfg.AgeSource); ok {
+			if first.IsZero() {
+				first, started = ts, pacer.Now()
+			}
+			if cfg.Speed > 0 {
+				due := started.Add(time.Duration(float64(ts.Sub(first)) / cfg.Speed))
+				if wait := due.Sub(pacer.Now()); wait > 0 {
+					select {
+					case <-pacer.After(wait):
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+			}
+			if cfg.Clock != nil {
+				if d := ts.Sub(cfg.Clock.Now()); d > 0 {
+					cfg.Clock.Advance(d)
+				}
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := do(&notification); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+	return nil
+}
+
+// RunFile replays the recording at path, see Run.
+func RunFile(ctx context.Context, path string, cfg Config, do chainstream.HandlerFunc) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+	defer f.Close()
+	return Run(ctx, f, cfg, do)
+}
+
+// NewFeed returns a Feed replaying the recording at path, see Run, over an unbuffered
+// channel. Updates is closed at the end of the recording.
+func NewFeed(path string, cfg Config) chainstream.Feed[*chainstream.TransactionNotification] {
+	return chainstream.NewFeed(0, func(ctx context.Context, do func(notification *chainstream.TransactionNotification) error) error {
+		return RunFile(ctx, path, cfg, do)
+	})
+}
+
+// Archive is a recording, as written by `zensol stream -raw`, that subscriptions rewind
+// through with chainstream.WithRewind.
+type Archive struct {
+	Path string
+	// AgeSource selects the recorded timestamp compared with the start of the window.
+	AgeSource chainstream.AgeSource
+}
+
+// Replay implements chainstream.Archive. Notifications without a timestamp are skipped.
+func (a Archive) Replay(ctx context.Context, since time.Time, do func(notification *chainstream.TransactionNotification) error) error {
+	return RunFile(ctx, a.Path, Config{AgeSource: a.AgeSource}, func(notification *chainstream.TransactionNotification) error {
+		if ts, ok := notification.Time(a.AgeSource); !ok || ts.Before(since) {
+			return nil
+		}
+		return do(notification)
+	})
+}
@@ -0,0 +1,102 @@
+package replay_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+	"github.com/gerasimovvladislav/zensol-go/replay"
+)
+
+// recording returns JSON lines of notifications observed at the given offsets from start.
+func recording(t *testing.T, start time.Time, offsets ...time.Duration) string {
+	t.Helper()
+	var b strings.Builder
+	for i, offset := range offsets {
+		var n chainstream.TransactionNotification
+		n.Params.Result.Value.Slot = uint64(i + 1)
+		n.Params.Result.Context.NodeTime = start.Add(offset)
+		data, err := json.Marshal(&n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func TestReplaySpeed(t *testing.T) {
+	start := time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+	input := recording(t, start, 0, 10*time.Second, 30*time.Second)
+
+	tests := []struct {
+		name     string
+		speed    float64
+		expected []time.Duration
+	}{
+		{"real time", 1, []time.Duration{0, 10 * time.Second, 30 * time.Second}},
+		{"ten times faster", 10, []time.Duration{0, time.Second, 3 * time.Second}},
+		{"as fast as possible", replay.AsFastAsPossible, []time.Duration{0, 0, 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pacer := clock.NewFake(time.Unix(0, 0))
+			virtual := clock.NewFake(start.Add(-time.Hour))
+			go func() {
+				// Drive the pacer whenever the replay waits for it.
+				for i := 0; i < 2 && test.speed > 0; i++ {
+					pacer.BlockUntil(1)
+					next := pacer.Now().Add(test.expected[i+1] - test.expected[i])
+					pacer.Advance(next.Sub(pacer.Now()))
+				}
+			}()
+
+			var offsets []time.Duration
+			err := replay.Run(context.Background(), strings.NewReader(input), replay.Config{
+				Speed: test.speed,
+				Clock: virtual,
+				Pacer: pacer,
+			}, func(n *chainstream.TransactionNotification) error {
+				offsets = append(offsets, pacer.Now().Sub(time.Unix(0, 0)))
+				if !virtual.Now().Equal(n.Params.Result.Context.NodeTime) {
+					t.Errorf("virtual clock %v, expected the recorded time %v", virtual.Now(), n.Params.Result.Context.NodeTime)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			for i := range test.expected {
+				if offsets[i] != test.expected[i] {
+					t.Errorf("delivery offsets %v, expected %v", offsets, test.expected)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestNewFeed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stream.jsonl")
+	if err := os.WriteFile(path, []byte(recording(t, time.Now(), 0, time.Second, 2*time.Second)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stream := replay.NewFeed(path, replay.Config{Speed: replay.AsFastAsPossible})
+	if err := stream.Subscribe(context.Background()); err != nil {
+		t.Fatalf("Subscribe() error: %v", err)
+	}
+	var slots []uint64
+	for notification := range stream.Updates() {
+		slots = append(slots, notification.Slot())
+	}
+	if len(slots) != 3 || slots[2] != 3 || stream.Err() != nil {
+		t.Errorf("replayed slots %v ending with %v, expected 1, 2, 3", slots, stream.Err())
+	}
+}
@@ -0,0 +1,113 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/internal/lru"
+	"github.com/gerasimovvladislav/zensol-go/rpc"
+	"github.com/gerasimovvladislav/zensol-go/store"
+)
+
+// MintFetcher loads token mints over RPC. *rpc.Client implements it.
+type MintFetcher interface {
+	GetMint(ctx context.Context, address string) (*rpc.Mint, error)
+}
+
+// decimalsKeyPrefix namespaces mint decimals in a shared store.
+const decimalsKeyPrefix = "resolver/decimals/"
+
+// TokenDecimals resolves mints to the number of decimals of their amounts. It is safe for
+// concurrent use.
+type TokenDecimals struct {
+	cache   *lru.Cache[string, int]
+	fetcher MintFetcher
+	store   store.Store
+	ttl     time.Duration
+}
+
+// NewTokenDecimals creates a resolver caching the decimals of up to size mints. The fetcher
+// is used for mints not seen in any observed notification and may be nil.
+func NewTokenDecimals(size int, fetcher MintFetcher) *TokenDecimals {
+	return &TokenDecimals{
+		cache:   lru.New[string, int](size),
+		fetcher: fetcher,
+	}
+}
+
+// UseStore makes the resolver consult the store before fetching over RPC and keep the
+// decimals it learns there for ttl, so they survive restarts. A zero ttl keeps them forever.
+func (r *TokenDecimals) UseStore(s store.Store, ttl time.Duration) {
+	r.store, r.ttl = s, ttl
+}
+
+// Observe caches the decimals of every mint in the token balances of the notification.
+// Newly learned mints are also written to the store.
+func (r *TokenDecimals) Observe(ctx context.Context, notification *chainstream.TransactionNotification) {
+	meta := &notification.Params.Result.Value.Meta
+	for _, balances := range [][]chainstream.TokenBalance{meta.PostTokenBalances, meta.PreTokenBalances} {
+		for _, balance := range balances {
+			if balance.Mint == "" {
+				continue
+			}
+			if _, ok := r.cache.Get(balance.Mint); ok {
+				continue
+			}
+			r.learn(ctx, balance.Mint, balance.UIAmount.Decimals)
+		}
+	}
+}
+
+// Decimals returns the decimals of a mint, fetching it over RPC on a cache and store miss.
+func (r *TokenDecimals) Decimals(ctx context.Context, mint string) (int, error) {
+	if decimals, ok := r.cache.Get(mint); ok {
+		return decimals, nil
+	}
+	if r.store != nil {
+		if data, err := r.store.Get(ctx, decimalsKeyPrefix+mint); err == nil {
+			if decimals, err := strconv.Atoi(string(data)); err == nil {
+				r.cache.Add(mint, decimals)
+				return decimals, nil
+			}
+		}
+	}
+	if r.fetcher == nil {
+		return 0, fmt.Errorf("resolver: decimals of mint %s are unknown", mint)
+	}
+	account, err := r.fetcher.GetMint(ctx, mint)
+	if err != nil {
+		return 0, fmt.Errorf("resolver: cannot fetch mint %s: %w", mint, err)
+	}
+	if account == nil {
+		return 0, fmt.Errorf("resolver: mint %s does not exist", mint)
+	}
+	r.learn(ctx, mint, account.Decimals)
+	return account.Decimals, nil
+}
+
+// Scale returns a raw token amount of the mint in whole tokens.
+func (r *TokenDecimals) Scale(ctx context.Context, mint string, amount uint64) (float64, error) {
+	decimals, err := r.Decimals(ctx, mint)
+	if err != nil {
+		return 0, err
+	}
+	return float64(amount) / math.Pow10(decimals), nil
+}
+
+// Shed evicts the least recently used fraction of the cached decimals, see
+// memguard.Shedder.
+func (r *TokenDecimals) Shed(fraction float64) int {
+	return r.cache.Shed(fraction)
+}
+
+// learn caches the decimals of a mint and keeps them in the store.
+func (r *TokenDecimals) learn(ctx context.Context, mint string, decimals int) {
+	r.cache.Add(mint, decimals)
+	if r.store != nil {
+		_ = r.store.Set(ctx, decimalsKeyPrefix+mint, []byte(strconv.Itoa(decimals)), r.ttl)
+	}
+}
@@ -0,0 +1,54 @@
+package resolver_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/resolver"
+	"github.com/gerasimovvladislav/zensol-go/rpc"
+	"github.com/gerasimovvladislav/zensol-go/store"
+)
+
+type fakeMints map[string]*rpc.Mint
+
+func (f fakeMints) GetMint(_ context.Context, address string) (*rpc.Mint, error) {
+	return f[address], nil
+}
+
+func TestDecimalsObserve(t *testing.T) {
+	shared := store.NewMemory(nil)
+	decimals := resolver.NewTokenDecimals(100, nil)
+	decimals.UseStore(shared, 0)
+	decimals.Observe(context.Background(), loadNotification(t, "../chainstream/testdata/sample_tx_sell.json"))
+
+	const mint = "DNvtizsEYyknJoW3QYwDA7ncjxri3KBBTeLydEZCpump"
+	if got, err := decimals.Decimals(context.Background(), mint); err != nil || got != 6 {
+		t.Errorf("Decimals() = %d, %v, expected 6", got, err)
+	}
+	if amount, err := decimals.Scale(context.Background(), mint, 2_500_000); err != nil || amount != 2.5 {
+		t.Errorf("Scale() = %v, %v, expected 2.5", amount, err)
+	}
+
+	// A fresh resolver without RPC finds the observed decimals in the store.
+	restarted := resolver.NewTokenDecimals(100, nil)
+	restarted.UseStore(shared, 0)
+	if got, err := restarted.Decimals(context.Background(), mint); err != nil || got != 6 {
+		t.Errorf("Decimals() from store = %d, %v, expected 6", got, err)
+	}
+}
+
+func TestDecimalsFallback(t *testing.T) {
+	fetcher := fakeMints{"mint": {Decimals: 9}}
+	decimals := resolver.NewTokenDecimals(100, fetcher)
+
+	if got, err := decimals.Decimals(context.Background(), "mint"); err != nil || got != 9 {
+		t.Errorf("Decimals() = %d, %v, expected 9", got, err)
+	}
+	delete(fetcher, "mint")
+	if got, err := decimals.Decimals(context.Background(), "mint"); err != nil || got != 9 {
+		t.Errorf("cached Decimals() = %d, %v, expected 9", got, err)
+	}
+	if _, err := decimals.Decimals(context.Background(), "missing"); err == nil {
+		t.Error("expected error for unknown mint")
+	}
+}
@@ -0,0 +1,145 @@
+// Package resolver maps raw on-chain addresses to the entities behind them,
+// caching what was learned from notifications and falling back to RPC.
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/internal/lru"
+	"github.com/gerasimovvladislav/zensol-go/rpc"
+	"github.com/gerasimovvladislav/zensol-go/store"
+)
+
+// TokenAccountFetcher loads token accounts over RPC. *rpc.Client implements it.
+type TokenAccountFetcher interface {
+	GetTokenAccount(ctx context.Context, address string) (*rpc.TokenAccount, error)
+}
+
+// ownerKeyPrefix namespaces token account owners in a shared store.
+const ownerKeyPrefix = "resolver/owner/"
+
+// TokenAccountOwner is the wallet and mint of a token account.
+type TokenAccountOwner struct {
+	Owner string `json:"owner"`
+	Mint  string `json:"mint"`
+}
+
+// TokenOwners resolves token accounts to their owner wallets. It is safe for concurrent use.
+type TokenOwners struct {
+	cache   *lru.Cache[string, TokenAccountOwner]
+	fetcher TokenAccountFetcher
+	store   store.Store
+	ttl     time.Duration
+}
+
+// NewTokenOwners creates a resolver caching up to size token accounts. The fetcher is
+// used for accounts not seen in any observed notification and may be nil.
+func NewTokenOwners(size int, fetcher TokenAccountFetcher) *TokenOwners {
+	return &TokenOwners{
+		cache:   lru.New[string, TokenAccountOwner](size),
+		fetcher: fetcher,
+	}
+}
+
+// UseStore makes the resolver consult the store before fetching over RPC and keep fetched
+// owners there for ttl, so they survive restarts. A zero ttl keeps them forever.
+func (r *TokenOwners) UseStore(s store.Store, ttl time.Duration) {
+	r.store, r.ttl = s, ttl
+}
+
+// Observe caches the owners of every token account in the token balances of the notification.
+func (r *TokenOwners) Observe(notification *chainstream.TransactionNotification) {
+	keys := notification.AccountKeys()
+	meta := &notification.Params.Result.Value.Meta
+	for _, balances := range [][]chainstream.TokenBalance{meta.PreTokenBalances, meta.PostTokenBalances} {
+		for _, balance := range balances {
+			if balance.Owner == "" || balance.AccountIndex >= len(keys) {
+				continue
+			}
+			r.cache.Add(keys[balance.AccountIndex], TokenAccountOwner{Owner: balance.Owner, Mint: balance.Mint})
+		}
+	}
+}
+
+// Resolve returns the owner and mint of a token account, fetching it over RPC on a cache miss.
+func (r *TokenOwners) Resolve(ctx context.Context, tokenAccount string) (TokenAccountOwner, error) {
+	if owner, ok := r.cache.Get(tokenAccount); ok {
+		return owner, nil
+	}
+	if r.store != nil {
+		if data, err := r.store.Get(ctx, ownerKeyPrefix+tokenAccount); err == nil {
+			var owner TokenAccountOwner
+			if json.Unmarshal(data, &owner) == nil {
+				r.cache.Add(tokenAccount, owner)
+				return owner, nil
+			}
+		}
+	}
+	if r.fetcher == nil {
+		return TokenAccountOwner{}, fmt.Errorf("resolver: owner of token account %s is unknown", tokenAccount)
+	}
+	account, err := r.fetcher.GetTokenAccount(ctx, tokenAccount)
+	if err != nil {
+		return TokenAccountOwner{}, fmt.Errorf("resolver: cannot fetch token account %s: %w", tokenAccount, err)
+	}
+	if account == nil {
+		return TokenAccountOwner{}, fmt.Errorf("resolver: token account %s does not exist", tokenAccount)
+	}
+	owner := TokenAccountOwner{Owner: account.Owner, Mint: account.Mint}
+	r.cache.Add(tokenAccount, owner)
+	if r.store != nil {
+		if data, err := json.Marshal(owner); err == nil {
+			_ = r.store.Set(ctx, ownerKeyPrefix+tokenAccount, data, r.ttl)
+		}
+	}
+	return owner, nil
+}
+
+// Shed evicts the least recently used fraction of the cached owners, see
+// memguard.Shedder. Owners kept in a store are fetched from it again.
+func (r *TokenOwners) Shed(fraction float64) int {
+	return r.cache.Shed(fraction)
+}
+
+// WalletTransfer is a transfer with both sides resolved to wallets.
+type WalletTransfer struct {
+	chainstream.Transfer
+	FromWallet string `json:"fromWallet,omitempty"`
+	ToWallet   string `json:"toWallet,omitempty"`
+}
+
+// WalletTransfers observes the notification and returns its transfers reported wallet to wallet.
+// Sides that cannot be resolved are left empty; the first resolution error is returned with
+// the partially resolved transfers.
+func (r *TokenOwners) WalletTransfers(ctx context.Context, notification *chainstream.TransactionNotification) ([]WalletTransfer, error) {
+	r.Observe(notification)
+
+	var firstErr error
+	resolve := func(account string) string {
+		if account == "" {
+			return ""
+		}
+		owner, err := r.Resolve(ctx, account)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return owner.Owner
+	}
+
+	transfers := notification.Transfers()
+	result := make([]WalletTransfer, len(transfers))
+	for i, transfer := range transfers {
+		result[i].Transfer = transfer
+		if transfer.Kind == chainstream.TransferSOL {
+			result[i].FromWallet, result[i].ToWallet = transfer.From, transfer.To
+			continue
+		}
+		result[i].FromWallet = resolve(transfer.From)
+		result[i].ToWallet = resolve(transfer.To)
+	}
+	return result, firstErr
+}
@@ -0,0 +1,84 @@
+package resolver_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/resolver"
+	"github.com/gerasimovvladislav/zensol-go/rpc"
+	"github.com/gerasimovvladislav/zensol-go/store"
+)
+
+type fakeFetcher map[string]*rpc.TokenAccount
+
+func (f fakeFetcher) GetTokenAccount(_ context.Context, address string) (*rpc.TokenAccount, error) {
+	return f[address], nil
+}
+
+func loadNotification(t *testing.T, file string) *chainstream.TransactionNotification {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	var tx chainstream.TransactionNotification
+	if err := json.Unmarshal(data, &tx); err != nil {
+		t.Fatalf("failed to unmarshal tx: %v", err)
+	}
+	return &tx
+}
+
+func TestWalletTransfers(t *testing.T) {
+	owners := resolver.NewTokenOwners(100, nil)
+	transfers, err := owners.WalletTransfers(context.Background(), loadNotification(t, "../chainstream/testdata/sample_tx_sell.json"))
+	if err != nil {
+		t.Fatalf("WalletTransfers() error: %v", err)
+	}
+
+	token := transfers[0]
+	if token.Kind != chainstream.TransferToken {
+		t.Fatalf("first transfer = %+v, expected token transfer", token)
+	}
+	if token.FromWallet != "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF" || token.ToWallet != "8fC59gfiQerpTpTiEJVvB4u1UgHuBsEDGnxiQUU5AJQo" {
+		t.Errorf("wallets = %s → %s", token.FromWallet, token.ToWallet)
+	}
+}
+
+func TestResolveFallback(t *testing.T) {
+	fetcher := fakeFetcher{"tokenAccount": {Owner: "wallet", Mint: "mint"}}
+	owners := resolver.NewTokenOwners(100, fetcher)
+
+	owner, err := owners.Resolve(context.Background(), "tokenAccount")
+	if err != nil || owner.Owner != "wallet" || owner.Mint != "mint" {
+		t.Errorf("Resolve() = %+v, %v", owner, err)
+	}
+
+	delete(fetcher, "tokenAccount")
+	if owner, err = owners.Resolve(context.Background(), "tokenAccount"); err != nil || owner.Owner != "wallet" {
+		t.Errorf("cached Resolve() = %+v, %v", owner, err)
+	}
+
+	if _, err = owners.Resolve(context.Background(), "missing"); err == nil {
+		t.Error("expected error for unknown token account")
+	}
+}
+
+func TestResolveStore(t *testing.T) {
+	shared := store.NewMemory(nil)
+	fetcher := fakeFetcher{"tokenAccount": {Owner: "wallet", Mint: "mint"}}
+	owners := resolver.NewTokenOwners(100, fetcher)
+	owners.UseStore(shared, 0)
+	if _, err := owners.Resolve(context.Background(), "tokenAccount"); err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+
+	// A fresh resolver without RPC finds the owner in the store.
+	restarted := resolver.NewTokenOwners(100, nil)
+	restarted.UseStore(shared, 0)
+	if owner, err := restarted.Resolve(context.Background(), "tokenAccount"); err != nil || owner.Owner != "wallet" {
+		t.Errorf("Resolve() from store = %+v, %v", owner, err)
+	}
+}
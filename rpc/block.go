@@ -0,0 +1,244 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+// Block holds the fields of a block other than its transactions.
+type Block struct {
+	Slot              uint64  `json:"-"`
+	Blockhash         string  `json:"blockhash"`
+	PreviousBlockhash string  `json:"previousBlockhash"`
+	ParentSlot        uint64  `json:"parentSlot"`
+	BlockTime         *int64  `json:"blockTime"`
+	BlockHeight       *uint64 `json:"blockHeight"`
+	// Transactions is the number of transactions passed to the callback.
+	Transactions int `json:"-"`
+}
+
+// StreamBlock fetches the block at slot and passes its transactions to do one by one while
+// the response is being read, so memory stays bounded by the largest transaction rather
+// than the whole block. When the block time follows the transactions in the response, it
+// is fetched with GetBlockTime first. A nil block is returned for slots without a block.
+// An error returned by do stops reading and is returned as is.
+func (c *Client) StreamBlock(ctx context.Context, slot uint64, commitment chainstream.Commitment, do func(*chainstream.TransactionNotification) error) (*Block, error) {
+	const method = "getBlock"
+	body, err := c.post(ctx, method, []interface{}{slot, map[string]interface{}{
+		"encoding":                       "json",
+		"commitment":                     commitment.String(),
+		"transactionDetails":             "full",
+		"rewards":                        false,
+		"maxSupportedTransactionVersion": 0,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	s := &blockStream{dec: json.NewDecoder(body), slot: slot, commitment: commitment, do: do}
+	s.blockTime = func() (*int64, error) { return c.GetBlockTime(ctx, slot) }
+	block, err := s.response()
+	var (
+		abortErr *abortError
+		rpcErr   *Error
+	)
+	switch {
+	case errors.As(err, &abortErr):
+		return nil, abortErr.err
+	case errors.As(err, &rpcErr):
+		return nil, err
+	case err != nil:
+		return nil, fmt.Errorf("cannot decode %s response: %w", method, err)
+	}
+	return block, nil
+}
+
+// abortError marks errors that stop reading the response and are returned as is: those
+// of the StreamBlock callback and of GetBlockTime.
+type abortError struct{ err error }
+
+func (e *abortError) Error() string { return e.err.Error() }
+
+// blockStream walks a getBlock response token by token.
+type blockStream struct {
+	dec        *json.Decoder
+	slot       uint64
+	commitment chainstream.Commitment
+	do         func(*chainstream.TransactionNotification) error
+	// blockTime fetches the block time when the transactions come before it.
+	blockTime func() (*int64, error)
+}
+
+// response reads the JSON-RPC envelope.
+func (s *blockStream) response() (*Block, error) {
+	var block *Block
+	err := s.object(func(key string) error {
+		switch key {
+		case "error":
+			var rpcErr *chainstream.RPCError
+			if err := s.dec.Decode(&rpcErr); err != nil {
+				return err
+			}
+			if rpcErr != nil {
+				return &Error{Method: "getBlock", RPCError: *rpcErr}
+			}
+			return nil
+		case "result":
+			var err error
+			block, err = s.result()
+			return err
+		}
+		return s.skip()
+	})
+	return block, err
+}
+
+// result reads the block, streaming its transactions.
+func (s *blockStream) result() (*Block, error) {
+	token, err := s.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, nil
+	}
+	if token != json.Delim('{') {
+		return nil, fmt.Errorf("unexpected result %v", token)
+	}
+
+	block := &Block{Slot: s.slot}
+	var raw json.RawMessage
+	for s.dec.More() {
+		key, err := s.key()
+		if err != nil {
+			return nil, err
+		}
+		if key == "transactions" {
+			if err = s.transactions(block); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err = s.dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		var field interface{}
+		switch key {
+		case "blockhash":
+			field = &block.Blockhash
+		case "previousBlockhash":
+			field = &block.PreviousBlockhash
+		case "parentSlot":
+			field = &block.ParentSlot
+		case "blockTime":
+			field = &block.BlockTime
+		case "blockHeight":
+			field = &block.BlockHeight
+		default:
+			continue
+		}
+		if err = json.Unmarshal(raw, field); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	_, err = s.dec.Token()
+	return block, err
+}
+
+// transactions decodes the transactions array one element at a time.
+func (s *blockStream) transactions(block *Block) error {
+	token, err := s.dec.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if token != json.Delim('[') {
+		return fmt.Errorf("unexpected transactions %v", token)
+	}
+	// Nodes send blockTime after the transactions, so it is fetched separately rather
+	// than holding the block in memory.
+	if block.BlockTime == nil && s.dec.More() {
+		if block.BlockTime, err = s.blockTime(); err != nil {
+			return &abortError{err}
+		}
+	}
+	for s.dec.More() {
+		var tx chainstream.BlockTransaction
+		if err = s.dec.Decode(&tx); err != nil {
+			return fmt.Errorf("invalid transaction %d: %w", block.Transactions, err)
+		}
+		if err = s.do(s.notification(block, &tx)); err != nil {
+			return &abortError{err}
+		}
+		block.Transactions++
+	}
+	_, err = s.dec.Token()
+	return err
+}
+
+// notification converts a block transaction the same way GetTransaction does.
+func (s *blockStream) notification(block *Block, tx *chainstream.BlockTransaction) *chainstream.TransactionNotification {
+	var notification chainstream.TransactionNotification
+	notification.JSONRPC = "2.0"
+	notification.Method = "transactionNotification"
+	value := &notification.Params.Result.Value
+	value.BlockTime = block.BlockTime
+	value.Slot = s.slot
+	value.Transaction = tx.Transaction
+	value.Meta = tx.Meta
+	context := &notification.Params.Result.Context
+	context.Slot = s.slot
+	context.SlotStatus = s.commitment.String()
+	context.Index = block.Transactions
+	if len(tx.Transaction.Signatures) > 0 {
+		context.Signature = tx.Transaction.Signatures[0]
+	}
+	return &notification
+}
+
+// object calls field for every key of the next JSON object; field must consume the value.
+func (s *blockStream) object(field func(key string) error) error {
+	token, err := s.dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != json.Delim('{') {
+		return fmt.Errorf("unexpected response %v", token)
+	}
+	for s.dec.More() {
+		key, err := s.key()
+		if err != nil {
+			return err
+		}
+		if err = field(key); err != nil {
+			return err
+		}
+	}
+	_, err = s.dec.Token()
+	return err
+}
+
+func (s *blockStream) key() (string, error) {
+	token, err := s.dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := token.(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected token %v", token)
+	}
+	return key, nil
+}
+
+// skip consumes the next value.
+func (s *blockStream) skip() error {
+	var raw json.RawMessage
+	return s.dec.Decode(&raw)
+}
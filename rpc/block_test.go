@@ -0,0 +1,115 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/rpc"
+)
+
+func TestStreamBlock(t *testing.T) {
+	transactions := make([]map[string]interface{}, 3)
+	for i, sig := range []string{"sigA", "sigB", "sigC"} {
+		transactions[i] = map[string]interface{}{
+			"transaction": map[string]interface{}{"signatures": []string{sig}, "message": map[string]interface{}{"accountKeys": []string{"wallet"}}},
+			"meta":        map[string]interface{}{"fee": 5000, "err": nil},
+		}
+	}
+	srv := newServer(t, func(method string, params []json.RawMessage) (interface{}, *rpc.Error) {
+		if method != "getBlock" || string(params[0]) != "330588464" {
+			t.Errorf("call = %s%s, expected getBlock of slot 330588464", method, params)
+		}
+		return map[string]interface{}{
+			"blockHeight":       308000000,
+			"blockTime":         1700000000,
+			"blockhash":         "hash",
+			"parentSlot":        330588463,
+			"previousBlockhash": "parent",
+			"transactions":      transactions,
+		}, nil
+	})
+	client := rpc.NewClient(srv.URL)
+
+	var signatures []string
+	block, err := client.StreamBlock(context.Background(), 330588464, chainstream.CommitmentConfirmed, func(n *chainstream.TransactionNotification) error {
+		if n.Slot() != 330588464 || n.Params.Result.Value.BlockTime == nil || n.Params.Result.Value.Meta.Fee != 5000 {
+			t.Errorf("unexpected notification %+v", n.Params.Result)
+		}
+		signatures = append(signatures, n.Signature())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamBlock() error: %v", err)
+	}
+	if block.Blockhash != "hash" || block.ParentSlot != 330588463 || *block.BlockHeight != 308000000 || block.Transactions != 3 {
+		t.Errorf("block = %+v", block)
+	}
+	if len(signatures) != 3 || signatures[2] != "sigC" {
+		t.Errorf("signatures = %v", signatures)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	_, err = client.StreamBlock(context.Background(), 330588464, chainstream.CommitmentConfirmed, func(*chainstream.TransactionNotification) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("StreamBlock() = %v after %d calls, expected the callback error after 1", err, calls)
+	}
+}
+
+func TestStreamBlockTimeAfterTransactions(t *testing.T) {
+	// Nodes send the fields in this order.
+	block := json.RawMessage(`{
+		"blockHeight": 308000000,
+		"blockhash": "hash",
+		"parentSlot": 330588463,
+		"previousBlockhash": "parent",
+		"transactions": [{"transaction": {"signatures": ["sigA"], "message": {"accountKeys": ["wallet"]}}, "meta": {"fee": 5000, "err": null}}],
+		"blockTime": 1700000000
+	}`)
+	var methods []string
+	srv := newServer(t, func(method string, _ []json.RawMessage) (interface{}, *rpc.Error) {
+		methods = append(methods, method)
+		if method == "getBlockTime" {
+			return 1700000000, nil
+		}
+		return block, nil
+	})
+
+	var blockTimes []int64
+	got, err := rpc.NewClient(srv.URL).StreamBlock(context.Background(), 330588464, chainstream.CommitmentConfirmed, func(n *chainstream.TransactionNotification) error {
+		if blockTime := n.Params.Result.Value.BlockTime; blockTime != nil {
+			blockTimes = append(blockTimes, *blockTime)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamBlock() error: %v", err)
+	}
+	if len(blockTimes) != 1 || blockTimes[0] != 1700000000 || got.BlockTime == nil || *got.BlockTime != 1700000000 {
+		t.Errorf("block times %v, block %+v, expected 1700000000", blockTimes, got)
+	}
+	if len(methods) != 2 || methods[1] != "getBlockTime" {
+		t.Errorf("calls = %v, expected getBlock then getBlockTime", methods)
+	}
+}
+
+func TestStreamBlockSkipped(t *testing.T) {
+	srv := newServer(t, func(string, []json.RawMessage) (interface{}, *rpc.Error) {
+		rpcErr := &rpc.Error{}
+		rpcErr.Code, rpcErr.Message = -32007, "Slot 1 was skipped"
+		return nil, rpcErr
+	})
+	_, err := rpc.NewClient(srv.URL).StreamBlock(context.Background(), 1, chainstream.CommitmentConfirmed, func(*chainstream.TransactionNotification) error {
+		return nil
+	})
+	var rpcErr *rpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != -32007 {
+		t.Errorf("StreamBlock() = %v, expected the RPC error", err)
+	}
+}
@@ -0,0 +1,101 @@
+// Package rpc is a minimal Solana JSON-RPC client over HTTP.
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+// Client calls Solana JSON-RPC methods on a single HTTP endpoint.
+type Client struct {
+	endpoint   string
+	httpClient *http.Client
+	nextID     atomic.Int64
+}
+
+// NewClient creates a client for the given HTTP RPC endpoint.
+func NewClient(endpoint string) *Client {
+	return &Client{
+		endpoint:   endpoint,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// WithHTTPClient replaces the HTTP client used for requests.
+func (c *Client) WithHTTPClient(httpClient *http.Client) *Client {
+	c.httpClient = httpClient
+	return c
+}
+
+// Error is an error returned by the RPC node.
+type Error struct {
+	Method string
+	chainstream.RPCError
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s error %d: %s", e.Method, e.Code, e.Message)
+}
+
+// Call invokes method with params and decodes the result into result.
+func (c *Client) Call(ctx context.Context, method string, params []interface{}, result interface{}) error {
+	body, err := c.post(ctx, method, params)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	var rpcResp struct {
+		Result json.RawMessage       `json:"result"`
+		Error  *chainstream.RPCError `json:"error"`
+	}
+	if err = json.NewDecoder(body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("cannot decode %s response: %w", method, err)
+	}
+	if rpcResp.Error != nil {
+		return &Error{Method: method, RPCError: *rpcResp.Error}
+	}
+	if result == nil {
+		return nil
+	}
+	if err = json.Unmarshal(rpcResp.Result, result); err != nil {
+		return fmt.Errorf("cannot decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+// post sends the request and returns the body of a successful HTTP response.
+func (c *Client) post(ctx context.Context, method string, params []interface{}) (io.ReadCloser, error) {
+	body, err := json.Marshal(chainstream.JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      int(c.nextID.Add(1)),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode %s request: %w", method, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot call %s: %w", method, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("cannot call %s: unexpected status %s", method, resp.Status)
+	}
+	return resp.Body, nil
+}
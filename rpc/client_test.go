@@ -0,0 +1,252 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/rpc"
+)
+
+func newServer(t *testing.T, handle func(method string, params []json.RawMessage) (interface{}, *rpc.Error)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int               `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("cannot decode request: %v", err)
+			return
+		}
+		result, rpcErr := handle(req.Method, req.Params)
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result}
+		if rpcErr != nil {
+			resp["error"] = rpcErr.RPCError
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGetSlot(t *testing.T) {
+	srv := newServer(t, func(method string, _ []json.RawMessage) (interface{}, *rpc.Error) {
+		if method != "getSlot" {
+			t.Errorf("method = %q, expected getSlot", method)
+		}
+		return 330588464, nil
+	})
+
+	slot, err := rpc.NewClient(srv.URL).GetSlot(context.Background(), chainstream.CommitmentConfirmed)
+	if err != nil {
+		t.Fatalf("GetSlot() error: %v", err)
+	}
+	if slot != 330588464 {
+		t.Errorf("GetSlot() = %d, expected 330588464", slot)
+	}
+}
+
+func TestCallError(t *testing.T) {
+	srv := newServer(t, func(string, []json.RawMessage) (interface{}, *rpc.Error) {
+		rpcErr := &rpc.Error{}
+		rpcErr.Code, rpcErr.Message = -32602, "Invalid param"
+		return nil, rpcErr
+	})
+
+	_, err := rpc.NewClient(srv.URL).GetSignaturesForAddress(context.Background(), "bad", nil)
+	var rpcErr *rpc.Error
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("expected *rpc.Error, got %v", err)
+	}
+	if rpcErr.Code != -32602 || rpcErr.Method != "getSignaturesForAddress" {
+		t.Errorf("unexpected error %+v", rpcErr)
+	}
+}
+
+func TestGetTransactionNotFound(t *testing.T) {
+	srv := newServer(t, func(string, []json.RawMessage) (interface{}, *rpc.Error) {
+		return nil, nil
+	})
+
+	notification, err := rpc.NewClient(srv.URL).GetTransaction(context.Background(), "sig", chainstream.CommitmentConfirmed)
+	if err != nil || notification != nil {
+		t.Errorf("GetTransaction() = %v, %v, expected nil, nil", notification, err)
+	}
+}
+
+func TestGetMint(t *testing.T) {
+	srv := newServer(t, func(method string, params []json.RawMessage) (interface{}, *rpc.Error) {
+		if method != "getAccountInfo" {
+			t.Errorf("method = %q, expected getAccountInfo", method)
+		}
+		parsed := map[string]interface{}{"type": "mint", "info": map[string]interface{}{"decimals": 6, "supply": "1000", "isInitialized": true}}
+		if string(params[0]) != `"mint"` {
+			parsed["type"] = "account"
+		}
+		return map[string]interface{}{"value": map[string]interface{}{"data": map[string]interface{}{"parsed": parsed}}}, nil
+	})
+
+	client := rpc.NewClient(srv.URL)
+	mint, err := client.GetMint(context.Background(), "mint")
+	if err != nil || mint.Decimals != 6 || mint.Supply != "1000" {
+		t.Errorf("GetMint() = %+v, %v", mint, err)
+	}
+	if _, err = client.GetMint(context.Background(), "tokenAccount"); err == nil {
+		t.Error("expected error for an account that is not a mint")
+	}
+}
+
+func TestGetTokenLargestAccounts(t *testing.T) {
+	srv := newServer(t, func(method string, params []json.RawMessage) (interface{}, *rpc.Error) {
+		if method != "getTokenLargestAccounts" || string(params[0]) != `"mint"` {
+			t.Errorf("method = %q, params %s", method, params)
+		}
+		return map[string]interface{}{"value": []map[string]interface{}{
+			{"address": "curve", "amount": "900", "decimals": 6, "uiAmountString": "0.0009"},
+			{"address": "holder", "amount": "100", "decimals": 6, "uiAmountString": "0.0001"},
+		}}, nil
+	})
+
+	accounts, err := rpc.NewClient(srv.URL).GetTokenLargestAccounts(context.Background(), "mint", chainstream.CommitmentConfirmed)
+	if err != nil {
+		t.Fatalf("GetTokenLargestAccounts() error: %v", err)
+	}
+	if len(accounts) != 2 || accounts[0].Address != "curve" || accounts[0].Amount != "900" || accounts[1].Decimals != 6 {
+		t.Errorf("GetTokenLargestAccounts() = %+v", accounts)
+	}
+}
+
+func TestGetAccountInfo(t *testing.T) {
+	srv := newServer(t, func(method string, params []json.RawMessage) (interface{}, *rpc.Error) {
+		if method != "getAccountInfo" || string(params[1]) != `{"commitment":"confirmed","encoding":"base64"}` {
+			t.Errorf("called %s %s", method, params)
+		}
+		return json.RawMessage(`{"context":{"apiVersion":"2.0.15","slot":330588464},"value":{"data":["AQI=","base64"],"executable":false,"lamports":1000,"owner":"11111111111111111111111111111111","rentEpoch":0,"space":2}}`), nil
+	})
+
+	params := chainstream.AccountSubscribeParams{Account: "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF"}
+	account, err := rpc.NewClient(srv.URL).AccountSnapshot(params)(context.Background())
+	if err != nil {
+		t.Fatalf("AccountSnapshot() error: %v", err)
+	}
+	if account.Slot() != 330588464 || account.Params.Result.Value.Lamports != 1000 {
+		t.Errorf("account = %+v", account.Params.Result)
+	}
+	if data, err := account.Params.Result.Value.Data.Bytes(); err != nil || len(data) != 2 {
+		t.Errorf("Bytes() = %v, %v", data, err)
+	}
+}
+
+func TestBlockhashValidity(t *testing.T) {
+	height := 100
+	srv := newServer(t, func(method string, _ []json.RawMessage) (interface{}, *rpc.Error) {
+		if method != "getBlockHeight" {
+			t.Errorf("method = %q, expected getBlockHeight", method)
+		}
+		height++
+		return height, nil
+	})
+
+	valid := rpc.NewClient(srv.URL).BlockhashValidity(102, chainstream.CommitmentConfirmed)
+	for _, expected := range []bool{true, true, false} {
+		if ok, err := valid(context.Background()); err != nil || ok != expected {
+			t.Errorf("valid at height %d = %v, %v, expected %v", height, ok, err, expected)
+		}
+	}
+}
+
+func TestSendTransaction(t *testing.T) {
+	const signature = "5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnbJLgp8uirBgmQpjKhoR4tjF3ZpRzrFmBV6UjKdiSZkQUW"
+	var params []json.RawMessage
+	srv := newServer(t, func(method string, p []json.RawMessage) (interface{}, *rpc.Error) {
+		if method != "sendTransaction" {
+			t.Errorf("method = %q, expected sendTransaction", method)
+		}
+		params = p
+		return signature, nil
+	})
+
+	retries := uint(0)
+	sent, err := rpc.NewClient(srv.URL).SendTransaction(context.Background(), []byte{1, 2, 3}, &rpc.SendOptions{
+		SkipPreflight:       true,
+		PreflightCommitment: chainstream.CommitmentProcessed,
+		MaxRetries:          &retries,
+	})
+	if err != nil {
+		t.Fatalf("SendTransaction() error: %v", err)
+	}
+	if sent != signature {
+		t.Errorf("SendTransaction() = %q, expected %q", sent, signature)
+	}
+	if len(params) != 2 || string(params[0]) != `"AQID"` ||
+		string(params[1]) != `{"encoding":"base64","skipPreflight":true,"preflightCommitment":"processed","maxRetries":0}` {
+		t.Errorf("params = %s", params)
+	}
+
+	if _, err = rpc.NewClient(srv.URL).SendTransaction(context.Background(), []byte{1, 2, 3}, nil); err != nil {
+		t.Fatalf("SendTransaction() error: %v", err)
+	}
+	if string(params[1]) != `{"encoding":"base64"}` {
+		t.Errorf("params without options = %s", params)
+	}
+}
+
+func TestSignatureCommitments(t *testing.T) {
+	srv := newServer(t, func(method string, params []json.RawMessage) (interface{}, *rpc.Error) {
+		if method != "getSignatureStatuses" || string(params[0]) != `["a","b","c"]` {
+			t.Errorf("called %s with %s", method, params)
+		}
+		return map[string]interface{}{
+			"context": map[string]interface{}{"slot": 82},
+			"value": []interface{}{
+				map[string]interface{}{"slot": 72, "confirmations": 10, "err": nil, "confirmationStatus": "confirmed"},
+				nil,
+				map[string]interface{}{"slot": 48, "confirmations": nil, "err": nil, "confirmationStatus": "finalized"},
+			},
+		}, nil
+	})
+
+	levels, err := rpc.NewClient(srv.URL).SignatureCommitments(context.Background(), []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("SignatureCommitments() error: %v", err)
+	}
+	expected := []chainstream.Commitment{chainstream.CommitmentConfirmed, 0, chainstream.CommitmentFinalized}
+	if len(levels) != len(expected) || levels[0] != expected[0] || levels[1] != expected[1] || levels[2] != expected[2] {
+		t.Errorf("SignatureCommitments() = %v, expected %v", levels, expected)
+	}
+}
+
+func TestLookupSignature(t *testing.T) {
+	srv := newServer(t, func(method string, params []json.RawMessage) (interface{}, *rpc.Error) {
+		if method != "getSignatureStatuses" {
+			t.Errorf("called %s", method)
+		}
+		if string(params[0]) == `["unknown"]` {
+			return map[string]interface{}{"context": map[string]interface{}{"slot": 82}, "value": []interface{}{nil}}, nil
+		}
+		return map[string]interface{}{
+			"context": map[string]interface{}{"slot": 82},
+			"value": []interface{}{
+				map[string]interface{}{"slot": 72, "confirmations": 10, "err": map[string]interface{}{"InstructionError": []interface{}{0, "InvalidArgument"}}, "confirmationStatus": "confirmed"},
+			},
+		}, nil
+	})
+	client := rpc.NewClient(srv.URL)
+
+	status, err := client.LookupSignature(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("LookupSignature() error: %v", err)
+	}
+	if status.Signature != "a" || status.Slot != 72 || status.Commitment != chainstream.CommitmentConfirmed || status.Succeeded() {
+		t.Errorf("LookupSignature() = %+v", status)
+	}
+	if status, err = client.LookupSignature(context.Background(), "unknown"); status != nil || err != nil {
+		t.Errorf("LookupSignature() = %+v, %v for an unknown signature", status, err)
+	}
+}
@@ -0,0 +1,317 @@
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+// SignatureInfo is an entry returned by getSignaturesForAddress.
+type SignatureInfo struct {
+	Signature string          `json:"signature"`
+	Slot      uint64          `json:"slot"`
+	Err       json.RawMessage `json:"err"`
+	BlockTime *int64          `json:"blockTime"`
+}
+
+// SignaturesOptions are optional parameters of getSignaturesForAddress.
+type SignaturesOptions struct {
+	Limit      int                    `json:"limit,omitempty"`
+	Before     string                 `json:"before,omitempty"`
+	Until      string                 `json:"until,omitempty"`
+	Commitment chainstream.Commitment `json:"commitment,omitempty"`
+}
+
+// GetSlot returns the current slot at the given commitment.
+func (c *Client) GetSlot(ctx context.Context, commitment chainstream.Commitment) (uint64, error) {
+	var slot uint64
+	err := c.Call(ctx, "getSlot", []interface{}{commitmentConfig(commitment)}, &slot)
+	return slot, err
+}
+
+// GetSignaturesForAddress returns signatures of transactions mentioning address, newest first.
+func (c *Client) GetSignaturesForAddress(ctx context.Context, address string, opts *SignaturesOptions) ([]SignatureInfo, error) {
+	params := []interface{}{address}
+	if opts != nil {
+		params = append(params, opts)
+	}
+	var signatures []SignatureInfo
+	err := c.Call(ctx, "getSignaturesForAddress", params, &signatures)
+	return signatures, err
+}
+
+// GetTransaction returns a confirmed transaction as a notification, so that it can be
+// handled the same way as streamed transactions. It returns nil if the transaction is not found.
+func (c *Client) GetTransaction(ctx context.Context, signature string, commitment chainstream.Commitment) (*chainstream.TransactionNotification, error) {
+	var value *chainstream.TransactionValue
+	err := c.Call(ctx, "getTransaction", []interface{}{signature, map[string]interface{}{
+		"encoding":                       "json",
+		"commitment":                     commitment.String(),
+		"maxSupportedTransactionVersion": 0,
+	}}, &value)
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	var notification chainstream.TransactionNotification
+	notification.JSONRPC = "2.0"
+	notification.Method = "transactionNotification"
+	notification.Params.Result.Value = *value
+	notification.Params.Result.Context.Slot = value.Slot
+	notification.Params.Result.Context.SlotStatus = commitment.String()
+	notification.Params.Result.Context.Signature = signature
+	return &notification, nil
+}
+
+// SignatureStatus is an entry returned by getSignatureStatuses.
+type SignatureStatus struct {
+	Slot uint64 `json:"slot"`
+	// Confirmations is the number of blocks confirmed since, nil once the slot is rooted.
+	Confirmations *uint64         `json:"confirmations"`
+	Err           json.RawMessage `json:"err"`
+	// ConfirmationStatus is processed, confirmed or finalized.
+	ConfirmationStatus string `json:"confirmationStatus"`
+}
+
+// GetSignatureStatuses returns the statuses of up to 256 signatures, in their order, nil
+// for the signatures the node does not know. Only the recent statuses kept by the node are
+// searched.
+func (c *Client) GetSignatureStatuses(ctx context.Context, signatures []string) ([]*SignatureStatus, error) {
+	var result struct {
+		Value []*SignatureStatus `json:"value"`
+	}
+	err := c.Call(ctx, "getSignatureStatuses", []interface{}{signatures}, &result)
+	return result.Value, err
+}
+
+// SignatureCommitments returns the commitment levels reached by the signatures, zero for
+// the signatures not found, for chainstream.NewCommitmentSplitter.
+func (c *Client) SignatureCommitments(ctx context.Context, signatures []string) ([]chainstream.Commitment, error) {
+	statuses, err := c.GetSignatureStatuses(ctx, signatures)
+	if err != nil {
+		return nil, err
+	}
+	levels := make([]chainstream.Commitment, len(statuses))
+	for i, status := range statuses {
+		if status != nil {
+			levels[i], _ = chainstream.ParseCommitment(status.ConfirmationStatus)
+		}
+	}
+	return levels, nil
+}
+
+// LookupSignature returns the status of the transaction with the signature, nil if the node
+// does not know it, for chainstream.WithSignatureLookup.
+func (c *Client) LookupSignature(ctx context.Context, signature string) (*chainstream.SignatureStatus, error) {
+	statuses, err := c.GetSignatureStatuses(ctx, []string{signature})
+	if err != nil || len(statuses) == 0 || statuses[0] == nil {
+		return nil, err
+	}
+	commitment, err := chainstream.ParseCommitment(statuses[0].ConfirmationStatus)
+	if err != nil {
+		return nil, err
+	}
+	return &chainstream.SignatureStatus{
+		Signature:  signature,
+		Slot:       statuses[0].Slot,
+		Commitment: commitment,
+		Err:        statuses[0].Err,
+	}, nil
+}
+
+// GetBlockHeight returns the current block height at the given commitment.
+func (c *Client) GetBlockHeight(ctx context.Context, commitment chainstream.Commitment) (uint64, error) {
+	var height uint64
+	err := c.Call(ctx, "getBlockHeight", []interface{}{commitmentConfig(commitment)}, &height)
+	return height, err
+}
+
+// GetBlockTime returns the estimated production time of the block of the slot as a Unix
+// timestamp, nil if the node does not know it.
+func (c *Client) GetBlockTime(ctx context.Context, slot uint64) (*int64, error) {
+	var blockTime *int64
+	err := c.Call(ctx, "getBlockTime", []interface{}{slot}, &blockTime)
+	return blockTime, err
+}
+
+// Blockhash is a recent blockhash and the last block height at which transactions using
+// it are valid.
+type Blockhash struct {
+	Blockhash            string `json:"blockhash"`
+	LastValidBlockHeight uint64 `json:"lastValidBlockHeight"`
+}
+
+// GetLatestBlockhash returns the latest blockhash at the given commitment.
+func (c *Client) GetLatestBlockhash(ctx context.Context, commitment chainstream.Commitment) (*Blockhash, error) {
+	var result struct {
+		Value Blockhash `json:"value"`
+	}
+	if err := c.Call(ctx, "getLatestBlockhash", []interface{}{commitmentConfig(commitment)}, &result); err != nil {
+		return nil, err
+	}
+	return &result.Value, nil
+}
+
+// SendOptions are optional parameters of sendTransaction.
+type SendOptions struct {
+	// SkipPreflight sends the transaction without simulating it first.
+	SkipPreflight bool `json:"skipPreflight,omitempty"`
+	// PreflightCommitment is the commitment the simulation runs at, finalized if unset.
+	PreflightCommitment chainstream.Commitment `json:"preflightCommitment,omitempty"`
+	// MaxRetries is the number of times the node resends the transaction to the leader.
+	// Nil lets the node resend it until its blockhash expires.
+	MaxRetries *uint `json:"maxRetries,omitempty"`
+}
+
+// SendTransaction submits a signed, serialized transaction and returns its signature. The
+// transaction is only received by the node: its signature has to be confirmed, e.g. by a
+// subscription. A failed simulation is returned as an *Error.
+func (c *Client) SendTransaction(ctx context.Context, serializedTx []byte, opts *SendOptions) (string, error) {
+	config := struct {
+		Encoding string `json:"encoding"`
+		SendOptions
+	}{Encoding: "base64"}
+	if opts != nil {
+		config.SendOptions = *opts
+	}
+	var signature string
+	err := c.Call(ctx, "sendTransaction", []interface{}{base64.StdEncoding.EncodeToString(serializedTx), config}, &signature)
+	return signature, err
+}
+
+// BlockhashValidity returns a chainstream.BlockhashValidity reporting the blockhash valid
+// while the block height at the commitment does not exceed lastValidBlockHeight, for
+// chainstream.WithBlockhashExpiry.
+func (c *Client) BlockhashValidity(lastValidBlockHeight uint64, commitment chainstream.Commitment) chainstream.BlockhashValidity {
+	return func(ctx context.Context) (bool, error) {
+		height, err := c.GetBlockHeight(ctx, commitment)
+		if err != nil {
+			return false, err
+		}
+		return height <= lastValidBlockHeight, nil
+	}
+}
+
+func commitmentConfig(commitment chainstream.Commitment) map[string]string {
+	if commitment == 0 {
+		return map[string]string{}
+	}
+	return map[string]string{"commitment": commitment.String()}
+}
+
+// TokenAccountBalance is an entry returned by getTokenLargestAccounts.
+type TokenAccountBalance struct {
+	Address string `json:"address"`
+	chainstream.TokenAmountUI
+}
+
+// GetTokenLargestAccounts returns the largest token accounts of a mint, at most 20, largest
+// first.
+func (c *Client) GetTokenLargestAccounts(ctx context.Context, mint string, commitment chainstream.Commitment) ([]TokenAccountBalance, error) {
+	var result struct {
+		Value []TokenAccountBalance `json:"value"`
+	}
+	err := c.Call(ctx, "getTokenLargestAccounts", []interface{}{mint, commitmentConfig(commitment)}, &result)
+	return result.Value, err
+}
+
+// TokenAccount is the parsed state of an SPL token account.
+type TokenAccount struct {
+	Mint        string                    `json:"mint"`
+	Owner       string                    `json:"owner"`
+	State       string                    `json:"state"`
+	TokenAmount chainstream.TokenAmountUI `json:"tokenAmount"`
+}
+
+// GetTokenAccount returns the parsed state of a token account, or nil if it does not exist.
+func (c *Client) GetTokenAccount(ctx context.Context, address string) (*TokenAccount, error) {
+	var result struct {
+		Value *struct {
+			Data struct {
+				Parsed struct {
+					Type string       `json:"type"`
+					Info TokenAccount `json:"info"`
+				} `json:"parsed"`
+			} `json:"data"`
+		} `json:"value"`
+	}
+	err := c.Call(ctx, "getAccountInfo", []interface{}{address, map[string]string{"encoding": "jsonParsed"}}, &result)
+	if err != nil || result.Value == nil {
+		return nil, err
+	}
+	if result.Value.Data.Parsed.Type != "account" {
+		return nil, fmt.Errorf("%s is not a token account", address)
+	}
+	return &result.Value.Data.Parsed.Info, nil
+}
+
+// Mint is the parsed state of an SPL token mint.
+type Mint struct {
+	Decimals        int    `json:"decimals"`
+	Supply          string `json:"supply"`
+	MintAuthority   string `json:"mintAuthority"`
+	FreezeAuthority string `json:"freezeAuthority"`
+	IsInitialized   bool   `json:"isInitialized"`
+}
+
+// GetMint returns the parsed state of a token mint, or nil if it does not exist.
+func (c *Client) GetMint(ctx context.Context, address string) (*Mint, error) {
+	var result struct {
+		Value *struct {
+			Data struct {
+				Parsed struct {
+					Type string `json:"type"`
+					Info Mint   `json:"info"`
+				} `json:"parsed"`
+			} `json:"data"`
+		} `json:"value"`
+	}
+	err := c.Call(ctx, "getAccountInfo", []interface{}{address, map[string]string{"encoding": "jsonParsed"}}, &result)
+	if err != nil || result.Value == nil {
+		return nil, err
+	}
+	if result.Value.Data.Parsed.Type != "mint" {
+		return nil, fmt.Errorf("%s is not a token mint", address)
+	}
+	return &result.Value.Data.Parsed.Info, nil
+}
+
+// GetAccountInfo returns the state of an account in the encoding as an account
+// notification of the slot it was read at, so that it can be handled the same way as
+// streamed changes. It returns nil if the account does not exist.
+func (c *Client) GetAccountInfo(ctx context.Context, address string, encoding chainstream.AccountEncoding, commitment chainstream.Commitment) (*chainstream.AccountNotification, error) {
+	var result struct {
+		Context chainstream.ContextMetadata `json:"context"`
+		Value   *chainstream.AccountValue   `json:"value"`
+	}
+	err := c.Call(ctx, "getAccountInfo", []interface{}{address, map[string]string{"encoding": string(encoding), "commitment": commitment.String()}}, &result)
+	if err != nil || result.Value == nil {
+		return nil, err
+	}
+
+	var notification chainstream.AccountNotification
+	notification.JSONRPC = "2.0"
+	notification.Method = "accountNotification"
+	notification.Params.Result.Context = result.Context
+	notification.Params.Result.Value = *result.Value
+	return &notification, nil
+}
+
+// AccountSnapshot returns a chainstream.AccountSnapshot loading the account with
+// GetAccountInfo in the encoding and at the commitment of the accountSubscribe params.
+func (c *Client) AccountSnapshot(params chainstream.AccountSubscribeParams) chainstream.AccountSnapshot {
+	encoding := params.Encoding
+	if encoding == "" {
+		encoding = chainstream.DefaultAccountEncoding
+	}
+	commitment := params.Commitment
+	if commitment == 0 {
+		commitment = chainstream.DefaultCommitment
+	}
+	return func(ctx context.Context) (*chainstream.AccountNotification, error) {
+		return c.GetAccountInfo(ctx, params.Account, encoding, commitment)
+	}
+}
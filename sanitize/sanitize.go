@@ -0,0 +1,188 @@
+// Package sanitize scrubs captured notifications so they can be shared as test fixtures
+// or archives without exposing real wallets.
+//
+// Wallets, token accounts, signatures and hashes are replaced with keys derived from a
+// seed: the same address always maps to the same key, and every derived key is valid
+// base58 decoding to as many bytes as the original (its text may be a character shorter
+// or longer), so sanitized notifications still decode, balance
+// changes still add up and swap routes are still detected. Programs, sysvars, Jito tip
+// accounts and wrapped SOL are kept.
+package sanitize
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mr-tron/base58"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/programs"
+)
+
+// Config controls what is replaced.
+type Config struct {
+	// Seed keys the derivation of replacement keys. Fixtures sanitized with the same seed
+	// share replacements; a secret seed keeps the original addresses from being guessed.
+	// Empty uses a random seed, so replacements differ from one sanitizer to the next.
+	Seed string
+	// KeepMints keeps token mints, so fixtures still refer to real tokens.
+	KeepMints bool
+	// DropLogs removes program log messages instead of rewriting the keys they mention.
+	DropLogs bool
+}
+
+// Sanitizer replaces addresses of notifications deterministically.
+type Sanitizer struct {
+	config Config
+}
+
+// New returns a sanitizer.
+func New(config Config) *Sanitizer {
+	if config.Seed == "" {
+		seed := make([]byte, 32)
+		if _, err := rand.Read(seed); err != nil {
+			panic(fmt.Sprintf("sanitize: cannot generate a seed: %v", err))
+		}
+		config.Seed = string(seed)
+	}
+	return &Sanitizer{config: config}
+}
+
+// Key returns the replacement of an account key, another 32-byte base58 public key. Keys
+// that are not 32-byte base58 public keys are returned unchanged.
+func (s *Sanitizer) Key(key string) string {
+	if decoded, err := base58.Decode(key); err != nil || len(decoded) != chainstream.PublicKeyLength {
+		return key
+	}
+	mac := hmac.New(sha256.New, []byte(s.config.Seed))
+	mac.Write([]byte("key:" + key))
+	return base58.Encode(mac.Sum(nil))
+}
+
+// Signature returns the replacement of a transaction signature.
+func (s *Sanitizer) Signature(signature string) string {
+	if signature == "" {
+		return ""
+	}
+	mac := hmac.New(sha512.New, []byte(s.config.Seed))
+	mac.Write([]byte("signature:" + signature))
+	return base58.Encode(mac.Sum(nil))
+}
+
+// Notification returns a sanitized copy of the notification.
+func (s *Sanitizer) Notification(notification *chainstream.TransactionNotification) (*chainstream.TransactionNotification, error) {
+	// A JSON round trip is the simplest deep copy of the nested slices.
+	data, err := json.Marshal(notification)
+	if err != nil {
+		return nil, err
+	}
+	var sanitized chainstream.TransactionNotification
+	if err = json.Unmarshal(data, &sanitized); err != nil {
+		return nil, err
+	}
+
+	keep := s.kept(&sanitized)
+	key := func(k string) string {
+		if _, ok := keep[k]; ok {
+			return k
+		}
+		return s.Key(k)
+	}
+
+	result := &sanitized.Params.Result
+	tx := &result.Value.Transaction
+	meta := &result.Value.Meta
+
+	result.Context.Signature = s.Signature(result.Context.Signature)
+	for i, signature := range tx.Signatures {
+		tx.Signatures[i] = s.Signature(signature)
+	}
+	tx.MessageHash = s.Key(tx.MessageHash)
+	tx.Message.RecentBlockhash = s.Key(tx.Message.RecentBlockhash)
+	replaceAll(tx.Message.AccountKeys, key)
+	for i := range tx.Message.AddressTableLookups {
+		lookup := &tx.Message.AddressTableLookups[i]
+		lookup.AccountKey = key(lookup.AccountKey)
+	}
+	replaceAll(meta.LoadedAddresses.Writable, key)
+	replaceAll(meta.LoadedAddresses.Readonly, key)
+	for _, balances := range [][]chainstream.TokenBalance{meta.PreTokenBalances, meta.PostTokenBalances} {
+		for i := range balances {
+			balances[i].Owner = key(balances[i].Owner)
+			balances[i].Mint = key(balances[i].Mint)
+		}
+	}
+
+	if s.config.DropLogs {
+		meta.LogMessages = nil
+	}
+	for i, line := range meta.LogMessages {
+		words := strings.Split(line, " ")
+		replaceAll(words, key)
+		meta.LogMessages[i] = strings.Join(words, " ")
+	}
+	return &sanitized, nil
+}
+
+// kept returns the keys of the notification that are not replaced.
+func (s *Sanitizer) kept(notification *chainstream.TransactionNotification) map[string]struct{} {
+	keep := map[string]struct{}{chainstream.WrappedSOLMint: {}}
+	for _, key := range notification.AccountKeys() {
+		if _, known := programs.Name(key); known || programs.IsJitoTip(key) || strings.HasPrefix(key, "Sysvar") {
+			keep[key] = struct{}{}
+		}
+	}
+	// Programs unknown to the programs package are kept too, they are not wallets.
+	for _, instruction := range notification.Instructions() {
+		keep[instruction.ProgramID] = struct{}{}
+	}
+	meta := &notification.Params.Result.Value.Meta
+	for _, balances := range [][]chainstream.TokenBalance{meta.PreTokenBalances, meta.PostTokenBalances} {
+		for _, balance := range balances {
+			keep[balance.ProgramID] = struct{}{}
+			if s.config.KeepMints {
+				keep[balance.Mint] = struct{}{}
+			}
+		}
+	}
+	return keep
+}
+
+// Copy reads notifications from r, a single JSON document or JSON lines, and writes the
+// sanitized notifications to w. indent writes indented JSON, as used for testdata. It
+// returns the number of notifications written.
+func (s *Sanitizer) Copy(w io.Writer, r io.Reader, indent bool) (int, error) {
+	dec := json.NewDecoder(r)
+	enc := json.NewEncoder(w)
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+	for n := 0; ; n++ {
+		var notification chainstream.TransactionNotification
+		if err := dec.Decode(&notification); errors.Is(err, io.EOF) {
+			return n, nil
+		} else if err != nil {
+			return n, fmt.Errorf("notification %d: %w", n+1, err)
+		}
+		sanitized, err := s.Notification(&notification)
+		if err != nil {
+			return n, fmt.Errorf("notification %d: %w", n+1, err)
+		}
+		if err = enc.Encode(sanitized); err != nil {
+			return n, err
+		}
+	}
+}
+
+func replaceAll(keys []string, replace func(string) string) {
+	for i, key := range keys {
+		keys[i] = replace(key)
+	}
+}
@@ -0,0 +1,126 @@
+package sanitize_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/programs"
+	"github.com/gerasimovvladislav/zensol-go/sanitize"
+)
+
+func loadNotification(t *testing.T, file string) *chainstream.TransactionNotification {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	var tx chainstream.TransactionNotification
+	if err := json.Unmarshal(data, &tx); err != nil {
+		t.Fatalf("failed to unmarshal tx: %v", err)
+	}
+	return &tx
+}
+
+func TestNotification(t *testing.T) {
+	tx := loadNotification(t, "../chainstream/testdata/sample_tx_buy.json")
+	s := sanitize.New(sanitize.Config{Seed: "seed"})
+
+	sanitized, err := s.Notification(tx)
+	if err != nil {
+		t.Fatalf("Notification() error: %v", err)
+	}
+	if sanitized.Owner() == tx.Owner() || sanitized.Signature() == tx.Signature() {
+		t.Errorf("owner %s and signature %s were kept", sanitized.Owner(), sanitized.Signature())
+	}
+	if sanitized.Owner() != s.Key(tx.Owner()) {
+		t.Error("owner replacement is not deterministic")
+	}
+	if tx.Owner() != loadNotification(t, "../chainstream/testdata/sample_tx_buy.json").Owner() {
+		t.Error("Notification() modified the original")
+	}
+
+	keys, original := sanitized.AccountKeys(), tx.AccountKeys()
+	for i, key := range keys {
+		if _, err := chainstream.NormalizeKey(key); err != nil {
+			t.Errorf("key %d: %v", i, err)
+		}
+		if _, known := programs.Name(original[i]); known && key != original[i] {
+			t.Errorf("program %s was replaced", original[i])
+		}
+	}
+	for _, line := range sanitized.Params.Result.Value.Meta.LogMessages {
+		if strings.Contains(line, tx.Owner()) {
+			t.Errorf("log %q mentions the owner", line)
+		}
+	}
+
+	routes, sanitizedRoutes := tx.SwapRoutes(), sanitized.SwapRoutes()
+	if len(sanitizedRoutes) != len(routes) {
+		t.Fatalf("%d routes after sanitizing, expected %d", len(sanitizedRoutes), len(routes))
+	}
+	for i := range routes {
+		if sanitizedRoutes[i].InputAmount() != routes[i].InputAmount() || sanitizedRoutes[i].OutputMint() != s.Key(routes[i].OutputMint()) {
+			t.Errorf("route %d = %+v, expected %+v re-keyed", i, sanitizedRoutes[i], routes[i])
+		}
+	}
+
+	if other, _ := sanitize.New(sanitize.Config{Seed: "other"}).Notification(tx); other.Owner() == sanitized.Owner() {
+		t.Error("replacement does not depend on the seed")
+	}
+}
+
+func TestKeepMintsAndDropLogs(t *testing.T) {
+	tx := loadNotification(t, "../chainstream/testdata/sample_tx_sell.json")
+	sanitized, err := sanitize.New(sanitize.Config{KeepMints: true, DropLogs: true}).Notification(tx)
+	if err != nil {
+		t.Fatalf("Notification() error: %v", err)
+	}
+	balances := tx.Params.Result.Value.Meta.PostTokenBalances
+	for i, balance := range sanitized.Params.Result.Value.Meta.PostTokenBalances {
+		if balance.Mint != balances[i].Mint || balance.Owner == balances[i].Owner {
+			t.Errorf("balance %d = %+v, expected the mint kept and the owner replaced", i, balance)
+		}
+	}
+	if logs := sanitized.Params.Result.Value.Meta.LogMessages; logs != nil {
+		t.Errorf("LogMessages = %v, expected none", logs)
+	}
+}
+
+func TestRandomSeed(t *testing.T) {
+	const key = "So11111111111111111111111111111111111111112"
+	s := sanitize.New(sanitize.Config{})
+	if s.Key(key) != s.Key(key) {
+		t.Error("replacement changes within a sanitizer")
+	}
+	if s.Key(key) == sanitize.New(sanitize.Config{}).Key(key) {
+		t.Error("sanitizers without a seed share replacements")
+	}
+}
+
+func TestCopy(t *testing.T) {
+	var lines bytes.Buffer
+	for _, file := range []string{"sample_tx_buy.json", "sample_tx_sell.json"} {
+		data, err := json.Marshal(loadNotification(t, "../chainstream/testdata/"+file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines.Write(append(data, '\n'))
+	}
+
+	var out bytes.Buffer
+	n, err := sanitize.New(sanitize.Config{Seed: "seed"}).Copy(&out, &lines, false)
+	if err != nil || n != 2 {
+		t.Fatalf("Copy() = %d, %v", n, err)
+	}
+	if got := strings.Count(out.String(), "\n"); got != 2 {
+		t.Errorf("wrote %d lines, expected 2", got)
+	}
+
+	if _, err = sanitize.New(sanitize.Config{}).Copy(&out, strings.NewReader("{"), true); err == nil {
+		t.Error("expected error for truncated input")
+	}
+}
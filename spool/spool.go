@@ -0,0 +1,302 @@
+// Package spool keeps events in a local append-only file while their sink (a queue, a
+// webhook, a database) is unavailable and replays them in order once it recovers, so a
+// downstream outage does not lose data.
+package spool
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+// Defaults of Config.
+const (
+	DefaultMaxBytes      = 64 << 20
+	DefaultRetryInterval = 5 * time.Second
+)
+
+// ErrFull is returned by Send when the event does not fit in the spool file.
+var ErrFull = errors.New("spool: file is full")
+
+// headerSize is the size of the length prefix of every record.
+const headerSize = 4
+
+// Sink delivers an event downstream. An error means the sink is unavailable.
+type Sink func(ctx context.Context, event []byte) error
+
+// Config controls a spool.
+type Config struct {
+	// Path is the spool file, created when missing.
+	Path string
+	// MaxBytes bounds the size of the spool file. Zero uses DefaultMaxBytes.
+	MaxBytes int64
+	// RetryInterval is the time between replays in Run. Zero uses DefaultRetryInterval.
+	RetryInterval time.Duration
+	// Sync flushes every spooled event to disk before Send returns, so it survives a
+	// power loss and not only a crash of the process. Otherwise the file is flushed when
+	// it is compacted and on Close.
+	Sync bool
+	// Clock drives Run. Nil uses the system clock.
+	Clock clock.Clock
+	// RetryBudget, when set, bounds the replays of Run together with the other subsystems
+	// sharing it: a replay is skipped when the budget is exhausted.
+	RetryBudget *chainstream.RetryBudget
+}
+
+// Spool passes events to a sink, appending them to a file while the sink fails. Once
+// events are spooled, new events are appended behind them until a replay has delivered
+// them all, so the sink receives events in order. It is safe for concurrent use.
+type Spool struct {
+	config Config
+	sink   Sink
+
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	pending int
+}
+
+// Open opens the spool file, keeping the events spooled by a previous run. A record torn
+// by a crash at the end of the file is dropped.
+func Open(config Config, sink Sink) (*Spool, error) {
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = DefaultMaxBytes
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = DefaultRetryInterval
+	}
+	if config.Clock == nil {
+		config.Clock = clock.System()
+	}
+	file, err := os.OpenFile(config.Path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("spool: cannot open file: %w", err)
+	}
+	s := &Spool{config: config, sink: sink, file: file}
+	if err = s.scan(); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// scan counts the records of the file and drops a torn one at its end.
+func (s *Spool) scan() error {
+	info, err := s.file.Stat()
+	if err != nil {
+		return fmt.Errorf("spool: cannot read file: %w", err)
+	}
+	r := bufio.NewReader(io.NewSectionReader(s.file, 0, info.Size()))
+	for {
+		event, err := s.readRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if err = s.file.Truncate(s.size); err != nil {
+				return fmt.Errorf("spool: cannot drop torn record: %w", err)
+			}
+			break
+		}
+		s.size += headerSize + int64(len(event))
+		s.pending++
+	}
+	return nil
+}
+
+// Send passes the event to the sink, or appends it to the spool file when the sink fails
+// or earlier events are still spooled.
+func (s *Spool) Send(ctx context.Context, event []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending == 0 {
+		if err := s.sink(ctx, event); err == nil || ctx.Err() != nil {
+			return err
+		}
+	}
+	return s.append(event)
+}
+
+func (s *Spool) append(event []byte) error {
+	size := headerSize + int64(len(event))
+	if s.size+size > s.config.MaxBytes {
+		return ErrFull
+	}
+	record := make([]byte, size)
+	binary.BigEndian.PutUint32(record, uint32(len(event)))
+	copy(record[headerSize:], event)
+	if _, err := s.file.Write(record); err != nil {
+		// Drop a partial write, so the file stays readable.
+		_ = s.file.Truncate(s.size)
+		return fmt.Errorf("spool: cannot write event: %w", err)
+	}
+	if s.config.Sync {
+		if err := s.file.Sync(); err != nil {
+			return fmt.Errorf("spool: cannot sync file: %w", err)
+		}
+	}
+	s.size += size
+	s.pending++
+	return nil
+}
+
+// Replay passes the spooled events to the sink in order. When the sink fails the events
+// it has not received stay spooled and the error is returned.
+func (s *Spool) Replay(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending == 0 {
+		return nil
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(s.file, 0, s.size))
+	var offset int64
+	var sent int
+	for sent < s.pending {
+		event, err := s.readRecord(r)
+		if err != nil {
+			return fmt.Errorf("spool: cannot read event: %w", err)
+		}
+		if err = s.sink(ctx, event); err != nil {
+			if dropErr := s.drop(offset, sent); dropErr != nil {
+				return dropErr
+			}
+			return err
+		}
+		offset += headerSize + int64(len(event))
+		sent++
+	}
+	if err := s.file.Truncate(0); err != nil {
+		return fmt.Errorf("spool: cannot truncate file: %w", err)
+	}
+	s.size, s.pending = 0, 0
+	return nil
+}
+
+// drop removes the first n events, which end at offset, from the spool file by replacing
+// it with a copy of the rest.
+func (s *Spool) drop(offset int64, n int) error {
+	if n == 0 {
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.config.Path), filepath.Base(s.config.Path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("spool: cannot compact file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = io.Copy(tmp, io.NewSectionReader(s.file, offset, s.size-offset)); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("spool: cannot compact file: %w", err)
+	}
+	// The copy must be on disk before it replaces the file, or a crash could leave an
+	// empty spool behind.
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("spool: cannot compact file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("spool: cannot compact file: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), s.config.Path); err != nil {
+		return fmt.Errorf("spool: cannot compact file: %w", err)
+	}
+	if err = syncDir(filepath.Dir(s.config.Path)); err != nil {
+		return fmt.Errorf("spool: cannot compact file: %w", err)
+	}
+	file, err := os.OpenFile(s.config.Path, os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("spool: cannot open file: %w", err)
+	}
+	_ = s.file.Close()
+	s.file = file
+	s.size -= offset
+	s.pending -= n
+	return nil
+}
+
+// syncDir flushes the entries of the directory, so that a rename in it is durable.
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	err = dir.Sync()
+	return errors.Join(err, dir.Close())
+}
+
+// readRecord reads the next event, returning io.EOF at the end of the file.
+func (s *Spool) readRecord(r *bufio.Reader) ([]byte, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if int64(size) > s.config.MaxBytes {
+		return nil, errors.New("spool: corrupt record")
+	}
+	event := make([]byte, size)
+	if _, err := io.ReadFull(r, event); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return event, nil
+}
+
+// Run replays the spooled events every Config.RetryInterval, within Config.RetryBudget,
+// until ctx is done.
+func (s *Spool) Run(ctx context.Context) error {
+	ticker := s.config.Clock.NewTicker(s.config.RetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C():
+			if s.Len() > 0 && s.config.RetryBudget.Allow(chainstream.RetrySink) {
+				_ = s.Replay(ctx)
+			}
+		}
+	}
+}
+
+// Len returns the number of spooled events.
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending
+}
+
+// Close flushes and closes the spool file. Spooled events are replayed after the next
+// Open.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.file.Sync(); err != nil {
+		_ = s.file.Close()
+		return fmt.Errorf("spool: cannot sync file: %w", err)
+	}
+	return s.file.Close()
+}
+
+// Handler returns a handler sending notifications to the spool as JSON. Its errors are
+// retried by the pipeline like other handler errors.
+func (s *Spool) Handler(ctx context.Context) chainstream.HandlerFunc {
+	return func(notification *chainstream.TransactionNotification) error {
+		data, err := json.Marshal(notification)
+		if err != nil {
+			return chainstream.Permanent(err)
+		}
+		return s.Send(ctx, data)
+	}
+}
@@ -0,0 +1,132 @@
+package spool_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/spool"
+)
+
+// sink records delivered events and fails while down is set.
+type sink struct {
+	down      bool
+	failAfter int
+	events    []string
+}
+
+func (s *sink) send(_ context.Context, event []byte) error {
+	if s.down || (s.failAfter > 0 && len(s.events) >= s.failAfter) {
+		return errors.New("sink unavailable")
+	}
+	s.events = append(s.events, string(event))
+	return nil
+}
+
+func TestSpoolOutage(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.spool")
+	out := &sink{}
+	s, err := spool.Open(spool.Config{Path: path}, out.send)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	send := func(events ...string) {
+		for _, event := range events {
+			if err := s.Send(ctx, []byte(event)); err != nil {
+				t.Fatalf("Send(%q) error: %v", event, err)
+			}
+		}
+	}
+	send("a")
+	out.down = true
+	send("b", "c", "d")
+	out.down = false
+	// Spooled events are ahead of new ones.
+	send("e")
+	if s.Len() != 4 || !reflect.DeepEqual(out.events, []string{"a"}) {
+		t.Fatalf("Len() = %d, delivered %v, expected four spooled events", s.Len(), out.events)
+	}
+
+	// The sink fails again after the next two events.
+	out.failAfter = 3
+	if err = s.Replay(ctx); err == nil {
+		t.Fatal("Replay() succeeded with a failing sink")
+	}
+	if s.Len() != 2 {
+		t.Fatalf("Len() = %d after a partial replay, expected 2", s.Len())
+	}
+	out.failAfter = 0
+	if err = s.Replay(ctx); err != nil {
+		t.Fatalf("Replay() error: %v", err)
+	}
+	if expected := []string{"a", "b", "c", "d", "e"}; s.Len() != 0 || !reflect.DeepEqual(out.events, expected) {
+		t.Errorf("Len() = %d, delivered %v, expected %v", s.Len(), out.events, expected)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+		t.Errorf("spool file not emptied: %v, %v", info, err)
+	}
+}
+
+func TestSpoolReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.spool")
+	out := &sink{down: true}
+	s, err := spool.Open(spool.Config{Path: path, Sync: true}, out.send)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err = s.Send(ctx, []byte(fmt.Sprint(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = s.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	// A crash in the middle of a write leaves a torn record behind.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.Write([]byte{0, 0, 0, 9, 'x'})
+	_ = f.Close()
+
+	out.down = false
+	s, err = spool.Open(spool.Config{Path: path}, out.send)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Len() != 3 {
+		t.Fatalf("Len() = %d after reopening, expected 3", s.Len())
+	}
+	if err = s.Replay(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"0", "1", "2"}; !reflect.DeepEqual(out.events, expected) {
+		t.Errorf("delivered %v, expected %v", out.events, expected)
+	}
+}
+
+func TestSpoolFull(t *testing.T) {
+	out := &sink{down: true}
+	s, err := spool.Open(spool.Config{Path: filepath.Join(t.TempDir(), "events.spool"), MaxBytes: 10}, out.send)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err = s.Send(context.Background(), []byte("12345")); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Send(context.Background(), []byte("12345")); !errors.Is(err, spool.ErrFull) {
+		t.Errorf("Send() error = %v, expected ErrFull", err)
+	}
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+// File is a Store that keeps every key in its own file in a directory. Writes are atomic,
+// so a crash never leaves a partially written value behind. Expired keys are removed when
+// read, and by a sweep of the directory every sweepEvery writes or on Sweep, so keys that
+// are never read again, such as dedup signatures, do not pile up.
+type File struct {
+	dir    string
+	clock  clock.Clock
+	writes atomic.Uint64
+}
+
+type fileItem struct {
+	Value     []byte    `json:"value"`
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
+}
+
+// NewFile creates a store in dir, creating the directory when needed. A nil clock uses
+// the system clock.
+func NewFile(dir string, clk clock.Clock) (*File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("store: cannot create directory: %w", err)
+	}
+	if clk == nil {
+		clk = clock.System()
+	}
+	return &File{dir: dir, clock: clk}, nil
+}
+
+// Get implements Store.
+func (f *File) Get(_ context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(f.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: cannot read %q: %w", key, err)
+	}
+	var item fileItem
+	if err = json.Unmarshal(data, &item); err != nil {
+		return nil, fmt.Errorf("store: cannot decode %q: %w", key, err)
+	}
+	if expired(f.clock.Now(), item.ExpiresAt) {
+		_ = os.Remove(f.path(key))
+		return nil, ErrNotFound
+	}
+	return item.Value, nil
+}
+
+// Set implements Store.
+func (f *File) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	data, err := json.Marshal(fileItem{Value: value, ExpiresAt: expiry(f.clock.Now(), ttl)})
+	if err != nil {
+		return err
+	}
+	path := f.path(key)
+	tmp, err := os.CreateTemp(f.dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("store: cannot write %q: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("store: cannot write %q: %w", key, err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("store: cannot write %q: %w", key, err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	if f.writes.Add(1)%sweepEvery == 0 {
+		_, _ = f.Sweep(ctx)
+	}
+	return nil
+}
+
+// Sweep removes the files of the expired keys and returns their number. It stops early
+// when ctx is done.
+func (f *File) Sweep(ctx context.Context) (int, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return 0, fmt.Errorf("store: cannot list directory: %w", err)
+	}
+	now := f.clock.Now()
+	removed := 0
+	for _, entry := range entries {
+		if err = ctx.Err(); err != nil {
+			return removed, err
+		}
+		// Temporary files of writes in progress end with a random suffix instead.
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(f.dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var item fileItem
+		if json.Unmarshal(data, &item) != nil || !expired(now, item.ExpiresAt) {
+			continue
+		}
+		if os.Remove(path) == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// Delete implements Store.
+func (f *File) Delete(_ context.Context, key string) error {
+	if err := os.Remove(f.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("store: cannot delete %q: %w", key, err)
+	}
+	return nil
+}
+
+func (f *File) path(key string) string {
+	return filepath.Join(f.dir, url.PathEscape(key)+".json")
+}
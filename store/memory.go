@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/clock"
+	"github.com/gerasimovvladislav/zensol-go/memguard"
+)
+
+// sweepEvery is the number of writes between removals of expired keys from a Memory or
+// File store.
+const sweepEvery = 1024
+
+// Memory is a Store kept in process memory.
+type Memory struct {
+	clock  clock.Clock
+	mu     sync.Mutex
+	items  map[string]memoryItem
+	writes int
+}
+
+type memoryItem struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemory creates an empty in-memory store. A nil clock uses the system clock.
+func NewMemory(clk clock.Clock) *Memory {
+	if clk == nil {
+		clk = clock.System()
+	}
+	return &Memory{clock: clk, items: make(map[string]memoryItem)}
+}
+
+// Get implements Store.
+func (m *Memory) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.items[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if expired(m.clock.Now(), item.expiresAt) {
+		delete(m.items, key)
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), item.value...), nil
+}
+
+// Set implements Store.
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock.Now()
+	m.items[key] = memoryItem{value: append([]byte(nil), value...), expiresAt: expiry(now, ttl)}
+	if m.writes++; m.writes%sweepEvery == 0 {
+		for k, item := range m.items {
+			if expired(now, item.expiresAt) {
+				delete(m.items, k)
+			}
+		}
+	}
+	return nil
+}
+
+// Delete implements Store.
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.items, key)
+	return nil
+}
+
+// Shed removes the expired keys and, when they are fewer than the fraction of the keys
+// held, the keys closest to expiry up to the fraction, see memguard.Shedder. Keys without
+// a ttl are removed last. It returns how many keys it removed.
+func (m *Memory) Shed(fraction float64) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := memguard.Portion(len(m.items), fraction)
+	now := m.clock.Now()
+	removed := 0
+	keys := make([]string, 0, len(m.items))
+	for k, item := range m.items {
+		if expired(now, item.expiresAt) {
+			delete(m.items, k)
+			removed++
+			continue
+		}
+		keys = append(keys, k)
+	}
+	if removed >= n {
+		return removed
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := m.items[keys[i]].expiresAt, m.items[keys[j]].expiresAt
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero()
+		}
+		return a.Before(b)
+	})
+	for _, k := range keys[:n-removed] {
+		delete(m.items, k)
+	}
+	return n
+}
+
+// Len returns the number of keys held, including expired keys not removed yet.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.items)
+}
@@ -0,0 +1,56 @@
+// Package store defines a small key-value store used for caches and checkpoints, so a
+// deployment chooses its persistence once and shares it between components.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by Get for missing and expired keys.
+var ErrNotFound = errors.New("store: key not found")
+
+// Store is a key-value store with optional expiry. Implementations are safe for concurrent use.
+type Store interface {
+	// Get returns the value of the key or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores the value under the key. A positive ttl expires the key after that time.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Prefixed returns a view of the store that prepends prefix to every key, so that
+// several components can share one store without key collisions.
+func Prefixed(s Store, prefix string) Store {
+	return &prefixed{store: s, prefix: prefix}
+}
+
+type prefixed struct {
+	store  Store
+	prefix string
+}
+
+func (p *prefixed) Get(ctx context.Context, key string) ([]byte, error) {
+	return p.store.Get(ctx, p.prefix+key)
+}
+
+func (p *prefixed) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return p.store.Set(ctx, p.prefix+key, value, ttl)
+}
+
+func (p *prefixed) Delete(ctx context.Context, key string) error {
+	return p.store.Delete(ctx, p.prefix+key)
+}
+
+func expiry(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+func expired(now, expiresAt time.Time) bool {
+	return !expiresAt.IsZero() && !now.Before(expiresAt)
+}
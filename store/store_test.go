@@ -0,0 +1,151 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/clock"
+	"github.com/gerasimovvladislav/zensol-go/store"
+)
+
+func TestStores(t *testing.T) {
+	ctx := context.Background()
+	stores := map[string]func(clk clock.Clock) store.Store{
+		"memory": func(clk clock.Clock) store.Store { return store.NewMemory(clk) },
+		"file": func(clk clock.Clock) store.Store {
+			s, err := store.NewFile(t.TempDir(), clk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+		"prefixed": func(clk clock.Clock) store.Store { return store.Prefixed(store.NewMemory(clk), "dedup/") },
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			clk := clock.NewFake(time.Unix(1700000000, 0))
+			s := newStore(clk)
+
+			if _, err := s.Get(ctx, "missing"); !errors.Is(err, store.ErrNotFound) {
+				t.Errorf("Get(missing) = %v, expected ErrNotFound", err)
+			}
+			if err := s.Set(ctx, "a/b", []byte("forever"), 0); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Set(ctx, "short", []byte("lived"), time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			if value, err := s.Get(ctx, "short"); err != nil || string(value) != "lived" {
+				t.Errorf("Get(short) = %q, %v", value, err)
+			}
+
+			clk.Advance(time.Minute)
+			if _, err := s.Get(ctx, "short"); !errors.Is(err, store.ErrNotFound) {
+				t.Errorf("Get(short) after ttl = %v, expected ErrNotFound", err)
+			}
+			if value, err := s.Get(ctx, "a/b"); err != nil || string(value) != "forever" {
+				t.Errorf("Get(a/b) = %q, %v", value, err)
+			}
+
+			if err := s.Delete(ctx, "a/b"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Delete(ctx, "a/b"); err != nil {
+				t.Errorf("Delete(missing) = %v", err)
+			}
+			if _, err := s.Get(ctx, "a/b"); !errors.Is(err, store.ErrNotFound) {
+				t.Errorf("Get(a/b) after delete = %v, expected ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestMemoryShed(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Unix(1700000000, 0))
+	s := store.NewMemory(clk)
+	for key, ttl := range map[string]time.Duration{"expired": time.Second, "soon": time.Minute, "later": time.Hour, "forever": 0} {
+		if err := s.Set(ctx, key, []byte(key), ttl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clk.Advance(time.Second)
+
+	if n := s.Shed(0.5); n != 2 {
+		t.Errorf("Shed(0.5) = %d, expected 2", n)
+	}
+	for _, key := range []string{"later", "forever"} {
+		if _, err := s.Get(ctx, key); err != nil {
+			t.Errorf("Get(%s) = %v", key, err)
+		}
+	}
+	if n := s.Shed(0.5); n != 1 || s.Len() != 1 {
+		t.Errorf("Shed(0.5) = %d, kept %d keys, expected forever", n, s.Len())
+	}
+	if _, err := s.Get(ctx, "forever"); err != nil {
+		t.Errorf("Get(forever) = %v", err)
+	}
+}
+
+func TestFileSweep(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Unix(1700000000, 0))
+	dir := t.TempDir()
+	s, err := store.NewFile(dir, clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"sig-1", "sig-2", "sig-3"} {
+		if err := s.Set(ctx, key, []byte{1}, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Set(ctx, "cursor", []byte("42"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	clk.Advance(time.Minute)
+	if removed, err := s.Sweep(ctx); err != nil || removed != 3 {
+		t.Errorf("Sweep() = %d, %v, expected the 3 expired keys", removed, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("%d files left, expected the key without ttl", len(entries))
+	}
+	if value, err := s.Get(ctx, "cursor"); err != nil || string(value) != "42" {
+		t.Errorf("Get(cursor) = %q, %v", value, err)
+	}
+}
+
+func TestFileSweepOnSet(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Unix(1700000000, 0))
+	dir := t.TempDir()
+	s, err := store.NewFile(dir, clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Signatures looked up once and never read again.
+	for i := range 1024 {
+		if err := s.Set(ctx, fmt.Sprintf("sig-%d", i), []byte{1}, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if i == 1000 {
+			clk.Advance(time.Minute)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 23 {
+		t.Errorf("%d files left, expected the 23 keys written after the first ones expired", len(entries))
+	}
+}
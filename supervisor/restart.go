@@ -0,0 +1,115 @@
+package supervisor
+
+import (
+	"fmt"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+// RestartMode selects when a task that returned is started again.
+type RestartMode int
+
+const (
+	// RestartNever leaves a returned task stopped.
+	RestartNever RestartMode = iota
+	// RestartOnFailure restarts a task that returned an error.
+	RestartOnFailure
+	// RestartAlways restarts a task whenever it returns before shutdown.
+	RestartAlways
+)
+
+// MarshalText implements encoding.TextMarshaler.
+func (m RestartMode) MarshalText() ([]byte, error) {
+	switch m {
+	case RestartNever:
+		return []byte("never"), nil
+	case RestartOnFailure:
+		return []byte("on-failure"), nil
+	case RestartAlways:
+		return []byte("always"), nil
+	}
+	return nil, fmt.Errorf("unknown restart mode %d", m)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (m *RestartMode) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "", "never":
+		*m = RestartNever
+	case "on-failure":
+		*m = RestartOnFailure
+	case "always":
+		*m = RestartAlways
+	default:
+		return fmt.Errorf("unknown restart mode %q", text)
+	}
+	return nil
+}
+
+// RestartPolicy describes when and how often a task is restarted.
+type RestartPolicy struct {
+	Mode RestartMode `json:"mode"`
+	// MaxRestarts limits the number of restarts, zero restarts without limit.
+	MaxRestarts int `json:"maxRestarts,omitempty"`
+	// Backoff is the delay before each restart.
+	Backoff chainstream.Duration `json:"backoff,omitempty"`
+}
+
+func (p RestartPolicy) restarts(err error) bool {
+	switch p.Mode {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return err != nil
+	}
+	return false
+}
+
+// EventKind identifies what happened to a task.
+type EventKind int
+
+const (
+	// EventStarted is reported when a task is started or restarted.
+	EventStarted EventKind = iota
+	// EventStopped is reported when a task returned without an error or because of shutdown.
+	EventStopped
+	// EventFailed is reported when a task returned an error.
+	EventFailed
+	// EventRestarting is reported before waiting for the restart backoff.
+	EventRestarting
+	// EventGaveUp is reported when a task reached its restart limit.
+	EventGaveUp
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventStarted:
+		return "started"
+	case EventStopped:
+		return "stopped"
+	case EventFailed:
+		return "failed"
+	case EventRestarting:
+		return "restarting"
+	case EventGaveUp:
+		return "gave up"
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// Event reports a change in the state of a supervised task.
+type Event struct {
+	Task string
+	Kind EventKind
+	// Err is the error the task returned, if any.
+	Err error
+	// Restarts is the number of times the task has been restarted so far.
+	Restarts int
+}
+
+func (e Event) String() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s (restarts: %d): %v", e.Task, e.Kind, e.Restarts, e.Err)
+	}
+	return fmt.Sprintf("%s: %s (restarts: %d)", e.Task, e.Kind, e.Restarts)
+}
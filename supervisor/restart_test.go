@@ -0,0 +1,87 @@
+package supervisor_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/supervisor"
+)
+
+func TestRestartPolicy(t *testing.T) {
+	failing := errors.New("connection lost")
+	tests := []struct {
+		name     string
+		policy   supervisor.RestartPolicy
+		result   error
+		runs     int
+		expected bool // whether Run returns an error
+	}{
+		{"never", supervisor.RestartPolicy{}, failing, 1, true},
+		{"on-failure limited", supervisor.RestartPolicy{Mode: supervisor.RestartOnFailure, MaxRestarts: 2}, failing, 3, true},
+		{"on-failure clean exit", supervisor.RestartPolicy{Mode: supervisor.RestartOnFailure, MaxRestarts: 2}, nil, 1, false},
+		{"always limited", supervisor.RestartPolicy{Mode: supervisor.RestartAlways, MaxRestarts: 3}, nil, 4, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := supervisor.New()
+			s.Signals = nil
+
+			var (
+				mu     sync.Mutex
+				events []supervisor.Event
+			)
+			s.OnEvent = func(e supervisor.Event) {
+				mu.Lock()
+				events = append(events, e)
+				mu.Unlock()
+			}
+
+			runs := 0
+			s.Go("stream", func(context.Context) error {
+				runs++
+				return test.result
+			})
+			if err := s.SetRestartPolicy("stream", test.policy); err != nil {
+				t.Fatal(err)
+			}
+
+			err := s.Run(context.Background())
+			if (err != nil) != test.expected {
+				t.Errorf("Run() = %v, expected error: %v", err, test.expected)
+			}
+			if runs != test.runs {
+				t.Errorf("runs = %d, expected %d", runs, test.runs)
+			}
+			for _, e := range events {
+				t.Logf("📣 %s", e)
+			}
+		})
+	}
+}
+
+func TestRestartUntilHealthy(t *testing.T) {
+	s := supervisor.New()
+	s.Signals = nil
+	s.RestartPolicy = supervisor.RestartPolicy{Mode: supervisor.RestartOnFailure}
+
+	runs := 0
+	s.Go("stream", func(context.Context) error {
+		runs++
+		if runs < 3 {
+			return errors.New("connection lost")
+		}
+		return nil
+	})
+
+	if err := s.Run(context.Background()); err != nil {
+		t.Errorf("Run() = %v, expected the task to recover", err)
+	}
+	if runs != 3 {
+		t.Errorf("runs = %d, expected 3", runs)
+	}
+	if err := s.SetRestartPolicy("unknown", supervisor.RestartPolicy{}); err == nil {
+		t.Error("SetRestartPolicy() accepted an unknown task")
+	}
+}
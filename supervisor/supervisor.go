@@ -0,0 +1,218 @@
+// Package supervisor runs a set of long-lived tasks, typically subscriptions, with a
+// shared lifecycle: it stops them on SIGINT/SIGTERM, waits for them to drain, runs
+// shutdown hooks such as sink flushes and checkpoint saves, and reports all errors at once.
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+)
+
+// DefaultShutdownTimeout bounds the time spent draining tasks and running shutdown hooks.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// ErrShutdownTimeout is reported when tasks do not return within the shutdown timeout.
+var ErrShutdownTimeout = errors.New("supervisor: tasks did not stop within the shutdown timeout")
+
+type task struct {
+	name   string
+	run    func(ctx context.Context) error
+	policy *RestartPolicy
+}
+
+type hook struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// Supervisor runs tasks until they finish, the context is done or a shutdown signal arrives.
+type Supervisor struct {
+	// ShutdownTimeout bounds draining tasks and, separately, running shutdown hooks.
+	ShutdownTimeout time.Duration
+	// Signals that trigger a shutdown, SIGINT and SIGTERM by default.
+	Signals []os.Signal
+	// FailFast stops all tasks as soon as one of them fails for good, that is returns an
+	// error its restart policy does not restart.
+	FailFast bool
+	// RestartPolicy applies to tasks without a policy of their own. The zero value never restarts.
+	RestartPolicy RestartPolicy
+	// OnEvent, when set, is called for every task start, stop, failure and restart.
+	// It is called from the task goroutines and must be safe for concurrent use.
+	OnEvent func(Event)
+	// Clock drives restart backoff. Nil uses the system clock.
+	Clock clock.Clock
+
+	tasks []task
+	hooks []hook
+}
+
+// New creates a supervisor with default settings.
+func New() *Supervisor {
+	return &Supervisor{
+		ShutdownTimeout: DefaultShutdownTimeout,
+		Signals:         []os.Signal{os.Interrupt, syscall.SIGTERM},
+	}
+}
+
+// Go adds a task. The task must return when its context is done.
+func (s *Supervisor) Go(name string, run func(ctx context.Context) error) {
+	s.tasks = append(s.tasks, task{name: name, run: run})
+}
+
+// SetRestartPolicy overrides the restart policy of the task with the given name.
+func (s *Supervisor) SetRestartPolicy(name string, policy RestartPolicy) error {
+	for i := range s.tasks {
+		if s.tasks[i].name == name {
+			s.tasks[i].policy = &policy
+			return nil
+		}
+	}
+	return fmt.Errorf("supervisor: unknown task %q", name)
+}
+
+// Subscribe adds a task consuming the subscription with the client.
+func (s *Supervisor) Subscribe(client chainstream.Client, sub *chainstream.SubscriptionConfig, do chainstream.HandlerFunc) {
+	request := sub.Request(len(s.tasks) + 1)
+	s.Go(sub.Name, func(ctx context.Context) error {
+		return client.HandleTransactionsNotifications(ctx, request, do)
+	})
+}
+
+// OnShutdown adds a hook run after all tasks have stopped, e.g. to flush a sink or save
+// a checkpoint. Hooks run in reverse order of registration.
+func (s *Supervisor) OnShutdown(name string, run func(ctx context.Context) error) {
+	s.hooks = append(s.hooks, hook{name, run})
+}
+
+// Run starts all tasks and blocks until they stop. It returns the errors of all tasks
+// and shutdown hooks joined together; a clean shutdown returns nil.
+func (s *Supervisor) Run(ctx context.Context) error {
+	if len(s.Signals) > 0 {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, s.Signals...)
+		defer stop()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	for _, t := range s.tasks {
+		wg.Add(1)
+		go func(t task) {
+			defer wg.Done()
+			err := s.supervise(ctx, t)
+			if err == nil {
+				return
+			}
+			mu.Lock()
+			errs = append(errs, fmt.Errorf("%s: %w", t.name, err))
+			mu.Unlock()
+			if s.FailFast {
+				cancel()
+			}
+		}(t)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		select {
+		case <-done:
+		case <-time.After(s.shutdownTimeout()):
+			mu.Lock()
+			errs = append(errs, ErrShutdownTimeout)
+			mu.Unlock()
+		}
+	}
+
+	hookCtx, hookCancel := context.WithTimeout(context.Background(), s.shutdownTimeout())
+	defer hookCancel()
+	for i := len(s.hooks) - 1; i >= 0; i-- {
+		if err := s.hooks[i].run(hookCtx); err != nil {
+			mu.Lock()
+			errs = append(errs, fmt.Errorf("%s: %w", s.hooks[i].name, err))
+			mu.Unlock()
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// supervise runs the task, restarting it according to its policy, and returns the error
+// it finally failed with.
+func (s *Supervisor) supervise(ctx context.Context, t task) error {
+	policy := s.RestartPolicy
+	if t.policy != nil {
+		policy = *t.policy
+	}
+	clk := s.Clock
+	if clk == nil {
+		clk = clock.System()
+	}
+	for restarts := 0; ; restarts++ {
+		s.emit(Event{Task: t.name, Kind: EventStarted, Restarts: restarts})
+		err := t.run(ctx)
+		if errors.Is(err, context.Canceled) {
+			err = nil
+		}
+		if ctx.Err() != nil {
+			s.emit(Event{Task: t.name, Kind: EventStopped, Err: err, Restarts: restarts})
+			return err
+		}
+		if err != nil {
+			s.emit(Event{Task: t.name, Kind: EventFailed, Err: err, Restarts: restarts})
+		} else {
+			s.emit(Event{Task: t.name, Kind: EventStopped, Restarts: restarts})
+		}
+		if !policy.restarts(err) {
+			return err
+		}
+		if policy.MaxRestarts > 0 && restarts >= policy.MaxRestarts {
+			s.emit(Event{Task: t.name, Kind: EventGaveUp, Err: err, Restarts: restarts})
+			if err != nil {
+				return fmt.Errorf("gave up after %d restarts: %w", restarts, err)
+			}
+			return nil
+		}
+		s.emit(Event{Task: t.name, Kind: EventRestarting, Err: err, Restarts: restarts + 1})
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-clk.After(time.Duration(policy.Backoff)):
+		}
+	}
+}
+
+func (s *Supervisor) emit(event Event) {
+	if s.OnEvent != nil {
+		s.OnEvent(event)
+	}
+}
+
+func (s *Supervisor) shutdownTimeout() time.Duration {
+	if s.ShutdownTimeout > 0 {
+		return s.ShutdownTimeout
+	}
+	return DefaultShutdownTimeout
+}
@@ -0,0 +1,82 @@
+package supervisor_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/supervisor"
+)
+
+func TestRunShutdown(t *testing.T) {
+	s := supervisor.New()
+	s.Signals = nil
+
+	var order []string
+	s.Go("stream", func(ctx context.Context) error {
+		<-ctx.Done()
+		order = append(order, "stream")
+		return nil
+	})
+	s.Go("broken", func(context.Context) error {
+		return errors.New("boom")
+	})
+	s.OnShutdown("checkpoint", func(context.Context) error {
+		order = append(order, "checkpoint")
+		return nil
+	})
+	s.OnShutdown("sink", func(context.Context) error {
+		order = append(order, "sink")
+		return errors.New("flush failed")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := s.Run(ctx)
+
+	if err == nil || !strings.Contains(err.Error(), "broken: boom") || !strings.Contains(err.Error(), "sink: flush failed") {
+		t.Errorf("Run() error = %v, expected task and hook errors", err)
+	}
+	if strings.Join(order, ",") != "stream,sink,checkpoint" {
+		t.Errorf("order = %v, expected tasks to drain before hooks run in reverse order", order)
+	}
+}
+
+func TestRunFailFast(t *testing.T) {
+	s := supervisor.New()
+	s.Signals = nil
+	s.FailFast = true
+
+	s.Go("stream", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	s.Go("broken", func(context.Context) error {
+		return errors.New("boom")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Run(ctx); err == nil || ctx.Err() != nil {
+		t.Errorf("Run() = %v, expected to stop on the first error", err)
+	}
+}
+
+func TestRunShutdownTimeout(t *testing.T) {
+	s := supervisor.New()
+	s.Signals = nil
+	s.ShutdownTimeout = 10 * time.Millisecond
+
+	s.Go("stuck", func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Run(ctx); !errors.Is(err, supervisor.ErrShutdownTimeout) {
+		t.Errorf("Run() = %v, expected ErrShutdownTimeout", err)
+	}
+}
@@ -0,0 +1,163 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/store"
+)
+
+// snapshotVersion is bumped whenever the snapshot format changes incompatibly.
+const snapshotVersion = 1
+
+// Snapshot is the serialized state of a tracker.
+type Snapshot struct {
+	Version   int        `json:"version"`
+	CreatedAt time.Time  `json:"createdAt"`
+	Wallets   []string   `json:"wallets"`
+	Positions []Position `json:"positions"`
+}
+
+// Snapshot returns the current state of the tracker.
+func (t *Tracker) Snapshot() *Snapshot {
+	positions := t.Positions()
+
+	t.mu.RLock()
+	wallets := make([]string, 0, len(t.wallets))
+	for wallet := range t.wallets {
+		wallets = append(wallets, wallet)
+	}
+	t.mu.RUnlock()
+
+	return &Snapshot{
+		Version:   snapshotVersion,
+		CreatedAt: time.Now().UTC(),
+		Wallets:   wallets,
+		Positions: positions,
+	}
+}
+
+// Restore replaces the state of the tracker with the snapshot.
+func (t *Tracker) Restore(s *Snapshot) error {
+	if s.Version != snapshotVersion {
+		return fmt.Errorf("tracker: unsupported snapshot version %d", s.Version)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.wallets = make(map[string]struct{}, len(s.Wallets))
+	for _, wallet := range s.Wallets {
+		t.wallets[wallet] = struct{}{}
+	}
+	t.positions = make(map[positionKey]*Position, len(s.Positions))
+	for i := range s.Positions {
+		pos := s.Positions[i]
+		t.positions[positionKey{pos.Wallet, pos.Mint}] = &pos
+	}
+	return nil
+}
+
+// WriteTo writes the snapshot of the tracker as JSON.
+func (t *Tracker) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.Marshal(t.Snapshot())
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// ReadFrom restores the tracker from JSON written by WriteTo.
+func (t *Tracker) ReadFrom(r io.Reader) (int64, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return int64(len(data)), err
+	}
+	var s Snapshot
+	if err = json.Unmarshal(data, &s); err != nil {
+		return int64(len(data)), fmt.Errorf("tracker: cannot decode snapshot: %w", err)
+	}
+	return int64(len(data)), t.Restore(&s)
+}
+
+// Save atomically writes the snapshot to path.
+func (t *Tracker) Save(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("tracker: cannot create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = t.WriteTo(tmp); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("tracker: cannot write snapshot: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("tracker: cannot write snapshot: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load restores the tracker from a snapshot file. A missing file is not an error.
+func (t *Tracker) Load(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("tracker: cannot open snapshot: %w", err)
+	}
+	defer f.Close()
+	_, err = t.ReadFrom(f)
+	return err
+}
+
+// SaveStore writes the snapshot to the store under key.
+func (t *Tracker) SaveStore(ctx context.Context, s store.Store, key string) error {
+	data, err := json.Marshal(t.Snapshot())
+	if err != nil {
+		return err
+	}
+	if err = s.Set(ctx, key, data, 0); err != nil {
+		return fmt.Errorf("tracker: cannot save snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadStore restores the tracker from a snapshot saved by SaveStore. A missing key is not an error.
+func (t *Tracker) LoadStore(ctx context.Context, s store.Store, key string) error {
+	data, err := s.Get(ctx, key)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("tracker: cannot load snapshot: %w", err)
+	}
+	_, err = t.ReadFrom(bytes.NewReader(data))
+	return err
+}
+
+// SaveEvery saves the tracker to path every interval until ctx is done, then saves it
+// one last time. Errors of periodic saves are passed to onError when it is not nil.
+func (t *Tracker) SaveEvery(ctx context.Context, path string, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.Save(path); err != nil && onError != nil {
+				onError(err)
+			}
+		case <-ctx.Done():
+			return t.Save(path)
+		}
+	}
+}
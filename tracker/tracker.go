@@ -0,0 +1,154 @@
+// Package tracker aggregates token positions and SOL flows of watched wallets
+// from transaction notifications.
+package tracker
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+)
+
+// Position is the aggregated activity of a wallet in a single mint.
+type Position struct {
+	Wallet   string `json:"wallet"`
+	Mint     string `json:"mint"`
+	Decimals int    `json:"decimals"`
+	// Amount is the net token amount in base units accumulated from observed transactions.
+	Amount int64 `json:"amount"`
+	// SOLSpent and SOLReceived are lamports paid for and received from the mint, fees excluded.
+	SOLSpent    uint64    `json:"solSpent"`
+	SOLReceived uint64    `json:"solReceived"`
+	Trades      int       `json:"trades"`
+	LastSlot    uint64    `json:"lastSlot"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// RealizedPnL returns SOLReceived minus SOLSpent in lamports.
+func (p *Position) RealizedPnL() int64 {
+	return int64(p.SOLReceived) - int64(p.SOLSpent)
+}
+
+type positionKey struct {
+	wallet string
+	mint   string
+}
+
+// Tracker accumulates positions of watched wallets. It is safe for concurrent use.
+type Tracker struct {
+	mu        sync.RWMutex
+	wallets   map[string]struct{}
+	positions map[positionKey]*Position
+}
+
+// New creates a tracker watching the given wallets.
+func New(wallets ...string) *Tracker {
+	t := &Tracker{
+		wallets:   make(map[string]struct{}, len(wallets)),
+		positions: make(map[positionKey]*Position),
+	}
+	for _, wallet := range wallets {
+		t.wallets[wallet] = struct{}{}
+	}
+	return t
+}
+
+// Watch adds wallets to the tracker.
+func (t *Tracker) Watch(wallets ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, wallet := range wallets {
+		t.wallets[wallet] = struct{}{}
+	}
+}
+
+// Apply updates positions of watched wallets touched by the notification.
+// Failed transactions are ignored.
+func (t *Tracker) Apply(notification *chainstream.TransactionNotification) {
+	if !notification.Succeeded() {
+		return
+	}
+	meta := &notification.Params.Result.Value.Meta
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tokenChanges := make(map[string][]chainstream.TokenBalanceChange)
+	for _, change := range notification.TokenBalanceChanges() {
+		if _, ok := t.wallets[change.Owner]; ok {
+			tokenChanges[change.Owner] = append(tokenChanges[change.Owner], change)
+		}
+	}
+	if len(tokenChanges) == 0 {
+		return
+	}
+
+	solDeltas := make(map[string]int64)
+	for _, change := range notification.BalanceChanges() {
+		solDeltas[change.Account] = change.Delta
+	}
+	if feePayer := notification.Owner(); feePayer != "" {
+		solDeltas[feePayer] += int64(meta.Fee)
+	}
+
+	updatedAt, _ := notification.Time(chainstream.AgeFromBlockTime)
+	for wallet, changes := range tokenChanges {
+		for _, change := range changes {
+			pos := t.position(wallet, change.Mint)
+			pos.Decimals = change.Decimals
+			pos.Amount += change.Delta
+			pos.Trades++
+			pos.LastSlot = notification.Slot()
+			pos.UpdatedAt = updatedAt
+
+			// SOL can only be attributed unambiguously when a single mint changed.
+			if len(changes) != 1 {
+				continue
+			}
+			if delta := solDeltas[wallet]; delta < 0 {
+				pos.SOLSpent += uint64(-delta)
+			} else {
+				pos.SOLReceived += uint64(delta)
+			}
+		}
+	}
+}
+
+func (t *Tracker) position(wallet, mint string) *Position {
+	key := positionKey{wallet, mint}
+	pos, ok := t.positions[key]
+	if !ok {
+		pos = &Position{Wallet: wallet, Mint: mint}
+		t.positions[key] = pos
+	}
+	return pos
+}
+
+// Position returns a copy of the position of wallet in mint.
+func (t *Tracker) Position(wallet, mint string) (Position, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	pos, ok := t.positions[positionKey{wallet, mint}]
+	if !ok {
+		return Position{}, false
+	}
+	return *pos, true
+}
+
+// Positions returns copies of all positions ordered by wallet and mint.
+func (t *Tracker) Positions() []Position {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	positions := make([]Position, 0, len(t.positions))
+	for _, pos := range t.positions {
+		positions = append(positions, *pos)
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Wallet != positions[j].Wallet {
+			return positions[i].Wallet < positions[j].Wallet
+		}
+		return positions[i].Mint < positions[j].Mint
+	})
+	return positions
+}
@@ -0,0 +1,95 @@
+package tracker_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/store"
+	"github.com/gerasimovvladislav/zensol-go/tracker"
+)
+
+const (
+	wallet = "53CkQzZiYAqwSdYRUX546ekKkNsKQCu9KTu9duvGZnhF"
+	mint   = "DNvtizsEYyknJoW3QYwDA7ncjxri3KBBTeLydEZCpump"
+)
+
+func loadNotification(t *testing.T, file string) *chainstream.TransactionNotification {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	var tx chainstream.TransactionNotification
+	if err := json.Unmarshal(data, &tx); err != nil {
+		t.Fatalf("failed to unmarshal tx: %v", err)
+	}
+	return &tx
+}
+
+func TestApply(t *testing.T) {
+	tr := tracker.New(wallet)
+	tr.Apply(loadNotification(t, "../chainstream/testdata/sample_tx_sell.json"))
+
+	pos, ok := tr.Position(wallet, mint)
+	if !ok {
+		t.Fatal("expected position to be tracked")
+	}
+	if pos.Amount != -357547484136 || pos.Trades != 1 || pos.SOLReceived == 0 || pos.SOLSpent != 0 {
+		t.Errorf("unexpected position %+v", pos)
+	}
+
+	tr.Apply(loadNotification(t, "../chainstream/testdata/sample_tx_create.json"))
+	if got := len(tr.Positions()); got != 1 {
+		t.Errorf("failed transaction changed positions, got %d", got)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	tr := tracker.New(wallet)
+	tr.Apply(loadNotification(t, "../chainstream/testdata/sample_tx_sell.json"))
+
+	path := filepath.Join(t.TempDir(), "tracker.json")
+	if err := tr.Save(path); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	restored := tracker.New()
+	if err := restored.Load(path); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	expected, _ := tr.Position(wallet, mint)
+	got, ok := restored.Position(wallet, mint)
+	if !ok || got != expected {
+		t.Errorf("restored position %+v, expected %+v", got, expected)
+	}
+
+	if err := tracker.New().Load(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("Load() of missing file error: %v", err)
+	}
+}
+
+func TestSaveLoadStore(t *testing.T) {
+	ctx := context.Background()
+	tr := tracker.New(wallet)
+	tr.Apply(loadNotification(t, "../chainstream/testdata/sample_tx_sell.json"))
+
+	s := store.NewMemory(nil)
+	if err := tr.SaveStore(ctx, s, "tracker"); err != nil {
+		t.Fatalf("SaveStore() error: %v", err)
+	}
+	restored := tracker.New()
+	if err := restored.LoadStore(ctx, s, "tracker"); err != nil {
+		t.Fatalf("LoadStore() error: %v", err)
+	}
+	expected, _ := tr.Position(wallet, mint)
+	if got, ok := restored.Position(wallet, mint); !ok || got != expected {
+		t.Errorf("restored position %+v, expected %+v", got, expected)
+	}
+	if err := tracker.New().LoadStore(ctx, s, "missing"); err != nil {
+		t.Errorf("LoadStore() of missing key error: %v", err)
+	}
+}
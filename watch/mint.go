@@ -0,0 +1,178 @@
+// Package watch subscribes to the transactions of things that account key filters cannot
+// name directly, resolving them to account keys over RPC and keeping the subscription
+// filter up to date as the resolution changes.
+package watch
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+	"github.com/gerasimovvladislav/zensol-go/rpc"
+)
+
+// Defaults of MintConfig.
+const (
+	DefaultMaxAccounts = 20
+	DefaultRefresh     = time.Minute
+)
+
+// TokenAccountLister lists the largest token accounts of a mint, largest first, like
+// rpc.Client.
+type TokenAccountLister interface {
+	GetTokenLargestAccounts(ctx context.Context, mint string, commitment chainstream.Commitment) ([]rpc.TokenAccountBalance, error)
+}
+
+// MintConfig describes the subscription of Mint.
+type MintConfig struct {
+	// Mint is the SPL mint to watch.
+	Mint string
+	// Network of the subscription, which must be the network of the lister. Zero uses
+	// chainstream.DefaultNetwork.
+	Network chainstream.Network
+	// Commitment of the subscription and of the lookups. Zero uses
+	// chainstream.DefaultCommitment.
+	Commitment chainstream.Commitment
+	// MaxAccounts bounds the token accounts in the filter, the largest first. Zero uses
+	// DefaultMaxAccounts, all those returned by getTokenLargestAccounts.
+	MaxAccounts int
+	// MinAmount leaves token accounts holding fewer base units out of the filter, e.g. to
+	// keep pool vaults and bonding curves only.
+	MinAmount uint64
+	// Refresh is the time between lookups of the largest token accounts. Zero uses
+	// DefaultRefresh.
+	Refresh time.Duration
+	// OnUpdate, when set, is called with the token accounts in the filter after the first
+	// lookup and every change.
+	OnUpdate func(accounts []string)
+	// OnError, when set, is called with the errors of refreshes, which keep the previous
+	// filter in place.
+	OnError func(err error)
+	// Clock drives refreshes. Nil uses the system clock.
+	Clock clock.Clock
+}
+
+// Mint subscribes with the manager to the transactions touching the mint of the config and
+// passes them to do until ctx is done, when it returns nil, or the subscription ends.
+//
+// Transactions trading a mint rarely mention it, but always move tokens in or out of the
+// pools and bonding curves holding it, which are among its largest token accounts. The
+// server-side filter requires transactions to mention the mint or one of its largest token
+// accounts, looked up with the lister. They are looked up again every Refresh and the
+// filter is updated when they change, e.g. once a token migrates to a new pool. The
+// transactions matching the filter that do not mention the mint and hold no token account
+// of it, e.g. swaps of other tokens through a shared pool authority, are dropped before do.
+func Mint(ctx context.Context, manager *chainstream.SubscriptionManager, lister TokenAccountLister, config MintConfig, do chainstream.HandlerFunc) error {
+	if config.Mint == "" {
+		return errors.New("watch: mint is required")
+	}
+	if config.Network == "" {
+		config.Network = chainstream.DefaultNetwork
+	}
+	if config.Commitment == 0 {
+		config.Commitment = chainstream.DefaultCommitment
+	}
+	if config.MaxAccounts <= 0 {
+		config.MaxAccounts = DefaultMaxAccounts
+	}
+	if config.Refresh <= 0 {
+		config.Refresh = DefaultRefresh
+	}
+	if config.Clock == nil {
+		config.Clock = clock.System()
+	}
+
+	accounts, err := largestAccounts(ctx, lister, config)
+	if err != nil {
+		return err
+	}
+	request, err := chainstream.NewTransactionsSubscribeRequest(config.Network, mintFilter(config, accounts))
+	if err != nil {
+		return err
+	}
+	mentions := &chainstream.AccountKeysFilter{OneOf: []string{config.Mint}}
+	touches := chainstream.AnyOf(mentions.Match, chainstream.TouchesMints(config.Mint))
+	sub, err := manager.Subscribe(ctx, request, chainstream.Chain(do, chainstream.Where(touches)))
+	if err != nil {
+		return err
+	}
+	if config.OnUpdate != nil {
+		config.OnUpdate(accounts)
+	}
+
+	ticker := config.Clock.NewTicker(config.Refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			_ = sub.Unsubscribe(context.Background())
+			return nil
+		case <-sub.Done():
+			return sub.Err()
+		case <-ticker.C():
+		}
+
+		refreshed, err := largestAccounts(ctx, lister, config)
+		if err == nil && !sameSet(accounts, refreshed) {
+			if err = sub.UpdateFilter(ctx, mintFilter(config, refreshed)); err == nil {
+				accounts = refreshed
+				if config.OnUpdate != nil {
+					config.OnUpdate(accounts)
+				}
+			}
+		}
+		if err != nil && ctx.Err() == nil && config.OnError != nil {
+			config.OnError(err)
+		}
+	}
+}
+
+// largestAccounts looks up the largest token accounts of the mint holding at least
+// MinAmount, at most MaxAccounts of them.
+func largestAccounts(ctx context.Context, lister TokenAccountLister, config MintConfig) ([]string, error) {
+	balances, err := lister.GetTokenLargestAccounts(ctx, config.Mint, config.Commitment)
+	if err != nil {
+		return nil, err
+	}
+	var accounts []string
+	for _, balance := range balances {
+		if len(accounts) == config.MaxAccounts {
+			break
+		}
+		if amount, err := strconv.ParseUint(balance.Amount, 10, 64); err == nil && amount < config.MinAmount {
+			continue
+		}
+		accounts = append(accounts, balance.Address)
+	}
+	return accounts, nil
+}
+
+// mintFilter requires transactions to mention the mint or one of the token accounts.
+func mintFilter(config MintConfig, accounts []string) chainstream.TransactionFilter {
+	return chainstream.NewFilter().
+		ExcludeVotes().
+		Commitment(config.Commitment).
+		OneOf(config.Mint).
+		OneOf(accounts...).
+		Build()
+}
+
+// sameSet reports whether a and b hold the same keys, in any order.
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,154 @@
+package watch_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gerasimovvladislav/zensol-go/chainstream"
+	"github.com/gerasimovvladislav/zensol-go/chainstream/chainstreamtest"
+	"github.com/gerasimovvladislav/zensol-go/clock"
+	"github.com/gerasimovvladislav/zensol-go/rpc"
+	"github.com/gerasimovvladislav/zensol-go/watch"
+)
+
+const (
+	mint    = "DNvtizsEYyknJoW3QYwDA7ncjxri3KBBTeLydEZCpump"
+	curve   = "2Bbpz6yGq54VSwRHxptxdNuo9sTNBesFPwPuTvw7kJVY"
+	holder  = "2KedgPeWSFS8vocGcDLg2Rxy6FVj69wF4UhcAkucPyqe"
+	newPool = "8fC59gfiQerpTpTiEJVvB4u1UgHuBsEDGnxiQUU5AJQo"
+)
+
+func loadNotification(t *testing.T, file string) *chainstream.TransactionNotification {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	var tx chainstream.TransactionNotification
+	if err := json.Unmarshal(data, &tx); err != nil {
+		t.Fatalf("failed to unmarshal tx: %v", err)
+	}
+	return &tx
+}
+
+// newServer acknowledges subscribe requests, sending the filters on filters, and sends
+// the notifications on the first subscription.
+func newServer(t *testing.T, filters chan<- []string, notifications ...*chainstream.TransactionNotification) string {
+	t.Helper()
+	return chainstreamtest.NewServer(t, chainstreamtest.Config{
+		Respond: func(_ context.Context, request chainstreamtest.Request, _ int64) (interface{}, *chainstream.RPCError) {
+			params, _ := request.TransactionParams()
+			filters <- params.Filter.AccountKeys.OneOf
+			return nil, nil
+		},
+		Notify: func(_ chainstreamtest.Request, id int64) []*chainstream.TransactionNotification {
+			if id != 1 {
+				return nil
+			}
+			return notifications
+		},
+	}).Endpoint()
+}
+
+// lister returns the largest accounts it is set to.
+type lister struct {
+	mu       sync.Mutex
+	balances []rpc.TokenAccountBalance
+}
+
+func (l *lister) set(balances ...rpc.TokenAccountBalance) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.balances = balances
+}
+
+func (l *lister) GetTokenLargestAccounts(_ context.Context, m string, _ chainstream.Commitment) ([]rpc.TokenAccountBalance, error) {
+	if m != mint {
+		return nil, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.balances, nil
+}
+
+func balance(address, amount string) rpc.TokenAccountBalance {
+	b := rpc.TokenAccountBalance{Address: address}
+	b.Amount = amount
+	return b
+}
+
+func TestMint(t *testing.T) {
+	filters := make(chan []string, 2)
+	endpoint := newServer(t, filters,
+		loadNotification(t, "../chainstream/testdata/sample_tx_buy.json"),
+		loadNotification(t, "../chainstream/testdata/sample_tx_create.json"))
+	manager := chainstream.NewClient(chainstream.NewConfig(endpoint)).NewSubscriptionManager()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() { _ = manager.Run(ctx) }()
+
+	accounts := &lister{}
+	accounts.set(balance(curve, "900000"), balance(holder, "100000"), balance("dust", "1"))
+	clk := clock.NewFake(time.Now())
+	updates := make(chan []string, 2)
+	delivered := make(chan string, 2)
+	done := make(chan error, 1)
+	go func() {
+		done <- watch.Mint(ctx, manager, accounts, watch.MintConfig{
+			Mint:      mint,
+			MinAmount: 1000,
+			Refresh:   time.Minute,
+			OnUpdate:  func(accounts []string) { updates <- accounts },
+			Clock:     clk,
+		}, func(n *chainstream.TransactionNotification) error {
+			delivered <- n.Signature()
+			return nil
+		})
+	}()
+
+	if filter := <-filters; !reflect.DeepEqual(filter, []string{mint, curve, holder}) {
+		t.Errorf("subscribed with accounts %v, expected the mint and its largest accounts", filter)
+	}
+	if update := <-updates; !reflect.DeepEqual(update, []string{curve, holder}) {
+		t.Errorf("OnUpdate() accounts = %v", update)
+	}
+	// Only the buy trades the mint; the create trades another one.
+	buy := loadNotification(t, "../chainstream/testdata/sample_tx_buy.json").Signature()
+	if signature := <-delivered; signature != buy {
+		t.Errorf("delivered %s, expected the buy", signature)
+	}
+
+	// A new pool appears. Refreshes before and after it update the filter once.
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+	accounts.set(balance(newPool, "2000000"), balance(curve, "900000"))
+	clk.Advance(time.Minute)
+	clk.Advance(time.Minute)
+	if filter := <-filters; !reflect.DeepEqual(filter, []string{mint, newPool, curve}) {
+		t.Errorf("updated the filter with accounts %v, expected the new pool", filter)
+	}
+	if update := <-updates; !reflect.DeepEqual(update, []string{newPool, curve}) {
+		t.Errorf("OnUpdate() accounts = %v", update)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Mint() error: %v", err)
+	}
+	if len(filters) > 0 || len(delivered) > 0 {
+		t.Errorf("%d unexpected filter updates and %d deliveries", len(filters), len(delivered))
+	}
+}
+
+func TestMintRequiresMint(t *testing.T) {
+	manager := chainstream.NewClient(chainstream.NewConfig("wss://example.com")).NewSubscriptionManager()
+	if err := watch.Mint(context.Background(), manager, &lister{}, watch.MintConfig{}, nil); err == nil {
+		t.Error("Mint() without a mint succeeded")
+	}
+}